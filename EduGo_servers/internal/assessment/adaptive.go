@@ -0,0 +1,99 @@
+package assessment
+
+import (
+	"time"
+
+	"EduGo_servers/internal/irt"
+	"EduGo_servers/internal/models"
+)
+
+// selectionTopN 每次在信息量最大的几道题中随机选题，控制题目曝光
+const selectionTopN = 3
+
+// ItemParams 题目的项目参数，未标定时按难度等级估计
+func ItemParams(question *models.Question) irt.Params {
+	if question.Parameter != nil {
+		return irt.Params{
+			Discrimination: question.Parameter.Discrimination,
+			Difficulty:     question.Parameter.Difficulty,
+		}
+	}
+	return irt.DefaultParams(question.Difficulty)
+}
+
+// UpdateAbility 根据已作答的题目重新估计学生能力
+func UpdateAbility(paper *models.Assessment, attempt *models.AssessmentAttempt) {
+	questions := make(map[int64]*models.Question, len(paper.Questions))
+	for _, item := range paper.Questions {
+		questions[item.QuestionID] = item.Question
+	}
+
+	answers := make([]irt.Answer, 0, len(attempt.Answers))
+	for _, answer := range attempt.Answers {
+		question := questions[answer.QuestionID]
+		if question == nil || answer.Correct == nil {
+			continue
+		}
+		answers = append(answers, irt.Answer{Params: ItemParams(question), Correct: *answer.Correct})
+	}
+	theta, se := irt.EstimateAbility(answers)
+	attempt.Ability, attempt.AbilitySE = &theta, &se
+}
+
+// NextQuestion 按停止规则判断是否继续作答，继续时返回下一题，停止时返回nil
+// 停止规则：达到最多题数、题库用完，或者作答题数不少于最少题数且标准误不大于目标值
+func NextQuestion(paper *models.Assessment, attempt *models.AssessmentAttempt) *models.AssessmentQuestion {
+	answered := len(attempt.Answers)
+	if paper.MaxItems > 0 && answered >= paper.MaxItems {
+		return nil
+	}
+	if paper.TargetSE > 0 && answered >= paper.MinItems && attempt.AbilitySE != nil && *attempt.AbilitySE <= paper.TargetSE {
+		return nil
+	}
+
+	used := make(map[int64]bool, answered)
+	for _, answer := range attempt.Answers {
+		used[answer.QuestionID] = true
+	}
+	var candidates []*models.AssessmentQuestion
+	var params []irt.Params
+	for i := range paper.Questions {
+		item := &paper.Questions[i]
+		if used[item.QuestionID] || item.Question == nil || !item.Question.IsObjective() {
+			continue
+		}
+		candidates = append(candidates, item)
+		params = append(params, ItemParams(item.Question))
+	}
+
+	theta := 0.0
+	if attempt.Ability != nil {
+		theta = *attempt.Ability
+	}
+	index := irt.SelectItem(theta, params, selectionTopN)
+	if index < 0 {
+		return nil
+	}
+	return candidates[index]
+}
+
+// AnswerAdaptive 对自适应测评当前题目的作答评分，更新能力估计并选出下一题
+// 满足停止规则时结束作答，返回true
+func AnswerAdaptive(paper *models.Assessment, attempt *models.AssessmentAttempt, item *models.AssessmentQuestion, responses []string, now time.Time) bool {
+	answer := models.AttemptAnswer{AttemptID: attempt.ID, QuestionID: item.QuestionID}
+	answer.SetResponses(responses)
+	result, _ := Grade(item.Question, responses, item.Points)
+	answer.Score, answer.Correct = &result.Score, &result.Correct
+	attempt.Answers = append(attempt.Answers, answer)
+
+	UpdateAbility(paper, attempt)
+	next := NextQuestion(paper, attempt)
+	if next == nil {
+		attempt.CurrentQuestionID = nil
+		attempt.SubmittedAt = &now
+		Summarize(paper, attempt)
+		return true
+	}
+	attempt.CurrentQuestionID = &next.QuestionID
+	return false
+}
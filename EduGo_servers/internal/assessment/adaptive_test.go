@@ -0,0 +1,135 @@
+package assessment
+
+import (
+	"math"
+	"testing"
+
+	"EduGo_servers/internal/irt"
+	"EduGo_servers/internal/models"
+)
+
+// adaptivePaper 题库为给定题型的自适应测评，题目ID从1开始，难度等级均为3
+func adaptivePaper(types ...string) *models.Assessment {
+	paper := &models.Assessment{}
+	for i, questionType := range types {
+		id := int64(i + 1)
+		paper.Questions = append(paper.Questions, models.AssessmentQuestion{
+			QuestionID: id,
+			Question:   &models.Question{ID: id, Type: questionType, Difficulty: 3},
+			Points:     1,
+		})
+	}
+	return paper
+}
+
+// answered 已按顺序答对的题目
+func answered(questionIDs ...int64) []models.AttemptAnswer {
+	correct := true
+	answers := make([]models.AttemptAnswer, 0, len(questionIDs))
+	for _, id := range questionIDs {
+		answers = append(answers, models.AttemptAnswer{QuestionID: id, Correct: &correct})
+	}
+	return answers
+}
+
+// withLimits 设置自适应测评的停止规则
+func withLimits(paper *models.Assessment, minItems, maxItems int, targetSE float64) *models.Assessment {
+	paper.MinItems, paper.MaxItems, paper.TargetSE = minItems, maxItems, targetSE
+	return paper
+}
+
+func TestItemParams(t *testing.T) {
+	tests := []struct {
+		name     string
+		question *models.Question
+		want     irt.Params
+	}{
+		{"未标定按难度等级估计", &models.Question{Difficulty: 5}, irt.Params{Discrimination: 1, Difficulty: 2}},
+		{
+			"已标定使用标定参数",
+			&models.Question{Difficulty: 5, Parameter: &models.ItemParameter{Discrimination: 1.7, Difficulty: -0.4}},
+			irt.Params{Discrimination: 1.7, Difficulty: -0.4},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ItemParams(tt.question); got != tt.want {
+				t.Errorf("ItemParams() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNextQuestion(t *testing.T) {
+	objective := []string{models.QuestionSingleChoice, models.QuestionTrueFalse, models.QuestionSingleChoice, models.QuestionFillBlank}
+	lowSE, highSE := 0.2, 0.8
+	tests := []struct {
+		name      string
+		paper     *models.Assessment
+		answers   []models.AttemptAnswer
+		abilitySE *float64
+		want      map[int64]bool // 可能选出的题目，为空表示应停止作答
+	}{
+		{"开始作答", adaptivePaper(objective...), nil, nil, map[int64]bool{1: true, 2: true, 3: true, 4: true}},
+		{"跳过已作答的题目", adaptivePaper(objective...), answered(1, 3), nil, map[int64]bool{2: true, 4: true}},
+		{"只剩一道未作答", adaptivePaper(objective...), answered(4, 2, 1), nil, map[int64]bool{3: true}},
+		{"题库用完", adaptivePaper(objective...), answered(1, 2, 3, 4), nil, nil},
+		{
+			"跳过简答题",
+			adaptivePaper(models.QuestionSingleChoice, models.QuestionShortAnswer, models.QuestionTrueFalse),
+			answered(1), nil, map[int64]bool{3: true},
+		},
+		{"只剩简答题", adaptivePaper(models.QuestionSingleChoice, models.QuestionShortAnswer), answered(1), nil, nil},
+		{"达到最多题数", withLimits(adaptivePaper(objective...), 0, 2, 0), answered(1, 2), nil, nil},
+		{"标准误达到目标值", withLimits(adaptivePaper(objective...), 1, 0, 0.3), answered(1), &lowSE, nil},
+		{"标准误未达到目标值", withLimits(adaptivePaper(objective...), 1, 0, 0.3), answered(1), &highSE, map[int64]bool{2: true, 3: true, 4: true}},
+		{"未达到最少题数", withLimits(adaptivePaper(objective...), 2, 0, 0.3), answered(1), &lowSE, map[int64]bool{2: true, 3: true, 4: true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for i := 0; i < 30; i++ {
+				attempt := &models.AssessmentAttempt{Answers: tt.answers, AbilitySE: tt.abilitySE}
+				next := NextQuestion(tt.paper, attempt)
+				if tt.want == nil {
+					if next != nil {
+						t.Fatalf("NextQuestion() = question %d, want nil", next.QuestionID)
+					}
+					continue
+				}
+				if next == nil {
+					t.Fatalf("NextQuestion() = nil, want one of %v", tt.want)
+				}
+				if !tt.want[next.QuestionID] {
+					t.Fatalf("NextQuestion() = question %d, want one of %v", next.QuestionID, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestUpdateAbility(t *testing.T) {
+	paper := adaptivePaper(models.QuestionSingleChoice, models.QuestionSingleChoice, models.QuestionSingleChoice)
+	correct, wrong := true, false
+	tests := []struct {
+		name    string
+		answers []models.AttemptAnswer
+		check   func(theta float64) bool
+	}{
+		{"没有作答", nil, func(theta float64) bool { return math.Abs(theta) < 1e-9 }},
+		{"答对提高能力", []models.AttemptAnswer{{QuestionID: 1, Correct: &correct}, {QuestionID: 2, Correct: &correct}}, func(theta float64) bool { return theta > 0 }},
+		{"答错降低能力", []models.AttemptAnswer{{QuestionID: 1, Correct: &wrong}, {QuestionID: 2, Correct: &wrong}}, func(theta float64) bool { return theta < 0 }},
+		{"忽略未评分和不在题库中的题目", []models.AttemptAnswer{{QuestionID: 1}, {QuestionID: 9, Correct: &correct}}, func(theta float64) bool { return math.Abs(theta) < 1e-9 }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attempt := &models.AssessmentAttempt{Answers: tt.answers}
+			UpdateAbility(paper, attempt)
+			if attempt.Ability == nil || attempt.AbilitySE == nil {
+				t.Fatal("UpdateAbility() did not set ability")
+			}
+			if !tt.check(*attempt.Ability) {
+				t.Errorf("Ability = %v", *attempt.Ability)
+			}
+		})
+	}
+}
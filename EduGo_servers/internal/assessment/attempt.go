@@ -0,0 +1,86 @@
+package assessment
+
+import (
+	"time"
+
+	"EduGo_servers/internal/models"
+)
+
+// GradeAttempt 交卷时对作答评分：客观题自动评分，简答题留待教师评分
+// 没有作答的题目也会生成一条作答记录，计为0分。自适应测评的题目在作答时已经评分，只按已作答的题目结束作答
+func GradeAttempt(paper *models.Assessment, attempt *models.AssessmentAttempt, submittedAt time.Time) {
+	if paper.IsAdaptive() {
+		attempt.CurrentQuestionID = nil
+		attempt.SubmittedAt = &submittedAt
+		UpdateAbility(paper, attempt)
+		Summarize(paper, attempt)
+		return
+	}
+
+	existing := make(map[int64]models.AttemptAnswer, len(attempt.Answers))
+	for _, answer := range attempt.Answers {
+		existing[answer.QuestionID] = answer
+	}
+
+	answers := make([]models.AttemptAnswer, 0, len(paper.Questions))
+	for _, item := range paper.Questions {
+		answer, ok := existing[item.QuestionID]
+		if !ok {
+			answer = models.AttemptAnswer{AttemptID: attempt.ID, QuestionID: item.QuestionID}
+			answer.SetResponses(nil)
+		}
+		answer.Score, answer.Correct = nil, nil
+		if item.Question != nil {
+			if result, ok := Grade(item.Question, answer.Responses(), item.Points); ok {
+				score, correct := result.Score, result.Correct
+				answer.Score, answer.Correct = &score, &correct
+			}
+		}
+		answers = append(answers, answer)
+	}
+
+	attempt.Answers = answers
+	attempt.SubmittedAt = &submittedAt
+	Summarize(paper, attempt)
+}
+
+// Summarize 重新计算作答的总分和状态，所有题目都已评分时为已评分，否则为等待教师评分
+// 自适应测评只统计已作答的题目
+func Summarize(paper *models.Assessment, attempt *models.AssessmentAttempt) {
+	if paper.IsAdaptive() {
+		points := make(map[int64]float64, len(paper.Questions))
+		for _, item := range paper.Questions {
+			points[item.QuestionID] = item.Points
+		}
+		attempt.Score, attempt.MaxScore = 0, 0
+		for _, answer := range attempt.Answers {
+			if answer.Score != nil {
+				attempt.Score += *answer.Score
+			}
+			attempt.MaxScore += points[answer.QuestionID]
+		}
+		attempt.Score = roundScore(attempt.Score)
+		attempt.Status = models.AttemptGraded
+		return
+	}
+
+	scored := make(map[int64]float64, len(attempt.Answers))
+	for _, answer := range attempt.Answers {
+		if answer.Score != nil {
+			scored[answer.QuestionID] = *answer.Score
+		}
+	}
+
+	attempt.Score = 0
+	attempt.Status = models.AttemptGraded
+	for _, item := range paper.Questions {
+		score, ok := scored[item.QuestionID]
+		if !ok {
+			attempt.Status = models.AttemptSubmitted
+			continue
+		}
+		attempt.Score += score
+	}
+	attempt.Score = roundScore(attempt.Score)
+	attempt.MaxScore = paper.TotalPoints()
+}
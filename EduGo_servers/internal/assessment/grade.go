@@ -0,0 +1,121 @@
+// Package assessment 对测评作答进行自动评分
+package assessment
+
+import (
+	"math"
+	"strings"
+
+	"EduGo_servers/internal/models"
+)
+
+// Result 一道题的评分结果
+type Result struct {
+	Score   float64
+	Correct bool
+}
+
+// Normalize 规范化作答内容以便比较：去掉首尾空白、合并连续空白、全角字符转半角、忽略大小写
+func Normalize(value string) string {
+	var b strings.Builder
+	for _, r := range strings.TrimSpace(value) {
+		switch {
+		case r == '　':
+			r = ' '
+		case r >= '！' && r <= '～':
+			r -= 0xfee0
+		}
+		b.WriteRune(r)
+	}
+	return strings.ToLower(strings.Join(strings.Fields(b.String()), " "))
+}
+
+// Grade 对客观题自动评分，points为该题在试卷中的分值
+// 简答题不能自动评分，返回false
+//
+// 评分规则：
+//   - 单选题、判断题：答对得满分
+//   - 多选题：全部选对得满分；少选且没有选错得一半分；有选错不得分
+//   - 填空题：按答对的空数比例得分，每个空与任一可接受答案一致即为答对
+func Grade(question *models.Question, responses []string, points float64) (Result, bool) {
+	switch question.Type {
+	case models.QuestionSingleChoice, models.QuestionMultipleChoice:
+		return gradeChoice(question, responses, points), true
+	case models.QuestionTrueFalse:
+		return gradeTrueFalse(question, responses, points), true
+	case models.QuestionFillBlank:
+		return gradeFillBlank(question, responses, points), true
+	}
+	return Result{}, false
+}
+
+func gradeChoice(question *models.Question, responses []string, points float64) Result {
+	correct := map[string]bool{}
+	for _, option := range question.Options {
+		if option.IsCorrect {
+			correct[Normalize(option.Label)] = true
+		}
+	}
+
+	selected := map[string]bool{}
+	for _, response := range responses {
+		if label := Normalize(response); label != "" {
+			selected[label] = true
+		}
+	}
+	if len(selected) == 0 {
+		return Result{}
+	}
+	for label := range selected {
+		if !correct[label] {
+			return Result{}
+		}
+	}
+	if len(selected) == len(correct) {
+		return Result{Score: points, Correct: true}
+	}
+	if question.Type == models.QuestionMultipleChoice {
+		return Result{Score: roundScore(points / 2)}
+	}
+	return Result{}
+}
+
+func gradeTrueFalse(question *models.Question, responses []string, points float64) Result {
+	if len(responses) != 1 || len(question.Answers) == 0 {
+		return Result{}
+	}
+	if Normalize(responses[0]) == Normalize(question.Answers[0].Content) {
+		return Result{Score: points, Correct: true}
+	}
+	return Result{}
+}
+
+func gradeFillBlank(question *models.Question, responses []string, points float64) Result {
+	blanks := question.BlankCount()
+	if blanks == 0 {
+		return Result{}
+	}
+
+	accepted := make([]map[string]bool, blanks)
+	for _, answer := range question.Answers {
+		if accepted[answer.Blank] == nil {
+			accepted[answer.Blank] = map[string]bool{}
+		}
+		accepted[answer.Blank][Normalize(answer.Content)] = true
+	}
+
+	right := 0
+	for i := 0; i < blanks && i < len(responses); i++ {
+		if value := Normalize(responses[i]); value != "" && accepted[i][value] {
+			right++
+		}
+	}
+	return Result{
+		Score:   roundScore(points * float64(right) / float64(blanks)),
+		Correct: right == blanks,
+	}
+}
+
+// roundScore 分数保留两位小数
+func roundScore(score float64) float64 {
+	return math.Round(score*100) / 100
+}
@@ -0,0 +1,105 @@
+// Package calendar 生成iCalendar（RFC 5545）格式的日历，用于课表订阅
+package calendar
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+const (
+	dateTimeLayout = "20060102T150405"
+	maxLineOctets  = 75
+)
+
+// Event 日历中的一个事件
+type Event struct {
+	UID         string
+	Summary     string
+	Description string
+	Location    string
+	Start       time.Time
+	End         time.Time
+	RepeatUntil *time.Time // 不为空时每周重复，直到该时间
+}
+
+// Calendar 一个日历，包含若干事件
+type Calendar struct {
+	Name   string
+	Events []Event
+}
+
+// WriteTo 以iCalendar格式写出日历，事件时间使用本地时间（浮动时间）
+func (c *Calendar) WriteTo(w io.Writer) (int64, error) {
+	var b strings.Builder
+	writeLine(&b, "BEGIN:VCALENDAR")
+	writeLine(&b, "VERSION:2.0")
+	writeLine(&b, "PRODID:-//EduGo//Timetable//CN")
+	writeLine(&b, "CALSCALE:GREGORIAN")
+	writeLine(&b, "METHOD:PUBLISH")
+	if c.Name != "" {
+		writeLine(&b, "X-WR-CALNAME:"+escapeText(c.Name))
+	}
+
+	stamp := time.Now().UTC().Format(dateTimeLayout) + "Z"
+	for _, event := range c.Events {
+		writeLine(&b, "BEGIN:VEVENT")
+		writeLine(&b, "UID:"+event.UID)
+		writeLine(&b, "DTSTAMP:"+stamp)
+		writeLine(&b, "DTSTART:"+event.Start.Format(dateTimeLayout))
+		writeLine(&b, "DTEND:"+event.End.Format(dateTimeLayout))
+		if event.RepeatUntil != nil {
+			writeLine(&b, "RRULE:FREQ=WEEKLY;UNTIL="+event.RepeatUntil.Format(dateTimeLayout))
+		}
+		writeLine(&b, "SUMMARY:"+escapeText(event.Summary))
+		if event.Location != "" {
+			writeLine(&b, "LOCATION:"+escapeText(event.Location))
+		}
+		if event.Description != "" {
+			writeLine(&b, "DESCRIPTION:"+escapeText(event.Description))
+		}
+		writeLine(&b, "END:VEVENT")
+	}
+	writeLine(&b, "END:VCALENDAR")
+
+	n, err := io.WriteString(w, b.String())
+	return int64(n), err
+}
+
+// escapeText 转义TEXT类型属性值中的特殊字符
+func escapeText(s string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`)
+	return replacer.Replace(s)
+}
+
+// writeLine 写出一行，超过75个字节时按规范折行，不拆分多字节字符
+func writeLine(b *strings.Builder, line string) {
+	width := 0
+	for _, r := range line {
+		size := len(string(r))
+		if width+size > maxLineOctets {
+			b.WriteString("\r\n ")
+			width = 1
+		}
+		b.WriteRune(r)
+		width += size
+	}
+	b.WriteString("\r\n")
+}
+
+// NextWeekday 返回from当天或之后第一个指定星期几（1-7，周一到周日）的日期
+func NextWeekday(from time.Time, weekday int) time.Time {
+	target := time.Weekday(weekday % 7)
+	days := (int(target) - int(from.Weekday()) + 7) % 7
+	return from.AddDate(0, 0, days)
+}
+
+// AtClock 返回date当天指定时刻（HH:MM）的时间
+func AtClock(date time.Time, clock string) (time.Time, error) {
+	t, err := time.Parse("15:04", clock)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid clock %q: %w", clock, err)
+	}
+	return time.Date(date.Year(), date.Month(), date.Day(), t.Hour(), t.Minute(), 0, 0, date.Location()), nil
+}
@@ -0,0 +1,599 @@
+package controllers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"EduGo_servers/internal/database"
+	"EduGo_servers/internal/models"
+	"EduGo_servers/internal/repository"
+)
+
+// termResponse 构造学期的返回数据
+func termResponse(term *models.AcademicTerm) gin.H {
+	return gin.H{
+		"id":         term.ID,
+		"name":       term.Name,
+		"start_date": term.StartDate.Format("2006-01-02"),
+		"end_date":   term.EndDate.Format("2006-01-02"),
+		"is_current": term.IsCurrent,
+	}
+}
+
+// offeringResponse 构造开课的返回数据
+func offeringResponse(offering *models.CourseOffering) gin.H {
+	teachers := []gin.H{}
+	for _, teacher := range offering.Teachers {
+		teachers = append(teachers, gin.H{
+			"id":        teacher.ID,
+			"username":  teacher.Username,
+			"firstName": teacher.FirstName,
+			"lastName":  teacher.LastName,
+		})
+	}
+	response := gin.H{
+		"id":               offering.ID,
+		"course_id":        offering.CourseID,
+		"term_id":          offering.TermID,
+		"teachers":         teachers,
+		"published":        offering.Published,
+		"capacity":         offering.Capacity,
+		"enrolled_count":   offering.EnrolledCount,
+		"available_seats":  offering.AvailableSeats(),
+		"waitlist_enabled": offering.WaitlistEnabled,
+		"enroll_start_at":  offering.EnrollStartAt,
+		"enroll_end_at":    offering.EnrollEndAt,
+		"drop_deadline":    offering.DropDeadline,
+		"grading_scale_id": offering.GradingScaleID,
+		"created_at":       offering.CreatedAt,
+	}
+	if offering.Course != nil {
+		response["course_code"] = offering.Course.Code
+		response["course_name"] = offering.Course.Name
+	}
+	if offering.Term != nil {
+		response["term_name"] = offering.Term.Name
+	}
+	return response
+}
+
+// resolveTerm 验证请求中引用的学期
+// termID为0时表示不关联学期；不再接受只有学期名称的自由文本学期。验证失败时已写入响应
+func resolveTerm(c *gin.Context, termID int64, semester string) (*models.AcademicTerm, bool) {
+	if termID == 0 {
+		if strings.TrimSpace(semester) != "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "请通过term_id指定学期"})
+			return nil, false
+		}
+		return nil, true
+	}
+
+	termRepo := repository.NewAcademicTermRepository(database.DB)
+	term, err := termRepo.GetTermByID(c.Request.Context(), termID)
+	if err != nil {
+		log.Printf("获取学期失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return nil, false
+	}
+	if term == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "学期不存在"})
+		return nil, false
+	}
+	return term, true
+}
+
+// getTermParam 获取路径参数指定的学期
+func getTermParam(c *gin.Context) (*models.AcademicTerm, bool) {
+	termID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的学期ID"})
+		return nil, false
+	}
+	return resolveTerm(c, termID, "")
+}
+
+// validateTermDates 解析并验证学期的起止日期，返回错误信息
+func validateTermDates(c *gin.Context, name, startDate, endDate string, excludeID int64) (time.Time, time.Time, string, error) {
+	start, err := time.ParseInLocation("2006-01-02", startDate, time.Local)
+	if err != nil {
+		return time.Time{}, time.Time{}, "无效的开始日期", nil
+	}
+	end, err := time.ParseInLocation("2006-01-02", endDate, time.Local)
+	if err != nil {
+		return time.Time{}, time.Time{}, "无效的结束日期", nil
+	}
+	if !end.After(start) {
+		return time.Time{}, time.Time{}, "结束日期必须晚于开始日期", nil
+	}
+
+	termRepo := repository.NewAcademicTermRepository(database.DB)
+	exists, err := termRepo.TermNameExists(c.Request.Context(), name, excludeID)
+	if err != nil {
+		return time.Time{}, time.Time{}, "", err
+	}
+	if exists {
+		return time.Time{}, time.Time{}, "学期名称已存在", nil
+	}
+
+	overlaps, err := termRepo.TermOverlaps(c.Request.Context(), start, end, excludeID)
+	if err != nil {
+		return time.Time{}, time.Time{}, "", err
+	}
+	if overlaps {
+		return time.Time{}, time.Time{}, "学期日期与其他学期重叠", nil
+	}
+	return start, end, "", nil
+}
+
+// CreateTerm 创建学期（管理员及以上权限）
+func CreateTerm(c *gin.Context) {
+	var input struct {
+		Name      string `json:"name" binding:"required"`
+		StartDate string `json:"start_date" binding:"required"`
+		EndDate   string `json:"end_date" binding:"required"`
+		IsCurrent bool   `json:"is_current"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的输入数据"})
+		return
+	}
+
+	name := strings.TrimSpace(input.Name)
+	start, end, message, err := validateTermDates(c, name, input.StartDate, input.EndDate, 0)
+	if err != nil {
+		log.Printf("验证学期失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+	if message != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": message})
+		return
+	}
+
+	term := &models.AcademicTerm{
+		Name:      name,
+		StartDate: start,
+		EndDate:   end,
+	}
+
+	termRepo := repository.NewAcademicTermRepository(database.DB)
+	if err := termRepo.CreateTerm(c.Request.Context(), term); err != nil {
+		log.Printf("创建学期失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+
+	if input.IsCurrent {
+		if err := termRepo.SetCurrentTerm(c.Request.Context(), term.ID); err != nil {
+			log.Printf("设置当前学期失败: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+			return
+		}
+		term.IsCurrent = true
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "学期创建成功",
+		"term":    termResponse(term),
+	})
+}
+
+// GetTerms 获取学期列表
+func GetTerms(c *gin.Context) {
+	termRepo := repository.NewAcademicTermRepository(database.DB)
+	terms, err := termRepo.GetTerms(c.Request.Context())
+	if err != nil {
+		log.Printf("获取学期列表失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+
+	termList := []gin.H{}
+	for _, term := range terms {
+		termList = append(termList, termResponse(term))
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"terms": termList,
+	})
+}
+
+// GetCurrentTerm 获取当前学期
+func GetCurrentTerm(c *gin.Context) {
+	termRepo := repository.NewAcademicTermRepository(database.DB)
+	term, err := termRepo.GetCurrentTerm(c.Request.Context(), time.Now())
+	if err != nil {
+		log.Printf("获取当前学期失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+	if term == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "当前没有学期"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"term": termResponse(term),
+	})
+}
+
+// GetTerm 获取学期详情
+func GetTerm(c *gin.Context) {
+	term, ok := getTermParam(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"term": termResponse(term),
+	})
+}
+
+// UpdateTerm 更新学期（管理员及以上权限）
+func UpdateTerm(c *gin.Context) {
+	term, ok := getTermParam(c)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		Name      *string `json:"name"`
+		StartDate *string `json:"start_date"`
+		EndDate   *string `json:"end_date"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的输入数据"})
+		return
+	}
+
+	name := term.Name
+	if input.Name != nil {
+		name = strings.TrimSpace(*input.Name)
+		if name == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "学期名称不能为空"})
+			return
+		}
+	}
+	startDate := term.StartDate.Format("2006-01-02")
+	if input.StartDate != nil {
+		startDate = *input.StartDate
+	}
+	endDate := term.EndDate.Format("2006-01-02")
+	if input.EndDate != nil {
+		endDate = *input.EndDate
+	}
+
+	start, end, message, err := validateTermDates(c, name, startDate, endDate, term.ID)
+	if err != nil {
+		log.Printf("验证学期失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+	if message != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": message})
+		return
+	}
+
+	term.Name = name
+	term.StartDate = start
+	term.EndDate = end
+
+	termRepo := repository.NewAcademicTermRepository(database.DB)
+	if err := termRepo.UpdateTerm(c.Request.Context(), term); err != nil {
+		log.Printf("更新学期失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "学期更新成功",
+		"term":    termResponse(term),
+	})
+}
+
+// DeleteTerm 删除学期（管理员及以上权限），已被开课或师生关系引用的学期不能删除
+func DeleteTerm(c *gin.Context) {
+	term, ok := getTermParam(c)
+	if !ok {
+		return
+	}
+
+	termRepo := repository.NewAcademicTermRepository(database.DB)
+	inUse, err := termRepo.IsTermInUse(c.Request.Context(), term.ID)
+	if err != nil {
+		log.Printf("检查学期引用失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+	if inUse {
+		c.JSON(http.StatusConflict, gin.H{"error": "学期已被开课或师生关系引用，不能删除"})
+		return
+	}
+
+	if err := termRepo.DeleteTerm(c.Request.Context(), term.ID); err != nil {
+		log.Printf("删除学期失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "学期删除成功",
+	})
+}
+
+// SetCurrentTerm 设置当前学期（管理员及以上权限）
+func SetCurrentTerm(c *gin.Context) {
+	term, ok := getTermParam(c)
+	if !ok {
+		return
+	}
+
+	termRepo := repository.NewAcademicTermRepository(database.DB)
+	if err := termRepo.SetCurrentTerm(c.Request.Context(), term.ID); err != nil {
+		log.Printf("设置当前学期失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+	term.IsCurrent = true
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "当前学期设置成功",
+		"term":    termResponse(term),
+	})
+}
+
+// RolloverTerm 学期结转（管理员及以上权限），将本学期的开课及授课教师复制到目标学期
+func RolloverTerm(c *gin.Context) {
+	term, ok := getTermParam(c)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		TargetTermID int64 `json:"target_term_id" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的输入数据"})
+		return
+	}
+
+	if input.TargetTermID == term.ID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "目标学期不能与源学期相同"})
+		return
+	}
+	target, ok := resolveTerm(c, input.TargetTermID, "")
+	if !ok {
+		return
+	}
+	if !target.StartDate.After(term.StartDate) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "目标学期必须晚于源学期"})
+		return
+	}
+
+	offeringRepo := repository.NewCourseOfferingRepository(database.DB)
+	created, err := offeringRepo.CopyOfferings(c.Request.Context(), term.ID, target.ID, c.GetInt64("userID"))
+	if err != nil {
+		log.Printf("学期结转失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "学期结转成功",
+		"created": created,
+		"term":    termResponse(target),
+	})
+}
+
+// GetTermOfferings 获取学期的开课列表，学生和家长只能看到已发布的开课
+func GetTermOfferings(c *gin.Context) {
+	term, ok := getTermParam(c)
+	if !ok {
+		return
+	}
+
+	role := c.GetString("role")
+	publishedOnly := c.Query("published") == "true" || role == models.RoleStudent || role == models.RoleParent
+
+	offeringRepo := repository.NewCourseOfferingRepository(database.DB)
+	offerings, err := offeringRepo.GetOfferingsByTerm(c.Request.Context(), term.ID, publishedOnly)
+	if err != nil {
+		log.Printf("获取开课列表失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+
+	offeringList := []gin.H{}
+	for _, offering := range offerings {
+		offeringList = append(offeringList, offeringResponse(offering))
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"offerings": offeringList,
+	})
+}
+
+// GetCourseOfferings 获取课程的开课列表
+func GetCourseOfferings(c *gin.Context) {
+	course, ok := getCourseParam(c)
+	if !ok {
+		return
+	}
+
+	offeringRepo := repository.NewCourseOfferingRepository(database.DB)
+	offerings, err := offeringRepo.GetOfferingsByCourse(c.Request.Context(), course.ID)
+	if err != nil {
+		log.Printf("获取开课列表失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+
+	offeringList := []gin.H{}
+	for _, offering := range offerings {
+		offeringList = append(offeringList, offeringResponse(offering))
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"offerings": offeringList,
+	})
+}
+
+// CreateCourseOffering 在指定学期开设课程（管理员或该课程的负责教师）
+// 未指定授课教师时使用课程的负责教师
+func CreateCourseOffering(c *gin.Context) {
+	course, ok := getCourseParam(c)
+	if !ok {
+		return
+	}
+
+	if !isAdminRole(c.GetString("role")) && !course.HasTeacher(c.GetInt64("userID")) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "只有管理员或课程负责教师可以开课"})
+		return
+	}
+
+	var input struct {
+		TermID     int64   `json:"term_id" binding:"required"`
+		TeacherIDs []int64 `json:"teacher_ids"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的输入数据"})
+		return
+	}
+
+	term, ok := resolveTerm(c, input.TermID, "")
+	if !ok {
+		return
+	}
+
+	teachers := course.Teachers
+	if len(input.TeacherIDs) > 0 {
+		var message string
+		var err error
+		teachers, message, err = loadCourseTeachers(c.Request.Context(), input.TeacherIDs)
+		if err != nil {
+			log.Printf("获取教师信息失败: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+			return
+		}
+		if message != "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": message})
+			return
+		}
+	}
+
+	offeringRepo := repository.NewCourseOfferingRepository(database.DB)
+	existing, err := offeringRepo.GetOffering(c.Request.Context(), course.ID, term.ID)
+	if err != nil {
+		log.Printf("获取开课失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+	if existing != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "该课程在此学期已开课"})
+		return
+	}
+
+	offering := &models.CourseOffering{
+		CourseID:  course.ID,
+		Course:    course,
+		TermID:    term.ID,
+		Term:      term,
+		Teachers:  teachers,
+		CreatedBy: c.GetInt64("userID"),
+	}
+
+	if err := offeringRepo.CreateOffering(c.Request.Context(), offering); err != nil {
+		log.Printf("创建开课失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":  "开课成功",
+		"offering": offeringResponse(offering),
+	})
+}
+
+// getOfferingParam 获取路径参数指定的开课，并验证其属于该课程
+func getOfferingParam(c *gin.Context, course *models.Course) (*models.CourseOffering, bool) {
+	offeringID, err := strconv.ParseInt(c.Param("offering_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的开课ID"})
+		return nil, false
+	}
+
+	offeringRepo := repository.NewCourseOfferingRepository(database.DB)
+	offering, err := offeringRepo.GetOfferingByID(c.Request.Context(), offeringID)
+	if err != nil {
+		log.Printf("获取开课失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return nil, false
+	}
+	if offering == nil || offering.CourseID != course.ID {
+		c.JSON(http.StatusNotFound, gin.H{"error": "开课不存在"})
+		return nil, false
+	}
+	return offering, true
+}
+
+// getManagedOffering 获取路径参数指定的开课，要求当前用户是管理员、课程负责教师或该开课的授课教师
+func getManagedOffering(c *gin.Context) (*models.CourseOffering, bool) {
+	course, ok := getCourseParam(c)
+	if !ok {
+		return nil, false
+	}
+	offering, ok := getOfferingParam(c, course)
+	if !ok {
+		return nil, false
+	}
+
+	userID := c.GetInt64("userID")
+	if !isAdminRole(c.GetString("role")) && !course.HasTeacher(userID) && !offering.HasTeacher(userID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "只有管理员或课程教师可以管理开课"})
+		return nil, false
+	}
+	return offering, true
+}
+
+// DeleteCourseOffering 取消开课（管理员或该课程的负责教师）
+func DeleteCourseOffering(c *gin.Context) {
+	course, ok := getCourseParam(c)
+	if !ok {
+		return
+	}
+
+	if !isAdminRole(c.GetString("role")) && !course.HasTeacher(c.GetInt64("userID")) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "只有管理员或课程负责教师可以取消开课"})
+		return
+	}
+
+	offering, ok := getOfferingParam(c, course)
+	if !ok {
+		return
+	}
+
+	offeringRepo := repository.NewCourseOfferingRepository(database.DB)
+	if err := offeringRepo.DeleteOffering(c.Request.Context(), offering.ID); err != nil {
+		if errors.Is(err, repository.ErrOfferingInUse) {
+			c.JSON(http.StatusConflict, gin.H{"error": "开课已有学生选课、作答、考勤或成绩记录，不能取消开课"})
+			return
+		}
+		log.Printf("删除开课失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "取消开课成功",
+	})
+}
@@ -0,0 +1,1113 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"EduGo_servers/internal/assessment"
+	"EduGo_servers/internal/database"
+	"EduGo_servers/internal/jobs"
+	"EduGo_servers/internal/models"
+	"EduGo_servers/internal/repository"
+)
+
+// assessmentResponse 构造试卷的返回数据，withQuestions为true时包含题目和答案（教师查看）
+func assessmentResponse(paper *models.Assessment, withQuestions bool) gin.H {
+	response := gin.H{
+		"id":                 paper.ID,
+		"offering_id":        paper.OfferingID,
+		"title":              paper.Title,
+		"description":        paper.Description,
+		"published":          paper.Published,
+		"time_limit_minutes": paper.TimeLimitMinutes,
+		"opens_at":           paper.OpensAt,
+		"closes_at":          paper.ClosesAt,
+		"max_attempts":       paper.MaxAttempts,
+		"show_answers":       paper.ShowAnswers,
+		"mode":               paper.Mode,
+		"question_count":     len(paper.Questions),
+		"total_points":       paper.TotalPoints(),
+		"created_by":         paper.CreatedBy,
+		"created_at":         paper.CreatedAt,
+		"updated_at":         paper.UpdatedAt,
+	}
+	if paper.IsAdaptive() {
+		response["min_items"] = paper.MinItems
+		response["max_items"] = paper.MaxItems
+		response["target_se"] = paper.TargetSE
+	}
+	if paper.Offering != nil && paper.Offering.Course != nil {
+		response["course_id"] = paper.Offering.CourseID
+		response["course_name"] = paper.Offering.Course.Name
+	}
+	if withQuestions {
+		response["questions"] = paperQuestionsResponse(paper, true)
+	}
+	return response
+}
+
+// paperQuestionsResponse 构造试卷题目列表，withAnswers为false时不包含正确答案
+func paperQuestionsResponse(paper *models.Assessment, withAnswers bool) []gin.H {
+	questions := make([]gin.H, 0, len(paper.Questions))
+	for _, item := range paper.Questions {
+		if item.Question == nil {
+			continue
+		}
+		question := questionResponse(item.Question, withAnswers)
+		question["position"] = item.Position
+		question["points"] = item.Points
+		questions = append(questions, question)
+	}
+	return questions
+}
+
+// attemptResponse 构造作答的返回数据
+// 作答进行中时包含题目（不含答案）和已保存的作答；提交后包含每题得分，reveal为true时同时给出正确答案和解析
+func attemptResponse(paper *models.Assessment, attempt *models.AssessmentAttempt, reveal bool) gin.H {
+	response := gin.H{
+		"id":            attempt.ID,
+		"assessment_id": attempt.AssessmentID,
+		"student_id":    attempt.StudentID,
+		"number":        attempt.Number,
+		"status":        attempt.Status,
+		"started_at":    attempt.StartedAt,
+		"deadline_at":   attempt.DeadlineAt,
+		"submitted_at":  attempt.SubmittedAt,
+	}
+	if attempt.Student != nil {
+		response["student"] = userSummary(attempt.Student)
+	}
+	if attempt.Status != models.AttemptInProgress {
+		response["score"] = attempt.Score
+		response["max_score"] = attempt.MaxScore
+		if attempt.Ability != nil {
+			response["ability"] = attempt.Ability
+			response["ability_se"] = attempt.AbilitySE
+		}
+	}
+	if paper == nil {
+		return response
+	}
+
+	answers := make([]gin.H, 0, len(attempt.Answers))
+	for _, answer := range attempt.Answers {
+		item := gin.H{
+			"question_id": answer.QuestionID,
+			"response":    answer.Responses(),
+		}
+		if attempt.Status != models.AttemptInProgress {
+			item["score"] = answer.Score
+			item["correct"] = answer.Correct
+			item["feedback"] = answer.Feedback
+		}
+		answers = append(answers, item)
+	}
+	response["answers"] = answers
+	if paper.IsAdaptive() {
+		response["answered_count"] = len(attempt.Answers)
+		if attempt.Status == models.AttemptInProgress {
+			// 自适应测评作答中只给出当前题目，已作答的题目不再显示
+			response["answers"] = []gin.H{}
+			response["current_question"] = currentQuestionResponse(paper, attempt)
+			return response
+		}
+		if reveal {
+			response["questions"] = answeredQuestionsResponse(paper, attempt)
+		}
+		return response
+	}
+	if attempt.Status == models.AttemptInProgress || reveal {
+		response["questions"] = paperQuestionsResponse(paper, attempt.Status != models.AttemptInProgress && reveal)
+	}
+	return response
+}
+
+// currentQuestionResponse 构造自适应测评当前待作答的题目，不包含正确答案
+func currentQuestionResponse(paper *models.Assessment, attempt *models.AssessmentAttempt) gin.H {
+	if attempt.CurrentQuestionID == nil {
+		return nil
+	}
+	for _, item := range paper.Questions {
+		if item.QuestionID == *attempt.CurrentQuestionID && item.Question != nil {
+			question := questionResponse(item.Question, false)
+			question["points"] = item.Points
+			return question
+		}
+	}
+	return nil
+}
+
+// answeredQuestionsResponse 按作答顺序构造自适应测评已作答的题目，包含正确答案和解析
+func answeredQuestionsResponse(paper *models.Assessment, attempt *models.AssessmentAttempt) []gin.H {
+	items := make(map[int64]models.AssessmentQuestion, len(paper.Questions))
+	for _, item := range paper.Questions {
+		items[item.QuestionID] = item
+	}
+	questions := make([]gin.H, 0, len(attempt.Answers))
+	for i, answer := range attempt.Answers {
+		item, ok := items[answer.QuestionID]
+		if !ok || item.Question == nil {
+			continue
+		}
+		question := questionResponse(item.Question, true)
+		question["position"] = i + 1
+		question["points"] = item.Points
+		questions = append(questions, question)
+	}
+	return questions
+}
+
+// canManageAssessment 检查当前用户能否管理试卷，与作业相同：管理员、课程负责教师或开课的授课教师
+func canManageAssessment(c *gin.Context, paper *models.Assessment) bool {
+	return canManageAssignment(c, paper.Offering)
+}
+
+// getAssessmentParam 获取路径参数指定的试卷
+func getAssessmentParam(c *gin.Context) (*models.Assessment, bool) {
+	assessmentID, ok := parseIDParam(c, "id", "无效的试卷ID")
+	if !ok {
+		return nil, false
+	}
+
+	paper, err := repository.NewAssessmentRepository(database.DB).GetAssessmentByID(c.Request.Context(), assessmentID)
+	if err != nil {
+		log.Printf("获取试卷失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return nil, false
+	}
+	if paper == nil || paper.Offering == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "试卷不存在"})
+		return nil, false
+	}
+	return paper, true
+}
+
+// getManagedAssessment 获取路径参数指定的试卷，要求当前用户可以管理该试卷
+func getManagedAssessment(c *gin.Context) (*models.Assessment, bool) {
+	paper, ok := getAssessmentParam(c)
+	if !ok {
+		return nil, false
+	}
+	if !canManageAssessment(c, paper) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "只有管理员或课程教师可以管理试卷"})
+		return nil, false
+	}
+	return paper, true
+}
+
+// getVisibleAssessment 获取路径参数指定的试卷，学生和家长只能看到已发布的试卷
+func getVisibleAssessment(c *gin.Context) (*models.Assessment, bool, bool) {
+	paper, ok := getAssessmentParam(c)
+	if !ok {
+		return nil, false, false
+	}
+
+	manage, allowed, err := canViewOfferingAssignments(c, paper.Offering)
+	if err != nil {
+		log.Printf("检查试卷权限失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return nil, false, false
+	}
+	if !manage && (!allowed || !paper.Published) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "试卷不存在"})
+		return nil, false, false
+	}
+	return paper, manage, true
+}
+
+// validateAssessment 检查试卷设置是否有效，返回错误信息
+func validateAssessment(paper *models.Assessment) string {
+	if strings.TrimSpace(paper.Title) == "" {
+		return "试卷标题不能为空"
+	}
+	if paper.TimeLimitMinutes < 0 {
+		return "作答时长不能为负数"
+	}
+	if paper.MaxAttempts < 0 {
+		return "作答次数不能为负数"
+	}
+	if paper.OpensAt != nil && paper.ClosesAt != nil && !paper.ClosesAt.After(*paper.OpensAt) {
+		return "截止时间必须晚于开放时间"
+	}
+	switch paper.Mode {
+	case models.AssessmentFixed:
+	case models.AssessmentAdaptive:
+		if paper.MinItems < 0 || paper.MaxItems < 0 {
+			return "作答题数不能为负数"
+		}
+		if paper.MaxItems > 0 && paper.MinItems > paper.MaxItems {
+			return "最少作答题数不能大于最多作答题数"
+		}
+		if paper.TargetSE < 0 || paper.TargetSE >= 1 {
+			return "目标标准误必须在0到1之间"
+		}
+		for _, item := range paper.Questions {
+			if item.Question != nil && !item.Question.IsObjective() {
+				return "自适应测评只能使用客观题"
+			}
+		}
+	default:
+		return "无效的测评模式"
+	}
+	return ""
+}
+
+// closeExpiredAttempt 超过交卷期限仍未提交的作答按期限时已保存的内容自动交卷
+func closeExpiredAttempt(ctx context.Context, paper *models.Assessment, attempt *models.AssessmentAttempt) error {
+	if attempt.Status != models.AttemptInProgress || !attempt.Expired(time.Now()) {
+		return nil
+	}
+	assessment.GradeAttempt(paper, attempt, *attempt.DeadlineAt)
+	err := repository.NewAssessmentRepository(database.DB).SubmitAttempt(ctx, attempt)
+	if errors.Is(err, repository.ErrAttemptNotInProgress) {
+		return nil
+	}
+	if err == nil {
+		jobs.NotifyMasteryUpdate()
+		syncAttemptGrade(ctx, attempt)
+	}
+	return err
+}
+
+// CreateAssessment 在开课中创建试卷（管理员或课程教师）
+func CreateAssessment(c *gin.Context) {
+	offering, ok := getManagedOffering(c)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		Title            string  `json:"title" binding:"required"`
+		Description      string  `json:"description"`
+		TimeLimitMinutes int     `json:"time_limit_minutes"`
+		OpensAt          *string `json:"opens_at"`
+		ClosesAt         *string `json:"closes_at"`
+		MaxAttempts      *int    `json:"max_attempts"`
+		ShowAnswers      bool    `json:"show_answers"`
+		Mode             string  `json:"mode"`
+		MinItems         int     `json:"min_items"`
+		MaxItems         int     `json:"max_items"`
+		TargetSE         float64 `json:"target_se"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的输入数据"})
+		return
+	}
+
+	paper := &models.Assessment{
+		OfferingID:       offering.ID,
+		Title:            strings.TrimSpace(input.Title),
+		Description:      input.Description,
+		TimeLimitMinutes: input.TimeLimitMinutes,
+		MaxAttempts:      1,
+		ShowAnswers:      input.ShowAnswers,
+		Mode:             input.Mode,
+		MinItems:         input.MinItems,
+		MaxItems:         input.MaxItems,
+		TargetSE:         input.TargetSE,
+		CreatedBy:        c.GetInt64("userID"),
+	}
+	if paper.Mode == "" {
+		paper.Mode = models.AssessmentFixed
+	}
+	if input.MaxAttempts != nil {
+		paper.MaxAttempts = *input.MaxAttempts
+	}
+	var err error
+	if paper.OpensAt, err = parseOptionalTime(input.OpensAt, nil); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的开放时间"})
+		return
+	}
+	if paper.ClosesAt, err = parseOptionalTime(input.ClosesAt, nil); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的截止时间"})
+		return
+	}
+	if message := validateAssessment(paper); message != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": message})
+		return
+	}
+
+	if err := repository.NewAssessmentRepository(database.DB).CreateAssessment(c.Request.Context(), paper); err != nil {
+		log.Printf("创建试卷失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+	paper.Offering = offering
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":    "试卷创建成功",
+		"assessment": assessmentResponse(paper, true),
+	})
+}
+
+// GetOfferingAssessments 获取开课的试卷列表
+// 学生只能看到已发布的试卷，家长可以通过student_id查看孩子的试卷
+func GetOfferingAssessments(c *gin.Context) {
+	course, ok := getCourseParam(c)
+	if !ok {
+		return
+	}
+	offering, ok := getOfferingParam(c, course)
+	if !ok {
+		return
+	}
+	offering.Course = course
+
+	manage, allowed, err := canViewOfferingAssignments(c, offering)
+	if err != nil {
+		log.Printf("检查试卷权限失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+	if !allowed {
+		c.JSON(http.StatusForbidden, gin.H{"error": "无权查看该开课的试卷"})
+		return
+	}
+
+	papers, err := repository.NewAssessmentRepository(database.DB).GetOfferingAssessments(c.Request.Context(), offering.ID, !manage)
+	if err != nil {
+		log.Printf("获取试卷列表失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+
+	result := make([]gin.H, 0, len(papers))
+	for _, paper := range papers {
+		paper.Offering = offering
+		result = append(result, assessmentResponse(paper, false))
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"offering_id": offering.ID,
+		"assessments": result,
+	})
+}
+
+// GetAssessment 获取试卷详情，教师可以看到题目和答案，学生只能在作答时看到题目
+func GetAssessment(c *gin.Context) {
+	paper, manage, ok := getVisibleAssessment(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, assessmentResponse(paper, manage))
+}
+
+// UpdateAssessment 修改试卷设置（管理员或课程教师）
+func UpdateAssessment(c *gin.Context) {
+	paper, ok := getManagedAssessment(c)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		Title            *string  `json:"title"`
+		Description      *string  `json:"description"`
+		TimeLimitMinutes *int     `json:"time_limit_minutes"`
+		OpensAt          *string  `json:"opens_at"`
+		ClosesAt         *string  `json:"closes_at"`
+		MaxAttempts      *int     `json:"max_attempts"`
+		ShowAnswers      *bool    `json:"show_answers"`
+		Published        *bool    `json:"published"`
+		Mode             *string  `json:"mode"`
+		MinItems         *int     `json:"min_items"`
+		MaxItems         *int     `json:"max_items"`
+		TargetSE         *float64 `json:"target_se"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的输入数据"})
+		return
+	}
+
+	if input.Title != nil {
+		paper.Title = strings.TrimSpace(*input.Title)
+	}
+	if input.Description != nil {
+		paper.Description = *input.Description
+	}
+	if input.TimeLimitMinutes != nil {
+		paper.TimeLimitMinutes = *input.TimeLimitMinutes
+	}
+	if input.MaxAttempts != nil {
+		paper.MaxAttempts = *input.MaxAttempts
+	}
+	if input.ShowAnswers != nil {
+		paper.ShowAnswers = *input.ShowAnswers
+	}
+	if input.MinItems != nil {
+		paper.MinItems = *input.MinItems
+	}
+	if input.MaxItems != nil {
+		paper.MaxItems = *input.MaxItems
+	}
+	if input.TargetSE != nil {
+		paper.TargetSE = *input.TargetSE
+	}
+	if input.Mode != nil && *input.Mode != paper.Mode {
+		count, err := repository.NewAssessmentRepository(database.DB).CountAttempts(c.Request.Context(), paper.ID)
+		if err != nil {
+			log.Printf("获取作答记录失败: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+			return
+		}
+		if count > 0 {
+			c.JSON(http.StatusConflict, gin.H{"error": "已有学生作答，不能修改测评模式"})
+			return
+		}
+		paper.Mode = *input.Mode
+	}
+	if input.Published != nil {
+		if *input.Published && len(paper.Questions) == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "试卷没有题目，不能发布"})
+			return
+		}
+		paper.Published = *input.Published
+	}
+
+	var err error
+	if paper.OpensAt, err = parseOptionalTime(input.OpensAt, paper.OpensAt); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的开放时间"})
+		return
+	}
+	if paper.ClosesAt, err = parseOptionalTime(input.ClosesAt, paper.ClosesAt); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的截止时间"})
+		return
+	}
+	if message := validateAssessment(paper); message != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": message})
+		return
+	}
+
+	if err := repository.NewAssessmentRepository(database.DB).UpdateAssessment(c.Request.Context(), paper); err != nil {
+		log.Printf("更新试卷失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":    "试卷更新成功",
+		"assessment": assessmentResponse(paper, true),
+	})
+}
+
+// SetAssessmentQuestions 设置试卷的题目和分值（管理员或课程教师），已有学生作答的试卷不能修改题目
+func SetAssessmentQuestions(c *gin.Context) {
+	paper, ok := getManagedAssessment(c)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		Questions []struct {
+			QuestionID int64   `json:"question_id" binding:"required"`
+			Points     float64 `json:"points"`
+		} `json:"questions"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的输入数据"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	assessmentRepo := repository.NewAssessmentRepository(database.DB)
+	count, err := assessmentRepo.CountAttempts(ctx, paper.ID)
+	if err != nil {
+		log.Printf("获取作答记录失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+	if count > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "已有学生作答，不能修改试卷题目"})
+		return
+	}
+
+	ids := make([]int64, 0, len(input.Questions))
+	seen := map[int64]bool{}
+	for _, item := range input.Questions {
+		if seen[item.QuestionID] {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "题目不能重复"})
+			return
+		}
+		if item.Points <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "题目分值必须大于0"})
+			return
+		}
+		seen[item.QuestionID] = true
+		ids = append(ids, item.QuestionID)
+	}
+	questions, err := repository.NewQuestionRepository(database.DB).GetQuestionsByIDs(ctx, ids)
+	if err != nil {
+		log.Printf("获取题目失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+	if len(questions) != len(ids) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "部分题目不存在"})
+		return
+	}
+	if len(ids) == 0 && paper.Published {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "已发布的试卷不能清空题目"})
+		return
+	}
+
+	byID := make(map[int64]*models.Question, len(questions))
+	for _, question := range questions {
+		if paper.IsAdaptive() && !question.IsObjective() {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "自适应测评只能使用客观题"})
+			return
+		}
+		byID[question.ID] = question
+	}
+	items := make([]models.AssessmentQuestion, 0, len(input.Questions))
+	for i, item := range input.Questions {
+		items = append(items, models.AssessmentQuestion{
+			AssessmentID: paper.ID,
+			QuestionID:   item.QuestionID,
+			Position:     i + 1,
+			Points:       item.Points,
+		})
+	}
+	if err := assessmentRepo.ReplaceQuestions(ctx, paper.ID, items); err != nil {
+		log.Printf("设置试卷题目失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+
+	for i := range items {
+		items[i].Question = byID[items[i].QuestionID]
+	}
+	paper.Questions = items
+	c.JSON(http.StatusOK, gin.H{
+		"message":    "试卷题目已更新",
+		"assessment": assessmentResponse(paper, true),
+	})
+}
+
+// DeleteAssessment 删除试卷（管理员或课程教师），作答记录保留
+func DeleteAssessment(c *gin.Context) {
+	paper, ok := getManagedAssessment(c)
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	lessonIDs, err := repository.NewProgressRepository(database.DB).GetItemLessonIDs(ctx, models.LessonItemAssessment, paper.ID)
+	if err != nil {
+		log.Printf("获取课时失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+	if err := repository.NewAssessmentRepository(database.DB).DeleteAssessment(ctx, paper.ID); err != nil {
+		log.Printf("删除试卷失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+	// 已删除的测评不再计入课时
+	if err := refreshLessonProgress(ctx, lessonIDs); err != nil {
+		log.Printf("更新学习进度失败: %v", err)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "试卷已删除"})
+}
+
+// StartAssessmentAttempt 学生开始作答，已有进行中的作答时返回该作答
+func StartAssessmentAttempt(c *gin.Context) {
+	if c.GetString("role") != models.RoleStudent {
+		c.JSON(http.StatusForbidden, gin.H{"error": "只有学生可以作答"})
+		return
+	}
+
+	paper, _, ok := getVisibleAssessment(c)
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	studentID := c.GetInt64("userID")
+	assessmentRepo := repository.NewAssessmentRepository(database.DB)
+	current, err := assessmentRepo.GetInProgressAttempt(ctx, paper.ID, studentID)
+	if err == nil && current != nil {
+		err = closeExpiredAttempt(ctx, paper, current)
+	}
+	if err != nil {
+		log.Printf("获取作答记录失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+	if current != nil && current.Status == models.AttemptInProgress {
+		c.JSON(http.StatusOK, attemptResponse(paper, current, false))
+		return
+	}
+
+	now := time.Now()
+	if !paper.IsOpen(now) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "试卷当前不在开放作答时间内"})
+		return
+	}
+
+	attempt := &models.AssessmentAttempt{
+		AssessmentID: paper.ID,
+		StudentID:    studentID,
+		StartedAt:    now,
+		DeadlineAt:   paper.AttemptDeadline(now),
+	}
+	if paper.IsAdaptive() {
+		// 从能力估计的先验（均值0，标准差1）开始选题
+		assessment.UpdateAbility(paper, attempt)
+		next := assessment.NextQuestion(paper, attempt)
+		if next == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "试卷没有可以作答的题目"})
+			return
+		}
+		attempt.CurrentQuestionID = &next.QuestionID
+	}
+	err = assessmentRepo.CreateAttempt(ctx, attempt, paper.MaxAttempts)
+	if errors.Is(err, repository.ErrAttemptLimitReached) {
+		c.JSON(http.StatusConflict, gin.H{"error": "已达到最多作答次数"})
+		return
+	}
+	if err != nil {
+		log.Printf("开始作答失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, attemptResponse(paper, attempt, false))
+}
+
+// GetAssessmentAttempts 获取试卷的全部作答（管理员或课程教师）
+func GetAssessmentAttempts(c *gin.Context) {
+	paper, ok := getManagedAssessment(c)
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	attempts, err := repository.NewAssessmentRepository(database.DB).GetAttempts(ctx, paper.ID)
+	if err != nil {
+		log.Printf("获取作答记录失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+
+	result := make([]gin.H, 0, len(attempts))
+	for _, attempt := range attempts {
+		if err := closeExpiredAttempt(ctx, paper, attempt); err != nil {
+			log.Printf("自动交卷失败: %v", err)
+		}
+		result = append(result, attemptResponse(nil, attempt, false))
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"assessment_id": paper.ID,
+		"total_points":  paper.TotalPoints(),
+		"attempts":      result,
+	})
+}
+
+// GetMyAssessmentAttempts 获取学生对试卷的作答记录，家长需要通过student_id指定孩子
+func GetMyAssessmentAttempts(c *gin.Context) {
+	paper, _, ok := getVisibleAssessment(c)
+	if !ok {
+		return
+	}
+	studentID, allowed, err := offeringStudentParam(c, paper.Offering)
+	if err != nil {
+		log.Printf("检查试卷权限失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+	if !allowed {
+		c.JSON(http.StatusForbidden, gin.H{"error": "无权查看该学生的作答"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	attempts, err := repository.NewAssessmentRepository(database.DB).GetStudentAttempts(ctx, paper.ID, studentID)
+	if err != nil {
+		log.Printf("获取作答记录失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+
+	result := make([]gin.H, 0, len(attempts))
+	for _, attempt := range attempts {
+		if err := closeExpiredAttempt(ctx, paper, attempt); err != nil {
+			log.Printf("自动交卷失败: %v", err)
+		}
+		result = append(result, attemptResponse(nil, attempt, false))
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"assessment_id": paper.ID,
+		"student_id":    studentID,
+		"attempts":      result,
+	})
+}
+
+// getAttemptParam 获取路径参数指定的作答及其试卷
+// 学生本人、家长和可以管理试卷的教师可以访问，返回的manage表示当前用户可以管理试卷
+func getAttemptParam(c *gin.Context) (*models.AssessmentAttempt, *models.Assessment, bool, bool) {
+	attemptID, ok := parseIDParam(c, "id", "无效的作答ID")
+	if !ok {
+		return nil, nil, false, false
+	}
+
+	ctx := c.Request.Context()
+	assessmentRepo := repository.NewAssessmentRepository(database.DB)
+	attempt, err := assessmentRepo.GetAttemptByID(ctx, attemptID)
+	if err != nil {
+		log.Printf("获取作答记录失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return nil, nil, false, false
+	}
+	if attempt == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "作答记录不存在"})
+		return nil, nil, false, false
+	}
+	paper, err := assessmentRepo.GetAssessmentByID(ctx, attempt.AssessmentID)
+	if err != nil {
+		log.Printf("获取试卷失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return nil, nil, false, false
+	}
+	if paper == nil || paper.Offering == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "作答记录不存在"})
+		return nil, nil, false, false
+	}
+
+	manage := canManageAssessment(c, paper)
+	if !manage {
+		allowed, err := canAccessUserData(c, attempt.StudentID)
+		if err != nil {
+			log.Printf("检查用户关系失败: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+			return nil, nil, false, false
+		}
+		if !allowed {
+			c.JSON(http.StatusNotFound, gin.H{"error": "作答记录不存在"})
+			return nil, nil, false, false
+		}
+	}
+
+	if err := closeExpiredAttempt(ctx, paper, attempt); err != nil {
+		log.Printf("自动交卷失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return nil, nil, false, false
+	}
+	return attempt, paper, manage, true
+}
+
+// getOwnAttemptParam 获取路径参数指定的作答，只有作答的学生本人可以访问，并且作答必须在进行中
+func getOwnAttemptParam(c *gin.Context) (*models.AssessmentAttempt, *models.Assessment, bool) {
+	attempt, paper, _, ok := getAttemptParam(c)
+	if !ok {
+		return nil, nil, false
+	}
+	if attempt.StudentID != c.GetInt64("userID") {
+		c.JSON(http.StatusForbidden, gin.H{"error": "只能修改自己的作答"})
+		return nil, nil, false
+	}
+	if attempt.Status != models.AttemptInProgress {
+		c.JSON(http.StatusConflict, gin.H{"error": "作答已提交"})
+		return nil, nil, false
+	}
+	return attempt, paper, true
+}
+
+// GetAssessmentAttempt 获取作答详情
+// 学生和家长在提交后可以看到每题得分，试卷允许时还可以看到正确答案和解析
+func GetAssessmentAttempt(c *gin.Context) {
+	attempt, paper, manage, ok := getAttemptParam(c)
+	if !ok {
+		return
+	}
+	if attempt.Status == models.AttemptInProgress && attempt.StudentID != c.GetInt64("userID") {
+		// 作答进行中时只有学生本人可以看到题目
+		c.JSON(http.StatusOK, attemptResponse(nil, attempt, false))
+		return
+	}
+	c.JSON(http.StatusOK, attemptResponse(paper, attempt, manage || paper.ShowAnswers))
+}
+
+// attemptResponsesInput 学生提交的作答内容
+type attemptResponsesInput struct {
+	Responses []struct {
+		QuestionID int64    `json:"question_id" binding:"required"`
+		Response   []string `json:"response"`
+	} `json:"responses"`
+}
+
+// toAnswers 把作答内容转换为作答记录，题目不在试卷中时返回错误信息
+func (input *attemptResponsesInput) toAnswers(paper *models.Assessment, attemptID int64) ([]*models.AttemptAnswer, string) {
+	inPaper := make(map[int64]bool, len(paper.Questions))
+	for _, item := range paper.Questions {
+		inPaper[item.QuestionID] = true
+	}
+
+	answers := make([]*models.AttemptAnswer, 0, len(input.Responses))
+	seen := map[int64]bool{}
+	for _, item := range input.Responses {
+		if !inPaper[item.QuestionID] {
+			return nil, "题目不在试卷中"
+		}
+		if seen[item.QuestionID] {
+			continue
+		}
+		seen[item.QuestionID] = true
+		answer := &models.AttemptAnswer{AttemptID: attemptID, QuestionID: item.QuestionID}
+		answer.SetResponses(item.Response)
+		answers = append(answers, answer)
+	}
+	return answers, ""
+}
+
+// SaveAttemptResponses 保存作答进度（学生本人）
+func SaveAttemptResponses(c *gin.Context) {
+	attempt, paper, ok := getOwnAttemptParam(c)
+	if !ok {
+		return
+	}
+	if paper.IsAdaptive() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "自适应测评需要逐题作答"})
+		return
+	}
+
+	var input attemptResponsesInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的输入数据"})
+		return
+	}
+	answers, message := input.toAnswers(paper, attempt.ID)
+	if message != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": message})
+		return
+	}
+
+	err := repository.NewAssessmentRepository(database.DB).SaveResponses(c.Request.Context(), attempt.ID, answers)
+	if errors.Is(err, repository.ErrAttemptNotInProgress) {
+		c.JSON(http.StatusConflict, gin.H{"error": "作答已提交"})
+		return
+	}
+	if err != nil {
+		log.Printf("保存作答失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":     "作答已保存",
+		"deadline_at": attempt.DeadlineAt,
+	})
+}
+
+// SubmitAssessmentAttempt 交卷（学生本人），可以同时提交最后的作答内容。客观题立即自动评分
+func SubmitAssessmentAttempt(c *gin.Context) {
+	attempt, paper, ok := getOwnAttemptParam(c)
+	if !ok {
+		return
+	}
+
+	var input attemptResponsesInput
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的输入数据"})
+			return
+		}
+	}
+	if paper.IsAdaptive() && len(input.Responses) > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "自适应测评需要逐题作答"})
+		return
+	}
+	answers, message := input.toAnswers(paper, attempt.ID)
+	if message != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": message})
+		return
+	}
+
+	byQuestion := make(map[int64]int, len(attempt.Answers))
+	for i, answer := range attempt.Answers {
+		byQuestion[answer.QuestionID] = i
+	}
+	for _, answer := range answers {
+		if i, ok := byQuestion[answer.QuestionID]; ok {
+			attempt.Answers[i].Response = answer.Response
+		} else {
+			attempt.Answers = append(attempt.Answers, *answer)
+		}
+	}
+
+	assessment.GradeAttempt(paper, attempt, time.Now())
+	err := repository.NewAssessmentRepository(database.DB).SubmitAttempt(c.Request.Context(), attempt)
+	if errors.Is(err, repository.ErrAttemptNotInProgress) {
+		c.JSON(http.StatusConflict, gin.H{"error": "作答已提交"})
+		return
+	}
+	if err != nil {
+		log.Printf("交卷失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+	jobs.NotifyMasteryUpdate()
+	syncAttemptGrade(c.Request.Context(), attempt)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "交卷成功",
+		"attempt": attemptResponse(paper, attempt, paper.ShowAnswers),
+	})
+}
+
+// AnswerAdaptiveQuestion 学生作答自适应测评的当前题目（学生本人）
+// 作答立即评分并更新能力估计，满足停止规则时自动结束作答，否则返回下一题
+func AnswerAdaptiveQuestion(c *gin.Context) {
+	attempt, paper, ok := getOwnAttemptParam(c)
+	if !ok {
+		return
+	}
+	if !paper.IsAdaptive() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "该试卷不是自适应测评"})
+		return
+	}
+
+	var input struct {
+		QuestionID int64    `json:"question_id" binding:"required"`
+		Response   []string `json:"response"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的输入数据"})
+		return
+	}
+	if attempt.CurrentQuestionID == nil || *attempt.CurrentQuestionID != input.QuestionID {
+		c.JSON(http.StatusConflict, gin.H{"error": "只能作答当前题目"})
+		return
+	}
+	var item *models.AssessmentQuestion
+	for i := range paper.Questions {
+		if paper.Questions[i].QuestionID == input.QuestionID {
+			item = &paper.Questions[i]
+			break
+		}
+	}
+	if item == nil || item.Question == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "题目不在试卷中"})
+		return
+	}
+
+	finished := assessment.AnswerAdaptive(paper, attempt, item, input.Response, time.Now())
+	answer := &attempt.Answers[len(attempt.Answers)-1]
+	err := repository.NewAssessmentRepository(database.DB).SaveAdaptiveAnswer(c.Request.Context(), attempt, answer)
+	if errors.Is(err, repository.ErrAttemptNotInProgress) {
+		c.JSON(http.StatusConflict, gin.H{"error": "该题已作答或作答已提交"})
+		return
+	}
+	if err != nil {
+		log.Printf("保存作答失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+
+	message := "作答已保存"
+	if finished {
+		message = "测评已完成"
+		jobs.NotifyMasteryUpdate()
+		syncAttemptGrade(c.Request.Context(), attempt)
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message": message,
+		"correct": answer.Correct,
+		"attempt": attemptResponse(paper, attempt, finished && paper.ShowAnswers),
+	})
+}
+
+// GradeAttemptAnswer 教师为一道题评分（管理员或课程教师），用于简答题评分或修改自动评分结果
+func GradeAttemptAnswer(c *gin.Context) {
+	attempt, paper, manage, ok := getAttemptParam(c)
+	if !ok {
+		return
+	}
+	if !manage {
+		c.JSON(http.StatusForbidden, gin.H{"error": "只有管理员或课程教师可以评分"})
+		return
+	}
+	if attempt.Status == models.AttemptInProgress {
+		c.JSON(http.StatusConflict, gin.H{"error": "作答尚未提交"})
+		return
+	}
+	questionID, ok := parseIDParam(c, "question_id", "无效的题目ID")
+	if !ok {
+		return
+	}
+
+	var input struct {
+		Score    *float64 `json:"score" binding:"required"`
+		Feedback string   `json:"feedback"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的输入数据"})
+		return
+	}
+
+	var points float64
+	found := false
+	for _, item := range paper.Questions {
+		if item.QuestionID == questionID {
+			points, found = item.Points, true
+			break
+		}
+	}
+	if !found {
+		c.JSON(http.StatusNotFound, gin.H{"error": "题目不在试卷中"})
+		return
+	}
+	if *input.Score < 0 || *input.Score > points {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "得分必须在0到该题分值之间"})
+		return
+	}
+
+	index := -1
+	for i, answer := range attempt.Answers {
+		if answer.QuestionID == questionID {
+			index = i
+			break
+		}
+	}
+	if index < 0 && paper.IsAdaptive() {
+		c.JSON(http.StatusNotFound, gin.H{"error": "学生没有作答该题"})
+		return
+	}
+	if index < 0 {
+		answer := models.AttemptAnswer{AttemptID: attempt.ID, QuestionID: questionID}
+		answer.SetResponses(nil)
+		attempt.Answers = append(attempt.Answers, answer)
+		index = len(attempt.Answers) - 1
+	}
+
+	graderID := c.GetInt64("userID")
+	score := *input.Score
+	correct := score == points
+	answer := &attempt.Answers[index]
+	answer.Score = &score
+	answer.Correct = &correct
+	answer.Feedback = strings.TrimSpace(input.Feedback)
+	answer.GradedBy = &graderID
+	if paper.IsAdaptive() {
+		assessment.UpdateAbility(paper, attempt)
+	}
+	assessment.Summarize(paper, attempt)
+
+	if err := repository.NewAssessmentRepository(database.DB).SaveGrading(c.Request.Context(), attempt, answer); err != nil {
+		log.Printf("保存评分失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+	jobs.NotifyMasteryUpdate()
+	syncAttemptGrade(c.Request.Context(), attempt)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "评分已保存",
+		"attempt": attemptResponse(paper, attempt, true),
+	})
+}
@@ -0,0 +1,890 @@
+package controllers
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"EduGo_servers/internal/database"
+	"EduGo_servers/internal/models"
+	"EduGo_servers/internal/repository"
+	"EduGo_servers/internal/storage"
+)
+
+const (
+	maxUploadFileSize     = 20 << 20 // 单个文件最大20MB
+	maxSubmissionFiles    = 10       // 每次提交最多10个文件
+	maxSubmissionBodySize = maxSubmissionFiles*maxUploadFileSize + 1<<20
+)
+
+// assignmentResponse 构造作业的返回数据
+func assignmentResponse(assignment *models.Assignment) gin.H {
+	attachments := make([]gin.H, 0, len(assignment.Attachments))
+	for i := range assignment.Attachments {
+		attachments = append(attachments, attachmentResponse(&assignment.Attachments[i]))
+	}
+
+	knowledgePoints := make([]gin.H, 0, len(assignment.KnowledgePoints))
+	for i := range assignment.KnowledgePoints {
+		knowledgePoints = append(knowledgePoints, knowledgePointSummary(&assignment.KnowledgePoints[i]))
+	}
+
+	response := gin.H{
+		"id":                   assignment.ID,
+		"offering_id":          assignment.OfferingID,
+		"title":                assignment.Title,
+		"description":          assignment.Description,
+		"published":            assignment.Published,
+		"due_at":               assignment.DueAt,
+		"max_score":            assignment.MaxScore,
+		"late_policy":          assignment.LatePolicy,
+		"late_penalty_percent": assignment.LatePenaltyPercent,
+		"late_cutoff_at":       assignment.LateCutoffAt,
+		"allow_resubmit":       assignment.AllowResubmit,
+		"attachments":          attachments,
+		"knowledge_points":     knowledgePoints,
+		"created_by":           assignment.CreatedBy,
+		"created_at":           assignment.CreatedAt,
+		"updated_at":           assignment.UpdatedAt,
+	}
+	if assignment.Offering != nil && assignment.Offering.Course != nil {
+		response["course_id"] = assignment.Offering.CourseID
+		response["course_name"] = assignment.Offering.Course.Name
+	}
+	return response
+}
+
+// attachmentResponse 构造作业附件的返回数据
+func attachmentResponse(attachment *models.AssignmentAttachment) gin.H {
+	return gin.H{
+		"id":           attachment.ID,
+		"file_name":    attachment.FileName,
+		"content_type": attachment.ContentType,
+		"size":         attachment.Size,
+		"created_at":   attachment.CreatedAt,
+	}
+}
+
+// submissionResponse 构造作业提交的返回数据
+func submissionResponse(assignment *models.Assignment, submission *models.Submission) gin.H {
+	files := make([]gin.H, 0, len(submission.Files))
+	for _, file := range submission.Files {
+		files = append(files, gin.H{
+			"id":           file.ID,
+			"file_name":    file.FileName,
+			"content_type": file.ContentType,
+			"size":         file.Size,
+		})
+	}
+
+	response := gin.H{
+		"id":            submission.ID,
+		"assignment_id": submission.AssignmentID,
+		"student_id":    submission.StudentID,
+		"version":       submission.Version,
+		"is_latest":     submission.IsLatest,
+		"content":       submission.Content,
+		"is_late":       submission.IsLate,
+		"late_days":     submission.LateDays,
+		"late_penalty":  assignment.LatePenalty(submission.LateDays),
+		"files":         files,
+		"submitted_at":  submission.SubmittedAt,
+	}
+	if submission.Student != nil {
+		response["student"] = userSummary(submission.Student)
+	}
+	return response
+}
+
+// canManageAssignment 检查当前用户是否为管理员、课程负责教师或开课的授课教师
+func canManageAssignment(c *gin.Context, offering *models.CourseOffering) bool {
+	userID := c.GetInt64("userID")
+	if isAdminRole(c.GetString("role")) || offering.HasTeacher(userID) {
+		return true
+	}
+	return offering.Course != nil && offering.Course.HasTeacher(userID)
+}
+
+// isOfferingStudent 检查学生是否在开课的学生名单中
+func isOfferingStudent(ctx context.Context, offeringID, studentID int64) (bool, error) {
+	offeringRepo := repository.NewCourseOfferingRepository(database.DB)
+	studentIDs, err := offeringRepo.GetOfferingStudentIDs(ctx, offeringID)
+	if err != nil {
+		return false, err
+	}
+	for _, id := range studentIDs {
+		if id == studentID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// offeringStudentParam 确定当前用户以学生身份查看开课时对应的学生
+// 学生查看自己；家长需要通过student_id指定孩子。返回的allowed表示该学生在开课的学生名单中
+func offeringStudentParam(c *gin.Context, offering *models.CourseOffering) (studentID int64, allowed bool, err error) {
+	studentID = c.GetInt64("userID")
+	if value := c.Query("student_id"); value != "" {
+		studentID, err = strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return 0, false, nil
+		}
+		if ok, err := canAccessUserData(c, studentID); err != nil || !ok {
+			return 0, false, err
+		}
+	} else if c.GetString("role") != models.RoleStudent {
+		return 0, false, nil
+	}
+
+	allowed, err = isOfferingStudent(c.Request.Context(), offering.ID, studentID)
+	return studentID, allowed, err
+}
+
+// canViewOfferingAssignments 检查当前用户能否查看开课的作业
+// 教师和管理员可以查看全部作业；学生只能查看自己所在开课已发布的作业，家长需要通过student_id指定孩子
+func canViewOfferingAssignments(c *gin.Context, offering *models.CourseOffering) (manage bool, allowed bool, err error) {
+	if canManageAssignment(c, offering) {
+		return true, true, nil
+	}
+	_, allowed, err = offeringStudentParam(c, offering)
+	return false, allowed, err
+}
+
+// getAssignmentParam 获取路径参数指定的作业
+func getAssignmentParam(c *gin.Context) (*models.Assignment, bool) {
+	assignmentID, ok := parseIDParam(c, "id", "无效的作业ID")
+	if !ok {
+		return nil, false
+	}
+
+	assignmentRepo := repository.NewAssignmentRepository(database.DB)
+	assignment, err := assignmentRepo.GetAssignmentByID(c.Request.Context(), assignmentID)
+	if err != nil {
+		log.Printf("获取作业失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return nil, false
+	}
+	if assignment == nil || assignment.Offering == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "作业不存在"})
+		return nil, false
+	}
+	return assignment, true
+}
+
+// getManagedAssignment 获取路径参数指定的作业，要求当前用户可以管理该作业
+func getManagedAssignment(c *gin.Context) (*models.Assignment, bool) {
+	assignment, ok := getAssignmentParam(c)
+	if !ok {
+		return nil, false
+	}
+	if !canManageAssignment(c, assignment.Offering) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "只有管理员或课程教师可以管理作业"})
+		return nil, false
+	}
+	return assignment, true
+}
+
+// getVisibleAssignment 获取路径参数指定的作业，学生和家长只能看到已发布的作业
+func getVisibleAssignment(c *gin.Context) (*models.Assignment, bool) {
+	assignment, ok := getAssignmentParam(c)
+	if !ok {
+		return nil, false
+	}
+
+	manage, allowed, err := canViewOfferingAssignments(c, assignment.Offering)
+	if err != nil {
+		log.Printf("检查作业权限失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return nil, false
+	}
+	if !manage && (!allowed || !assignment.Published) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "作业不存在"})
+		return nil, false
+	}
+	return assignment, true
+}
+
+// validateAssignment 检查作业设置是否有效，返回错误信息
+func validateAssignment(assignment *models.Assignment) string {
+	if strings.TrimSpace(assignment.Title) == "" {
+		return "作业标题不能为空"
+	}
+	if assignment.MaxScore <= 0 {
+		return "满分必须大于0"
+	}
+	if !models.IsValidLatePolicy(assignment.LatePolicy) {
+		return "无效的迟交策略"
+	}
+	if assignment.LatePenaltyPercent < 0 || assignment.LatePenaltyPercent > 100 {
+		return "迟交扣分比例必须在0到100之间"
+	}
+	if assignment.LateCutoffAt != nil && !assignment.LateCutoffAt.After(assignment.DueAt) {
+		return "迟交截止时间必须晚于作业截止时间"
+	}
+	return ""
+}
+
+// newStorageKey 生成文件在存储中的键，加入随机前缀避免同名文件相互覆盖
+func newStorageKey(prefix, fileName string) (string, error) {
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s/%s_%s", prefix, hex.EncodeToString(buf), storage.SafeFileName(fileName)), nil
+}
+
+// saveUploadedFile 把上传的文件保存到存储中，返回文件键和大小
+func saveUploadedFile(ctx context.Context, header *multipart.FileHeader, prefix string) (string, int64, error) {
+	key, err := newStorageKey(prefix, header.Filename)
+	if err != nil {
+		return "", 0, err
+	}
+
+	file, err := header.Open()
+	if err != nil {
+		return "", 0, err
+	}
+	defer file.Close()
+
+	size, err := storage.Store.Save(ctx, key, file)
+	if err != nil {
+		return "", 0, err
+	}
+	return key, size, nil
+}
+
+// uploadContentType 获取上传文件的类型，未提供时使用通用二进制类型
+func uploadContentType(header *multipart.FileHeader) string {
+	if contentType := header.Header.Get("Content-Type"); contentType != "" {
+		return contentType
+	}
+	return "application/octet-stream"
+}
+
+// deleteStoredFiles 删除存储中的文件，失败时只记录日志
+func deleteStoredFiles(ctx context.Context, keys ...string) {
+	for _, key := range keys {
+		if err := storage.Store.Delete(ctx, key); err != nil {
+			log.Printf("删除文件 %s 失败: %v", key, err)
+		}
+	}
+}
+
+// serveStoredFile 以附件形式返回存储中的文件
+func serveStoredFile(c *gin.Context, key, fileName, contentType string, size int64) {
+	reader, err := storage.Store.Open(c.Request.Context(), key)
+	if errors.Is(err, storage.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "文件不存在"})
+		return
+	}
+	if err != nil {
+		log.Printf("读取文件失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+	defer reader.Close()
+
+	disposition := mime.FormatMediaType("attachment", map[string]string{"filename": fileName})
+	c.DataFromReader(http.StatusOK, size, contentType, reader, map[string]string{
+		"Content-Disposition": disposition,
+	})
+}
+
+// CreateAssignment 在开课中布置作业（管理员或课程教师）
+func CreateAssignment(c *gin.Context) {
+	offering, ok := getManagedOffering(c)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		Title              string   `json:"title" binding:"required"`
+		Description        string   `json:"description"`
+		DueAt              string   `json:"due_at" binding:"required"`
+		MaxScore           *float64 `json:"max_score"`
+		LatePolicy         string   `json:"late_policy"`
+		LatePenaltyPercent float64  `json:"late_penalty_percent"`
+		LateCutoffAt       *string  `json:"late_cutoff_at"`
+		AllowResubmit      bool     `json:"allow_resubmit"`
+		Published          bool     `json:"published"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的输入数据"})
+		return
+	}
+
+	dueAt, err := parseDateParam(input.DueAt)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的截止时间"})
+		return
+	}
+
+	assignment := &models.Assignment{
+		OfferingID:         offering.ID,
+		Title:              strings.TrimSpace(input.Title),
+		Description:        input.Description,
+		Published:          input.Published,
+		DueAt:              dueAt,
+		MaxScore:           100,
+		LatePolicy:         input.LatePolicy,
+		LatePenaltyPercent: input.LatePenaltyPercent,
+		AllowResubmit:      input.AllowResubmit,
+		CreatedBy:          c.GetInt64("userID"),
+	}
+	if input.MaxScore != nil {
+		assignment.MaxScore = *input.MaxScore
+	}
+	if assignment.LatePolicy == "" {
+		assignment.LatePolicy = models.LatePolicyReject
+	}
+	if assignment.LateCutoffAt, err = parseOptionalTime(input.LateCutoffAt, nil); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的迟交截止时间"})
+		return
+	}
+	if message := validateAssignment(assignment); message != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": message})
+		return
+	}
+
+	assignmentRepo := repository.NewAssignmentRepository(database.DB)
+	if err := assignmentRepo.CreateAssignment(c.Request.Context(), assignment); err != nil {
+		log.Printf("创建作业失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+	assignment.Offering = offering
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":    "作业创建成功",
+		"assignment": assignmentResponse(assignment),
+	})
+}
+
+// GetOfferingAssignments 获取开课的作业列表
+// 学生只能看到已发布的作业，家长可以通过student_id查看孩子的作业
+func GetOfferingAssignments(c *gin.Context) {
+	course, ok := getCourseParam(c)
+	if !ok {
+		return
+	}
+	offering, ok := getOfferingParam(c, course)
+	if !ok {
+		return
+	}
+	offering.Course = course
+
+	manage, allowed, err := canViewOfferingAssignments(c, offering)
+	if err != nil {
+		log.Printf("检查作业权限失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+	if !allowed {
+		c.JSON(http.StatusForbidden, gin.H{"error": "无权查看该开课的作业"})
+		return
+	}
+
+	assignmentRepo := repository.NewAssignmentRepository(database.DB)
+	assignments, err := assignmentRepo.GetOfferingAssignments(c.Request.Context(), offering.ID, !manage)
+	if err != nil {
+		log.Printf("获取作业列表失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+
+	result := make([]gin.H, 0, len(assignments))
+	for _, assignment := range assignments {
+		assignment.Offering = offering
+		result = append(result, assignmentResponse(assignment))
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"offering_id": offering.ID,
+		"assignments": result,
+	})
+}
+
+// GetAssignment 获取作业详情
+func GetAssignment(c *gin.Context) {
+	assignment, ok := getVisibleAssignment(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"assignment": assignmentResponse(assignment)})
+}
+
+// UpdateAssignment 更新作业（管理员或课程教师）
+func UpdateAssignment(c *gin.Context) {
+	assignment, ok := getManagedAssignment(c)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		Title              *string  `json:"title"`
+		Description        *string  `json:"description"`
+		DueAt              *string  `json:"due_at"`
+		MaxScore           *float64 `json:"max_score"`
+		LatePolicy         *string  `json:"late_policy"`
+		LatePenaltyPercent *float64 `json:"late_penalty_percent"`
+		LateCutoffAt       *string  `json:"late_cutoff_at"`
+		AllowResubmit      *bool    `json:"allow_resubmit"`
+		Published          *bool    `json:"published"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的输入数据"})
+		return
+	}
+
+	if input.Title != nil {
+		assignment.Title = strings.TrimSpace(*input.Title)
+	}
+	if input.Description != nil {
+		assignment.Description = *input.Description
+	}
+	if input.DueAt != nil {
+		dueAt, err := parseDateParam(*input.DueAt)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的截止时间"})
+			return
+		}
+		assignment.DueAt = dueAt
+	}
+	if input.MaxScore != nil {
+		assignment.MaxScore = *input.MaxScore
+	}
+	if input.LatePolicy != nil {
+		assignment.LatePolicy = *input.LatePolicy
+	}
+	if input.LatePenaltyPercent != nil {
+		assignment.LatePenaltyPercent = *input.LatePenaltyPercent
+	}
+	if input.AllowResubmit != nil {
+		assignment.AllowResubmit = *input.AllowResubmit
+	}
+	if input.Published != nil {
+		assignment.Published = *input.Published
+	}
+
+	var err error
+	if assignment.LateCutoffAt, err = parseOptionalTime(input.LateCutoffAt, assignment.LateCutoffAt); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的迟交截止时间"})
+		return
+	}
+	if message := validateAssignment(assignment); message != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": message})
+		return
+	}
+
+	assignmentRepo := repository.NewAssignmentRepository(database.DB)
+	if err := assignmentRepo.UpdateAssignment(c.Request.Context(), assignment); err != nil {
+		log.Printf("更新作业失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":    "作业更新成功",
+		"assignment": assignmentResponse(assignment),
+	})
+}
+
+// DeleteAssignment 删除作业（管理员或课程教师），已有的提交记录保留
+func DeleteAssignment(c *gin.Context) {
+	assignment, ok := getManagedAssignment(c)
+	if !ok {
+		return
+	}
+
+	assignmentRepo := repository.NewAssignmentRepository(database.DB)
+	if err := assignmentRepo.DeleteAssignment(c.Request.Context(), assignment.ID); err != nil {
+		log.Printf("删除作业失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "作业删除成功"})
+}
+
+// UploadAssignmentAttachment 上传作业附件（管理员或课程教师）
+func UploadAssignmentAttachment(c *gin.Context) {
+	assignment, ok := getManagedAssignment(c)
+	if !ok {
+		return
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxUploadFileSize+1<<20)
+	header, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请上传文件"})
+		return
+	}
+	if header.Size > maxUploadFileSize {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "文件不能超过20MB"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	key, size, err := saveUploadedFile(ctx, header, fmt.Sprintf("assignments/%d/attachments", assignment.ID))
+	if err != nil {
+		log.Printf("保存作业附件失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+
+	attachment := &models.AssignmentAttachment{
+		AssignmentID: assignment.ID,
+		FileName:     storage.SafeFileName(header.Filename),
+		StorageKey:   key,
+		ContentType:  uploadContentType(header),
+		Size:         size,
+		UploadedBy:   c.GetInt64("userID"),
+	}
+	assignmentRepo := repository.NewAssignmentRepository(database.DB)
+	if err := assignmentRepo.CreateAttachment(ctx, attachment); err != nil {
+		log.Printf("保存作业附件记录失败: %v", err)
+		deleteStoredFiles(ctx, key)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":    "附件上传成功",
+		"attachment": attachmentResponse(attachment),
+	})
+}
+
+// getAttachmentParam 获取路径参数指定的作业附件
+func getAttachmentParam(c *gin.Context, assignment *models.Assignment) (*models.AssignmentAttachment, bool) {
+	attachmentID, ok := parseIDParam(c, "attachment_id", "无效的附件ID")
+	if !ok {
+		return nil, false
+	}
+	for i := range assignment.Attachments {
+		if assignment.Attachments[i].ID == attachmentID {
+			return &assignment.Attachments[i], true
+		}
+	}
+	c.JSON(http.StatusNotFound, gin.H{"error": "附件不存在"})
+	return nil, false
+}
+
+// DeleteAssignmentAttachment 删除作业附件（管理员或课程教师）
+func DeleteAssignmentAttachment(c *gin.Context) {
+	assignment, ok := getManagedAssignment(c)
+	if !ok {
+		return
+	}
+	attachment, ok := getAttachmentParam(c, assignment)
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	assignmentRepo := repository.NewAssignmentRepository(database.DB)
+	if err := assignmentRepo.DeleteAttachment(ctx, attachment.ID); err != nil {
+		log.Printf("删除作业附件失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+	deleteStoredFiles(ctx, attachment.StorageKey)
+
+	c.JSON(http.StatusOK, gin.H{"message": "附件删除成功"})
+}
+
+// DownloadAssignmentAttachment 下载作业附件
+func DownloadAssignmentAttachment(c *gin.Context) {
+	assignment, ok := getVisibleAssignment(c)
+	if !ok {
+		return
+	}
+	attachment, ok := getAttachmentParam(c, assignment)
+	if !ok {
+		return
+	}
+	serveStoredFile(c, attachment.StorageKey, attachment.FileName, attachment.ContentType, attachment.Size)
+}
+
+// SubmitAssignment 学生提交作业，可以包含文字作答和多个文件
+// 允许重新提交的作业每次提交都会保留为新版本；截止后按迟交策略处理
+func SubmitAssignment(c *gin.Context) {
+	if c.GetString("role") != models.RoleStudent {
+		c.JSON(http.StatusForbidden, gin.H{"error": "只有学生可以提交作业"})
+		return
+	}
+
+	assignment, ok := getVisibleAssignment(c)
+	if !ok {
+		return
+	}
+
+	now := time.Now()
+	if !assignment.AcceptsSubmission(now) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "作业已截止，不再接收提交"})
+		return
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxSubmissionBodySize)
+	form, err := c.MultipartForm()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的输入数据"})
+		return
+	}
+
+	content := ""
+	if values := form.Value["content"]; len(values) > 0 {
+		content = values[0]
+	}
+	headers := form.File["files"]
+	if strings.TrimSpace(content) == "" && len(headers) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "提交内容不能为空"})
+		return
+	}
+	if len(headers) > maxSubmissionFiles {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("每次最多提交%d个文件", maxSubmissionFiles)})
+		return
+	}
+	for _, header := range headers {
+		if header.Size > maxUploadFileSize {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "文件不能超过20MB"})
+			return
+		}
+	}
+
+	ctx := c.Request.Context()
+	studentID := c.GetInt64("userID")
+	lateDays := assignment.LateDays(now)
+	submission := &models.Submission{
+		AssignmentID: assignment.ID,
+		StudentID:    studentID,
+		Content:      content,
+		IsLate:       lateDays > 0,
+		LateDays:     lateDays,
+		SubmittedAt:  now,
+	}
+
+	prefix := fmt.Sprintf("assignments/%d/submissions/%d", assignment.ID, studentID)
+	var keys []string
+	for _, header := range headers {
+		key, size, err := saveUploadedFile(ctx, header, prefix)
+		if err != nil {
+			log.Printf("保存提交文件失败: %v", err)
+			deleteStoredFiles(ctx, keys...)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+			return
+		}
+		keys = append(keys, key)
+		submission.Files = append(submission.Files, models.SubmissionFile{
+			FileName:    storage.SafeFileName(header.Filename),
+			StorageKey:  key,
+			ContentType: uploadContentType(header),
+			Size:        size,
+		})
+	}
+
+	assignmentRepo := repository.NewAssignmentRepository(database.DB)
+	err = assignmentRepo.CreateSubmission(ctx, submission, assignment.AllowResubmit)
+	if err != nil {
+		deleteStoredFiles(ctx, keys...)
+	}
+	if errors.Is(err, repository.ErrResubmitNotAllowed) {
+		c.JSON(http.StatusConflict, gin.H{"error": "该作业不允许重新提交"})
+		return
+	}
+	if err != nil {
+		log.Printf("提交作业失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+
+	message := "作业提交成功"
+	if submission.IsLate {
+		message = "作业已提交（迟交）"
+	}
+	c.JSON(http.StatusCreated, gin.H{
+		"message":    message,
+		"submission": submissionResponse(assignment, submission),
+	})
+}
+
+// respondSubmissionHistory 返回学生对某个作业的全部提交记录
+func respondSubmissionHistory(c *gin.Context, assignment *models.Assignment, studentID int64) {
+	assignmentRepo := repository.NewAssignmentRepository(database.DB)
+	submissions, err := assignmentRepo.GetSubmissionHistory(c.Request.Context(), assignment.ID, studentID)
+	if err != nil {
+		log.Printf("获取提交记录失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+
+	result := make([]gin.H, 0, len(submissions))
+	for _, submission := range submissions {
+		result = append(result, submissionResponse(assignment, submission))
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"assignment_id": assignment.ID,
+		"student_id":    studentID,
+		"submitted":     len(submissions) > 0,
+		"submissions":   result,
+	})
+}
+
+// GetMySubmissions 获取当前学生对某个作业的提交历史
+func GetMySubmissions(c *gin.Context) {
+	assignment, ok := getVisibleAssignment(c)
+	if !ok {
+		return
+	}
+	respondSubmissionHistory(c, assignment, c.GetInt64("userID"))
+}
+
+// GetStudentSubmissions 获取某名学生对某个作业的提交历史（课程教师、管理员、学生本人或其家长）
+func GetStudentSubmissions(c *gin.Context) {
+	assignment, ok := getAssignmentParam(c)
+	if !ok {
+		return
+	}
+	studentID, ok := parseIDParam(c, "student_id", "无效的学生ID")
+	if !ok {
+		return
+	}
+
+	if !canManageAssignment(c, assignment.Offering) {
+		allowed, err := canAccessUserData(c, studentID)
+		if err != nil {
+			log.Printf("检查用户关系失败: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+			return
+		}
+		if !allowed || !assignment.Published {
+			c.JSON(http.StatusForbidden, gin.H{"error": "无权查看该学生的提交"})
+			return
+		}
+	}
+
+	respondSubmissionHistory(c, assignment, studentID)
+}
+
+// GetAssignmentSubmissions 获取作业的提交情况（管理员或课程教师）
+// 返回每名学生的最新提交以及尚未提交的学生名单
+func GetAssignmentSubmissions(c *gin.Context) {
+	assignment, ok := getManagedAssignment(c)
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	assignmentRepo := repository.NewAssignmentRepository(database.DB)
+	submissions, err := assignmentRepo.GetLatestSubmissions(ctx, assignment.ID)
+	if err != nil {
+		log.Printf("获取作业提交失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+
+	offeringRepo := repository.NewCourseOfferingRepository(database.DB)
+	studentIDs, err := offeringRepo.GetOfferingStudentIDs(ctx, assignment.OfferingID)
+	if err != nil {
+		log.Printf("获取开课学生失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+
+	submitted := make(map[int64]bool, len(submissions))
+	lateCount := 0
+	result := make([]gin.H, 0, len(submissions))
+	for _, submission := range submissions {
+		submitted[submission.StudentID] = true
+		if submission.IsLate {
+			lateCount++
+		}
+		result = append(result, submissionResponse(assignment, submission))
+	}
+
+	var missingIDs []int64
+	for _, id := range studentIDs {
+		if !submitted[id] {
+			missingIDs = append(missingIDs, id)
+		}
+	}
+	missingStudents, err := repository.NewUserRepository(database.DB).GetUsersByIDs(ctx, missingIDs)
+	if err != nil {
+		log.Printf("获取学生信息失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+	notSubmitted := make([]gin.H, 0, len(missingStudents))
+	for _, student := range missingStudents {
+		notSubmitted = append(notSubmitted, userSummary(student))
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"assignment_id":   assignment.ID,
+		"total_students":  len(studentIDs),
+		"submitted_count": len(submissions),
+		"late_count":      lateCount,
+		"submissions":     result,
+		"not_submitted":   notSubmitted,
+	})
+}
+
+// DownloadSubmissionFile 下载提交的文件（课程教师、管理员、学生本人或其家长）
+func DownloadSubmissionFile(c *gin.Context) {
+	fileID, ok := parseIDParam(c, "id", "无效的文件ID")
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	assignmentRepo := repository.NewAssignmentRepository(database.DB)
+	file, err := assignmentRepo.GetSubmissionFileByID(ctx, fileID)
+	if err != nil {
+		log.Printf("获取提交文件失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+	if file == nil || file.Submission == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "文件不存在"})
+		return
+	}
+
+	assignment, err := assignmentRepo.GetAssignmentByID(ctx, file.Submission.AssignmentID)
+	if err != nil {
+		log.Printf("获取作业失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+	if assignment == nil || assignment.Offering == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "文件不存在"})
+		return
+	}
+
+	if !canManageAssignment(c, assignment.Offering) {
+		allowed, err := canAccessUserData(c, file.Submission.StudentID)
+		if err != nil {
+			log.Printf("检查用户关系失败: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+			return
+		}
+		if !allowed {
+			c.JSON(http.StatusForbidden, gin.H{"error": "无权下载该文件"})
+			return
+		}
+	}
+
+	serveStoredFile(c, file.StorageKey, file.FileName, file.ContentType, file.Size)
+}
@@ -0,0 +1,466 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"EduGo_servers/internal/database"
+	"EduGo_servers/internal/models"
+	"EduGo_servers/internal/notify"
+	"EduGo_servers/internal/repository"
+)
+
+// attendanceRecordResponse 构造考勤记录的返回数据
+func attendanceRecordResponse(record *models.AttendanceRecord) gin.H {
+	response := gin.H{
+		"id":         record.ID,
+		"session_id": record.SessionID,
+		"date":       record.Date.Format("2006-01-02"),
+		"student_id": record.StudentID,
+		"status":     record.Status,
+		"note":       record.Note,
+		"marked_by":  record.MarkedBy,
+		"updated_at": record.UpdatedAt,
+	}
+	if record.Session != nil {
+		if record.Session.Offering != nil && record.Session.Offering.Course != nil {
+			response["course_name"] = record.Session.Offering.Course.Name
+		}
+		if record.Session.Period != nil {
+			response["period"] = periodResponse(record.Session.Period)
+		}
+	}
+	return response
+}
+
+// attendanceSummary 汇总考勤数量，计算缺勤率（缺勤和请假）和无故缺勤率
+func attendanceSummary(counts map[string]int64) gin.H {
+	var total int64
+	for _, count := range counts {
+		total += count
+	}
+
+	absenceRate, unexcusedRate := 0.0, 0.0
+	if total > 0 {
+		absenceRate = float64(counts[models.AttendanceAbsent]+counts[models.AttendanceExcused]) / float64(total)
+		unexcusedRate = float64(counts[models.AttendanceAbsent]) / float64(total)
+	}
+
+	return gin.H{
+		"total":          total,
+		"present":        counts[models.AttendancePresent],
+		"late":           counts[models.AttendanceLate],
+		"absent":         counts[models.AttendanceAbsent],
+		"excused":        counts[models.AttendanceExcused],
+		"absence_rate":   absenceRate,
+		"unexcused_rate": unexcusedRate,
+	}
+}
+
+// getTeachingSession 获取路径参数指定的排课，要求当前用户是该课的教师或管理员
+func getTeachingSession(c *gin.Context) (*models.ScheduledSession, bool) {
+	id, ok := parseIDParam(c, "id", "无效的课表ID")
+	if !ok {
+		return nil, false
+	}
+
+	timetableRepo := repository.NewTimetableRepository(database.DB)
+	session, err := timetableRepo.GetSessionByID(c.Request.Context(), id)
+	if err != nil {
+		log.Printf("获取课表失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return nil, false
+	}
+	if session == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "课表不存在"})
+		return nil, false
+	}
+	if !isAdminRole(c.GetString("role")) && session.TeacherID != c.GetInt64("userID") {
+		c.JSON(http.StatusForbidden, gin.H{"error": "只有任课教师可以考勤"})
+		return nil, false
+	}
+	return session, true
+}
+
+// parseAttendanceDate 解析考勤日期，日期必须是该课的上课日、在学期内且不晚于今天。失败时已写入响应
+func parseAttendanceDate(c *gin.Context, session *models.ScheduledSession, value string) (time.Time, bool) {
+	date, err := time.ParseInLocation("2006-01-02", value, time.Local)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的日期"})
+		return time.Time{}, false
+	}
+
+	weekday := int(date.Weekday())
+	if weekday == 0 {
+		weekday = 7
+	}
+	if weekday != session.Weekday {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "该日期没有这节课"})
+		return time.Time{}, false
+	}
+	if session.Offering != nil && session.Offering.Term != nil && !session.Offering.Term.Contains(date) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "日期不在学期内"})
+		return time.Time{}, false
+	}
+	if date.After(time.Now()) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "不能为将来的日期考勤"})
+		return time.Time{}, false
+	}
+	return date, true
+}
+
+// GetSessionAttendance 获取某节课某天的考勤名单（任课教师或管理员）
+// 尚未考勤的学生status为空
+func GetSessionAttendance(c *gin.Context) {
+	session, ok := getTeachingSession(c)
+	if !ok {
+		return
+	}
+	date, ok := parseAttendanceDate(c, session, c.Query("date"))
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	students, err := repository.NewTimetableRepository(database.DB).GetSessionStudents(ctx, session)
+	if err != nil {
+		log.Printf("获取上课学生失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+	records, err := repository.NewAttendanceRepository(database.DB).GetSessionAttendance(ctx, session.ID, date)
+	if err != nil {
+		log.Printf("获取考勤记录失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+
+	recordMap := make(map[int64]*models.AttendanceRecord, len(records))
+	for _, record := range records {
+		recordMap[record.StudentID] = record
+	}
+
+	studentList := []gin.H{}
+	for _, student := range students {
+		item := userSummary(student)
+		item["status"] = ""
+		item["note"] = ""
+		if record, exists := recordMap[student.ID]; exists {
+			item["status"] = record.Status
+			item["note"] = record.Note
+		}
+		studentList = append(studentList, item)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"session":  sessionResponse(session),
+		"date":     date.Format("2006-01-02"),
+		"students": studentList,
+	})
+}
+
+// MarkSessionAttendance 批量考勤（任课教师或管理员）
+// default_status不为空时，records中未列出的学生都标记为该状态；新标记为无故缺勤的学生会通知其家长
+func MarkSessionAttendance(c *gin.Context) {
+	session, ok := getTeachingSession(c)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		Date          string `json:"date" binding:"required"`
+		DefaultStatus string `json:"default_status"`
+		Records       []struct {
+			StudentID int64  `json:"student_id" binding:"required"`
+			Status    string `json:"status" binding:"required"`
+			Note      string `json:"note" binding:"max=255"`
+		} `json:"records" binding:"dive"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的输入数据"})
+		return
+	}
+	if input.DefaultStatus != "" && !models.IsValidAttendanceStatus(input.DefaultStatus) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的考勤状态: " + input.DefaultStatus})
+		return
+	}
+
+	date, ok := parseAttendanceDate(c, session, input.Date)
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	students, err := repository.NewTimetableRepository(database.DB).GetSessionStudents(ctx, session)
+	if err != nil {
+		log.Printf("获取上课学生失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+	roster := make(map[int64]*models.User, len(students))
+	for _, student := range students {
+		roster[student.ID] = student
+	}
+
+	markedBy := c.GetInt64("userID")
+	records := []*models.AttendanceRecord{}
+	listed := map[int64]bool{}
+	for _, item := range input.Records {
+		if !models.IsValidAttendanceStatus(item.Status) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的考勤状态: " + item.Status})
+			return
+		}
+		if roster[item.StudentID] == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "学生不在这节课的名单中: " + strconv.FormatInt(item.StudentID, 10)})
+			return
+		}
+		if listed[item.StudentID] {
+			continue
+		}
+		listed[item.StudentID] = true
+		records = append(records, &models.AttendanceRecord{
+			SessionID: session.ID,
+			Date:      date,
+			StudentID: item.StudentID,
+			Status:    item.Status,
+			Note:      item.Note,
+			MarkedBy:  markedBy,
+		})
+	}
+	if input.DefaultStatus != "" {
+		for _, student := range students {
+			if listed[student.ID] {
+				continue
+			}
+			records = append(records, &models.AttendanceRecord{
+				SessionID: session.ID,
+				Date:      date,
+				StudentID: student.ID,
+				Status:    input.DefaultStatus,
+				MarkedBy:  markedBy,
+			})
+		}
+	}
+
+	attendanceRepo := repository.NewAttendanceRepository(database.DB)
+	previous, err := attendanceRepo.GetSessionAttendance(ctx, session.ID, date)
+	if err != nil {
+		log.Printf("获取考勤记录失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+	previousStatus := make(map[int64]string, len(previous))
+	for _, record := range previous {
+		previousStatus[record.StudentID] = record.Status
+	}
+
+	if err := attendanceRepo.SaveAttendance(ctx, records); err != nil {
+		log.Printf("保存考勤失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+
+	absent := []*models.User{}
+	for _, record := range records {
+		if record.Status == models.AttendanceAbsent && previousStatus[record.StudentID] != models.AttendanceAbsent {
+			absent = append(absent, roster[record.StudentID])
+		}
+	}
+	notified, err := notifyParentsOfAbsence(ctx, session, date, absent)
+	if err != nil {
+		log.Printf("发送缺勤通知失败: %v", err)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":  "考勤保存成功",
+		"saved":    len(records),
+		"notified": notified,
+	})
+}
+
+// notifyParentsOfAbsence 通知无故缺勤学生的家长，返回发送的通知数量
+func notifyParentsOfAbsence(ctx context.Context, session *models.ScheduledSession, date time.Time, students []*models.User) (int, error) {
+	courseName := ""
+	if session.Offering != nil && session.Offering.Course != nil {
+		courseName = session.Offering.Course.Name
+	}
+	periodName := ""
+	if session.Period != nil {
+		periodName = session.Period.Name
+	}
+
+	relationRepo := repository.NewUserRelationRepository(database.DB)
+	notifications := []*models.Notification{}
+	for _, student := range students {
+		relations, err := relationRepo.GetRelationsInvolvingUser(ctx, student.ID)
+		if err != nil {
+			return 0, err
+		}
+
+		name := student.LastName + student.FirstName
+		if name == "" {
+			name = student.Username
+		}
+		for _, relation := range relations.StudentParent {
+			if relation.UserID != student.ID || relation.Status != models.RelationStatusActive {
+				continue
+			}
+			notifications = append(notifications, &models.Notification{
+				UserID:  relation.RelatedUserID,
+				Type:    models.NotificationAttendanceAbsence,
+				Title:   "缺勤通知",
+				Content: fmt.Sprintf("%s 在 %s %s%s 无故缺勤", name, date.Format("2006-01-02"), periodName, courseName),
+				Link:    fmt.Sprintf("/attendance/records?student_id=%d", student.ID),
+			})
+		}
+	}
+
+	if err := notify.Send(ctx, database.DB, notifications...); err != nil {
+		return 0, err
+	}
+	return len(notifications), nil
+}
+
+// parseAttendanceQuery 解析学生考勤查询参数，未指定student_id时为当前用户。失败时已写入响应
+func parseAttendanceQuery(c *gin.Context) (*repository.AttendanceQuery, bool) {
+	query := &repository.AttendanceQuery{StudentID: c.GetInt64("userID")}
+	if studentID := c.Query("student_id"); studentID != "" {
+		id, err := strconv.ParseInt(studentID, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的学生ID"})
+			return nil, false
+		}
+		query.StudentID = id
+	}
+
+	allowed, err := canViewStudentData(c, query.StudentID)
+	if err != nil {
+		log.Printf("检查用户关系失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return nil, false
+	}
+	if !allowed {
+		c.JSON(http.StatusForbidden, gin.H{"error": "无权查看该学生的考勤"})
+		return nil, false
+	}
+
+	if termID := c.Query("term_id"); termID != "" {
+		id, err := strconv.ParseInt(termID, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的学期ID"})
+			return nil, false
+		}
+		query.TermID = id
+	}
+	for name, target := range map[string]**time.Time{"from": &query.From, "to": &query.To} {
+		if value := c.Query(name); value != "" {
+			t, err := parseDateParam(value)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "无效的日期: " + name})
+				return nil, false
+			}
+			*target = &t
+		}
+	}
+	return query, true
+}
+
+// GetStudentAttendance 获取学生的考勤记录（本人、家长、管理员或该学生的教师）
+func GetStudentAttendance(c *gin.Context) {
+	query, ok := parseAttendanceQuery(c)
+	if !ok {
+		return
+	}
+
+	records, err := repository.NewAttendanceRepository(database.DB).GetAttendanceRecords(c.Request.Context(), query)
+	if err != nil {
+		log.Printf("获取考勤记录失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+
+	recordList := []gin.H{}
+	for _, record := range records {
+		recordList = append(recordList, attendanceRecordResponse(record))
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"student_id": query.StudentID,
+		"records":    recordList,
+	})
+}
+
+// GetAttendanceSummary 获取学生的考勤统计（本人、家长、管理员或该学生的教师）
+func GetAttendanceSummary(c *gin.Context) {
+	query, ok := parseAttendanceQuery(c)
+	if !ok {
+		return
+	}
+
+	counts, err := repository.NewAttendanceRepository(database.DB).CountAttendance(c.Request.Context(), query)
+	if err != nil {
+		log.Printf("统计考勤失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+
+	byStatus := map[string]int64{}
+	for _, count := range counts {
+		byStatus[count.Status] += count.Count
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"student_id": query.StudentID,
+		"summary":    attendanceSummary(byStatus),
+	})
+}
+
+// GetOfferingAttendanceStats 获取开课中每名学生的考勤统计（管理员或课程教师）
+func GetOfferingAttendanceStats(c *gin.Context) {
+	offering, ok := getManagedOffering(c)
+	if !ok {
+		return
+	}
+
+	query := &repository.AttendanceQuery{OfferingID: offering.ID}
+	counts, err := repository.NewAttendanceRepository(database.DB).CountAttendance(c.Request.Context(), query)
+	if err != nil {
+		log.Printf("统计考勤失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+
+	byStudent := map[int64]map[string]int64{}
+	studentIDs := []int64{}
+	for _, count := range counts {
+		if byStudent[count.StudentID] == nil {
+			byStudent[count.StudentID] = map[string]int64{}
+			studentIDs = append(studentIDs, count.StudentID)
+		}
+		byStudent[count.StudentID][count.Status] += count.Count
+	}
+
+	overall := map[string]int64{}
+	students := []gin.H{}
+	for _, studentID := range studentIDs {
+		for status, count := range byStudent[studentID] {
+			overall[status] += count
+		}
+		summary := attendanceSummary(byStudent[studentID])
+		summary["student_id"] = studentID
+		students = append(students, summary)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"offering_id": offering.ID,
+		"summary":     attendanceSummary(overall),
+		"students":    students,
+	})
+}
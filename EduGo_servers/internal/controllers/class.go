@@ -0,0 +1,569 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"EduGo_servers/internal/database"
+	"EduGo_servers/internal/models"
+	"EduGo_servers/internal/repository"
+)
+
+// userSummary 构造班级中教师、学生的简要信息
+func userSummary(user *models.User) gin.H {
+	return gin.H{
+		"id":        user.ID,
+		"username":  user.Username,
+		"firstName": user.FirstName,
+		"lastName":  user.LastName,
+	}
+}
+
+// classResponse 构造班级的返回数据
+func classResponse(class *models.Class) gin.H {
+	var headTeacher gin.H
+	if class.HeadTeacher != nil {
+		headTeacher = userSummary(class.HeadTeacher)
+	}
+
+	subjectTeachers := []gin.H{}
+	for _, assignment := range class.SubjectTeachers {
+		item := gin.H{
+			"id":         assignment.ID,
+			"subject":    assignment.Subject,
+			"teacher_id": assignment.TeacherID,
+		}
+		if assignment.Teacher != nil {
+			item["teacher"] = userSummary(assignment.Teacher)
+		}
+		subjectTeachers = append(subjectTeachers, item)
+	}
+
+	return gin.H{
+		"id":               class.ID,
+		"name":             class.Name,
+		"grade_level":      class.GradeLevel,
+		"enrollment_year":  class.EnrollmentYear,
+		"head_teacher_id":  class.HeadTeacherID,
+		"head_teacher":     headTeacher,
+		"subject_teachers": subjectTeachers,
+		"created_at":       class.CreatedAt,
+	}
+}
+
+// getClassParam 获取路径参数指定的班级
+func getClassParam(c *gin.Context) (*models.Class, bool) {
+	classID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的班级ID"})
+		return nil, false
+	}
+
+	classRepo := repository.NewClassRepository(database.DB)
+	class, err := classRepo.GetClassByID(c.Request.Context(), classID)
+	if err != nil {
+		log.Printf("获取班级失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return nil, false
+	}
+	if class == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "班级不存在"})
+		return nil, false
+	}
+	return class, true
+}
+
+// getAccessibleClass 获取路径参数指定的班级，教师只能访问自己担任班主任或任课教师的班级
+func getAccessibleClass(c *gin.Context) (*models.Class, bool) {
+	class, ok := getClassParam(c)
+	if !ok {
+		return nil, false
+	}
+	if !isAdminRole(c.GetString("role")) && !class.IsTeacher(c.GetInt64("userID")) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "无权访问该班级"})
+		return nil, false
+	}
+	return class, true
+}
+
+// loadHeadTeacher 验证班主任，teacherID为nil或0时表示不设班主任
+func loadHeadTeacher(ctx context.Context, teacherID *int64) (*models.User, string, error) {
+	if teacherID == nil || *teacherID == 0 {
+		return nil, "", nil
+	}
+	teachers, message, err := loadCourseTeachers(ctx, []int64{*teacherID})
+	if err != nil || message != "" {
+		return nil, message, err
+	}
+	return &teachers[0], "", nil
+}
+
+// loadClassStudents 加载并验证学生列表，返回错误信息
+func loadClassStudents(ctx context.Context, studentIDs []int64) ([]models.User, string, error) {
+	userRepo := repository.NewUserRepository(database.DB)
+	students := make([]models.User, 0, len(studentIDs))
+	seen := map[int64]bool{}
+	for _, id := range studentIDs {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+
+		student, err := userRepo.GetUserByID(ctx, id)
+		if err != nil {
+			return nil, "", err
+		}
+		if student == nil || student.Role != models.RoleStudent {
+			return nil, "学生不存在: " + strconv.FormatInt(id, 10), nil
+		}
+		students = append(students, *student)
+	}
+	return students, "", nil
+}
+
+// CreateClass 创建班级（管理员及以上权限）
+func CreateClass(c *gin.Context) {
+	var input struct {
+		Name           string `json:"name" binding:"required"`
+		GradeLevel     string `json:"grade_level" binding:"required"`
+		EnrollmentYear int    `json:"enrollment_year"`
+		HeadTeacherID  *int64 `json:"head_teacher_id"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的输入数据"})
+		return
+	}
+
+	name := strings.TrimSpace(input.Name)
+	gradeLevel := strings.TrimSpace(input.GradeLevel)
+	if name == "" || gradeLevel == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "班级名称和年级不能为空"})
+		return
+	}
+
+	headTeacher, message, err := loadHeadTeacher(c.Request.Context(), input.HeadTeacherID)
+	if err != nil {
+		log.Printf("获取教师信息失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+	if message != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": message})
+		return
+	}
+
+	classRepo := repository.NewClassRepository(database.DB)
+	exists, err := classRepo.ClassNameExists(c.Request.Context(), gradeLevel, name, 0)
+	if err != nil {
+		log.Printf("获取班级失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+	if exists {
+		c.JSON(http.StatusConflict, gin.H{"error": "该年级已存在同名班级"})
+		return
+	}
+
+	class := &models.Class{
+		Name:           name,
+		GradeLevel:     gradeLevel,
+		EnrollmentYear: input.EnrollmentYear,
+		CreatedBy:      c.GetInt64("userID"),
+	}
+	if headTeacher != nil {
+		class.HeadTeacherID = &headTeacher.ID
+	}
+
+	if err := classRepo.CreateClass(c.Request.Context(), class); err != nil {
+		log.Printf("创建班级失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+	class.HeadTeacher = headTeacher
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "班级创建成功",
+		"class":   classResponse(class),
+	})
+}
+
+// respondClassList 按查询条件返回班级列表
+func respondClassList(c *gin.Context, query *repository.ClassListQuery) {
+	query.GradeLevel = c.Query("grade_level")
+	query.Search = c.Query("search")
+	query.Page, _ = strconv.Atoi(c.Query("page"))
+	query.PageSize, _ = strconv.Atoi(c.Query("page_size"))
+
+	classRepo := repository.NewClassRepository(database.DB)
+	classes, total, err := classRepo.ListClasses(c.Request.Context(), query)
+	if err != nil {
+		log.Printf("获取班级列表失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+
+	classList := []gin.H{}
+	for _, class := range classes {
+		classList = append(classList, classResponse(class))
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"classes": classList,
+		"pagination": gin.H{
+			"page":     query.Page,
+			"pageSize": query.PageSize,
+			"total":    total,
+		},
+	})
+}
+
+// GetClasses 获取班级列表（管理员及以上权限）
+func GetClasses(c *gin.Context) {
+	query := &repository.ClassListQuery{}
+	if teacherID := c.Query("teacher_id"); teacherID != "" {
+		id, err := strconv.ParseInt(teacherID, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的教师ID"})
+			return
+		}
+		query.TeacherID = id
+	}
+	respondClassList(c, query)
+}
+
+// GetMyClasses 获取当前教师担任班主任或任课教师的班级
+func GetMyClasses(c *gin.Context) {
+	respondClassList(c, &repository.ClassListQuery{TeacherID: c.GetInt64("userID")})
+}
+
+// GetClass 获取班级详情（管理员或该班级的教师）
+func GetClass(c *gin.Context) {
+	class, ok := getAccessibleClass(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"class": classResponse(class),
+	})
+}
+
+// GetClassStudents 获取班级学生名单（管理员或该班级的教师）
+func GetClassStudents(c *gin.Context) {
+	class, ok := getAccessibleClass(c)
+	if !ok {
+		return
+	}
+
+	classRepo := repository.NewClassRepository(database.DB)
+	students, err := classRepo.GetClassStudents(c.Request.Context(), class.ID)
+	if err != nil {
+		log.Printf("获取班级学生失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+
+	studentList := []gin.H{}
+	for _, student := range students {
+		item := userSummary(student)
+		item["status"] = student.Status
+		studentList = append(studentList, item)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"class_id": class.ID,
+		"students": studentList,
+	})
+}
+
+// UpdateClass 更新班级（管理员及以上权限）
+func UpdateClass(c *gin.Context) {
+	class, ok := getClassParam(c)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		Name           *string `json:"name"`
+		GradeLevel     *string `json:"grade_level"`
+		EnrollmentYear *int    `json:"enrollment_year"`
+		HeadTeacherID  *int64  `json:"head_teacher_id"` // 0表示取消班主任
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的输入数据"})
+		return
+	}
+
+	if input.Name != nil {
+		class.Name = strings.TrimSpace(*input.Name)
+	}
+	if input.GradeLevel != nil {
+		class.GradeLevel = strings.TrimSpace(*input.GradeLevel)
+	}
+	if class.Name == "" || class.GradeLevel == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "班级名称和年级不能为空"})
+		return
+	}
+	if input.EnrollmentYear != nil {
+		class.EnrollmentYear = *input.EnrollmentYear
+	}
+	if input.HeadTeacherID != nil {
+		headTeacher, message, err := loadHeadTeacher(c.Request.Context(), input.HeadTeacherID)
+		if err != nil {
+			log.Printf("获取教师信息失败: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+			return
+		}
+		if message != "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": message})
+			return
+		}
+		class.HeadTeacher = headTeacher
+		class.HeadTeacherID = nil
+		if headTeacher != nil {
+			class.HeadTeacherID = &headTeacher.ID
+		}
+	}
+
+	classRepo := repository.NewClassRepository(database.DB)
+	exists, err := classRepo.ClassNameExists(c.Request.Context(), class.GradeLevel, class.Name, class.ID)
+	if err != nil {
+		log.Printf("获取班级失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+	if exists {
+		c.JSON(http.StatusConflict, gin.H{"error": "该年级已存在同名班级"})
+		return
+	}
+
+	if err := classRepo.UpdateClass(c.Request.Context(), class); err != nil {
+		log.Printf("更新班级失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "班级更新成功",
+		"class":   classResponse(class),
+	})
+}
+
+// DeleteClass 删除班级（管理员及以上权限），学生名单、任课教师安排和班级课表一并删除
+func DeleteClass(c *gin.Context) {
+	class, ok := getClassParam(c)
+	if !ok {
+		return
+	}
+
+	classRepo := repository.NewClassRepository(database.DB)
+	if err := classRepo.DeleteClass(c.Request.Context(), class.ID); err != nil {
+		if errors.Is(err, repository.ErrClassInUse) {
+			c.JSON(http.StatusConflict, gin.H{"error": "班级课表已有考勤记录，不能删除班级"})
+			return
+		}
+		log.Printf("删除班级失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "班级删除成功",
+	})
+}
+
+// AddClassStudents 将学生编入班级（管理员及以上权限）
+// 每名学生只能属于一个班级，已在其他班级的学生需要先移出
+func AddClassStudents(c *gin.Context) {
+	class, ok := getClassParam(c)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		StudentIDs []int64 `json:"student_ids" binding:"required,min=1"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的输入数据"})
+		return
+	}
+
+	students, message, err := loadClassStudents(c.Request.Context(), input.StudentIDs)
+	if err != nil {
+		log.Printf("获取学生信息失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+	if message != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": message})
+		return
+	}
+
+	classRepo := repository.NewClassRepository(database.DB)
+	current, err := classRepo.GetStudentClasses(c.Request.Context(), input.StudentIDs)
+	if err != nil {
+		log.Printf("获取学生班级失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+
+	newStudents := make([]models.User, 0, len(students))
+	conflicts := []int64{}
+	for _, student := range students {
+		classID, inClass := current[student.ID]
+		switch {
+		case !inClass:
+			newStudents = append(newStudents, student)
+		case classID != class.ID:
+			conflicts = append(conflicts, student.ID)
+		}
+	}
+	if len(conflicts) > 0 {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":       "部分学生已在其他班级",
+			"student_ids": conflicts,
+		})
+		return
+	}
+
+	if len(newStudents) > 0 {
+		err := classRepo.AddClassStudents(c.Request.Context(), class, newStudents)
+		if errors.Is(err, repository.ErrStudentInClass) {
+			// 检查之后学生被同时编入了其他班级
+			c.JSON(http.StatusConflict, gin.H{"error": "部分学生已在其他班级"})
+			return
+		}
+		if err != nil {
+			log.Printf("添加班级学生失败: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "学生添加成功",
+		"added":   len(newStudents),
+	})
+}
+
+// RemoveClassStudent 将学生移出班级（管理员及以上权限）
+func RemoveClassStudent(c *gin.Context) {
+	class, ok := getClassParam(c)
+	if !ok {
+		return
+	}
+
+	studentID, err := strconv.ParseInt(c.Param("student_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的学生ID"})
+		return
+	}
+
+	classRepo := repository.NewClassRepository(database.DB)
+	removed, err := classRepo.RemoveClassStudent(c.Request.Context(), class.ID, studentID)
+	if err != nil {
+		log.Printf("移除班级学生失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+	if !removed {
+		c.JSON(http.StatusNotFound, gin.H{"error": "学生不在该班级"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "学生移除成功",
+	})
+}
+
+// SetClassSubjectTeacher 设置班级某学科的任课教师（管理员及以上权限）
+func SetClassSubjectTeacher(c *gin.Context) {
+	class, ok := getClassParam(c)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		Subject   string `json:"subject" binding:"required"`
+		TeacherID int64  `json:"teacher_id" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的输入数据"})
+		return
+	}
+
+	subject := strings.TrimSpace(input.Subject)
+	if subject == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "学科不能为空"})
+		return
+	}
+
+	teachers, message, err := loadCourseTeachers(c.Request.Context(), []int64{input.TeacherID})
+	if err != nil {
+		log.Printf("获取教师信息失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+	if message != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": message})
+		return
+	}
+
+	classRepo := repository.NewClassRepository(database.DB)
+	assignment, err := classRepo.SetSubjectTeacher(c.Request.Context(), class.ID, subject, input.TeacherID)
+	if err != nil {
+		log.Printf("设置任课教师失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "任课教师设置成功",
+		"subject_teacher": gin.H{
+			"id":         assignment.ID,
+			"subject":    assignment.Subject,
+			"teacher_id": assignment.TeacherID,
+			"teacher":    userSummary(&teachers[0]),
+		},
+	})
+}
+
+// RemoveClassSubjectTeacher 取消班级的任课教师安排（管理员及以上权限）
+func RemoveClassSubjectTeacher(c *gin.Context) {
+	class, ok := getClassParam(c)
+	if !ok {
+		return
+	}
+
+	assignmentID, err := strconv.ParseInt(c.Param("assignment_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的任课安排ID"})
+		return
+	}
+
+	classRepo := repository.NewClassRepository(database.DB)
+	removed, err := classRepo.RemoveSubjectTeacher(c.Request.Context(), class.ID, assignmentID)
+	if err != nil {
+		log.Printf("取消任课教师失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+	if !removed {
+		c.JSON(http.StatusNotFound, gin.H{"error": "任课安排不存在"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "任课教师已取消",
+	})
+}
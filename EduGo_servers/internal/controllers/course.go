@@ -0,0 +1,362 @@
+package controllers
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"EduGo_servers/internal/database"
+	"EduGo_servers/internal/models"
+	"EduGo_servers/internal/repository"
+)
+
+// courseResponse 构造课程的返回数据
+func courseResponse(course *models.Course) gin.H {
+	teachers := []gin.H{}
+	for _, teacher := range course.Teachers {
+		teachers = append(teachers, gin.H{
+			"id":        teacher.ID,
+			"username":  teacher.Username,
+			"firstName": teacher.FirstName,
+			"lastName":  teacher.LastName,
+		})
+	}
+	return gin.H{
+		"id":          course.ID,
+		"code":        course.Code,
+		"name":        course.Name,
+		"subject":     course.Subject,
+		"grade_level": course.GradeLevel,
+		"description": course.Description,
+		"teachers":    teachers,
+		"created_at":  course.CreatedAt,
+	}
+}
+
+// loadCourseTeachers 加载并验证教师列表，返回错误信息
+func loadCourseTeachers(ctx context.Context, teacherIDs []int64) ([]models.User, string, error) {
+	userRepo := repository.NewUserRepository(database.DB)
+	teachers := make([]models.User, 0, len(teacherIDs))
+	seen := map[int64]bool{}
+	for _, id := range teacherIDs {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+
+		teacher, err := userRepo.GetUserByID(ctx, id)
+		if err != nil {
+			return nil, "", err
+		}
+		if teacher == nil || teacher.Role != models.RoleTeacher {
+			return nil, "教师不存在: " + strconv.FormatInt(id, 10), nil
+		}
+		teachers = append(teachers, *teacher)
+	}
+	return teachers, "", nil
+}
+
+// resolveCourse 验证请求中引用的课程
+// courseID为0时表示不关联课程；不再接受只有课程名称的自由文本课程；
+// teacherID大于0时要求该教师负责此课程。验证失败时已写入响应
+func resolveCourse(c *gin.Context, courseID int64, courseName string, teacherID int64) (*models.Course, bool) {
+	if courseID == 0 {
+		if strings.TrimSpace(courseName) != "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "请通过course_id指定课程"})
+			return nil, false
+		}
+		return nil, true
+	}
+
+	courseRepo := repository.NewCourseRepository(database.DB)
+	course, err := courseRepo.GetCourseByID(c.Request.Context(), courseID)
+	if err != nil {
+		log.Printf("获取课程失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return nil, false
+	}
+	if course == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "课程不存在"})
+		return nil, false
+	}
+	if teacherID > 0 && !course.HasTeacher(teacherID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "教师不负责该课程"})
+		return nil, false
+	}
+	return course, true
+}
+
+// isAdminRole 检查角色是否为管理员或超级管理员
+func isAdminRole(role string) bool {
+	return role == models.RoleSuperAdmin || role == models.RoleAdmin
+}
+
+// getCourseParam 获取路径参数指定的课程
+func getCourseParam(c *gin.Context) (*models.Course, bool) {
+	courseID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的课程ID"})
+		return nil, false
+	}
+
+	courseRepo := repository.NewCourseRepository(database.DB)
+	course, err := courseRepo.GetCourseByID(c.Request.Context(), courseID)
+	if err != nil {
+		log.Printf("获取课程失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return nil, false
+	}
+	if course == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "课程不存在"})
+		return nil, false
+	}
+	return course, true
+}
+
+// CreateCourse 创建课程（教师及以上权限）
+// 教师创建的课程自动将自己设为负责教师，课程至少需要一名负责教师
+func CreateCourse(c *gin.Context) {
+	var input struct {
+		Code        string  `json:"code" binding:"required"`
+		Name        string  `json:"name" binding:"required"`
+		Subject     string  `json:"subject"`
+		GradeLevel  string  `json:"grade_level"`
+		Description string  `json:"description"`
+		TeacherIDs  []int64 `json:"teacher_ids"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的输入数据"})
+		return
+	}
+
+	code := strings.ToUpper(strings.TrimSpace(input.Code))
+	name := strings.TrimSpace(input.Name)
+	if code == "" || name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "课程代码和名称不能为空"})
+		return
+	}
+
+	userID := c.GetInt64("userID")
+	teacherIDs := input.TeacherIDs
+	if c.GetString("role") == models.RoleTeacher {
+		teacherIDs = append(teacherIDs, userID)
+	}
+
+	teachers, message, err := loadCourseTeachers(c.Request.Context(), teacherIDs)
+	if err != nil {
+		log.Printf("获取教师信息失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+	if message != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": message})
+		return
+	}
+	if len(teachers) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "课程至少需要一名负责教师"})
+		return
+	}
+
+	courseRepo := repository.NewCourseRepository(database.DB)
+	exists, err := courseRepo.CourseCodeExists(c.Request.Context(), code)
+	if err != nil {
+		log.Printf("获取课程失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+	if exists {
+		c.JSON(http.StatusConflict, gin.H{"error": "课程代码已存在"})
+		return
+	}
+
+	course := &models.Course{
+		Code:        code,
+		Name:        name,
+		Subject:     input.Subject,
+		GradeLevel:  input.GradeLevel,
+		Description: input.Description,
+		Teachers:    teachers,
+		CreatedBy:   userID,
+	}
+
+	if err := courseRepo.CreateCourse(c.Request.Context(), course); err != nil {
+		log.Printf("创建课程失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "课程创建成功",
+		"course":  courseResponse(course),
+	})
+}
+
+// GetCourses 获取课程列表
+func GetCourses(c *gin.Context) {
+	query := &repository.CourseListQuery{
+		Subject:    c.Query("subject"),
+		GradeLevel: c.Query("grade_level"),
+		Search:     c.Query("search"),
+	}
+	query.Page, _ = strconv.Atoi(c.Query("page"))
+	query.PageSize, _ = strconv.Atoi(c.Query("page_size"))
+	if teacherID := c.Query("teacher_id"); teacherID != "" {
+		id, err := strconv.ParseInt(teacherID, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的教师ID"})
+			return
+		}
+		query.TeacherID = id
+	}
+
+	courseRepo := repository.NewCourseRepository(database.DB)
+	courses, total, err := courseRepo.ListCourses(c.Request.Context(), query)
+	if err != nil {
+		log.Printf("获取课程列表失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+
+	courseList := []gin.H{}
+	for _, course := range courses {
+		courseList = append(courseList, courseResponse(course))
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"courses": courseList,
+		"pagination": gin.H{
+			"page":     query.Page,
+			"pageSize": query.PageSize,
+			"total":    total,
+		},
+	})
+}
+
+// GetCourse 获取课程详情
+func GetCourse(c *gin.Context) {
+	course, ok := getCourseParam(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"course": courseResponse(course),
+	})
+}
+
+// UpdateCourse 更新课程（管理员或该课程的负责教师）
+func UpdateCourse(c *gin.Context) {
+	course, ok := getCourseParam(c)
+	if !ok {
+		return
+	}
+
+	userID := c.GetInt64("userID")
+	role := c.GetString("role")
+	if !isAdminRole(role) && !course.HasTeacher(userID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "只有管理员或课程负责教师可以修改课程"})
+		return
+	}
+
+	var input struct {
+		Name        *string  `json:"name"`
+		Subject     *string  `json:"subject"`
+		GradeLevel  *string  `json:"grade_level"`
+		Description *string  `json:"description"`
+		TeacherIDs  *[]int64 `json:"teacher_ids"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的输入数据"})
+		return
+	}
+
+	if input.Name != nil {
+		name := strings.TrimSpace(*input.Name)
+		if name == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "课程名称不能为空"})
+			return
+		}
+		course.Name = name
+	}
+	if input.Subject != nil {
+		course.Subject = *input.Subject
+	}
+	if input.GradeLevel != nil {
+		course.GradeLevel = *input.GradeLevel
+	}
+	if input.Description != nil {
+		course.Description = *input.Description
+	}
+
+	// 先验证教师列表，再在同一事务中保存课程和教师，避免部分更新
+	var teachers []models.User
+	if input.TeacherIDs != nil {
+		var message string
+		var err error
+		teachers, message, err = loadCourseTeachers(c.Request.Context(), *input.TeacherIDs)
+		if err != nil {
+			log.Printf("获取教师信息失败: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+			return
+		}
+		if message != "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": message})
+			return
+		}
+		if len(teachers) == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "课程至少需要一名负责教师"})
+			return
+		}
+	}
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		ctx := c.Request.Context()
+		courseRepo := repository.NewCourseRepository(tx)
+		if err := courseRepo.UpdateCourse(ctx, course); err != nil {
+			return err
+		}
+		if input.TeacherIDs != nil {
+			return courseRepo.ReplaceCourseTeachers(ctx, course, teachers)
+		}
+		return nil
+	})
+	if err != nil {
+		log.Printf("更新课程失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+	if input.TeacherIDs != nil {
+		course.Teachers = teachers
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "课程更新成功",
+		"course":  courseResponse(course),
+	})
+}
+
+// DeleteCourse 删除课程（管理员及以上权限）
+func DeleteCourse(c *gin.Context) {
+	course, ok := getCourseParam(c)
+	if !ok {
+		return
+	}
+
+	courseRepo := repository.NewCourseRepository(database.DB)
+	if err := courseRepo.DeleteCourse(c.Request.Context(), course.ID); err != nil {
+		log.Printf("删除课程失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "课程删除成功",
+	})
+}
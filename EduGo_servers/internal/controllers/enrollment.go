@@ -0,0 +1,336 @@
+package controllers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"EduGo_servers/internal/database"
+	"EduGo_servers/internal/models"
+	"EduGo_servers/internal/repository"
+)
+
+// enrollmentResponse 构造选课记录的返回数据
+func enrollmentResponse(enrollment *models.Enrollment) gin.H {
+	response := gin.H{
+		"id":            enrollment.ID,
+		"offering_id":   enrollment.OfferingID,
+		"student_id":    enrollment.StudentID,
+		"status":        enrollment.Status,
+		"waitlisted_at": enrollment.WaitlistedAt,
+		"enrolled_at":   enrollment.EnrolledAt,
+		"dropped_at":    enrollment.DroppedAt,
+		"created_at":    enrollment.CreatedAt,
+	}
+	if enrollment.Offering != nil {
+		response["offering"] = offeringResponse(enrollment.Offering)
+	}
+	if enrollment.Student != nil {
+		response["student"] = userSummary(enrollment.Student)
+	}
+	return response
+}
+
+// parseOptionalTime 解析可选的时间字段，空字符串表示清除
+func parseOptionalTime(value *string, current *time.Time) (*time.Time, error) {
+	if value == nil {
+		return current, nil
+	}
+	if *value == "" {
+		return nil, nil
+	}
+	t, err := parseDateParam(*value)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// UpdateOfferingEnrollment 更新开课的选课设置（管理员或课程教师）
+// 扩容后自动为候补学生分配名额
+func UpdateOfferingEnrollment(c *gin.Context) {
+	offering, ok := getManagedOffering(c)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		Published       *bool   `json:"published"`
+		Capacity        *int    `json:"capacity"`
+		WaitlistEnabled *bool   `json:"waitlist_enabled"`
+		EnrollStartAt   *string `json:"enroll_start_at"`
+		EnrollEndAt     *string `json:"enroll_end_at"`
+		DropDeadline    *string `json:"drop_deadline"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的输入数据"})
+		return
+	}
+
+	if input.Published != nil {
+		offering.Published = *input.Published
+	}
+	if input.WaitlistEnabled != nil {
+		offering.WaitlistEnabled = *input.WaitlistEnabled
+	}
+	if input.Capacity != nil {
+		if *input.Capacity < 0 || (*input.Capacity > 0 && *input.Capacity < offering.EnrolledCount) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "名额不能少于已选人数"})
+			return
+		}
+		offering.Capacity = *input.Capacity
+	}
+
+	var err error
+	if offering.EnrollStartAt, err = parseOptionalTime(input.EnrollStartAt, offering.EnrollStartAt); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的选课开始时间"})
+		return
+	}
+	if offering.EnrollEndAt, err = parseOptionalTime(input.EnrollEndAt, offering.EnrollEndAt); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的选课截止时间"})
+		return
+	}
+	if offering.DropDeadline, err = parseOptionalTime(input.DropDeadline, offering.DropDeadline); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的退课截止时间"})
+		return
+	}
+	if offering.EnrollStartAt != nil && offering.EnrollEndAt != nil && !offering.EnrollEndAt.After(*offering.EnrollStartAt) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "选课截止时间必须晚于开始时间"})
+		return
+	}
+
+	offeringRepo := repository.NewCourseOfferingRepository(database.DB)
+	if err := offeringRepo.UpdateOffering(c.Request.Context(), offering); err != nil {
+		log.Printf("更新开课失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+
+	enrollmentRepo := repository.NewEnrollmentRepository(database.DB)
+	promoted, err := enrollmentRepo.PromoteWaitlist(c.Request.Context(), offering, time.Now())
+	if err != nil {
+		log.Printf("候补递补失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+	offering.EnrolledCount += len(promoted)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":  "选课设置更新成功",
+		"offering": offeringResponse(offering),
+		"promoted": len(promoted),
+	})
+}
+
+// GetOfferingEnrollments 获取开课的选课名单（管理员或课程教师）
+func GetOfferingEnrollments(c *gin.Context) {
+	offering, ok := getManagedOffering(c)
+	if !ok {
+		return
+	}
+
+	status := c.Query("status")
+	switch status {
+	case "", models.EnrollmentEnrolled, models.EnrollmentWaitlisted, models.EnrollmentDropped:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的选课状态"})
+		return
+	}
+
+	enrollmentRepo := repository.NewEnrollmentRepository(database.DB)
+	enrollments, err := enrollmentRepo.GetOfferingEnrollments(c.Request.Context(), offering.ID, status)
+	if err != nil {
+		log.Printf("获取选课名单失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+
+	enrollmentList := []gin.H{}
+	for _, enrollment := range enrollments {
+		enrollmentList = append(enrollmentList, enrollmentResponse(enrollment))
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"offering":    offeringResponse(offering),
+		"enrollments": enrollmentList,
+	})
+}
+
+// RemoveEnrollment 将学生移出开课（管理员或课程教师），不受退课截止时间限制
+func RemoveEnrollment(c *gin.Context) {
+	offering, ok := getManagedOffering(c)
+	if !ok {
+		return
+	}
+
+	enrollmentID, err := strconv.ParseInt(c.Param("enrollment_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的选课ID"})
+		return
+	}
+
+	enrollmentRepo := repository.NewEnrollmentRepository(database.DB)
+	enrollment, err := enrollmentRepo.GetEnrollmentByID(c.Request.Context(), enrollmentID)
+	if err != nil {
+		log.Printf("获取选课记录失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+	if enrollment == nil || enrollment.OfferingID != offering.ID {
+		c.JSON(http.StatusNotFound, gin.H{"error": "选课记录不存在"})
+		return
+	}
+
+	dropEnrollment(c, offering, enrollment)
+}
+
+// EnrollInOffering 学生选课
+// 有名额时直接选上；满员时如果开课允许候补则进入候补队列
+func EnrollInOffering(c *gin.Context) {
+	if c.GetString("role") != models.RoleStudent {
+		c.JSON(http.StatusForbidden, gin.H{"error": "只有学生可以选课"})
+		return
+	}
+
+	var input struct {
+		OfferingID int64 `json:"offering_id" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的输入数据"})
+		return
+	}
+
+	offeringRepo := repository.NewCourseOfferingRepository(database.DB)
+	offering, err := offeringRepo.GetOfferingByID(c.Request.Context(), input.OfferingID)
+	if err != nil {
+		log.Printf("获取开课失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+	if offering == nil || !offering.Published {
+		c.JSON(http.StatusNotFound, gin.H{"error": "开课不存在"})
+		return
+	}
+
+	now := time.Now()
+	if !offering.EnrollmentOpen(now) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "不在选课时间内"})
+		return
+	}
+
+	enrollmentRepo := repository.NewEnrollmentRepository(database.DB)
+	enrollment, err := enrollmentRepo.Enroll(c.Request.Context(), offering, c.GetInt64("userID"), now)
+	if errors.Is(err, repository.ErrAlreadyEnrolled) {
+		c.JSON(http.StatusConflict, gin.H{"error": "已选该课程或正在候补"})
+		return
+	}
+	if errors.Is(err, repository.ErrOfferingFull) {
+		c.JSON(http.StatusConflict, gin.H{"error": "课程名额已满"})
+		return
+	}
+	if err != nil {
+		log.Printf("选课失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+
+	response := enrollmentResponse(enrollment)
+	message := "选课成功"
+	if enrollment.Status == models.EnrollmentWaitlisted {
+		message = "课程已满，已进入候补"
+		position, err := enrollmentRepo.GetWaitlistPosition(c.Request.Context(), enrollment)
+		if err != nil {
+			log.Printf("获取候补位置失败: %v", err)
+		}
+		response["waitlist_position"] = position
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":    message,
+		"enrollment": response,
+	})
+}
+
+// GetMyEnrollments 获取当前学生的选课记录
+func GetMyEnrollments(c *gin.Context) {
+	enrollmentRepo := repository.NewEnrollmentRepository(database.DB)
+	enrollments, err := enrollmentRepo.GetStudentEnrollments(c.Request.Context(), c.GetInt64("userID"))
+	if err != nil {
+		log.Printf("获取选课记录失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+
+	enrollmentList := []gin.H{}
+	for _, enrollment := range enrollments {
+		response := enrollmentResponse(enrollment)
+		if enrollment.Status == models.EnrollmentWaitlisted {
+			position, err := enrollmentRepo.GetWaitlistPosition(c.Request.Context(), enrollment)
+			if err != nil {
+				log.Printf("获取候补位置失败: %v", err)
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+				return
+			}
+			response["waitlist_position"] = position
+		}
+		enrollmentList = append(enrollmentList, response)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"enrollments": enrollmentList,
+	})
+}
+
+// DropMyEnrollment 学生退课或退出候补，退课须在退课截止时间之前
+func DropMyEnrollment(c *gin.Context) {
+	enrollmentID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的选课ID"})
+		return
+	}
+
+	enrollmentRepo := repository.NewEnrollmentRepository(database.DB)
+	enrollment, err := enrollmentRepo.GetEnrollmentByID(c.Request.Context(), enrollmentID)
+	if err != nil {
+		log.Printf("获取选课记录失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+	if enrollment == nil || enrollment.StudentID != c.GetInt64("userID") {
+		c.JSON(http.StatusNotFound, gin.H{"error": "选课记录不存在"})
+		return
+	}
+
+	if enrollment.Status == models.EnrollmentEnrolled && !enrollment.Offering.CanDrop(time.Now()) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "已过退课截止时间"})
+		return
+	}
+
+	dropEnrollment(c, enrollment.Offering, enrollment)
+}
+
+// dropEnrollment 退课并返回递补结果
+func dropEnrollment(c *gin.Context, offering *models.CourseOffering, enrollment *models.Enrollment) {
+	enrollmentRepo := repository.NewEnrollmentRepository(database.DB)
+	promoted, err := enrollmentRepo.Drop(c.Request.Context(), offering, enrollment, time.Now())
+	if errors.Is(err, repository.ErrEnrollmentNotActive) {
+		c.JSON(http.StatusConflict, gin.H{"error": "选课记录已退课"})
+		return
+	}
+	if err != nil {
+		log.Printf("退课失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":  "退课成功",
+		"promoted": len(promoted),
+	})
+}
@@ -0,0 +1,1188 @@
+package controllers
+
+import (
+	"context"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"EduGo_servers/internal/database"
+	"EduGo_servers/internal/grading"
+	"EduGo_servers/internal/models"
+	"EduGo_servers/internal/repository"
+)
+
+// gradingScaleResponse 构造评分制的返回数据
+func gradingScaleResponse(scale *models.GradingScale) gin.H {
+	bands := make([]gin.H, 0, len(scale.Bands))
+	for _, band := range scale.Bands {
+		bands = append(bands, gin.H{
+			"label":       band.Label,
+			"min_percent": band.MinPercent,
+			"grade_point": band.GradePoint,
+		})
+	}
+	return gin.H{
+		"id":       scale.ID,
+		"name":     scale.Name,
+		"type":     scale.Type,
+		"built_in": scale.BuiltIn,
+		"bands":    bands,
+	}
+}
+
+// gradeCategoryResponse 构造成绩分类的返回数据
+func gradeCategoryResponse(category *models.GradeCategory) gin.H {
+	return gin.H{
+		"id":          category.ID,
+		"offering_id": category.OfferingID,
+		"name":        category.Name,
+		"weight":      category.Weight,
+		"drop_lowest": category.DropLowest,
+	}
+}
+
+// gradeItemResponse 构造成绩项的返回数据
+func gradeItemResponse(item *models.GradeItem) gin.H {
+	return gin.H{
+		"id":            item.ID,
+		"offering_id":   item.OfferingID,
+		"category_id":   item.CategoryID,
+		"title":         item.Title,
+		"max_score":     item.MaxScore,
+		"assignment_id": item.AssignmentID,
+		"assessment_id": item.AssessmentID,
+		"created_at":    item.CreatedAt,
+	}
+}
+
+// gradeEntryResponse 构造得分的返回数据
+func gradeEntryResponse(entry *models.GradeEntry, item *models.GradeItem) gin.H {
+	response := gin.H{
+		"item_id":      entry.ItemID,
+		"student_id":   entry.StudentID,
+		"score":        entry.Score,
+		"late_penalty": entry.LatePenalty,
+		"excused":      entry.Excused,
+		"comment":      entry.Comment,
+		"graded_by":    entry.GradedBy,
+		"updated_at":   entry.UpdatedAt,
+	}
+	if item != nil && entry.Score != nil {
+		response["effective_score"] = entry.EffectiveScore(item.MaxScore)
+	}
+	return response
+}
+
+// gradeResultResponse 构造学生总评的返回数据
+func gradeResultResponse(result grading.Result, scale *models.GradingScale) gin.H {
+	response := gin.H{
+		"student_id": result.StudentID,
+		"graded":     result.Graded,
+		"percent":    result.Percent,
+		"categories": result.Categories,
+	}
+	if result.Graded {
+		response["label"], response["grade_point"] = scale.Convert(result.Percent)
+	}
+	return response
+}
+
+// termGradeResponse 构造学期总评的返回数据
+func termGradeResponse(grade *models.TermGrade) gin.H {
+	response := gin.H{
+		"offering_id": grade.OfferingID,
+		"student_id":  grade.StudentID,
+		"percent":     grade.Percent,
+		"label":       grade.Label,
+		"grade_point": grade.GradePoint,
+		"computed_by": grade.ComputedBy,
+		"computed_at": grade.ComputedAt,
+	}
+	if grade.Student != nil {
+		response["student"] = userSummary(grade.Student)
+	}
+	if grade.Offering != nil {
+		response["offering"] = offeringResponse(grade.Offering)
+	}
+	return response
+}
+
+// offeringGradingScale 获取开课使用的评分制，未设置时使用百分制
+func offeringGradingScale(ctx context.Context, offering *models.CourseOffering) (*models.GradingScale, error) {
+	if offering.GradingScaleID != nil {
+		scale, err := repository.NewGradebookRepository(database.DB).GetGradingScaleByID(ctx, *offering.GradingScaleID)
+		if err != nil || scale != nil {
+			return scale, err
+		}
+	}
+	return &models.GradingScale{Name: "百分制", Type: models.GradingScalePercentage}, nil
+}
+
+// gradebookData 开课成绩册中的成绩分类、成绩项和得分
+type gradebookData struct {
+	categories []*models.GradeCategory
+	items      []*models.GradeItem
+	entries    []*models.GradeEntry
+	scale      *models.GradingScale
+}
+
+// loadGradebook 加载开课的成绩册，studentID不为0时只加载该学生的得分
+func loadGradebook(ctx context.Context, offering *models.CourseOffering, studentID int64) (*gradebookData, error) {
+	gradebookRepo := repository.NewGradebookRepository(database.DB)
+	categories, err := gradebookRepo.GetCategories(ctx, offering.ID)
+	if err != nil {
+		return nil, err
+	}
+	items, err := gradebookRepo.GetItems(ctx, offering.ID)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := gradebookRepo.GetEntries(ctx, offering.ID, studentID)
+	if err != nil {
+		return nil, err
+	}
+	scale, err := offeringGradingScale(ctx, offering)
+	if err != nil {
+		return nil, err
+	}
+	return &gradebookData{categories: categories, items: items, entries: entries, scale: scale}, nil
+}
+
+// GetGradingScales 获取评分制列表
+func GetGradingScales(c *gin.Context) {
+	gradebookRepo := repository.NewGradebookRepository(database.DB)
+	scales, err := gradebookRepo.GetGradingScales(c.Request.Context())
+	if err != nil {
+		log.Printf("获取评分制失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+
+	result := make([]gin.H, 0, len(scales))
+	for _, scale := range scales {
+		result = append(result, gradingScaleResponse(scale))
+	}
+	c.JSON(http.StatusOK, gin.H{"scales": result})
+}
+
+// CreateGradingScale 创建评分制（管理员及以上权限）
+func CreateGradingScale(c *gin.Context) {
+	var input struct {
+		Name  string `json:"name" binding:"required"`
+		Type  string `json:"type" binding:"required"`
+		Bands []struct {
+			Label      string  `json:"label"`
+			MinPercent float64 `json:"min_percent"`
+			GradePoint float64 `json:"grade_point"`
+		} `json:"bands"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的输入数据"})
+		return
+	}
+	if !models.IsValidGradingScaleType(input.Type) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的评分制类型"})
+		return
+	}
+	if input.Type != models.GradingScalePercentage && len(input.Bands) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "等级制和五级制需要设置等级"})
+		return
+	}
+
+	scale := &models.GradingScale{
+		Name:      strings.TrimSpace(input.Name),
+		Type:      input.Type,
+		CreatedBy: c.GetInt64("userID"),
+	}
+	seen := map[float64]bool{}
+	for _, band := range input.Bands {
+		label := strings.TrimSpace(band.Label)
+		if label == "" || band.MinPercent < 0 || band.MinPercent > 100 || seen[band.MinPercent] {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "等级名称不能为空，最低分必须在0到100之间且不能重复"})
+			return
+		}
+		seen[band.MinPercent] = true
+		scale.Bands = append(scale.Bands, models.GradingScaleBand{
+			Label:      label,
+			MinPercent: band.MinPercent,
+			GradePoint: band.GradePoint,
+		})
+	}
+
+	ctx := c.Request.Context()
+	gradebookRepo := repository.NewGradebookRepository(database.DB)
+	exists, err := gradebookRepo.GradingScaleNameExists(ctx, scale.Name)
+	if err != nil {
+		log.Printf("检查评分制名称失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+	if exists {
+		c.JSON(http.StatusConflict, gin.H{"error": "评分制名称已存在"})
+		return
+	}
+
+	if err := gradebookRepo.CreateGradingScale(ctx, scale); err != nil {
+		log.Printf("创建评分制失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+
+	scale, err = gradebookRepo.GetGradingScaleByID(ctx, scale.ID)
+	if err != nil || scale == nil {
+		log.Printf("获取评分制失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "评分制创建成功",
+		"scale":   gradingScaleResponse(scale),
+	})
+}
+
+// DeleteGradingScale 删除评分制（管理员及以上权限），内置和正在使用的评分制不能删除
+func DeleteGradingScale(c *gin.Context) {
+	scaleID, ok := parseIDParam(c, "id", "无效的评分制ID")
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	gradebookRepo := repository.NewGradebookRepository(database.DB)
+	scale, err := gradebookRepo.GetGradingScaleByID(ctx, scaleID)
+	if err != nil {
+		log.Printf("获取评分制失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+	if scale == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "评分制不存在"})
+		return
+	}
+	if scale.BuiltIn {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "内置评分制不能删除"})
+		return
+	}
+
+	inUse, err := gradebookRepo.IsGradingScaleInUse(ctx, scaleID)
+	if err != nil {
+		log.Printf("检查评分制使用情况失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+	if inUse {
+		c.JSON(http.StatusConflict, gin.H{"error": "评分制正在被开课使用，不能删除"})
+		return
+	}
+
+	if err := gradebookRepo.DeleteGradingScale(ctx, scaleID); err != nil {
+		log.Printf("删除评分制失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "评分制删除成功"})
+}
+
+// GetGradebook 获取开课的成绩册（管理员或课程教师）
+// 返回成绩分类、成绩项、学生名单中每名学生的得分和当前总评
+func GetGradebook(c *gin.Context) {
+	offering, ok := getManagedOffering(c)
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	data, err := loadGradebook(ctx, offering, 0)
+	if err != nil {
+		log.Printf("获取成绩册失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+
+	studentIDs, err := repository.NewCourseOfferingRepository(database.DB).GetOfferingStudentIDs(ctx, offering.ID)
+	if err != nil {
+		log.Printf("获取开课学生失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+	students, err := repository.NewUserRepository(database.DB).GetUsersByIDs(ctx, studentIDs)
+	if err != nil {
+		log.Printf("获取学生信息失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+
+	items := make(map[int64]*models.GradeItem, len(data.items))
+	itemList := make([]gin.H, 0, len(data.items))
+	for _, item := range data.items {
+		items[item.ID] = item
+		itemList = append(itemList, gradeItemResponse(item))
+	}
+
+	entriesByStudent := map[int64][]gin.H{}
+	for _, entry := range data.entries {
+		entriesByStudent[entry.StudentID] = append(entriesByStudent[entry.StudentID], gradeEntryResponse(entry, items[entry.ItemID]))
+	}
+
+	rows := make([]gin.H, 0, len(students))
+	for _, student := range students {
+		result := grading.Compute(student.ID, data.categories, data.items, data.entries)
+		entries := entriesByStudent[student.ID]
+		if entries == nil {
+			entries = []gin.H{}
+		}
+		rows = append(rows, gin.H{
+			"student": userSummary(student),
+			"entries": entries,
+			"grade":   gradeResultResponse(result, data.scale),
+		})
+	}
+
+	categories := make([]gin.H, 0, len(data.categories))
+	var totalWeight float64
+	for _, category := range data.categories {
+		totalWeight += category.Weight
+		categories = append(categories, gradeCategoryResponse(category))
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"offering_id":   offering.ID,
+		"grading_scale": gradingScaleResponse(data.scale),
+		"categories":    categories,
+		"total_weight":  totalWeight,
+		"items":         itemList,
+		"students":      rows,
+	})
+}
+
+// SetGradebookScale 设置开课总评使用的评分制（管理员或课程教师）
+func SetGradebookScale(c *gin.Context) {
+	offering, ok := getManagedOffering(c)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		GradingScaleID *int64 `json:"grading_scale_id"` // 为空表示使用百分制
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的输入数据"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	if input.GradingScaleID != nil {
+		scale, err := repository.NewGradebookRepository(database.DB).GetGradingScaleByID(ctx, *input.GradingScaleID)
+		if err != nil {
+			log.Printf("获取评分制失败: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+			return
+		}
+		if scale == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "评分制不存在"})
+			return
+		}
+	}
+
+	offering.GradingScaleID = input.GradingScaleID
+	if err := repository.NewCourseOfferingRepository(database.DB).UpdateOffering(ctx, offering); err != nil {
+		log.Printf("更新开课失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":  "评分制设置成功",
+		"offering": offeringResponse(offering),
+	})
+}
+
+// getGradeCategoryParam 获取路径参数指定的成绩分类，要求属于该开课
+func getGradeCategoryParam(c *gin.Context, offering *models.CourseOffering) (*models.GradeCategory, bool) {
+	categoryID, ok := parseIDParam(c, "category_id", "无效的成绩分类ID")
+	if !ok {
+		return nil, false
+	}
+
+	category, err := repository.NewGradebookRepository(database.DB).GetCategoryByID(c.Request.Context(), categoryID)
+	if err != nil {
+		log.Printf("获取成绩分类失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return nil, false
+	}
+	if category == nil || category.OfferingID != offering.ID {
+		c.JSON(http.StatusNotFound, gin.H{"error": "成绩分类不存在"})
+		return nil, false
+	}
+	return category, true
+}
+
+// saveGradeCategory 校验并保存成绩分类
+func saveGradeCategory(c *gin.Context, category *models.GradeCategory) bool {
+	category.Name = strings.TrimSpace(category.Name)
+	if category.Name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "分类名称不能为空"})
+		return false
+	}
+	if category.Weight < 0 || category.Weight > 100 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "权重必须在0到100之间"})
+		return false
+	}
+	if category.DropLowest < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "去掉的最低分项数不能为负数"})
+		return false
+	}
+
+	ctx := c.Request.Context()
+	gradebookRepo := repository.NewGradebookRepository(database.DB)
+	exists, err := gradebookRepo.CategoryNameExists(ctx, category.OfferingID, category.Name, category.ID)
+	if err != nil {
+		log.Printf("检查成绩分类名称失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return false
+	}
+	if exists {
+		c.JSON(http.StatusConflict, gin.H{"error": "分类名称已存在"})
+		return false
+	}
+
+	if category.ID == 0 {
+		err = gradebookRepo.CreateCategory(ctx, category)
+	} else {
+		err = gradebookRepo.UpdateCategory(ctx, category)
+	}
+	if err != nil {
+		log.Printf("保存成绩分类失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return false
+	}
+	return true
+}
+
+// CreateGradeCategory 创建成绩分类（管理员或课程教师）
+func CreateGradeCategory(c *gin.Context) {
+	offering, ok := getManagedOffering(c)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		Name       string  `json:"name" binding:"required"`
+		Weight     float64 `json:"weight"`
+		DropLowest int     `json:"drop_lowest"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的输入数据"})
+		return
+	}
+
+	category := &models.GradeCategory{OfferingID: offering.ID, Name: input.Name, Weight: input.Weight, DropLowest: input.DropLowest}
+	if !saveGradeCategory(c, category) {
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":  "成绩分类创建成功",
+		"category": gradeCategoryResponse(category),
+	})
+}
+
+// UpdateGradeCategory 更新成绩分类（管理员或课程教师）
+func UpdateGradeCategory(c *gin.Context) {
+	offering, ok := getManagedOffering(c)
+	if !ok {
+		return
+	}
+	category, ok := getGradeCategoryParam(c, offering)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		Name       *string  `json:"name"`
+		Weight     *float64 `json:"weight"`
+		DropLowest *int     `json:"drop_lowest"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的输入数据"})
+		return
+	}
+
+	if input.Name != nil {
+		category.Name = *input.Name
+	}
+	if input.Weight != nil {
+		category.Weight = *input.Weight
+	}
+	if input.DropLowest != nil {
+		category.DropLowest = *input.DropLowest
+	}
+	if !saveGradeCategory(c, category) {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":  "成绩分类更新成功",
+		"category": gradeCategoryResponse(category),
+	})
+}
+
+// DeleteGradeCategory 删除成绩分类（管理员或课程教师），分类下还有成绩项时不能删除
+func DeleteGradeCategory(c *gin.Context) {
+	offering, ok := getManagedOffering(c)
+	if !ok {
+		return
+	}
+	category, ok := getGradeCategoryParam(c, offering)
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	gradebookRepo := repository.NewGradebookRepository(database.DB)
+	count, err := gradebookRepo.CountCategoryItems(ctx, category.ID)
+	if err != nil {
+		log.Printf("统计成绩项失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+	if count > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "分类下还有成绩项，不能删除"})
+		return
+	}
+
+	if err := gradebookRepo.DeleteCategory(ctx, category.ID); err != nil {
+		log.Printf("删除成绩分类失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "成绩分类删除成功"})
+}
+
+// syncAssessmentScores 把学生在测评上得分率最高的已评分作答按成绩项满分折算后记入关联测评的成绩项，
+// studentID为0时处理所有学生。保留免考标记和评语，没有已评分作答的学生不处理
+func syncAssessmentScores(ctx context.Context, item *models.GradeItem, studentID int64) error {
+	assessmentRepo := repository.NewAssessmentRepository(database.DB)
+	var attempts []*models.AssessmentAttempt
+	var err error
+	if studentID != 0 {
+		attempts, err = assessmentRepo.GetStudentAttempts(ctx, *item.AssessmentID, studentID)
+	} else {
+		attempts, err = assessmentRepo.GetAttempts(ctx, *item.AssessmentID)
+	}
+	if err != nil {
+		return err
+	}
+	best := map[int64]float64{}
+	for _, attempt := range attempts {
+		if attempt.Status != models.AttemptGraded || attempt.MaxScore <= 0 {
+			continue
+		}
+		ratio := attempt.Score / attempt.MaxScore
+		if current, ok := best[attempt.StudentID]; !ok || ratio > current {
+			best[attempt.StudentID] = ratio
+		}
+	}
+	if len(best) == 0 {
+		return nil
+	}
+
+	gradebookRepo := repository.NewGradebookRepository(database.DB)
+	existing, err := gradebookRepo.GetEntries(ctx, item.OfferingID, studentID)
+	if err != nil {
+		return err
+	}
+	entries := make(map[int64]*models.GradeEntry, len(best))
+	for _, entry := range existing {
+		if entry.ItemID == item.ID {
+			entries[entry.StudentID] = entry
+		}
+	}
+	changed := make([]*models.GradeEntry, 0, len(best))
+	for id, ratio := range best {
+		entry := entries[id]
+		if entry == nil {
+			entry = &models.GradeEntry{ItemID: item.ID, StudentID: id}
+		}
+		score := math.Round(ratio*item.MaxScore*100) / 100
+		entry.Score = &score
+		entry.LatePenalty = 0
+		entry.GradedBy = 0
+		entry.ScoredAt = nil
+		changed = append(changed, entry)
+	}
+	return gradebookRepo.SaveEntries(ctx, changed)
+}
+
+// syncAttemptGrade 作答评分完成后更新关联该测评的成绩项，失败只记录日志
+func syncAttemptGrade(ctx context.Context, attempt *models.AssessmentAttempt) {
+	if attempt.Status != models.AttemptGraded {
+		return
+	}
+	item, err := repository.NewGradebookRepository(database.DB).GetItemByAssessment(ctx, attempt.AssessmentID)
+	if err == nil && item != nil {
+		err = syncAssessmentScores(ctx, item, attempt.StudentID)
+	}
+	if err != nil {
+		log.Printf("更新测评成绩项失败: %v", err)
+	}
+}
+
+// getGradeItemParam 获取路径参数指定的成绩项，要求属于该开课
+func getGradeItemParam(c *gin.Context, offering *models.CourseOffering) (*models.GradeItem, bool) {
+	itemID, ok := parseIDParam(c, "item_id", "无效的成绩项ID")
+	if !ok {
+		return nil, false
+	}
+
+	item, err := repository.NewGradebookRepository(database.DB).GetItemByID(c.Request.Context(), itemID)
+	if err != nil {
+		log.Printf("获取成绩项失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return nil, false
+	}
+	if item == nil || item.OfferingID != offering.ID {
+		c.JSON(http.StatusNotFound, gin.H{"error": "成绩项不存在"})
+		return nil, false
+	}
+	return item, true
+}
+
+// CreateGradeItem 创建成绩项（管理员或课程教师）
+// 关联作业时，标题和满分默认取自作业，评分时自动计算迟交扣分
+func CreateGradeItem(c *gin.Context) {
+	offering, ok := getManagedOffering(c)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		CategoryID   int64    `json:"category_id" binding:"required"`
+		Title        string   `json:"title"`
+		MaxScore     *float64 `json:"max_score"`
+		AssignmentID *int64   `json:"assignment_id"`
+		AssessmentID *int64   `json:"assessment_id"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的输入数据"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	gradebookRepo := repository.NewGradebookRepository(database.DB)
+	category, err := gradebookRepo.GetCategoryByID(ctx, input.CategoryID)
+	if err != nil {
+		log.Printf("获取成绩分类失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+	if category == nil || category.OfferingID != offering.ID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "成绩分类不存在"})
+		return
+	}
+
+	item := &models.GradeItem{
+		OfferingID: offering.ID,
+		CategoryID: category.ID,
+		Title:      strings.TrimSpace(input.Title),
+		CreatedBy:  c.GetInt64("userID"),
+	}
+	if input.AssignmentID != nil && input.AssessmentID != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "成绩项只能关联一个作业或测评"})
+		return
+	}
+	if input.AssignmentID != nil {
+		assignment, err := repository.NewAssignmentRepository(database.DB).GetAssignmentByID(ctx, *input.AssignmentID)
+		if err != nil {
+			log.Printf("获取作业失败: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+			return
+		}
+		if assignment == nil || assignment.OfferingID != offering.ID {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "作业不存在"})
+			return
+		}
+
+		items, err := gradebookRepo.GetItems(ctx, offering.ID)
+		if err != nil {
+			log.Printf("获取成绩项失败: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+			return
+		}
+		for _, existing := range items {
+			if existing.AssignmentID != nil && *existing.AssignmentID == assignment.ID {
+				c.JSON(http.StatusConflict, gin.H{"error": "该作业已有对应的成绩项"})
+				return
+			}
+		}
+
+		item.AssignmentID = &assignment.ID
+		item.MaxScore = assignment.MaxScore
+		if item.Title == "" {
+			item.Title = assignment.Title
+		}
+	}
+	if input.AssessmentID != nil {
+		paper, err := repository.NewAssessmentRepository(database.DB).GetAssessmentByID(ctx, *input.AssessmentID)
+		if err != nil {
+			log.Printf("获取试卷失败: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+			return
+		}
+		if paper == nil || paper.OfferingID != offering.ID {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "试卷不存在"})
+			return
+		}
+
+		existing, err := gradebookRepo.GetItemByAssessment(ctx, paper.ID)
+		if err != nil {
+			log.Printf("获取成绩项失败: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+			return
+		}
+		if existing != nil {
+			c.JSON(http.StatusConflict, gin.H{"error": "该测评已有对应的成绩项"})
+			return
+		}
+
+		item.AssessmentID = &paper.ID
+		// 自适应测评每名学生作答的题目不同，得分按得分率折算，默认满分100
+		item.MaxScore = 100
+		if !paper.IsAdaptive() {
+			item.MaxScore = paper.TotalPoints()
+		}
+		if item.Title == "" {
+			item.Title = paper.Title
+		}
+	}
+	if input.MaxScore != nil {
+		item.MaxScore = *input.MaxScore
+	}
+	if item.Title == "" || item.MaxScore <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "成绩项标题不能为空，满分必须大于0"})
+		return
+	}
+
+	if err := gradebookRepo.CreateItem(ctx, item); err != nil {
+		log.Printf("创建成绩项失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+	// 已评分的作答立即记入成绩项
+	if item.AssessmentID != nil {
+		if err := syncAssessmentScores(ctx, item, 0); err != nil {
+			log.Printf("更新测评成绩项失败: %v", err)
+		}
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "成绩项创建成功",
+		"item":    gradeItemResponse(item),
+	})
+}
+
+// UpdateGradeItem 更新成绩项（管理员或课程教师）
+func UpdateGradeItem(c *gin.Context) {
+	offering, ok := getManagedOffering(c)
+	if !ok {
+		return
+	}
+	item, ok := getGradeItemParam(c, offering)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		CategoryID *int64   `json:"category_id"`
+		Title      *string  `json:"title"`
+		MaxScore   *float64 `json:"max_score"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的输入数据"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	gradebookRepo := repository.NewGradebookRepository(database.DB)
+	if input.CategoryID != nil {
+		category, err := gradebookRepo.GetCategoryByID(ctx, *input.CategoryID)
+		if err != nil {
+			log.Printf("获取成绩分类失败: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+			return
+		}
+		if category == nil || category.OfferingID != offering.ID {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "成绩分类不存在"})
+			return
+		}
+		item.CategoryID = category.ID
+		item.Category = nil
+	}
+	if input.Title != nil {
+		item.Title = strings.TrimSpace(*input.Title)
+	}
+	if input.MaxScore != nil {
+		item.MaxScore = *input.MaxScore
+	}
+	if item.Title == "" || item.MaxScore <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "成绩项标题不能为空，满分必须大于0"})
+		return
+	}
+
+	if err := gradebookRepo.UpdateItem(ctx, item); err != nil {
+		log.Printf("更新成绩项失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+	// 满分变化后按新的满分重新折算测评得分
+	if item.AssessmentID != nil && input.MaxScore != nil {
+		if err := syncAssessmentScores(ctx, item, 0); err != nil {
+			log.Printf("更新测评成绩项失败: %v", err)
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "成绩项更新成功",
+		"item":    gradeItemResponse(item),
+	})
+}
+
+// DeleteGradeItem 删除成绩项及其得分（管理员或课程教师）
+func DeleteGradeItem(c *gin.Context) {
+	offering, ok := getManagedOffering(c)
+	if !ok {
+		return
+	}
+	item, ok := getGradeItemParam(c, offering)
+	if !ok {
+		return
+	}
+
+	if err := repository.NewGradebookRepository(database.DB).DeleteItem(c.Request.Context(), item.ID); err != nil {
+		log.Printf("删除成绩项失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "成绩项删除成功"})
+}
+
+// SaveGradeScores 批量录入成绩项的得分（管理员或课程教师）
+// 关联作业的成绩项按学生最新提交的迟交天数记录迟交扣分
+func SaveGradeScores(c *gin.Context) {
+	offering, ok := getManagedOffering(c)
+	if !ok {
+		return
+	}
+	item, ok := getGradeItemParam(c, offering)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		Scores []struct {
+			StudentID int64    `json:"student_id" binding:"required"`
+			Score     *float64 `json:"score"` // 为空表示清除得分
+			Excused   bool     `json:"excused"`
+			Comment   string   `json:"comment"`
+		} `json:"scores" binding:"required,dive"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的输入数据"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	studentIDs, err := repository.NewCourseOfferingRepository(database.DB).GetOfferingStudentIDs(ctx, offering.ID)
+	if err != nil {
+		log.Printf("获取开课学生失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+	roster := make(map[int64]bool, len(studentIDs))
+	for _, id := range studentIDs {
+		roster[id] = true
+	}
+
+	penalties := map[int64]float64{}
+	if item.AssignmentID != nil {
+		assignmentRepo := repository.NewAssignmentRepository(database.DB)
+		assignment, err := assignmentRepo.GetAssignmentByID(ctx, *item.AssignmentID)
+		if err != nil {
+			log.Printf("获取作业失败: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+			return
+		}
+		if assignment != nil {
+			submissions, err := assignmentRepo.GetLatestSubmissions(ctx, assignment.ID)
+			if err != nil {
+				log.Printf("获取作业提交失败: %v", err)
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+				return
+			}
+			for _, submission := range submissions {
+				penalties[submission.StudentID] = assignment.LatePenalty(submission.LateDays)
+			}
+		}
+	}
+
+	graderID := c.GetInt64("userID")
+	entries := make([]*models.GradeEntry, 0, len(input.Scores))
+	for _, score := range input.Scores {
+		if !roster[score.StudentID] {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "学生 " + strconv.FormatInt(score.StudentID, 10) + " 不在该开课的学生名单中"})
+			return
+		}
+		if score.Score != nil && (*score.Score < 0 || *score.Score > item.MaxScore) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "得分必须在0到满分之间"})
+			return
+		}
+		if len(score.Comment) > 500 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "评语不能超过500个字符"})
+			return
+		}
+		entries = append(entries, &models.GradeEntry{
+			ItemID:      item.ID,
+			StudentID:   score.StudentID,
+			Score:       score.Score,
+			LatePenalty: penalties[score.StudentID],
+			Excused:     score.Excused,
+			Comment:     score.Comment,
+			GradedBy:    graderID,
+		})
+	}
+
+	if err := repository.NewGradebookRepository(database.DB).SaveEntries(ctx, entries); err != nil {
+		log.Printf("保存成绩失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+
+	result := make([]gin.H, 0, len(entries))
+	for _, entry := range entries {
+		result = append(result, gradeEntryResponse(entry, item))
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message": "成绩保存成功",
+		"entries": result,
+	})
+}
+
+// ComputeTermGrades 计算并保存开课中每名学生的学期总评（管理员或课程教师）
+// 重新计算会覆盖之前保存的总评，没有任何已评分成绩项或已不在开课中的学生不生成总评，之前保存的也删除
+func ComputeTermGrades(c *gin.Context) {
+	offering, ok := getManagedOffering(c)
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	data, err := loadGradebook(ctx, offering, 0)
+	if err != nil {
+		log.Printf("获取成绩册失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+	studentIDs, err := repository.NewCourseOfferingRepository(database.DB).GetOfferingStudentIDs(ctx, offering.ID)
+	if err != nil {
+		log.Printf("获取开课学生失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+
+	now := time.Now()
+	computedBy := c.GetInt64("userID")
+	var grades []*models.TermGrade
+	for _, studentID := range studentIDs {
+		result := grading.Compute(studentID, data.categories, data.items, data.entries)
+		if !result.Graded {
+			continue
+		}
+		label, gradePoint := data.scale.Convert(result.Percent)
+		grades = append(grades, &models.TermGrade{
+			OfferingID: offering.ID,
+			StudentID:  studentID,
+			Percent:    result.Percent,
+			Label:      label,
+			GradePoint: gradePoint,
+			ComputedBy: computedBy,
+			ComputedAt: now,
+		})
+	}
+
+	gradebookRepo := repository.NewGradebookRepository(database.DB)
+	if err := gradebookRepo.SaveTermGrades(ctx, offering.ID, grades); err != nil {
+		log.Printf("保存学期总评失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":  "学期总评计算完成",
+		"computed": len(grades),
+		"skipped":  len(studentIDs) - len(grades),
+	})
+}
+
+// GetOfferingTermGrades 获取开课已保存的学期总评（管理员或课程教师）
+func GetOfferingTermGrades(c *gin.Context) {
+	offering, ok := getManagedOffering(c)
+	if !ok {
+		return
+	}
+
+	grades, err := repository.NewGradebookRepository(database.DB).GetOfferingTermGrades(c.Request.Context(), offering.ID)
+	if err != nil {
+		log.Printf("获取学期总评失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+
+	result := make([]gin.H, 0, len(grades))
+	for _, grade := range grades {
+		result = append(result, termGradeResponse(grade))
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"offering_id": offering.ID,
+		"term_grades": result,
+	})
+}
+
+// GetMyGradebook 学生查看自己在开课中的成绩（只读），家长可以通过student_id查看孩子的成绩
+func GetMyGradebook(c *gin.Context) {
+	course, ok := getCourseParam(c)
+	if !ok {
+		return
+	}
+	offering, ok := getOfferingParam(c, course)
+	if !ok {
+		return
+	}
+
+	studentID, allowed, err := offeringStudentParam(c, offering)
+	if err != nil {
+		log.Printf("检查成绩权限失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+	if !allowed {
+		c.JSON(http.StatusForbidden, gin.H{"error": "无权查看该开课的成绩"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	data, err := loadGradebook(ctx, offering, studentID)
+	if err != nil {
+		log.Printf("获取成绩册失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+
+	entries := make(map[int64]*models.GradeEntry, len(data.entries))
+	for _, entry := range data.entries {
+		entries[entry.ItemID] = entry
+	}
+	items := make([]gin.H, 0, len(data.items))
+	for _, item := range data.items {
+		response := gradeItemResponse(item)
+		if entry := entries[item.ID]; entry != nil {
+			response["entry"] = gradeEntryResponse(entry, item)
+		}
+		items = append(items, response)
+	}
+
+	categories := make([]gin.H, 0, len(data.categories))
+	for _, category := range data.categories {
+		categories = append(categories, gradeCategoryResponse(category))
+	}
+
+	response := gin.H{
+		"offering":      offeringResponse(offering),
+		"student_id":    studentID,
+		"grading_scale": gradingScaleResponse(data.scale),
+		"categories":    categories,
+		"items":         items,
+		"grade":         gradeResultResponse(grading.Compute(studentID, data.categories, data.items, data.entries), data.scale),
+	}
+
+	termGrades, err := repository.NewGradebookRepository(database.DB).GetStudentTermGrades(ctx, studentID, offering.TermID)
+	if err != nil {
+		log.Printf("获取学期总评失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+	for _, grade := range termGrades {
+		if grade.OfferingID == offering.ID {
+			grade.Offering = nil
+			response["term_grade"] = termGradeResponse(grade)
+		}
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// GetStudentTermGrades 获取学生的学期总评（本人、关联的家长或管理员）
+func GetStudentTermGrades(c *gin.Context) {
+	studentID := c.GetInt64("userID")
+	if value := c.Query("student_id"); value != "" {
+		id, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的学生ID"})
+			return
+		}
+		studentID = id
+	}
+
+	allowed, err := canAccessUserData(c, studentID)
+	if err != nil {
+		log.Printf("检查用户关系失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+	if !allowed {
+		c.JSON(http.StatusForbidden, gin.H{"error": "无权查看该学生的成绩"})
+		return
+	}
+
+	var termID int64
+	if value := c.Query("term_id"); value != "" {
+		id, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的学期ID"})
+			return
+		}
+		termID = id
+	}
+
+	grades, err := repository.NewGradebookRepository(database.DB).GetStudentTermGrades(c.Request.Context(), studentID, termID)
+	if err != nil {
+		log.Printf("获取学期总评失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+
+	result := make([]gin.H, 0, len(grades))
+	for _, grade := range grades {
+		result = append(result, termGradeResponse(grade))
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"student_id":  studentID,
+		"term_grades": result,
+	})
+}
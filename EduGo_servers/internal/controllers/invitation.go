@@ -0,0 +1,457 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+
+	"EduGo_servers/internal/database"
+	"EduGo_servers/internal/models"
+	"EduGo_servers/internal/repository"
+)
+
+// 邀请有效期
+const (
+	defaultInvitationHours = 72
+	maxInvitationHours     = 30 * 24
+)
+
+// invitationClaims 邀请令牌中的声明
+type invitationClaims struct {
+	InvitationID int64 `json:"invitation_id"`
+	jwt.RegisteredClaims
+}
+
+// invitationSecret 邀请令牌的签名密钥，与登录令牌区分，避免邀请令牌被当作登录令牌使用
+func invitationSecret() []byte {
+	return append(jwtSecret(), []byte(":invitation")...)
+}
+
+// signInvitationToken 生成带签名和过期时间的邀请令牌
+func signInvitationToken(invitation *models.Invitation) (string, error) {
+	claims := &invitationClaims{
+		InvitationID: invitation.ID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   "invitation",
+			ExpiresAt: jwt.NewNumericDate(invitation.ExpiresAt),
+			IssuedAt:  jwt.NewNumericDate(invitation.CreatedAt),
+		},
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString(invitationSecret())
+}
+
+// parseInvitationToken 验证邀请令牌并返回邀请ID
+func parseInvitationToken(tokenString string) (int64, error) {
+	claims := &invitationClaims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		return invitationSecret(), nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
+	if err != nil || !token.Valid || claims.Subject != "invitation" {
+		return 0, errors.New("invalid invitation token")
+	}
+	return claims.InvitationID, nil
+}
+
+// loadUsableInvitation 根据令牌加载可用的邀请，不可用时返回nil和错误信息
+func loadUsableInvitation(ctx context.Context, tokenString string) (*models.Invitation, string, error) {
+	invitationID, err := parseInvitationToken(tokenString)
+	if err != nil {
+		return nil, "无效或已过期的邀请", nil
+	}
+
+	invitationRepo := repository.NewInvitationRepository(database.DB)
+	invitation, err := invitationRepo.GetInvitationByID(ctx, invitationID)
+	if err != nil {
+		return nil, "", err
+	}
+	if invitation == nil || !invitation.IsUsable(time.Now()) {
+		return nil, "邀请已失效", nil
+	}
+	return invitation, "", nil
+}
+
+// isStaffRole 检查是否为教职工（教师及以上）
+func isStaffRole(role string) bool {
+	return isAdminRole(role) || role == models.RoleTeacher
+}
+
+// canInviteRole 检查邀请人是否可以邀请指定角色的用户
+// 学生邀请家长另有限制，见CreateInvitation
+func canInviteRole(inviterRole, role string) bool {
+	switch role {
+	case models.RoleAdmin:
+		return inviterRole == models.RoleSuperAdmin
+	case models.RoleTeacher:
+		return inviterRole == models.RoleSuperAdmin || inviterRole == models.RoleAdmin
+	case models.RoleStudent:
+		return inviterRole == models.RoleSuperAdmin || inviterRole == models.RoleAdmin || inviterRole == models.RoleTeacher
+	case models.RoleParent:
+		return isStaffRole(inviterRole) || inviterRole == models.RoleStudent
+	}
+	return false
+}
+
+// invitationRelationRoles 关系类型对应的被邀请人角色和关系发起者角色
+var invitationRelationRoles = map[string]struct {
+	invitee string
+	anchors []string
+}{
+	models.RelationAdminTeacher:   {models.RoleTeacher, []string{models.RoleAdmin, models.RoleSuperAdmin}},
+	models.RelationTeacherStudent: {models.RoleStudent, []string{models.RoleTeacher}},
+	models.RelationStudentParent:  {models.RoleParent, []string{models.RoleStudent}},
+}
+
+// createInvitationRelation 为通过邀请注册的用户建立邀请中指定的关系
+func createInvitationRelation(ctx context.Context, relationRepo repository.UserRelationRepository, invitation *models.Invitation, userID int64) error {
+	base := models.UserRelation{
+		UserID:        invitation.RelatedUserID,
+		RelatedUserID: userID,
+		RelationType:  invitation.RelationType,
+		Status:        models.RelationStatusActive,
+	}
+
+	switch invitation.RelationType {
+	case "":
+		return nil
+	case models.RelationAdminTeacher:
+		return relationRepo.CreateAdminTeacherRelation(ctx, &models.AdminTeacherRelation{
+			UserRelation: base,
+			Department:   invitation.Department,
+			Position:     invitation.Position,
+		})
+	case models.RelationTeacherStudent:
+		return relationRepo.CreateTeacherStudentRelation(ctx, &models.TeacherStudentRelation{
+			UserRelation: base,
+			CourseID:     invitation.CourseID,
+			CourseName:   invitation.CourseName,
+			TermID:       invitation.TermID,
+			Semester:     invitation.Semester,
+		})
+	case models.RelationStudentParent:
+		return relationRepo.CreateStudentParentRelation(ctx, &models.StudentParentRelation{
+			UserRelation: base,
+			Relationship: invitation.Relationship,
+		})
+	}
+	return repository.ErrInvalidRelationType
+}
+
+// invitationResponse 构造邀请的返回数据
+func invitationResponse(invitation *models.Invitation) gin.H {
+	return gin.H{
+		"id":              invitation.ID,
+		"role":            invitation.Role,
+		"email":           invitation.Email,
+		"relation_type":   invitation.RelationType,
+		"related_user_id": invitation.RelatedUserID,
+		"course_id":       invitation.CourseID,
+		"course_name":     invitation.CourseName,
+		"term_id":         invitation.TermID,
+		"semester":        invitation.Semester,
+		"relationship":    invitation.Relationship,
+		"department":      invitation.Department,
+		"position":        invitation.Position,
+		"max_uses":        invitation.MaxUses,
+		"used_count":      invitation.UsedCount,
+		"expires_at":      invitation.ExpiresAt,
+		"revoked_at":      invitation.RevokedAt,
+		"created_at":      invitation.CreatedAt,
+	}
+}
+
+// CreateInvitation 创建邀请
+// 超级管理员可以邀请管理员，管理员可以邀请教师，教师及以上可以邀请学生和家长
+// 学生只能为自己邀请家长，且邀请只能使用一次
+func CreateInvitation(c *gin.Context) {
+	var input struct {
+		Role           string `json:"role" binding:"required"`
+		Email          string `json:"email" binding:"omitempty,email"`
+		RelationType   string `json:"relation_type"`
+		RelatedUserID  int64  `json:"related_user_id"`
+		CourseID       int64  `json:"course_id"`
+		CourseName     string `json:"course_name"` // 已废弃，课程通过course_id指定
+		TermID         int64  `json:"term_id"`
+		Semester       string `json:"semester"` // 已废弃，学期通过term_id指定
+		Relationship   string `json:"relationship"`
+		Department     string `json:"department"`
+		Position       string `json:"position"`
+		MaxUses        *int   `json:"max_uses"`
+		ExpiresInHours int    `json:"expires_in_hours"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的输入数据"})
+		return
+	}
+
+	inviterID := c.GetInt64("userID")
+	inviterRole := c.GetString("role")
+
+	if !isValidRole(input.Role) || input.Role == models.RoleSuperAdmin {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的用户角色"})
+		return
+	}
+	if !canInviteRole(inviterRole, input.Role) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "无权邀请该角色的用户"})
+		return
+	}
+
+	maxUses := 1
+	if input.MaxUses != nil {
+		if *input.MaxUses < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的使用次数"})
+			return
+		}
+		maxUses = *input.MaxUses
+	}
+
+	// 学生的邀请只能一次性地为自己建立学生-家长关系，避免生成可反复注册的链接
+	if !isStaffRole(inviterRole) {
+		if maxUses != 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "学生创建的邀请只能使用一次"})
+			return
+		}
+		if input.RelationType != models.RelationStudentParent || (input.RelatedUserID != 0 && input.RelatedUserID != inviterID) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "学生只能为自己邀请家长"})
+			return
+		}
+	}
+
+	hours := input.ExpiresInHours
+	if hours == 0 {
+		hours = defaultInvitationHours
+	}
+	if hours < 0 || hours > maxInvitationHours {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("有效期必须在1到%d小时之间", maxInvitationHours)})
+		return
+	}
+
+	invitation := &models.Invitation{
+		CreatedBy: inviterID,
+		Role:      input.Role,
+		Email:     input.Email,
+		UsageLimit: models.UsageLimit{
+			MaxUses:   maxUses,
+			ExpiresAt: time.Now().Add(time.Duration(hours) * time.Hour),
+		},
+	}
+
+	if input.RelationType != "" {
+		roles, ok := invitationRelationRoles[input.RelationType]
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的关系类型"})
+			return
+		}
+		if roles.invitee != input.Role {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "关系类型与被邀请人角色不匹配"})
+			return
+		}
+
+		anchorID := input.RelatedUserID
+		if anchorID == 0 {
+			anchorID = inviterID
+		}
+
+		userRepo := repository.NewUserRepository(database.DB)
+		anchor, err := userRepo.GetUserByID(c.Request.Context(), anchorID)
+		if err != nil {
+			log.Printf("获取用户信息失败: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+			return
+		}
+		if anchor == nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "关联用户不存在"})
+			return
+		}
+
+		anchorRoleValid := false
+		for _, role := range roles.anchors {
+			if anchor.Role == role {
+				anchorRoleValid = true
+			}
+		}
+		if !anchorRoleValid {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "关联用户的角色与关系类型不匹配"})
+			return
+		}
+
+		// 为他人建立关系需要管理员权限；教师可以为自己教授的学生邀请家长
+		if anchorID != inviterID && inviterRole != models.RoleSuperAdmin && inviterRole != models.RoleAdmin {
+			allowed := false
+			if inviterRole == models.RoleTeacher && input.RelationType == models.RelationStudentParent {
+				relationRepo := repository.NewUserRelationRepository(database.DB)
+				allowed, err = relationRepo.HasRelation(c.Request.Context(), models.RelationTeacherStudent, inviterID, anchorID)
+				if err != nil {
+					log.Printf("查询教师-学生关系失败: %v", err)
+					c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+					return
+				}
+			}
+			if !allowed {
+				c.JSON(http.StatusForbidden, gin.H{"error": "无权为该用户建立关系"})
+				return
+			}
+		}
+
+		// 教师-学生关系中的课程必须由该教师负责
+		if input.RelationType == models.RelationTeacherStudent {
+			course, ok := resolveCourse(c, input.CourseID, input.CourseName, anchorID)
+			if !ok {
+				return
+			}
+			if course != nil {
+				invitation.CourseID = course.ID
+				invitation.CourseName = course.Name
+			}
+
+			term, ok := resolveTerm(c, input.TermID, input.Semester)
+			if !ok {
+				return
+			}
+			if term != nil {
+				invitation.TermID = term.ID
+				invitation.Semester = term.Name
+			}
+		}
+
+		invitation.RelationType = input.RelationType
+		invitation.RelatedUserID = anchorID
+		invitation.Relationship = input.Relationship
+		invitation.Department = input.Department
+		invitation.Position = input.Position
+	}
+
+	invitationRepo := repository.NewInvitationRepository(database.DB)
+	if err := invitationRepo.CreateInvitation(c.Request.Context(), invitation); err != nil {
+		log.Printf("创建邀请失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+
+	token, err := signInvitationToken(invitation)
+	if err != nil {
+		log.Printf("生成邀请令牌失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":    "邀请创建成功",
+		"token":      token,
+		"invitation": invitationResponse(invitation),
+	})
+}
+
+// GetMyInvitations 获取当前用户创建的邀请列表
+func GetMyInvitations(c *gin.Context) {
+	invitationRepo := repository.NewInvitationRepository(database.DB)
+	invitations, err := invitationRepo.GetInvitationsByCreator(c.Request.Context(), c.GetInt64("userID"))
+	if err != nil {
+		log.Printf("获取邀请列表失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+
+	invitationList := []gin.H{}
+	for _, invitation := range invitations {
+		invitationList = append(invitationList, invitationResponse(invitation))
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"invitations": invitationList,
+	})
+}
+
+// RevokeInvitation 撤销邀请（邀请人或管理员）
+func RevokeInvitation(c *gin.Context) {
+	invitationID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的邀请ID"})
+		return
+	}
+
+	invitationRepo := repository.NewInvitationRepository(database.DB)
+	invitation, err := invitationRepo.GetInvitationByID(c.Request.Context(), invitationID)
+	if err != nil {
+		log.Printf("获取邀请失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+
+	if invitation == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "邀请不存在"})
+		return
+	}
+
+	role := c.GetString("role")
+	if invitation.CreatedBy != c.GetInt64("userID") && role != models.RoleSuperAdmin && role != models.RoleAdmin {
+		c.JSON(http.StatusForbidden, gin.H{"error": "无权撤销该邀请"})
+		return
+	}
+
+	if err := invitationRepo.RevokeInvitation(c.Request.Context(), invitationID); err != nil {
+		log.Printf("撤销邀请失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "邀请已撤销",
+	})
+}
+
+// VerifyInvitation 验证邀请令牌，返回注册时将获得的角色和关系信息（公开接口）
+func VerifyInvitation(c *gin.Context) {
+	invitation, message, err := loadUsableInvitation(c.Request.Context(), c.Query("token"))
+	if err != nil {
+		log.Printf("获取邀请失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+	if invitation == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": message})
+		return
+	}
+
+	result := gin.H{
+		"role":          invitation.Role,
+		"email":         invitation.Email,
+		"relation_type": invitation.RelationType,
+		"course_name":   invitation.CourseName,
+		"semester":      invitation.Semester,
+		"relationship":  invitation.Relationship,
+		"expires_at":    invitation.ExpiresAt,
+	}
+
+	// 返回关系发起者的姓名，便于注册页面展示"加入李老师的数学课"等信息
+	if invitation.RelationType != "" {
+		userRepo := repository.NewUserRepository(database.DB)
+		related, err := userRepo.GetUserByID(c.Request.Context(), invitation.RelatedUserID)
+		if err != nil {
+			log.Printf("获取用户信息失败: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+			return
+		}
+		if related != nil {
+			result["related_user"] = gin.H{
+				"id":        related.ID,
+				"username":  related.Username,
+				"firstName": related.FirstName,
+				"lastName":  related.LastName,
+				"role":      related.Role,
+			}
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"invitation": result,
+	})
+}
@@ -0,0 +1,459 @@
+package controllers
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"EduGo_servers/internal/database"
+	"EduGo_servers/internal/models"
+	"EduGo_servers/internal/repository"
+)
+
+// 加入码设置
+const (
+	joinCodeLength          = 6
+	joinCodeAlphabet        = "ABCDEFGHJKMNPQRSTUVWXYZ23456789" // 去掉了容易混淆的 I、L、O、0、1
+	defaultJoinCodeHours    = 7 * 24
+	maxJoinCodeHours        = 180 * 24
+	joinCodeGenerateRetries = 5
+)
+
+// generateJoinCode 生成一个未被占用的加入码
+func generateJoinCode(ctx context.Context, joinCodeRepo repository.JoinCodeRepository) (string, error) {
+	alphabetSize := big.NewInt(int64(len(joinCodeAlphabet)))
+	for i := 0; i < joinCodeGenerateRetries; i++ {
+		var sb strings.Builder
+		for j := 0; j < joinCodeLength; j++ {
+			n, err := rand.Int(rand.Reader, alphabetSize)
+			if err != nil {
+				return "", err
+			}
+			sb.WriteByte(joinCodeAlphabet[n.Int64()])
+		}
+
+		code := sb.String()
+		exists, err := joinCodeRepo.CodeExists(ctx, code)
+		if err != nil {
+			return "", err
+		}
+		if !exists {
+			return code, nil
+		}
+	}
+	return "", errors.New("failed to generate unique join code")
+}
+
+// joinCodeResponse 构造加入码的返回数据
+func joinCodeResponse(joinCode *models.JoinCode) gin.H {
+	return gin.H{
+		"id":               joinCode.ID,
+		"code":             joinCode.Code,
+		"teacher_id":       joinCode.TeacherID,
+		"course_id":        joinCode.CourseID,
+		"course_name":      joinCode.CourseName,
+		"term_id":          joinCode.TermID,
+		"semester":         joinCode.Semester,
+		"require_approval": joinCode.RequireApproval,
+		"max_uses":         joinCode.MaxUses,
+		"used_count":       joinCode.UsedCount,
+		"expires_at":       joinCode.ExpiresAt,
+		"revoked_at":       joinCode.RevokedAt,
+		"created_at":       joinCode.CreatedAt,
+	}
+}
+
+// getOwnJoinCode 获取路径参数指定的加入码，并检查是否属于当前教师
+func getOwnJoinCode(c *gin.Context) (*models.JoinCode, bool) {
+	joinCodeID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的加入码ID"})
+		return nil, false
+	}
+
+	joinCodeRepo := repository.NewJoinCodeRepository(database.DB)
+	joinCode, err := joinCodeRepo.GetJoinCodeByID(c.Request.Context(), joinCodeID)
+	if err != nil {
+		log.Printf("获取加入码失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return nil, false
+	}
+
+	if joinCode == nil || joinCode.TeacherID != c.GetInt64("userID") {
+		c.JSON(http.StatusNotFound, gin.H{"error": "加入码不存在"})
+		return nil, false
+	}
+	return joinCode, true
+}
+
+// CreateJoinCode 创建班级加入码（教师及以上权限）
+func CreateJoinCode(c *gin.Context) {
+	var input struct {
+		CourseID        int64  `json:"course_id"`
+		CourseName      string `json:"course_name"` // 已废弃，课程通过course_id指定
+		TermID          int64  `json:"term_id"`
+		Semester        string `json:"semester"` // 已废弃，学期通过term_id指定
+		RequireApproval bool   `json:"require_approval"`
+		MaxUses         int    `json:"max_uses"`
+		ExpiresInHours  int    `json:"expires_in_hours"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的输入数据"})
+		return
+	}
+
+	if input.MaxUses < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的使用次数"})
+		return
+	}
+
+	hours := input.ExpiresInHours
+	if hours == 0 {
+		hours = defaultJoinCodeHours
+	}
+	if hours < 0 || hours > maxJoinCodeHours {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("有效期必须在1到%d小时之间", maxJoinCodeHours)})
+		return
+	}
+
+	teacherID := c.GetInt64("userID")
+	courseTeacherID := int64(0)
+	if c.GetString("role") == models.RoleTeacher {
+		courseTeacherID = teacherID
+	}
+	course, ok := resolveCourse(c, input.CourseID, input.CourseName, courseTeacherID)
+	if !ok {
+		return
+	}
+	term, ok := resolveTerm(c, input.TermID, input.Semester)
+	if !ok {
+		return
+	}
+	if course == nil || term == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "加入码必须指定课程和学期"})
+		return
+	}
+
+	joinCodeRepo := repository.NewJoinCodeRepository(database.DB)
+	code, err := generateJoinCode(c.Request.Context(), joinCodeRepo)
+	if err != nil {
+		log.Printf("生成加入码失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+
+	joinCode := &models.JoinCode{
+		TeacherID:       teacherID,
+		Code:            code,
+		CourseID:        course.ID,
+		CourseName:      course.Name,
+		TermID:          term.ID,
+		Semester:        term.Name,
+		RequireApproval: input.RequireApproval,
+		UsageLimit: models.UsageLimit{
+			MaxUses:   input.MaxUses,
+			ExpiresAt: time.Now().Add(time.Duration(hours) * time.Hour),
+		},
+	}
+
+	if err := joinCodeRepo.CreateJoinCode(c.Request.Context(), joinCode); err != nil {
+		log.Printf("创建加入码失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":   "加入码创建成功",
+		"join_code": joinCodeResponse(joinCode),
+	})
+}
+
+// GetJoinCodes 获取当前教师的加入码列表（教师及以上权限）
+func GetJoinCodes(c *gin.Context) {
+	joinCodeRepo := repository.NewJoinCodeRepository(database.DB)
+	joinCodes, err := joinCodeRepo.GetJoinCodesByTeacher(c.Request.Context(), c.GetInt64("userID"))
+	if err != nil {
+		log.Printf("获取加入码列表失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+
+	joinCodeList := []gin.H{}
+	for _, joinCode := range joinCodes {
+		joinCodeList = append(joinCodeList, joinCodeResponse(joinCode))
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"join_codes": joinCodeList,
+	})
+}
+
+// RegenerateJoinCode 重新生成加入码，旧的加入码立即失效，使用次数清零（教师及以上权限）
+func RegenerateJoinCode(c *gin.Context) {
+	joinCode, ok := getOwnJoinCode(c)
+	if !ok {
+		return
+	}
+
+	if !joinCode.IsBound() {
+		c.JSON(http.StatusConflict, gin.H{"error": "加入码未绑定课程和学期，请创建新的加入码"})
+		return
+	}
+
+	var input struct {
+		ExpiresInHours int `json:"expires_in_hours"`
+	}
+	// 请求体可选
+	_ = c.ShouldBindJSON(&input)
+
+	if input.ExpiresInHours < 0 || input.ExpiresInHours > maxJoinCodeHours {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("有效期必须在1到%d小时之间", maxJoinCodeHours)})
+		return
+	}
+
+	joinCodeRepo := repository.NewJoinCodeRepository(database.DB)
+	code, err := generateJoinCode(c.Request.Context(), joinCodeRepo)
+	if err != nil {
+		log.Printf("生成加入码失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+
+	joinCode.Code = code
+	joinCode.UsedCount = 0
+	joinCode.RevokedAt = nil
+	if input.ExpiresInHours > 0 {
+		joinCode.ExpiresAt = time.Now().Add(time.Duration(input.ExpiresInHours) * time.Hour)
+	} else if !joinCode.ExpiresAt.After(time.Now()) {
+		joinCode.ExpiresAt = time.Now().Add(defaultJoinCodeHours * time.Hour)
+	}
+
+	if err := joinCodeRepo.UpdateJoinCode(c.Request.Context(), joinCode); err != nil {
+		log.Printf("更新加入码失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":   "加入码已重新生成",
+		"join_code": joinCodeResponse(joinCode),
+	})
+}
+
+// RevokeJoinCode 撤销加入码（教师及以上权限）
+func RevokeJoinCode(c *gin.Context) {
+	joinCode, ok := getOwnJoinCode(c)
+	if !ok {
+		return
+	}
+
+	if joinCode.RevokedAt == nil {
+		now := time.Now()
+		joinCode.RevokedAt = &now
+		joinCodeRepo := repository.NewJoinCodeRepository(database.DB)
+		if err := joinCodeRepo.UpdateJoinCode(c.Request.Context(), joinCode); err != nil {
+			log.Printf("撤销加入码失败: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "加入码已撤销",
+	})
+}
+
+// RedeemJoinCode 学生使用加入码加入教师的课程
+func RedeemJoinCode(c *gin.Context) {
+	var input struct {
+		Code string `json:"code" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的输入数据"})
+		return
+	}
+
+	if c.GetString("role") != models.RoleStudent {
+		c.JSON(http.StatusForbidden, gin.H{"error": "只有学生可以使用加入码"})
+		return
+	}
+
+	studentID := c.GetInt64("userID")
+	code := strings.ToUpper(strings.TrimSpace(input.Code))
+
+	joinCodeRepo := repository.NewJoinCodeRepository(database.DB)
+	joinCode, err := joinCodeRepo.GetJoinCodeByCode(c.Request.Context(), code)
+	if err != nil {
+		log.Printf("获取加入码失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+
+	// 未绑定课程和学期的旧加入码不能再使用
+	if joinCode == nil || !joinCode.IsBound() || !joinCode.IsUsable(time.Now()) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "加入码无效或已过期"})
+		return
+	}
+
+	teacher, err := repository.NewUserRepository(database.DB).GetUserByID(c.Request.Context(), joinCode.TeacherID)
+	if err != nil {
+		log.Printf("获取教师信息失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+	if teacher == nil || teacher.Status != models.UserStatusActive {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "加入码无效或已过期"})
+		return
+	}
+
+	status := models.RelationStatusActive
+	if joinCode.RequireApproval {
+		status = models.RelationStatusPending
+	}
+
+	relation := &models.TeacherStudentRelation{
+		UserRelation: models.UserRelation{
+			UserID:        joinCode.TeacherID,
+			RelatedUserID: studentID,
+			RelationType:  models.RelationTeacherStudent,
+			Status:        status,
+		},
+		CourseID:   joinCode.CourseID,
+		CourseName: joinCode.CourseName,
+		TermID:     joinCode.TermID,
+		Semester:   joinCode.Semester,
+	}
+
+	// 使用加入码和建立关系在同一事务中完成，已加入时由唯一索引拒绝，使用次数随事务回滚
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		ctx := c.Request.Context()
+		if err := repository.NewJoinCodeRepository(tx).ConsumeJoinCode(ctx, joinCode.ID); err != nil {
+			return err
+		}
+		return repository.NewUserRelationRepository(tx).CreateTeacherStudentRelation(ctx, relation)
+	})
+	if errors.Is(err, repository.ErrRelationExists) {
+		c.JSON(http.StatusConflict, gin.H{"error": "已加入该课程"})
+		return
+	}
+	if errors.Is(err, repository.ErrJoinCodeUnavailable) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "加入码无效或已过期"})
+		return
+	}
+	if err != nil {
+		log.Printf("使用加入码失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+
+	message := "已加入课程"
+	if status == models.RelationStatusPending {
+		message = "已提交加入申请，等待教师审批"
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": message,
+		"relation": gin.H{
+			"id":          relation.ID,
+			"teacher_id":  relation.UserID,
+			"student_id":  relation.RelatedUserID,
+			"course_id":   relation.CourseID,
+			"course_name": relation.CourseName,
+			"term_id":     relation.TermID,
+			"semester":    relation.Semester,
+			"status":      relation.Status,
+		},
+	})
+}
+
+// GetPendingStudentRelations 获取待当前教师审批的加入申请（教师及以上权限）
+func GetPendingStudentRelations(c *gin.Context) {
+	relationRepo := repository.NewUserRelationRepository(database.DB)
+	relations, err := relationRepo.GetTeacherStudentRelationsByStatus(c.Request.Context(), c.GetInt64("userID"), models.RelationStatusPending)
+	if err != nil {
+		log.Printf("获取待审批关系失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+
+	relationList := []gin.H{}
+	for _, relation := range relations {
+		relationList = append(relationList, gin.H{
+			"id":          relation.ID,
+			"student_id":  relation.RelatedUserID,
+			"course_id":   relation.CourseID,
+			"course_name": relation.CourseName,
+			"term_id":     relation.TermID,
+			"semester":    relation.Semester,
+			"created_at":  relation.CreatedAt,
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"relations": relationList,
+	})
+}
+
+// ApproveStudentRelation 通过学生的加入申请（教师及以上权限）
+func ApproveStudentRelation(c *gin.Context) {
+	reviewStudentRelation(c, true)
+}
+
+// RejectStudentRelation 拒绝学生的加入申请（教师及以上权限）
+func RejectStudentRelation(c *gin.Context) {
+	reviewStudentRelation(c, false)
+}
+
+// reviewStudentRelation 审批当前教师的待审批教师-学生关系
+func reviewStudentRelation(c *gin.Context, approve bool) {
+	relationID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的关系ID"})
+		return
+	}
+
+	relationRepo := repository.NewUserRelationRepository(database.DB)
+	relation, err := relationRepo.GetTeacherStudentRelationByID(c.Request.Context(), relationID)
+	if err != nil {
+		log.Printf("获取教师-学生关系失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+
+	if relation == nil || relation.UserID != c.GetInt64("userID") || relation.Status != models.RelationStatusPending {
+		c.JSON(http.StatusNotFound, gin.H{"error": "待审批的申请不存在"})
+		return
+	}
+
+	if approve {
+		relation.Status = models.RelationStatusActive
+		err = relationRepo.UpdateTeacherStudentRelation(c.Request.Context(), relation)
+	} else {
+		err = relationRepo.DeleteTeacherStudentRelation(c.Request.Context(), relation.ID)
+	}
+	if err != nil {
+		log.Printf("审批教师-学生关系失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+
+	message := "已拒绝加入申请"
+	if approve {
+		message = "已通过加入申请"
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message": message,
+	})
+}
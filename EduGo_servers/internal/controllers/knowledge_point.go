@@ -0,0 +1,507 @@
+package controllers
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"EduGo_servers/internal/database"
+	"EduGo_servers/internal/mastery"
+	"EduGo_servers/internal/models"
+	"EduGo_servers/internal/repository"
+)
+
+// knowledgePointSummary 知识点的简要信息，用于题目、作业等引用知识点的地方
+func knowledgePointSummary(point *models.KnowledgePoint) gin.H {
+	return gin.H{
+		"id":      point.ID,
+		"subject": point.Subject,
+		"name":    point.Name,
+	}
+}
+
+// knowledgePointResponse 构造知识点的返回数据
+func knowledgePointResponse(point *models.KnowledgePoint) gin.H {
+	return gin.H{
+		"id":          point.ID,
+		"subject":     point.Subject,
+		"parent_id":   point.ParentID,
+		"name":        point.Name,
+		"description": point.Description,
+		"position":    point.Position,
+		"created_by":  point.CreatedBy,
+		"created_at":  point.CreatedAt,
+		"updated_at":  point.UpdatedAt,
+	}
+}
+
+// knowledgeTree 构造嵌套的知识点树，node生成每个节点的数据，下级知识点放在children中
+func knowledgeTree(points []*models.KnowledgePoint, node func(point *models.KnowledgePoint) gin.H) []gin.H {
+	children := mastery.Children(points)
+	var build func(parentID int64, depth int) []gin.H
+	build = func(parentID int64, depth int) []gin.H {
+		nodes := []gin.H{}
+		// 防止数据异常时出现环
+		if depth > len(points) {
+			return nodes
+		}
+		for _, point := range children[parentID] {
+			item := node(point)
+			item["children"] = build(point.ID, depth+1)
+			nodes = append(nodes, item)
+		}
+		return nodes
+	}
+	return build(0, 0)
+}
+
+// knowledgeLinker 把题目的知识点关联到知识点树，同一学科的知识点只加载一次
+type knowledgeLinker struct {
+	ctx    context.Context
+	repo   repository.KnowledgePointRepository
+	points map[string][]*models.KnowledgePoint
+}
+
+func newKnowledgeLinker(ctx context.Context) *knowledgeLinker {
+	return &knowledgeLinker{
+		ctx:    ctx,
+		repo:   repository.NewKnowledgePointRepository(database.DB),
+		points: map[string][]*models.KnowledgePoint{},
+	}
+}
+
+// subjectPoints 学科的全部知识点
+func (l *knowledgeLinker) subjectPoints(subject string) ([]*models.KnowledgePoint, error) {
+	if subject == "" {
+		return nil, nil
+	}
+	if points, ok := l.points[subject]; ok {
+		return points, nil
+	}
+	points, err := l.repo.GetSubjectKnowledgePoints(l.ctx, subject)
+	if err != nil {
+		return nil, err
+	}
+	l.points[subject] = points
+	return points, nil
+}
+
+// link 把题目的知识点关联到知识点树，返回错误信息
+// ids指定的知识点必须属于题目的学科；按名称填写的知识点在该学科中只有一个同名知识点时自动关联
+func (l *knowledgeLinker) link(question *models.Question, ids []int64) (string, error) {
+	if len(ids) == 0 && len(question.Tags) == 0 {
+		return "", nil
+	}
+	if len(ids) > 0 && question.Subject == "" {
+		return "关联知识点的题目需要设置学科", nil
+	}
+	points, err := l.subjectPoints(question.Subject)
+	if err != nil {
+		return "", err
+	}
+	byID := make(map[int64]*models.KnowledgePoint, len(points))
+	byName := make(map[string][]*models.KnowledgePoint, len(points))
+	for _, point := range points {
+		byID[point.ID] = point
+		byName[point.Name] = append(byName[point.Name], point)
+	}
+
+	for _, id := range ids {
+		point, ok := byID[id]
+		if !ok {
+			return "知识点不存在或不属于题目的学科", nil
+		}
+		pointID := point.ID
+		found := false
+		for i := range question.Tags {
+			tag := &question.Tags[i]
+			if tag.KnowledgePoint != point.Name {
+				continue
+			}
+			if tag.KnowledgePointID != nil && *tag.KnowledgePointID != pointID {
+				return "不能同时关联同名的知识点", nil
+			}
+			tag.KnowledgePointID = &pointID
+			found = true
+		}
+		if !found {
+			question.Tags = append(question.Tags, models.QuestionTag{KnowledgePoint: point.Name, KnowledgePointID: &pointID})
+		}
+	}
+	for i := range question.Tags {
+		tag := &question.Tags[i]
+		if matches := byName[tag.KnowledgePoint]; tag.KnowledgePointID == nil && len(matches) == 1 {
+			pointID := matches[0].ID
+			tag.KnowledgePointID = &pointID
+		}
+	}
+	return "", nil
+}
+
+// knowledgePointInput 知识点的输入数据
+type knowledgePointInput struct {
+	Subject     string  `json:"subject"`
+	ParentID    *int64  `json:"parent_id"` // 修改时为0表示移动到顶层
+	Name        string  `json:"name"`
+	Description *string `json:"description"`
+	Position    *int    `json:"position"`
+}
+
+// getKnowledgePointParam 获取路径参数指定的知识点
+func getKnowledgePointParam(c *gin.Context, name string) (*models.KnowledgePoint, bool) {
+	pointID, ok := parseIDParam(c, name, "无效的知识点ID")
+	if !ok {
+		return nil, false
+	}
+
+	point, err := repository.NewKnowledgePointRepository(database.DB).GetKnowledgePointByID(c.Request.Context(), pointID)
+	if err != nil {
+		log.Printf("获取知识点失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return nil, false
+	}
+	if point == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "知识点不存在"})
+		return nil, false
+	}
+	return point, true
+}
+
+// getManagedKnowledgePoint 获取路径参数指定的知识点，只有创建人和管理员可以修改
+func getManagedKnowledgePoint(c *gin.Context) (*models.KnowledgePoint, bool) {
+	point, ok := getKnowledgePointParam(c, "id")
+	if !ok {
+		return nil, false
+	}
+	if point.CreatedBy != c.GetInt64("userID") && !isAdminRole(c.GetString("role")) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "只有创建人或管理员可以修改知识点"})
+		return nil, false
+	}
+	return point, true
+}
+
+// validKnowledgePointName 检查知识点名称，返回错误信息
+func validKnowledgePointName(name string) string {
+	if name == "" {
+		return "知识点名称不能为空"
+	}
+	if len([]rune(name)) > 100 {
+		return "知识点名称过长"
+	}
+	return ""
+}
+
+// GetKnowledgePoints 获取学科的知识点树
+func GetKnowledgePoints(c *gin.Context) {
+	subject := strings.TrimSpace(c.Query("subject"))
+	if subject == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请指定学科"})
+		return
+	}
+
+	points, err := repository.NewKnowledgePointRepository(database.DB).GetSubjectKnowledgePoints(c.Request.Context(), subject)
+	if err != nil {
+		log.Printf("获取知识点失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"subject":          subject,
+		"knowledge_points": knowledgeTree(points, knowledgePointResponse),
+	})
+}
+
+// GetKnowledgePointSubjects 获取已建立知识点树的学科
+func GetKnowledgePointSubjects(c *gin.Context) {
+	subjects, err := repository.NewKnowledgePointRepository(database.DB).GetSubjects(c.Request.Context())
+	if err != nil {
+		log.Printf("获取知识点学科失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"subjects": subjects})
+}
+
+// CreateKnowledgePoint 在知识点树中添加知识点（教师及以上权限），下级知识点与上级属于同一学科
+func CreateKnowledgePoint(c *gin.Context) {
+	var input knowledgePointInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的输入数据"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	pointRepo := repository.NewKnowledgePointRepository(database.DB)
+	point := &models.KnowledgePoint{
+		Subject:   strings.TrimSpace(input.Subject),
+		Name:      strings.TrimSpace(input.Name),
+		CreatedBy: c.GetInt64("userID"),
+	}
+	if input.Description != nil {
+		point.Description = strings.TrimSpace(*input.Description)
+	}
+	if input.ParentID != nil && *input.ParentID != 0 {
+		parent, err := pointRepo.GetKnowledgePointByID(ctx, *input.ParentID)
+		if err != nil {
+			log.Printf("获取知识点失败: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+			return
+		}
+		if parent == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "上级知识点不存在"})
+			return
+		}
+		if point.Subject != "" && point.Subject != parent.Subject {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "下级知识点必须与上级知识点属于同一学科"})
+			return
+		}
+		point.Subject = parent.Subject
+		point.ParentID = &parent.ID
+	}
+	if point.Subject == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "学科不能为空"})
+		return
+	}
+	if len([]rune(point.Subject)) > 50 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "学科名称过长"})
+		return
+	}
+	if message := validKnowledgePointName(point.Name); message != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": message})
+		return
+	}
+
+	exists, err := pointRepo.NameExists(ctx, point.Subject, point.ParentID, point.Name, 0)
+	if err != nil {
+		log.Printf("检查知识点名称失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+	if exists {
+		c.JSON(http.StatusConflict, gin.H{"error": "同一上级知识点下已有同名的知识点"})
+		return
+	}
+
+	if input.Position != nil {
+		point.Position = *input.Position
+	} else if point.Position, err = pointRepo.NextPosition(ctx, point.Subject, point.ParentID); err != nil {
+		log.Printf("获取知识点顺序失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+
+	if err := pointRepo.CreateKnowledgePoint(ctx, point); err != nil {
+		log.Printf("创建知识点失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, knowledgePointResponse(point))
+}
+
+// UpdateKnowledgePoint 修改知识点（创建人或管理员），可以移动到同一学科的其他知识点下，学科不能修改
+func UpdateKnowledgePoint(c *gin.Context) {
+	point, ok := getManagedKnowledgePoint(c)
+	if !ok {
+		return
+	}
+
+	var input knowledgePointInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的输入数据"})
+		return
+	}
+	if subject := strings.TrimSpace(input.Subject); subject != "" && subject != point.Subject {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "知识点的学科不能修改"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	pointRepo := repository.NewKnowledgePointRepository(database.DB)
+	moved := false
+	if input.ParentID != nil {
+		var parentID *int64
+		if *input.ParentID != 0 {
+			points, err := pointRepo.GetSubjectKnowledgePoints(ctx, point.Subject)
+			if err != nil {
+				log.Printf("获取知识点失败: %v", err)
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+				return
+			}
+			found := false
+			for _, candidate := range points {
+				if candidate.ID == *input.ParentID {
+					found = true
+					break
+				}
+			}
+			if !found {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "上级知识点不存在或不属于同一学科"})
+				return
+			}
+			if mastery.IsDescendant(points, point.ID, *input.ParentID) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "不能移动到自身或下级知识点下"})
+				return
+			}
+			parentID = input.ParentID
+		}
+		moved = (parentID == nil) != (point.ParentID == nil) || (parentID != nil && *parentID != *point.ParentID)
+		point.ParentID = parentID
+	}
+	if name := strings.TrimSpace(input.Name); name != "" {
+		if message := validKnowledgePointName(name); message != "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": message})
+			return
+		}
+		point.Name = name
+	}
+	if input.Description != nil {
+		point.Description = strings.TrimSpace(*input.Description)
+	}
+
+	exists, err := pointRepo.NameExists(ctx, point.Subject, point.ParentID, point.Name, point.ID)
+	if err != nil {
+		log.Printf("检查知识点名称失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+	if exists {
+		c.JSON(http.StatusConflict, gin.H{"error": "同一上级知识点下已有同名的知识点"})
+		return
+	}
+
+	if input.Position != nil {
+		point.Position = *input.Position
+	} else if moved {
+		if point.Position, err = pointRepo.NextPosition(ctx, point.Subject, point.ParentID); err != nil {
+			log.Printf("获取知识点顺序失败: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+			return
+		}
+	}
+
+	if err := pointRepo.UpdateKnowledgePoint(ctx, point); err != nil {
+		log.Printf("更新知识点失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+
+	c.JSON(http.StatusOK, knowledgePointResponse(point))
+}
+
+// DeleteKnowledgePoint 删除知识点（创建人或管理员），有下级知识点时不能删除
+// 题目保留知识点名称，作业的关联和学生在该知识点上的掌握度一并删除
+func DeleteKnowledgePoint(c *gin.Context) {
+	point, ok := getManagedKnowledgePoint(c)
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	pointRepo := repository.NewKnowledgePointRepository(database.DB)
+	hasChildren, err := pointRepo.HasChildren(ctx, point.ID)
+	if err != nil {
+		log.Printf("检查下级知识点失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+	if hasChildren {
+		c.JSON(http.StatusConflict, gin.H{"error": "请先删除或移走下级知识点"})
+		return
+	}
+
+	if err := pointRepo.DeleteKnowledgePoint(ctx, point.ID); err != nil {
+		log.Printf("删除知识点失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "知识点已删除"})
+}
+
+// SetAssignmentKnowledgePoints 设置作业涉及的知识点（管理员或课程教师），整体替换
+func SetAssignmentKnowledgePoints(c *gin.Context) {
+	assignment, ok := getManagedAssignment(c)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		KnowledgePointIDs []int64 `json:"knowledge_point_ids"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的输入数据"})
+		return
+	}
+	ids := make([]int64, 0, len(input.KnowledgePointIDs))
+	seen := map[int64]bool{}
+	for _, id := range input.KnowledgePointIDs {
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+
+	ctx := c.Request.Context()
+	points, err := repository.NewKnowledgePointRepository(database.DB).GetKnowledgePointsByIDs(ctx, ids)
+	if err != nil {
+		log.Printf("获取知识点失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+	if len(points) != len(ids) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "知识点不存在"})
+		return
+	}
+
+	values := make([]models.KnowledgePoint, 0, len(points))
+	for _, point := range points {
+		values = append(values, *point)
+	}
+	if err := repository.NewAssignmentRepository(database.DB).SetKnowledgePoints(ctx, assignment, values); err != nil {
+		log.Printf("设置作业知识点失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+	assignment.KnowledgePoints = values
+
+	c.JSON(http.StatusOK, assignmentResponse(assignment))
+}
+
+// knowledgePointFilter 解析按知识点筛选的查询参数，返回该知识点及其全部下级知识点的ID。失败时已写入响应
+func knowledgePointFilter(c *gin.Context) ([]int64, bool) {
+	value := c.Query("knowledge_point_id")
+	if value == "" {
+		return nil, true
+	}
+	pointID, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的知识点ID"})
+		return nil, false
+	}
+
+	ctx := c.Request.Context()
+	pointRepo := repository.NewKnowledgePointRepository(database.DB)
+	point, err := pointRepo.GetKnowledgePointByID(ctx, pointID)
+	if err != nil {
+		log.Printf("获取知识点失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return nil, false
+	}
+	if point == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "知识点不存在"})
+		return nil, false
+	}
+	points, err := pointRepo.GetSubjectKnowledgePoints(ctx, point.Subject)
+	if err != nil {
+		log.Printf("获取知识点失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return nil, false
+	}
+	return mastery.Descendants(points, point.ID), true
+}
@@ -0,0 +1,510 @@
+package controllers
+
+import (
+	"context"
+	"crypto/rsa"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"EduGo_servers/internal/database"
+	"EduGo_servers/internal/lti"
+	"EduGo_servers/internal/models"
+	"EduGo_servers/internal/repository"
+)
+
+var ltiKeyMu sync.Mutex
+
+// ltiSigningKey 获取最新的LTI签名密钥，还没有密钥时生成一个
+func ltiSigningKey(ctx context.Context) (string, *rsa.PrivateKey, error) {
+	ltiKeyMu.Lock()
+	defer ltiKeyMu.Unlock()
+
+	ltiRepo := repository.NewLTIRepository(database.DB)
+	keys, err := ltiRepo.GetKeys(ctx)
+	if err != nil {
+		return "", nil, err
+	}
+	if len(keys) == 0 {
+		kid, privateKey, err := lti.GenerateKey()
+		if err != nil {
+			return "", nil, err
+		}
+		key := &models.LTIKey{KID: kid, PrivateKey: privateKey}
+		if err := ltiRepo.CreateKey(ctx, key); err != nil {
+			return "", nil, err
+		}
+		keys = append(keys, key)
+	}
+
+	privateKey, err := lti.ParsePrivateKey(keys[0].PrivateKey)
+	if err != nil {
+		return "", nil, err
+	}
+	return keys[0].KID, privateKey, nil
+}
+
+// ltiPublicKeys 全部LTI签名密钥的公钥，键为密钥ID
+func ltiPublicKeys(ctx context.Context) (map[string]*rsa.PublicKey, error) {
+	keys, err := repository.NewLTIRepository(database.DB).GetKeys(ctx)
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[string]*rsa.PublicKey, len(keys))
+	for _, key := range keys {
+		privateKey, err := lti.ParsePrivateKey(key.PrivateKey)
+		if err != nil {
+			return nil, err
+		}
+		result[key.KID] = &privateKey.PublicKey
+	}
+	return result, nil
+}
+
+// GetLTIKeySet LTI签名密钥的公钥集（公开），外部工具和平台用它校验EduGo签名的消息
+func GetLTIKeySet(c *gin.Context) {
+	ctx := c.Request.Context()
+	// 保证至少有一个密钥，工具可以在第一次启动前获取密钥集
+	if _, _, err := ltiSigningKey(ctx); err != nil {
+		log.Printf("获取LTI签名密钥失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+	keys, err := ltiPublicKeys(ctx)
+	if err != nil {
+		log.Printf("获取LTI签名密钥失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+
+	set := lti.JWKS{Keys: make([]lti.JWK, 0, len(keys))}
+	for kid, key := range keys {
+		set.Keys = append(set.Keys, lti.PublicJWK(kid, key))
+	}
+	c.JSON(http.StatusOK, set)
+}
+
+// GetLTIConfig EduGo作为平台和工具的LTI配置（公开），用于在对方系统中注册
+func GetLTIConfig(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"platform": gin.H{
+			"issuer":                 lti.Issuer,
+			"authorization_endpoint": lti.URL("/authorize"),
+			"token_endpoint":         lti.URL("/token"),
+			"jwks_uri":               lti.URL("/jwks"),
+			"deep_link_return_url":   lti.URL("/deep-links/return"),
+		},
+		"tool": gin.H{
+			"login_url":  lti.URL("/login"),
+			"launch_url": lti.URL("/launch"),
+			"jwks_uri":   lti.URL("/jwks"),
+		},
+	})
+}
+
+// splitLines 按行拆分并去掉空行
+func splitLines(value string) []string {
+	var lines []string
+	for _, line := range strings.Split(value, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+func ltiToolResponse(tool *models.LTITool) gin.H {
+	redirectURIs := splitLines(tool.RedirectURIs)
+	if redirectURIs == nil {
+		redirectURIs = []string{}
+	}
+	return gin.H{
+		"id":              tool.ID,
+		"name":            tool.Name,
+		"client_id":       tool.ClientID,
+		"deployment_id":   tool.DeploymentID,
+		"login_url":       tool.LoginURL,
+		"target_link_uri": tool.TargetLinkURI,
+		"redirect_uris":   redirectURIs,
+		"deep_link_url":   tool.DeepLinkURL,
+		"keyset_url":      tool.KeySetURL,
+		"created_at":      tool.CreatedAt,
+		"updated_at":      tool.UpdatedAt,
+	}
+}
+
+// ltiToolInput 注册外部工具的输入，修改时各字段均可选
+type ltiToolInput struct {
+	Name          *string  `json:"name"`
+	LoginURL      *string  `json:"login_url"`
+	TargetLinkURI *string  `json:"target_link_uri"`
+	RedirectURIs  []string `json:"redirect_uris"`
+	DeepLinkURL   *string  `json:"deep_link_url"`
+	KeySetURL     *string  `json:"keyset_url"`
+}
+
+// applyLTIToolInput 把输入应用到工具并检查，失败时已写入响应
+func applyLTIToolInput(c *gin.Context, tool *models.LTITool, input *ltiToolInput) bool {
+	if input.Name != nil {
+		tool.Name = strings.TrimSpace(*input.Name)
+	}
+	if input.LoginURL != nil {
+		tool.LoginURL = strings.TrimSpace(*input.LoginURL)
+	}
+	if input.TargetLinkURI != nil {
+		tool.TargetLinkURI = strings.TrimSpace(*input.TargetLinkURI)
+	}
+	if input.RedirectURIs != nil {
+		tool.RedirectURIs = strings.Join(input.RedirectURIs, "\n")
+	}
+	if input.DeepLinkURL != nil {
+		tool.DeepLinkURL = strings.TrimSpace(*input.DeepLinkURL)
+	}
+	if input.KeySetURL != nil {
+		tool.KeySetURL = strings.TrimSpace(*input.KeySetURL)
+	}
+
+	if tool.Name == "" || len(tool.Name) > 100 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "工具名称不能为空，且不能超过100个字符"})
+		return false
+	}
+	urls := append([]string{tool.LoginURL, tool.TargetLinkURI, tool.KeySetURL}, splitLines(tool.RedirectURIs)...)
+	if tool.DeepLinkURL != "" {
+		urls = append(urls, tool.DeepLinkURL)
+	}
+	for _, value := range urls {
+		if !lti.IsHTTPURL(value) || len(value) > 500 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的地址: " + value})
+			return false
+		}
+	}
+	return true
+}
+
+// ltiRedirectAllowed 检查id_token的接收地址是否已为工具注册，没有注册接收地址时只允许启动地址
+func ltiRedirectAllowed(tool *models.LTITool, redirectURI string) bool {
+	allowed := splitLines(tool.RedirectURIs)
+	if len(allowed) == 0 {
+		allowed = []string{tool.TargetLinkURI, tool.DeepLinkURL}
+	}
+	for _, uri := range allowed {
+		if uri != "" && uri == redirectURI {
+			return true
+		}
+	}
+	return false
+}
+
+// GetLTITools 获取已注册的外部工具，教师创建链接时选择
+func GetLTITools(c *gin.Context) {
+	tools, err := repository.NewLTIRepository(database.DB).GetTools(c.Request.Context())
+	if err != nil {
+		log.Printf("获取LTI工具失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+
+	result := make([]gin.H, 0, len(tools))
+	for _, tool := range tools {
+		result = append(result, ltiToolResponse(tool))
+	}
+	c.JSON(http.StatusOK, gin.H{"tools": result})
+}
+
+// CreateLTITool 注册外部工具（管理员），客户端ID和部署ID由平台生成
+func CreateLTITool(c *gin.Context) {
+	var input ltiToolInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的输入数据"})
+		return
+	}
+
+	tool := &models.LTITool{CreatedBy: c.GetInt64("userID")}
+	if !applyLTIToolInput(c, tool, &input) {
+		return
+	}
+	clientID, err := randomHex(16)
+	if err != nil {
+		log.Printf("生成LTI客户端ID失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+	deploymentID, err := randomHex(8)
+	if err != nil {
+		log.Printf("生成LTI部署ID失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+	tool.ClientID = clientID
+	tool.DeploymentID = deploymentID
+
+	if err := repository.NewLTIRepository(database.DB).CreateTool(c.Request.Context(), tool); err != nil {
+		log.Printf("注册LTI工具失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "工具注册成功",
+		"tool":    ltiToolResponse(tool),
+	})
+}
+
+// getLTIToolParam 获取路径参数指定的外部工具
+func getLTIToolParam(c *gin.Context) (*models.LTITool, bool) {
+	toolID, ok := parseIDParam(c, "id", "无效的工具ID")
+	if !ok {
+		return nil, false
+	}
+	tool, err := repository.NewLTIRepository(database.DB).GetToolByID(c.Request.Context(), toolID)
+	if err != nil {
+		log.Printf("获取LTI工具失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return nil, false
+	}
+	if tool == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "工具不存在"})
+		return nil, false
+	}
+	return tool, true
+}
+
+// UpdateLTITool 修改外部工具（管理员）
+func UpdateLTITool(c *gin.Context) {
+	tool, ok := getLTIToolParam(c)
+	if !ok {
+		return
+	}
+
+	var input ltiToolInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的输入数据"})
+		return
+	}
+	if !applyLTIToolInput(c, tool, &input) {
+		return
+	}
+
+	if err := repository.NewLTIRepository(database.DB).UpdateTool(c.Request.Context(), tool); err != nil {
+		log.Printf("修改LTI工具失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "工具修改成功",
+		"tool":    ltiToolResponse(tool),
+	})
+}
+
+// DeleteLTITool 删除外部工具及其链接（管理员），已回传的成绩保留
+func DeleteLTITool(c *gin.Context) {
+	tool, ok := getLTIToolParam(c)
+	if !ok {
+		return
+	}
+
+	if err := repository.NewLTIRepository(database.DB).DeleteTool(c.Request.Context(), tool.ID); err != nil {
+		log.Printf("删除LTI工具失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "工具删除成功"})
+}
+
+func ltiPlatformResponse(platform *models.LTIPlatform) gin.H {
+	return gin.H{
+		"id":             platform.ID,
+		"name":           platform.Name,
+		"issuer":         platform.Issuer,
+		"client_id":      platform.ClientID,
+		"deployment_id":  platform.DeploymentID,
+		"auth_login_url": platform.AuthLoginURL,
+		"keyset_url":     platform.KeySetURL,
+		"created_at":     platform.CreatedAt,
+		"updated_at":     platform.UpdatedAt,
+	}
+}
+
+// ltiPlatformInput 注册外部平台的输入，修改时各字段均可选
+type ltiPlatformInput struct {
+	Name         *string `json:"name"`
+	Issuer       *string `json:"issuer"`
+	ClientID     *string `json:"client_id"`
+	DeploymentID *string `json:"deployment_id"`
+	AuthLoginURL *string `json:"auth_login_url"`
+	KeySetURL    *string `json:"keyset_url"`
+}
+
+// saveLTIPlatform 把输入应用到平台、检查并保存，失败时已写入响应
+func saveLTIPlatform(c *gin.Context, platform *models.LTIPlatform, input *ltiPlatformInput) bool {
+	for _, field := range []struct {
+		value  *string
+		target *string
+	}{
+		{input.Name, &platform.Name},
+		{input.Issuer, &platform.Issuer},
+		{input.ClientID, &platform.ClientID},
+		{input.DeploymentID, &platform.DeploymentID},
+		{input.AuthLoginURL, &platform.AuthLoginURL},
+		{input.KeySetURL, &platform.KeySetURL},
+	} {
+		if field.value != nil {
+			*field.target = strings.TrimSpace(*field.value)
+		}
+	}
+
+	switch {
+	case platform.Name == "" || len(platform.Name) > 100:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "平台名称不能为空，且不能超过100个字符"})
+		return false
+	case platform.Issuer == "" || len(platform.Issuer) > 191 || platform.ClientID == "" || len(platform.ClientID) > 191:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "平台标识和客户端ID不能为空，且不能超过191个字符"})
+		return false
+	case len(platform.DeploymentID) > 191:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "部署ID不能超过191个字符"})
+		return false
+	case !lti.IsHTTPURL(platform.AuthLoginURL) || len(platform.AuthLoginURL) > 500:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的授权地址"})
+		return false
+	case !lti.IsHTTPURL(platform.KeySetURL) || len(platform.KeySetURL) > 500:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的密钥集地址"})
+		return false
+	}
+
+	ctx := c.Request.Context()
+	ltiRepo := repository.NewLTIRepository(database.DB)
+	exists, err := ltiRepo.PlatformExists(ctx, platform.Issuer, platform.ClientID, platform.ID)
+	if err != nil {
+		log.Printf("检查LTI平台失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return false
+	}
+	if exists {
+		c.JSON(http.StatusConflict, gin.H{"error": "该平台和客户端ID已注册"})
+		return false
+	}
+
+	if platform.ID == 0 {
+		err = ltiRepo.CreatePlatform(ctx, platform)
+	} else {
+		err = ltiRepo.UpdatePlatform(ctx, platform)
+	}
+	if err != nil {
+		log.Printf("保存LTI平台失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return false
+	}
+	return true
+}
+
+// GetLTIPlatforms 获取已注册的外部平台（管理员）
+func GetLTIPlatforms(c *gin.Context) {
+	platforms, err := repository.NewLTIRepository(database.DB).GetPlatforms(c.Request.Context())
+	if err != nil {
+		log.Printf("获取LTI平台失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+
+	result := make([]gin.H, 0, len(platforms))
+	for _, platform := range platforms {
+		result = append(result, ltiPlatformResponse(platform))
+	}
+	c.JSON(http.StatusOK, gin.H{"platforms": result})
+}
+
+// CreateLTIPlatform 注册启动EduGo的外部平台（管理员）
+func CreateLTIPlatform(c *gin.Context) {
+	var input ltiPlatformInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的输入数据"})
+		return
+	}
+
+	platform := &models.LTIPlatform{CreatedBy: c.GetInt64("userID")}
+	if !saveLTIPlatform(c, platform, &input) {
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":  "平台注册成功",
+		"platform": ltiPlatformResponse(platform),
+	})
+}
+
+// getLTIPlatformParam 获取路径参数指定的外部平台
+func getLTIPlatformParam(c *gin.Context) (*models.LTIPlatform, bool) {
+	platformID, ok := parseIDParam(c, "id", "无效的平台ID")
+	if !ok {
+		return nil, false
+	}
+	platform, err := repository.NewLTIRepository(database.DB).GetPlatformByID(c.Request.Context(), platformID)
+	if err != nil {
+		log.Printf("获取LTI平台失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return nil, false
+	}
+	if platform == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "平台不存在"})
+		return nil, false
+	}
+	return platform, true
+}
+
+// UpdateLTIPlatform 修改外部平台（管理员）
+func UpdateLTIPlatform(c *gin.Context) {
+	platform, ok := getLTIPlatformParam(c)
+	if !ok {
+		return
+	}
+
+	var input ltiPlatformInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的输入数据"})
+		return
+	}
+	if !saveLTIPlatform(c, platform, &input) {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":  "平台修改成功",
+		"platform": ltiPlatformResponse(platform),
+	})
+}
+
+// DeleteLTIPlatform 删除外部平台（管理员），已创建的用户和课程保留，但不能再从该平台启动
+func DeleteLTIPlatform(c *gin.Context) {
+	platform, ok := getLTIPlatformParam(c)
+	if !ok {
+		return
+	}
+
+	if err := repository.NewLTIRepository(database.DB).DeletePlatform(c.Request.Context(), platform.ID); err != nil {
+		log.Printf("删除LTI平台失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "平台删除成功"})
+}
+
+// ltiHTML 返回浏览器页面，LTI的启动和授权接口由浏览器表单直接访问
+func ltiHTML(c *gin.Context, status int, body []byte) {
+	c.Header("Cache-Control", "no-store")
+	c.Data(status, "text/html; charset=utf-8", body)
+}
+
+// ltiError 以页面形式返回浏览器访问的LTI接口的错误
+func ltiError(c *gin.Context, status int, message string) {
+	ltiHTML(c, status, lti.MessagePage(message))
+}
+
+// ltiExpiry LTI消息的有效期
+const ltiExpiry = 5 * time.Minute
@@ -0,0 +1,422 @@
+package controllers
+
+import (
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+
+	"EduGo_servers/internal/database"
+	"EduGo_servers/internal/lti"
+	"EduGo_servers/internal/models"
+	"EduGo_servers/internal/repository"
+)
+
+// ltiAccessTokenExpiry 成绩服务访问令牌的有效期
+const ltiAccessTokenExpiry = time.Hour
+
+// ltiSupportedScopes 成绩服务支持的权限
+var ltiSupportedScopes = map[string]bool{
+	lti.ScopeLineItemReadOnly: true,
+	lti.ScopeResultReadOnly:   true,
+	lti.ScopeScore:            true,
+}
+
+// ltiOAuthError 返回OAuth 2.0格式的错误，工具按错误码处理
+func ltiOAuthError(c *gin.Context, status int, code, description string) {
+	c.Header("Cache-Control", "no-store")
+	c.JSON(status, gin.H{"error": code, "error_description": description})
+}
+
+// IssueLTIAccessToken 成绩服务的令牌地址（公开），工具以私钥签名的客户端断言换取访问令牌
+func IssueLTIAccessToken(c *gin.Context) {
+	if c.PostForm("grant_type") != "client_credentials" {
+		ltiOAuthError(c, http.StatusBadRequest, "unsupported_grant_type", "只支持client_credentials")
+		return
+	}
+	if c.PostForm("client_assertion_type") != "urn:ietf:params:oauth:client-assertion-type:jwt-bearer" {
+		ltiOAuthError(c, http.StatusBadRequest, "invalid_request", "无效的client_assertion_type")
+		return
+	}
+
+	assertion := c.PostForm("client_assertion")
+	clientID, err := lti.UnverifiedIssuer(assertion)
+	if err != nil || clientID == "" {
+		ltiOAuthError(c, http.StatusUnauthorized, "invalid_client", "无效的客户端断言")
+		return
+	}
+
+	ctx := c.Request.Context()
+	tool, err := repository.NewLTIRepository(database.DB).GetToolByClientID(ctx, clientID)
+	if err != nil {
+		log.Printf("获取LTI工具失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+	if tool == nil {
+		ltiOAuthError(c, http.StatusUnauthorized, "invalid_client", "工具未注册")
+		return
+	}
+	claims, err := lti.Verify(ctx, assertion, tool.KeySetURL, tool.ClientID, lti.URL("/token"))
+	if err != nil {
+		log.Printf("校验LTI客户端断言失败 (tool=%d): %v", tool.ID, err)
+		ltiOAuthError(c, http.StatusUnauthorized, "invalid_client", "无效的客户端断言")
+		return
+	}
+	if sub, _ := claims.GetSubject(); sub != tool.ClientID {
+		ltiOAuthError(c, http.StatusUnauthorized, "invalid_client", "无效的客户端断言")
+		return
+	}
+
+	// 每个断言只能使用一次，记录保留到断言不再被接受为止
+	jti := lti.String(claims, "jti")
+	acceptedUntil, err := lti.AcceptedUntil(claims)
+	if jti == "" || len(jti) > 191 || err != nil {
+		ltiOAuthError(c, http.StatusUnauthorized, "invalid_client", "无效的客户端断言")
+		return
+	}
+	fresh, err := repository.NewLTIRepository(database.DB).UseAssertion(ctx, &models.LTIAssertion{
+		ToolID:    tool.ID,
+		JTI:       jti,
+		ExpiresAt: acceptedUntil,
+	})
+	if err != nil {
+		log.Printf("记录LTI客户端断言失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+	if !fresh {
+		ltiOAuthError(c, http.StatusUnauthorized, "invalid_client", "客户端断言已被使用")
+		return
+	}
+
+	// 只授予支持的权限，一个都没有时拒绝
+	var scopes []string
+	for _, scope := range strings.Fields(c.PostForm("scope")) {
+		if ltiSupportedScopes[scope] {
+			scopes = append(scopes, scope)
+		}
+	}
+	if len(scopes) == 0 {
+		ltiOAuthError(c, http.StatusBadRequest, "invalid_scope", "没有可授予的权限")
+		return
+	}
+
+	kid, key, err := ltiSigningKey(ctx)
+	if err != nil {
+		log.Printf("获取LTI签名密钥失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+	now := time.Now()
+	scope := strings.Join(scopes, " ")
+	accessToken, err := lti.Sign(jwt.MapClaims{
+		"iss":   lti.Issuer,
+		"sub":   tool.ClientID,
+		"aud":   lti.URL("/ags"),
+		"iat":   now.Unix(),
+		"exp":   now.Add(ltiAccessTokenExpiry).Unix(),
+		"scope": scope,
+	}, kid, key)
+	if err != nil {
+		log.Printf("签名LTI访问令牌失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, gin.H{
+		"access_token": accessToken,
+		"token_type":   "Bearer",
+		"expires_in":   int(ltiAccessTokenExpiry.Seconds()),
+		"scope":        scope,
+	})
+}
+
+// getLTIServiceTool 校验成绩服务请求的访问令牌，要求令牌包含指定权限之一，失败时已写入响应
+func getLTIServiceTool(c *gin.Context, scopes ...string) (*models.LTITool, bool) {
+	header := c.GetHeader("Authorization")
+	if !strings.HasPrefix(header, "Bearer ") {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "需要访问令牌"})
+		return nil, false
+	}
+
+	ctx := c.Request.Context()
+	keys, err := ltiPublicKeys(ctx)
+	if err != nil {
+		log.Printf("获取LTI公钥失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return nil, false
+	}
+	claims, err := lti.VerifyLocal(strings.TrimPrefix(header, "Bearer "), keys, lti.URL("/ags"))
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "无效的访问令牌"})
+		return nil, false
+	}
+
+	granted := false
+	for _, scope := range strings.Fields(lti.String(claims, "scope")) {
+		for _, required := range scopes {
+			if scope == required {
+				granted = true
+			}
+		}
+	}
+	if !granted {
+		c.JSON(http.StatusForbidden, gin.H{"error": "访问令牌没有该权限"})
+		return nil, false
+	}
+
+	clientID, _ := claims.GetSubject()
+	tool, err := repository.NewLTIRepository(database.DB).GetToolByClientID(ctx, clientID)
+	if err != nil {
+		log.Printf("获取LTI工具失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return nil, false
+	}
+	if tool == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "工具未注册"})
+		return nil, false
+	}
+	return tool, true
+}
+
+// getLTIToolLineItems 获取工具在开课中可以访问的成绩项，即工具的链接关联的成绩项，键为成绩项ID
+func getLTIToolLineItems(c *gin.Context, tool *models.LTITool) (int64, map[int64]*models.LTILink, bool) {
+	offeringID, ok := parseIDParam(c, "offering_id", "无效的开课ID")
+	if !ok {
+		return 0, nil, false
+	}
+	links, err := repository.NewLTIRepository(database.DB).GetToolOfferingLinks(c.Request.Context(), tool.ID, offeringID)
+	if err != nil {
+		log.Printf("获取LTI链接失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return 0, nil, false
+	}
+
+	items := make(map[int64]*models.LTILink)
+	for _, link := range links {
+		if link.GradeItemID != nil {
+			items[*link.GradeItemID] = link
+		}
+	}
+	return offeringID, items, true
+}
+
+// getLTILineItemParam 获取路径参数指定的成绩项，要求工具可以访问，失败时已写入响应
+func getLTILineItemParam(c *gin.Context, scopes ...string) (*models.GradeItem, *models.LTILink, bool) {
+	tool, ok := getLTIServiceTool(c, scopes...)
+	if !ok {
+		return nil, nil, false
+	}
+	offeringID, links, ok := getLTIToolLineItems(c, tool)
+	if !ok {
+		return nil, nil, false
+	}
+	itemID, ok := parseIDParam(c, "item_id", "无效的成绩项ID")
+	if !ok {
+		return nil, nil, false
+	}
+
+	link, ok := links[itemID]
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "成绩项不存在"})
+		return nil, nil, false
+	}
+	item, err := getOfferingGradeItem(c.Request.Context(), offeringID, itemID)
+	if err != nil {
+		log.Printf("获取成绩项失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return nil, nil, false
+	}
+	if item == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "成绩项不存在"})
+		return nil, nil, false
+	}
+	return item, link, true
+}
+
+func ltiLineItemResponse(item *models.GradeItem, link *models.LTILink) gin.H {
+	return gin.H{
+		"id":             ltiLineItemURL(item.OfferingID, item.ID),
+		"label":          item.Title,
+		"scoreMaximum":   item.MaxScore,
+		"resourceLinkId": link.ResourceLinkID,
+	}
+}
+
+// GetLTILineItems 成绩服务：获取开课中工具可以访问的成绩项，可以用resource_link_id筛选
+func GetLTILineItems(c *gin.Context) {
+	tool, ok := getLTIServiceTool(c, lti.ScopeLineItemReadOnly)
+	if !ok {
+		return
+	}
+	offeringID, links, ok := getLTIToolLineItems(c, tool)
+	if !ok {
+		return
+	}
+
+	items, err := repository.NewGradebookRepository(database.DB).GetItems(c.Request.Context(), offeringID)
+	if err != nil {
+		log.Printf("获取成绩项失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+
+	resourceLinkID := c.Query("resource_link_id")
+	result := make([]gin.H, 0, len(links))
+	for _, item := range items {
+		link, ok := links[item.ID]
+		if !ok || (resourceLinkID != "" && link.ResourceLinkID != resourceLinkID) {
+			continue
+		}
+		result = append(result, ltiLineItemResponse(item, link))
+	}
+	c.Header("Content-Type", lti.MediaTypeLineItemContainer)
+	c.JSON(http.StatusOK, result)
+}
+
+// GetLTILineItem 成绩服务：获取成绩项
+func GetLTILineItem(c *gin.Context) {
+	item, link, ok := getLTILineItemParam(c, lti.ScopeLineItemReadOnly)
+	if !ok {
+		return
+	}
+	c.Header("Content-Type", lti.MediaTypeLineItem)
+	c.JSON(http.StatusOK, ltiLineItemResponse(item, link))
+}
+
+// PostLTIScore 成绩服务：回传学生的得分
+// 只有评分完成的得分写入成绩册，按成绩项满分换算；保留教师设置的迟交扣分和免考
+func PostLTIScore(c *gin.Context) {
+	item, _, ok := getLTILineItemParam(c, lti.ScopeScore)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		UserID           string   `json:"userId" binding:"required"`
+		ScoreGiven       *float64 `json:"scoreGiven"`
+		ScoreMaximum     *float64 `json:"scoreMaximum"`
+		Comment          string   `json:"comment"`
+		ActivityProgress string   `json:"activityProgress" binding:"required"`
+		GradingProgress  string   `json:"gradingProgress" binding:"required"`
+		Timestamp        string   `json:"timestamp" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的输入数据"})
+		return
+	}
+	if input.ScoreGiven != nil && (input.ScoreMaximum == nil || *input.ScoreMaximum <= 0 || *input.ScoreGiven < 0) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的得分"})
+		return
+	}
+	timestamp, err := time.Parse(time.RFC3339Nano, input.Timestamp)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的timestamp"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	studentID, err := strconv.ParseInt(input.UserID, 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的userId"})
+		return
+	}
+	enrolled, err := isOfferingStudent(ctx, item.OfferingID, studentID)
+	if err != nil {
+		log.Printf("检查选课失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+	if !enrolled {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "该用户不是开课的学生"})
+		return
+	}
+	if input.GradingProgress != "FullyGraded" {
+		c.Status(http.StatusNoContent)
+		return
+	}
+
+	gradebookRepo := repository.NewGradebookRepository(database.DB)
+	entries, err := gradebookRepo.GetEntries(ctx, item.OfferingID, studentID)
+	if err != nil {
+		log.Printf("获取成绩失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+	entry := &models.GradeEntry{ItemID: item.ID, StudentID: studentID}
+	for _, existing := range entries {
+		if existing.ItemID == item.ID {
+			entry = existing
+		}
+	}
+	// 晚于已保存成绩发出的回传才生效，乱序到达的旧成绩直接忽略
+	if entry.ScoredAt != nil && timestamp.Before(*entry.ScoredAt) {
+		c.Status(http.StatusNoContent)
+		return
+	}
+
+	entry.Score = nil
+	if input.ScoreGiven != nil {
+		score := math.Min(*input.ScoreGiven / *input.ScoreMaximum * item.MaxScore, item.MaxScore)
+		score = math.Round(score*100) / 100
+		entry.Score = &score
+	}
+	entry.Comment = truncate(input.Comment, 500)
+	entry.GradedBy = 0
+	entry.ScoredAt = &timestamp
+	if err := gradebookRepo.SaveEntries(ctx, []*models.GradeEntry{entry}); err != nil {
+		log.Printf("保存LTI回传成绩失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// GetLTIResults 成绩服务：获取成绩项的得分，可以用user_id筛选
+func GetLTIResults(c *gin.Context) {
+	item, _, ok := getLTILineItemParam(c, lti.ScopeResultReadOnly)
+	if !ok {
+		return
+	}
+
+	var studentID int64
+	if value := c.Query("user_id"); value != "" {
+		var err error
+		if studentID, err = strconv.ParseInt(value, 10, 64); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的user_id"})
+			return
+		}
+	}
+	entries, err := repository.NewGradebookRepository(database.DB).GetEntries(c.Request.Context(), item.OfferingID, studentID)
+	if err != nil {
+		log.Printf("获取成绩失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+
+	lineItemURL := ltiLineItemURL(item.OfferingID, item.ID)
+	result := make([]gin.H, 0, len(entries))
+	for _, entry := range entries {
+		if entry.ItemID != item.ID || entry.Score == nil {
+			continue
+		}
+		userID := strconv.FormatInt(entry.StudentID, 10)
+		result = append(result, gin.H{
+			"id":            lineItemURL + "/results/" + userID,
+			"scoreOf":       lineItemURL,
+			"userId":        userID,
+			"resultScore":   *entry.Score,
+			"resultMaximum": item.MaxScore,
+			"comment":       entry.Comment,
+		})
+	}
+	c.Header("Content-Type", lti.MediaTypeResultContainer)
+	c.JSON(http.StatusOK, result)
+}
@@ -0,0 +1,838 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+
+	"EduGo_servers/internal/database"
+	"EduGo_servers/internal/lti"
+	"EduGo_servers/internal/models"
+	"EduGo_servers/internal/repository"
+	"EduGo_servers/internal/xapi"
+)
+
+// ltiDeepLinkExpiry 深度链接的启动有效期，教师需要在此期间在工具中选好内容
+const ltiDeepLinkExpiry = time.Hour
+
+func ltiLinkResponse(link *models.LTILink) gin.H {
+	response := gin.H{
+		"id":               link.ID,
+		"resource_link_id": link.ResourceLinkID,
+		"offering_id":      link.OfferingID,
+		"tool_id":          link.ToolID,
+		"title":            link.Title,
+		"url":              link.URL,
+		"custom":           ltiLinkCustom(link),
+		"grade_item_id":    link.GradeItemID,
+		"created_at":       link.CreatedAt,
+		"updated_at":       link.UpdatedAt,
+	}
+	if link.Tool != nil {
+		response["tool_name"] = link.Tool.Name
+	}
+	return response
+}
+
+// ltiLinkCustom 链接的自定义参数
+func ltiLinkCustom(link *models.LTILink) map[string]string {
+	custom := map[string]string{}
+	if link.Custom != "" {
+		if err := json.Unmarshal([]byte(link.Custom), &custom); err != nil {
+			log.Printf("解析LTI链接自定义参数失败 (link=%d): %v", link.ID, err)
+		}
+	}
+	return custom
+}
+
+// encodeLTICustom 把自定义参数编码为JSON，没有参数时为空字符串
+func encodeLTICustom(custom map[string]string) (string, error) {
+	if len(custom) == 0 {
+		return "", nil
+	}
+	data, err := json.Marshal(custom)
+	return string(data), err
+}
+
+// getOfferingGradeItem 获取开课中的成绩项，不存在时返回nil
+func getOfferingGradeItem(ctx context.Context, offeringID, itemID int64) (*models.GradeItem, error) {
+	item, err := repository.NewGradebookRepository(database.DB).GetItemByID(ctx, itemID)
+	if err != nil || item == nil || item.OfferingID != offeringID {
+		return nil, err
+	}
+	return item, nil
+}
+
+// GetOfferingLTILinks 获取开课中的外部工具链接（开课的教师和学生）
+func GetOfferingLTILinks(c *gin.Context) {
+	course, ok := getCourseParam(c)
+	if !ok {
+		return
+	}
+	offering, ok := getOfferingParam(c, course)
+	if !ok {
+		return
+	}
+	offering.Course = course
+
+	_, allowed, err := canViewOfferingAssignments(c, offering)
+	if err != nil {
+		log.Printf("检查开课权限失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+	if !allowed {
+		c.JSON(http.StatusForbidden, gin.H{"error": "无权查看该开课的外部工具"})
+		return
+	}
+
+	links, err := repository.NewLTIRepository(database.DB).GetOfferingLinks(c.Request.Context(), offering.ID)
+	if err != nil {
+		log.Printf("获取LTI链接失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+
+	result := make([]gin.H, 0, len(links))
+	for _, link := range links {
+		result = append(result, ltiLinkResponse(link))
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"offering_id": offering.ID,
+		"links":       result,
+	})
+}
+
+// CreateOfferingLTILink 在开课中添加外部工具链接（管理员或课程教师）
+// 关联成绩项后工具可以通过成绩服务回传该成绩项的得分
+func CreateOfferingLTILink(c *gin.Context) {
+	offering, ok := getManagedOffering(c)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		ToolID      int64             `json:"tool_id" binding:"required"`
+		Title       string            `json:"title" binding:"required,max=200"`
+		URL         string            `json:"url"`
+		Custom      map[string]string `json:"custom"`
+		GradeItemID *int64            `json:"grade_item_id"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的输入数据"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	ltiRepo := repository.NewLTIRepository(database.DB)
+	tool, err := ltiRepo.GetToolByID(ctx, input.ToolID)
+	if err != nil {
+		log.Printf("获取LTI工具失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+	if tool == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "工具不存在"})
+		return
+	}
+
+	link := &models.LTILink{
+		OfferingID: offering.ID,
+		ToolID:     tool.ID,
+		Tool:       tool,
+		CreatedBy:  c.GetInt64("userID"),
+	}
+	if !applyLTILinkInput(c, link, &input.Title, &input.URL, input.Custom, input.GradeItemID) {
+		return
+	}
+	if link.ResourceLinkID, err = xapi.NewUUID(); err != nil {
+		log.Printf("生成LTI资源链接ID失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+
+	if err := ltiRepo.CreateLinks(ctx, []*models.LTILink{link}); err != nil {
+		log.Printf("创建LTI链接失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "外部工具链接创建成功",
+		"link":    ltiLinkResponse(link),
+	})
+}
+
+// applyLTILinkInput 把输入应用到链接并检查，失败时已写入响应；gradeItemID为0表示取消关联成绩项
+func applyLTILinkInput(c *gin.Context, link *models.LTILink, title, rawURL *string, custom map[string]string, gradeItemID *int64) bool {
+	if title != nil {
+		link.Title = strings.TrimSpace(*title)
+	}
+	if rawURL != nil {
+		link.URL = strings.TrimSpace(*rawURL)
+	}
+	if custom != nil {
+		encoded, err := encodeLTICustom(custom)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的自定义参数"})
+			return false
+		}
+		link.Custom = encoded
+	}
+
+	if link.Title == "" || len(link.Title) > 200 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "链接标题不能为空，且不能超过200个字符"})
+		return false
+	}
+	if link.URL != "" && (!lti.IsHTTPURL(link.URL) || len(link.URL) > 500) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的启动地址"})
+		return false
+	}
+
+	if gradeItemID != nil {
+		if *gradeItemID == 0 {
+			link.GradeItemID = nil
+			return true
+		}
+		item, err := getOfferingGradeItem(c.Request.Context(), link.OfferingID, *gradeItemID)
+		if err != nil {
+			log.Printf("获取成绩项失败: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+			return false
+		}
+		if item == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "成绩项不存在"})
+			return false
+		}
+		link.GradeItemID = &item.ID
+	}
+	return true
+}
+
+// getOfferingLTILinkParam 获取路径参数指定的链接，要求属于该开课
+func getOfferingLTILinkParam(c *gin.Context, offering *models.CourseOffering) (*models.LTILink, bool) {
+	linkID, ok := parseIDParam(c, "link_id", "无效的链接ID")
+	if !ok {
+		return nil, false
+	}
+	link, err := repository.NewLTIRepository(database.DB).GetLinkByID(c.Request.Context(), linkID)
+	if err != nil {
+		log.Printf("获取LTI链接失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return nil, false
+	}
+	if link == nil || link.OfferingID != offering.ID {
+		c.JSON(http.StatusNotFound, gin.H{"error": "链接不存在"})
+		return nil, false
+	}
+	return link, true
+}
+
+// UpdateOfferingLTILink 修改外部工具链接（管理员或课程教师），grade_item_id为0时取消关联成绩项
+func UpdateOfferingLTILink(c *gin.Context) {
+	offering, ok := getManagedOffering(c)
+	if !ok {
+		return
+	}
+	link, ok := getOfferingLTILinkParam(c, offering)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		Title       *string           `json:"title"`
+		URL         *string           `json:"url"`
+		Custom      map[string]string `json:"custom"`
+		GradeItemID *int64            `json:"grade_item_id"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的输入数据"})
+		return
+	}
+	if !applyLTILinkInput(c, link, input.Title, input.URL, input.Custom, input.GradeItemID) {
+		return
+	}
+
+	if err := repository.NewLTIRepository(database.DB).UpdateLink(c.Request.Context(), link); err != nil {
+		log.Printf("修改LTI链接失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "外部工具链接修改成功",
+		"link":    ltiLinkResponse(link),
+	})
+}
+
+// DeleteOfferingLTILink 删除外部工具链接（管理员或课程教师），关联的成绩项和已回传的成绩保留
+func DeleteOfferingLTILink(c *gin.Context) {
+	offering, ok := getManagedOffering(c)
+	if !ok {
+		return
+	}
+	link, ok := getOfferingLTILinkParam(c, offering)
+	if !ok {
+		return
+	}
+
+	if err := repository.NewLTIRepository(database.DB).DeleteLink(c.Request.Context(), link.ID); err != nil {
+		log.Printf("删除LTI链接失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "外部工具链接删除成功"})
+}
+
+// startLTILaunch 保存启动并返回工具的OIDC登录发起地址，启动令牌同时作为login_hint和lti_message_hint
+func startLTILaunch(c *gin.Context, launch *models.LTILaunch, tool *models.LTITool, targetLinkURI string) {
+	token, err := randomHex(32)
+	if err != nil {
+		log.Printf("生成LTI启动令牌失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+	launch.Token = token
+	launch.UserID = c.GetInt64("userID")
+	launch.ToolID = tool.ID
+	launch.CreatedAt = time.Now()
+	if err := repository.NewLTIRepository(database.DB).CreateLaunch(c.Request.Context(), launch); err != nil {
+		log.Printf("保存LTI启动失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+
+	params := url.Values{}
+	params.Set("iss", lti.Issuer)
+	params.Set("login_hint", token)
+	params.Set("lti_message_hint", token)
+	params.Set("target_link_uri", targetLinkURI)
+	params.Set("client_id", tool.ClientID)
+	params.Set("lti_deployment_id", tool.DeploymentID)
+	c.JSON(http.StatusOK, gin.H{
+		"url":        lti.WithQuery(tool.LoginURL, params),
+		"expires_at": launch.ExpiresAt,
+	})
+}
+
+// LaunchLTILink 启动外部工具链接（开课的教师和已选课的学生）
+// 返回工具的OIDC登录发起地址，前端在新窗口或iframe中打开，登录发起后授权由浏览器完成
+func LaunchLTILink(c *gin.Context) {
+	linkID, ok := parseIDParam(c, "id", "无效的链接ID")
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	link, err := repository.NewLTIRepository(database.DB).GetLinkByID(ctx, linkID)
+	if err != nil {
+		log.Printf("获取LTI链接失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+	if link == nil || link.Tool == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "链接不存在"})
+		return
+	}
+
+	offering, _, err := loadLTIOffering(ctx, link.OfferingID)
+	if err != nil {
+		log.Printf("获取开课失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+	if offering == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "链接不存在"})
+		return
+	}
+	if !canManageAssignment(c, offering) {
+		enrolled, err := isOfferingStudent(ctx, offering.ID, c.GetInt64("userID"))
+		if err != nil {
+			log.Printf("检查选课失败: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+			return
+		}
+		if !enrolled {
+			c.JSON(http.StatusForbidden, gin.H{"error": "只有开课的教师和学生可以启动该工具"})
+			return
+		}
+	}
+
+	targetLinkURI := link.URL
+	if targetLinkURI == "" {
+		targetLinkURI = link.Tool.TargetLinkURI
+	}
+	startLTILaunch(c, &models.LTILaunch{
+		OfferingID:  offering.ID,
+		LinkID:      &link.ID,
+		MessageType: lti.MessageResourceLink,
+		ExpiresAt:   time.Now().Add(ltiExpiry),
+	}, link.Tool, targetLinkURI)
+}
+
+// StartLTIDeepLinking 发起深度链接（管理员或课程教师），教师在工具中选择内容后，工具返回的链接加入开课
+// 指定grade_category_id时，工具返回的需要评分的内容在该分类下创建成绩项
+func StartLTIDeepLinking(c *gin.Context) {
+	offering, ok := getManagedOffering(c)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		ToolID          int64  `json:"tool_id" binding:"required"`
+		GradeCategoryID *int64 `json:"grade_category_id"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的输入数据"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	tool, err := repository.NewLTIRepository(database.DB).GetToolByID(ctx, input.ToolID)
+	if err != nil {
+		log.Printf("获取LTI工具失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+	if tool == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "工具不存在"})
+		return
+	}
+	if tool.DeepLinkURL == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "该工具不支持深度链接"})
+		return
+	}
+	if input.GradeCategoryID != nil {
+		category, err := repository.NewGradebookRepository(database.DB).GetCategoryByID(ctx, *input.GradeCategoryID)
+		if err != nil {
+			log.Printf("获取成绩分类失败: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+			return
+		}
+		if category == nil || category.OfferingID != offering.ID {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "成绩分类不存在"})
+			return
+		}
+	}
+
+	startLTILaunch(c, &models.LTILaunch{
+		OfferingID:  offering.ID,
+		MessageType: lti.MessageDeepLinkingRequest,
+		CategoryID:  input.GradeCategoryID,
+		ExpiresAt:   time.Now().Add(ltiDeepLinkExpiry),
+	}, tool, tool.DeepLinkURL)
+}
+
+// loadLTIOffering 获取开课及其课程（包括负责教师），开课不存在时返回nil
+func loadLTIOffering(ctx context.Context, offeringID int64) (*models.CourseOffering, *models.Course, error) {
+	offering, err := repository.NewCourseOfferingRepository(database.DB).GetOfferingByID(ctx, offeringID)
+	if err != nil || offering == nil {
+		return nil, nil, err
+	}
+	course, err := repository.NewCourseRepository(database.DB).GetCourseByID(ctx, offering.CourseID)
+	if err != nil || course == nil {
+		return nil, nil, err
+	}
+	offering.Course = course
+	return offering, course, nil
+}
+
+// ltiParam 读取浏览器以GET或表单POST传来的参数
+func ltiParam(c *gin.Context, name string) string {
+	return c.Request.FormValue(name)
+}
+
+// AuthorizeLTILaunch 平台的OIDC授权地址（公开），工具的登录发起完成后浏览器带着login_hint回到这里
+// 校验启动和接收地址后签发id_token，以自动提交的表单发送到工具
+func AuthorizeLTILaunch(c *gin.Context) {
+	switch {
+	case ltiParam(c, "scope") != "openid" || ltiParam(c, "response_type") != "id_token":
+		ltiError(c, http.StatusBadRequest, "无效的授权请求")
+		return
+	case ltiParam(c, "response_mode") != "form_post":
+		ltiError(c, http.StatusBadRequest, "只支持form_post响应方式")
+		return
+	case ltiParam(c, "prompt") != "" && ltiParam(c, "prompt") != "none":
+		ltiError(c, http.StatusBadRequest, "无效的prompt")
+		return
+	case ltiParam(c, "nonce") == "":
+		ltiError(c, http.StatusBadRequest, "缺少nonce")
+		return
+	}
+
+	ctx := c.Request.Context()
+	ltiRepo := repository.NewLTIRepository(database.DB)
+	now := time.Now()
+	loginHint := ltiParam(c, "login_hint")
+	launch, err := ltiRepo.GetLaunch(ctx, loginHint)
+	if err != nil {
+		log.Printf("获取LTI启动失败: %v", err)
+		ltiError(c, http.StatusInternalServerError, "服务器内部错误")
+		return
+	}
+	if launch == nil || now.After(launch.ExpiresAt) {
+		ltiError(c, http.StatusBadRequest, "启动已过期，请重新打开工具")
+		return
+	}
+	if hint := ltiParam(c, "lti_message_hint"); hint != "" && hint != loginHint {
+		ltiError(c, http.StatusBadRequest, "无效的lti_message_hint")
+		return
+	}
+
+	tool, err := ltiRepo.GetToolByID(ctx, launch.ToolID)
+	if err != nil {
+		log.Printf("获取LTI工具失败: %v", err)
+		ltiError(c, http.StatusInternalServerError, "服务器内部错误")
+		return
+	}
+	if tool == nil || tool.ClientID != ltiParam(c, "client_id") {
+		ltiError(c, http.StatusBadRequest, "无效的client_id")
+		return
+	}
+	redirectURI := ltiParam(c, "redirect_uri")
+	if !ltiRedirectAllowed(tool, redirectURI) {
+		ltiError(c, http.StatusBadRequest, "redirect_uri未注册")
+		return
+	}
+
+	authorized, err := ltiRepo.AuthorizeLaunch(ctx, launch.ID, now)
+	if err != nil {
+		log.Printf("更新LTI启动失败: %v", err)
+		ltiError(c, http.StatusInternalServerError, "服务器内部错误")
+		return
+	}
+	if !authorized {
+		ltiError(c, http.StatusBadRequest, "启动已使用，请重新打开工具")
+		return
+	}
+
+	claims, err := buildLTIMessage(ctx, launch, tool, ltiParam(c, "nonce"), now)
+	if err != nil {
+		log.Printf("生成LTI消息失败: %v", err)
+		ltiError(c, http.StatusInternalServerError, "服务器内部错误")
+		return
+	}
+	if claims == nil {
+		ltiError(c, http.StatusBadRequest, "链接或开课已不存在")
+		return
+	}
+	kid, key, err := ltiSigningKey(ctx)
+	if err != nil {
+		log.Printf("获取LTI签名密钥失败: %v", err)
+		ltiError(c, http.StatusInternalServerError, "服务器内部错误")
+		return
+	}
+	idToken, err := lti.Sign(claims, kid, key)
+	if err != nil {
+		log.Printf("签名LTI消息失败: %v", err)
+		ltiError(c, http.StatusInternalServerError, "服务器内部错误")
+		return
+	}
+
+	fields := map[string]string{"id_token": idToken}
+	if state := ltiParam(c, "state"); state != "" {
+		fields["state"] = state
+	}
+	page, err := lti.AutoSubmitForm(redirectURI, fields)
+	if err != nil {
+		log.Printf("生成LTI表单失败: %v", err)
+		ltiError(c, http.StatusInternalServerError, "服务器内部错误")
+		return
+	}
+	ltiHTML(c, http.StatusOK, page)
+}
+
+// ltiLineItemsURL 开课的成绩项容器地址
+func ltiLineItemsURL(offeringID int64) string {
+	return lti.URL(fmt.Sprintf("/ags/offerings/%d/lineitems", offeringID))
+}
+
+// ltiLineItemURL 成绩项地址
+func ltiLineItemURL(offeringID, itemID int64) string {
+	return lti.URL(fmt.Sprintf("/ags/offerings/%d/lineitems/%d", offeringID, itemID))
+}
+
+// buildLTIMessage 生成启动消息的声明，链接、开课或用户已不存在时返回nil
+// 管理开课的用户以教师角色启动，学生以学习者角色启动
+func buildLTIMessage(ctx context.Context, launch *models.LTILaunch, tool *models.LTITool, nonce string, now time.Time) (jwt.MapClaims, error) {
+	user, err := repository.NewUserRepository(database.DB).GetUserByID(ctx, launch.UserID)
+	if err != nil || user == nil {
+		return nil, err
+	}
+	offering, course, err := loadLTIOffering(ctx, launch.OfferingID)
+	if err != nil || offering == nil {
+		return nil, err
+	}
+
+	manage := isAdminRole(user.Role) || course.HasTeacher(user.ID) || offering.HasTeacher(user.ID)
+	roles := []string{lti.RoleLearner}
+	if manage {
+		roles = []string{lti.RoleInstructor}
+	}
+	if isAdminRole(user.Role) {
+		roles = append(roles, lti.RoleAdministrator)
+	}
+
+	claims := jwt.MapClaims{
+		"iss":   lti.Issuer,
+		"aud":   tool.ClientID,
+		"azp":   tool.ClientID,
+		"sub":   strconv.FormatInt(user.ID, 10),
+		"iat":   now.Unix(),
+		"exp":   now.Add(ltiExpiry).Unix(),
+		"nonce": nonce,
+
+		lti.ClaimMessageType:  launch.MessageType,
+		lti.ClaimVersion:      lti.Version,
+		lti.ClaimDeploymentID: tool.DeploymentID,
+		lti.ClaimRoles:        roles,
+		lti.ClaimContext: map[string]interface{}{
+			"id":    strconv.FormatInt(offering.ID, 10),
+			"label": course.Code,
+			"title": course.Name,
+			"type":  []string{lti.ContextCourse},
+		},
+		lti.ClaimToolPlatform:       map[string]interface{}{"guid": lti.Issuer, "name": "EduGo"},
+		lti.ClaimLaunchPresentation: map[string]interface{}{"document_target": "iframe"},
+	}
+	name := user.LastName + user.FirstName
+	if name == "" {
+		name = user.Username
+	}
+	claims["name"] = name
+	claims["given_name"] = user.FirstName
+	claims["family_name"] = user.LastName
+	if user.Email != nil {
+		claims["email"] = *user.Email
+	}
+
+	switch launch.MessageType {
+	case lti.MessageDeepLinkingRequest:
+		claims[lti.ClaimTargetLinkURI] = tool.DeepLinkURL
+		claims[lti.ClaimDeepLinkingSettings] = map[string]interface{}{
+			"deep_link_return_url":                 lti.URL("/deep-links/return"),
+			"accept_types":                         []string{"ltiResourceLink"},
+			"accept_presentation_document_targets": []string{"iframe", "window"},
+			"accept_multiple":                      true,
+			"auto_create":                          true,
+			"data":                                 launch.Token,
+		}
+	default:
+		if launch.LinkID == nil {
+			return nil, nil
+		}
+		link, err := repository.NewLTIRepository(database.DB).GetLinkByID(ctx, *launch.LinkID)
+		if err != nil || link == nil {
+			return nil, err
+		}
+		targetLinkURI := link.URL
+		if targetLinkURI == "" {
+			targetLinkURI = tool.TargetLinkURI
+		}
+		claims[lti.ClaimTargetLinkURI] = targetLinkURI
+		claims[lti.ClaimResourceLink] = map[string]interface{}{"id": link.ResourceLinkID, "title": link.Title}
+		if custom := ltiLinkCustom(link); len(custom) > 0 {
+			claims[lti.ClaimCustom] = custom
+		}
+		endpoint := map[string]interface{}{
+			"scope":     []string{lti.ScopeLineItemReadOnly, lti.ScopeResultReadOnly, lti.ScopeScore},
+			"lineitems": ltiLineItemsURL(offering.ID),
+		}
+		if link.GradeItemID != nil {
+			endpoint["lineitem"] = ltiLineItemURL(offering.ID, *link.GradeItemID)
+		}
+		claims[lti.ClaimAGSEndpoint] = endpoint
+	}
+	return claims, nil
+}
+
+// ltiContentItem 深度链接响应中的一个资源链接
+type ltiContentItem struct {
+	Type     string                 `json:"type"`
+	Title    string                 `json:"title"`
+	URL      string                 `json:"url"`
+	Custom   map[string]interface{} `json:"custom"`
+	LineItem *struct {
+		ScoreMaximum float64 `json:"scoreMaximum"`
+		Label        string  `json:"label"`
+	} `json:"lineItem"`
+}
+
+// ReturnLTIDeepLinks 深度链接的返回地址（公开），工具以自动提交的表单发送签名的JWT
+// 工具返回的资源链接加入发起深度链接的开课，需要评分的内容在发起时指定的分类下创建成绩项
+func ReturnLTIDeepLinks(c *gin.Context) {
+	token := c.PostForm("JWT")
+	clientID, err := lti.UnverifiedIssuer(token)
+	if err != nil || clientID == "" {
+		ltiError(c, http.StatusBadRequest, "无效的深度链接响应")
+		return
+	}
+
+	ctx := c.Request.Context()
+	ltiRepo := repository.NewLTIRepository(database.DB)
+	tool, err := ltiRepo.GetToolByClientID(ctx, clientID)
+	if err != nil {
+		log.Printf("获取LTI工具失败: %v", err)
+		ltiError(c, http.StatusInternalServerError, "服务器内部错误")
+		return
+	}
+	if tool == nil {
+		ltiError(c, http.StatusBadRequest, "工具未注册")
+		return
+	}
+	claims, err := lti.Verify(ctx, token, tool.KeySetURL, tool.ClientID, lti.Issuer)
+	if err != nil {
+		log.Printf("校验LTI深度链接响应失败 (tool=%d): %v", tool.ID, err)
+		ltiError(c, http.StatusBadRequest, "无效的深度链接响应")
+		return
+	}
+	if err := lti.CheckMessage(claims, lti.MessageDeepLinkingResponse); err != nil {
+		ltiError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if lti.String(claims, lti.ClaimDeploymentID) != tool.DeploymentID {
+		ltiError(c, http.StatusBadRequest, "无效的deployment_id")
+		return
+	}
+
+	launch, err := ltiRepo.GetLaunch(ctx, lti.String(claims, lti.ClaimDeepLinkingData))
+	if err != nil {
+		log.Printf("获取LTI启动失败: %v", err)
+		ltiError(c, http.StatusInternalServerError, "服务器内部错误")
+		return
+	}
+	if launch == nil || launch.ToolID != tool.ID || launch.MessageType != lti.MessageDeepLinkingRequest ||
+		launch.AuthorizedAt == nil || time.Now().After(launch.ExpiresAt) {
+		ltiError(c, http.StatusBadRequest, "深度链接已过期，请重新选择内容")
+		return
+	}
+	deleted, err := ltiRepo.DeleteLaunch(ctx, launch.ID)
+	if err != nil {
+		log.Printf("删除LTI启动失败: %v", err)
+		ltiError(c, http.StatusInternalServerError, "服务器内部错误")
+		return
+	}
+	if !deleted {
+		ltiError(c, http.StatusBadRequest, "深度链接已处理")
+		return
+	}
+	if message := lti.String(claims, "https://purl.imsglobal.org/spec/lti-dl/claim/errormsg"); message != "" {
+		ltiHTML(c, http.StatusOK, lti.MessagePage("工具返回错误: "+message))
+		return
+	}
+
+	var items []ltiContentItem
+	if raw, ok := claims[lti.ClaimContentItems]; ok {
+		data, _ := json.Marshal(raw)
+		if err := json.Unmarshal(data, &items); err != nil {
+			ltiError(c, http.StatusBadRequest, "无效的内容")
+			return
+		}
+	}
+
+	links, err := createLTIDeepLinks(ctx, launch, tool, items)
+	if err != nil {
+		var invalid ltiInvalidContent
+		if errors.As(err, &invalid) {
+			ltiError(c, http.StatusBadRequest, invalid.Error())
+			return
+		}
+		log.Printf("保存LTI深度链接失败: %v", err)
+		ltiError(c, http.StatusInternalServerError, "服务器内部错误")
+		return
+	}
+	ltiHTML(c, http.StatusOK, lti.MessagePage(fmt.Sprintf("已添加%d个外部工具链接，可以关闭此页面", len(links))))
+}
+
+// ltiInvalidContent 深度链接返回的内容无效
+type ltiInvalidContent string
+
+func (e ltiInvalidContent) Error() string { return string(e) }
+
+// createLTIDeepLinks 把深度链接返回的资源链接加入开课，其他类型的内容忽略
+func createLTIDeepLinks(ctx context.Context, launch *models.LTILaunch, tool *models.LTITool, items []ltiContentItem) ([]*models.LTILink, error) {
+	gradebookRepo := repository.NewGradebookRepository(database.DB)
+	var category *models.GradeCategory
+	if launch.CategoryID != nil {
+		var err error
+		if category, err = gradebookRepo.GetCategoryByID(ctx, *launch.CategoryID); err != nil {
+			return nil, err
+		}
+	}
+
+	links := make([]*models.LTILink, 0, len(items))
+	for _, item := range items {
+		if item.Type != "ltiResourceLink" {
+			continue
+		}
+		link := &models.LTILink{
+			OfferingID: launch.OfferingID,
+			ToolID:     tool.ID,
+			Title:      strings.TrimSpace(item.Title),
+			URL:        strings.TrimSpace(item.URL),
+			CreatedBy:  launch.UserID,
+		}
+		if link.Title == "" {
+			link.Title = tool.Name
+		}
+		if len(link.Title) > 200 {
+			return nil, ltiInvalidContent("链接标题不能超过200个字符")
+		}
+		if link.URL != "" && (!lti.IsHTTPURL(link.URL) || len(link.URL) > 500) {
+			return nil, ltiInvalidContent("无效的启动地址: " + link.URL)
+		}
+		custom := make(map[string]string, len(item.Custom))
+		for key, value := range item.Custom {
+			if s, ok := value.(string); ok {
+				custom[key] = s
+			} else {
+				custom[key] = fmt.Sprint(value)
+			}
+		}
+		var err error
+		if link.Custom, err = encodeLTICustom(custom); err != nil {
+			return nil, err
+		}
+		if link.ResourceLinkID, err = xapi.NewUUID(); err != nil {
+			return nil, err
+		}
+
+		// 需要评分的内容在发起时指定的分类下创建成绩项，没有指定分类时只添加链接
+		if item.LineItem != nil && category != nil && category.OfferingID == launch.OfferingID {
+			if item.LineItem.ScoreMaximum <= 0 {
+				return nil, ltiInvalidContent("scoreMaximum必须大于0")
+			}
+			gradeItem := &models.GradeItem{
+				OfferingID: launch.OfferingID,
+				CategoryID: category.ID,
+				Title:      strings.TrimSpace(item.LineItem.Label),
+				MaxScore:   item.LineItem.ScoreMaximum,
+				CreatedBy:  launch.UserID,
+			}
+			if gradeItem.Title == "" || len(gradeItem.Title) > 200 {
+				gradeItem.Title = link.Title
+			}
+			if err := gradebookRepo.CreateItem(ctx, gradeItem); err != nil {
+				return nil, err
+			}
+			link.GradeItemID = &gradeItem.ID
+		}
+		links = append(links, link)
+	}
+
+	if err := repository.NewLTIRepository(database.DB).CreateLinks(ctx, links); err != nil {
+		return nil, err
+	}
+	return links, nil
+}
@@ -0,0 +1,471 @@
+package controllers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+
+	"EduGo_servers/internal/database"
+	"EduGo_servers/internal/lti"
+	"EduGo_servers/internal/models"
+	"EduGo_servers/internal/repository"
+)
+
+// ltiStateExpiry 作为工具发起登录后等待平台启动的时间
+const ltiStateExpiry = 10 * time.Minute
+
+// ltiStateCookiePrefix 浏览器中保存state的cookie名前缀，每次发起登录使用单独的cookie，多个标签页同时启动互不影响
+const ltiStateCookiePrefix = "lti_state_"
+
+var (
+	// errLTIEnrollmentClosed 学生启动时外部课程绑定的开课不在选课时间内
+	errLTIEnrollmentClosed = errors.New("lti offering enrollment closed")
+	// errLTIWaitlisted 学生启动时外部课程绑定的开课已满，学生正在候补
+	errLTIWaitlisted = errors.New("lti offering waitlisted")
+)
+
+// ltiHash 外部标识的摘要，用于生成用户名和课程代码
+func ltiHash(platformID int64, value string) string {
+	sum := sha256.Sum256([]byte(strconv.FormatInt(platformID, 10) + ":" + value))
+	return hex.EncodeToString(sum[:])[:12]
+}
+
+// findLTIPlatform 根据签发方和客户端ID确定平台，平台没有传client_id时签发方只能注册了一个平台
+func findLTIPlatform(ctx context.Context, issuer, clientID string) (*models.LTIPlatform, error) {
+	platforms, err := repository.NewLTIRepository(database.DB).GetPlatformsByIssuer(ctx, issuer)
+	if err != nil {
+		return nil, err
+	}
+	if clientID == "" {
+		if len(platforms) == 1 {
+			return platforms[0], nil
+		}
+		return nil, nil
+	}
+	for _, platform := range platforms {
+		if platform.ClientID == clientID {
+			return platform, nil
+		}
+	}
+	return nil, nil
+}
+
+// setLTIStateCookie 设置只发送到启动地址的state cookie，maxAge为负数时删除
+// 平台通过跨站表单提交启动，cookie需要SameSite=None，浏览器要求这时同时设置Secure
+func setLTIStateCookie(c *gin.Context, state string, maxAge int) {
+	path := "/"
+	if launch, err := url.Parse(lti.URL("/launch")); err == nil && launch.Path != "" {
+		path = launch.Path
+	}
+	c.SetSameSite(http.SameSiteNoneMode)
+	c.SetCookie(ltiStateCookiePrefix+state, state, maxAge, path, "", true, true)
+}
+
+// InitiateLTILogin 作为工具的OIDC登录发起地址（公开），平台启动EduGo时浏览器先到这里
+// 保存state和nonce，并在浏览器cookie中保存state，然后重定向到平台的授权地址
+func InitiateLTILogin(c *gin.Context) {
+	issuer := ltiParam(c, "iss")
+	loginHint := ltiParam(c, "login_hint")
+	if issuer == "" || loginHint == "" {
+		ltiError(c, http.StatusBadRequest, "缺少iss或login_hint")
+		return
+	}
+
+	ctx := c.Request.Context()
+	platform, err := findLTIPlatform(ctx, issuer, ltiParam(c, "client_id"))
+	if err != nil {
+		log.Printf("获取LTI平台失败: %v", err)
+		ltiError(c, http.StatusInternalServerError, "服务器内部错误")
+		return
+	}
+	if platform == nil {
+		ltiError(c, http.StatusBadRequest, "平台未注册")
+		return
+	}
+	if deploymentID := ltiParam(c, "lti_deployment_id"); deploymentID != "" &&
+		platform.DeploymentID != "" && deploymentID != platform.DeploymentID {
+		ltiError(c, http.StatusBadRequest, "无效的deployment_id")
+		return
+	}
+
+	state, err := randomHex(16)
+	if err != nil {
+		log.Printf("生成LTI state失败: %v", err)
+		ltiError(c, http.StatusInternalServerError, "服务器内部错误")
+		return
+	}
+	nonce, err := randomHex(16)
+	if err != nil {
+		log.Printf("生成LTI nonce失败: %v", err)
+		ltiError(c, http.StatusInternalServerError, "服务器内部错误")
+		return
+	}
+	if err := repository.NewLTIRepository(database.DB).CreateState(ctx, &models.LTIState{
+		State:      state,
+		Nonce:      nonce,
+		PlatformID: platform.ID,
+		ExpiresAt:  time.Now().Add(ltiStateExpiry),
+	}); err != nil {
+		log.Printf("保存LTI state失败: %v", err)
+		ltiError(c, http.StatusInternalServerError, "服务器内部错误")
+		return
+	}
+
+	setLTIStateCookie(c, state, int(ltiStateExpiry/time.Second))
+
+	params := url.Values{}
+	params.Set("scope", "openid")
+	params.Set("response_type", "id_token")
+	params.Set("response_mode", "form_post")
+	params.Set("prompt", "none")
+	params.Set("client_id", platform.ClientID)
+	params.Set("redirect_uri", lti.URL("/launch"))
+	params.Set("login_hint", loginHint)
+	params.Set("state", state)
+	params.Set("nonce", nonce)
+	if hint := ltiParam(c, "lti_message_hint"); hint != "" {
+		params.Set("lti_message_hint", hint)
+	}
+	c.Header("Cache-Control", "no-store")
+	c.Redirect(http.StatusFound, lti.WithQuery(platform.AuthLoginURL, params))
+}
+
+// HandleLTILaunch 作为工具的启动地址（公开），校验平台签发的id_token后登录对应的EduGo用户
+// 首次启动时创建用户和课程，外部课程绑定开课后学生自动选课，最后带着登录令牌跳转到前端
+func HandleLTILaunch(c *gin.Context) {
+	// state必须由这个浏览器发起登录，防止把攻击者发起的启动提交到别人的浏览器中登录攻击者的账号
+	value := c.PostForm("state")
+	if cookie, err := c.Cookie(ltiStateCookiePrefix + value); err != nil || value == "" || cookie != value {
+		ltiError(c, http.StatusBadRequest, "启动已过期，请从平台重新打开")
+		return
+	}
+	setLTIStateCookie(c, value, -1)
+
+	ctx := c.Request.Context()
+	ltiRepo := repository.NewLTIRepository(database.DB)
+	state, err := ltiRepo.TakeState(ctx, value, time.Now())
+	if err != nil {
+		log.Printf("获取LTI state失败: %v", err)
+		ltiError(c, http.StatusInternalServerError, "服务器内部错误")
+		return
+	}
+	if state == nil {
+		ltiError(c, http.StatusBadRequest, "启动已过期，请从平台重新打开")
+		return
+	}
+	platform, err := ltiRepo.GetPlatformByID(ctx, state.PlatformID)
+	if err != nil {
+		log.Printf("获取LTI平台失败: %v", err)
+		ltiError(c, http.StatusInternalServerError, "服务器内部错误")
+		return
+	}
+	if platform == nil {
+		ltiError(c, http.StatusBadRequest, "平台未注册")
+		return
+	}
+
+	claims, err := lti.Verify(ctx, c.PostForm("id_token"), platform.KeySetURL, platform.Issuer, platform.ClientID)
+	if err != nil {
+		log.Printf("校验LTI启动失败 (platform=%d): %v", platform.ID, err)
+		ltiError(c, http.StatusBadRequest, "无效的id_token")
+		return
+	}
+	if lti.String(claims, "nonce") != state.Nonce {
+		ltiError(c, http.StatusBadRequest, "无效的nonce")
+		return
+	}
+	if err := lti.CheckMessage(claims, lti.MessageResourceLink); err != nil {
+		ltiError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if platform.DeploymentID != "" && lti.String(claims, lti.ClaimDeploymentID) != platform.DeploymentID {
+		ltiError(c, http.StatusBadRequest, "无效的deployment_id")
+		return
+	}
+	subject, _ := claims.GetSubject()
+	if subject == "" {
+		ltiError(c, http.StatusBadRequest, "不支持匿名启动")
+		return
+	}
+
+	instructor := lti.IsInstructor(lti.Strings(claims, lti.ClaimRoles))
+	user, err := ltiLaunchUser(ctx, platform, subject, claims, instructor)
+	if err != nil {
+		log.Printf("创建LTI用户失败: %v", err)
+		ltiError(c, http.StatusInternalServerError, "服务器内部错误")
+		return
+	}
+	if user == nil || user.Status != models.UserStatusActive {
+		ltiError(c, http.StatusForbidden, "账号已停用，请联系管理员")
+		return
+	}
+
+	fragment := url.Values{}
+	if contextClaim := lti.Object(claims, lti.ClaimContext); contextClaim != nil {
+		ltiContext, err := ltiLaunchContext(ctx, platform, contextClaim, user, instructor)
+		if errors.Is(err, errLTIEnrollmentClosed) {
+			ltiError(c, http.StatusForbidden, "课程不在选课时间内，无法加入")
+			return
+		}
+		if errors.Is(err, repository.ErrOfferingFull) {
+			ltiError(c, http.StatusConflict, "课程名额已满，无法加入")
+			return
+		}
+		if errors.Is(err, errLTIWaitlisted) {
+			ltiError(c, http.StatusConflict, "课程名额已满，已进入候补，选上后再从平台进入")
+			return
+		}
+		if err != nil {
+			log.Printf("同步LTI课程失败: %v", err)
+			ltiError(c, http.StatusInternalServerError, "服务器内部错误")
+			return
+		}
+		if ltiContext != nil {
+			fragment.Set("course_id", strconv.FormatInt(ltiContext.CourseID, 10))
+			if ltiContext.OfferingID != nil {
+				fragment.Set("offering_id", strconv.FormatInt(*ltiContext.OfferingID, 10))
+			}
+		}
+	}
+
+	token, err := generateJWT(user.ID, user.Username)
+	if err != nil {
+		log.Printf("生成JWT失败: %v", err)
+		ltiError(c, http.StatusInternalServerError, "服务器内部错误")
+		return
+	}
+	record := &models.LoginRecord{
+		UserID:    user.ID,
+		IP:        c.ClientIP(),
+		UserAgent: truncate(c.Request.UserAgent(), 255),
+		CreatedAt: time.Now(),
+	}
+	if err := repository.NewUserRepository(database.DB).CreateLoginRecord(ctx, record); err != nil {
+		log.Printf("记录登录历史失败: %v", err)
+	}
+
+	// 令牌放在片段中，不会出现在服务器日志和Referer里
+	fragment.Set("token", token)
+	c.Header("Cache-Control", "no-store")
+	c.Redirect(http.StatusFound, lti.FrontendURL+"/lti/launch#"+fragment.Encode())
+}
+
+// ltiLaunchUser 获取外部用户对应的EduGo用户，首次启动时创建，用户已删除时返回nil
+// 新用户没有邮箱，密码随机，只能通过平台启动登录，教师角色的外部用户创建为教师
+func ltiLaunchUser(ctx context.Context, platform *models.LTIPlatform, subject string, claims jwt.MapClaims, instructor bool) (*models.User, error) {
+	ltiRepo := repository.NewLTIRepository(database.DB)
+	ltiUser, err := ltiRepo.GetLTIUser(ctx, platform.ID, subject)
+	if err != nil {
+		return nil, err
+	}
+	if ltiUser != nil {
+		return ltiUser.User, nil
+	}
+
+	password, err := randomHex(32)
+	if err != nil {
+		return nil, err
+	}
+	user := &models.User{
+		Username:  fmt.Sprintf("lti_%d_%s", platform.ID, ltiHash(platform.ID, subject)),
+		Role:      models.RoleStudent,
+		Status:    models.UserStatusActive,
+		FirstName: truncate(strings.TrimSpace(lti.String(claims, "given_name")), 50),
+		LastName:  truncate(strings.TrimSpace(lti.String(claims, "family_name")), 50),
+	}
+	if user.FirstName == "" && user.LastName == "" {
+		user.FirstName = truncate(strings.TrimSpace(lti.String(claims, "name")), 50)
+	}
+	if instructor {
+		user.Role = models.RoleTeacher
+	}
+	if err := user.HashPassword(password); err != nil {
+		return nil, err
+	}
+	ltiUser = &models.LTIUser{PlatformID: platform.ID, Subject: subject}
+	if err := ltiRepo.CreateLTIUser(ctx, user, ltiUser); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// ltiLaunchContext 获取外部课程对应的EduGo课程，首次启动时创建；教师启动时加入课程的负责教师，
+// 学生启动时如果外部课程已绑定开课则按选课规则自动选课，没有选上时返回对应的错误。课程已删除时返回nil
+func ltiLaunchContext(ctx context.Context, platform *models.LTIPlatform, claim map[string]interface{}, user *models.User, instructor bool) (*models.LTIContext, error) {
+	contextID, _ := claim["id"].(string)
+	if contextID == "" {
+		return nil, nil
+	}
+	title, _ := claim["title"].(string)
+	if title = strings.TrimSpace(title); title == "" {
+		title, _ = claim["label"].(string)
+		title = strings.TrimSpace(title)
+	}
+
+	ltiRepo := repository.NewLTIRepository(database.DB)
+	ltiContext, err := ltiRepo.GetContext(ctx, platform.ID, contextID)
+	if err != nil {
+		return nil, err
+	}
+	if ltiContext == nil {
+		course := &models.Course{
+			Code:      "LTI-" + strings.ToUpper(ltiHash(platform.ID, contextID)),
+			Name:      truncate(title, 100),
+			CreatedBy: user.ID,
+		}
+		if course.Name == "" {
+			course.Name = platform.Name + "课程"
+		}
+		ltiContext = &models.LTIContext{PlatformID: platform.ID, ContextID: contextID, Title: truncate(title, 200)}
+		if err := ltiRepo.CreateContext(ctx, course, ltiContext); err != nil {
+			return nil, err
+		}
+	}
+
+	courseRepo := repository.NewCourseRepository(database.DB)
+	course, err := courseRepo.GetCourseByID(ctx, ltiContext.CourseID)
+	if err != nil || course == nil {
+		return nil, err
+	}
+
+	switch {
+	case instructor && user.Role == models.RoleTeacher:
+		if !course.HasTeacher(user.ID) {
+			if err := courseRepo.ReplaceCourseTeachers(ctx, course, append(course.Teachers, *user)); err != nil {
+				return nil, err
+			}
+		}
+	case !instructor && user.Role == models.RoleStudent && ltiContext.OfferingID != nil:
+		offering, err := repository.NewCourseOfferingRepository(database.DB).GetOfferingByID(ctx, *ltiContext.OfferingID)
+		if err != nil {
+			return nil, err
+		}
+		if offering == nil {
+			break
+		}
+		enrolled, err := isOfferingStudent(ctx, offering.ID, user.ID)
+		if err != nil {
+			return nil, err
+		}
+		if enrolled {
+			break
+		}
+
+		// 与学生自己选课的规则相同，已在候补中的学生再次启动时Enroll返回ErrAlreadyEnrolled
+		now := time.Now()
+		if !offering.EnrollmentOpen(now) {
+			return nil, errLTIEnrollmentClosed
+		}
+		enrollment, err := repository.NewEnrollmentRepository(database.DB).Enroll(ctx, offering, user.ID, now)
+		if errors.Is(err, repository.ErrAlreadyEnrolled) {
+			return nil, errLTIWaitlisted
+		}
+		if err != nil {
+			return nil, err
+		}
+		if enrollment.Status == models.EnrollmentWaitlisted {
+			return nil, errLTIWaitlisted
+		}
+	}
+	return ltiContext, nil
+}
+
+// GetLTIPlatformContexts 获取平台启动时创建的课程对应关系（管理员）
+func GetLTIPlatformContexts(c *gin.Context) {
+	platform, ok := getLTIPlatformParam(c)
+	if !ok {
+		return
+	}
+
+	contexts, err := repository.NewLTIRepository(database.DB).GetPlatformContexts(c.Request.Context(), platform.ID)
+	if err != nil {
+		log.Printf("获取LTI课程失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+
+	result := make([]gin.H, 0, len(contexts))
+	for _, ltiContext := range contexts {
+		result = append(result, gin.H{
+			"id":          ltiContext.ID,
+			"context_id":  ltiContext.ContextID,
+			"title":       ltiContext.Title,
+			"course_id":   ltiContext.CourseID,
+			"offering_id": ltiContext.OfferingID,
+			"created_at":  ltiContext.CreatedAt,
+		})
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"platform_id": platform.ID,
+		"contexts":    result,
+	})
+}
+
+// UpdateLTIContext 把外部课程绑定到课程的开课（管理员），学生之后启动时自动选课；offering_id为0时解除绑定
+func UpdateLTIContext(c *gin.Context) {
+	contextID, ok := parseIDParam(c, "id", "无效的课程对应关系ID")
+	if !ok {
+		return
+	}
+
+	var input struct {
+		OfferingID *int64 `json:"offering_id" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的输入数据"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	ltiRepo := repository.NewLTIRepository(database.DB)
+	ltiContext, err := ltiRepo.GetContextByID(ctx, contextID)
+	if err != nil {
+		log.Printf("获取LTI课程失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+	if ltiContext == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "课程对应关系不存在"})
+		return
+	}
+
+	if *input.OfferingID == 0 {
+		ltiContext.OfferingID = nil
+	} else {
+		offering, err := repository.NewCourseOfferingRepository(database.DB).GetOfferingByID(ctx, *input.OfferingID)
+		if err != nil {
+			log.Printf("获取开课失败: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+			return
+		}
+		if offering == nil || offering.CourseID != ltiContext.CourseID {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "开课不存在或不属于对应的课程"})
+			return
+		}
+		ltiContext.OfferingID = &offering.ID
+	}
+
+	if err := ltiRepo.UpdateContext(ctx, ltiContext); err != nil {
+		log.Printf("修改LTI课程失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":     "课程对应关系修改成功",
+		"id":          ltiContext.ID,
+		"course_id":   ltiContext.CourseID,
+		"offering_id": ltiContext.OfferingID,
+	})
+}
@@ -0,0 +1,368 @@
+package controllers
+
+import (
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"EduGo_servers/internal/database"
+	"EduGo_servers/internal/jobs"
+	"EduGo_servers/internal/mastery"
+	"EduGo_servers/internal/models"
+	"EduGo_servers/internal/repository"
+)
+
+const maxWeakPoints = 10 // 每个学科最多列出的薄弱知识点数
+
+// masteryResponse 构造学生在一个知识点上的掌握度，没有作答记录时为nil
+func masteryResponse(record *models.StudentMastery) gin.H {
+	if record == nil {
+		return nil
+	}
+	return gin.H{
+		"probability":      record.Probability,
+		"level":            mastery.Level(record.Probability, record.Observations),
+		"observations":     record.Observations,
+		"correct":          record.Correct,
+		"last_observed_at": record.LastObservedAt,
+	}
+}
+
+// masterySummaryResponse 构造知识点连同下级知识点的汇总掌握情况
+func masterySummaryResponse(summary mastery.Summary) gin.H {
+	response := gin.H{
+		"probability":  nil,
+		"level":        summary.Level(),
+		"assessed":     summary.Assessed,
+		"observations": summary.Observations,
+	}
+	if summary.Assessed > 0 {
+		response["probability"] = summary.Probability
+	}
+	return response
+}
+
+// studentSubjectMastery 构造学生在一个学科上的掌握情况：知识点树、已掌握和薄弱的知识点
+func studentSubjectMastery(subject string, points []*models.KnowledgePoint, masteries map[int64]*models.StudentMastery) gin.H {
+	summaries := mastery.Rollup(points, masteries)
+	tree := knowledgeTree(points, func(point *models.KnowledgePoint) gin.H {
+		return gin.H{
+			"id":        point.ID,
+			"parent_id": point.ParentID,
+			"name":      point.Name,
+			"mastery":   masteryResponse(masteries[point.ID]),
+			"summary":   masterySummaryResponse(summaries[point.ID]),
+		}
+	})
+
+	assessed, mastered := 0, 0
+	var weak []*models.KnowledgePoint
+	for _, point := range points {
+		record, ok := masteries[point.ID]
+		if !ok {
+			continue
+		}
+		assessed++
+		switch mastery.Level(record.Probability, record.Observations) {
+		case models.MasteryMastered:
+			mastered++
+		case models.MasteryWeak:
+			weak = append(weak, point)
+		}
+	}
+	sort.SliceStable(weak, func(i, j int) bool {
+		return masteries[weak[i].ID].Probability < masteries[weak[j].ID].Probability
+	})
+	if len(weak) > maxWeakPoints {
+		weak = weak[:maxWeakPoints]
+	}
+	weakPoints := make([]gin.H, 0, len(weak))
+	for _, point := range weak {
+		item := knowledgePointSummary(point)
+		item["probability"] = masteries[point.ID].Probability
+		weakPoints = append(weakPoints, item)
+	}
+
+	return gin.H{
+		"subject":          subject,
+		"total":            len(points),
+		"assessed":         assessed,
+		"mastered":         mastered,
+		"weak_points":      weakPoints,
+		"knowledge_points": tree,
+	}
+}
+
+// GetStudentMastery 获取学生的知识点掌握情况（本人、家长、管理员或该学生的教师）
+// 指定subject时只返回该学科，否则返回学生有作答记录的全部学科
+func GetStudentMastery(c *gin.Context) {
+	studentID, ok := parseIDParam(c, "id", "无效的学生ID")
+	if !ok {
+		return
+	}
+	allowed, err := canViewStudentData(c, studentID)
+	if err != nil {
+		log.Printf("检查用户关系失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+	if !allowed {
+		c.JSON(http.StatusForbidden, gin.H{"error": "无权查看该学生的知识点掌握情况"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	masteryRepo := repository.NewMasteryRepository(database.DB)
+	pointRepo := repository.NewKnowledgePointRepository(database.DB)
+	var subjects []string
+	if subject := strings.TrimSpace(c.Query("subject")); subject != "" {
+		subjects = []string{subject}
+	} else if subjects, err = masteryRepo.GetStudentSubjects(ctx, studentID); err != nil {
+		log.Printf("获取学生的学科失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+
+	result := make([]gin.H, 0, len(subjects))
+	for _, subject := range subjects {
+		points, err := pointRepo.GetSubjectKnowledgePoints(ctx, subject)
+		if err != nil {
+			log.Printf("获取知识点失败: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+			return
+		}
+		pointIDs := make([]int64, 0, len(points))
+		for _, point := range points {
+			pointIDs = append(pointIDs, point.ID)
+		}
+		masteries := map[int64]*models.StudentMastery{}
+		if len(pointIDs) > 0 {
+			records, err := masteryRepo.GetStudentMasteries(ctx, []int64{studentID}, pointIDs)
+			if err != nil {
+				log.Printf("获取知识点掌握度失败: %v", err)
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+				return
+			}
+			for _, record := range records {
+				masteries[record.KnowledgePointID] = record
+			}
+		}
+		result = append(result, studentSubjectMastery(subject, points, masteries))
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"student_id": studentID,
+		"subjects":   result,
+	})
+}
+
+// GetStudentKnowledgePointMastery 获取学生在一个知识点上的作答记录和掌握度的变化（本人、家长、管理员或该学生的教师）
+func GetStudentKnowledgePointMastery(c *gin.Context) {
+	studentID, ok := parseIDParam(c, "id", "无效的学生ID")
+	if !ok {
+		return
+	}
+	allowed, err := canViewStudentData(c, studentID)
+	if err != nil {
+		log.Printf("检查用户关系失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+	if !allowed {
+		c.JSON(http.StatusForbidden, gin.H{"error": "无权查看该学生的知识点掌握情况"})
+		return
+	}
+	point, ok := getKnowledgePointParam(c, "knowledge_point_id")
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	observations, err := repository.NewMasteryRepository(database.DB).GetObservations(ctx, studentID, []int64{point.ID}, 0)
+	if err != nil {
+		log.Printf("获取作答记录失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+
+	// 按时间顺序重放，得到每次作答后的掌握概率
+	params := mastery.DefaultParams
+	probability := params.Init
+	history := make([]gin.H, 0, len(observations))
+	var record *models.StudentMastery
+	for _, observation := range observations {
+		probability = params.Update(probability, mastery.Observation{Correct: observation.Correct, Guess: observation.Guess})
+		history = append(history, gin.H{
+			"attempt_id":  observation.AttemptID,
+			"question_id": observation.QuestionID,
+			"correct":     observation.Correct,
+			"observed_at": observation.ObservedAt,
+			"probability": probability,
+		})
+		if record == nil {
+			record = &models.StudentMastery{StudentID: studentID, KnowledgePointID: point.ID}
+		}
+		record.Probability = probability
+		record.Observations++
+		if observation.Correct {
+			record.Correct++
+		}
+		record.LastObservedAt = observation.ObservedAt
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"student_id":      studentID,
+		"knowledge_point": knowledgePointResponse(point),
+		"mastery":         masteryResponse(record),
+		"history":         history,
+	})
+}
+
+// GetOfferingMastery 获取开课学生的知识点掌握情况（管理员或课程教师），默认使用课程的学科
+// 每个知识点统计各掌握程度的学生数，每个学生统计已掌握和薄弱的知识点数
+func GetOfferingMastery(c *gin.Context) {
+	offering, ok := getManagedOffering(c)
+	if !ok {
+		return
+	}
+	subject := strings.TrimSpace(c.Query("subject"))
+	if subject == "" && offering.Course != nil {
+		subject = offering.Course.Subject
+	}
+	if subject == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请指定学科"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	points, err := repository.NewKnowledgePointRepository(database.DB).GetSubjectKnowledgePoints(ctx, subject)
+	if err != nil {
+		log.Printf("获取知识点失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+	enrollments, err := repository.NewEnrollmentRepository(database.DB).GetOfferingEnrollments(ctx, offering.ID, models.EnrollmentEnrolled)
+	if err != nil {
+		log.Printf("获取选课名单失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+
+	studentIDs := make([]int64, 0, len(enrollments))
+	for _, enrollment := range enrollments {
+		studentIDs = append(studentIDs, enrollment.StudentID)
+	}
+	pointIDs := make([]int64, 0, len(points))
+	for _, point := range points {
+		pointIDs = append(pointIDs, point.ID)
+	}
+	byStudent := map[int64]map[int64]*models.StudentMastery{}
+	if len(pointIDs) > 0 {
+		records, err := repository.NewMasteryRepository(database.DB).GetStudentMasteries(ctx, studentIDs, pointIDs)
+		if err != nil {
+			log.Printf("获取知识点掌握度失败: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+			return
+		}
+		for _, record := range records {
+			if byStudent[record.StudentID] == nil {
+				byStudent[record.StudentID] = map[int64]*models.StudentMastery{}
+			}
+			byStudent[record.StudentID][record.KnowledgePointID] = record
+		}
+	}
+
+	// 每个知识点按学生汇总后的掌握程度分布
+	type pointStats struct {
+		sum      float64
+		assessed int
+		levels   map[string]int
+	}
+	stats := make(map[int64]*pointStats, len(points))
+	for _, point := range points {
+		stats[point.ID] = &pointStats{levels: map[string]int{}}
+	}
+	students := make([]gin.H, 0, len(enrollments))
+	for _, enrollment := range enrollments {
+		masteries := byStudent[enrollment.StudentID]
+		for id, summary := range mastery.Rollup(points, masteries) {
+			s := stats[id]
+			s.levels[summary.Level()]++
+			if summary.Assessed > 0 {
+				s.sum += summary.Probability
+				s.assessed++
+			}
+		}
+
+		var sum float64
+		mastered, weak := 0, 0
+		for _, record := range masteries {
+			sum += record.Probability
+			switch mastery.Level(record.Probability, record.Observations) {
+			case models.MasteryMastered:
+				mastered++
+			case models.MasteryWeak:
+				weak++
+			}
+		}
+		item := gin.H{
+			"student_id":  enrollment.StudentID,
+			"assessed":    len(masteries),
+			"mastered":    mastered,
+			"weak":        weak,
+			"probability": nil, // 有作答记录的知识点掌握概率的平均值
+		}
+		if len(masteries) > 0 {
+			item["probability"] = sum / float64(len(masteries))
+		}
+		if enrollment.Student != nil {
+			item["student"] = userSummary(enrollment.Student)
+		}
+		students = append(students, item)
+	}
+
+	tree := knowledgeTree(points, func(point *models.KnowledgePoint) gin.H {
+		s := stats[point.ID]
+		item := gin.H{
+			"id":          point.ID,
+			"parent_id":   point.ParentID,
+			"name":        point.Name,
+			"probability": nil, // 有作答记录的学生汇总掌握概率的平均值
+			"assessed":    s.assessed,
+			"mastered":    s.levels[models.MasteryMastered],
+			"developing":  s.levels[models.MasteryDeveloping],
+			"weak":        s.levels[models.MasteryWeak],
+			"not_started": s.levels[models.MasteryNotStarted],
+		}
+		if s.assessed > 0 {
+			item["probability"] = s.sum / float64(s.assessed)
+		}
+		return item
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"offering_id":      offering.ID,
+		"subject":          subject,
+		"student_count":    len(enrollments),
+		"knowledge_points": tree,
+		"students":         students,
+	})
+}
+
+// RebuildMastery 按题目当前关联的知识点重新计算全部学生的掌握度（管理员），计算在后台进行
+func RebuildMastery(c *gin.Context) {
+	count, err := repository.NewMasteryRepository(database.DB).ResetAttempts(c.Request.Context())
+	if err != nil {
+		log.Printf("重置掌握度计算状态失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+	jobs.NotifyMasteryUpdate()
+	c.JSON(http.StatusAccepted, gin.H{
+		"message":  "掌握度重新计算已开始",
+		"attempts": count,
+	})
+}
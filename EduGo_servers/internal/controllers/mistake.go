@@ -0,0 +1,414 @@
+package controllers
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"EduGo_servers/internal/assessment"
+	"EduGo_servers/internal/database"
+	"EduGo_servers/internal/mastery"
+	"EduGo_servers/internal/models"
+	"EduGo_servers/internal/repository"
+)
+
+const (
+	defaultPracticeQuestions = 10 // 每次重练默认的题数
+	maxPracticeQuestions     = 50
+)
+
+// mistakeResponse 构造错题的返回数据
+// reveal为true时题目包含正确答案和解析：学生和家长只有在来源试卷允许显示答案时才能看到，试卷删除后不再显示
+func mistakeResponse(entry *models.MistakeEntry, reveal bool) gin.H {
+	response := gin.H{
+		"id":               entry.ID,
+		"student_id":       entry.StudentID,
+		"question_id":      entry.QuestionID,
+		"assessment_id":    entry.AssessmentID,
+		"attempt_id":       entry.AttemptID,
+		"response":         entry.Responses(),
+		"wrong_count":      entry.WrongCount,
+		"correct_streak":   entry.CorrectStreak,
+		"status":           entry.Status,
+		"last_wrong_at":    entry.LastWrongAt,
+		"last_answered_at": entry.LastAnsweredAt,
+		"resolved_at":      entry.ResolvedAt,
+		"answers_visible":  reveal,
+		"created_at":       entry.CreatedAt,
+	}
+	if entry.Assessment != nil {
+		response["assessment_title"] = entry.Assessment.Title
+	}
+	if entry.Question != nil {
+		response["question"] = questionResponse(entry.Question, reveal)
+	}
+	return response
+}
+
+// canRevealMistake 检查当前用户能否看到错题的正确答案和解析，教师和管理员总是可以看到
+func canRevealMistake(c *gin.Context, entry *models.MistakeEntry) bool {
+	role := c.GetString("role")
+	if role == models.RoleTeacher || isAdminRole(role) {
+		return true
+	}
+	return entry.Assessment != nil && entry.Assessment.ShowAnswers
+}
+
+// practiceResponse 构造错题重练的返回数据，已作答的题目给出对错、正确答案和解析
+func practiceResponse(practice *models.MistakePractice) gin.H {
+	items := make([]gin.H, 0, len(practice.Items))
+	for _, item := range practice.Items {
+		answered := item.AnsweredAt != nil
+		entry := gin.H{
+			"position":    item.Position,
+			"question_id": item.QuestionID,
+			"answered":    answered,
+		}
+		if answered {
+			entry["response"] = item.Responses()
+			entry["correct"] = item.Correct
+			entry["answered_at"] = item.AnsweredAt
+		}
+		if item.Question != nil {
+			entry["question"] = questionResponse(item.Question, answered)
+		}
+		items = append(items, entry)
+	}
+	return gin.H{
+		"id":             practice.ID,
+		"student_id":     practice.StudentID,
+		"status":         practice.Status,
+		"subject":        practice.Subject,
+		"question_count": practice.QuestionCount,
+		"correct_count":  practice.CorrectCount,
+		"items":          items,
+		"completed_at":   practice.CompletedAt,
+		"created_at":     practice.CreatedAt,
+	}
+}
+
+// GetMistakes 查询学生的错题本（本人、家长、管理员或该学生的教师），未指定student_id时为当前用户
+// 默认只返回待复习的错题，status=resolved查询已移出的错题，status=all查询全部
+func GetMistakes(c *gin.Context) {
+	query := &repository.MistakeListQuery{
+		StudentID: c.GetInt64("userID"),
+		Status:    models.MistakeActive,
+		Subject:   strings.TrimSpace(c.Query("subject")),
+	}
+	if studentID := c.Query("student_id"); studentID != "" {
+		id, err := strconv.ParseInt(studentID, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的学生ID"})
+			return
+		}
+		query.StudentID = id
+	}
+	switch status := c.Query("status"); status {
+	case "":
+	case "all":
+		query.Status = ""
+	case models.MistakeActive, models.MistakeResolved:
+		query.Status = status
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的错题状态"})
+		return
+	}
+
+	allowed, err := canViewStudentData(c, query.StudentID)
+	if err != nil {
+		log.Printf("检查用户关系失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+	if !allowed {
+		c.JSON(http.StatusForbidden, gin.H{"error": "无权查看该学生的错题本"})
+		return
+	}
+	knowledgePointIDs, ok := knowledgePointFilter(c)
+	if !ok {
+		return
+	}
+	query.KnowledgePointIDs = knowledgePointIDs
+	query.Page, _ = strconv.Atoi(c.Query("page"))
+	query.PageSize, _ = strconv.Atoi(c.Query("page_size"))
+
+	entries, total, err := repository.NewMistakeRepository(database.DB).ListEntries(c.Request.Context(), query)
+	if err != nil {
+		log.Printf("获取错题失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+
+	mistakeList := []gin.H{}
+	for _, entry := range entries {
+		mistakeList = append(mistakeList, mistakeResponse(entry, canRevealMistake(c, entry)))
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"student_id": query.StudentID,
+		"mistakes":   mistakeList,
+		"pagination": gin.H{
+			"page":     query.Page,
+			"pageSize": query.PageSize,
+			"total":    total,
+		},
+	})
+}
+
+// DeleteMistake 学生把一道题从自己的错题本中删除，之后再次答错时会重新记入
+func DeleteMistake(c *gin.Context) {
+	entryID, ok := parseIDParam(c, "id", "无效的错题ID")
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	mistakeRepo := repository.NewMistakeRepository(database.DB)
+	entry, err := mistakeRepo.GetEntryByID(ctx, entryID)
+	if err != nil {
+		log.Printf("获取错题失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+	if entry == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "错题不存在"})
+		return
+	}
+	if entry.StudentID != c.GetInt64("userID") {
+		c.JSON(http.StatusForbidden, gin.H{"error": "只能删除自己的错题"})
+		return
+	}
+
+	if err := mistakeRepo.DeleteEntry(ctx, entry.ID); err != nil {
+		log.Printf("删除错题失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "错题已删除"})
+}
+
+// CreateMistakePractice 学生从自己的错题本中抽题重练，可以按学科和知识点筛选
+// 只抽取来源试卷允许显示答案的客观题，连续答对次数少的和较久没有练习的优先
+func CreateMistakePractice(c *gin.Context) {
+	if c.GetString("role") != models.RoleStudent {
+		c.JSON(http.StatusForbidden, gin.H{"error": "只有学生可以重练错题"})
+		return
+	}
+	var input struct {
+		Subject          string `json:"subject"`
+		KnowledgePointID *int64 `json:"knowledge_point_id"`
+		Count            int    `json:"count"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的输入数据"})
+		return
+	}
+	if input.Count == 0 {
+		input.Count = defaultPracticeQuestions
+	}
+	if input.Count < 0 || input.Count > maxPracticeQuestions {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "题数必须在1到" + strconv.Itoa(maxPracticeQuestions) + "之间"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	var knowledgePointIDs []int64
+	if input.KnowledgePointID != nil {
+		pointRepo := repository.NewKnowledgePointRepository(database.DB)
+		point, err := pointRepo.GetKnowledgePointByID(ctx, *input.KnowledgePointID)
+		if err != nil {
+			log.Printf("获取知识点失败: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+			return
+		}
+		if point == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "知识点不存在"})
+			return
+		}
+		points, err := pointRepo.GetSubjectKnowledgePoints(ctx, point.Subject)
+		if err != nil {
+			log.Printf("获取知识点失败: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+			return
+		}
+		knowledgePointIDs = mastery.Descendants(points, point.ID)
+	}
+
+	studentID := c.GetInt64("userID")
+	subject := strings.TrimSpace(input.Subject)
+	mistakeRepo := repository.NewMistakeRepository(database.DB)
+	entries, err := mistakeRepo.GetPracticeEntries(ctx, studentID, subject, knowledgePointIDs, input.Count)
+	if err != nil {
+		log.Printf("获取可重练的错题失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+	if len(entries) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "没有可以重练的错题"})
+		return
+	}
+
+	practice := &models.MistakePractice{
+		StudentID:     studentID,
+		Status:        models.MistakePracticeInProgress,
+		Subject:       subject,
+		QuestionCount: len(entries),
+	}
+	for i, entry := range entries {
+		practice.Items = append(practice.Items, models.MistakePracticeItem{
+			EntryID:    entry.ID,
+			QuestionID: entry.QuestionID,
+			Position:   i + 1,
+		})
+	}
+	if err := mistakeRepo.CreatePractice(ctx, practice); err != nil {
+		log.Printf("创建错题重练失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+	if practice, err = mistakeRepo.GetPracticeByID(ctx, practice.ID); err != nil || practice == nil {
+		log.Printf("获取错题重练失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, practiceResponse(practice))
+}
+
+// getOwnPractice 获取路径参数指定的错题重练，只有学生本人可以访问。失败时已写入响应
+func getOwnPractice(c *gin.Context) (*models.MistakePractice, bool) {
+	practiceID, ok := parseIDParam(c, "id", "无效的重练ID")
+	if !ok {
+		return nil, false
+	}
+
+	practice, err := repository.NewMistakeRepository(database.DB).GetPracticeByID(c.Request.Context(), practiceID)
+	if err != nil {
+		log.Printf("获取错题重练失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return nil, false
+	}
+	if practice == nil || practice.StudentID != c.GetInt64("userID") {
+		c.JSON(http.StatusNotFound, gin.H{"error": "错题重练不存在"})
+		return nil, false
+	}
+	return practice, true
+}
+
+// GetMistakePractice 获取错题重练（学生本人）
+func GetMistakePractice(c *gin.Context) {
+	practice, ok := getOwnPractice(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, practiceResponse(practice))
+}
+
+// AnswerMistakePractice 学生作答重练中的一道题，立即评分并给出正确答案和解析
+// 答错时错题重新计数，答对时累计连续答对次数，连续答对models.MistakeResolveStreak次后移出错题本
+// 作答结果同时计入学生的间隔复习计划
+func AnswerMistakePractice(c *gin.Context) {
+	practice, ok := getOwnPractice(c)
+	if !ok {
+		return
+	}
+	var input struct {
+		QuestionID int64    `json:"question_id" binding:"required"`
+		Response   []string `json:"response"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的输入数据"})
+		return
+	}
+
+	var item *models.MistakePracticeItem
+	for i := range practice.Items {
+		if practice.Items[i].QuestionID == input.QuestionID {
+			item = &practice.Items[i]
+			break
+		}
+	}
+	if item == nil || item.Question == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "题目不在本次重练中"})
+		return
+	}
+	if item.AnsweredAt != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "该题已作答"})
+		return
+	}
+	result, ok := assessment.Grade(item.Question, input.Response, 1)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "该题不能自动评分"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	mistakeRepo := repository.NewMistakeRepository(database.DB)
+	entry, err := mistakeRepo.GetEntryByID(ctx, item.EntryID)
+	if err != nil {
+		log.Printf("获取错题失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+
+	now := time.Now()
+	item.SetResponses(input.Response)
+	item.Correct = &result.Correct
+	item.AnsweredAt = &now
+	if result.Correct {
+		practice.CorrectCount++
+	}
+	remaining := 0
+	for _, other := range practice.Items {
+		if other.AnsweredAt == nil {
+			remaining++
+		}
+	}
+	if remaining == 0 {
+		practice.Status = models.MistakePracticeCompleted
+		practice.CompletedAt = &now
+	}
+	// 错题已被删除时只记录重练的作答
+	if entry != nil {
+		if result.Correct {
+			entry.RecordCorrect(now)
+		} else {
+			entry.RecordWrong(item.Response, now)
+		}
+	}
+
+	if err := mistakeRepo.SavePracticeAnswer(ctx, practice, item, entry); err != nil {
+		log.Printf("保存错题重练作答失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+	if err := recordPracticeReview(ctx, item, practice.StudentID, result.Correct); err != nil {
+		log.Printf("更新复习计划失败: %v", err)
+	}
+
+	response := gin.H{
+		"correct":   result.Correct,
+		"question":  questionResponse(item.Question, true),
+		"remaining": remaining,
+		"practice": gin.H{
+			"id":             practice.ID,
+			"status":         practice.Status,
+			"question_count": practice.QuestionCount,
+			"correct_count":  practice.CorrectCount,
+		},
+	}
+	if entry != nil {
+		response["mistake"] = gin.H{
+			"id":             entry.ID,
+			"status":         entry.Status,
+			"wrong_count":    entry.WrongCount,
+			"correct_streak": entry.CorrectStreak,
+		}
+	}
+	c.JSON(http.StatusOK, response)
+}
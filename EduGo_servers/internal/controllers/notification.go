@@ -0,0 +1,105 @@
+package controllers
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"EduGo_servers/internal/database"
+	"EduGo_servers/internal/repository"
+)
+
+// GetNotifications 获取当前用户的通知
+func GetNotifications(c *gin.Context) {
+	page, _ := strconv.Atoi(c.Query("page"))
+	if page < 1 {
+		page = 1
+	}
+	pageSize, _ := strconv.Atoi(c.Query("page_size"))
+	if pageSize <= 0 {
+		pageSize = repository.DefaultPageSize
+	}
+	if pageSize > repository.MaxPageSize {
+		pageSize = repository.MaxPageSize
+	}
+
+	userID := c.GetInt64("userID")
+	notificationRepo := repository.NewNotificationRepository(database.DB)
+	notifications, total, err := notificationRepo.GetNotifications(c.Request.Context(), userID, c.Query("unread") == "true", page, pageSize)
+	if err != nil {
+		log.Printf("获取通知失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+	unread, err := notificationRepo.CountUnread(c.Request.Context(), userID)
+	if err != nil {
+		log.Printf("获取未读通知数量失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+
+	notificationList := []gin.H{}
+	for _, notification := range notifications {
+		notificationList = append(notificationList, gin.H{
+			"id":         notification.ID,
+			"type":       notification.Type,
+			"title":      notification.Title,
+			"content":    notification.Content,
+			"link":       notification.Link,
+			"read_at":    notification.ReadAt,
+			"created_at": notification.CreatedAt,
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"notifications": notificationList,
+		"unread":        unread,
+		"pagination": gin.H{
+			"page":     page,
+			"pageSize": pageSize,
+			"total":    total,
+		},
+	})
+}
+
+// MarkNotificationRead 将通知标记为已读
+func MarkNotificationRead(c *gin.Context) {
+	id, ok := parseIDParam(c, "id", "无效的通知ID")
+	if !ok {
+		return
+	}
+
+	notificationRepo := repository.NewNotificationRepository(database.DB)
+	found, err := notificationRepo.MarkRead(c.Request.Context(), c.GetInt64("userID"), id)
+	if err != nil {
+		log.Printf("标记通知已读失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+	if !found {
+		c.JSON(http.StatusNotFound, gin.H{"error": "通知不存在"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "已标记为已读",
+	})
+}
+
+// MarkAllNotificationsRead 将当前用户的全部通知标记为已读
+func MarkAllNotificationsRead(c *gin.Context) {
+	notificationRepo := repository.NewNotificationRepository(database.DB)
+	count, err := notificationRepo.MarkAllRead(c.Request.Context(), c.GetInt64("userID"))
+	if err != nil {
+		log.Printf("标记通知已读失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "已全部标记为已读",
+		"count":   count,
+	})
+}
@@ -0,0 +1,394 @@
+package controllers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"EduGo_servers/internal/database"
+	"EduGo_servers/internal/jobs"
+	"EduGo_servers/internal/models"
+	"EduGo_servers/internal/repository"
+)
+
+// canAccessUserData 检查当前用户是否可以管理目标用户的个人数据：本人、管理员或关联的家长
+func canAccessUserData(c *gin.Context, targetID int64) (bool, error) {
+	currentUserID := c.GetInt64("userID")
+	role := c.GetString("role")
+	if targetID == currentUserID || role == models.RoleSuperAdmin || role == models.RoleAdmin {
+		return true, nil
+	}
+	if role != models.RoleParent {
+		return false, nil
+	}
+	relationRepo := repository.NewUserRelationRepository(database.DB)
+	return relationRepo.HasRelation(c.Request.Context(), models.RelationStudentParent, targetID, currentUserID)
+}
+
+// canViewStudentData 检查当前用户能否查看学生的考勤、掌握度等学习数据：本人、家长、管理员或该学生的教师
+func canViewStudentData(c *gin.Context, studentID int64) (bool, error) {
+	allowed, err := canAccessUserData(c, studentID)
+	if err != nil || allowed {
+		return allowed, err
+	}
+	if c.GetString("role") != models.RoleTeacher {
+		return false, nil
+	}
+	relationRepo := repository.NewUserRelationRepository(database.DB)
+	return relationRepo.HasRelation(c.Request.Context(), models.RelationTeacherStudent, c.GetInt64("userID"), studentID)
+}
+
+// requireOwnStudents 检查学生都是当前教师的学生，管理员不受限制。失败时已写入响应，message为无权限时的提示
+func requireOwnStudents(c *gin.Context, studentIDs []int64, message string) bool {
+	if isAdminRole(c.GetString("role")) {
+		return true
+	}
+	ctx := c.Request.Context()
+	teacherID := c.GetInt64("userID")
+	relationRepo := repository.NewUserRelationRepository(database.DB)
+	for _, studentID := range studentIDs {
+		related, err := relationRepo.HasRelation(ctx, models.RelationTeacherStudent, teacherID, studentID)
+		if err != nil {
+			log.Printf("检查用户关系失败: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+			return false
+		}
+		if !related {
+			c.JSON(http.StatusForbidden, gin.H{"error": message + ": " + strconv.FormatInt(studentID, 10)})
+			return false
+		}
+	}
+	return true
+}
+
+// dataExportResponse 构造数据导出任务的返回数据
+func dataExportResponse(export *models.DataExport) gin.H {
+	return gin.H{
+		"id":           export.ID,
+		"user_id":      export.UserID,
+		"requested_by": export.RequestedBy,
+		"status":       export.Status,
+		"error":        export.Error,
+		"downloadable": export.Status == models.DataExportCompleted && export.FilePath != "",
+		"completed_at": export.CompletedAt,
+		"expires_at":   export.ExpiresAt,
+		"created_at":   export.CreatedAt,
+	}
+}
+
+// RequestDataExport 发起个人数据导出（本人、关联的家长或管理员）
+func RequestDataExport(c *gin.Context) {
+	var input struct {
+		UserID int64 `json:"user_id"`
+	}
+	// 请求体可选，默认导出本人数据
+	_ = c.ShouldBindJSON(&input)
+
+	targetID := input.UserID
+	if targetID == 0 {
+		targetID = c.GetInt64("userID")
+	}
+
+	allowed, err := canAccessUserData(c, targetID)
+	if err != nil {
+		log.Printf("检查数据访问权限失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+	if !allowed {
+		c.JSON(http.StatusForbidden, gin.H{"error": "无权导出该用户的数据"})
+		return
+	}
+
+	userRepo := repository.NewUserRepository(database.DB)
+	user, err := userRepo.GetUserByID(c.Request.Context(), targetID)
+	if err != nil {
+		log.Printf("获取用户信息失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+	if user == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "用户不存在"})
+		return
+	}
+
+	export := &models.DataExport{
+		UserID:      targetID,
+		RequestedBy: c.GetInt64("userID"),
+		Status:      models.DataExportPending,
+	}
+
+	exportRepo := repository.NewDataExportRepository(database.DB)
+	if err := exportRepo.CreateDataExport(c.Request.Context(), export); err != nil {
+		log.Printf("创建数据导出失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+	jobs.NotifyDataExport()
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"message": "数据导出已开始，完成后可下载",
+		"export":  dataExportResponse(export),
+	})
+}
+
+// GetMyDataExports 获取当前用户发起的数据导出
+func GetMyDataExports(c *gin.Context) {
+	exportRepo := repository.NewDataExportRepository(database.DB)
+	exports, err := exportRepo.GetDataExportsByRequester(c.Request.Context(), c.GetInt64("userID"))
+	if err != nil {
+		log.Printf("获取数据导出列表失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+
+	exportList := []gin.H{}
+	for _, export := range exports {
+		exportList = append(exportList, dataExportResponse(export))
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"exports": exportList,
+	})
+}
+
+// DownloadDataExport 下载已完成的数据导出压缩包
+func DownloadDataExport(c *gin.Context) {
+	exportID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的导出ID"})
+		return
+	}
+
+	exportRepo := repository.NewDataExportRepository(database.DB)
+	export, err := exportRepo.GetDataExportByID(c.Request.Context(), exportID)
+	if err != nil {
+		log.Printf("获取数据导出失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+	if export == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "数据导出不存在"})
+		return
+	}
+
+	role := c.GetString("role")
+	if export.RequestedBy != c.GetInt64("userID") && export.UserID != c.GetInt64("userID") &&
+		role != models.RoleSuperAdmin && role != models.RoleAdmin {
+		c.JSON(http.StatusForbidden, gin.H{"error": "无权下载该数据导出"})
+		return
+	}
+
+	if export.Status != models.DataExportCompleted || export.FilePath == "" ||
+		(export.ExpiresAt != nil && export.ExpiresAt.Before(time.Now())) {
+		c.JSON(http.StatusConflict, gin.H{"error": "数据导出尚未完成或已过期"})
+		return
+	}
+
+	if _, err := os.Stat(export.FilePath); err != nil {
+		log.Printf("导出文件不存在: %v", err)
+		c.JSON(http.StatusNotFound, gin.H{"error": "导出文件不存在"})
+		return
+	}
+
+	c.FileAttachment(export.FilePath, fmt.Sprintf("edugo_user_%d_data.zip", export.UserID))
+}
+
+// erasureRequestResponse 构造数据删除申请的返回数据
+func erasureRequestResponse(request *models.ErasureRequest) gin.H {
+	return gin.H{
+		"id":           request.ID,
+		"user_id":      request.UserID,
+		"requested_by": request.RequestedBy,
+		"reason":       request.Reason,
+		"status":       request.Status,
+		"reviewed_by":  request.ReviewedBy,
+		"review_note":  request.ReviewNote,
+		"reviewed_at":  request.ReviewedAt,
+		"created_at":   request.CreatedAt,
+	}
+}
+
+// RequestErasure 申请删除个人数据（本人、关联的家长或管理员），需管理员审核
+func RequestErasure(c *gin.Context) {
+	var input struct {
+		UserID int64  `json:"user_id"`
+		Reason string `json:"reason"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的输入数据"})
+		return
+	}
+
+	targetID := input.UserID
+	if targetID == 0 {
+		targetID = c.GetInt64("userID")
+	}
+
+	allowed, err := canAccessUserData(c, targetID)
+	if err != nil {
+		log.Printf("检查数据访问权限失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+	if !allowed {
+		c.JSON(http.StatusForbidden, gin.H{"error": "无权申请删除该用户的数据"})
+		return
+	}
+
+	userRepo := repository.NewUserRepository(database.DB)
+	user, err := userRepo.GetUserByID(c.Request.Context(), targetID)
+	if err != nil {
+		log.Printf("获取用户信息失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+	if user == nil || user.Status == models.UserStatusErased {
+		c.JSON(http.StatusNotFound, gin.H{"error": "用户不存在"})
+		return
+	}
+	if user.Role == models.RoleSuperAdmin {
+		c.JSON(http.StatusForbidden, gin.H{"error": "不能删除超级管理员的数据"})
+		return
+	}
+
+	erasureRepo := repository.NewErasureRequestRepository(database.DB)
+	pending, err := erasureRepo.HasPendingErasureRequest(c.Request.Context(), targetID)
+	if err != nil {
+		log.Printf("查询数据删除申请失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+	if pending {
+		c.JSON(http.StatusConflict, gin.H{"error": "该用户已有待审核的数据删除申请"})
+		return
+	}
+
+	request := &models.ErasureRequest{
+		UserID:      targetID,
+		RequestedBy: c.GetInt64("userID"),
+		Reason:      input.Reason,
+		Status:      models.ErasurePending,
+	}
+	if err := erasureRepo.CreateErasureRequest(c.Request.Context(), request); err != nil {
+		log.Printf("创建数据删除申请失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "数据删除申请已提交，等待管理员审核",
+		"request": erasureRequestResponse(request),
+	})
+}
+
+// GetErasureRequests 获取数据删除申请列表（管理员及以上权限）
+func GetErasureRequests(c *gin.Context) {
+	erasureRepo := repository.NewErasureRequestRepository(database.DB)
+	requests, err := erasureRepo.GetErasureRequests(c.Request.Context(), c.Query("status"))
+	if err != nil {
+		log.Printf("获取数据删除申请失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+
+	requestList := []gin.H{}
+	for _, request := range requests {
+		requestList = append(requestList, erasureRequestResponse(request))
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"requests": requestList,
+	})
+}
+
+// ApproveErasureRequest 通过数据删除申请并匿名化用户（管理员及以上权限）
+func ApproveErasureRequest(c *gin.Context) {
+	reviewErasureRequest(c, true)
+}
+
+// RejectErasureRequest 拒绝数据删除申请（管理员及以上权限）
+func RejectErasureRequest(c *gin.Context) {
+	reviewErasureRequest(c, false)
+}
+
+// reviewErasureRequest 审核待处理的数据删除申请
+func reviewErasureRequest(c *gin.Context, approve bool) {
+	requestID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的申请ID"})
+		return
+	}
+
+	var input struct {
+		Note string `json:"note"`
+	}
+	// 请求体可选
+	_ = c.ShouldBindJSON(&input)
+
+	erasureRepo := repository.NewErasureRequestRepository(database.DB)
+	request, err := erasureRepo.GetErasureRequestByID(c.Request.Context(), requestID)
+	if err != nil {
+		log.Printf("获取数据删除申请失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+	if request == nil || request.Status != models.ErasurePending {
+		c.JSON(http.StatusNotFound, gin.H{"error": "待审核的申请不存在"})
+		return
+	}
+
+	if approve {
+		userRepo := repository.NewUserRepository(database.DB)
+		user, err := userRepo.GetUserByID(c.Request.Context(), request.UserID)
+		if err != nil {
+			log.Printf("获取用户信息失败: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+			return
+		}
+		if user != nil {
+			if user.Role == models.RoleSuperAdmin {
+				c.JSON(http.StatusForbidden, gin.H{"error": "不能删除超级管理员的数据"})
+				return
+			}
+			if c.GetString("role") == models.RoleAdmin && user.Role == models.RoleAdmin {
+				c.JSON(http.StatusForbidden, gin.H{"error": "管理员不能删除其他管理员的数据"})
+				return
+			}
+		}
+
+		if err := userRepo.AnonymizeUser(c.Request.Context(), request.UserID); err != nil {
+			log.Printf("匿名化用户失败: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+			return
+		}
+		request.Status = models.ErasureCompleted
+	} else {
+		request.Status = models.ErasureRejected
+	}
+
+	now := time.Now()
+	request.ReviewedBy = c.GetInt64("userID")
+	request.ReviewNote = input.Note
+	request.ReviewedAt = &now
+	if err := erasureRepo.UpdateErasureRequest(c.Request.Context(), request); err != nil {
+		log.Printf("更新数据删除申请失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+
+	message := "已拒绝数据删除申请"
+	if approve {
+		message = "用户数据已匿名化"
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message": message,
+		"request": erasureRequestResponse(request),
+	})
+}
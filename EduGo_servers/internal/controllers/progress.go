@@ -0,0 +1,918 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"EduGo_servers/internal/database"
+	"EduGo_servers/internal/models"
+	"EduGo_servers/internal/progress"
+	"EduGo_servers/internal/repository"
+)
+
+const maxViewSeconds = 24 * 60 * 60 // 一次查看资源最多记录24小时
+
+// lessonResponse 构造课时的返回数据
+func lessonResponse(lesson *models.Lesson) gin.H {
+	resources := make([]gin.H, 0, len(lesson.Resources))
+	for _, resource := range lesson.Resources {
+		resources = append(resources, gin.H{
+			"id":    resource.ID,
+			"type":  resource.Type,
+			"title": resource.Title,
+		})
+	}
+	assessments := make([]gin.H, 0, len(lesson.Assessments))
+	for _, assessment := range lesson.Assessments {
+		assessments = append(assessments, gin.H{
+			"id":          assessment.ID,
+			"offering_id": assessment.OfferingID,
+			"title":       assessment.Title,
+		})
+	}
+
+	return gin.H{
+		"id":          lesson.ID,
+		"unit_id":     lesson.UnitID,
+		"course_id":   lesson.CourseID,
+		"title":       lesson.Title,
+		"description": lesson.Description,
+		"position":    lesson.Position,
+		"resources":   resources,
+		"assessments": assessments,
+		"created_at":  lesson.CreatedAt,
+		"updated_at":  lesson.UpdatedAt,
+	}
+}
+
+// unitResponse 构造单元的返回数据，包括单元中的课时
+func unitResponse(unit *models.CourseUnit) gin.H {
+	lessons := make([]gin.H, 0, len(unit.Lessons))
+	for i := range unit.Lessons {
+		lessons = append(lessons, lessonResponse(&unit.Lessons[i]))
+	}
+	return gin.H{
+		"id":          unit.ID,
+		"course_id":   unit.CourseID,
+		"title":       unit.Title,
+		"description": unit.Description,
+		"position":    unit.Position,
+		"lessons":     lessons,
+		"created_at":  unit.CreatedAt,
+		"updated_at":  unit.UpdatedAt,
+	}
+}
+
+// progressResponse 构造课时进度的返回数据，record为空表示还没有开始
+func progressResponse(record *models.LessonProgress, lesson *models.Lesson) gin.H {
+	if record == nil {
+		record = &models.LessonProgress{Status: models.ProgressNotStarted, TotalItems: lesson.ItemCount()}
+	}
+	return gin.H{
+		"status":           record.Status,
+		"completed_items":  record.CompletedItems,
+		"total_items":      record.TotalItems,
+		"percent":          progress.Percent(record.CompletedItems, record.TotalItems),
+		"time_spent":       record.TimeSpent,
+		"score":            record.Score,
+		"started_at":       record.StartedAt,
+		"completed_at":     record.CompletedAt,
+		"last_activity_at": record.LastActivityAt,
+	}
+}
+
+// getManagedCourse 获取路径参数指定的课程，只有课程的负责教师和管理员可以管理单元和课时
+func getManagedCourse(c *gin.Context) (*models.Course, bool) {
+	course, ok := getCourseParam(c)
+	if !ok {
+		return nil, false
+	}
+	if !isAdminRole(c.GetString("role")) && !course.HasTeacher(c.GetInt64("userID")) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "只有课程的负责教师可以管理单元和课时"})
+		return nil, false
+	}
+	return course, true
+}
+
+// getUnitParam 获取路径参数指定的课程单元
+func getUnitParam(c *gin.Context, course *models.Course) (*models.CourseUnit, bool) {
+	unitID, ok := parseIDParam(c, "unit_id", "无效的单元ID")
+	if !ok {
+		return nil, false
+	}
+
+	unit, err := repository.NewLessonRepository(database.DB).GetUnitByID(c.Request.Context(), unitID)
+	if err != nil {
+		log.Printf("获取单元失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return nil, false
+	}
+	if unit == nil || unit.CourseID != course.ID {
+		c.JSON(http.StatusNotFound, gin.H{"error": "单元不存在"})
+		return nil, false
+	}
+	return unit, true
+}
+
+// getLessonParam 获取路径参数指定的课程课时
+func getLessonParam(c *gin.Context, course *models.Course) (*models.Lesson, bool) {
+	lessonID, ok := parseIDParam(c, "lesson_id", "无效的课时ID")
+	if !ok {
+		return nil, false
+	}
+
+	lesson, err := repository.NewLessonRepository(database.DB).GetLessonByID(c.Request.Context(), lessonID)
+	if err != nil {
+		log.Printf("获取课时失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return nil, false
+	}
+	if lesson == nil || lesson.CourseID != course.ID {
+		c.JSON(http.StatusNotFound, gin.H{"error": "课时不存在"})
+		return nil, false
+	}
+	return lesson, true
+}
+
+// loadLessonResources 加载课时的学习资源，资源需要对课程的学生可见。失败时已写入响应
+func loadLessonResources(c *gin.Context, course *models.Course, ids []int64) ([]models.Resource, bool) {
+	resourceRepo := repository.NewResourceRepository(database.DB)
+	resources := make([]models.Resource, 0, len(ids))
+	for _, id := range uniqueIDs(ids) {
+		resource, err := resourceRepo.GetResourceByID(c.Request.Context(), id)
+		if err != nil {
+			log.Printf("获取资源失败: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+			return nil, false
+		}
+		if resource == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("资源 %d 不存在", id)})
+			return nil, false
+		}
+		if !resource.VisibleTo(0, []int64{course.ID}) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("资源 %d 对课程的学生不可见", id)})
+			return nil, false
+		}
+		resources = append(resources, *resource)
+	}
+	return resources, true
+}
+
+// loadLessonAssessments 加载课时的测评，测评需要属于课程的开课。失败时已写入响应
+func loadLessonAssessments(c *gin.Context, course *models.Course, ids []int64) ([]models.Assessment, bool) {
+	ids = uniqueIDs(ids)
+	assessments, err := repository.NewLessonRepository(database.DB).GetCourseAssessments(c.Request.Context(), course.ID, ids)
+	if err != nil {
+		log.Printf("获取测评失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return nil, false
+	}
+	if len(assessments) != len(ids) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "测评不存在或不属于该课程"})
+		return nil, false
+	}
+
+	values := make([]models.Assessment, 0, len(assessments))
+	for _, assessment := range assessments {
+		values = append(values, *assessment)
+	}
+	return values, true
+}
+
+// refreshLessonProgress 课时的学习内容变化后重新汇总学生的进度，已删除的课时跳过
+func refreshLessonProgress(ctx context.Context, lessonIDs []int64) error {
+	lessonRepo := repository.NewLessonRepository(database.DB)
+	progressRepo := repository.NewProgressRepository(database.DB)
+	for _, id := range lessonIDs {
+		lesson, err := lessonRepo.GetLessonByID(ctx, id)
+		if err != nil {
+			return err
+		}
+		if lesson == nil {
+			continue
+		}
+		if err := progressRepo.RefreshProgress(ctx, lesson, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetCourseUnits 按顺序获取课程的单元和课时
+func GetCourseUnits(c *gin.Context) {
+	course, ok := getCourseParam(c)
+	if !ok {
+		return
+	}
+
+	units, err := repository.NewLessonRepository(database.DB).GetCourseUnits(c.Request.Context(), course.ID)
+	if err != nil {
+		log.Printf("获取课程单元失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+
+	result := make([]gin.H, 0, len(units))
+	for _, unit := range units {
+		result = append(result, unitResponse(unit))
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"course_id": course.ID,
+		"units":     result,
+	})
+}
+
+// CreateCourseUnit 创建课程单元（管理员或课程的负责教师），未指定顺序时排在最后
+func CreateCourseUnit(c *gin.Context) {
+	course, ok := getManagedCourse(c)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		Title       string `json:"title" binding:"required,max=200"`
+		Description string `json:"description"`
+		Position    *int   `json:"position"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的输入数据"})
+		return
+	}
+	title := strings.TrimSpace(input.Title)
+	if title == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "单元标题不能为空"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	lessonRepo := repository.NewLessonRepository(database.DB)
+	unit := &models.CourseUnit{
+		CourseID:    course.ID,
+		Title:       title,
+		Description: input.Description,
+	}
+	var err error
+	if input.Position != nil {
+		unit.Position = *input.Position
+	} else if unit.Position, err = lessonRepo.NextUnitPosition(ctx, course.ID); err != nil {
+		log.Printf("获取单元顺序失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+
+	if err := lessonRepo.CreateUnit(ctx, unit); err != nil {
+		log.Printf("创建单元失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, unitResponse(unit))
+}
+
+// UpdateCourseUnit 修改课程单元（管理员或课程的负责教师）
+func UpdateCourseUnit(c *gin.Context) {
+	course, ok := getManagedCourse(c)
+	if !ok {
+		return
+	}
+	unit, ok := getUnitParam(c, course)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		Title       *string `json:"title" binding:"omitempty,max=200"`
+		Description *string `json:"description"`
+		Position    *int    `json:"position"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的输入数据"})
+		return
+	}
+
+	if input.Title != nil {
+		title := strings.TrimSpace(*input.Title)
+		if title == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "单元标题不能为空"})
+			return
+		}
+		unit.Title = title
+	}
+	if input.Description != nil {
+		unit.Description = *input.Description
+	}
+	if input.Position != nil {
+		unit.Position = *input.Position
+	}
+
+	if err := repository.NewLessonRepository(database.DB).UpdateUnit(c.Request.Context(), unit); err != nil {
+		log.Printf("更新单元失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+
+	c.JSON(http.StatusOK, unitResponse(unit))
+}
+
+// DeleteCourseUnit 删除课程单元及其课时和学生的学习进度（管理员或课程的负责教师）
+func DeleteCourseUnit(c *gin.Context) {
+	course, ok := getManagedCourse(c)
+	if !ok {
+		return
+	}
+	unit, ok := getUnitParam(c, course)
+	if !ok {
+		return
+	}
+
+	if err := repository.NewLessonRepository(database.DB).DeleteUnit(c.Request.Context(), unit.ID); err != nil {
+		log.Printf("删除单元失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "单元删除成功"})
+}
+
+// CreateLesson 在单元中创建课时（管理员或课程的负责教师），未指定顺序时排在最后
+// 学习资源需要对课程的学生可见，测评需要属于课程的开课
+func CreateLesson(c *gin.Context) {
+	course, ok := getManagedCourse(c)
+	if !ok {
+		return
+	}
+	unit, ok := getUnitParam(c, course)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		Title         string  `json:"title" binding:"required,max=200"`
+		Description   string  `json:"description"`
+		Position      *int    `json:"position"`
+		ResourceIDs   []int64 `json:"resource_ids"`
+		AssessmentIDs []int64 `json:"assessment_ids"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的输入数据"})
+		return
+	}
+	title := strings.TrimSpace(input.Title)
+	if title == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "课时标题不能为空"})
+		return
+	}
+
+	lesson := &models.Lesson{
+		UnitID:      unit.ID,
+		CourseID:    course.ID,
+		Title:       title,
+		Description: input.Description,
+	}
+	if lesson.Resources, ok = loadLessonResources(c, course, input.ResourceIDs); !ok {
+		return
+	}
+	if lesson.Assessments, ok = loadLessonAssessments(c, course, input.AssessmentIDs); !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	lessonRepo := repository.NewLessonRepository(database.DB)
+	var err error
+	if input.Position != nil {
+		lesson.Position = *input.Position
+	} else if lesson.Position, err = lessonRepo.NextLessonPosition(ctx, unit.ID); err != nil {
+		log.Printf("获取课时顺序失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+
+	if err := lessonRepo.CreateLesson(ctx, lesson); err != nil {
+		log.Printf("创建课时失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, lessonResponse(lesson))
+}
+
+// UpdateLesson 修改课时（管理员或课程的负责教师），学习资源和测评整体替换，可以移到课程的其他单元
+// 学习内容变化后重新汇总学生在课时中的进度
+func UpdateLesson(c *gin.Context) {
+	course, ok := getManagedCourse(c)
+	if !ok {
+		return
+	}
+	lesson, ok := getLessonParam(c, course)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		UnitID        *int64   `json:"unit_id"`
+		Title         *string  `json:"title" binding:"omitempty,max=200"`
+		Description   *string  `json:"description"`
+		Position      *int     `json:"position"`
+		ResourceIDs   *[]int64 `json:"resource_ids"`
+		AssessmentIDs *[]int64 `json:"assessment_ids"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的输入数据"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	lessonRepo := repository.NewLessonRepository(database.DB)
+	if input.UnitID != nil && *input.UnitID != lesson.UnitID {
+		unit, err := lessonRepo.GetUnitByID(ctx, *input.UnitID)
+		if err != nil {
+			log.Printf("获取单元失败: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+			return
+		}
+		if unit == nil || unit.CourseID != course.ID {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "单元不存在"})
+			return
+		}
+		lesson.UnitID = unit.ID
+		if input.Position == nil {
+			if lesson.Position, err = lessonRepo.NextLessonPosition(ctx, unit.ID); err != nil {
+				log.Printf("获取课时顺序失败: %v", err)
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+				return
+			}
+		}
+	}
+	if input.Title != nil {
+		title := strings.TrimSpace(*input.Title)
+		if title == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "课时标题不能为空"})
+			return
+		}
+		lesson.Title = title
+	}
+	if input.Description != nil {
+		lesson.Description = *input.Description
+	}
+	if input.Position != nil {
+		lesson.Position = *input.Position
+	}
+	if input.ResourceIDs != nil {
+		if lesson.Resources, ok = loadLessonResources(c, course, *input.ResourceIDs); !ok {
+			return
+		}
+	}
+	if input.AssessmentIDs != nil {
+		if lesson.Assessments, ok = loadLessonAssessments(c, course, *input.AssessmentIDs); !ok {
+			return
+		}
+	}
+
+	if err := lessonRepo.UpdateLesson(ctx, lesson); err != nil {
+		log.Printf("更新课时失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+	if input.ResourceIDs != nil || input.AssessmentIDs != nil {
+		if err := repository.NewProgressRepository(database.DB).RefreshProgress(ctx, lesson, nil); err != nil {
+			log.Printf("更新学习进度失败: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, lessonResponse(lesson))
+}
+
+// DeleteLesson 删除课时及学生在课时中的学习进度（管理员或课程的负责教师）
+func DeleteLesson(c *gin.Context) {
+	course, ok := getManagedCourse(c)
+	if !ok {
+		return
+	}
+	lesson, ok := getLessonParam(c, course)
+	if !ok {
+		return
+	}
+
+	if err := repository.NewLessonRepository(database.DB).DeleteLesson(c.Request.Context(), lesson.ID); err != nil {
+		log.Printf("删除课时失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "课时删除成功"})
+}
+
+// recordResourceView 把学生查看资源计入所在课程中包含该资源的课时，返回这些课时
+// seconds累加到学习时长，completed表示资源已学完
+func recordResourceView(ctx context.Context, studentID, resourceID int64, seconds int, completed bool) ([]*models.Lesson, error) {
+	progressRepo := repository.NewProgressRepository(database.DB)
+	courseIDs, err := repository.NewResourceRepository(database.DB).GetUserCourseIDs(ctx, studentID)
+	if err != nil {
+		return nil, err
+	}
+	lessons, err := progressRepo.GetResourceLessons(ctx, resourceID, courseIDs)
+	if err != nil || len(lessons) == 0 {
+		return lessons, err
+	}
+
+	lessonIDs := make([]int64, 0, len(lessons))
+	for _, lesson := range lessons {
+		lessonIDs = append(lessonIDs, lesson.ID)
+	}
+	existing, err := progressRepo.GetActivities(ctx, studentID, lessonIDs)
+	if err != nil {
+		return nil, err
+	}
+	activities := map[int64]*models.LessonActivity{}
+	for _, activity := range existing {
+		if activity.ItemType == models.LessonItemResource && activity.ItemID == resourceID {
+			activities[activity.LessonID] = activity
+		}
+	}
+
+	now := time.Now()
+	changed := make([]*models.LessonActivity, 0, len(lessons))
+	for _, lesson := range lessons {
+		activity, ok := activities[lesson.ID]
+		if !ok {
+			activity = &models.LessonActivity{
+				StudentID: studentID,
+				LessonID:  lesson.ID,
+				ItemType:  models.LessonItemResource,
+				ItemID:    resourceID,
+			}
+		}
+		activity.TimeSpent += seconds
+		if completed && !activity.Completed {
+			activity.Completed = true
+			activity.CompletedAt = &now
+		}
+		changed = append(changed, activity)
+	}
+	if err := progressRepo.SaveActivities(ctx, changed); err != nil {
+		return nil, err
+	}
+	for _, lesson := range lessons {
+		if err := progressRepo.RefreshProgress(ctx, lesson, []int64{studentID}); err != nil {
+			return nil, err
+		}
+	}
+	return lessons, nil
+}
+
+// RecordResourceView 学生记录一次查看资源，计入所在课程中包含该资源的课时
+// seconds为这次查看的时长，累加到学习时长；completed默认为true，表示资源已学完
+func RecordResourceView(c *gin.Context) {
+	if c.GetString("role") != models.RoleStudent {
+		c.JSON(http.StatusForbidden, gin.H{"error": "只有学生可以记录学习进度"})
+		return
+	}
+	resource, ok := getResourceParam(c)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		Seconds   int   `json:"seconds" binding:"min=0"`
+		Completed *bool `json:"completed"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的输入数据"})
+		return
+	}
+	if input.Seconds > maxViewSeconds {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "查看时长不能超过24小时"})
+		return
+	}
+	completed := input.Completed == nil || *input.Completed
+
+	ctx := c.Request.Context()
+	studentID := c.GetInt64("userID")
+	lessons, err := recordResourceView(ctx, studentID, resource.ID, input.Seconds, completed)
+	if err != nil {
+		log.Printf("记录学习进度失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+
+	lessonIDs := make([]int64, 0, len(lessons))
+	for _, lesson := range lessons {
+		lessonIDs = append(lessonIDs, lesson.ID)
+	}
+	records, err := repository.NewProgressRepository(database.DB).GetProgress(ctx, []int64{studentID}, lessonIDs)
+	if err != nil {
+		log.Printf("获取学习进度失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+	byLesson := make(map[int64]*models.LessonProgress, len(records))
+	for _, record := range records {
+		byLesson[record.LessonID] = record
+	}
+
+	result := make([]gin.H, 0, len(lessons))
+	for _, lesson := range lessons {
+		item := progressResponse(byLesson[lesson.ID], lesson)
+		item["lesson_id"] = lesson.ID
+		item["course_id"] = lesson.CourseID
+		result = append(result, item)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"resource_id": resource.ID,
+		"lessons":     result,
+	})
+}
+
+// courseLessons 按顺序列出课程单元中的全部课时
+func courseLessons(units []*models.CourseUnit) []*models.Lesson {
+	var lessons []*models.Lesson
+	for _, unit := range units {
+		for i := range unit.Lessons {
+			lessons = append(lessons, &unit.Lessons[i])
+		}
+	}
+	return lessons
+}
+
+// progressTotals 汇总学生在一组课时中的进度：已完成的课时数、学习时长、测评平均得分率和最近学习时间
+func progressTotals(lessons []*models.Lesson, records map[int64]*models.LessonProgress) gin.H {
+	completed, started, timeSpent := 0, 0, 0
+	var scoreSum float64
+	scored := 0
+	var lastActivity *time.Time
+	for _, lesson := range lessons {
+		record := records[lesson.ID]
+		if record == nil {
+			continue
+		}
+		started++
+		if record.Status == models.ProgressCompleted {
+			completed++
+		}
+		timeSpent += record.TimeSpent
+		if record.Score != nil {
+			scoreSum += *record.Score
+			scored++
+		}
+		if record.LastActivityAt != nil && (lastActivity == nil || record.LastActivityAt.After(*lastActivity)) {
+			lastActivity = record.LastActivityAt
+		}
+	}
+
+	totals := gin.H{
+		"total_lessons":     len(lessons),
+		"started_lessons":   started,
+		"completed_lessons": completed,
+		"percent":           progress.Percent(completed, len(lessons)),
+		"time_spent":        timeSpent,
+		"score":             nil, // 有测评得分的课时的平均得分率
+		"last_activity_at":  lastActivity,
+	}
+	if scored > 0 {
+		totals["score"] = scoreSum / float64(scored)
+	}
+	return totals
+}
+
+// GetStudentProgress 获取学生在课程各单元和课时中的学习进度（本人、家长、管理员或该学生的教师）
+// 未指定course_id时返回学生所在的、已经划分了单元的全部课程
+func GetStudentProgress(c *gin.Context) {
+	studentID, ok := parseIDParam(c, "id", "无效的学生ID")
+	if !ok {
+		return
+	}
+	allowed, err := canViewStudentData(c, studentID)
+	if err != nil {
+		log.Printf("检查用户关系失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+	if !allowed {
+		c.JSON(http.StatusForbidden, gin.H{"error": "无权查看该学生的学习进度"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	value := c.Query("course_id")
+	var courseIDs []int64
+	if value != "" {
+		courseID, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的课程ID"})
+			return
+		}
+		courseIDs = []int64{courseID}
+	} else if courseIDs, err = repository.NewResourceRepository(database.DB).GetUserCourseIDs(ctx, studentID); err != nil {
+		log.Printf("获取学生的课程失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+
+	courseRepo := repository.NewCourseRepository(database.DB)
+	lessonRepo := repository.NewLessonRepository(database.DB)
+	progressRepo := repository.NewProgressRepository(database.DB)
+	result := make([]gin.H, 0, len(courseIDs))
+	for _, courseID := range courseIDs {
+		course, err := courseRepo.GetCourseByID(ctx, courseID)
+		if err != nil {
+			log.Printf("获取课程失败: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+			return
+		}
+		if course == nil {
+			if value != "" {
+				c.JSON(http.StatusNotFound, gin.H{"error": "课程不存在"})
+				return
+			}
+			continue
+		}
+		units, err := lessonRepo.GetCourseUnits(ctx, course.ID)
+		if err != nil {
+			log.Printf("获取课程单元失败: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+			return
+		}
+		if len(units) == 0 && value == "" {
+			continue
+		}
+
+		lessons := courseLessons(units)
+		lessonIDs := make([]int64, 0, len(lessons))
+		for _, lesson := range lessons {
+			lessonIDs = append(lessonIDs, lesson.ID)
+		}
+		records, err := progressRepo.GetProgress(ctx, []int64{studentID}, lessonIDs)
+		if err != nil {
+			log.Printf("获取学习进度失败: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+			return
+		}
+		byLesson := make(map[int64]*models.LessonProgress, len(records))
+		for _, record := range records {
+			byLesson[record.LessonID] = record
+		}
+
+		unitList := make([]gin.H, 0, len(units))
+		for _, unit := range units {
+			unitLessons := make([]*models.Lesson, 0, len(unit.Lessons))
+			lessonList := make([]gin.H, 0, len(unit.Lessons))
+			for i := range unit.Lessons {
+				lesson := &unit.Lessons[i]
+				unitLessons = append(unitLessons, lesson)
+				lessonList = append(lessonList, gin.H{
+					"id":       lesson.ID,
+					"title":    lesson.Title,
+					"position": lesson.Position,
+					"progress": progressResponse(byLesson[lesson.ID], lesson),
+				})
+			}
+			unitList = append(unitList, gin.H{
+				"id":       unit.ID,
+				"title":    unit.Title,
+				"position": unit.Position,
+				"lessons":  lessonList,
+				"summary":  progressTotals(unitLessons, byLesson),
+			})
+		}
+		result = append(result, gin.H{
+			"course": gin.H{
+				"id":   course.ID,
+				"code": course.Code,
+				"name": course.Name,
+			},
+			"units":   unitList,
+			"summary": progressTotals(lessons, byLesson),
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"student_id": studentID,
+		"courses":    result,
+	})
+}
+
+// GetClassProgress 获取班级学生在课程中的学习进度（管理员或该班级的教师）
+// 每个课时统计完成和学习中的学生数、完成率和平均得分率，每个学生统计完成的课时数和学习时长
+func GetClassProgress(c *gin.Context) {
+	class, ok := getAccessibleClass(c)
+	if !ok {
+		return
+	}
+	courseID, err := strconv.ParseInt(c.Query("course_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请指定课程"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	course, err := repository.NewCourseRepository(database.DB).GetCourseByID(ctx, courseID)
+	if err != nil {
+		log.Printf("获取课程失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+	if course == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "课程不存在"})
+		return
+	}
+	units, err := repository.NewLessonRepository(database.DB).GetCourseUnits(ctx, course.ID)
+	if err != nil {
+		log.Printf("获取课程单元失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+	students, err := repository.NewClassRepository(database.DB).GetClassStudents(ctx, class.ID)
+	if err != nil {
+		log.Printf("获取班级学生失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+
+	lessons := courseLessons(units)
+	lessonIDs := make([]int64, 0, len(lessons))
+	for _, lesson := range lessons {
+		lessonIDs = append(lessonIDs, lesson.ID)
+	}
+	studentIDs := make([]int64, 0, len(students))
+	for _, student := range students {
+		studentIDs = append(studentIDs, student.ID)
+	}
+	records, err := repository.NewProgressRepository(database.DB).GetProgress(ctx, studentIDs, lessonIDs)
+	if err != nil {
+		log.Printf("获取学习进度失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+	byStudent := map[int64]map[int64]*models.LessonProgress{}
+	for _, record := range records {
+		if byStudent[record.StudentID] == nil {
+			byStudent[record.StudentID] = map[int64]*models.LessonProgress{}
+		}
+		byStudent[record.StudentID][record.LessonID] = record
+	}
+
+	lessonList := make([]gin.H, 0, len(lessons))
+	for _, lesson := range lessons {
+		completed, inProgress := 0, 0
+		var scoreSum float64
+		scored := 0
+		for _, student := range students {
+			record := byStudent[student.ID][lesson.ID]
+			if record == nil {
+				continue
+			}
+			switch record.Status {
+			case models.ProgressCompleted:
+				completed++
+			case models.ProgressInProgress:
+				inProgress++
+			}
+			if record.Score != nil {
+				scoreSum += *record.Score
+				scored++
+			}
+		}
+		item := gin.H{
+			"id":          lesson.ID,
+			"unit_id":     lesson.UnitID,
+			"title":       lesson.Title,
+			"completed":   completed,
+			"in_progress": inProgress,
+			"not_started": len(students) - completed - inProgress,
+			"percent":     progress.Percent(completed, len(students)),
+			"score":       nil, // 有测评得分的学生的平均得分率
+		}
+		if scored > 0 {
+			item["score"] = scoreSum / float64(scored)
+		}
+		lessonList = append(lessonList, item)
+	}
+
+	studentList := make([]gin.H, 0, len(students))
+	for _, student := range students {
+		item := progressTotals(lessons, byStudent[student.ID])
+		item["student"] = userSummary(student)
+		studentList = append(studentList, item)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"class_id": class.ID,
+		"course": gin.H{
+			"id":   course.ID,
+			"code": course.Code,
+			"name": course.Name,
+		},
+		"lessons":  lessonList,
+		"students": studentList,
+	})
+}
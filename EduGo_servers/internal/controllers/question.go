@@ -0,0 +1,402 @@
+package controllers
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"EduGo_servers/internal/assessment"
+	"EduGo_servers/internal/database"
+	"EduGo_servers/internal/jobs"
+	"EduGo_servers/internal/models"
+	"EduGo_servers/internal/repository"
+)
+
+const maxQuestionOptions = 26 // 选项标签为A到Z
+
+// questionResponse 构造题目的返回数据，withAnswers为false时不包含正确答案和解析
+func questionResponse(question *models.Question, withAnswers bool) gin.H {
+	options := make([]gin.H, 0, len(question.Options))
+	for _, option := range question.Options {
+		item := gin.H{
+			"label":   option.Label,
+			"content": option.Content,
+		}
+		if withAnswers {
+			item["is_correct"] = option.IsCorrect
+		}
+		options = append(options, item)
+	}
+
+	knowledgePoints := make([]string, 0, len(question.Tags))
+	knowledgePointIDs := []int64{}
+	for _, tag := range question.Tags {
+		knowledgePoints = append(knowledgePoints, tag.KnowledgePoint)
+		if tag.KnowledgePointID != nil {
+			knowledgePointIDs = append(knowledgePointIDs, *tag.KnowledgePointID)
+		}
+	}
+
+	response := gin.H{
+		"id":                  question.ID,
+		"type":                question.Type,
+		"subject":             question.Subject,
+		"stem":                question.Stem,
+		"difficulty":          question.Difficulty,
+		"options":             options,
+		"knowledge_points":    knowledgePoints,
+		"knowledge_point_ids": knowledgePointIDs, // 关联到知识点树的知识点
+	}
+	if question.Type == models.QuestionFillBlank {
+		response["blank_count"] = question.BlankCount()
+	}
+	if !withAnswers {
+		return response
+	}
+
+	switch question.Type {
+	case models.QuestionTrueFalse:
+		if len(question.Answers) > 0 {
+			response["answer"] = question.Answers[0].Content == "true"
+		}
+	case models.QuestionFillBlank:
+		blanks := make([][]string, question.BlankCount())
+		for _, answer := range question.Answers {
+			blanks[answer.Blank] = append(blanks[answer.Blank], answer.Content)
+		}
+		response["blanks"] = blanks
+	case models.QuestionShortAnswer:
+		if len(question.Answers) > 0 {
+			response["reference_answer"] = question.Answers[0].Content
+		}
+	}
+	response["explanation"] = question.Explanation
+	if question.IsObjective() {
+		parameters := gin.H{"calibrated": question.Parameter != nil}
+		params := assessment.ItemParams(question)
+		parameters["discrimination"] = params.Discrimination
+		parameters["difficulty"] = params.Difficulty
+		if question.Parameter != nil {
+			parameters["responses"] = question.Parameter.Responses
+			parameters["calibrated_at"] = question.Parameter.CalibratedAt
+		}
+		response["item_parameters"] = parameters
+	}
+	response["created_by"] = question.CreatedBy
+	response["created_at"] = question.CreatedAt
+	response["updated_at"] = question.UpdatedAt
+	return response
+}
+
+// questionInput 题目的输入数据
+type questionInput struct {
+	Type              string                `json:"type" binding:"required"`
+	Subject           string                `json:"subject"`
+	Stem              string                `json:"stem" binding:"required"`
+	Explanation       string                `json:"explanation"`
+	Difficulty        *int                  `json:"difficulty"`
+	Options           []questionOptionInput `json:"options"`          // 选择题的选项，按顺序标记为A、B、C...
+	Answer            *bool                 `json:"answer"`           // 判断题的答案
+	Blanks            [][]string            `json:"blanks"`           // 填空题每个空可接受的答案
+	ReferenceAnswer   string                `json:"reference_answer"` // 简答题的参考答案
+	KnowledgePoints   []string              `json:"knowledge_points"`
+	KnowledgePointIDs []int64               `json:"knowledge_point_ids"` // 知识点树中的知识点，须属于题目的学科
+}
+
+// questionOptionInput 选择题选项的输入数据
+type questionOptionInput struct {
+	Content   string `json:"content"`
+	IsCorrect bool   `json:"is_correct"`
+}
+
+// apply 校验输入并写入题目，返回错误信息
+func (input *questionInput) apply(question *models.Question) string {
+	if !models.IsValidQuestionType(input.Type) {
+		return "无效的题型"
+	}
+	stem := strings.TrimSpace(input.Stem)
+	if stem == "" {
+		return "题干不能为空"
+	}
+	difficulty := 3
+	if input.Difficulty != nil {
+		difficulty = *input.Difficulty
+	}
+	if difficulty < 1 || difficulty > 5 {
+		return "难度必须在1到5之间"
+	}
+
+	var options []models.QuestionOption
+	var answers []models.QuestionAnswer
+	switch input.Type {
+	case models.QuestionSingleChoice, models.QuestionMultipleChoice:
+		if len(input.Options) < 2 || len(input.Options) > maxQuestionOptions {
+			return "选择题需要2到26个选项"
+		}
+		correct := 0
+		for i, option := range input.Options {
+			content := strings.TrimSpace(option.Content)
+			if content == "" {
+				return "选项内容不能为空"
+			}
+			if option.IsCorrect {
+				correct++
+			}
+			options = append(options, models.QuestionOption{
+				Label:     string(rune('A' + i)),
+				Content:   content,
+				IsCorrect: option.IsCorrect,
+				Position:  i,
+			})
+		}
+		if input.Type == models.QuestionSingleChoice && correct != 1 {
+			return "单选题必须有且只有一个正确选项"
+		}
+		if correct == 0 {
+			return "多选题至少需要一个正确选项"
+		}
+	case models.QuestionTrueFalse:
+		if input.Answer == nil {
+			return "判断题需要设置答案"
+		}
+		answers = append(answers, models.QuestionAnswer{Content: strconv.FormatBool(*input.Answer)})
+	case models.QuestionFillBlank:
+		if len(input.Blanks) == 0 {
+			return "填空题至少需要一个空"
+		}
+		for i, accepted := range input.Blanks {
+			count := 0
+			for _, value := range accepted {
+				if value = strings.TrimSpace(value); value != "" {
+					answers = append(answers, models.QuestionAnswer{Blank: i, Content: value})
+					count++
+				}
+			}
+			if count == 0 {
+				return "填空题的每个空至少需要一个答案"
+			}
+		}
+	case models.QuestionShortAnswer:
+		if reference := strings.TrimSpace(input.ReferenceAnswer); reference != "" {
+			answers = append(answers, models.QuestionAnswer{Content: reference})
+		}
+	}
+
+	var tags []models.QuestionTag
+	seen := map[string]bool{}
+	for _, point := range input.KnowledgePoints {
+		point = strings.TrimSpace(point)
+		if point == "" || seen[point] {
+			continue
+		}
+		if len([]rune(point)) > 100 {
+			return "知识点名称过长"
+		}
+		seen[point] = true
+		tags = append(tags, models.QuestionTag{KnowledgePoint: point})
+	}
+
+	question.Type = input.Type
+	question.Subject = strings.TrimSpace(input.Subject)
+	question.Stem = stem
+	question.Explanation = strings.TrimSpace(input.Explanation)
+	question.Difficulty = difficulty
+	question.Options = options
+	question.Answers = answers
+	question.Tags = tags
+	return ""
+}
+
+// applyQuestionInput 校验输入并写入题目，把知识点关联到知识点树。失败时已写入响应
+func applyQuestionInput(c *gin.Context, input *questionInput, question *models.Question) bool {
+	message := input.apply(question)
+	if message == "" {
+		var err error
+		if message, err = newKnowledgeLinker(c.Request.Context()).link(question, input.KnowledgePointIDs); err != nil {
+			log.Printf("关联知识点失败: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+			return false
+		}
+	}
+	if message != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": message})
+		return false
+	}
+	return true
+}
+
+// getQuestionParam 获取路径参数指定的题目
+func getQuestionParam(c *gin.Context) (*models.Question, bool) {
+	questionID, ok := parseIDParam(c, "id", "无效的题目ID")
+	if !ok {
+		return nil, false
+	}
+
+	question, err := repository.NewQuestionRepository(database.DB).GetQuestionByID(c.Request.Context(), questionID)
+	if err != nil {
+		log.Printf("获取题目失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return nil, false
+	}
+	if question == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "题目不存在"})
+		return nil, false
+	}
+	return question, true
+}
+
+// getManagedQuestion 获取路径参数指定的题目，只有出题人和管理员可以修改
+func getManagedQuestion(c *gin.Context) (*models.Question, bool) {
+	question, ok := getQuestionParam(c)
+	if !ok {
+		return nil, false
+	}
+	if question.CreatedBy != c.GetInt64("userID") && !isAdminRole(c.GetString("role")) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "只有出题人或管理员可以修改题目"})
+		return nil, false
+	}
+	return question, true
+}
+
+// CreateQuestion 向题库添加题目（教师及以上权限）
+func CreateQuestion(c *gin.Context) {
+	var input questionInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的输入数据"})
+		return
+	}
+
+	question := &models.Question{CreatedBy: c.GetInt64("userID")}
+	if !applyQuestionInput(c, &input, question) {
+		return
+	}
+
+	if err := repository.NewQuestionRepository(database.DB).CreateQuestion(c.Request.Context(), question); err != nil {
+		log.Printf("创建题目失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, questionResponse(question, true))
+}
+
+// questionListQuery 从查询参数构造题库查询条件，按知识点筛选时包括下级知识点。失败时已写入响应
+func questionListQuery(c *gin.Context) (*repository.QuestionListQuery, bool) {
+	knowledgePointIDs, ok := knowledgePointFilter(c)
+	if !ok {
+		return nil, false
+	}
+	query := &repository.QuestionListQuery{
+		KnowledgePointIDs: knowledgePointIDs,
+		Type:              c.Query("type"),
+		Subject:           c.Query("subject"),
+		KnowledgePoint:    c.Query("knowledge_point"),
+		Search:            c.Query("search"),
+	}
+	query.Page, _ = strconv.Atoi(c.Query("page"))
+	query.PageSize, _ = strconv.Atoi(c.Query("page_size"))
+	query.Difficulty, _ = strconv.Atoi(c.Query("difficulty"))
+	if c.Query("mine") == "true" {
+		query.CreatedBy = c.GetInt64("userID")
+	}
+	return query, true
+}
+
+// GetQuestions 查询题库（教师及以上权限）
+func GetQuestions(c *gin.Context) {
+	query, ok := questionListQuery(c)
+	if !ok {
+		return
+	}
+	questions, total, err := repository.NewQuestionRepository(database.DB).ListQuestions(c.Request.Context(), query)
+	if err != nil {
+		log.Printf("获取题目列表失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+
+	questionList := []gin.H{}
+	for _, question := range questions {
+		questionList = append(questionList, questionResponse(question, true))
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"questions": questionList,
+		"pagination": gin.H{
+			"page":     query.Page,
+			"pageSize": query.PageSize,
+			"total":    total,
+		},
+	})
+}
+
+// GetQuestion 获取题目详情（教师及以上权限）
+func GetQuestion(c *gin.Context) {
+	question, ok := getQuestionParam(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, questionResponse(question, true))
+}
+
+// UpdateQuestion 修改题目（出题人或管理员）
+// 已组卷的试卷使用修改后的题目，已提交的作答不会重新评分
+func UpdateQuestion(c *gin.Context) {
+	question, ok := getManagedQuestion(c)
+	if !ok {
+		return
+	}
+
+	var input questionInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的输入数据"})
+		return
+	}
+	if !applyQuestionInput(c, &input, question) {
+		return
+	}
+
+	if err := repository.NewQuestionRepository(database.DB).UpdateQuestion(c.Request.Context(), question); err != nil {
+		log.Printf("更新题目失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+
+	c.JSON(http.StatusOK, questionResponse(question, true))
+}
+
+// DeleteQuestion 从题库删除题目（出题人或管理员），已组卷的试卷不受影响
+func DeleteQuestion(c *gin.Context) {
+	question, ok := getManagedQuestion(c)
+	if !ok {
+		return
+	}
+
+	if err := repository.NewQuestionRepository(database.DB).DeleteQuestion(c.Request.Context(), question.ID); err != nil {
+		log.Printf("删除题目失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "题目已删除"})
+}
+
+// GetQuestionKnowledgePoints 获取题库中使用过的知识点（教师及以上权限）
+func GetQuestionKnowledgePoints(c *gin.Context) {
+	points, err := repository.NewQuestionRepository(database.DB).GetKnowledgePoints(c.Request.Context(), c.Query("subject"))
+	if err != nil {
+		log.Printf("获取知识点失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"knowledge_points": points})
+}
+
+// CalibrateItems 根据历史作答立即重新标定题目参数（管理员），标定在后台进行
+func CalibrateItems(c *gin.Context) {
+	jobs.NotifyItemCalibration()
+	c.JSON(http.StatusAccepted, gin.H{"message": "题目参数标定已开始"})
+}
@@ -0,0 +1,282 @@
+package controllers
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"EduGo_servers/internal/database"
+	"EduGo_servers/internal/models"
+	"EduGo_servers/internal/questionio"
+	"EduGo_servers/internal/repository"
+)
+
+const (
+	maxQuestionImportSize = 10 << 20 // 导入文件最大10MB
+	maxQuestionExport     = 1000     // 每次最多导出的题目数
+)
+
+// 题目导入导出的文件格式
+const (
+	questionFormatQTI         = "qti"
+	questionFormatSpreadsheet = "spreadsheet"
+	questionFormatCSV         = "csv"
+)
+
+// draftInput 把导入文件中解析出的题目转换为添加题目的输入，按相同的规则校验
+func draftInput(draft *questionio.Draft) *questionInput {
+	input := &questionInput{
+		Type:            draft.Type,
+		Subject:         draft.Subject,
+		Stem:            draft.Stem,
+		Explanation:     draft.Explanation,
+		Difficulty:      draft.Difficulty,
+		Answer:          draft.Answer,
+		Blanks:          draft.Blanks,
+		ReferenceAnswer: draft.ReferenceAnswer,
+		KnowledgePoints: draft.KnowledgePoints,
+	}
+	for _, option := range draft.Options {
+		input.Options = append(input.Options, questionOptionInput{Content: option.Content, IsCorrect: option.IsCorrect})
+	}
+	return input
+}
+
+// importFormat 导入文件的格式，未指定时按扩展名判断
+func importFormat(format, fileName string) string {
+	if format != "" {
+		return format
+	}
+	switch strings.ToLower(path.Ext(fileName)) {
+	case ".zip", ".xml":
+		return questionFormatQTI
+	case ".csv", ".xlsx":
+		return questionFormatSpreadsheet
+	}
+	return ""
+}
+
+// ImportQuestions 从QTI 2.x 题目包或电子表格导入题目（教师及以上权限）
+// 每道题按添加题目的规则校验，校验通过的题目导入题库，未通过的在校验报告中列出；dry_run为true时只校验不导入
+func ImportQuestions(c *gin.Context) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxQuestionImportSize+1<<20)
+	header, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请上传文件"})
+		return
+	}
+	if header.Size > maxQuestionImportSize {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "文件不能超过10MB"})
+		return
+	}
+	file, err := header.Open()
+	if err != nil {
+		log.Printf("读取导入文件失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+	data, err := io.ReadAll(file)
+	file.Close()
+	if err != nil {
+		log.Printf("读取导入文件失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+
+	var drafts []questionio.Draft
+	switch importFormat(c.PostForm("format"), header.Filename) {
+	case questionFormatQTI:
+		drafts, err = questionio.ParseQTI(header.Filename, data)
+	case questionFormatSpreadsheet:
+		drafts, err = questionio.ParseSpreadsheet(header.Filename, data)
+	default:
+		err = questionio.ErrUnsupportedFormat
+	}
+	switch {
+	case errors.Is(err, questionio.ErrUnsupportedFormat):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "不支持的文件格式，QTI题目包请上传zip或xml文件，电子表格请上传csv或xlsx文件"})
+		return
+	case errors.Is(err, questionio.ErrMissingColumns):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "表头缺少题型或题干列，请使用导入模板"})
+		return
+	case errors.Is(err, questionio.ErrTooManyQuestions):
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("每次最多导入%d道题", questionio.MaxImportQuestions)})
+		return
+	case err != nil:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无法解析文件"})
+		return
+	}
+	if len(drafts) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "文件中没有题目"})
+		return
+	}
+
+	defaultSubject := strings.TrimSpace(c.PostForm("subject"))
+	dryRun := c.PostForm("dry_run") == "true"
+	userID := c.GetInt64("userID")
+	linker := newKnowledgeLinker(c.Request.Context())
+	questions := make([]*models.Question, 0, len(drafts))
+	report := []gin.H{}
+	for i := range drafts {
+		draft := &drafts[i]
+		message := draft.Error
+		if message == "" {
+			input := draftInput(draft)
+			if strings.TrimSpace(input.Subject) == "" {
+				input.Subject = defaultSubject
+			}
+			question := &models.Question{CreatedBy: userID}
+			if message = input.apply(question); message == "" {
+				// 按名称填写的知识点在知识点树中有唯一的同名知识点时自动关联
+				if _, err := linker.link(question, nil); err != nil {
+					log.Printf("关联知识点失败: %v", err)
+					c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+					return
+				}
+				questions = append(questions, question)
+				continue
+			}
+		}
+		report = append(report, gin.H{
+			"source": draft.Source,
+			"error":  message,
+		})
+	}
+
+	ids := []int64{}
+	if !dryRun {
+		if err := repository.NewQuestionRepository(database.DB).CreateQuestions(c.Request.Context(), questions); err != nil {
+			log.Printf("导入题目失败: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+			return
+		}
+		for _, question := range questions {
+			ids = append(ids, question.ID)
+		}
+	}
+
+	message := fmt.Sprintf("已导入%d道题", len(ids))
+	if dryRun {
+		message = "校验完成"
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message":      message,
+		"dry_run":      dryRun,
+		"total":        len(drafts),
+		"valid":        len(questions),
+		"imported":     len(ids),
+		"failed":       len(report),
+		"question_ids": ids,
+		"errors":       report,
+	})
+}
+
+// ExportQuestions 把题目导出为QTI 2.1 题目包或CSV（教师及以上权限）
+// 可以指定题目ID、试卷或者按题库查询条件筛选
+func ExportQuestions(c *gin.Context) {
+	format := c.DefaultQuery("format", questionFormatQTI)
+	if format != questionFormatQTI && format != questionFormatCSV {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的导出格式"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	var questions []*models.Question
+	if value := c.Query("assessment_id"); value != "" {
+		assessmentID, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的试卷ID"})
+			return
+		}
+		paper, err := repository.NewAssessmentRepository(database.DB).GetAssessmentByID(ctx, assessmentID)
+		if err != nil {
+			log.Printf("获取试卷失败: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+			return
+		}
+		if paper == nil || paper.Offering == nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "试卷不存在"})
+			return
+		}
+		if !canManageAssessment(c, paper) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "只有管理员或课程教师可以导出试卷题目"})
+			return
+		}
+		for _, item := range paper.Questions {
+			if item.Question != nil {
+				questions = append(questions, item.Question)
+			}
+		}
+	} else {
+		query, ok := questionListQuery(c)
+		if !ok {
+			return
+		}
+		if value := c.Query("ids"); value != "" {
+			for _, field := range strings.Split(value, ",") {
+				id, err := strconv.ParseInt(strings.TrimSpace(field), 10, 64)
+				if err != nil {
+					c.JSON(http.StatusBadRequest, gin.H{"error": "无效的题目ID"})
+					return
+				}
+				query.IDs = append(query.IDs, id)
+			}
+		}
+
+		var err error
+		questions, err = repository.NewQuestionRepository(database.DB).FindQuestions(ctx, query, maxQuestionExport+1)
+		if err != nil {
+			log.Printf("获取题目失败: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+			return
+		}
+		if len(questions) > maxQuestionExport {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("每次最多导出%d道题，请缩小范围", maxQuestionExport)})
+			return
+		}
+	}
+	if len(questions) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "没有符合条件的题目"})
+		return
+	}
+
+	var buf bytes.Buffer
+	fileName, contentType := "questions_qti.zip", "application/zip"
+	var err error
+	if format == questionFormatCSV {
+		fileName, contentType = "questions.csv", "text/csv; charset=utf-8"
+		err = questionio.WriteSpreadsheet(&buf, questions)
+	} else {
+		err = questionio.ExportQTI(&buf, questions)
+	}
+	if err != nil {
+		log.Printf("导出题目失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+
+	c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": fileName}))
+	c.Data(http.StatusOK, contentType, buf.Bytes())
+}
+
+// GetQuestionImportTemplate 下载电子表格导入模板（教师及以上权限）
+func GetQuestionImportTemplate(c *gin.Context) {
+	var buf bytes.Buffer
+	if err := questionio.WriteTemplate(&buf); err != nil {
+		log.Printf("生成导入模板失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+
+	c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": "题目导入模板.csv"}))
+	c.Data(http.StatusOK, "text/csv; charset=utf-8", buf.Bytes())
+}
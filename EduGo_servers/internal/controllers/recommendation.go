@@ -0,0 +1,728 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"EduGo_servers/internal/database"
+	"EduGo_servers/internal/mastery"
+	"EduGo_servers/internal/models"
+	"EduGo_servers/internal/notify"
+	"EduGo_servers/internal/recommend"
+	"EduGo_servers/internal/repository"
+	"EduGo_servers/internal/review"
+)
+
+const (
+	defaultRecommendations = 10  // 默认返回的推荐数
+	maxRecommendations     = 50  // 最多返回的推荐数
+	maxSimilarStudents     = 50  // 参考反馈的相似同学人数
+	maxOverrideStudents    = 100 // 教师一次最多调整的学生数
+)
+
+// recommendationContext 生成推荐时加载的数据，用于构造返回结果
+type recommendationContext struct {
+	points    map[int64]*models.KnowledgePoint
+	resources map[int64]*models.Resource
+	questions map[int64]*models.Question
+}
+
+// reasonResponse 构造推荐理由的返回数据，message为向学生显示的说明
+func reasonResponse(reason recommend.Reason, points map[int64]*models.KnowledgePoint) gin.H {
+	name := ""
+	if point := points[reason.KnowledgePointID]; point != nil {
+		name = point.Name
+	}
+
+	var message string
+	switch reason.Kind {
+	case recommend.ReasonWeakPoint:
+		message = fmt.Sprintf("「%s」的掌握度只有%d%%", name, int(math.Round(reason.Value*100)))
+	case recommend.ReasonMistake:
+		message = fmt.Sprintf("因为你在「%s」上有%d道错题还没有订正", name, int(reason.Value))
+	case recommend.ReasonRecent:
+		message = fmt.Sprintf("你最近在学习「%s」", name)
+	case recommend.ReasonPeers:
+		message = fmt.Sprintf("%d位情况相似的同学觉得有用", int(reason.Value))
+	}
+
+	response := gin.H{"kind": reason.Kind, "message": message}
+	if reason.KnowledgePointID != 0 {
+		response["knowledge_point_id"] = reason.KnowledgePointID
+	}
+	return response
+}
+
+// recommendationResponse 构造推荐项的返回数据，题目不包含正确答案
+func recommendationResponse(item recommend.Recommendation, data *recommendationContext) gin.H {
+	reasons := make([]gin.H, 0, len(item.Reasons))
+	for _, reason := range item.Reasons {
+		reasons = append(reasons, reasonResponse(reason, data.points))
+	}
+	response := gin.H{
+		"type":    item.Type,
+		"id":      item.ID,
+		"score":   math.Round(item.Score*1000) / 1000,
+		"pinned":  false,
+		"reasons": reasons,
+	}
+	switch item.Type {
+	case recommend.ItemResource:
+		response["resource"] = resourceResponse(data.resources[item.ID])
+	case recommend.ItemQuestion:
+		response["question"] = questionResponse(data.questions[item.ID], false)
+	}
+	return response
+}
+
+// overrideResponse 构造教师调整推荐的返回数据
+func overrideResponse(override *models.RecommendationOverride) gin.H {
+	response := gin.H{
+		"id":         override.ID,
+		"student_id": override.StudentID,
+		"type":       override.ItemType,
+		"item_id":    override.ItemID,
+		"action":     override.Action,
+		"note":       override.Note,
+		"created_by": override.CreatedBy,
+		"created_at": override.CreatedAt,
+		"updated_at": override.UpdatedAt,
+	}
+	if override.Creator != nil {
+		response["creator"] = userSummary(override.Creator)
+	}
+	return response
+}
+
+// loadRecommendationProfile 加载学生的掌握度、错题和近期作答的知识点
+func loadRecommendationProfile(ctx context.Context, studentID int64, now time.Time) (*recommend.Profile, error) {
+	profile := &recommend.Profile{Mastery: map[int64]float64{}, Now: now}
+
+	masteries, err := repository.NewMasteryRepository(database.DB).GetStudentMasteries(ctx, []int64{studentID}, nil)
+	if err != nil {
+		return nil, err
+	}
+	for _, record := range masteries {
+		profile.Mastery[record.KnowledgePointID] = record.Probability
+	}
+
+	recommendationRepo := repository.NewRecommendationRepository(database.DB)
+	if profile.Mistakes, err = recommendationRepo.GetMistakePointCounts(ctx, studentID); err != nil {
+		return nil, err
+	}
+	if profile.Recent, err = recommendationRepo.GetRecentPoints(ctx, studentID, now.Add(-recommend.RecentWindow)); err != nil {
+		return nil, err
+	}
+	return profile, nil
+}
+
+// resourceCandidate 资源对应的候选推荐项
+func resourceCandidate(resource *models.Resource) recommend.Candidate {
+	candidate := recommend.Candidate{ItemKey: recommend.ItemKey{Type: recommend.ItemResource, ID: resource.ID}}
+	for _, point := range resource.KnowledgePoints {
+		candidate.KnowledgePointIDs = append(candidate.KnowledgePointIDs, point.ID)
+	}
+	return candidate
+}
+
+// questionCandidate 题目对应的候选推荐项
+func questionCandidate(question *models.Question) recommend.Candidate {
+	candidate := recommend.Candidate{ItemKey: recommend.ItemKey{Type: recommend.ItemQuestion, ID: question.ID}}
+	for _, tag := range question.Tags {
+		if tag.KnowledgePointID != nil {
+			candidate.KnowledgePointIDs = append(candidate.KnowledgePointIDs, *tag.KnowledgePointID)
+		}
+	}
+	return candidate
+}
+
+// GetStudentRecommendations 为学生推荐学习资源和练习题（本人、家长、管理员或该学生的教师）
+// 根据薄弱知识点、没有订正的错题、近期学习的知识点和情况相似的同学的反馈排序，教师置顶的推荐排在最前面
+// 学生给出反馈的项和教师设为不再推荐的项不再出现
+func GetStudentRecommendations(c *gin.Context) {
+	studentID, ok := parseIDParam(c, "id", "无效的学生ID")
+	if !ok {
+		return
+	}
+	allowed, err := canViewStudentData(c, studentID)
+	if err != nil {
+		log.Printf("检查用户关系失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+	if !allowed {
+		c.JSON(http.StatusForbidden, gin.H{"error": "无权查看该学生的推荐"})
+		return
+	}
+
+	limit := defaultRecommendations
+	if value := c.Query("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 || n > maxRecommendations {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "推荐数必须在1到" + strconv.Itoa(maxRecommendations) + "之间"})
+			return
+		}
+		limit = n
+	}
+	strategyName := c.DefaultQuery("strategy", recommend.Current)
+	strategy, ok := recommend.GetStrategy(strategyName)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的排序策略"})
+		return
+	}
+	subject := strings.TrimSpace(c.Query("subject"))
+
+	ctx := c.Request.Context()
+	profile, err := loadRecommendationProfile(ctx, studentID, time.Now())
+	if err != nil {
+		log.Printf("获取学生学习情况失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+
+	// 推荐围绕薄弱、有错题和最近学习但还没有掌握的知识点
+	var pointIDs []int64
+	for pointID, probability := range profile.Mastery {
+		if probability < mastery.WeakThreshold {
+			pointIDs = append(pointIDs, pointID)
+		}
+	}
+	for pointID := range profile.Mistakes {
+		pointIDs = append(pointIDs, pointID)
+	}
+	for pointID := range profile.Recent {
+		if probability, known := profile.Mastery[pointID]; !known || probability < mastery.MasteredThreshold {
+			pointIDs = append(pointIDs, pointID)
+		}
+	}
+	points, err := repository.NewKnowledgePointRepository(database.DB).GetKnowledgePointsByIDs(ctx, uniqueIDs(pointIDs))
+	if err != nil {
+		log.Printf("获取知识点失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+	data := &recommendationContext{
+		points:    map[int64]*models.KnowledgePoint{},
+		resources: map[int64]*models.Resource{},
+		questions: map[int64]*models.Question{},
+	}
+	var targetIDs, weakIDs []int64
+	for _, point := range points {
+		data.points[point.ID] = point
+		if subject != "" && point.Subject != subject {
+			continue
+		}
+		targetIDs = append(targetIDs, point.ID)
+		if probability, known := profile.Mastery[point.ID]; known && probability < mastery.WeakThreshold {
+			weakIDs = append(weakIDs, point.ID)
+		}
+	}
+
+	recommendationRepo := repository.NewRecommendationRepository(database.DB)
+	similar, err := recommendationRepo.GetSimilarStudents(ctx, studentID, weakIDs, maxSimilarStudents)
+	if err == nil {
+		profile.PeerHelpful, err = recommendationRepo.GetHelpfulCounts(ctx, similar)
+	}
+	if err != nil {
+		log.Printf("获取相似同学的反馈失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+
+	feedback, err := recommendationRepo.GetStudentFeedback(ctx, studentID)
+	if err != nil {
+		log.Printf("获取推荐反馈失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+	overrides, err := recommendationRepo.GetStudentOverrides(ctx, studentID)
+	if err != nil {
+		log.Printf("获取教师调整的推荐失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+	answered := map[recommend.ItemKey]time.Time{}
+	for _, record := range feedback {
+		answered[recommend.ItemKey{Type: record.ItemType, ID: record.ItemID}] = record.UpdatedAt
+	}
+	excluded := map[recommend.ItemKey]bool{}
+	for key := range answered {
+		excluded[key] = true
+	}
+	var pins []*models.RecommendationOverride
+	for _, override := range overrides {
+		key := recommend.ItemKey{Type: override.ItemType, ID: override.ItemID}
+		excluded[key] = true
+		// 学生在教师置顶之后给出反馈的不再置顶
+		if at, ok := answered[key]; override.Action == models.OverridePin && (!ok || at.Before(override.UpdatedAt)) {
+			pins = append(pins, override)
+		}
+	}
+
+	// 收集候选项：知识点相关的资源和练习题，以及相似同学认为有用的项
+	var peerResourceIDs, peerQuestionIDs []int64
+	for key := range profile.PeerHelpful {
+		if excluded[key] {
+			continue
+		}
+		if key.Type == recommend.ItemResource {
+			peerResourceIDs = append(peerResourceIDs, key.ID)
+		} else {
+			peerQuestionIDs = append(peerQuestionIDs, key.ID)
+		}
+	}
+	resourceRepo := repository.NewResourceRepository(database.DB)
+	courseIDs, err := resourceRepo.GetUserCourseIDs(ctx, studentID)
+	if err != nil {
+		log.Printf("获取用户课程失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+	var resourceQueries []*repository.ResourceListQuery
+	var questionQueries []*repository.PracticeQuestionQuery
+	if len(targetIDs) > 0 {
+		resourceQueries = append(resourceQueries, &repository.ResourceListQuery{KnowledgePointIDs: targetIDs})
+		questionQueries = append(questionQueries, &repository.PracticeQuestionQuery{KnowledgePointIDs: targetIDs})
+	}
+	if len(peerResourceIDs) > 0 {
+		resourceQueries = append(resourceQueries, &repository.ResourceListQuery{IDs: peerResourceIDs, Subject: subject})
+	}
+	if len(peerQuestionIDs) > 0 {
+		questionQueries = append(questionQueries, &repository.PracticeQuestionQuery{IDs: peerQuestionIDs, Subject: subject})
+	}
+
+	var candidates []recommend.Candidate
+	for _, query := range resourceQueries {
+		query.PageSize = repository.MaxPageSize
+		query.ViewerID = studentID
+		query.ViewerCourseIDs = courseIDs
+		resources, _, err := resourceRepo.ListResources(ctx, query)
+		if err != nil {
+			log.Printf("获取候选资源失败: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+			return
+		}
+		for _, resource := range resources {
+			if data.resources[resource.ID] == nil && !excluded[recommend.ItemKey{Type: recommend.ItemResource, ID: resource.ID}] {
+				data.resources[resource.ID] = resource
+				candidates = append(candidates, resourceCandidate(resource))
+			}
+		}
+	}
+	for _, query := range questionQueries {
+		query.StudentID = studentID
+		questions, err := recommendationRepo.GetPracticeQuestions(ctx, query)
+		if err != nil {
+			log.Printf("获取候选题目失败: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+			return
+		}
+		for _, question := range questions {
+			if data.questions[question.ID] == nil && !excluded[recommend.ItemKey{Type: recommend.ItemQuestion, ID: question.ID}] {
+				data.questions[question.ID] = question
+				candidates = append(candidates, questionCandidate(question))
+			}
+		}
+	}
+
+	result := make([]gin.H, 0, limit)
+	for _, pin := range pins {
+		if len(result) >= limit {
+			break
+		}
+		var itemSubject string
+		item := recommend.Recommendation{Candidate: recommend.Candidate{ItemKey: recommend.ItemKey{Type: pin.ItemType, ID: pin.ItemID}}}
+		switch pin.ItemType {
+		case recommend.ItemResource:
+			resource, err := resourceRepo.GetResourceByID(ctx, pin.ItemID)
+			if err != nil {
+				log.Printf("获取资源失败: %v", err)
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+				return
+			}
+			// 置顶后资源改为学生无法查看时不再显示
+			if resource == nil || !resource.VisibleTo(studentID, courseIDs) {
+				continue
+			}
+			data.resources[resource.ID] = resource
+			itemSubject = resource.Subject
+		case recommend.ItemQuestion:
+			questions, err := repository.NewQuestionRepository(database.DB).GetQuestionsByIDs(ctx, []int64{pin.ItemID})
+			if err != nil {
+				log.Printf("获取题目失败: %v", err)
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+				return
+			}
+			if len(questions) == 0 {
+				continue
+			}
+			data.questions[pin.ItemID] = questions[0]
+			itemSubject = questions[0].Subject
+		}
+		if subject != "" && itemSubject != "" && itemSubject != subject {
+			continue
+		}
+
+		response := recommendationResponse(item, data)
+		message := "老师推荐"
+		if pin.Note != "" {
+			message += "：" + pin.Note
+		}
+		response["pinned"] = true
+		response["reasons"] = []gin.H{{"kind": recommend.ReasonTeacher, "message": message}}
+		response["pinned_by"] = pin.CreatedBy
+		result = append(result, response)
+	}
+
+	for _, item := range strategy.Rank(profile, candidates) {
+		if len(result) >= limit {
+			break
+		}
+		result = append(result, recommendationResponse(item, data))
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"student_id":      studentID,
+		"strategy":        strategyName,
+		"recommendations": result,
+	})
+}
+
+// recommendationItemExists 检查推荐项是否存在
+func recommendationItemExists(ctx context.Context, itemType string, itemID int64) (bool, error) {
+	if itemType == recommend.ItemResource {
+		resource, err := repository.NewResourceRepository(database.DB).GetResourceByID(ctx, itemID)
+		return resource != nil, err
+	}
+	questions, err := repository.NewQuestionRepository(database.DB).GetQuestionsByIDs(ctx, []int64{itemID})
+	return len(questions) > 0, err
+}
+
+// SubmitRecommendationFeedback 学生对推荐项给出反馈（学生本人），给出反馈后该项不再推荐给本人
+// 认为有用的项会更多地推荐给情况相似的同学
+func SubmitRecommendationFeedback(c *gin.Context) {
+	if c.GetString("role") != models.RoleStudent {
+		c.JSON(http.StatusForbidden, gin.H{"error": "只有学生可以反馈推荐"})
+		return
+	}
+
+	var input struct {
+		Type   string `json:"type" binding:"required"`
+		ID     int64  `json:"id" binding:"required"`
+		Action string `json:"action" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的输入数据"})
+		return
+	}
+	if !recommend.IsValidItemType(input.Type) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的推荐类型"})
+		return
+	}
+	if !models.IsValidFeedback(input.Action) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的反馈"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	exists, err := recommendationItemExists(ctx, input.Type, input.ID)
+	if err != nil {
+		log.Printf("获取推荐项失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+	if !exists {
+		c.JSON(http.StatusNotFound, gin.H{"error": "推荐项不存在"})
+		return
+	}
+
+	feedback := &models.RecommendationFeedback{
+		StudentID: c.GetInt64("userID"),
+		ItemType:  input.Type,
+		ItemID:    input.ID,
+		Action:    input.Action,
+	}
+	if err := repository.NewRecommendationRepository(database.DB).SaveFeedback(ctx, feedback); err != nil {
+		log.Printf("保存推荐反馈失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "反馈已记录"})
+}
+
+// AddRecommendedQuestion 学生把推荐的练习题加入自己的复习队列（学生本人），当天即可复习
+// 只能加入可以推荐的练习题，即学生已经看到答案的题目
+func AddRecommendedQuestion(c *gin.Context) {
+	if c.GetString("role") != models.RoleStudent {
+		c.JSON(http.StatusForbidden, gin.H{"error": "只有学生可以加入复习"})
+		return
+	}
+	questionID, ok := parseIDParam(c, "id", "无效的题目ID")
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	studentID := c.GetInt64("userID")
+	reviewRepo := repository.NewReviewRepository(database.DB)
+	existing, err := reviewRepo.GetItems(ctx, []int64{studentID}, []int64{questionID})
+	if err != nil {
+		log.Printf("获取复习队列失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+	if len(existing) > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "题目已在复习队列中"})
+		return
+	}
+
+	recommendationRepo := repository.NewRecommendationRepository(database.DB)
+	questions, err := recommendationRepo.GetPracticeQuestions(ctx, &repository.PracticeQuestionQuery{
+		StudentID: studentID,
+		IDs:       []int64{questionID},
+		Limit:     1,
+	})
+	if err != nil {
+		log.Printf("获取题目失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+	if len(questions) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "题目不存在或不能加入复习"})
+		return
+	}
+
+	item := &models.ReviewItem{
+		StudentID:  studentID,
+		QuestionID: questionID,
+		Source:     models.ReviewSourceRecommend,
+		EaseFactor: review.InitialEaseFactor,
+		DueAt:      review.StartOfDay(time.Now()),
+	}
+	if err := reviewRepo.SaveItems(ctx, []*models.ReviewItem{item}); err != nil {
+		log.Printf("加入复习队列失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+	feedback := &models.RecommendationFeedback{
+		StudentID: studentID,
+		ItemType:  recommend.ItemQuestion,
+		ItemID:    questionID,
+		Action:    models.FeedbackAccepted,
+	}
+	if err := recommendationRepo.SaveFeedback(ctx, feedback); err != nil {
+		log.Printf("保存推荐反馈失败: %v", err)
+	}
+
+	item.Question = questions[0]
+	c.JSON(http.StatusCreated, reviewItemResponse(item))
+}
+
+// GetStudentRecommendationOverrides 获取教师对学生推荐结果的调整（本人、家长、管理员或该学生的教师）
+func GetStudentRecommendationOverrides(c *gin.Context) {
+	studentID, ok := parseIDParam(c, "id", "无效的学生ID")
+	if !ok {
+		return
+	}
+	allowed, err := canViewStudentData(c, studentID)
+	if err != nil {
+		log.Printf("检查用户关系失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+	if !allowed {
+		c.JSON(http.StatusForbidden, gin.H{"error": "无权查看该学生的推荐"})
+		return
+	}
+
+	overrides, err := repository.NewRecommendationRepository(database.DB).GetStudentOverrides(c.Request.Context(), studentID)
+	if err != nil {
+		log.Printf("获取教师调整的推荐失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+
+	list := make([]gin.H, 0, len(overrides))
+	for _, override := range overrides {
+		list = append(list, overrideResponse(override))
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"student_id": studentID,
+		"overrides":  list,
+	})
+}
+
+// CreateRecommendationOverrides 教师调整学生的推荐结果（管理员或与学生有师生关系的教师）
+// pin置顶推荐一项，note作为推荐理由向学生显示；block不再向学生推荐该项。同一项再次调整时覆盖之前的调整
+func CreateRecommendationOverrides(c *gin.Context) {
+	var input struct {
+		StudentIDs []int64 `json:"student_ids" binding:"required"`
+		Type       string  `json:"type" binding:"required"`
+		ID         int64   `json:"id" binding:"required"`
+		Action     string  `json:"action" binding:"required"`
+		Note       string  `json:"note" binding:"max=500"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的输入数据"})
+		return
+	}
+	if len(input.StudentIDs) == 0 || len(input.StudentIDs) > maxOverrideStudents {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("请选择1到%d名学生", maxOverrideStudents)})
+		return
+	}
+	if !recommend.IsValidItemType(input.Type) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的推荐类型"})
+		return
+	}
+	if input.Action != models.OverridePin && input.Action != models.OverrideBlock {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的调整方式"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	students, message, err := loadClassStudents(ctx, input.StudentIDs)
+	if err != nil {
+		log.Printf("获取学生失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+	if message != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": message})
+		return
+	}
+	studentIDs := make([]int64, 0, len(students))
+	for _, student := range students {
+		studentIDs = append(studentIDs, student.ID)
+	}
+	if !requireOwnStudents(c, studentIDs, "只能调整自己的学生的推荐") {
+		return
+	}
+
+	var title string
+	if input.Type == recommend.ItemResource {
+		resourceRepo := repository.NewResourceRepository(database.DB)
+		resource, err := resourceRepo.GetResourceByID(ctx, input.ID)
+		if err != nil {
+			log.Printf("获取资源失败: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+			return
+		}
+		if resource == nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "资源不存在"})
+			return
+		}
+		// 置顶的资源必须是学生可以查看的
+		for _, studentID := range studentIDs {
+			if input.Action != models.OverridePin {
+				break
+			}
+			courseIDs, err := resourceRepo.GetUserCourseIDs(ctx, studentID)
+			if err != nil {
+				log.Printf("获取用户课程失败: %v", err)
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+				return
+			}
+			if !resource.VisibleTo(studentID, courseIDs) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "学生无法查看该资源: " + strconv.FormatInt(studentID, 10)})
+				return
+			}
+		}
+		title = resource.Title
+	} else {
+		questions, err := repository.NewQuestionRepository(database.DB).GetQuestionsByIDs(ctx, []int64{input.ID})
+		if err != nil {
+			log.Printf("获取题目失败: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+			return
+		}
+		if len(questions) == 0 {
+			c.JSON(http.StatusNotFound, gin.H{"error": "题目不存在"})
+			return
+		}
+		title = "一道练习题"
+	}
+
+	teacherID := c.GetInt64("userID")
+	overrides := make([]*models.RecommendationOverride, 0, len(studentIDs))
+	for _, studentID := range studentIDs {
+		overrides = append(overrides, &models.RecommendationOverride{
+			StudentID: studentID,
+			ItemType:  input.Type,
+			ItemID:    input.ID,
+			Action:    input.Action,
+			Note:      strings.TrimSpace(input.Note),
+			CreatedBy: teacherID,
+		})
+	}
+	if err := repository.NewRecommendationRepository(database.DB).SaveOverrides(ctx, overrides); err != nil {
+		log.Printf("保存教师调整的推荐失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+
+	if input.Action == models.OverridePin {
+		notifications := make([]*models.Notification, 0, len(studentIDs))
+		for _, studentID := range studentIDs {
+			notifications = append(notifications, &models.Notification{
+				UserID:  studentID,
+				Type:    models.NotificationRecommendation,
+				Title:   "老师给你推荐了学习内容",
+				Content: fmt.Sprintf("老师给你推荐了「%s」", title),
+				Link:    fmt.Sprintf("/recommendations/students/%d", studentID),
+			})
+		}
+		if err := notify.Send(ctx, database.DB, notifications...); err != nil {
+			log.Printf("发送推荐通知失败: %v", err)
+		}
+	}
+
+	list := make([]gin.H, 0, len(overrides))
+	for _, override := range overrides {
+		list = append(list, overrideResponse(override))
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message":   "推荐已调整",
+		"overrides": list,
+	})
+}
+
+// DeleteRecommendationOverride 撤销教师对推荐的调整（调整人或管理员）
+func DeleteRecommendationOverride(c *gin.Context) {
+	overrideID, ok := parseIDParam(c, "id", "无效的调整ID")
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	recommendationRepo := repository.NewRecommendationRepository(database.DB)
+	override, err := recommendationRepo.GetOverrideByID(ctx, overrideID)
+	if err != nil {
+		log.Printf("获取教师调整的推荐失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+	if override == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "调整不存在"})
+		return
+	}
+	if override.CreatedBy != c.GetInt64("userID") && !isAdminRole(c.GetString("role")) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "只能撤销自己的调整"})
+		return
+	}
+
+	if err := recommendationRepo.DeleteOverride(ctx, override.ID); err != nil {
+		log.Printf("撤销教师调整的推荐失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "调整已撤销"})
+}
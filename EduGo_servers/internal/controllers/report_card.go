@@ -0,0 +1,580 @@
+package controllers
+
+import (
+	"bytes"
+	"errors"
+	"log"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"EduGo_servers/internal/database"
+	"EduGo_servers/internal/jobs"
+	"EduGo_servers/internal/models"
+	"EduGo_servers/internal/reportcard"
+	"EduGo_servers/internal/repository"
+)
+
+// reportCardTemplateResponse 构造成绩单模板的返回数据
+func reportCardTemplateResponse(template *models.ReportCardTemplate) gin.H {
+	return gin.H{
+		"id":              template.ID,
+		"name":            template.Name,
+		"title":           template.Title,
+		"school_name":     template.SchoolName,
+		"footer":          template.Footer,
+		"show_categories": template.ShowCategories,
+		"show_attendance": template.ShowAttendance,
+		"show_comments":   template.ShowComments,
+		"html":            template.HTML,
+		"is_default":      template.IsDefault,
+		"created_by":      template.CreatedBy,
+		"created_at":      template.CreatedAt,
+		"updated_at":      template.UpdatedAt,
+	}
+}
+
+// reportCardCommentResponse 构造成绩单评语的返回数据
+func reportCardCommentResponse(comment *models.ReportCardComment) gin.H {
+	var author gin.H
+	if comment.Author != nil {
+		author = userSummary(comment.Author)
+	}
+	return gin.H{
+		"id":          comment.ID,
+		"term_id":     comment.TermID,
+		"student_id":  comment.StudentID,
+		"offering_id": comment.OfferingID,
+		"author":      author,
+		"content":     comment.Content,
+		"updated_at":  comment.UpdatedAt,
+	}
+}
+
+// reportCardBatchResponse 构造成绩单批量生成任务的返回数据
+func reportCardBatchResponse(batch *models.ReportCardBatch) gin.H {
+	return gin.H{
+		"id":           batch.ID,
+		"class_id":     batch.ClassID,
+		"term_id":      batch.TermID,
+		"template_id":  batch.TemplateID,
+		"format":       batch.Format,
+		"status":       batch.Status,
+		"total":        batch.Total,
+		"generated":    batch.Generated,
+		"error":        batch.Error,
+		"requested_by": batch.RequestedBy,
+		"created_at":   batch.CreatedAt,
+		"completed_at": batch.CompletedAt,
+	}
+}
+
+// isStudentClassTeacher 判断当前用户是否为学生所在班级的班主任或任课教师
+func isStudentClassTeacher(c *gin.Context, studentID int64) (bool, error) {
+	classRepo := repository.NewClassRepository(database.DB)
+	classes, err := classRepo.GetStudentClasses(c.Request.Context(), []int64{studentID})
+	if err != nil {
+		return false, err
+	}
+	classID, ok := classes[studentID]
+	if !ok {
+		return false, nil
+	}
+	class, err := classRepo.GetClassByID(c.Request.Context(), classID)
+	if err != nil || class == nil {
+		return false, err
+	}
+	return class.IsTeacher(c.GetInt64("userID")), nil
+}
+
+// canViewReportCard 判断当前用户能否查看学生的成绩单：本人、管理员、家长或学生所在班级的教师
+func canViewReportCard(c *gin.Context, studentID int64) (bool, error) {
+	allowed, err := canAccessUserData(c, studentID)
+	if err != nil || allowed {
+		return allowed, err
+	}
+	if c.GetString("role") != models.RoleTeacher {
+		return false, nil
+	}
+	return isStudentClassTeacher(c, studentID)
+}
+
+// canCommentReportCard 判断当前用户能否填写评语：课程评语由管理员或任课教师填写，总评语由管理员或班级教师填写
+func canCommentReportCard(c *gin.Context, studentID, offeringID int64) (bool, string, error) {
+	if isAdminRole(c.GetString("role")) {
+		return true, "", nil
+	}
+	if offeringID == 0 {
+		allowed, err := isStudentClassTeacher(c, studentID)
+		return allowed, "只有管理员或班级教师可以填写总评语", err
+	}
+
+	offering, err := repository.NewCourseOfferingRepository(database.DB).GetOfferingByID(c.Request.Context(), offeringID)
+	if err != nil || offering == nil {
+		return false, "开课不存在", err
+	}
+	userID := c.GetInt64("userID")
+	if offering.HasTeacher(userID) {
+		return true, "", nil
+	}
+	course, err := repository.NewCourseRepository(database.DB).GetCourseByID(c.Request.Context(), offering.CourseID)
+	if err != nil || course == nil {
+		return false, "开课不存在", err
+	}
+	return course.HasTeacher(userID), "只有管理员或任课教师可以填写课程评语", nil
+}
+
+// reportCardQuery 解析成绩单查询的学生和学期参数，未指定学生时为当前用户，未指定学期时为当前学期。失败时已写入响应
+func reportCardQuery(c *gin.Context) (int64, *models.AcademicTerm, bool) {
+	studentID := c.GetInt64("userID")
+	if value := c.Query("student_id"); value != "" {
+		id, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的学生ID"})
+			return 0, nil, false
+		}
+		studentID = id
+	}
+
+	allowed, err := canViewReportCard(c, studentID)
+	if err != nil {
+		log.Printf("检查成绩单权限失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return 0, nil, false
+	}
+	if !allowed {
+		c.JSON(http.StatusForbidden, gin.H{"error": "无权查看该学生的成绩单"})
+		return 0, nil, false
+	}
+
+	term, ok := resolveTimetableTerm(c)
+	if !ok {
+		return 0, nil, false
+	}
+	return studentID, term, true
+}
+
+// parseReportCardFormat 解析成绩单格式，默认为PDF
+func parseReportCardFormat(value string) (string, bool) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "", models.ReportCardFormatPDF:
+		return models.ReportCardFormatPDF, true
+	case models.ReportCardFormatHTML:
+		return models.ReportCardFormatHTML, true
+	}
+	return "", false
+}
+
+// GetReportCard 生成学生的学期成绩单
+func GetReportCard(c *gin.Context) {
+	studentID, term, ok := reportCardQuery(c)
+	if !ok {
+		return
+	}
+	format, ok := parseReportCardFormat(c.Query("format"))
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "格式只能为pdf或html"})
+		return
+	}
+	var templateID *int64
+	if value := c.Query("template_id"); value != "" {
+		id, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的模板ID"})
+			return
+		}
+		templateID = &id
+	}
+
+	ctx := c.Request.Context()
+	options, err := reportcard.ResolveOptions(ctx, database.DB, templateID)
+	if errors.Is(err, reportcard.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "成绩单模板不存在"})
+		return
+	}
+	if err != nil {
+		log.Printf("获取成绩单模板失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+
+	data, err := reportcard.Build(ctx, database.DB, studentID, term.ID)
+	if errors.Is(err, reportcard.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "学生不存在"})
+		return
+	}
+	if err != nil {
+		log.Printf("汇总成绩单数据失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := reportcard.Render(&buf, format, options, data); err != nil {
+		log.Printf("生成成绩单失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+
+	if format == models.ReportCardFormatHTML {
+		c.Data(http.StatusOK, "text/html; charset=utf-8", buf.Bytes())
+		return
+	}
+	disposition := mime.FormatMediaType("attachment", map[string]string{"filename": reportcard.FileName(data, format)})
+	c.Header("Content-Disposition", disposition)
+	c.Data(http.StatusOK, "application/pdf", buf.Bytes())
+}
+
+// GetReportCardComments 获取学生在学期成绩单上的评语
+func GetReportCardComments(c *gin.Context) {
+	studentID, term, ok := reportCardQuery(c)
+	if !ok {
+		return
+	}
+
+	comments, err := repository.NewReportCardRepository(database.DB).GetComments(c.Request.Context(), studentID, term.ID)
+	if err != nil {
+		log.Printf("获取成绩单评语失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+
+	result := make([]gin.H, 0, len(comments))
+	for _, comment := range comments {
+		result = append(result, reportCardCommentResponse(comment))
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"student_id": studentID,
+		"term_id":    term.ID,
+		"comments":   result,
+	})
+}
+
+// SaveReportCardComment 填写或修改成绩单评语
+func SaveReportCardComment(c *gin.Context) {
+	var input struct {
+		StudentID  int64  `json:"student_id" binding:"required"`
+		TermID     int64  `json:"term_id" binding:"required"`
+		OfferingID int64  `json:"offering_id"`
+		Content    string `json:"content" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的输入数据"})
+		return
+	}
+	content := strings.TrimSpace(input.Content)
+	if content == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "评语不能为空"})
+		return
+	}
+
+	term, ok := resolveTerm(c, input.TermID, "")
+	if !ok {
+		return
+	}
+
+	allowed, message, err := canCommentReportCard(c, input.StudentID, input.OfferingID)
+	if err != nil {
+		log.Printf("检查成绩单权限失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+	if !allowed {
+		c.JSON(http.StatusForbidden, gin.H{"error": message})
+		return
+	}
+
+	comment := &models.ReportCardComment{
+		TermID:     term.ID,
+		StudentID:  input.StudentID,
+		OfferingID: input.OfferingID,
+		AuthorID:   c.GetInt64("userID"),
+		Content:    content,
+	}
+	if err := repository.NewReportCardRepository(database.DB).SaveComment(c.Request.Context(), comment); err != nil {
+		log.Printf("保存成绩单评语失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+
+	c.JSON(http.StatusOK, reportCardCommentResponse(comment))
+}
+
+// GetReportCardTemplates 获取成绩单模板列表
+func GetReportCardTemplates(c *gin.Context) {
+	templates, err := repository.NewReportCardRepository(database.DB).GetTemplates(c.Request.Context())
+	if err != nil {
+		log.Printf("获取成绩单模板失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+
+	result := make([]gin.H, 0, len(templates))
+	for _, template := range templates {
+		result = append(result, reportCardTemplateResponse(template))
+	}
+	c.JSON(http.StatusOK, gin.H{"templates": result})
+}
+
+// reportCardTemplateInput 成绩单模板的输入数据
+type reportCardTemplateInput struct {
+	Name           string `json:"name" binding:"required"`
+	Title          string `json:"title"`
+	SchoolName     string `json:"school_name"`
+	Footer         string `json:"footer"`
+	ShowCategories *bool  `json:"show_categories"`
+	ShowAttendance *bool  `json:"show_attendance"`
+	ShowComments   *bool  `json:"show_comments"`
+	HTML           string `json:"html"`
+	IsDefault      bool   `json:"is_default"`
+}
+
+// apply 校验输入并写入模板，返回错误信息
+func (input *reportCardTemplateInput) apply(c *gin.Context, template *models.ReportCardTemplate) (string, error) {
+	name := strings.TrimSpace(input.Name)
+	if name == "" {
+		return "模板名称不能为空", nil
+	}
+	exists, err := repository.NewReportCardRepository(database.DB).TemplateNameExists(c.Request.Context(), name, template.ID)
+	if err != nil {
+		return "", err
+	}
+	if exists {
+		return "模板名称已存在", nil
+	}
+	if strings.TrimSpace(input.HTML) != "" {
+		if _, err := reportcard.ParseHTMLTemplate(input.HTML); err != nil {
+			return "HTML模板无效: " + err.Error(), nil
+		}
+	}
+
+	template.Name = name
+	template.Title = strings.TrimSpace(input.Title)
+	template.SchoolName = strings.TrimSpace(input.SchoolName)
+	template.Footer = strings.TrimSpace(input.Footer)
+	template.HTML = input.HTML
+	template.IsDefault = input.IsDefault
+	if input.ShowCategories != nil {
+		template.ShowCategories = *input.ShowCategories
+	}
+	if input.ShowAttendance != nil {
+		template.ShowAttendance = *input.ShowAttendance
+	}
+	if input.ShowComments != nil {
+		template.ShowComments = *input.ShowComments
+	}
+	return "", nil
+}
+
+// CreateReportCardTemplate 创建成绩单模板（管理员）
+func CreateReportCardTemplate(c *gin.Context) {
+	var input reportCardTemplateInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的输入数据"})
+		return
+	}
+
+	defaults := reportcard.DefaultOptions()
+	template := &models.ReportCardTemplate{
+		ShowCategories: defaults.ShowCategories,
+		ShowAttendance: defaults.ShowAttendance,
+		ShowComments:   defaults.ShowComments,
+		CreatedBy:      c.GetInt64("userID"),
+	}
+	message, err := input.apply(c, template)
+	if err != nil {
+		log.Printf("检查成绩单模板失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+	if message != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": message})
+		return
+	}
+
+	if err := repository.NewReportCardRepository(database.DB).CreateTemplate(c.Request.Context(), template); err != nil {
+		log.Printf("创建成绩单模板失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, reportCardTemplateResponse(template))
+}
+
+// getReportCardTemplateParam 获取路径参数指定的成绩单模板
+func getReportCardTemplateParam(c *gin.Context) (*models.ReportCardTemplate, bool) {
+	id, ok := parseIDParam(c, "id", "无效的模板ID")
+	if !ok {
+		return nil, false
+	}
+	template, err := repository.NewReportCardRepository(database.DB).GetTemplateByID(c.Request.Context(), id)
+	if err != nil {
+		log.Printf("获取成绩单模板失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return nil, false
+	}
+	if template == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "成绩单模板不存在"})
+		return nil, false
+	}
+	return template, true
+}
+
+// UpdateReportCardTemplate 修改成绩单模板（管理员）
+func UpdateReportCardTemplate(c *gin.Context) {
+	template, ok := getReportCardTemplateParam(c)
+	if !ok {
+		return
+	}
+
+	var input reportCardTemplateInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的输入数据"})
+		return
+	}
+	message, err := input.apply(c, template)
+	if err != nil {
+		log.Printf("检查成绩单模板失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+	if message != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": message})
+		return
+	}
+
+	if err := repository.NewReportCardRepository(database.DB).UpdateTemplate(c.Request.Context(), template); err != nil {
+		log.Printf("更新成绩单模板失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+
+	c.JSON(http.StatusOK, reportCardTemplateResponse(template))
+}
+
+// DeleteReportCardTemplate 删除成绩单模板（管理员）
+func DeleteReportCardTemplate(c *gin.Context) {
+	template, ok := getReportCardTemplateParam(c)
+	if !ok {
+		return
+	}
+
+	if err := repository.NewReportCardRepository(database.DB).DeleteTemplate(c.Request.Context(), template.ID); err != nil {
+		log.Printf("删除成绩单模板失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "成绩单模板已删除"})
+}
+
+// CreateReportCardBatch 为班级批量生成成绩单（管理员或班级教师），在后台执行
+func CreateReportCardBatch(c *gin.Context) {
+	var input struct {
+		ClassID    int64  `json:"class_id" binding:"required"`
+		TermID     int64  `json:"term_id" binding:"required"`
+		TemplateID *int64 `json:"template_id"`
+		Format     string `json:"format"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的输入数据"})
+		return
+	}
+	format, ok := parseReportCardFormat(input.Format)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "格式只能为pdf或html"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	class, err := repository.NewClassRepository(database.DB).GetClassByID(ctx, input.ClassID)
+	if err != nil {
+		log.Printf("获取班级失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+	if class == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "班级不存在"})
+		return
+	}
+	if !isAdminRole(c.GetString("role")) && !class.IsTeacher(c.GetInt64("userID")) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "无权访问该班级"})
+		return
+	}
+
+	term, ok := resolveTerm(c, input.TermID, "")
+	if !ok {
+		return
+	}
+	if _, err := reportcard.ResolveOptions(ctx, database.DB, input.TemplateID); errors.Is(err, reportcard.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "成绩单模板不存在"})
+		return
+	} else if err != nil {
+		log.Printf("获取成绩单模板失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+
+	batch := &models.ReportCardBatch{
+		ClassID:     class.ID,
+		TermID:      term.ID,
+		TemplateID:  input.TemplateID,
+		Format:      format,
+		Status:      models.ReportCardBatchPending,
+		RequestedBy: c.GetInt64("userID"),
+	}
+	if err := repository.NewReportCardRepository(database.DB).CreateBatch(ctx, batch); err != nil {
+		log.Printf("创建成绩单生成任务失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+	jobs.NotifyReportCardBatch()
+
+	c.JSON(http.StatusAccepted, reportCardBatchResponse(batch))
+}
+
+// getReportCardBatchParam 获取路径参数指定的成绩单生成任务，只有发起人和管理员可以访问
+func getReportCardBatchParam(c *gin.Context) (*models.ReportCardBatch, bool) {
+	id, ok := parseIDParam(c, "id", "无效的任务ID")
+	if !ok {
+		return nil, false
+	}
+	batch, err := repository.NewReportCardRepository(database.DB).GetBatchByID(c.Request.Context(), id)
+	if err != nil {
+		log.Printf("获取成绩单生成任务失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return nil, false
+	}
+	if batch == nil || (batch.RequestedBy != c.GetInt64("userID") && !isAdminRole(c.GetString("role"))) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "任务不存在"})
+		return nil, false
+	}
+	return batch, true
+}
+
+// GetReportCardBatch 查询成绩单批量生成任务的状态
+func GetReportCardBatch(c *gin.Context) {
+	batch, ok := getReportCardBatchParam(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, reportCardBatchResponse(batch))
+}
+
+// DownloadReportCardBatch 下载批量生成的成绩单压缩包
+func DownloadReportCardBatch(c *gin.Context) {
+	batch, ok := getReportCardBatchParam(c)
+	if !ok {
+		return
+	}
+	if batch.Status != models.ReportCardBatchCompleted || batch.StorageKey == "" {
+		c.JSON(http.StatusConflict, gin.H{"error": "成绩单尚未生成完成"})
+		return
+	}
+
+	fileName := "report_cards_" + strconv.FormatInt(batch.ClassID, 10) + "_" + batch.CreatedAt.Format("20060102") + ".zip"
+	serveStoredFile(c, batch.StorageKey, fileName, "application/zip", -1)
+}
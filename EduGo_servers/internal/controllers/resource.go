@@ -0,0 +1,634 @@
+package controllers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"EduGo_servers/internal/database"
+	"EduGo_servers/internal/models"
+	"EduGo_servers/internal/repository"
+	"EduGo_servers/internal/storage"
+)
+
+const maxResourceFileSize = 200 << 20 // 资源文件最大200MB
+
+// resourceResponse 构造学习资源的返回数据
+func resourceResponse(resource *models.Resource) gin.H {
+	courses := make([]gin.H, 0, len(resource.Courses))
+	for _, course := range resource.Courses {
+		courses = append(courses, gin.H{
+			"id":   course.ID,
+			"code": course.Code,
+			"name": course.Name,
+		})
+	}
+	points := make([]gin.H, 0, len(resource.KnowledgePoints))
+	for i := range resource.KnowledgePoints {
+		points = append(points, knowledgePointSummary(&resource.KnowledgePoints[i]))
+	}
+
+	response := gin.H{
+		"id":               resource.ID,
+		"type":             resource.Type,
+		"title":            resource.Title,
+		"description":      resource.Description,
+		"subject":          resource.Subject,
+		"grade_level":      resource.GradeLevel,
+		"url":              resource.URL,
+		"has_file":         resource.HasFile(),
+		"visibility":       resource.Visibility,
+		"owner_id":         resource.OwnerID,
+		"courses":          courses,
+		"knowledge_points": points,
+		"created_at":       resource.CreatedAt,
+		"updated_at":       resource.UpdatedAt,
+	}
+	if resource.HasFile() {
+		response["file_name"] = resource.FileName
+		response["content_type"] = resource.ContentType
+		response["size"] = resource.Size
+	}
+	if resource.Owner != nil {
+		response["owner"] = userSummary(resource.Owner)
+	}
+	return response
+}
+
+// canManageResource 检查当前用户能否修改资源，只有创建人和管理员可以修改
+func canManageResource(c *gin.Context, resource *models.Resource) bool {
+	return resource.OwnerID == c.GetInt64("userID") || isAdminRole(c.GetString("role"))
+}
+
+// canViewResource 检查当前用户能否查看资源
+func canViewResource(c *gin.Context, resource *models.Resource) (bool, error) {
+	userID := c.GetInt64("userID")
+	if canManageResource(c, resource) || resource.VisibleTo(userID, nil) {
+		return true, nil
+	}
+	if resource.Visibility != models.ResourceCourse {
+		return false, nil
+	}
+
+	courseIDs, err := repository.NewResourceRepository(database.DB).GetUserCourseIDs(c.Request.Context(), userID)
+	if err != nil {
+		return false, err
+	}
+	return resource.VisibleTo(userID, courseIDs), nil
+}
+
+// getResourceParam 获取路径参数指定的资源，只返回当前用户可以查看的资源
+func getResourceParam(c *gin.Context) (*models.Resource, bool) {
+	resourceID, ok := parseIDParam(c, "id", "无效的资源ID")
+	if !ok {
+		return nil, false
+	}
+
+	resource, err := repository.NewResourceRepository(database.DB).GetResourceByID(c.Request.Context(), resourceID)
+	if err != nil {
+		log.Printf("获取资源失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return nil, false
+	}
+	if resource == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "资源不存在"})
+		return nil, false
+	}
+
+	allowed, err := canViewResource(c, resource)
+	if err != nil {
+		log.Printf("检查资源权限失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return nil, false
+	}
+	if !allowed {
+		c.JSON(http.StatusForbidden, gin.H{"error": "没有权限查看该资源"})
+		return nil, false
+	}
+	return resource, true
+}
+
+// getManagedResource 获取路径参数指定的资源，只有创建人和管理员可以修改
+func getManagedResource(c *gin.Context) (*models.Resource, bool) {
+	resource, ok := getResourceParam(c)
+	if !ok {
+		return nil, false
+	}
+	if !canManageResource(c, resource) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "只有资源的创建人可以修改"})
+		return nil, false
+	}
+	return resource, true
+}
+
+// getPublicResourceParam 获取路径参数指定的公开资源，不公开的资源视为不存在
+func getPublicResourceParam(c *gin.Context) (*models.Resource, bool) {
+	resourceID, ok := parseIDParam(c, "id", "无效的资源ID")
+	if !ok {
+		return nil, false
+	}
+
+	resource, err := repository.NewResourceRepository(database.DB).GetResourceByID(c.Request.Context(), resourceID)
+	if err != nil {
+		log.Printf("获取资源失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return nil, false
+	}
+	if resource == nil || resource.Visibility != models.ResourcePublic {
+		c.JSON(http.StatusNotFound, gin.H{"error": "资源不存在"})
+		return nil, false
+	}
+	return resource, true
+}
+
+// validateResource 检查资源的类型、地址和可见范围，返回错误信息
+func validateResource(resource *models.Resource) string {
+	switch {
+	case !models.IsValidResourceType(resource.Type):
+		return "无效的资源类型"
+	case !models.IsValidResourceVisibility(resource.Visibility):
+		return "无效的可见范围"
+	case resource.Type == models.ResourceLink && resource.URL == "":
+		return "链接类资源必须填写地址"
+	case resource.Type == models.ResourceLink && resource.HasFile():
+		return "链接类资源不能包含文件"
+	case resource.Visibility == models.ResourceCourse && len(resource.Courses) == 0:
+		return "课程可见的资源必须关联课程"
+	}
+	if resource.URL != "" {
+		parsed, err := url.Parse(resource.URL)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			return "无效的资源地址"
+		}
+	}
+	return ""
+}
+
+// uniqueIDs 去掉重复的ID，保留原有顺序
+func uniqueIDs(ids []int64) []int64 {
+	result := make([]int64, 0, len(ids))
+	seen := map[int64]bool{}
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			result = append(result, id)
+		}
+	}
+	return result
+}
+
+// loadResourceCourses 加载资源要关联的课程，教师只能关联自己负责的课程。失败时已写入响应
+func loadResourceCourses(c *gin.Context, ids []int64) ([]models.Course, bool) {
+	ctx := c.Request.Context()
+	userID := c.GetInt64("userID")
+	admin := isAdminRole(c.GetString("role"))
+	courseRepo := repository.NewCourseRepository(database.DB)
+
+	courses := make([]models.Course, 0, len(ids))
+	for _, id := range uniqueIDs(ids) {
+		course, err := courseRepo.GetCourseByID(ctx, id)
+		if err != nil {
+			log.Printf("获取课程失败: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+			return nil, false
+		}
+		if course == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("课程 %d 不存在", id)})
+			return nil, false
+		}
+		if !admin && !course.HasTeacher(userID) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "只能把资源关联到自己负责的课程"})
+			return nil, false
+		}
+		courses = append(courses, *course)
+	}
+	return courses, true
+}
+
+// loadResourceKnowledgePoints 加载资源涉及的知识点。失败时已写入响应
+func loadResourceKnowledgePoints(c *gin.Context, ids []int64) ([]models.KnowledgePoint, bool) {
+	ids = uniqueIDs(ids)
+	points, err := repository.NewKnowledgePointRepository(database.DB).GetKnowledgePointsByIDs(c.Request.Context(), ids)
+	if err != nil {
+		log.Printf("获取知识点失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return nil, false
+	}
+	if len(points) != len(ids) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "知识点不存在"})
+		return nil, false
+	}
+
+	values := make([]models.KnowledgePoint, 0, len(points))
+	for _, point := range points {
+		values = append(values, *point)
+	}
+	return values, true
+}
+
+// CreateResource 创建学习资源（教师及以上权限），文件类资源创建后再上传文件
+func CreateResource(c *gin.Context) {
+	var input struct {
+		Type              string  `json:"type" binding:"required"`
+		Title             string  `json:"title" binding:"required,max=200"`
+		Description       string  `json:"description"`
+		Subject           string  `json:"subject" binding:"max=50"`
+		GradeLevel        string  `json:"grade_level" binding:"max=50"`
+		URL               string  `json:"url" binding:"max=500"`
+		Visibility        string  `json:"visibility"`
+		CourseIDs         []int64 `json:"course_ids"`
+		KnowledgePointIDs []int64 `json:"knowledge_point_ids"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的输入数据"})
+		return
+	}
+
+	resource := &models.Resource{
+		Type:        input.Type,
+		Title:       strings.TrimSpace(input.Title),
+		Description: input.Description,
+		Subject:     strings.TrimSpace(input.Subject),
+		GradeLevel:  strings.TrimSpace(input.GradeLevel),
+		URL:         strings.TrimSpace(input.URL),
+		Visibility:  input.Visibility,
+		OwnerID:     c.GetInt64("userID"),
+	}
+	if resource.Visibility == "" {
+		resource.Visibility = models.ResourcePrivate
+	}
+	if resource.Title == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "资源标题不能为空"})
+		return
+	}
+
+	var ok bool
+	if resource.Courses, ok = loadResourceCourses(c, input.CourseIDs); !ok {
+		return
+	}
+	if resource.KnowledgePoints, ok = loadResourceKnowledgePoints(c, input.KnowledgePointIDs); !ok {
+		return
+	}
+	if msg := validateResource(resource); msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	if err := repository.NewResourceRepository(database.DB).CreateResource(c.Request.Context(), resource); err != nil {
+		log.Printf("创建资源失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, resourceResponse(resource))
+}
+
+// resourceListQuery 解析资源列表的查询参数，可见范围按当前用户设置。失败时已写入响应
+func resourceListQuery(c *gin.Context) (*repository.ResourceListQuery, bool) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", strconv.Itoa(repository.DefaultPageSize)))
+	search := c.Query("q")
+	if search == "" {
+		search = c.Query("search")
+	}
+
+	query := &repository.ResourceListQuery{
+		Page:       page,
+		PageSize:   pageSize,
+		Search:     strings.TrimSpace(search),
+		Type:       c.Query("type"),
+		Subject:    c.Query("subject"),
+		GradeLevel: c.Query("grade_level"),
+		Visibility: c.Query("visibility"),
+	}
+	pointIDs, ok := knowledgePointFilter(c)
+	if !ok {
+		return nil, false
+	}
+	query.KnowledgePointIDs = pointIDs
+	return query, true
+}
+
+// setResourceViewer 按当前用户设置资源列表的可见范围，管理员可以看到全部资源
+func setResourceViewer(c *gin.Context, query *repository.ResourceListQuery, courseIDs []int64) {
+	if isAdminRole(c.GetString("role")) {
+		query.AllVisible = true
+		return
+	}
+	query.ViewerID = c.GetInt64("userID")
+	query.ViewerCourseIDs = courseIDs
+}
+
+// respondResources 查询资源并返回分页列表
+func respondResources(c *gin.Context, query *repository.ResourceListQuery) {
+	resources, total, err := repository.NewResourceRepository(database.DB).ListResources(c.Request.Context(), query)
+	if err != nil {
+		log.Printf("获取资源列表失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+
+	list := make([]gin.H, 0, len(resources))
+	for _, resource := range resources {
+		list = append(list, resourceResponse(resource))
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"resources": list,
+		"pagination": gin.H{
+			"page":     query.Page,
+			"pageSize": query.PageSize,
+			"total":    total,
+		},
+	})
+}
+
+// GetResources 搜索资源库，只返回当前用户可以查看的资源
+// mine=true时只返回自己创建的资源，course_id指定时只返回该课程资源列表中的资源
+func GetResources(c *gin.Context) {
+	query, ok := resourceListQuery(c)
+	if !ok {
+		return
+	}
+	if c.Query("mine") == "true" {
+		query.OwnerID = c.GetInt64("userID")
+	}
+	if value := c.Query("course_id"); value != "" {
+		courseID, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的课程ID"})
+			return
+		}
+		query.CourseID = courseID
+	}
+
+	var courseIDs []int64
+	if !isAdminRole(c.GetString("role")) {
+		var err error
+		courseIDs, err = repository.NewResourceRepository(database.DB).GetUserCourseIDs(c.Request.Context(), c.GetInt64("userID"))
+		if err != nil {
+			log.Printf("获取用户课程失败: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+			return
+		}
+	}
+	setResourceViewer(c, query, courseIDs)
+	respondResources(c, query)
+}
+
+// GetCourseResources 获取课程的资源列表（课程教师、选课学生和管理员）
+func GetCourseResources(c *gin.Context) {
+	courseID, ok := parseIDParam(c, "id", "无效的课程ID")
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	course, err := repository.NewCourseRepository(database.DB).GetCourseByID(ctx, courseID)
+	if err != nil {
+		log.Printf("获取课程失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+	if course == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "课程不存在"})
+		return
+	}
+
+	var courseIDs []int64
+	if !isAdminRole(c.GetString("role")) {
+		courseIDs, err = repository.NewResourceRepository(database.DB).GetUserCourseIDs(ctx, c.GetInt64("userID"))
+		if err != nil {
+			log.Printf("获取用户课程失败: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+			return
+		}
+		member := false
+		for _, id := range courseIDs {
+			if id == course.ID {
+				member = true
+				break
+			}
+		}
+		if !member {
+			c.JSON(http.StatusForbidden, gin.H{"error": "没有权限查看该课程的资源"})
+			return
+		}
+	}
+
+	query, ok := resourceListQuery(c)
+	if !ok {
+		return
+	}
+	query.CourseID = course.ID
+	setResourceViewer(c, query, courseIDs)
+	respondResources(c, query)
+}
+
+// GetResource 获取资源详情
+func GetResource(c *gin.Context) {
+	resource, ok := getResourceParam(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, resourceResponse(resource))
+}
+
+// UpdateResource 修改资源（创建人或管理员），关联的课程和知识点整体替换
+func UpdateResource(c *gin.Context) {
+	resource, ok := getManagedResource(c)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		Title             *string  `json:"title" binding:"omitempty,max=200"`
+		Description       *string  `json:"description"`
+		Subject           *string  `json:"subject" binding:"omitempty,max=50"`
+		GradeLevel        *string  `json:"grade_level" binding:"omitempty,max=50"`
+		URL               *string  `json:"url" binding:"omitempty,max=500"`
+		Visibility        *string  `json:"visibility"`
+		CourseIDs         *[]int64 `json:"course_ids"`
+		KnowledgePointIDs *[]int64 `json:"knowledge_point_ids"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的输入数据"})
+		return
+	}
+
+	if input.Title != nil {
+		title := strings.TrimSpace(*input.Title)
+		if title == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "资源标题不能为空"})
+			return
+		}
+		resource.Title = title
+	}
+	if input.Description != nil {
+		resource.Description = *input.Description
+	}
+	if input.Subject != nil {
+		resource.Subject = strings.TrimSpace(*input.Subject)
+	}
+	if input.GradeLevel != nil {
+		resource.GradeLevel = strings.TrimSpace(*input.GradeLevel)
+	}
+	if input.URL != nil {
+		resource.URL = strings.TrimSpace(*input.URL)
+	}
+	if input.Visibility != nil {
+		resource.Visibility = *input.Visibility
+	}
+	if input.CourseIDs != nil {
+		if resource.Courses, ok = loadResourceCourses(c, *input.CourseIDs); !ok {
+			return
+		}
+	}
+	if input.KnowledgePointIDs != nil {
+		if resource.KnowledgePoints, ok = loadResourceKnowledgePoints(c, *input.KnowledgePointIDs); !ok {
+			return
+		}
+	}
+	if msg := validateResource(resource); msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	if err := repository.NewResourceRepository(database.DB).UpdateResource(c.Request.Context(), resource); err != nil {
+		log.Printf("更新资源失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+
+	c.JSON(http.StatusOK, resourceResponse(resource))
+}
+
+// DeleteResource 删除资源及其文件（创建人或管理员），包含该资源的课时重新汇总学习进度
+func DeleteResource(c *gin.Context) {
+	resource, ok := getManagedResource(c)
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	lessonIDs, err := repository.NewProgressRepository(database.DB).GetItemLessonIDs(ctx, models.LessonItemResource, resource.ID)
+	if err != nil {
+		log.Printf("获取课时失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+	if err := repository.NewResourceRepository(database.DB).DeleteResource(ctx, resource.ID); err != nil {
+		log.Printf("删除资源失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+	if err := refreshLessonProgress(ctx, lessonIDs); err != nil {
+		log.Printf("更新学习进度失败: %v", err)
+	}
+	if resource.HasFile() {
+		deleteStoredFiles(ctx, resource.StorageKey)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "资源删除成功"})
+}
+
+// UploadResourceFile 上传资源文件（创建人或管理员），已有文件时替换
+func UploadResourceFile(c *gin.Context) {
+	resource, ok := getManagedResource(c)
+	if !ok {
+		return
+	}
+	if resource.Type == models.ResourceLink {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "链接类资源不能上传文件"})
+		return
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxResourceFileSize+1<<20)
+	header, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请上传文件"})
+		return
+	}
+	if header.Size > maxResourceFileSize {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "文件不能超过200MB"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	key, size, err := saveUploadedFile(ctx, header, fmt.Sprintf("resources/%d", resource.ID))
+	if err != nil {
+		log.Printf("保存资源文件失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+
+	oldKey := resource.StorageKey
+	resource.FileName = storage.SafeFileName(header.Filename)
+	resource.StorageKey = key
+	resource.ContentType = uploadContentType(header)
+	resource.Size = size
+	if err := repository.NewResourceRepository(database.DB).UpdateResource(ctx, resource); err != nil {
+		log.Printf("保存资源文件记录失败: %v", err)
+		deleteStoredFiles(ctx, key)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+	if oldKey != "" {
+		deleteStoredFiles(ctx, oldKey)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":  "文件上传成功",
+		"resource": resourceResponse(resource),
+	})
+}
+
+// serveResourceFile 返回资源的文件
+func serveResourceFile(c *gin.Context, resource *models.Resource) {
+	if !resource.HasFile() {
+		c.JSON(http.StatusNotFound, gin.H{"error": "资源没有上传文件"})
+		return
+	}
+	serveStoredFile(c, resource.StorageKey, resource.FileName, resource.ContentType, resource.Size)
+}
+
+// DownloadResourceFile 下载资源文件
+func DownloadResourceFile(c *gin.Context) {
+	resource, ok := getResourceParam(c)
+	if !ok {
+		return
+	}
+	serveResourceFile(c, resource)
+}
+
+// GetPublicResources 搜索公开的资源，不需要登录
+func GetPublicResources(c *gin.Context) {
+	query, ok := resourceListQuery(c)
+	if !ok {
+		return
+	}
+	query.PublicOnly = true
+	respondResources(c, query)
+}
+
+// GetPublicResource 获取公开资源的详情，不需要登录
+func GetPublicResource(c *gin.Context) {
+	resource, ok := getPublicResourceParam(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, resourceResponse(resource))
+}
+
+// DownloadPublicResourceFile 下载公开资源的文件，不需要登录
+func DownloadPublicResourceFile(c *gin.Context) {
+	resource, ok := getPublicResourceParam(c)
+	if !ok {
+		return
+	}
+	serveResourceFile(c, resource)
+}
@@ -0,0 +1,468 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"EduGo_servers/internal/assessment"
+	"EduGo_servers/internal/database"
+	"EduGo_servers/internal/models"
+	"EduGo_servers/internal/notify"
+	"EduGo_servers/internal/repository"
+	"EduGo_servers/internal/review"
+)
+
+const (
+	defaultDailyReviews = 20 // 每天默认的复习题数
+	maxDailyReviews     = 100
+	maxReviewAssignment = 100 // 教师一次最多布置的学生数和题数
+)
+
+// reviewItemResponse 构造复习题目的返回数据，题目不包含正确答案
+func reviewItemResponse(item *models.ReviewItem) gin.H {
+	response := gin.H{
+		"id":               item.ID,
+		"student_id":       item.StudentID,
+		"question_id":      item.QuestionID,
+		"source":           item.Source,
+		"assigned_by":      item.AssignedBy,
+		"ease_factor":      item.EaseFactor,
+		"interval_days":    item.IntervalDays,
+		"repetitions":      item.Repetitions,
+		"lapses":           item.Lapses,
+		"review_count":     item.ReviewCount,
+		"due_at":           item.DueAt,
+		"last_reviewed_at": item.LastReviewedAt,
+		"created_at":       item.CreatedAt,
+	}
+	if item.Question != nil {
+		response["question"] = questionResponse(item.Question, false)
+	}
+	return response
+}
+
+// reviewLogResponse 构造一次复习记录的返回数据
+func reviewLogResponse(record *models.ReviewLog) gin.H {
+	return gin.H{
+		"id":            record.ID,
+		"response":      record.Responses(),
+		"correct":       record.Correct,
+		"quality":       record.Quality,
+		"ease_factor":   record.EaseFactor,
+		"interval_days": record.IntervalDays,
+		"reviewed_at":   record.ReviewedAt,
+	}
+}
+
+// applyReview 按本次复习的回忆质量更新复习进度，并返回本次复习的记录（不含作答内容）
+func applyReview(item *models.ReviewItem, correct *bool, quality int, reviewedAt time.Time) *models.ReviewLog {
+	state := review.State{
+		EaseFactor:  item.EaseFactor,
+		Interval:    item.IntervalDays,
+		Repetitions: item.Repetitions,
+	}.Next(quality)
+	if quality < review.PassQuality {
+		item.Lapses++
+	}
+	item.EaseFactor = state.EaseFactor
+	item.IntervalDays = state.Interval
+	item.Repetitions = state.Repetitions
+	item.ReviewCount++
+	item.DueAt = review.DueAt(reviewedAt, state.Interval)
+	item.LastReviewedAt = &reviewedAt
+
+	return &models.ReviewLog{
+		StudentID:    item.StudentID,
+		QuestionID:   item.QuestionID,
+		Correct:      correct,
+		Quality:      quality,
+		EaseFactor:   item.EaseFactor,
+		IntervalDays: item.IntervalDays,
+		ReviewedAt:   reviewedAt,
+	}
+}
+
+// recordPracticeReview 把错题重练中一道题的结果计入学生的复习计划，题目不在复习队列中时加入
+func recordPracticeReview(ctx context.Context, item *models.MistakePracticeItem, studentID int64, correct bool) error {
+	reviewRepo := repository.NewReviewRepository(database.DB)
+	items, err := reviewRepo.GetItems(ctx, []int64{studentID}, []int64{item.QuestionID})
+	if err != nil {
+		return err
+	}
+	reviewItem := &models.ReviewItem{
+		StudentID:  studentID,
+		QuestionID: item.QuestionID,
+		Source:     models.ReviewSourcePractice,
+		EaseFactor: review.InitialEaseFactor,
+	}
+	if len(items) > 0 {
+		reviewItem = items[0]
+	}
+	record := applyReview(reviewItem, &correct, review.QualityFor(correct), *item.AnsweredAt)
+	record.Response = item.Response
+	return reviewRepo.SaveReview(ctx, reviewItem, record)
+}
+
+// GetTodayReviews 获取学生今天应复习的题目（学生本人），包括之前到期还没有复习的题目
+// limit为本次返回的最多题数，默认20
+func GetTodayReviews(c *gin.Context) {
+	if c.GetString("role") != models.RoleStudent {
+		c.JSON(http.StatusForbidden, gin.H{"error": "只有学生可以复习"})
+		return
+	}
+	limit := defaultDailyReviews
+	if value := c.Query("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 || n > maxDailyReviews {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "题数必须在1到" + strconv.Itoa(maxDailyReviews) + "之间"})
+			return
+		}
+		limit = n
+	}
+
+	today := review.StartOfDay(time.Now())
+	items, total, err := repository.NewReviewRepository(database.DB).
+		GetDueItems(c.Request.Context(), c.GetInt64("userID"), today.AddDate(0, 0, 1), limit)
+	if err != nil {
+		log.Printf("获取复习题目失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+
+	itemList := make([]gin.H, 0, len(items))
+	for _, item := range items {
+		itemList = append(itemList, reviewItemResponse(item))
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"date":      today.Format("2006-01-02"),
+		"due_count": total,
+		"items":     itemList,
+	})
+}
+
+// AnswerReview 学生提交一道复习题的作答（学生本人），评分后按SM-2算法安排下一次复习
+// 客观题自动评分，可以用quality细分回忆质量（答对为3到5，答错为0到2）；简答题必须自评quality
+// 学生已经在试卷上看到过答案的题目才返回正确答案和解析
+func AnswerReview(c *gin.Context) {
+	itemID, ok := parseIDParam(c, "id", "无效的复习题目ID")
+	if !ok {
+		return
+	}
+	var input struct {
+		Response []string `json:"response"`
+		Quality  *int     `json:"quality"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的输入数据"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	reviewRepo := repository.NewReviewRepository(database.DB)
+	item, err := reviewRepo.GetItemByID(ctx, itemID)
+	if err != nil {
+		log.Printf("获取复习题目失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+	if item == nil || item.StudentID != c.GetInt64("userID") {
+		c.JSON(http.StatusNotFound, gin.H{"error": "复习题目不存在"})
+		return
+	}
+	if item.Question == nil || item.Question.DeletedAt.Valid {
+		c.JSON(http.StatusConflict, gin.H{"error": "题目已从题库删除"})
+		return
+	}
+	now := time.Now()
+	if !item.DueAt.Before(review.StartOfDay(now).AddDate(0, 0, 1)) {
+		c.JSON(http.StatusConflict, gin.H{"error": "该题还没有到复习日期"})
+		return
+	}
+
+	var correct *bool
+	quality := 0
+	if result, graded := assessment.Grade(item.Question, input.Response, 1); graded {
+		correct = &result.Correct
+		quality = review.QualityFor(result.Correct)
+		if input.Quality != nil {
+			quality = *input.Quality
+			if quality < review.MinQuality || quality > review.MaxQuality || (quality >= review.PassQuality) != result.Correct {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "回忆质量与作答结果不符"})
+				return
+			}
+		}
+	} else {
+		if input.Quality == nil || *input.Quality < review.MinQuality || *input.Quality > review.MaxQuality {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "请自评回忆质量（0到5）"})
+			return
+		}
+		quality = *input.Quality
+	}
+
+	record := applyReview(item, correct, quality, now)
+	record.SetResponses(input.Response)
+	if err := reviewRepo.SaveReview(ctx, item, record); err != nil {
+		log.Printf("保存复习记录失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+
+	// 复习结果同样计入错题本
+	if correct != nil {
+		mistakeRepo := repository.NewMistakeRepository(database.DB)
+		entries, err := mistakeRepo.GetStudentEntries(ctx, item.StudentID, []int64{item.QuestionID})
+		if err == nil && len(entries) > 0 {
+			if *correct {
+				entries[0].RecordCorrect(now)
+			} else {
+				entries[0].RecordWrong(record.Response, now)
+			}
+			err = mistakeRepo.SaveEntries(ctx, entries, nil)
+		}
+		if err != nil {
+			log.Printf("更新错题本失败: %v", err)
+		}
+	}
+
+	// 只有学生已经在试卷上看到过答案的题目才返回正确答案和解析，否则只返回对错
+	reveal, err := repository.NewAssessmentRepository(database.DB).IsQuestionRevealed(ctx, item.StudentID, item.QuestionID)
+	if err != nil {
+		log.Printf("检查题目答案是否公布失败: %v", err)
+	}
+
+	schedule := reviewItemResponse(item)
+	delete(schedule, "question")
+	c.JSON(http.StatusOK, gin.H{
+		"correct":         correct,
+		"quality":         quality,
+		"question":        questionResponse(item.Question, reveal),
+		"answers_visible": reveal,
+		"item":            schedule,
+	})
+}
+
+// GetStudentReviews 分页查询学生的复习队列（本人、家长、管理员或该学生的教师），按应复习的日期排列
+func GetStudentReviews(c *gin.Context) {
+	studentID, ok := parseIDParam(c, "id", "无效的学生ID")
+	if !ok {
+		return
+	}
+	allowed, err := canViewStudentData(c, studentID)
+	if err != nil {
+		log.Printf("检查用户关系失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+	if !allowed {
+		c.JSON(http.StatusForbidden, gin.H{"error": "无权查看该学生的复习计划"})
+		return
+	}
+
+	query := &repository.ReviewListQuery{StudentID: studentID}
+	query.Page, _ = strconv.Atoi(c.Query("page"))
+	query.PageSize, _ = strconv.Atoi(c.Query("page_size"))
+
+	ctx := c.Request.Context()
+	reviewRepo := repository.NewReviewRepository(database.DB)
+	items, total, err := reviewRepo.ListItems(ctx, query)
+	if err != nil {
+		log.Printf("获取复习队列失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+	dueCount, err := reviewRepo.CountDueItems(ctx, studentID, review.StartOfDay(time.Now()).AddDate(0, 0, 1))
+	if err != nil {
+		log.Printf("获取复习队列失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+
+	itemList := []gin.H{}
+	for _, item := range items {
+		itemList = append(itemList, reviewItemResponse(item))
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"student_id": studentID,
+		"due_count":  dueCount, // 今天应复习的题数
+		"items":      itemList,
+		"pagination": gin.H{
+			"page":     query.Page,
+			"pageSize": query.PageSize,
+			"total":    total,
+		},
+	})
+}
+
+// GetReviewItem 获取复习题目及每次复习的记录（本人、家长、管理员或该学生的教师）
+func GetReviewItem(c *gin.Context) {
+	itemID, ok := parseIDParam(c, "id", "无效的复习题目ID")
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	reviewRepo := repository.NewReviewRepository(database.DB)
+	item, err := reviewRepo.GetItemByID(ctx, itemID)
+	if err != nil {
+		log.Printf("获取复习题目失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+	if item == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "复习题目不存在"})
+		return
+	}
+	allowed, err := canViewStudentData(c, item.StudentID)
+	if err != nil {
+		log.Printf("检查用户关系失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+	if !allowed {
+		c.JSON(http.StatusNotFound, gin.H{"error": "复习题目不存在"})
+		return
+	}
+
+	records, err := reviewRepo.GetItemLogs(ctx, item.ID)
+	if err != nil {
+		log.Printf("获取复习记录失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+	history := make([]gin.H, 0, len(records))
+	for _, record := range records {
+		history = append(history, reviewLogResponse(record))
+	}
+
+	response := reviewItemResponse(item)
+	response["history"] = history
+	c.JSON(http.StatusOK, response)
+}
+
+// AssignReviews 教师把题目布置到学生的复习队列中（管理员或与学生有师生关系的教师），题目当天到期
+// 题目已在学生的复习队列中时保留复习进度，只把复习日期提前到当天
+func AssignReviews(c *gin.Context) {
+	var input struct {
+		StudentIDs  []int64 `json:"student_ids" binding:"required"`
+		QuestionIDs []int64 `json:"question_ids" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的输入数据"})
+		return
+	}
+	if len(input.StudentIDs) == 0 || len(input.QuestionIDs) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请选择学生和题目"})
+		return
+	}
+	if len(input.StudentIDs) > maxReviewAssignment || len(input.QuestionIDs) > maxReviewAssignment {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("一次最多布置给%d名学生、%d道题", maxReviewAssignment, maxReviewAssignment)})
+		return
+	}
+
+	ctx := c.Request.Context()
+	students, message, err := loadClassStudents(ctx, input.StudentIDs)
+	if err != nil {
+		log.Printf("获取学生失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+	if message != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": message})
+		return
+	}
+	studentIDs := make([]int64, 0, len(students))
+	for _, student := range students {
+		studentIDs = append(studentIDs, student.ID)
+	}
+	teacherID := c.GetInt64("userID")
+	if !requireOwnStudents(c, studentIDs, "只能给自己的学生布置复习") {
+		return
+	}
+	questionIDs := make([]int64, 0, len(input.QuestionIDs))
+	seen := map[int64]bool{}
+	for _, id := range input.QuestionIDs {
+		if !seen[id] {
+			seen[id] = true
+			questionIDs = append(questionIDs, id)
+		}
+	}
+	questions, err := repository.NewQuestionRepository(database.DB).GetQuestionsByIDs(ctx, questionIDs)
+	if err != nil {
+		log.Printf("获取题目失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+	if len(questions) != len(questionIDs) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "题目不存在"})
+		return
+	}
+
+	reviewRepo := repository.NewReviewRepository(database.DB)
+	existing, err := reviewRepo.GetItems(ctx, studentIDs, questionIDs)
+	if err != nil {
+		log.Printf("获取复习队列失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+	type itemKey struct{ studentID, questionID int64 }
+	items := make(map[itemKey]*models.ReviewItem, len(existing))
+	for _, item := range existing {
+		items[itemKey{item.StudentID, item.QuestionID}] = item
+	}
+
+	today := review.StartOfDay(time.Now())
+	var changed []*models.ReviewItem
+	created := 0
+	for _, studentID := range studentIDs {
+		for _, questionID := range questionIDs {
+			item, ok := items[itemKey{studentID, questionID}]
+			if !ok {
+				item = &models.ReviewItem{
+					StudentID:  studentID,
+					QuestionID: questionID,
+					Source:     models.ReviewSourceTeacher,
+					EaseFactor: review.InitialEaseFactor,
+					DueAt:      today,
+				}
+				created++
+			} else if item.DueAt.After(today) {
+				item.DueAt = today
+			}
+			item.AssignedBy = &teacherID
+			changed = append(changed, item)
+		}
+	}
+	if err := reviewRepo.SaveItems(ctx, changed); err != nil {
+		log.Printf("布置复习题目失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+
+	notifications := make([]*models.Notification, 0, len(studentIDs))
+	for _, studentID := range studentIDs {
+		notifications = append(notifications, &models.Notification{
+			UserID:  studentID,
+			Type:    models.NotificationReviewAssigned,
+			Title:   "新的复习题目",
+			Content: fmt.Sprintf("老师给你布置了 %d 道复习题，今天就可以开始复习", len(questionIDs)),
+			Link:    "/reviews/today",
+		})
+	}
+	if err := notify.Send(ctx, database.DB, notifications...); err != nil {
+		log.Printf("发送复习通知失败: %v", err)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "复习题目已布置",
+		"created": created,
+		"updated": len(changed) - created,
+	})
+}
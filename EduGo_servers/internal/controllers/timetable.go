@@ -0,0 +1,918 @@
+package controllers
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"EduGo_servers/internal/calendar"
+	"EduGo_servers/internal/database"
+	"EduGo_servers/internal/models"
+	"EduGo_servers/internal/repository"
+)
+
+// periodResponse 构造课节的返回数据
+func periodResponse(period *models.Period) gin.H {
+	return gin.H{
+		"id":         period.ID,
+		"number":     period.Number,
+		"name":       period.Name,
+		"start_time": period.StartTime,
+		"end_time":   period.EndTime,
+	}
+}
+
+// roomResponse 构造教室的返回数据
+func roomResponse(room *models.Room) gin.H {
+	return gin.H{
+		"id":       room.ID,
+		"name":     room.Name,
+		"building": room.Building,
+		"capacity": room.Capacity,
+	}
+}
+
+// sessionResponse 构造课表中一节课的返回数据
+func sessionResponse(session *models.ScheduledSession) gin.H {
+	response := gin.H{
+		"id":          session.ID,
+		"offering_id": session.OfferingID,
+		"term_id":     session.TermID,
+		"weekday":     session.Weekday,
+		"period_id":   session.PeriodID,
+		"teacher_id":  session.TeacherID,
+		"class_id":    session.ClassID,
+		"room_id":     session.RoomID,
+	}
+	if session.Offering != nil && session.Offering.Course != nil {
+		response["course_id"] = session.Offering.Course.ID
+		response["course_code"] = session.Offering.Course.Code
+		response["course_name"] = session.Offering.Course.Name
+	}
+	if session.Period != nil {
+		response["period"] = periodResponse(session.Period)
+	}
+	if session.Teacher != nil {
+		response["teacher"] = userSummary(session.Teacher)
+	}
+	if session.Class != nil {
+		response["class_name"] = session.Class.GradeLevel + session.Class.Name
+	}
+	if session.Room != nil {
+		response["room"] = roomResponse(session.Room)
+	}
+	return response
+}
+
+// conflictResponse 构造课表冲突的返回数据
+func conflictResponse(conflicts []repository.ScheduleConflict) []gin.H {
+	list := []gin.H{}
+	for _, conflict := range conflicts {
+		item := gin.H{
+			"type":       conflict.Type,
+			"session_id": conflict.SessionID,
+		}
+		if len(conflict.StudentIDs) > 0 {
+			item["student_ids"] = conflict.StudentIDs
+		}
+		list = append(list, item)
+	}
+	return list
+}
+
+// parseIDParam 解析路径中的ID参数
+func parseIDParam(c *gin.Context, name, message string) (int64, bool) {
+	id, err := strconv.ParseInt(c.Param(name), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": message})
+		return 0, false
+	}
+	return id, true
+}
+
+// validClock 检查时刻格式是否为HH:MM
+func validClock(clock string) bool {
+	_, err := time.Parse("15:04", clock)
+	return err == nil && len(clock) == 5
+}
+
+// CreatePeriod 创建课节（管理员及以上权限）
+func CreatePeriod(c *gin.Context) {
+	var input struct {
+		Number    int    `json:"number" binding:"required,min=1"`
+		Name      string `json:"name" binding:"required"`
+		StartTime string `json:"start_time" binding:"required"`
+		EndTime   string `json:"end_time" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的输入数据"})
+		return
+	}
+
+	period := &models.Period{
+		Number:    input.Number,
+		Name:      strings.TrimSpace(input.Name),
+		StartTime: input.StartTime,
+		EndTime:   input.EndTime,
+	}
+	if !savePeriod(c, period, true) {
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "课节创建成功",
+		"period":  periodResponse(period),
+	})
+}
+
+// savePeriod 验证并保存课节，失败时已写入响应
+func savePeriod(c *gin.Context, period *models.Period, create bool) bool {
+	if !validClock(period.StartTime) || !validClock(period.EndTime) || period.EndTime <= period.StartTime {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的上下课时间"})
+		return false
+	}
+
+	timetableRepo := repository.NewTimetableRepository(database.DB)
+	exists, err := timetableRepo.PeriodNumberExists(c.Request.Context(), period.Number, period.ID)
+	if err != nil {
+		log.Printf("获取课节失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return false
+	}
+	if exists {
+		c.JSON(http.StatusConflict, gin.H{"error": "课节序号已存在"})
+		return false
+	}
+
+	if create {
+		err = timetableRepo.CreatePeriod(c.Request.Context(), period)
+	} else {
+		err = timetableRepo.UpdatePeriod(c.Request.Context(), period)
+	}
+	if err != nil {
+		log.Printf("保存课节失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return false
+	}
+	return true
+}
+
+// GetPeriods 获取课节列表
+func GetPeriods(c *gin.Context) {
+	timetableRepo := repository.NewTimetableRepository(database.DB)
+	periods, err := timetableRepo.GetPeriods(c.Request.Context())
+	if err != nil {
+		log.Printf("获取课节列表失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+
+	periodList := []gin.H{}
+	for _, period := range periods {
+		periodList = append(periodList, periodResponse(period))
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"periods": periodList,
+	})
+}
+
+// UpdatePeriod 更新课节（管理员及以上权限）
+func UpdatePeriod(c *gin.Context) {
+	id, ok := parseIDParam(c, "id", "无效的课节ID")
+	if !ok {
+		return
+	}
+
+	timetableRepo := repository.NewTimetableRepository(database.DB)
+	period, err := timetableRepo.GetPeriodByID(c.Request.Context(), id)
+	if err != nil {
+		log.Printf("获取课节失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+	if period == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "课节不存在"})
+		return
+	}
+
+	var input struct {
+		Number    *int    `json:"number"`
+		Name      *string `json:"name"`
+		StartTime *string `json:"start_time"`
+		EndTime   *string `json:"end_time"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的输入数据"})
+		return
+	}
+
+	if input.Number != nil {
+		if *input.Number < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的课节序号"})
+			return
+		}
+		period.Number = *input.Number
+	}
+	if input.Name != nil {
+		period.Name = strings.TrimSpace(*input.Name)
+	}
+	if input.StartTime != nil {
+		period.StartTime = *input.StartTime
+	}
+	if input.EndTime != nil {
+		period.EndTime = *input.EndTime
+	}
+	if !savePeriod(c, period, false) {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "课节更新成功",
+		"period":  periodResponse(period),
+	})
+}
+
+// DeletePeriod 删除课节（管理员及以上权限），已排课的课节不能删除
+func DeletePeriod(c *gin.Context) {
+	id, ok := parseIDParam(c, "id", "无效的课节ID")
+	if !ok {
+		return
+	}
+
+	timetableRepo := repository.NewTimetableRepository(database.DB)
+	count, err := timetableRepo.CountSessions(c.Request.Context(), "period_id", id)
+	if err != nil {
+		log.Printf("检查课节引用失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+	if count > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "课节已被课表引用，不能删除"})
+		return
+	}
+
+	if err := timetableRepo.DeletePeriod(c.Request.Context(), id); err != nil {
+		log.Printf("删除课节失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "课节删除成功",
+	})
+}
+
+// CreateRoom 创建教室（管理员及以上权限）
+func CreateRoom(c *gin.Context) {
+	var input struct {
+		Name     string `json:"name" binding:"required"`
+		Building string `json:"building"`
+		Capacity int    `json:"capacity" binding:"min=0"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的输入数据"})
+		return
+	}
+
+	room := &models.Room{
+		Name:     strings.TrimSpace(input.Name),
+		Building: input.Building,
+		Capacity: input.Capacity,
+	}
+	if !saveRoom(c, room, true) {
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "教室创建成功",
+		"room":    roomResponse(room),
+	})
+}
+
+// saveRoom 验证并保存教室，失败时已写入响应
+func saveRoom(c *gin.Context, room *models.Room, create bool) bool {
+	if room.Name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "教室名称不能为空"})
+		return false
+	}
+
+	timetableRepo := repository.NewTimetableRepository(database.DB)
+	exists, err := timetableRepo.RoomNameExists(c.Request.Context(), room.Name, room.ID)
+	if err != nil {
+		log.Printf("获取教室失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return false
+	}
+	if exists {
+		c.JSON(http.StatusConflict, gin.H{"error": "教室名称已存在"})
+		return false
+	}
+
+	if create {
+		err = timetableRepo.CreateRoom(c.Request.Context(), room)
+	} else {
+		err = timetableRepo.UpdateRoom(c.Request.Context(), room)
+	}
+	if err != nil {
+		log.Printf("保存教室失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return false
+	}
+	return true
+}
+
+// GetRooms 获取教室列表
+func GetRooms(c *gin.Context) {
+	timetableRepo := repository.NewTimetableRepository(database.DB)
+	rooms, err := timetableRepo.GetRooms(c.Request.Context())
+	if err != nil {
+		log.Printf("获取教室列表失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+
+	roomList := []gin.H{}
+	for _, room := range rooms {
+		roomList = append(roomList, roomResponse(room))
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"rooms": roomList,
+	})
+}
+
+// UpdateRoom 更新教室（管理员及以上权限）
+func UpdateRoom(c *gin.Context) {
+	id, ok := parseIDParam(c, "id", "无效的教室ID")
+	if !ok {
+		return
+	}
+
+	timetableRepo := repository.NewTimetableRepository(database.DB)
+	room, err := timetableRepo.GetRoomByID(c.Request.Context(), id)
+	if err != nil {
+		log.Printf("获取教室失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+	if room == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "教室不存在"})
+		return
+	}
+
+	var input struct {
+		Name     *string `json:"name"`
+		Building *string `json:"building"`
+		Capacity *int    `json:"capacity"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的输入数据"})
+		return
+	}
+
+	if input.Name != nil {
+		room.Name = strings.TrimSpace(*input.Name)
+	}
+	if input.Building != nil {
+		room.Building = *input.Building
+	}
+	if input.Capacity != nil {
+		if *input.Capacity < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的教室容量"})
+			return
+		}
+		room.Capacity = *input.Capacity
+	}
+	if !saveRoom(c, room, false) {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "教室更新成功",
+		"room":    roomResponse(room),
+	})
+}
+
+// DeleteRoom 删除教室（管理员及以上权限），已排课的教室不能删除
+func DeleteRoom(c *gin.Context) {
+	id, ok := parseIDParam(c, "id", "无效的教室ID")
+	if !ok {
+		return
+	}
+
+	timetableRepo := repository.NewTimetableRepository(database.DB)
+	count, err := timetableRepo.CountSessions(c.Request.Context(), "room_id", id)
+	if err != nil {
+		log.Printf("检查教室引用失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+	if count > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "教室已被课表引用，不能删除"})
+		return
+	}
+
+	if err := timetableRepo.DeleteRoom(c.Request.Context(), id); err != nil {
+		log.Printf("删除教室失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "教室删除成功",
+	})
+}
+
+// CreateSession 排课（管理员及以上权限）
+// 存在教师、教室、班级或学生冲突时返回409，force为true时仍然排课并返回冲突列表
+func CreateSession(c *gin.Context) {
+	var input struct {
+		OfferingID int64  `json:"offering_id" binding:"required"`
+		Weekday    int    `json:"weekday" binding:"required,min=1,max=7"`
+		PeriodID   int64  `json:"period_id" binding:"required"`
+		TeacherID  int64  `json:"teacher_id"`
+		ClassID    *int64 `json:"class_id"`
+		RoomID     *int64 `json:"room_id"`
+		Force      bool   `json:"force"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的输入数据"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	offeringRepo := repository.NewCourseOfferingRepository(database.DB)
+	offering, err := offeringRepo.GetOfferingByID(ctx, input.OfferingID)
+	if err != nil {
+		log.Printf("获取开课失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+	if offering == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "开课不存在"})
+		return
+	}
+
+	// 未指定教师时使用开课的第一位授课教师
+	teacherID := input.TeacherID
+	if teacherID == 0 && len(offering.Teachers) > 0 {
+		teacherID = offering.Teachers[0].ID
+	}
+	if teacherID == 0 || !offering.HasTeacher(teacherID) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "教师不是该开课的授课教师"})
+		return
+	}
+
+	timetableRepo := repository.NewTimetableRepository(database.DB)
+	period, err := timetableRepo.GetPeriodByID(ctx, input.PeriodID)
+	if err != nil {
+		log.Printf("获取课节失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+	if period == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "课节不存在"})
+		return
+	}
+
+	session := &models.ScheduledSession{
+		OfferingID: offering.ID,
+		TermID:     offering.TermID,
+		Weekday:    input.Weekday,
+		PeriodID:   period.ID,
+		TeacherID:  teacherID,
+		CreatedBy:  c.GetInt64("userID"),
+	}
+
+	if input.ClassID != nil && *input.ClassID > 0 {
+		class, err := repository.NewClassRepository(database.DB).GetClassByID(ctx, *input.ClassID)
+		if err != nil {
+			log.Printf("获取班级失败: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+			return
+		}
+		if class == nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "班级不存在"})
+			return
+		}
+		session.ClassID = &class.ID
+	}
+	if input.RoomID != nil && *input.RoomID > 0 {
+		room, err := timetableRepo.GetRoomByID(ctx, *input.RoomID)
+		if err != nil {
+			log.Printf("获取教室失败: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+			return
+		}
+		if room == nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "教室不存在"})
+			return
+		}
+		session.RoomID = &room.ID
+	}
+
+	conflicts, err := timetableRepo.FindConflicts(ctx, session)
+	if err != nil {
+		log.Printf("检查课表冲突失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+	if len(conflicts) > 0 && !input.Force {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":     "课表冲突",
+			"conflicts": conflictResponse(conflicts),
+		})
+		return
+	}
+
+	if err := timetableRepo.CreateSession(ctx, session); err != nil {
+		log.Printf("排课失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+
+	created, err := timetableRepo.GetSessionByID(ctx, session.ID)
+	if err != nil || created == nil {
+		log.Printf("获取课表失败: %v", err)
+		created = session
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":   "排课成功",
+		"session":   sessionResponse(created),
+		"conflicts": conflictResponse(conflicts),
+	})
+}
+
+// DeleteSession 删除排课（管理员及以上权限）
+func DeleteSession(c *gin.Context) {
+	id, ok := parseIDParam(c, "id", "无效的课表ID")
+	if !ok {
+		return
+	}
+
+	timetableRepo := repository.NewTimetableRepository(database.DB)
+	session, err := timetableRepo.GetSessionByID(c.Request.Context(), id)
+	if err != nil {
+		log.Printf("获取课表失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+	if session == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "课表不存在"})
+		return
+	}
+
+	if err := timetableRepo.DeleteSession(c.Request.Context(), id); err != nil {
+		log.Printf("删除排课失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "排课删除成功",
+	})
+}
+
+// resolveTimetableTerm 获取查询参数指定的学期，未指定时使用当前学期。失败时已写入响应
+func resolveTimetableTerm(c *gin.Context) (*models.AcademicTerm, bool) {
+	if termID := c.Query("term_id"); termID != "" {
+		id, err := strconv.ParseInt(termID, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的学期ID"})
+			return nil, false
+		}
+		return resolveTerm(c, id, "")
+	}
+
+	termRepo := repository.NewAcademicTermRepository(database.DB)
+	term, err := termRepo.GetCurrentTerm(c.Request.Context(), time.Now())
+	if err != nil {
+		log.Printf("获取当前学期失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return nil, false
+	}
+	if term == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "当前没有学期"})
+		return nil, false
+	}
+	return term, true
+}
+
+// respondTimetable 返回课表
+func respondTimetable(c *gin.Context, term *models.AcademicTerm, sessions []*models.ScheduledSession) {
+	sessionList := []gin.H{}
+	for _, session := range sessions {
+		sessionList = append(sessionList, sessionResponse(session))
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"term":     termResponse(term),
+		"sessions": sessionList,
+	})
+}
+
+// GetTimetable 按班级、教师或教室查询课表（教师及以上权限）
+func GetTimetable(c *gin.Context) {
+	term, ok := resolveTimetableTerm(c)
+	if !ok {
+		return
+	}
+
+	query := &repository.SessionQuery{TermID: term.ID}
+	params := map[string]*int64{
+		"class_id":   &query.ClassID,
+		"teacher_id": &query.TeacherID,
+		"room_id":    &query.RoomID,
+	}
+	for name, target := range params {
+		if value := c.Query(name); value != "" {
+			id, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "无效的查询参数: " + name})
+				return
+			}
+			*target = id
+		}
+	}
+	if query.ClassID == 0 && query.TeacherID == 0 && query.RoomID == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请指定class_id、teacher_id或room_id"})
+		return
+	}
+
+	timetableRepo := repository.NewTimetableRepository(database.DB)
+	sessions, err := timetableRepo.ListSessions(c.Request.Context(), query)
+	if err != nil {
+		log.Printf("获取课表失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+
+	respondTimetable(c, term, sessions)
+}
+
+// userTimetable 获取用户的课表：教师及管理员为自己任教的课，学生为所在班级和已选上的课，家长为所有孩子的课
+func userTimetable(ctx context.Context, user *models.User, termID int64) ([]*models.ScheduledSession, error) {
+	timetableRepo := repository.NewTimetableRepository(database.DB)
+	switch user.Role {
+	case models.RoleStudent:
+		return timetableRepo.ListSessions(ctx, &repository.SessionQuery{TermID: termID, StudentID: user.ID})
+	case models.RoleParent:
+		relations, err := repository.NewUserRelationRepository(database.DB).GetRelationsInvolvingUser(ctx, user.ID)
+		if err != nil {
+			return nil, err
+		}
+		var sessions []*models.ScheduledSession
+		seen := map[int64]bool{}
+		for _, relation := range relations.StudentParent {
+			if relation.RelatedUserID != user.ID || relation.Status != models.RelationStatusActive {
+				continue
+			}
+			childSessions, err := timetableRepo.ListSessions(ctx, &repository.SessionQuery{TermID: termID, StudentID: relation.UserID})
+			if err != nil {
+				return nil, err
+			}
+			for _, session := range childSessions {
+				if !seen[session.ID] {
+					seen[session.ID] = true
+					sessions = append(sessions, session)
+				}
+			}
+		}
+		return sessions, nil
+	default:
+		return timetableRepo.ListSessions(ctx, &repository.SessionQuery{TermID: termID, TeacherID: user.ID})
+	}
+}
+
+// GetMyTimetable 获取当前用户的课表
+// 家长可以通过student_id查看某个孩子的课表
+func GetMyTimetable(c *gin.Context) {
+	term, ok := resolveTimetableTerm(c)
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	userRepo := repository.NewUserRepository(database.DB)
+	targetID := c.GetInt64("userID")
+	if studentID := c.Query("student_id"); studentID != "" {
+		id, err := strconv.ParseInt(studentID, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的学生ID"})
+			return
+		}
+		allowed, err := canAccessUserData(c, id)
+		if err != nil {
+			log.Printf("检查用户关系失败: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+			return
+		}
+		if !allowed {
+			c.JSON(http.StatusForbidden, gin.H{"error": "无权查看该学生的课表"})
+			return
+		}
+		targetID = id
+	}
+
+	user, err := userRepo.GetUserByID(ctx, targetID)
+	if err != nil {
+		log.Printf("获取用户信息失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+	if user == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "用户不存在"})
+		return
+	}
+
+	sessions, err := userTimetable(ctx, user, term.ID)
+	if err != nil {
+		log.Printf("获取课表失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+
+	respondTimetable(c, term, sessions)
+}
+
+// generateFeedToken 生成课表订阅令牌
+func generateFeedToken() (string, error) {
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// calendarFeedResponse 构造课表订阅地址
+func calendarFeedResponse(c *gin.Context, feed *models.CalendarFeed) gin.H {
+	scheme := "http"
+	if c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	return gin.H{
+		"token": feed.Token,
+		"url":   fmt.Sprintf("%s://%s/api/v1/calendar/%s.ics", scheme, c.Request.Host, feed.Token),
+	}
+}
+
+// GetCalendarFeed 获取当前用户的课表订阅地址，首次获取时生成
+func GetCalendarFeed(c *gin.Context) {
+	rotateCalendarFeed(c, false)
+}
+
+// RegenerateCalendarFeed 重新生成课表订阅地址，旧地址立即失效
+func RegenerateCalendarFeed(c *gin.Context) {
+	rotateCalendarFeed(c, true)
+}
+
+func rotateCalendarFeed(c *gin.Context, regenerate bool) {
+	userID := c.GetInt64("userID")
+	timetableRepo := repository.NewTimetableRepository(database.DB)
+	feed, err := timetableRepo.GetCalendarFeed(c.Request.Context(), userID)
+	if err != nil {
+		log.Printf("获取课表订阅失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+
+	if feed == nil || regenerate {
+		if feed == nil {
+			feed = &models.CalendarFeed{UserID: userID}
+		}
+		token, err := generateFeedToken()
+		if err != nil {
+			log.Printf("生成订阅令牌失败: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+			return
+		}
+		feed.Token = token
+		if err := timetableRepo.SaveCalendarFeed(c.Request.Context(), feed); err != nil {
+			log.Printf("保存课表订阅失败: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"feed": calendarFeedResponse(c, feed),
+	})
+}
+
+// ExportCalendarFeed 导出iCalendar格式的课表（公开，通过订阅令牌识别用户）
+// 日历客户端无法携带JWT，订阅地址中的令牌即为凭证
+func ExportCalendarFeed(c *gin.Context) {
+	token := strings.TrimSuffix(c.Param("token"), ".ics")
+
+	ctx := c.Request.Context()
+	timetableRepo := repository.NewTimetableRepository(database.DB)
+	feed, err := timetableRepo.GetCalendarFeedByToken(ctx, token)
+	if err != nil {
+		log.Printf("获取课表订阅失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+	if feed == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "订阅地址无效"})
+		return
+	}
+
+	user, err := repository.NewUserRepository(database.DB).GetUserByID(ctx, feed.UserID)
+	if err != nil {
+		log.Printf("获取用户信息失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+	if user == nil || user.Status != models.UserStatusActive {
+		c.JSON(http.StatusNotFound, gin.H{"error": "订阅地址无效"})
+		return
+	}
+
+	term, ok := resolveTimetableTerm(c)
+	if !ok {
+		return
+	}
+
+	sessions, err := userTimetable(ctx, user, term.ID)
+	if err != nil {
+		log.Printf("获取课表失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+
+	cal, err := buildTimetableCalendar(term, sessions)
+	if err != nil {
+		log.Printf("生成日历失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+
+	c.Header("Content-Type", "text/calendar; charset=utf-8")
+	c.Header("Content-Disposition", `inline; filename="timetable.ics"`)
+	c.Status(http.StatusOK)
+	if _, err := cal.WriteTo(c.Writer); err != nil {
+		log.Printf("写出日历失败: %v", err)
+	}
+}
+
+// buildTimetableCalendar 将学期课表转换为每周重复的日历事件，重复到学期结束
+func buildTimetableCalendar(term *models.AcademicTerm, sessions []*models.ScheduledSession) (*calendar.Calendar, error) {
+	termStart := time.Date(term.StartDate.Year(), term.StartDate.Month(), term.StartDate.Day(), 0, 0, 0, 0, time.Local)
+	until := time.Date(term.EndDate.Year(), term.EndDate.Month(), term.EndDate.Day(), 23, 59, 59, 0, time.Local)
+
+	cal := &calendar.Calendar{Name: term.Name + " 课表"}
+	for _, session := range sessions {
+		if session.Period == nil {
+			continue
+		}
+		day := calendar.NextWeekday(termStart, session.Weekday)
+		if day.After(until) {
+			continue
+		}
+		start, err := calendar.AtClock(day, session.Period.StartTime)
+		if err != nil {
+			return nil, err
+		}
+		end, err := calendar.AtClock(day, session.Period.EndTime)
+		if err != nil {
+			return nil, err
+		}
+
+		event := calendar.Event{
+			UID:         fmt.Sprintf("session-%d@edugo", session.ID),
+			Start:       start,
+			End:         end,
+			RepeatUntil: &until,
+		}
+		if session.Offering != nil && session.Offering.Course != nil {
+			event.Summary = session.Offering.Course.Name
+		}
+		if session.Room != nil {
+			event.Location = session.Room.Name
+		}
+		if session.Teacher != nil {
+			name := session.Teacher.LastName + session.Teacher.FirstName
+			if name == "" {
+				name = session.Teacher.Username
+			}
+			event.Description = "教师: " + name
+		}
+		cal.Events = append(cal.Events, event)
+	}
+	return cal, nil
+}
@@ -2,16 +2,19 @@ package controllers
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
 	"regexp"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
 	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
 
 	"EduGo_servers/internal/database"
 	"EduGo_servers/internal/models"
@@ -48,6 +51,11 @@ func Login(c *gin.Context) {
 		return
 	}
 
+	if user.Status != models.UserStatusActive {
+		c.JSON(http.StatusForbidden, gin.H{"error": "账号未激活或已停用"})
+		return
+	}
+
 	token, err := generateJWT(user.ID, user.Username)
 	if err != nil {
 		log.Printf("生成JWT失败: %v", err)
@@ -55,6 +63,17 @@ func Login(c *gin.Context) {
 		return
 	}
 
+	// 记录登录历史，失败不影响登录
+	record := &models.LoginRecord{
+		UserID:    user.ID,
+		IP:        c.ClientIP(),
+		UserAgent: truncate(c.Request.UserAgent(), 255),
+		CreatedAt: time.Now(),
+	}
+	if err := userRepo.CreateLoginRecord(c.Request.Context(), record); err != nil {
+		log.Printf("记录登录历史失败: %v", err)
+	}
+
 	c.JSON(http.StatusOK, gin.H{
 		"message": "登录成功",
 		"token":   token,
@@ -62,14 +81,16 @@ func Login(c *gin.Context) {
 }
 
 // Register 处理用户注册请求
+// 携带邀请令牌时，角色由邀请指定，并在注册成功后建立邀请中的关系
 func Register(c *gin.Context) {
 	var input struct {
-		Username  string `json:"username" binding:"required"`
-		Password  string `json:"password" binding:"required,min=8"`
-		Email     string `json:"email" binding:"required,email"`
-		FirstName string `json:"first_name"`
-		LastName  string `json:"last_name"`
-		Role      string `json:"role"`
+		Username        string `json:"username" binding:"required"`
+		Password        string `json:"password" binding:"required,min=8"`
+		Email           string `json:"email" binding:"required,email"`
+		FirstName       string `json:"first_name"`
+		LastName        string `json:"last_name"`
+		Role            string `json:"role"`
+		InvitationToken string `json:"invitation_token"`
 	}
 
 	if err := c.ShouldBindJSON(&input); err != nil {
@@ -89,9 +110,31 @@ func Register(c *gin.Context) {
 		return
 	}
 
+	var invitation *models.Invitation
+	if input.InvitationToken != "" {
+		var message string
+		var err error
+		invitation, message, err = loadUsableInvitation(c.Request.Context(), input.InvitationToken)
+		if err != nil {
+			log.Printf("获取邀请失败: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+			return
+		}
+		if invitation == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": message})
+			return
+		}
+		if invitation.Email != "" && !strings.EqualFold(invitation.Email, input.Email) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "注册邮箱与邀请不符"})
+			return
+		}
+	}
+
 	// 验证用户角色
 	role := input.Role
-	if role == "" {
+	if invitation != nil {
+		role = invitation.Role
+	} else if role == "" {
 		role = models.RoleStudent // 默认为学生
 	} else {
 		// 只允许注册为教师、学生或家长
@@ -115,7 +158,7 @@ func Register(c *gin.Context) {
 
 	user := models.User{
 		Username:  input.Username,
-		Email:     input.Email,
+		Email:     &input.Email,
 		FirstName: input.FirstName,
 		LastName:  input.LastName,
 		Role:      role,
@@ -127,10 +170,33 @@ func Register(c *gin.Context) {
 		return
 	}
 
-	if err := userRepo.CreateUser(c.Request.Context(), &user); err != nil {
-		log.Printf("创建用户失败: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
-		return
+	if invitation == nil {
+		if err := userRepo.CreateUser(c.Request.Context(), &user); err != nil {
+			log.Printf("创建用户失败: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+			return
+		}
+	} else {
+		// 使用邀请、创建用户和建立关系在同一事务中完成
+		err := database.DB.Transaction(func(tx *gorm.DB) error {
+			ctx := c.Request.Context()
+			if err := repository.NewInvitationRepository(tx).ConsumeInvitation(ctx, invitation.ID); err != nil {
+				return err
+			}
+			if err := repository.NewUserRepository(tx).CreateUser(ctx, &user); err != nil {
+				return err
+			}
+			return createInvitationRelation(ctx, repository.NewUserRelationRepository(tx), invitation, user.ID)
+		})
+		if errors.Is(err, repository.ErrInvitationUnavailable) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "邀请已失效"})
+			return
+		}
+		if err != nil {
+			log.Printf("通过邀请创建用户失败: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+			return
+		}
 	}
 
 	c.JSON(http.StatusCreated, gin.H{
@@ -169,12 +235,17 @@ func generateJWT(userID int64, username string) (string, error) {
 	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString(jwtSecret())
+}
+
+// jwtSecret 获取JWT签名密钥
+func jwtSecret() []byte {
 	secret := []byte(os.Getenv("JWT_SECRET"))
 	if len(secret) == 0 {
 		// 如果环境变量未设置，使用默认密钥（仅用于开发环境）
 		secret = []byte("your-secret-key")
 	}
-	return token.SignedString(secret)
+	return secret
 }
 
 // RefreshToken 刷新JWT令牌
@@ -237,7 +308,7 @@ func UpdateUser(c *gin.Context) {
 	}
 
 	if input.Email != "" {
-		user.Email = input.Email
+		user.Email = &input.Email
 	}
 	if input.FirstName != "" {
 		user.FirstName = input.FirstName
@@ -264,6 +335,15 @@ func UpdateUser(c *gin.Context) {
 	})
 }
 
+// truncate 按字符截断字符串
+func truncate(s string, maxLen int) string {
+	runes := []rune(s)
+	if len(runes) <= maxLen {
+		return s
+	}
+	return string(runes[:maxLen])
+}
+
 // validatePasswordStrength 验证密码强度
 func validatePasswordStrength(password string) error {
 	if len(password) < 8 {
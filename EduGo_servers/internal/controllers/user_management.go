@@ -1,9 +1,12 @@
 package controllers
 
 import (
+	"errors"
+	"fmt"
 	"log"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 
@@ -12,18 +15,142 @@ import (
 	"EduGo_servers/internal/repository"
 )
 
-// GetAllUsers 获取所有用户（超级管理员权限）
-func GetAllUsers(c *gin.Context) {
+// parseUserListQuery 从请求参数解析用户列表查询条件
+func parseUserListQuery(c *gin.Context) (*repository.UserListQuery, error) {
+	query := &repository.UserListQuery{
+		Status:    c.Query("status"),
+		Search:    c.Query("search"),
+		SortBy:    c.DefaultQuery("sort_by", "id"),
+		SortOrder: c.DefaultQuery("sort_order", "asc"),
+	}
+
+	intParams := map[string]*int{
+		"page":      &query.Page,
+		"page_size": &query.PageSize,
+	}
+	for name, target := range intParams {
+		if value := c.Query(name); value != "" {
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("无效的分页参数: %s", name)
+			}
+			*target = n
+		}
+	}
+
+	if cursor := c.Query("cursor"); cursor != "" {
+		n, err := strconv.ParseInt(cursor, 10, 64)
+		if err != nil || n < 1 {
+			return nil, errors.New("无效的游标")
+		}
+		query.Cursor = n
+	}
+
+	if role := c.Query("role"); role != "" {
+		if !isValidRole(role) {
+			return nil, errors.New("无效的用户角色")
+		}
+		query.Roles = []string{role}
+	}
+
+	timeParams := map[string]**time.Time{
+		"created_from": &query.CreatedFrom,
+		"created_to":   &query.CreatedTo,
+	}
+	for name, target := range timeParams {
+		if value := c.Query(name); value != "" {
+			t, err := parseDateParam(value)
+			if err != nil {
+				return nil, fmt.Errorf("无效的日期参数: %s", name)
+			}
+			*target = &t
+		}
+	}
+
+	if relationType := c.Query("relation_type"); relationType != "" {
+		query.RelatedToUserID = c.GetInt64("userID")
+		query.RelationType = relationType
+		switch c.DefaultQuery("relation_direction", "outgoing") {
+		case "outgoing":
+		case "incoming":
+			query.RelationReverse = true
+		default:
+			return nil, errors.New("无效的关系方向")
+		}
+	}
+
+	return query, nil
+}
+
+// parseDateParam 解析日期参数，支持 2006-01-02 和 RFC3339 格式
+func parseDateParam(value string) (time.Time, error) {
+	if t, err := time.ParseInLocation("2006-01-02", value, time.Local); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
+
+// isValidRole 验证角色是否有效
+func isValidRole(role string) bool {
+	switch role {
+	case models.RoleSuperAdmin, models.RoleAdmin, models.RoleTeacher, models.RoleStudent, models.RoleParent:
+		return true
+	}
+	return false
+}
+
+// visibleRoles 返回当前角色在用户列表中可以查看的角色，nil表示不限制
+func visibleRoles(role string) []string {
+	switch role {
+	case models.RoleSuperAdmin:
+		return nil
+	case models.RoleAdmin:
+		return []string{models.RoleTeacher, models.RoleStudent}
+	default:
+		return []string{models.RoleStudent}
+	}
+}
+
+// restrictRoles 将查询的角色限制在可见范围内
+func restrictRoles(query *repository.UserListQuery, allowed []string) {
+	if allowed == nil {
+		return
+	}
+	if len(query.Roles) == 0 {
+		query.Roles = allowed
+		return
+	}
+	var roles []string
+	for _, role := range query.Roles {
+		for _, a := range allowed {
+			if role == a {
+				roles = append(roles, role)
+			}
+		}
+	}
+	if len(roles) == 0 {
+		// 不可见的角色，返回空结果
+		roles = []string{""}
+	}
+	query.Roles = roles
+}
+
+// respondUserList 查询并返回分页的用户列表
+func respondUserList(c *gin.Context, key string, query *repository.UserListQuery) {
 	userRepo := repository.NewUserRepository(database.DB)
-	users, err := userRepo.GetAllUsers(c.Request.Context())
+	result, err := userRepo.ListUsers(c.Request.Context(), query)
+	if errors.Is(err, repository.ErrInvalidRelationType) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的关系类型"})
+		return
+	}
 	if err != nil {
 		log.Printf("获取用户列表失败: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
 		return
 	}
 
-	var userList []gin.H
-	for _, user := range users {
+	userList := []gin.H{}
+	for _, user := range result.Users {
 		userList = append(userList, gin.H{
 			"id":        user.ID,
 			"username":  user.Username,
@@ -36,54 +163,52 @@ func GetAllUsers(c *gin.Context) {
 		})
 	}
 
+	pagination := gin.H{
+		"page":     query.Page,
+		"pageSize": query.PageSize,
+		"total":    result.Total,
+	}
+	if query.Cursor > 0 {
+		delete(pagination, "page")
+	}
+	if result.NextCursor > 0 {
+		pagination["nextCursor"] = result.NextCursor
+	}
+
 	c.JSON(http.StatusOK, gin.H{
-		"users": userList,
+		key:          userList,
+		"pagination": pagination,
 	})
 }
 
+// GetAllUsers 获取用户列表，根据当前用户角色过滤可见范围
+func GetAllUsers(c *gin.Context) {
+	query, err := parseUserListQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	restrictRoles(query, visibleRoles(c.GetString("role")))
+	respondUserList(c, "users", query)
+}
+
 // GetUsersByRole 根据角色获取用户（管理员及以上权限）
 func GetUsersByRole(c *gin.Context) {
 	role := c.Param("role")
-	
-	// 验证角色是否有效
-	validRoles := map[string]bool{
-		models.RoleSuperAdmin: true,
-		models.RoleAdmin:      true,
-		models.RoleTeacher:    true,
-		models.RoleStudent:    true,
-		models.RoleParent:     true,
-	}
-	
-	if !validRoles[role] {
+	if !isValidRole(role) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的用户角色"})
 		return
 	}
-	
-	userRepo := repository.NewUserRepository(database.DB)
-	users, err := userRepo.GetUsersByRole(c.Request.Context(), role)
+
+	query, err := parseUserListQuery(c)
 	if err != nil {
-		log.Printf("获取用户列表失败: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	var userList []gin.H
-	for _, user := range users {
-		userList = append(userList, gin.H{
-			"id":        user.ID,
-			"username":  user.Username,
-			"email":     user.Email,
-			"firstName": user.FirstName,
-			"lastName":  user.LastName,
-			"role":      user.Role,
-			"status":    user.Status,
-			"createdAt": user.CreatedAt,
-		})
-	}
-
-	c.JSON(http.StatusOK, gin.H{
-		"users": userList,
-	})
+	query.Roles = []string{role}
+	respondUserList(c, "users", query)
 }
 
 // GetUserByID 根据ID获取用户（管理员及以上权限）
@@ -122,6 +247,191 @@ func GetUserByID(c *gin.Context) {
 	})
 }
 
+// CreateUser 管理员创建用户（管理员及以上权限）
+// 管理员可以创建教师、学生和家长，超级管理员还可以创建管理员；邮箱可为空
+func CreateUser(c *gin.Context) {
+	var input struct {
+		Username  string `json:"username" binding:"required"`
+		Password  string `json:"password" binding:"required,min=8"`
+		Email     string `json:"email" binding:"omitempty,email"`
+		FirstName string `json:"first_name"`
+		LastName  string `json:"last_name"`
+		Role      string `json:"role" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	allowedRoles := map[string]bool{
+		models.RoleTeacher: true,
+		models.RoleStudent: true,
+		models.RoleParent:  true,
+	}
+	if c.GetString("role") == models.RoleSuperAdmin {
+		allowedRoles[models.RoleAdmin] = true
+	}
+	if !allowedRoles[input.Role] {
+		c.JSON(http.StatusForbidden, gin.H{"error": "无权创建该角色的用户"})
+		return
+	}
+
+	if err := validatePasswordStrength(input.Password); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userRepo := repository.NewUserRepository(database.DB)
+	if exists := userRepo.UserExists(input.Username, input.Email); exists {
+		c.JSON(http.StatusConflict, gin.H{"error": "用户名或邮箱已被注册"})
+		return
+	}
+
+	user := models.User{
+		Username:  input.Username,
+		FirstName: input.FirstName,
+		LastName:  input.LastName,
+		Role:      input.Role,
+	}
+	if input.Email != "" {
+		user.Email = &input.Email
+	}
+
+	if err := user.HashPassword(input.Password); err != nil {
+		log.Printf("密码加密失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+
+	if err := userRepo.CreateUser(c.Request.Context(), &user); err != nil {
+		log.Printf("创建用户失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "用户创建成功",
+		"user": gin.H{
+			"id":        user.ID,
+			"username":  user.Username,
+			"email":     user.Email,
+			"firstName": user.FirstName,
+			"lastName":  user.LastName,
+			"role":      user.Role,
+			"status":    user.Status,
+		},
+	})
+}
+
+// DeleteUser 软删除用户（管理员及以上权限）
+// 用户的所有关系随之软删除，保留期内可以恢复
+func DeleteUser(c *gin.Context) {
+	userIDStr := c.Param("id")
+	userID, err := strconv.ParseInt(userIDStr, 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的用户ID"})
+		return
+	}
+
+	if userID == c.GetInt64("userID") {
+		c.JSON(http.StatusForbidden, gin.H{"error": "不能删除自己"})
+		return
+	}
+
+	userRepo := repository.NewUserRepository(database.DB)
+	user, err := userRepo.GetUserByID(c.Request.Context(), userID)
+	if err != nil {
+		log.Printf("获取用户信息失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+
+	if user == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "用户不存在"})
+		return
+	}
+
+	// 不允许删除超级管理员
+	if user.Role == models.RoleSuperAdmin {
+		c.JSON(http.StatusForbidden, gin.H{"error": "不能删除超级管理员"})
+		return
+	}
+
+	// 管理员不能删除其他管理员
+	if c.GetString("role") == models.RoleAdmin && user.Role == models.RoleAdmin {
+		c.JSON(http.StatusForbidden, gin.H{"error": "管理员不能删除其他管理员"})
+		return
+	}
+
+	if err := userRepo.DeleteUser(c.Request.Context(), userID); err != nil {
+		log.Printf("删除用户失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "用户删除成功",
+	})
+}
+
+// GetDeletedUsers 获取已软删除、尚未彻底清理的用户列表（管理员及以上权限）
+func GetDeletedUsers(c *gin.Context) {
+	query, err := parseUserListQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	query.Deleted = true
+	restrictRoles(query, visibleRoles(c.GetString("role")))
+	respondUserList(c, "users", query)
+}
+
+// RestoreUser 恢复已软删除的用户（管理员及以上权限）
+func RestoreUser(c *gin.Context) {
+	userIDStr := c.Param("id")
+	userID, err := strconv.ParseInt(userIDStr, 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的用户ID"})
+		return
+	}
+
+	userRepo := repository.NewUserRepository(database.DB)
+	user, err := userRepo.GetDeletedUserByID(c.Request.Context(), userID)
+	if err != nil {
+		log.Printf("获取用户信息失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+
+	if user == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "已删除的用户不存在"})
+		return
+	}
+
+	// 管理员不能恢复管理员
+	if c.GetString("role") == models.RoleAdmin && user.Role == models.RoleAdmin {
+		c.JSON(http.StatusForbidden, gin.H{"error": "管理员不能恢复其他管理员"})
+		return
+	}
+
+	if err := userRepo.RestoreUser(c.Request.Context(), userID); err != nil {
+		log.Printf("恢复用户失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "用户恢复成功",
+		"user": gin.H{
+			"id":       user.ID,
+			"username": user.Username,
+			"role":     user.Role,
+		},
+	})
+}
+
 // UpdateUserRole 更新用户角色（超级管理员权限）
 func UpdateUserRole(c *gin.Context) {
 	userIDStr := c.Param("id")
@@ -335,8 +645,9 @@ func CreateTeacherStudentRelation(c *gin.Context) {
 	var input struct {
 		StudentID  int64  `json:"student_id" binding:"required"`
 		CourseID   int64  `json:"course_id"`
-		CourseName string `json:"course_name"`
-		Semester   string `json:"semester"`
+		CourseName string `json:"course_name"` // 已废弃，课程通过course_id指定
+		TermID     int64  `json:"term_id"`
+		Semester   string `json:"semester"` // 已废弃，学期通过term_id指定
 	}
 	
 	if err := c.ShouldBindJSON(&input); err != nil {
@@ -345,6 +656,28 @@ func CreateTeacherStudentRelation(c *gin.Context) {
 	}
 	
 	teacherID := c.GetInt64("userID")
+
+	// 验证课程，教师只能关联自己负责的课程
+	courseTeacherID := int64(0)
+	if c.GetString("role") == models.RoleTeacher {
+		courseTeacherID = teacherID
+	}
+	course, ok := resolveCourse(c, input.CourseID, input.CourseName, courseTeacherID)
+	if !ok {
+		return
+	}
+	courseName := ""
+	if course != nil {
+		courseName = course.Name
+	}
+	term, ok := resolveTerm(c, input.TermID, input.Semester)
+	if !ok {
+		return
+	}
+	semester := ""
+	if term != nil {
+		semester = term.Name
+	}
 	
 	// 验证学生是否存在且角色是否为学生
 	userRepo := repository.NewUserRepository(database.DB)
@@ -374,12 +707,18 @@ func CreateTeacherStudentRelation(c *gin.Context) {
 			Status:       "active",
 		},
 		CourseID:   input.CourseID,
-		CourseName: input.CourseName,
-		Semester:   input.Semester,
+		CourseName: courseName,
+		TermID:     input.TermID,
+		Semester:   semester,
 	}
 	
 	relationRepo := repository.NewUserRelationRepository(database.DB)
-	if err := relationRepo.CreateTeacherStudentRelation(c.Request.Context(), relation); err != nil {
+	err = relationRepo.CreateTeacherStudentRelation(c.Request.Context(), relation)
+	if errors.Is(err, repository.ErrRelationExists) {
+		c.JSON(http.StatusConflict, gin.H{"error": "该课程和学期的教师-学生关系已存在"})
+		return
+	}
+	if err != nil {
 		log.Printf("创建教师-学生关系失败: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
 		return
@@ -393,6 +732,7 @@ func CreateTeacherStudentRelation(c *gin.Context) {
 			"student_id":  relation.RelatedUserID,
 			"course_id":   relation.CourseID,
 			"course_name": relation.CourseName,
+			"term_id":     relation.TermID,
 			"semester":    relation.Semester,
 		},
 	})
@@ -460,92 +800,31 @@ func CreateStudentParentRelation(c *gin.Context) {
 	})
 }
 
-// GetTeachersByAdmin 获取管理员管理的教师列表（管理员及以上权限）
-func GetTeachersByAdmin(c *gin.Context) {
-	adminID := c.GetInt64("userID")
-	
-	relationRepo := repository.NewUserRelationRepository(database.DB)
-	teachers, err := relationRepo.GetTeachersByAdminID(c.Request.Context(), adminID)
+// respondRelatedUsers 返回与当前用户存在指定关系的用户列表
+func respondRelatedUsers(c *gin.Context, key string, relationType string) {
+	query, err := parseUserListQuery(c)
 	if err != nil {
-		log.Printf("获取教师列表失败: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	
-	var teacherList []gin.H
-	for _, teacher := range teachers {
-		teacherList = append(teacherList, gin.H{
-			"id":        teacher.ID,
-			"username":  teacher.Username,
-			"email":     teacher.Email,
-			"firstName": teacher.FirstName,
-			"lastName":  teacher.LastName,
-			"role":      teacher.Role,
-			"status":    teacher.Status,
-		})
-	}
-	
-	c.JSON(http.StatusOK, gin.H{
-		"teachers": teacherList,
-	})
+
+	query.RelatedToUserID = c.GetInt64("userID")
+	query.RelationType = relationType
+	query.RelationReverse = false
+	respondUserList(c, key, query)
+}
+
+// GetTeachersByAdmin 获取管理员管理的教师列表（管理员及以上权限）
+func GetTeachersByAdmin(c *gin.Context) {
+	respondRelatedUsers(c, "teachers", models.RelationAdminTeacher)
 }
 
 // GetStudentsByTeacher 获取教师教授的学生列表（教师及以上权限）
 func GetStudentsByTeacher(c *gin.Context) {
-	teacherID := c.GetInt64("userID")
-	
-	relationRepo := repository.NewUserRelationRepository(database.DB)
-	students, err := relationRepo.GetStudentsByTeacherID(c.Request.Context(), teacherID)
-	if err != nil {
-		log.Printf("获取学生列表失败: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
-		return
-	}
-	
-	var studentList []gin.H
-	for _, student := range students {
-		studentList = append(studentList, gin.H{
-			"id":        student.ID,
-			"username":  student.Username,
-			"email":     student.Email,
-			"firstName": student.FirstName,
-			"lastName":  student.LastName,
-			"role":      student.Role,
-			"status":    student.Status,
-		})
-	}
-	
-	c.JSON(http.StatusOK, gin.H{
-		"students": studentList,
-	})
+	respondRelatedUsers(c, "students", models.RelationTeacherStudent)
 }
 
 // GetParentsByStudent 获取学生的家长列表（学生及以上权限）
 func GetParentsByStudent(c *gin.Context) {
-	studentID := c.GetInt64("userID")
-	
-	relationRepo := repository.NewUserRelationRepository(database.DB)
-	parents, err := relationRepo.GetParentsByStudentID(c.Request.Context(), studentID)
-	if err != nil {
-		log.Printf("获取家长列表失败: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
-		return
-	}
-	
-	var parentList []gin.H
-	for _, parent := range parents {
-		parentList = append(parentList, gin.H{
-			"id":        parent.ID,
-			"username":  parent.Username,
-			"email":     parent.Email,
-			"firstName": parent.FirstName,
-			"lastName":  parent.LastName,
-			"role":      parent.Role,
-			"status":    parent.Status,
-		})
-	}
-	
-	c.JSON(http.StatusOK, gin.H{
-		"parents": parentList,
-	})
+	respondRelatedUsers(c, "parents", models.RelationStudentParent)
 }
@@ -0,0 +1,679 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"EduGo_servers/internal/database"
+	"EduGo_servers/internal/models"
+	"EduGo_servers/internal/repository"
+	"EduGo_servers/internal/xapi"
+)
+
+const (
+	maxXAPIStatementBody = 10 << 20 // 一次提交的语句最大10MB
+	maxXAPIStatements    = 100      // 一次查询最多返回的语句数
+)
+
+// hashXAPISecret 凭据密钥的SHA-256，密钥是随机生成的，不需要加盐
+func hashXAPISecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// randomHex 生成n字节的随机十六进制字符串
+func randomHex(n int) (string, error) {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// AuthenticateXAPICredential 校验学习记录存储的基本认证凭据，只有正常状态的用户可以使用
+func AuthenticateXAPICredential(c *gin.Context, key, secret string) (int64, string, error) {
+	ctx := c.Request.Context()
+	xapiRepo := repository.NewXAPIRepository(database.DB)
+	credential, err := xapiRepo.GetCredentialByKey(ctx, key)
+	if err != nil {
+		log.Printf("获取xAPI凭据失败: %v", err)
+		return 0, "", err
+	}
+	if credential == nil || subtle.ConstantTimeCompare([]byte(hashXAPISecret(secret)), []byte(credential.SecretHash)) != 1 {
+		return 0, "", nil
+	}
+	if credential.User.Status != models.UserStatusActive {
+		return 0, "", nil
+	}
+
+	// 最近使用时间每分钟最多更新一次
+	now := time.Now()
+	if credential.LastUsedAt == nil || now.Sub(*credential.LastUsedAt) > time.Minute {
+		if err := xapiRepo.TouchCredential(ctx, credential.ID, now); err != nil {
+			log.Printf("更新xAPI凭据使用时间失败: %v", err)
+		}
+	}
+	return credential.User.ID, credential.User.Role, nil
+}
+
+// xapiCredentialResponse 构造xAPI凭据的返回数据，不包括密钥
+func xapiCredentialResponse(credential *models.XAPICredential) gin.H {
+	return gin.H{
+		"id":           credential.ID,
+		"name":         credential.Name,
+		"key":          credential.Key,
+		"last_used_at": credential.LastUsedAt,
+		"created_at":   credential.CreatedAt,
+	}
+}
+
+// CreateXAPICredential 为当前用户创建学习记录存储的凭据，密钥只在创建时返回一次
+func CreateXAPICredential(c *gin.Context) {
+	var input struct {
+		Name string `json:"name" binding:"required,max=100"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的输入数据"})
+		return
+	}
+	name := strings.TrimSpace(input.Name)
+	if name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "凭据名称不能为空"})
+		return
+	}
+
+	key, err := randomHex(12)
+	if err != nil {
+		log.Printf("生成xAPI凭据失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+	secret, err := randomHex(32)
+	if err != nil {
+		log.Printf("生成xAPI凭据失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+	credential := &models.XAPICredential{
+		UserID:     c.GetInt64("userID"),
+		Name:       name,
+		Key:        "xapi_" + key,
+		SecretHash: hashXAPISecret(secret),
+	}
+	if err := repository.NewXAPIRepository(database.DB).CreateCredential(c.Request.Context(), credential); err != nil {
+		log.Printf("创建xAPI凭据失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+
+	response := xapiCredentialResponse(credential)
+	response["secret"] = secret
+	c.JSON(http.StatusCreated, response)
+}
+
+// GetXAPICredentials 获取当前用户的学习记录存储凭据
+func GetXAPICredentials(c *gin.Context) {
+	credentials, err := repository.NewXAPIRepository(database.DB).GetUserCredentials(c.Request.Context(), c.GetInt64("userID"))
+	if err != nil {
+		log.Printf("获取xAPI凭据失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+
+	result := make([]gin.H, 0, len(credentials))
+	for _, credential := range credentials {
+		result = append(result, xapiCredentialResponse(credential))
+	}
+	c.JSON(http.StatusOK, gin.H{"credentials": result})
+}
+
+// DeleteXAPICredential 撤销学习记录存储的凭据（本人或管理员）
+func DeleteXAPICredential(c *gin.Context) {
+	credentialID, ok := parseIDParam(c, "id", "无效的凭据ID")
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	xapiRepo := repository.NewXAPIRepository(database.DB)
+	credential, err := xapiRepo.GetCredentialByID(ctx, credentialID)
+	if err != nil {
+		log.Printf("获取xAPI凭据失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+	if credential == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "凭据不存在"})
+		return
+	}
+	if credential.UserID != c.GetInt64("userID") && !isAdminRole(c.GetString("role")) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "无权撤销该凭据"})
+		return
+	}
+
+	if err := xapiRepo.DeleteCredential(ctx, credential.ID); err != nil {
+		log.Printf("撤销xAPI凭据失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "凭据已撤销"})
+}
+
+// GetXAPIAbout 学习记录存储支持的xAPI版本（公开）
+func GetXAPIAbout(c *gin.Context) {
+	c.Header("X-Experience-API-Version", xapi.Version)
+	c.JSON(http.StatusOK, gin.H{"version": []string{xapi.Version}})
+}
+
+// xapiAgentResolver 查找代理人对应的EduGo用户，同一请求中的结果会被缓存
+type xapiAgentResolver struct {
+	ctx   context.Context
+	repo  repository.XAPIRepository
+	users map[string]*models.User
+}
+
+func newXAPIAgentResolver(ctx context.Context) *xapiAgentResolver {
+	return &xapiAgentResolver{
+		ctx:   ctx,
+		repo:  repository.NewXAPIRepository(database.DB),
+		users: map[string]*models.User{},
+	}
+}
+
+// resolve 按本系统账号或邮箱查找代理人对应的用户，小组和没有对应用户的代理人返回nil
+func (r *xapiAgentResolver) resolve(agent *xapi.Agent) (*models.User, error) {
+	if agent.IsGroup() {
+		return nil, nil
+	}
+	ifi := agent.IFI()
+	if user, ok := r.users[ifi]; ok {
+		return user, nil
+	}
+	user, err := r.repo.GetAgentUser(r.ctx, agent.Username(), agent.Email())
+	if err != nil {
+		return nil, err
+	}
+	r.users[ifi] = user
+	return user, nil
+}
+
+// xapiAuthority 当前用户作为语句的授权方
+func xapiAuthority(c *gin.Context) (*xapi.Agent, bool) {
+	user, err := repository.NewUserRepository(database.DB).GetUserByID(c.Request.Context(), c.GetInt64("userID"))
+	if err != nil {
+		log.Printf("获取用户信息失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return nil, false
+	}
+	if user == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "用户不存在"})
+		return nil, false
+	}
+	authority := xapi.AccountAgent(user.Username)
+	return &authority, true
+}
+
+// sameXAPIStatement 检查同一ID的两条语句内容是否相同，相同时重复提交视为成功
+func sameXAPIStatement(a, b *models.XAPIStatement) bool {
+	return a.ActorKey == b.ActorKey && a.VerbID == b.VerbID && a.ActivityID == b.ActivityID &&
+		a.Registration == b.Registration && a.VoidTarget == b.VoidTarget
+}
+
+// xapiStatementRecord 把规范化的语句转换为保存的记录
+func xapiStatementRecord(statement *xapi.Statement, actor *models.User, authorityID int64) *models.XAPIStatement {
+	record := &models.XAPIStatement{
+		StatementID:  statement.ID,
+		ActorKey:     statement.ActorKey(),
+		VerbID:       xapi.Key(statement.VerbID),
+		Registration: strings.ToLower(statement.Registration),
+		Completion:   statement.Result.Completion,
+		Success:      statement.Result.Success,
+		Scaled:       statement.Result.Scaled,
+		Duration:     int(statement.Result.Duration.Seconds()),
+		AuthorityID:  authorityID,
+		Timestamp:    statement.Timestamp,
+		Stored:       statement.Stored,
+		Body:         string(statement.Body),
+	}
+	if actor != nil {
+		record.UserID = &actor.ID
+	}
+	switch statement.ObjectType {
+	case xapi.ObjectActivity:
+		record.ActivityID = xapi.Key(statement.ObjectID)
+	case xapi.ObjectStatementRef:
+		if statement.IsVoiding() {
+			record.VoidTarget = strings.ToLower(statement.ObjectID)
+		}
+	}
+
+	addRefs := func(kind string, direct, related []string) {
+		isDirect := map[string]bool{}
+		for _, key := range direct {
+			isDirect[key] = true
+		}
+		seen := map[string]bool{}
+		for _, key := range related {
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			record.Refs = append(record.Refs, models.XAPIStatementRef{Kind: kind, Value: key, Direct: isDirect[key]})
+		}
+	}
+	addRefs(models.XAPIRefActivity, statement.DirectActivities, statement.RelatedActivities)
+	addRefs(models.XAPIRefAgent, statement.DirectAgents, statement.RelatedAgents)
+	return record
+}
+
+// readXAPIStatements 读取请求中的一条语句或语句数组
+func readXAPIStatements(c *gin.Context) ([]json.RawMessage, bool) {
+	if !xapi.IsJSON(c.GetHeader("Content-Type")) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "语句必须以application/json提交，不支持附件"})
+		return nil, false
+	}
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxXAPIStatementBody)
+	data, err := c.GetRawData()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的语句JSON"})
+		return nil, false
+	}
+	data = bytes.TrimSpace(data)
+	if len(data) > 0 && data[0] == '[' {
+		var items []json.RawMessage
+		if err := json.Unmarshal(data, &items); err != nil || len(items) == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的语句JSON"})
+			return nil, false
+		}
+		return items, true
+	}
+	return []json.RawMessage{data}, true
+}
+
+// storeXAPIStatements 校验并保存语句，返回语句ID。失败时已写入响应
+// 非管理员只能提交执行者为本人的语句、作废本人的语句；已存在的语句内容相同时跳过，不同时返回409
+func storeXAPIStatements(c *gin.Context, items []json.RawMessage, statementID string) ([]string, bool) {
+	authority, ok := xapiAuthority(c)
+	if !ok {
+		return nil, false
+	}
+
+	ctx := c.Request.Context()
+	userID := c.GetInt64("userID")
+	admin := isAdminRole(c.GetString("role"))
+	resolver := newXAPIAgentResolver(ctx)
+	now := time.Now()
+
+	statements := make([]*xapi.Statement, 0, len(items))
+	records := make([]*models.XAPIStatement, 0, len(items))
+	actors := make([]*models.User, 0, len(items))
+	ids := make([]string, 0, len(items))
+	seen := map[string]bool{}
+	for _, item := range items {
+		statement, err := xapi.Prepare(item, statementID, *authority, now)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return nil, false
+		}
+		if seen[statement.ID] {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "语句ID重复: " + statement.ID})
+			return nil, false
+		}
+		seen[statement.ID] = true
+
+		actor, err := resolver.resolve(&statement.Actor)
+		if err != nil {
+			log.Printf("查找语句执行者失败: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+			return nil, false
+		}
+		if !admin && (actor == nil || actor.ID != userID) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "只能提交本人的学习记录"})
+			return nil, false
+		}
+		statements = append(statements, statement)
+		records = append(records, xapiStatementRecord(statement, actor, userID))
+		actors = append(actors, actor)
+		ids = append(ids, statement.ID)
+	}
+
+	xapiRepo := repository.NewXAPIRepository(database.DB)
+	lookup := append([]string{}, ids...)
+	for _, record := range records {
+		if record.VoidTarget != "" {
+			lookup = append(lookup, record.VoidTarget)
+		}
+	}
+	existing, err := xapiRepo.GetStatementsByIDs(ctx, lookup)
+	if err != nil {
+		log.Printf("获取语句失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return nil, false
+	}
+	stored := make(map[string]*models.XAPIStatement, len(existing))
+	for _, statement := range existing {
+		stored[statement.StatementID] = statement
+	}
+
+	pending := make([]*models.XAPIStatement, 0, len(records))
+	var applied []int
+	for i, record := range records {
+		if previous, ok := stored[record.StatementID]; ok {
+			if !sameXAPIStatement(previous, record) {
+				c.JSON(http.StatusConflict, gin.H{"error": "语句ID已存在: " + record.StatementID})
+				return nil, false
+			}
+			continue
+		}
+		if target, ok := stored[record.VoidTarget]; ok {
+			if target.VoidTarget != "" {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "作废语句不能被作废"})
+				return nil, false
+			}
+			owned := (target.UserID != nil && *target.UserID == userID) || target.AuthorityID == userID
+			if !admin && !owned {
+				c.JSON(http.StatusForbidden, gin.H{"error": "只能作废本人的学习记录"})
+				return nil, false
+			}
+		}
+		pending = append(pending, record)
+		applied = append(applied, i)
+	}
+	if err := xapiRepo.SaveStatements(ctx, pending); err != nil {
+		log.Printf("保存语句失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return nil, false
+	}
+
+	// 学生学习本系统资源的语句计入课时进度，失败不影响语句的保存
+	for _, i := range applied {
+		if err := applyXAPIProgress(ctx, statements[i], actors[i]); err != nil {
+			log.Printf("根据语句更新学习进度失败 (statement=%s): %v", statements[i].ID, err)
+		}
+	}
+	return ids, true
+}
+
+// applyXAPIProgress 把学生对本系统学习资源的经历、完成和通过语句计入包含该资源的课时
+// 语句结果中的时长累加到学习时长，完成、通过或result.completion为true时资源视为已学完
+func applyXAPIProgress(ctx context.Context, statement *xapi.Statement, actor *models.User) error {
+	if actor == nil || actor.Role != models.RoleStudent || statement.ObjectType != xapi.ObjectActivity {
+		return nil
+	}
+	resourceID, ok := xapi.ParseResourceActivityID(statement.ObjectID)
+	if !ok {
+		return nil
+	}
+	completed := statement.Result.Completion != nil && *statement.Result.Completion
+	switch statement.VerbID {
+	case xapi.VerbCompleted, xapi.VerbPassed:
+		completed = true
+	case xapi.VerbExperienced:
+	default:
+		if statement.Result.Completion == nil {
+			return nil
+		}
+	}
+
+	seconds := int(statement.Result.Duration.Seconds())
+	if seconds > maxViewSeconds {
+		seconds = maxViewSeconds
+	}
+	_, err := recordResourceView(ctx, actor.ID, resourceID, seconds, completed)
+	return err
+}
+
+// PutXAPIStatement 以指定的statementId保存一条语句
+func PutXAPIStatement(c *gin.Context) {
+	statementID := strings.ToLower(c.Query("statementId"))
+	if !xapi.IsUUID(statementID) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "缺少有效的statementId"})
+		return
+	}
+	items, ok := readXAPIStatements(c)
+	if !ok {
+		return
+	}
+	if len(items) != 1 || bytes.HasPrefix(items[0], []byte("[")) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "PUT只能提交一条语句"})
+		return
+	}
+	if _, ok := storeXAPIStatements(c, items, statementID); !ok {
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// PostXAPIStatements 保存一条或多条语句，返回语句ID
+func PostXAPIStatements(c *gin.Context) {
+	items, ok := readXAPIStatements(c)
+	if !ok {
+		return
+	}
+	ids, ok := storeXAPIStatements(c, items, "")
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, ids)
+}
+
+// canViewXAPIStatement 检查当前用户能否查看语句：管理员、提交人、执行者本人或能查看执行者学习数据的用户
+func canViewXAPIStatement(c *gin.Context, statement *models.XAPIStatement) (bool, error) {
+	userID := c.GetInt64("userID")
+	if isAdminRole(c.GetString("role")) || statement.AuthorityID == userID {
+		return true, nil
+	}
+	if statement.UserID == nil {
+		return false, nil
+	}
+	return canViewStudentData(c, *statement.UserID)
+}
+
+// getXAPIStatement 按statementId或voidedStatementId获取一条语句
+func getXAPIStatement(c *gin.Context, statementID string, voided bool) {
+	statementID = strings.ToLower(statementID)
+	if !xapi.IsUUID(statementID) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的语句ID"})
+		return
+	}
+	for _, name := range []string{"agent", "verb", "activity", "registration", "related_activities", "related_agents", "since", "until", "limit", "ascending", "cursor"} {
+		if _, ok := c.GetQuery(name); ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "按语句ID获取时不能使用其他查询条件"})
+			return
+		}
+	}
+
+	statement, err := repository.NewXAPIRepository(database.DB).GetStatement(c.Request.Context(), statementID)
+	if err != nil {
+		log.Printf("获取语句失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+	if statement == nil || statement.Voided != voided {
+		c.JSON(http.StatusNotFound, gin.H{"error": "语句不存在"})
+		return
+	}
+	allowed, err := canViewXAPIStatement(c, statement)
+	if err != nil {
+		log.Printf("检查用户关系失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+	if !allowed {
+		c.JSON(http.StatusForbidden, gin.H{"error": "无权查看该语句"})
+		return
+	}
+
+	c.Header("X-Experience-API-Consistent-Through", time.Now().UTC().Format(time.RFC3339Nano))
+	c.Header("Last-Modified", statement.Stored.UTC().Format(http.TimeFormat))
+	c.Data(http.StatusOK, "application/json; charset=utf-8", []byte(statement.Body))
+}
+
+// parseXAPITime 解析查询参数中的时间，参数为空时返回nil
+func parseXAPITime(c *gin.Context, name string) (*time.Time, bool) {
+	value := c.Query(name)
+	if value == "" {
+		return nil, true
+	}
+	t, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的" + name})
+		return nil, false
+	}
+	return &t, true
+}
+
+// xapiStatementQuery 解析语句查询参数，并按当前用户的权限限制查询范围。失败时已写入响应
+// 非管理员只能查询本人执行或提交的语句，指定agent时可以查询有权查看学习数据的学生的语句
+func xapiStatementQuery(c *gin.Context) (*repository.XAPIStatementQuery, bool) {
+	query := &repository.XAPIStatementQuery{
+		VerbID:            c.Query("verb"),
+		Registration:      strings.ToLower(c.Query("registration")),
+		RelatedActivities: c.Query("related_activities") == "true",
+		RelatedAgents:     c.Query("related_agents") == "true",
+		Ascending:         c.Query("ascending") == "true",
+		Limit:             maxXAPIStatements,
+	}
+	if query.VerbID != "" && !xapi.IsIRI(query.VerbID) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的verb"})
+		return nil, false
+	}
+	if activity := c.Query("activity"); activity != "" {
+		if !xapi.IsIRI(activity) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的activity"})
+			return nil, false
+		}
+		query.Activity = xapi.Key(activity)
+	}
+	if query.Registration != "" && !xapi.IsUUID(query.Registration) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的registration"})
+		return nil, false
+	}
+	var ok bool
+	if query.Since, ok = parseXAPITime(c, "since"); !ok {
+		return nil, false
+	}
+	if query.Until, ok = parseXAPITime(c, "until"); !ok {
+		return nil, false
+	}
+	if value := c.Query("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的limit"})
+			return nil, false
+		}
+		if limit > 0 && limit < maxXAPIStatements {
+			query.Limit = limit
+		}
+	}
+	if value := c.Query("cursor"); value != "" {
+		after, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || after <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的cursor"})
+			return nil, false
+		}
+		query.After = after
+	}
+	switch c.DefaultQuery("format", "exact") {
+	case "exact", "ids", "canonical":
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的format"})
+		return nil, false
+	}
+
+	var agent *xapi.Agent
+	if value := c.Query("agent"); value != "" {
+		var err error
+		if agent, err = xapi.ParseAgent(value); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return nil, false
+		}
+		query.Agent = xapi.Key(agent.IFI())
+	}
+	if isAdminRole(c.GetString("role")) {
+		return query, true
+	}
+
+	query.VisibleTo = c.GetInt64("userID")
+	if agent == nil {
+		return query, true
+	}
+	user, err := newXAPIAgentResolver(c.Request.Context()).resolve(agent)
+	if err != nil {
+		log.Printf("查找代理人失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return nil, false
+	}
+	if user == nil || user.ID == query.VisibleTo {
+		return query, true
+	}
+	allowed, err := canViewStudentData(c, user.ID)
+	if err != nil {
+		log.Printf("检查用户关系失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return nil, false
+	}
+	if !allowed {
+		c.JSON(http.StatusForbidden, gin.H{"error": "无权查看该用户的学习记录"})
+		return nil, false
+	}
+	query.VisibleTo = 0
+	query.UserID = &user.ID
+	return query, true
+}
+
+// GetXAPIStatements 按statementId或voidedStatementId获取一条语句，或按条件查询未作废的语句
+// 还有更多语句时more为下一页的地址
+func GetXAPIStatements(c *gin.Context) {
+	if id, ok := c.GetQuery("statementId"); ok {
+		getXAPIStatement(c, id, false)
+		return
+	}
+	if id, ok := c.GetQuery("voidedStatementId"); ok {
+		getXAPIStatement(c, id, true)
+		return
+	}
+
+	query, ok := xapiStatementQuery(c)
+	if !ok {
+		return
+	}
+	consistentThrough := time.Now().UTC()
+	statements, err := repository.NewXAPIRepository(database.DB).QueryStatements(c.Request.Context(), query)
+	if err != nil {
+		log.Printf("查询语句失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+
+	more := ""
+	if len(statements) > query.Limit {
+		statements = statements[:query.Limit]
+		values := c.Request.URL.Query()
+		values.Set("cursor", strconv.FormatInt(statements[len(statements)-1].ID, 10))
+		more = c.Request.URL.Path + "?" + values.Encode()
+	}
+	bodies := make([]json.RawMessage, 0, len(statements))
+	for _, statement := range statements {
+		bodies = append(bodies, json.RawMessage(statement.Body))
+	}
+
+	c.Header("X-Experience-API-Consistent-Through", consistentThrough.Format(time.RFC3339Nano))
+	c.JSON(http.StatusOK, gin.H{
+		"statements": bodies,
+		"more":       more,
+	})
+}
@@ -0,0 +1,368 @@
+package controllers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"EduGo_servers/internal/database"
+	"EduGo_servers/internal/models"
+	"EduGo_servers/internal/repository"
+	"EduGo_servers/internal/xapi"
+)
+
+const (
+	maxXAPIDocumentBody = 1 << 20 // 状态和活动档案最大1MB
+	maxXAPIDocumentID   = 191
+)
+
+// xapiDocumentScope 文档所属的活动（和代理人、注册）
+type xapiDocumentScope struct {
+	kind         string
+	idParam      string // 文档ID的查询参数名
+	activityID   string
+	agentKey     string
+	userID       *int64
+	registration *string
+}
+
+// key 文档ID对应的唯一键
+func (s *xapiDocumentScope) key(documentID string) string {
+	registration := ""
+	if s.registration != nil {
+		registration = *s.registration
+	}
+	return xapi.DocumentKey(s.kind, s.activityID, s.agentKey, registration, documentID)
+}
+
+// parseXAPIActivityParam 解析activityId查询参数
+func parseXAPIActivityParam(c *gin.Context) (string, bool) {
+	activityID := c.Query("activityId")
+	if !xapi.IsIRI(activityID) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "缺少有效的activityId"})
+		return "", false
+	}
+	return xapi.Key(activityID), true
+}
+
+// getXAPIStateScope 解析状态的活动、代理人和注册，非管理员只能访问本人的状态
+func getXAPIStateScope(c *gin.Context) (*xapiDocumentScope, bool) {
+	activityID, ok := parseXAPIActivityParam(c)
+	if !ok {
+		return nil, false
+	}
+	agent, err := xapi.ParseAgent(c.Query("agent"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "缺少有效的agent"})
+		return nil, false
+	}
+	if agent.IsGroup() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "状态的agent不能是小组"})
+		return nil, false
+	}
+	registration := strings.ToLower(c.Query("registration"))
+	if registration != "" && !xapi.IsUUID(registration) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的registration"})
+		return nil, false
+	}
+
+	user, err := newXAPIAgentResolver(c.Request.Context()).resolve(agent)
+	if err != nil {
+		log.Printf("查找代理人失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return nil, false
+	}
+	if !isAdminRole(c.GetString("role")) && (user == nil || user.ID != c.GetInt64("userID")) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "只能访问本人的学习状态"})
+		return nil, false
+	}
+
+	scope := &xapiDocumentScope{
+		kind:         models.XAPIDocumentState,
+		idParam:      "stateId",
+		activityID:   activityID,
+		agentKey:     xapi.Key(agent.IFI()),
+		registration: &registration,
+	}
+	if user != nil {
+		scope.userID = &user.ID
+	}
+	return scope, true
+}
+
+// getXAPIProfileScope 解析活动档案的活动，只有教师和管理员可以修改活动档案
+func getXAPIProfileScope(c *gin.Context, write bool) (*xapiDocumentScope, bool) {
+	if write && c.GetString("role") != models.RoleTeacher && !isAdminRole(c.GetString("role")) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "只有教师和管理员可以修改活动档案"})
+		return nil, false
+	}
+	activityID, ok := parseXAPIActivityParam(c)
+	if !ok {
+		return nil, false
+	}
+	return &xapiDocumentScope{
+		kind:       models.XAPIDocumentActivityProfile,
+		idParam:    "profileId",
+		activityID: activityID,
+	}, true
+}
+
+// getXAPIDocumentID 读取文档ID参数，required为false时参数可以为空
+func getXAPIDocumentID(c *gin.Context, scope *xapiDocumentScope, required bool) (string, bool) {
+	documentID := c.Query(scope.idParam)
+	if (required && documentID == "") || len(documentID) > maxXAPIDocumentID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "缺少有效的" + scope.idParam})
+		return "", false
+	}
+	return documentID, true
+}
+
+// checkXAPIPreconditions 检查If-Match和If-None-Match条件请求，不满足时返回412
+func checkXAPIPreconditions(c *gin.Context, document *models.XAPIDocument) bool {
+	matches := func(header string) bool {
+		for _, tag := range strings.Split(header, ",") {
+			tag = strings.TrimSpace(tag)
+			if tag == "*" || (document != nil && tag == document.ETag) {
+				return document != nil
+			}
+		}
+		return false
+	}
+	if header := c.GetHeader("If-Match"); header != "" && !matches(header) {
+		c.JSON(http.StatusPreconditionFailed, gin.H{"error": "文档已被修改"})
+		return false
+	}
+	if header := c.GetHeader("If-None-Match"); header != "" && matches(header) {
+		c.JSON(http.StatusPreconditionFailed, gin.H{"error": "文档已存在"})
+		return false
+	}
+	return true
+}
+
+// getXAPIDocument 获取一个文档，未指定文档ID时返回文档ID列表
+func getXAPIDocument(c *gin.Context, scope *xapiDocumentScope) {
+	documentID, ok := getXAPIDocumentID(c, scope, false)
+	if !ok {
+		return
+	}
+	ctx := c.Request.Context()
+	xapiRepo := repository.NewXAPIRepository(database.DB)
+
+	if documentID == "" {
+		since, ok := parseXAPITime(c, "since")
+		if !ok {
+			return
+		}
+		ids, err := xapiRepo.GetDocumentIDs(ctx, scope.kind, scope.activityID, scope.agentKey, scope.registration, since)
+		if err != nil {
+			log.Printf("获取xAPI文档失败: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+			return
+		}
+		if ids == nil {
+			ids = []string{}
+		}
+		c.JSON(http.StatusOK, ids)
+		return
+	}
+
+	document, err := xapiRepo.GetDocument(ctx, scope.key(documentID))
+	if err != nil {
+		log.Printf("获取xAPI文档失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+	if document == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "文档不存在"})
+		return
+	}
+	c.Header("ETag", document.ETag)
+	c.Header("Last-Modified", document.UpdatedAt.UTC().Format(http.TimeFormat))
+	c.Data(http.StatusOK, document.ContentType, document.Content)
+}
+
+// saveXAPIDocument 保存文档：PUT替换已有文档，POST把JSON对象合并到已有文档
+// 活动档案用PUT替换已有文档时必须带If-Match或If-None-Match
+func saveXAPIDocument(c *gin.Context, scope *xapiDocumentScope, merge bool) {
+	documentID, ok := getXAPIDocumentID(c, scope, true)
+	if !ok {
+		return
+	}
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxXAPIDocumentBody)
+	content, err := c.GetRawData()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "文档过大或读取失败"})
+		return
+	}
+	contentType := c.GetHeader("Content-Type")
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	ctx := c.Request.Context()
+	xapiRepo := repository.NewXAPIRepository(database.DB)
+	key := scope.key(documentID)
+	document, err := xapiRepo.GetDocument(ctx, key)
+	if err != nil {
+		log.Printf("获取xAPI文档失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+	if !checkXAPIPreconditions(c, document) {
+		return
+	}
+	if !merge && document != nil && scope.kind == models.XAPIDocumentActivityProfile &&
+		c.GetHeader("If-Match") == "" && c.GetHeader("If-None-Match") == "" {
+		c.JSON(http.StatusConflict, gin.H{"error": "替换已有的活动档案需要If-Match或If-None-Match请求头"})
+		return
+	}
+
+	if merge {
+		if !xapi.IsJSON(contentType) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "POST合并的文档必须是application/json"})
+			return
+		}
+		existing := []byte("{}")
+		if document != nil {
+			if !xapi.IsJSON(document.ContentType) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "已有文档不是JSON，不能合并"})
+				return
+			}
+			existing = document.Content
+		}
+		if content, err = xapi.Merge(existing, content); err != nil {
+			if errors.Is(err, xapi.ErrNotJSONObject) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			log.Printf("合并xAPI文档失败: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+			return
+		}
+		contentType = "application/json"
+	}
+
+	if document == nil {
+		document = &models.XAPIDocument{
+			Kind:       scope.kind,
+			Key:        key,
+			ActivityID: scope.activityID,
+			AgentKey:   scope.agentKey,
+			UserID:     scope.userID,
+			DocumentID: documentID,
+		}
+		if scope.registration != nil {
+			document.Registration = *scope.registration
+		}
+	}
+	document.ContentType = contentType
+	document.Content = content
+	document.ETag = xapi.ETag(content)
+	document.UpdatedAt = time.Now()
+	if err := xapiRepo.SaveDocument(ctx, document); err != nil {
+		log.Printf("保存xAPI文档失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+
+	c.Header("ETag", document.ETag)
+	c.Status(http.StatusNoContent)
+}
+
+// deleteXAPIDocument 删除一个文档；状态未指定stateId时删除该活动和代理人下的全部状态
+func deleteXAPIDocument(c *gin.Context, scope *xapiDocumentScope) {
+	documentID, ok := getXAPIDocumentID(c, scope, scope.kind == models.XAPIDocumentActivityProfile)
+	if !ok {
+		return
+	}
+	ctx := c.Request.Context()
+	xapiRepo := repository.NewXAPIRepository(database.DB)
+
+	if documentID == "" {
+		if err := xapiRepo.DeleteDocuments(ctx, scope.kind, scope.activityID, scope.agentKey, scope.registration); err != nil {
+			log.Printf("删除xAPI文档失败: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+			return
+		}
+		c.Status(http.StatusNoContent)
+		return
+	}
+
+	document, err := xapiRepo.GetDocument(ctx, scope.key(documentID))
+	if err != nil {
+		log.Printf("获取xAPI文档失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+		return
+	}
+	if !checkXAPIPreconditions(c, document) {
+		return
+	}
+	if document != nil {
+		if err := xapiRepo.DeleteDocument(ctx, document.ID); err != nil {
+			log.Printf("删除xAPI文档失败: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+			return
+		}
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// GetXAPIState 获取代理人在活动中的状态，未指定stateId时返回状态ID列表
+func GetXAPIState(c *gin.Context) {
+	if scope, ok := getXAPIStateScope(c); ok {
+		getXAPIDocument(c, scope)
+	}
+}
+
+// PutXAPIState 保存代理人在活动中的状态
+func PutXAPIState(c *gin.Context) {
+	if scope, ok := getXAPIStateScope(c); ok {
+		saveXAPIDocument(c, scope, false)
+	}
+}
+
+// PostXAPIState 把JSON对象合并到代理人在活动中的状态
+func PostXAPIState(c *gin.Context) {
+	if scope, ok := getXAPIStateScope(c); ok {
+		saveXAPIDocument(c, scope, true)
+	}
+}
+
+// DeleteXAPIState 删除代理人在活动中的状态
+func DeleteXAPIState(c *gin.Context) {
+	if scope, ok := getXAPIStateScope(c); ok {
+		deleteXAPIDocument(c, scope)
+	}
+}
+
+// GetXAPIActivityProfile 获取活动档案，未指定profileId时返回档案ID列表
+func GetXAPIActivityProfile(c *gin.Context) {
+	if scope, ok := getXAPIProfileScope(c, false); ok {
+		getXAPIDocument(c, scope)
+	}
+}
+
+// PutXAPIActivityProfile 保存活动档案（教师、管理员）
+func PutXAPIActivityProfile(c *gin.Context) {
+	if scope, ok := getXAPIProfileScope(c, true); ok {
+		saveXAPIDocument(c, scope, false)
+	}
+}
+
+// PostXAPIActivityProfile 把JSON对象合并到活动档案（教师、管理员）
+func PostXAPIActivityProfile(c *gin.Context) {
+	if scope, ok := getXAPIProfileScope(c, true); ok {
+		saveXAPIDocument(c, scope, true)
+	}
+}
+
+// DeleteXAPIActivityProfile 删除活动档案（教师、管理员）
+func DeleteXAPIActivityProfile(c *gin.Context) {
+	if scope, ok := getXAPIProfileScope(c, true); ok {
+		deleteXAPIDocument(c, scope)
+	}
+}
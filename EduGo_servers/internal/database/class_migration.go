@@ -0,0 +1,26 @@
+package database
+
+import (
+	"log"
+
+	"gorm.io/gorm"
+
+	"EduGo_servers/internal/models"
+)
+
+// dedupeClassStudents 添加班级学生唯一索引之前，移除同时在多个班级的学生的多余记录，保留最早建立的班级
+func dedupeClassStudents(db *gorm.DB) error {
+	migrator := db.Migrator()
+	if !migrator.HasTable(&models.ClassStudent{}) || migrator.HasIndex(&models.ClassStudent{}, "idx_class_students_user") {
+		return nil
+	}
+	result := db.Exec(`DELETE duplicate FROM class_students duplicate
+		JOIN class_students kept ON kept.user_id = duplicate.user_id AND kept.class_id < duplicate.class_id`)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected > 0 {
+		log.Printf("已移除 %d 条重复的班级学生记录，每名学生只保留在最早建立的班级", result.RowsAffected)
+	}
+	return nil
+}
@@ -0,0 +1,133 @@
+package database
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"gorm.io/gorm"
+
+	"EduGo_servers/internal/models"
+)
+
+// normalizeCourseName 规范化课程名称，用于合并大小写、空白不同的同一课程
+func normalizeCourseName(name string) string {
+	return strings.ToLower(strings.Join(strings.Fields(name), ""))
+}
+
+// migrateLegacyCourseNames 将教师-学生关系中只有自由文本课程名称的记录关联到课程表
+// 课程表建立之前 course_id 是自由填写的数字，不指向任何课程：课程表为空时全部非0的 course_id 都按旧数据处理，
+// 之后指向不存在的课程的 course_id 也按旧数据处理，按课程名称重新关联，没有课程名称的清零。
+// 名称规范化后相同的记录合并为同一课程，课程代码为 LEGACY-<序号>，关系中的教师成为课程负责教师。
+// 关联后与已有关系重复的合并为一条。包括随用户软删除的关系，可以重复执行
+func migrateLegacyCourseNames(db *gorm.DB) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		var courseCount int64
+		if err := tx.Unscoped().Model(&models.Course{}).Count(&courseCount).Error; err != nil {
+			return err
+		}
+		stale := tx.Where("course_id <> 0")
+		if courseCount > 0 {
+			stale = stale.Where("course_id NOT IN (?)", tx.Unscoped().Model(&models.Course{}).Select("id"))
+		}
+		var relations []*models.TeacherStudentRelation
+		err := tx.Unscoped().Where(stale).Or("course_id = 0 AND course_name <> ''").Order("id").Find(&relations).Error
+		if err != nil || len(relations) == 0 {
+			return err
+		}
+
+		var courses []*models.Course
+		if err := tx.Find(&courses).Error; err != nil {
+			return err
+		}
+		byName := map[string]*models.Course{}
+		for _, course := range courses {
+			key := normalizeCourseName(course.Name)
+			if _, ok := byName[key]; !ok {
+				byName[key] = course
+			}
+		}
+
+		mapped, cleared, merged, created := 0, 0, 0, 0
+		for _, relation := range relations {
+			var course *models.Course
+			if key := normalizeCourseName(relation.CourseName); key != "" {
+				var ok bool
+				if course, ok = byName[key]; !ok {
+					code, err := nextLegacyCourseCode(tx)
+					if err != nil {
+						return err
+					}
+					course = &models.Course{
+						Code: code,
+						Name: strings.Join(strings.Fields(relation.CourseName), " "),
+					}
+					if err := tx.Create(course).Error; err != nil {
+						return err
+					}
+					byName[key] = course
+					created++
+				}
+
+				var teacher models.User
+				if err := tx.First(&teacher, relation.UserID).Error; err == nil && teacher.Role == models.RoleTeacher {
+					if err := tx.Model(course).Association("Teachers").Append(&teacher); err != nil {
+						return err
+					}
+				}
+			}
+
+			updates := map[string]interface{}{"course_id": int64(0)}
+			if course != nil {
+				updates = map[string]interface{}{"course_id": course.ID, "course_name": course.Name}
+			} else if relation.CourseID == 0 {
+				continue
+			}
+
+			duplicate, err := findDuplicateRelation(tx, relation, updates["course_id"].(int64))
+			if err != nil {
+				return err
+			}
+			if duplicate != nil {
+				kept, err := mergeDuplicateRelation(tx, duplicate, relation)
+				if err != nil {
+					return err
+				}
+				merged++
+				if kept == duplicate {
+					continue
+				}
+			}
+
+			if err := tx.Unscoped().Model(relation).Updates(updates).Error; err != nil {
+				return err
+			}
+			if course != nil {
+				mapped++
+			} else {
+				cleared++
+			}
+		}
+		log.Printf("已将 %d 条教师-学生关系关联到课程表，%d 条没有课程名称的清除了旧课程ID，合并 %d 条重复关系，新建 %d 门迁移课程",
+			mapped, cleared, merged, created)
+		return nil
+	})
+}
+
+// nextLegacyCourseCode 生成未被占用的迁移课程代码
+func nextLegacyCourseCode(tx *gorm.DB) (string, error) {
+	var count int64
+	if err := tx.Unscoped().Model(&models.Course{}).Where("code LIKE 'LEGACY-%'").Count(&count).Error; err != nil {
+		return "", err
+	}
+	for n := count + 1; ; n++ {
+		code := fmt.Sprintf("LEGACY-%04d", n)
+		var exists int64
+		if err := tx.Unscoped().Model(&models.Course{}).Where("code = ?", code).Count(&exists).Error; err != nil {
+			return "", err
+		}
+		if exists == 0 {
+			return code, nil
+		}
+	}
+}
@@ -19,7 +19,8 @@ func InitDB(host, port, user, password, dbname string) error {
 		user, password, host, port, dbname)
 
 	var err error
-	DB, err = gorm.Open(mysql.Open(dsn), &gorm.Config{})
+	// 开启错误转换，唯一索引冲突返回gorm.ErrDuplicatedKey
+	DB, err = gorm.Open(mysql.Open(dsn), &gorm.Config{TranslateError: true})
 	if err != nil {
 		return fmt.Errorf("failed to connect to database: %w", err)
 	}
@@ -43,6 +44,14 @@ func InitDB(host, port, user, password, dbname string) error {
 
 	log.Println("Successfully connected to database")
 
+	// 班级学生名单使用自定义连接表，学生只能属于一个班级
+	if err := DB.SetupJoinTable(&models.Class{}, "Students", &models.ClassStudent{}); err != nil {
+		return fmt.Errorf("failed to set up class students join table: %w", err)
+	}
+	if err := dedupeClassStudents(DB); err != nil {
+		return fmt.Errorf("failed to dedupe class students: %w", err)
+	}
+
 	// Auto migrate models
 	err = DB.AutoMigrate(
 		&models.User{},
@@ -50,11 +59,90 @@ func InitDB(host, port, user, password, dbname string) error {
 		&models.AdminTeacherRelation{},
 		&models.TeacherStudentRelation{},
 		&models.StudentParentRelation{},
+		&models.Invitation{},
+		&models.JoinCode{},
+		&models.LoginRecord{},
+		&models.DataExport{},
+		&models.ErasureRequest{},
+		&models.Course{},
+		&models.AcademicTerm{},
+		&models.CourseOffering{},
+		&models.Class{},
+		&models.ClassSubjectTeacher{},
+		&models.Enrollment{},
+		&models.Period{},
+		&models.Room{},
+		&models.ScheduledSession{},
+		&models.CalendarFeed{},
+		&models.AttendanceRecord{},
+		&models.Notification{},
+		&models.Assignment{},
+		&models.AssignmentAttachment{},
+		&models.Submission{},
+		&models.SubmissionFile{},
+		&models.GradingScale{},
+		&models.GradingScaleBand{},
+		&models.GradeCategory{},
+		&models.GradeItem{},
+		&models.GradeEntry{},
+		&models.TermGrade{},
+		&models.ReportCardTemplate{},
+		&models.ReportCardComment{},
+		&models.ReportCardBatch{},
+		&models.Question{},
+		&models.QuestionOption{},
+		&models.QuestionAnswer{},
+		&models.QuestionTag{},
+		&models.ItemParameter{},
+		&models.KnowledgePoint{},
+		&models.KnowledgeObservation{},
+		&models.StudentMastery{},
+		&models.MistakeEntry{},
+		&models.MistakePractice{},
+		&models.MistakePracticeItem{},
+		&models.ReviewItem{},
+		&models.ReviewLog{},
+		&models.Resource{},
+		&models.RecommendationFeedback{},
+		&models.RecommendationOverride{},
+		&models.CourseUnit{},
+		&models.Lesson{},
+		&models.LessonActivity{},
+		&models.LessonProgress{},
+		&models.XAPICredential{},
+		&models.XAPIStatement{},
+		&models.XAPIStatementRef{},
+		&models.XAPIDocument{},
+		&models.LTIKey{},
+		&models.LTITool{},
+		&models.LTILink{},
+		&models.LTILaunch{},
+		&models.LTIAssertion{},
+		&models.LTIPlatform{},
+		&models.LTIState{},
+		&models.LTIUser{},
+		&models.LTIContext{},
+		&models.Assessment{},
+		&models.AssessmentQuestion{},
+		&models.AssessmentAttempt{},
+		&models.AttemptAnswer{},
 	)
 	if err != nil {
 		return fmt.Errorf("failed to auto migrate models: %w", err)
 	}
 
+	if err := migrateTeacherStudentUniqueness(DB); err != nil {
+		return fmt.Errorf("failed to add teacher-student relation unique index: %w", err)
+	}
+
+	if err := migrateLegacyCourseNames(DB); err != nil {
+		return fmt.Errorf("failed to migrate legacy course names: %w", err)
+	}
+
+	if err := seedGradingScales(DB); err != nil {
+		return fmt.Errorf("failed to seed grading scales: %w", err)
+	}
+
 	log.Println("Database migration completed")
 	return nil
 }
@@ -0,0 +1,47 @@
+package database
+
+import (
+	"gorm.io/gorm"
+
+	"EduGo_servers/internal/models"
+)
+
+// builtInGradingScales 系统内置的评分制
+var builtInGradingScales = []models.GradingScale{
+	{Name: "百分制", Type: models.GradingScalePercentage},
+	{Name: "等级制", Type: models.GradingScaleLetter, Bands: []models.GradingScaleBand{
+		{Label: "A", MinPercent: 90, GradePoint: 4},
+		{Label: "B", MinPercent: 80, GradePoint: 3},
+		{Label: "C", MinPercent: 70, GradePoint: 2},
+		{Label: "D", MinPercent: 60, GradePoint: 1},
+		{Label: "F", MinPercent: 0, GradePoint: 0},
+	}},
+	{Name: "五级制", Type: models.GradingScaleFiveLevel, Bands: []models.GradingScaleBand{
+		{Label: "优秀", MinPercent: 90, GradePoint: 4},
+		{Label: "良好", MinPercent: 80, GradePoint: 3},
+		{Label: "中等", MinPercent: 70, GradePoint: 2},
+		{Label: "及格", MinPercent: 60, GradePoint: 1},
+		{Label: "不及格", MinPercent: 0, GradePoint: 0},
+	}},
+}
+
+// seedGradingScales 创建缺少的内置评分制，可以重复执行
+func seedGradingScales(db *gorm.DB) error {
+	for _, builtIn := range builtInGradingScales {
+		var count int64
+		if err := db.Model(&models.GradingScale{}).Where("name = ?", builtIn.Name).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			continue
+		}
+
+		scale := builtIn
+		scale.BuiltIn = true
+		scale.Bands = append([]models.GradingScaleBand(nil), builtIn.Bands...)
+		if err := db.Create(&scale).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
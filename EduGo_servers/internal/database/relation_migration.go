@@ -0,0 +1,118 @@
+package database
+
+import (
+	"log"
+
+	"gorm.io/gorm"
+
+	"EduGo_servers/internal/models"
+)
+
+// teacherStudentUniqueIndex 同一教师和学生在同一课程和学期下只有一条教师-学生关系
+// 关系字段来自各关系表共用的 UserRelation，不能用模型标签声明，由 migrateTeacherStudentUniqueness 创建
+const teacherStudentUniqueIndex = "idx_teacher_student_course_term"
+
+// relationRank 合并重复关系时的优先级：未删除的优先，其次是有效的
+func relationRank(relation *models.TeacherStudentRelation) int {
+	rank := 0
+	if !relation.DeletedAt.Valid {
+		rank += 2
+	}
+	if relation.Status == models.RelationStatusActive {
+		rank++
+	}
+	return rank
+}
+
+// mergeDuplicateRelation 删除两条重复关系中优先级较低的一条，优先级相同时保留a，返回保留的关系
+func mergeDuplicateRelation(tx *gorm.DB, a, b *models.TeacherStudentRelation) (*models.TeacherStudentRelation, error) {
+	keep, drop := a, b
+	if relationRank(b) > relationRank(a) {
+		keep, drop = b, a
+	}
+	if err := tx.Unscoped().Delete(drop).Error; err != nil {
+		return nil, err
+	}
+	return keep, nil
+}
+
+// findDuplicateRelation 查找关联到courseID后与relation重复的另一条关系，包括随用户软删除的
+func findDuplicateRelation(tx *gorm.DB, relation *models.TeacherStudentRelation, courseID int64) (*models.TeacherStudentRelation, error) {
+	var duplicates []*models.TeacherStudentRelation
+	err := tx.Unscoped().
+		Where("id <> ? AND user_id = ? AND related_user_id = ?", relation.ID, relation.UserID, relation.RelatedUserID).
+		Where("course_id = ? AND term_id = ? AND semester = ?", courseID, relation.TermID, relation.Semester).
+		Limit(1).
+		Find(&duplicates).Error
+	if err != nil || len(duplicates) == 0 {
+		return nil, err
+	}
+	return duplicates[0], nil
+}
+
+// migrateTeacherStudentUniqueness 合并重复的教师-学生关系并添加唯一索引，
+// 并发使用同一加入码等情况下由索引拒绝重复的关系。索引已存在时跳过
+func migrateTeacherStudentUniqueness(db *gorm.DB) error {
+	if db.Migrator().HasIndex(&models.TeacherStudentRelation{}, teacherStudentUniqueIndex) {
+		return nil
+	}
+
+	merged := 0
+	err := db.Transaction(func(tx *gorm.DB) error {
+		// 唯一索引不把NULL视为重复，先统一为零值
+		for column, zero := range map[string]interface{}{"course_id": 0, "term_id": 0, "semester": ""} {
+			err := tx.Unscoped().Model(&models.TeacherStudentRelation{}).
+				Where(column+" IS NULL").
+				Update(column, zero).Error
+			if err != nil {
+				return err
+			}
+		}
+
+		var groups []struct {
+			UserID        int64
+			RelatedUserID int64
+			CourseID      int64
+			TermID        int64
+			Semester      string
+		}
+		err := tx.Unscoped().Model(&models.TeacherStudentRelation{}).
+			Select("user_id, related_user_id, course_id, term_id, semester").
+			Group("user_id, related_user_id, course_id, term_id, semester").
+			Having("COUNT(*) > 1").
+			Scan(&groups).Error
+		if err != nil {
+			return err
+		}
+
+		for _, group := range groups {
+			var relations []*models.TeacherStudentRelation
+			err := tx.Unscoped().
+				Where("user_id = ? AND related_user_id = ?", group.UserID, group.RelatedUserID).
+				Where("course_id = ? AND term_id = ? AND semester = ?", group.CourseID, group.TermID, group.Semester).
+				Order("id").
+				Find(&relations).Error
+			if err != nil {
+				return err
+			}
+			for i := 1; i < len(relations); i++ {
+				kept, err := mergeDuplicateRelation(tx, relations[0], relations[i])
+				if err != nil {
+					return err
+				}
+				relations[0] = kept
+				merged++
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if merged > 0 {
+		log.Printf("已合并 %d 条重复的教师-学生关系", merged)
+	}
+
+	return db.Exec("CREATE UNIQUE INDEX " + teacherStudentUniqueIndex +
+		" ON teacher_student_relations (user_id, related_user_id, course_id, term_id, semester)").Error
+}
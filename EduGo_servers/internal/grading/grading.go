@@ -0,0 +1,95 @@
+// Package grading 根据成绩分类权重计算学生的总评成绩
+package grading
+
+import (
+	"math"
+	"sort"
+
+	"EduGo_servers/internal/models"
+)
+
+// CategoryResult 学生在一个成绩分类上的得分
+type CategoryResult struct {
+	CategoryID int64   `json:"category_id"`
+	Name       string  `json:"name"`
+	Weight     float64 `json:"weight"`
+	Earned     float64 `json:"earned"`
+	Possible   float64 `json:"possible"`
+	Percent    float64 `json:"percent"`
+	Graded     bool    `json:"graded"`  // 该分类下是否有已评分的成绩项
+	Dropped    int     `json:"dropped"` // 去掉的最低分成绩项数
+}
+
+// Result 学生的总评成绩
+type Result struct {
+	StudentID  int64            `json:"student_id"`
+	Categories []CategoryResult `json:"categories"`
+	Percent    float64          `json:"percent"`
+	Graded     bool             `json:"graded"`
+}
+
+// score 一个已评分成绩项的得分和满分
+type score struct {
+	earned   float64
+	possible float64
+}
+
+// Compute 计算一名学生的总评
+// 未评分和免考的成绩项不计入；分类设置了去掉最低分时，去掉得分率最低的几项，但至少保留一项；
+// 没有已评分成绩项的分类不计入，其余分类的权重按比例放大，权重都为0时没有总评
+func Compute(studentID int64, categories []*models.GradeCategory, items []*models.GradeItem, entries []*models.GradeEntry) Result {
+	byItem := make(map[int64]*models.GradeEntry, len(entries))
+	for _, entry := range entries {
+		if entry.StudentID == studentID {
+			byItem[entry.ItemID] = entry
+		}
+	}
+
+	result := Result{StudentID: studentID, Categories: make([]CategoryResult, 0, len(categories))}
+	var weighted, totalWeight float64
+	for _, category := range categories {
+		categoryResult := CategoryResult{CategoryID: category.ID, Name: category.Name, Weight: category.Weight}
+		var scores []score
+		for _, item := range items {
+			if item.CategoryID != category.ID || item.MaxScore <= 0 {
+				continue
+			}
+			entry := byItem[item.ID]
+			if entry == nil || entry.Score == nil || entry.Excused {
+				continue
+			}
+			scores = append(scores, score{earned: entry.EffectiveScore(item.MaxScore), possible: item.MaxScore})
+		}
+
+		if drop := min(category.DropLowest, len(scores)-1); drop > 0 {
+			sort.SliceStable(scores, func(i, j int) bool {
+				return scores[i].earned/scores[i].possible < scores[j].earned/scores[j].possible
+			})
+			scores = scores[drop:]
+			categoryResult.Dropped = drop
+		}
+		for _, s := range scores {
+			categoryResult.Earned += s.earned
+			categoryResult.Possible += s.possible
+		}
+
+		if categoryResult.Possible > 0 {
+			categoryResult.Graded = true
+			categoryResult.Percent = round(categoryResult.Earned / categoryResult.Possible * 100)
+			weighted += category.Weight * categoryResult.Earned / categoryResult.Possible * 100
+			totalWeight += category.Weight
+		}
+		result.Categories = append(result.Categories, categoryResult)
+	}
+
+	if totalWeight > 0 {
+		result.Graded = true
+		result.Percent = round(weighted / totalWeight)
+	}
+	return result
+}
+
+// round 保留两位小数
+func round(value float64) float64 {
+	return math.Round(value*100) / 100
+}
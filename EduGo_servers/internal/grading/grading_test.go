@@ -0,0 +1,191 @@
+package grading
+
+import (
+	"math"
+	"testing"
+
+	"EduGo_servers/internal/models"
+)
+
+const studentID = 1
+
+// 作业分类有三个成绩项，满分分别为10、20、10；考试分类有一个满分100的成绩项
+func sampleItems() []*models.GradeItem {
+	return []*models.GradeItem{
+		{ID: 11, CategoryID: 1, MaxScore: 10},
+		{ID: 12, CategoryID: 1, MaxScore: 20},
+		{ID: 13, CategoryID: 1, MaxScore: 10},
+		{ID: 21, CategoryID: 2, MaxScore: 100},
+	}
+}
+
+// categories 作业和考试两个分类，作业分类去掉homeworkDrop个最低分
+func categories(homeworkWeight, examWeight float64, homeworkDrop int) []*models.GradeCategory {
+	return []*models.GradeCategory{
+		{ID: 1, Name: "作业", Weight: homeworkWeight, DropLowest: homeworkDrop},
+		{ID: 2, Name: "考试", Weight: examWeight},
+	}
+}
+
+// entry 学生在成绩项上的得分，score为负数表示未评分
+func entry(itemID int64, score float64) *models.GradeEntry {
+	e := &models.GradeEntry{ItemID: itemID, StudentID: studentID}
+	if score >= 0 {
+		e.Score = &score
+	}
+	return e
+}
+
+// excused 免考的成绩项
+func excused(itemID int64, score float64) *models.GradeEntry {
+	e := entry(itemID, score)
+	e.Excused = true
+	return e
+}
+
+// late 迟交扣除满分penalty%的得分
+func late(itemID int64, score, penalty float64) *models.GradeEntry {
+	e := entry(itemID, score)
+	e.LatePenalty = penalty
+	return e
+}
+
+func TestCompute(t *testing.T) {
+	type want struct {
+		graded   bool
+		percent  float64
+		homework CategoryResult // 只比较Earned、Possible、Percent、Graded和Dropped
+	}
+	tests := []struct {
+		name       string
+		categories []*models.GradeCategory
+		entries    []*models.GradeEntry
+		want       want
+	}{
+		{
+			"按权重加权平均",
+			categories(30, 70, 0),
+			[]*models.GradeEntry{entry(11, 8), entry(12, 15), entry(13, 0), entry(21, 90)},
+			want{true, 57.5*0.3 + 90*0.7, CategoryResult{Earned: 23, Possible: 40, Percent: 57.5, Graded: true}},
+		},
+		{
+			"权重之和不是100时按比例换算",
+			categories(10, 10, 0),
+			[]*models.GradeEntry{entry(11, 8), entry(12, 15), entry(13, 0), entry(21, 90)},
+			want{true, (57.5 + 90) / 2, CategoryResult{Earned: 23, Possible: 40, Percent: 57.5, Graded: true}},
+		},
+		{
+			"没有已评分成绩项的分类不计入",
+			categories(30, 70, 0),
+			[]*models.GradeEntry{entry(21, 90)},
+			want{true, 90, CategoryResult{}},
+		},
+		{
+			"未评分和免考的成绩项不计入",
+			categories(30, 70, 0),
+			[]*models.GradeEntry{entry(11, 8), excused(12, 0), entry(13, -1), entry(21, 90)},
+			want{true, 80*0.3 + 90*0.7, CategoryResult{Earned: 8, Possible: 10, Percent: 80, Graded: true}},
+		},
+		{
+			"分类下全部免考",
+			categories(30, 70, 0),
+			[]*models.GradeEntry{excused(11, 0), excused(12, 0), excused(13, 0), entry(21, 60)},
+			want{true, 60, CategoryResult{}},
+		},
+		{
+			"扣除迟交分",
+			categories(30, 70, 0),
+			[]*models.GradeEntry{late(11, 8, 20), late(12, 2, 50), entry(21, 90)},
+			want{true, 20*0.3 + 90*0.7, CategoryResult{Earned: 6, Possible: 30, Percent: 20, Graded: true}},
+		},
+		{
+			"去掉得分率最低的一项",
+			categories(30, 70, 1),
+			[]*models.GradeEntry{entry(11, 8), entry(12, 15), entry(13, 3), entry(21, 90)},
+			want{true, 23.0/30*100*0.3 + 90*0.7, CategoryResult{Earned: 23, Possible: 30, Percent: 76.67, Graded: true, Dropped: 1}},
+		},
+		{
+			"按得分率而不是得分去掉",
+			categories(30, 70, 1),
+			[]*models.GradeEntry{entry(11, 3), entry(12, 4), entry(13, 9), entry(21, 90)},
+			want{true, 60*0.3 + 90*0.7, CategoryResult{Earned: 12, Possible: 20, Percent: 60, Graded: true, Dropped: 1}},
+		},
+		{
+			"只在已评分的成绩项中去掉",
+			categories(30, 70, 1),
+			[]*models.GradeEntry{entry(11, 8), entry(12, 15), excused(13, 0), entry(21, 90)},
+			want{true, 80*0.3 + 90*0.7, CategoryResult{Earned: 8, Possible: 10, Percent: 80, Graded: true, Dropped: 1}},
+		},
+		{
+			"去掉最低分时至少保留一项",
+			categories(30, 70, 5),
+			[]*models.GradeEntry{entry(11, 8), entry(12, 15), entry(13, 3), entry(21, 90)},
+			want{true, 80*0.3 + 90*0.7, CategoryResult{Earned: 8, Possible: 10, Percent: 80, Graded: true, Dropped: 2}},
+		},
+		{
+			"只有一项时不去掉",
+			categories(30, 70, 1),
+			[]*models.GradeEntry{entry(13, 3), entry(21, 90)},
+			want{true, 30*0.3 + 90*0.7, CategoryResult{Earned: 3, Possible: 10, Percent: 30, Graded: true}},
+		},
+		{
+			"权重为0的分类不影响总评",
+			categories(0, 70, 0),
+			[]*models.GradeEntry{entry(11, 1), entry(21, 90)},
+			want{true, 90, CategoryResult{Earned: 1, Possible: 10, Percent: 10, Graded: true}},
+		},
+		{
+			"权重都为0时没有总评",
+			categories(0, 0, 0),
+			[]*models.GradeEntry{entry(11, 8), entry(21, 90)},
+			want{false, 0, CategoryResult{Earned: 8, Possible: 10, Percent: 80, Graded: true}},
+		},
+		{
+			"没有已评分的成绩项",
+			categories(30, 70, 1),
+			[]*models.GradeEntry{entry(11, -1), excused(21, 0)},
+			want{false, 0, CategoryResult{}},
+		},
+		{
+			"其他学生的得分不计入",
+			categories(30, 70, 0),
+			[]*models.GradeEntry{{ItemID: 21, StudentID: 2, Score: new(float64)}, entry(21, 75)},
+			want{true, 75, CategoryResult{}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := Compute(studentID, tt.categories, sampleItems(), tt.entries)
+			if math.IsNaN(result.Percent) || math.IsInf(result.Percent, 0) {
+				t.Fatalf("Percent = %v, want finite", result.Percent)
+			}
+			if result.StudentID != studentID {
+				t.Errorf("StudentID = %d, want %d", result.StudentID, studentID)
+			}
+			if result.Graded != tt.want.graded || math.Abs(result.Percent-round(tt.want.percent)) > 1e-9 {
+				t.Errorf("Compute() = graded %v, percent %v, want graded %v, percent %v",
+					result.Graded, result.Percent, tt.want.graded, round(tt.want.percent))
+			}
+			if len(result.Categories) != len(tt.categories) {
+				t.Fatalf("got %d categories, want %d", len(result.Categories), len(tt.categories))
+			}
+			homework := result.Categories[0]
+			if homework.CategoryID != 1 || homework.Weight != tt.categories[0].Weight {
+				t.Errorf("homework = %+v, want category 1 with weight %v", homework, tt.categories[0].Weight)
+			}
+			w := tt.want.homework
+			if homework.Graded != w.Graded || homework.Dropped != w.Dropped ||
+				math.Abs(homework.Earned-w.Earned) > 1e-9 || math.Abs(homework.Possible-w.Possible) > 1e-9 ||
+				math.Abs(homework.Percent-w.Percent) > 1e-9 {
+				t.Errorf("homework = %+v, want %+v", homework, w)
+			}
+		})
+	}
+}
+
+func TestComputeWithoutCategories(t *testing.T) {
+	result := Compute(studentID, nil, sampleItems(), []*models.GradeEntry{entry(11, 8)})
+	if result.Graded || result.Percent != 0 || len(result.Categories) != 0 {
+		t.Errorf("Compute() = %+v, want ungraded without categories", result)
+	}
+}
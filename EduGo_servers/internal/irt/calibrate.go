@@ -0,0 +1,152 @@
+package irt
+
+import (
+	"math"
+)
+
+// Response 一条历史作答记录，Person为一次作答（同一学生的不同作答视为不同的被试）
+type Response struct {
+	Person  int64
+	Item    int64
+	Correct bool
+}
+
+// Calibration 一道题的标定结果
+type Calibration struct {
+	Params
+	Responses int // 参与标定的作答数
+}
+
+const (
+	emCycles          = 50
+	newtonSteps       = 5
+	maxNewtonStep     = 0.5
+	difficultyPriorSD = 2.0 // 难度的正态先验N(0, 2²)，防止全对或全错的题目难度发散
+	discriminationSD  = 1.0 // 区分度的正态先验N(1, 1²)
+)
+
+// Calibrate 用边际极大似然的EM算法（Bock-Aitkin）标定项目参数，学生能力的分布取标准正态
+// 作答数少于minResponses的题目不标定
+func Calibrate(responses []Response, minResponses int) map[int64]Calibration {
+	counts := map[int64]int{}
+	for _, response := range responses {
+		counts[response.Item]++
+	}
+
+	byPerson := map[int64][]Response{}
+	byItem := map[int64]int{}
+	for _, response := range responses {
+		if counts[response.Item] < minResponses {
+			continue
+		}
+		byPerson[response.Person] = append(byPerson[response.Person], response)
+		byItem[response.Item]++
+	}
+
+	// 初始难度取答对率的logit
+	params := make(map[int64]Params, len(byItem))
+	correct := map[int64]float64{}
+	for _, personResponses := range byPerson {
+		for _, response := range personResponses {
+			correct[response.Item] += outcome(response)
+		}
+	}
+	for item, n := range byItem {
+		rate := (correct[item] + 0.5) / (float64(n) + 1) // 平滑，避免答对率为0或1
+		params[item] = Params{Discrimination: 1, Difficulty: clamp(-math.Log(rate/(1-rate)), MinTheta, MaxTheta)}
+	}
+
+	prior := make([]float64, len(quadrature))
+	for k, point := range quadrature {
+		prior[k] = -point * point / 2
+	}
+
+	for cycle := 0; cycle < emCycles; cycle++ {
+		// E步：按每名被试的后验分布，把作答分配到各积分点，得到每道题在各积分点上的期望作答数n和期望答对数r
+		expectedN := make(map[int64][]float64, len(params))
+		expectedR := make(map[int64][]float64, len(params))
+		for item := range params {
+			expectedN[item] = make([]float64, len(quadrature))
+			expectedR[item] = make([]float64, len(quadrature))
+		}
+		posterior := make([]float64, len(quadrature))
+		for _, personResponses := range byPerson {
+			maxLog := math.Inf(-1)
+			for k, point := range quadrature {
+				logLikelihood := prior[k]
+				for _, response := range personResponses {
+					prob := Probability(point, params[response.Item])
+					if response.Correct {
+						logLikelihood += math.Log(prob)
+					} else {
+						logLikelihood += math.Log(1 - prob)
+					}
+				}
+				posterior[k] = logLikelihood
+				maxLog = math.Max(maxLog, logLikelihood)
+			}
+			var sum float64
+			for k := range posterior {
+				posterior[k] = math.Exp(posterior[k] - maxLog)
+				sum += posterior[k]
+			}
+			for _, response := range personResponses {
+				n, r := expectedN[response.Item], expectedR[response.Item]
+				for k := range posterior {
+					weight := posterior[k] / sum
+					n[k] += weight
+					r[k] += weight * outcome(response)
+				}
+			}
+		}
+
+		// M步：逐题最大化期望对数似然
+		for item, p := range params {
+			params[item] = fitItem(p, expectedN[item], expectedR[item])
+		}
+	}
+
+	result := make(map[int64]Calibration, len(params))
+	for item, p := range params {
+		result[item] = Calibration{Params: p, Responses: byItem[item]}
+	}
+	return result
+}
+
+// fitItem 用Newton-Raphson分别更新题目的难度和区分度，n和r为各积分点上的期望作答数和期望答对数
+func fitItem(p Params, n, r []float64) Params {
+	for step := 0; step < newtonSteps; step++ {
+		// 难度
+		gradient := -p.Difficulty / (difficultyPriorSD * difficultyPriorSD)
+		hessian := -1 / (difficultyPriorSD * difficultyPriorSD)
+		for k, theta := range quadrature {
+			prob := Probability(theta, p)
+			gradient += -p.Discrimination * (r[k] - n[k]*prob)
+			hessian -= n[k] * p.Discrimination * p.Discrimination * prob * (1 - prob)
+		}
+		p.Difficulty = clamp(p.Difficulty-damp(gradient/hessian), MinTheta, MaxTheta)
+
+		// 区分度
+		gradient = -(p.Discrimination - 1) / (discriminationSD * discriminationSD)
+		hessian = -1 / (discriminationSD * discriminationSD)
+		for k, theta := range quadrature {
+			prob := Probability(theta, p)
+			gradient += (theta - p.Difficulty) * (r[k] - n[k]*prob)
+			hessian -= n[k] * (theta - p.Difficulty) * (theta - p.Difficulty) * prob * (1 - prob)
+		}
+		p.Discrimination = clamp(p.Discrimination-damp(gradient/hessian), MinDiscrimination, MaxDiscrimination)
+	}
+	return p
+}
+
+// damp 限制Newton-Raphson每一步的变化量，避免震荡
+func damp(delta float64) float64 {
+	return clamp(delta, -maxNewtonStep, maxNewtonStep)
+}
+
+func outcome(response Response) float64 {
+	if response.Correct {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,121 @@
+package irt
+
+import (
+	"math"
+	"math/rand/v2"
+	"testing"
+)
+
+// simulate 按给定的项目参数为persons名能力服从标准正态分布的被试生成作答，题目ID从1开始连续编号
+func simulate(rng *rand.Rand, items map[int64]Params, persons int) []Response {
+	var responses []Response
+	for person := 0; person < persons; person++ {
+		theta := rng.NormFloat64()
+		for item := int64(1); item <= int64(len(items)); item++ {
+			responses = append(responses, Response{
+				Person:  int64(person),
+				Item:    item,
+				Correct: rng.Float64() < Probability(theta, items[item]),
+			})
+		}
+	}
+	return responses
+}
+
+func TestCalibrateRecoversParams(t *testing.T) {
+	items := map[int64]Params{
+		1: {Discrimination: 0.8, Difficulty: -1.5},
+		2: {Discrimination: 1.2, Difficulty: -0.5},
+		3: {Discrimination: 1.0, Difficulty: 0},
+		4: {Discrimination: 1.5, Difficulty: 0.5},
+		5: {Discrimination: 1.0, Difficulty: 1.5},
+		6: {Discrimination: 2.0, Difficulty: -0.2},
+	}
+	responses := simulate(rand.New(rand.NewPCG(1, 2)), items, 3000)
+
+	result := Calibrate(responses, 10)
+	if len(result) != len(items) {
+		t.Fatalf("calibrated %d items, want %d", len(result), len(items))
+	}
+	for item, want := range items {
+		got := result[item]
+		if got.Responses != 3000 {
+			t.Errorf("item %d: Responses = %d, want 3000", item, got.Responses)
+		}
+		if math.Abs(got.Difficulty-want.Difficulty) > 0.25 {
+			t.Errorf("item %d: Difficulty = %.3f, want %.3f", item, got.Difficulty, want.Difficulty)
+		}
+		if math.Abs(got.Discrimination-want.Discrimination) > 0.3 {
+			t.Errorf("item %d: Discrimination = %.3f, want %.3f", item, got.Discrimination, want.Discrimination)
+		}
+	}
+}
+
+func TestCalibrate(t *testing.T) {
+	tests := []struct {
+		name      string
+		responses []Response
+		min       int
+		want      map[int64]int // 参与标定的题目及作答数
+	}{
+		{"没有作答", nil, 1, map[int64]int{}},
+		{
+			"作答数不足的题目不标定",
+			[]Response{
+				{Person: 1, Item: 1, Correct: true}, {Person: 2, Item: 1, Correct: false}, {Person: 3, Item: 1, Correct: true},
+				{Person: 1, Item: 2, Correct: true},
+			},
+			2,
+			map[int64]int{1: 3},
+		},
+		{
+			"全对和全错的题目",
+			[]Response{
+				{Person: 1, Item: 1, Correct: true}, {Person: 2, Item: 1, Correct: true}, {Person: 3, Item: 1, Correct: true},
+				{Person: 1, Item: 2, Correct: false}, {Person: 2, Item: 2, Correct: false}, {Person: 3, Item: 2, Correct: false},
+			},
+			1,
+			map[int64]int{1: 3, 2: 3},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := Calibrate(tt.responses, tt.min)
+			if len(result) != len(tt.want) {
+				t.Fatalf("calibrated %d items, want %d", len(result), len(tt.want))
+			}
+			for item, responses := range tt.want {
+				got, ok := result[item]
+				if !ok {
+					t.Fatalf("item %d not calibrated", item)
+				}
+				if got.Responses != responses {
+					t.Errorf("item %d: Responses = %d, want %d", item, got.Responses, responses)
+				}
+				if got.Difficulty < MinTheta || got.Difficulty > MaxTheta || math.IsNaN(got.Difficulty) {
+					t.Errorf("item %d: Difficulty = %v, want within [%v, %v]", item, got.Difficulty, MinTheta, MaxTheta)
+				}
+				if got.Discrimination < MinDiscrimination || got.Discrimination > MaxDiscrimination || math.IsNaN(got.Discrimination) {
+					t.Errorf("item %d: Discrimination = %v, want within [%v, %v]", item, got.Discrimination, MinDiscrimination, MaxDiscrimination)
+				}
+			}
+		})
+	}
+}
+
+func TestCalibrateOrdersDifficulty(t *testing.T) {
+	// 答对率越低的题目标定的难度越高
+	var responses []Response
+	for person := int64(0); person < 20; person++ {
+		responses = append(responses,
+			Response{Person: person, Item: 1, Correct: person < 16},
+			Response{Person: person, Item: 2, Correct: person < 10},
+			Response{Person: person, Item: 3, Correct: person < 4},
+		)
+	}
+	result := Calibrate(responses, 1)
+	if !(result[1].Difficulty < result[2].Difficulty && result[2].Difficulty < result[3].Difficulty) {
+		t.Errorf("difficulties = %.3f, %.3f, %.3f, want increasing",
+			result[1].Difficulty, result[2].Difficulty, result[3].Difficulty)
+	}
+}
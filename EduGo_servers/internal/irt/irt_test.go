@@ -0,0 +1,155 @@
+package irt
+
+import (
+	"math"
+	"testing"
+)
+
+const tolerance = 1e-9
+
+func TestProbability(t *testing.T) {
+	tests := []struct {
+		name   string
+		theta  float64
+		params Params
+		want   float64
+	}{
+		{"能力等于难度", 0, Params{Discrimination: 1, Difficulty: 0}, 0.5},
+		{"高区分度能力等于难度", -1, Params{Discrimination: 2.5, Difficulty: -1}, 0.5},
+		{"能力高于难度", 1, Params{Discrimination: 1, Difficulty: 0}, 1 / (1 + math.Exp(-1))},
+		{"能力低于难度", 0, Params{Discrimination: 2, Difficulty: 1}, 1 / (1 + math.Exp(2))},
+		{"区分度放大差距", 0.5, Params{Discrimination: 1.5, Difficulty: -0.5}, 1 / (1 + math.Exp(-1.5))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Probability(tt.theta, tt.params); math.Abs(got-tt.want) > tolerance {
+				t.Errorf("Probability(%v, %+v) = %v, want %v", tt.theta, tt.params, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestInformation(t *testing.T) {
+	tests := []struct {
+		name   string
+		theta  float64
+		params Params
+		want   float64
+	}{
+		{"能力等于难度时最大", 0, Params{Discrimination: 1, Difficulty: 0}, 0.25},
+		{"随区分度平方增长", 2, Params{Discrimination: 2, Difficulty: 2}, 1},
+		{"偏离难度时减小", 1, Params{Discrimination: 1, Difficulty: 0}, math.Exp(-1) / math.Pow(1+math.Exp(-1), 2)},
+		{"左右对称", -1, Params{Discrimination: 1, Difficulty: 0}, math.Exp(-1) / math.Pow(1+math.Exp(-1), 2)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Information(tt.theta, tt.params); math.Abs(got-tt.want) > tolerance {
+				t.Errorf("Information(%v, %+v) = %v, want %v", tt.theta, tt.params, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDefaultParams(t *testing.T) {
+	tests := []struct {
+		level int
+		want  Params
+	}{
+		{1, Params{Discrimination: 1, Difficulty: -2}},
+		{3, Params{Discrimination: 1, Difficulty: 0}},
+		{5, Params{Discrimination: 1, Difficulty: 2}},
+		{10, Params{Discrimination: 1, Difficulty: MaxTheta}},
+	}
+	for _, tt := range tests {
+		if got := DefaultParams(tt.level); got != tt.want {
+			t.Errorf("DefaultParams(%d) = %+v, want %+v", tt.level, got, tt.want)
+		}
+	}
+}
+
+// answersOf 在给定难度的题目上按相同的对错作答
+func answersOf(correct bool, difficulties ...float64) []Answer {
+	answers := make([]Answer, 0, len(difficulties))
+	for _, difficulty := range difficulties {
+		answers = append(answers, Answer{Params: Params{Discrimination: 1.5, Difficulty: difficulty}, Correct: correct})
+	}
+	return answers
+}
+
+func TestEstimateAbility(t *testing.T) {
+	many := make([]float64, 40)
+	for i := range many {
+		many[i] = float64(i%9) - 4
+	}
+	tests := []struct {
+		name    string
+		answers []Answer
+		sign    float64 // 估计值的符号，0表示应为0
+	}{
+		{"没有作答", nil, 0},
+		{"全对", answersOf(true, -1, 0, 1, 2), 1},
+		{"全错", answersOf(false, -2, -1, 0, 1), -1},
+		{"大量全对", answersOf(true, many...), 1},
+		{"大量全错", answersOf(false, many...), -1},
+		{"对错各半", append(answersOf(true, -1, 0), answersOf(false, 0, 1)...), 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			theta, se := EstimateAbility(tt.answers)
+			if math.IsNaN(theta) || math.IsInf(theta, 0) || math.IsNaN(se) || math.IsInf(se, 0) {
+				t.Fatalf("EstimateAbility() = %v, %v, want finite values", theta, se)
+			}
+			if theta < MinTheta || theta > MaxTheta {
+				t.Errorf("theta = %v, want within [%v, %v]", theta, MinTheta, MaxTheta)
+			}
+			if se <= 0 || se > 1 {
+				t.Errorf("se = %v, want within (0, 1]", se)
+			}
+			switch {
+			case tt.sign == 0 && math.Abs(theta) > 1e-6:
+				t.Errorf("theta = %v, want 0", theta)
+			case tt.sign*theta < 0:
+				t.Errorf("theta = %v, want sign %v", theta, tt.sign)
+			}
+		})
+	}
+}
+
+func TestEstimateAbilityNarrowsWithMoreAnswers(t *testing.T) {
+	_, few := EstimateAbility(answersOf(true, -1, 0))
+	_, more := EstimateAbility(append(answersOf(true, -1, 0, -0.5), answersOf(false, 0.5, 1, 1.5)...))
+	if more >= few {
+		t.Errorf("se with more answers = %v, want less than %v", more, few)
+	}
+}
+
+func TestSelectItem(t *testing.T) {
+	candidates := []Params{
+		{Discrimination: 1, Difficulty: -3},
+		{Discrimination: 1, Difficulty: 0.2},
+		{Discrimination: 1, Difficulty: 3},
+		{Discrimination: 0.5, Difficulty: 0},
+	}
+	tests := []struct {
+		name       string
+		theta      float64
+		candidates []Params
+		topN       int
+		want       map[int]bool
+	}{
+		{"没有候选", 0, nil, 3, map[int]bool{-1: true}},
+		{"只取信息量最大的一道", 0, candidates, 1, map[int]bool{1: true}},
+		{"topN小于1时按1处理", 3, candidates, 0, map[int]bool{2: true}},
+		{"在信息量最大的两道中随机", 0, candidates, 2, map[int]bool{1: true, 3: true}},
+		{"topN超过候选数", 0, candidates[:2], 5, map[int]bool{0: true, 1: true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for i := 0; i < 50; i++ {
+				if got := SelectItem(tt.theta, tt.candidates, tt.topN); !tt.want[got] {
+					t.Fatalf("SelectItem() = %d, want one of %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,402 @@
+package jobs
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"gorm.io/gorm"
+
+	"EduGo_servers/internal/models"
+	"EduGo_servers/internal/repository"
+	"EduGo_servers/internal/xapi"
+)
+
+// ExportSection 个人数据导出压缩包中的一个数据分组
+type ExportSection struct {
+	Name    string // 压缩包中的文件名（不含扩展名）
+	Collect func(ctx context.Context, db *gorm.DB, userID int64) (interface{}, error)
+}
+
+var exportSections []ExportSection
+
+// RegisterExportSection 注册导出数据分组，压缩包中的分组按注册顺序排列，各模块的分组统一在下面的init中注册
+func RegisterExportSection(section ExportSection) {
+	exportSections = append(exportSections, section)
+}
+
+func init() {
+	RegisterExportSection(ExportSection{Name: "profile", Collect: collectProfile})
+	RegisterExportSection(ExportSection{Name: "relations", Collect: collectRelations})
+	RegisterExportSection(ExportSection{Name: "login_history", Collect: collectLoginHistory})
+	RegisterExportSection(ExportSection{Name: "enrollments", Collect: collectEnrollments})
+	RegisterExportSection(ExportSection{Name: "attendance", Collect: collectAttendance})
+	RegisterExportSection(ExportSection{Name: "submissions", Collect: collectSubmissions})
+	RegisterExportSection(ExportSection{Name: "grades", Collect: collectGrades})
+	RegisterExportSection(ExportSection{Name: "assessments", Collect: collectAssessments})
+	RegisterExportSection(ExportSection{Name: "mastery", Collect: collectMastery})
+	RegisterExportSection(ExportSection{Name: "mistakes", Collect: collectMistakes})
+	RegisterExportSection(ExportSection{Name: "reviews", Collect: collectReviews})
+	RegisterExportSection(ExportSection{Name: "resources", Collect: collectResources})
+	RegisterExportSection(ExportSection{Name: "recommendations", Collect: collectRecommendations})
+	RegisterExportSection(ExportSection{Name: "progress", Collect: collectProgress})
+	RegisterExportSection(ExportSection{Name: "xapi", Collect: collectXAPI})
+	RegisterExportSection(ExportSection{Name: "lti", Collect: collectLTI})
+}
+
+func collectProfile(ctx context.Context, db *gorm.DB, userID int64) (interface{}, error) {
+	user, err := repository.NewUserRepository(db).GetUserByID(ctx, userID)
+	if err != nil || user == nil {
+		return nil, err
+	}
+	// 不导出密码哈希
+	return map[string]interface{}{
+		"id":          user.ID,
+		"username":    user.Username,
+		"email":       user.Email,
+		"firstName":   user.FirstName,
+		"lastName":    user.LastName,
+		"role":        user.Role,
+		"status":      user.Status,
+		"createdAt":   user.CreatedAt,
+		"updatedAt":   user.UpdatedAt,
+		"lastLoginAt": user.LastLoginAt,
+	}, nil
+}
+
+func collectRelations(ctx context.Context, db *gorm.DB, userID int64) (interface{}, error) {
+	return repository.NewUserRelationRepository(db).GetRelationsInvolvingUser(ctx, userID)
+}
+
+func collectLoginHistory(ctx context.Context, db *gorm.DB, userID int64) (interface{}, error) {
+	return repository.NewUserRepository(db).GetLoginRecords(ctx, userID)
+}
+
+func collectEnrollments(ctx context.Context, db *gorm.DB, userID int64) (interface{}, error) {
+	return repository.NewEnrollmentRepository(db).GetStudentEnrollments(ctx, userID)
+}
+
+func collectAttendance(ctx context.Context, db *gorm.DB, userID int64) (interface{}, error) {
+	return repository.NewAttendanceRepository(db).GetAttendanceRecords(ctx, &repository.AttendanceQuery{StudentID: userID})
+}
+
+// collectSubmissions 导出作业提交记录，文件本身只导出文件名和大小
+func collectSubmissions(ctx context.Context, db *gorm.DB, userID int64) (interface{}, error) {
+	return repository.NewAssignmentRepository(db).GetStudentSubmissions(ctx, userID)
+}
+
+func collectGrades(ctx context.Context, db *gorm.DB, userID int64) (interface{}, error) {
+	gradebookRepo := repository.NewGradebookRepository(db)
+	entries, err := gradebookRepo.GetStudentEntries(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	termGrades, err := gradebookRepo.GetStudentTermGrades(ctx, userID, 0)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{
+		"entries":     entries,
+		"term_grades": termGrades,
+	}, nil
+}
+
+func collectAssessments(ctx context.Context, db *gorm.DB, userID int64) (interface{}, error) {
+	return repository.NewAssessmentRepository(db).GetAllStudentAttempts(ctx, userID)
+}
+
+func collectMastery(ctx context.Context, db *gorm.DB, userID int64) (interface{}, error) {
+	return repository.NewMasteryRepository(db).GetStudentMasteries(ctx, []int64{userID}, nil)
+}
+
+func collectMistakes(ctx context.Context, db *gorm.DB, userID int64) (interface{}, error) {
+	mistakeRepo := repository.NewMistakeRepository(db)
+	entries, err := mistakeRepo.GetAllStudentEntries(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	practices, err := mistakeRepo.GetStudentPractices(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{
+		"entries":   entries,
+		"practices": practices,
+	}, nil
+}
+
+func collectReviews(ctx context.Context, db *gorm.DB, userID int64) (interface{}, error) {
+	reviewRepo := repository.NewReviewRepository(db)
+	items, err := reviewRepo.GetAllStudentItems(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	logs, err := reviewRepo.GetStudentLogs(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{
+		"items": items,
+		"logs":  logs,
+	}, nil
+}
+
+func collectResources(ctx context.Context, db *gorm.DB, userID int64) (interface{}, error) {
+	return repository.NewResourceRepository(db).GetOwnedResources(ctx, userID)
+}
+
+func collectRecommendations(ctx context.Context, db *gorm.DB, userID int64) (interface{}, error) {
+	recommendationRepo := repository.NewRecommendationRepository(db)
+	feedback, err := recommendationRepo.GetStudentFeedback(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	overrides, err := recommendationRepo.GetStudentOverrides(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{
+		"feedback":  feedback,
+		"overrides": overrides,
+	}, nil
+}
+
+func collectProgress(ctx context.Context, db *gorm.DB, userID int64) (interface{}, error) {
+	progressRepo := repository.NewProgressRepository(db)
+	activities, err := progressRepo.GetAllStudentActivities(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	records, err := progressRepo.GetAllStudentProgress(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{
+		"activities": activities,
+		"lessons":    records,
+	}, nil
+}
+
+func collectXAPI(ctx context.Context, db *gorm.DB, userID int64) (interface{}, error) {
+	xapiRepo := repository.NewXAPIRepository(db)
+	records, err := xapiRepo.GetUserStatements(ctx, userID, nil)
+	if err != nil {
+		return nil, err
+	}
+	statements := make([]json.RawMessage, 0, len(records))
+	for _, record := range records {
+		statements = append(statements, json.RawMessage(record.Body))
+	}
+
+	documents, err := xapiRepo.GetUserDocuments(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	states := make([]map[string]interface{}, 0, len(documents))
+	for _, document := range documents {
+		// JSON文档原样导出，其他内容按base64导出
+		var content interface{} = document.Content
+		if xapi.IsJSON(document.ContentType) && json.Valid(document.Content) {
+			content = json.RawMessage(document.Content)
+		}
+		states = append(states, map[string]interface{}{
+			"stateId":      document.DocumentID,
+			"registration": document.Registration,
+			"contentType":  document.ContentType,
+			"content":      content,
+			"updatedAt":    document.UpdatedAt,
+		})
+	}
+
+	credentials, err := xapiRepo.GetUserCredentials(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{
+		"statements":  statements,
+		"states":      states,
+		"credentials": credentials,
+	}, nil
+}
+
+func collectLTI(ctx context.Context, db *gorm.DB, userID int64) (interface{}, error) {
+	accounts, err := repository.NewLTIRepository(db).GetUserLTIAccounts(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]map[string]interface{}, 0, len(accounts))
+	for _, account := range accounts {
+		item := map[string]interface{}{
+			"subject":    account.Subject,
+			"created_at": account.CreatedAt,
+		}
+		if account.Platform != nil {
+			item["platform"] = account.Platform.Name
+			item["issuer"] = account.Platform.Issuer
+		}
+		result = append(result, item)
+	}
+	return map[string]interface{}{
+		"accounts": result,
+	}, nil
+}
+
+// exportClaimTimeout 导出任务的处理时限，超过时限仍在处理中的任务视为处理实例已崩溃，重新领取
+const exportClaimTimeout = 30 * time.Minute
+
+var exportNotify = make(chan struct{}, 1)
+
+// NotifyDataExport 通知导出任务有新的导出请求，无需等待下一次轮询
+func NotifyDataExport() {
+	select {
+	case exportNotify <- struct{}{}:
+	default:
+	}
+}
+
+// StartDataExportWorker 启动个人数据导出任务，处理等待中的导出请求并清理过期的导出文件
+func StartDataExportWorker(db *gorm.DB, dir string, retention time.Duration) {
+	go func() {
+		ticker := time.NewTicker(time.Minute)
+		defer ticker.Stop()
+
+		for {
+			processDataExports(db, dir, retention)
+			cleanupDataExports(db)
+
+			select {
+			case <-ticker.C:
+			case <-exportNotify:
+			}
+		}
+	}()
+}
+
+func processDataExports(db *gorm.DB, dir string, retention time.Duration) {
+	ctx := context.Background()
+	exportRepo := repository.NewDataExportRepository(db)
+
+	staleBefore := time.Now().Add(-exportClaimTimeout)
+	exports, err := exportRepo.GetPendingDataExports(ctx, staleBefore)
+	if err != nil {
+		log.Printf("获取待处理的数据导出失败: %v", err)
+		return
+	}
+
+	for _, export := range exports {
+		claimed, err := exportRepo.ClaimDataExport(ctx, export.ID, time.Now(), staleBefore)
+		if err != nil {
+			log.Printf("领取数据导出任务失败: %v", err)
+			continue
+		}
+		if !claimed {
+			continue
+		}
+
+		path, err := buildExportArchive(ctx, db, dir, export)
+		now := time.Now()
+		if err != nil {
+			log.Printf("生成数据导出失败 (id=%d): %v", export.ID, err)
+			export.Status = models.DataExportFailed
+			export.Error = "生成导出文件失败"
+		} else {
+			expiresAt := now.Add(retention)
+			export.Status = models.DataExportCompleted
+			export.FilePath = path
+			export.CompletedAt = &now
+			export.ExpiresAt = &expiresAt
+		}
+
+		if err := exportRepo.UpdateDataExport(ctx, export); err != nil {
+			log.Printf("更新数据导出状态失败: %v", err)
+		}
+	}
+}
+
+// buildExportArchive 将用户的全部数据写入压缩包，每个数据分组一个JSON文件
+func buildExportArchive(ctx context.Context, db *gorm.DB, dir string, export *models.DataExport) (string, error) {
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return "", err
+	}
+
+	path := filepath.Join(dir, fmt.Sprintf("user_%d_export_%d.zip", export.UserID, export.ID))
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o640)
+	if err != nil {
+		return "", err
+	}
+
+	err = writeExportArchive(ctx, db, file, export)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(path)
+		return "", err
+	}
+	return path, nil
+}
+
+func writeExportArchive(ctx context.Context, db *gorm.DB, file *os.File, export *models.DataExport) error {
+	archive := zip.NewWriter(file)
+
+	sectionNames := make([]string, 0, len(exportSections))
+	for _, section := range exportSections {
+		data, err := section.Collect(ctx, db, export.UserID)
+		if err != nil {
+			return fmt.Errorf("collect %s: %w", section.Name, err)
+		}
+		if err := writeJSONEntry(archive, section.Name+".json", data); err != nil {
+			return err
+		}
+		sectionNames = append(sectionNames, section.Name)
+	}
+
+	manifest := map[string]interface{}{
+		"user_id":     export.UserID,
+		"export_id":   export.ID,
+		"exported_at": time.Now(),
+		"sections":    sectionNames,
+	}
+	if err := writeJSONEntry(archive, "manifest.json", manifest); err != nil {
+		return err
+	}
+	return archive.Close()
+}
+
+func writeJSONEntry(archive *zip.Writer, name string, data interface{}) error {
+	w, err := archive.Create(name)
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(data)
+}
+
+// cleanupDataExports 删除过期的导出文件
+func cleanupDataExports(db *gorm.DB) {
+	ctx := context.Background()
+	exportRepo := repository.NewDataExportRepository(db)
+
+	exports, err := exportRepo.GetExpiredDataExports(ctx, time.Now())
+	if err != nil {
+		log.Printf("获取过期的数据导出失败: %v", err)
+		return
+	}
+
+	for _, export := range exports {
+		if err := os.Remove(export.FilePath); err != nil && !os.IsNotExist(err) {
+			log.Printf("删除导出文件失败: %v", err)
+			continue
+		}
+		export.FilePath = ""
+		if err := exportRepo.UpdateDataExport(ctx, export); err != nil {
+			log.Printf("更新数据导出状态失败: %v", err)
+		}
+	}
+}
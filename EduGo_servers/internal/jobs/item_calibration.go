@@ -0,0 +1,79 @@
+package jobs
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+
+	"EduGo_servers/internal/irt"
+	"EduGo_servers/internal/models"
+	"EduGo_servers/internal/repository"
+)
+
+var itemCalibrationNotify = make(chan struct{}, 1)
+
+// NotifyItemCalibration 立即重新标定题目参数，无需等待下一次定时标定
+func NotifyItemCalibration() {
+	select {
+	case itemCalibrationNotify <- struct{}{}:
+	default:
+	}
+}
+
+// StartItemCalibrationJob 启动定时任务，根据历史作答标定题目的项目参数
+// 作答数少于minResponses的题目不标定，自适应测评时按出题人设置的难度等级估计参数
+func StartItemCalibrationJob(db *gorm.DB, interval time.Duration, minResponses int) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			calibrateItems(db, minResponses)
+
+			select {
+			case <-ticker.C:
+			case <-itemCalibrationNotify:
+			}
+		}
+	}()
+}
+
+func calibrateItems(db *gorm.DB, minResponses int) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+
+	questionRepo := repository.NewQuestionRepository(db)
+	records, err := questionRepo.GetResponseRecords(ctx)
+	if err != nil {
+		log.Printf("获取题目作答记录失败: %v", err)
+		return
+	}
+
+	responses := make([]irt.Response, 0, len(records))
+	for _, record := range records {
+		responses = append(responses, irt.Response{Person: record.AttemptID, Item: record.QuestionID, Correct: record.Correct})
+	}
+	calibrations := irt.Calibrate(responses, minResponses)
+	if len(calibrations) == 0 {
+		return
+	}
+
+	now := time.Now()
+	params := make([]*models.ItemParameter, 0, len(calibrations))
+	for questionID, calibration := range calibrations {
+		params = append(params, &models.ItemParameter{
+			QuestionID:     questionID,
+			Discrimination: calibration.Discrimination,
+			Difficulty:     calibration.Difficulty,
+			Responses:      calibration.Responses,
+			CalibratedAt:   now,
+		})
+	}
+	if err := questionRepo.SaveItemParameters(ctx, params); err != nil {
+		log.Printf("保存题目参数失败: %v", err)
+		return
+	}
+	log.Printf("已标定 %d 道题目的项目参数", len(params))
+}
@@ -0,0 +1,210 @@
+package jobs
+
+import (
+	"context"
+	"log"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+
+	"EduGo_servers/internal/mastery"
+	"EduGo_servers/internal/models"
+	"EduGo_servers/internal/repository"
+)
+
+const (
+	masteryBatchSize   = 100 // 每批处理的作答数
+	masteryMaxFailures = 5   // 作答连续处理失败达到该次数后不再自动重试，重新计算掌握度时再处理
+)
+
+var masteryNotify = make(chan struct{}, 1)
+
+// NotifyMasteryUpdate 通知掌握度任务有新交卷或重新评分的作答，无需等待下一次轮询
+func NotifyMasteryUpdate() {
+	select {
+	case masteryNotify <- struct{}{}:
+	default:
+	}
+}
+
+// StartMasteryWorker 启动知识点掌握度任务，把已交卷作答的评分结果计入学生的知识点掌握度、错题本和课时学习进度
+// 教师重新评分后作答会被再次处理，启动时会补算之前交卷的作答
+func StartMasteryWorker(db *gorm.DB) {
+	go func() {
+		ticker := time.NewTicker(time.Minute)
+		defer ticker.Stop()
+
+		for {
+			processMasteryUpdates(db)
+
+			select {
+			case <-ticker.C:
+			case <-masteryNotify:
+			}
+		}
+	}()
+}
+
+func processMasteryUpdates(db *gorm.DB) {
+	ctx := context.Background()
+	masteryRepo := repository.NewMasteryRepository(db)
+	assessmentRepo := repository.NewAssessmentRepository(db)
+	mistakeRepo := repository.NewMistakeRepository(db)
+	progressRepo := repository.NewProgressRepository(db)
+	papers := map[int64]*models.Assessment{}
+
+	// 每次运行按ID顺序把每个作答最多处理一次，失败的作答留到下一次运行重试，不阻塞之后的作答
+	var afterID int64
+	for {
+		attempts, err := masteryRepo.GetPendingAttempts(ctx, afterID, masteryMaxFailures, masteryBatchSize)
+		if err != nil {
+			log.Printf("获取待计入掌握度的作答失败: %v", err)
+			return
+		}
+		for _, attempt := range attempts {
+			afterID = attempt.ID
+			paper, ok := papers[attempt.AssessmentID]
+			if !ok {
+				if paper, err = assessmentRepo.GetAssessmentByID(ctx, attempt.AssessmentID); err != nil {
+					recordMasteryFailure(ctx, masteryRepo, attempt, "获取试卷失败", err)
+					continue
+				}
+				papers[attempt.AssessmentID] = paper
+			}
+			// 错题和学习进度先于掌握度保存，掌握度保存后作答才标记为已处理
+			if err := updateAttemptMistakes(ctx, mistakeRepo, paper, attempt); err != nil {
+				recordMasteryFailure(ctx, masteryRepo, attempt, "更新错题本失败", err)
+				continue
+			}
+			if err := updateAttemptProgress(ctx, progressRepo, paper, attempt); err != nil {
+				recordMasteryFailure(ctx, masteryRepo, attempt, "更新学习进度失败", err)
+				continue
+			}
+			if err := updateAttemptMastery(ctx, masteryRepo, paper, attempt); err != nil {
+				recordMasteryFailure(ctx, masteryRepo, attempt, "更新知识点掌握度失败", err)
+				continue
+			}
+		}
+		if len(attempts) < masteryBatchSize {
+			return
+		}
+	}
+}
+
+// recordMasteryFailure 记录作答处理失败，连续失败达到上限后不再自动重试
+func recordMasteryFailure(ctx context.Context, masteryRepo repository.MasteryRepository, attempt *models.AssessmentAttempt, message string, cause error) {
+	log.Printf("%s (attempt=%d): %v", message, attempt.ID, cause)
+	failures, err := masteryRepo.RecordAttemptFailure(ctx, attempt.ID)
+	if err != nil {
+		log.Printf("记录作答处理失败次数失败 (attempt=%d): %v", attempt.ID, err)
+		return
+	}
+	if failures >= masteryMaxFailures {
+		log.Printf("作答连续%d次处理失败，不再自动重试 (attempt=%d)", failures, attempt.ID)
+	}
+}
+
+// attemptObservedAt 作答结果的时间，即交卷时间
+func attemptObservedAt(attempt *models.AssessmentAttempt) time.Time {
+	if attempt.SubmittedAt != nil {
+		return *attempt.SubmittedAt
+	}
+	return attempt.UpdatedAt
+}
+
+// updateAttemptMastery 把一次作答中已评分题目的对错计入题目关联的知识点，并重新估计这些知识点的掌握度
+// 试卷已删除时作答不再计入
+func updateAttemptMastery(ctx context.Context, masteryRepo repository.MasteryRepository, paper *models.Assessment, attempt *models.AssessmentAttempt) error {
+	observedAt := attemptObservedAt(attempt)
+
+	var observations []*models.KnowledgeObservation
+	if paper != nil {
+		questions := make(map[int64]*models.Question, len(paper.Questions))
+		for _, item := range paper.Questions {
+			if item.Question != nil {
+				questions[item.QuestionID] = item.Question
+			}
+		}
+		for _, answer := range attempt.Answers {
+			question, ok := questions[answer.QuestionID]
+			if !ok || answer.Correct == nil {
+				continue
+			}
+			for _, tag := range question.Tags {
+				if tag.KnowledgePointID == nil {
+					continue
+				}
+				observations = append(observations, &models.KnowledgeObservation{
+					StudentID:        attempt.StudentID,
+					KnowledgePointID: *tag.KnowledgePointID,
+					AttemptID:        attempt.ID,
+					QuestionID:       answer.QuestionID,
+					Correct:          *answer.Correct,
+					Guess:            mastery.Guess(question),
+					ObservedAt:       observedAt,
+				})
+			}
+		}
+	}
+
+	// 重新估计上次和这次涉及的全部知识点
+	previous, err := masteryRepo.GetAttemptKnowledgePoints(ctx, attempt.ID)
+	if err != nil {
+		return err
+	}
+	affected := map[int64]bool{}
+	for _, id := range previous {
+		affected[id] = true
+	}
+	for _, observation := range observations {
+		affected[observation.KnowledgePointID] = true
+	}
+	pointIDs := make([]int64, 0, len(affected))
+	for id := range affected {
+		pointIDs = append(pointIDs, id)
+	}
+
+	history, err := masteryRepo.GetObservations(ctx, attempt.StudentID, pointIDs, attempt.ID)
+	if err != nil {
+		return err
+	}
+	history = append(history, observations...)
+	sort.SliceStable(history, func(i, j int) bool {
+		if !history[i].ObservedAt.Equal(history[j].ObservedAt) {
+			return history[i].ObservedAt.Before(history[j].ObservedAt)
+		}
+		return history[i].AttemptID < history[j].AttemptID
+	})
+
+	byPoint := map[int64][]*models.KnowledgeObservation{}
+	for _, observation := range history {
+		byPoint[observation.KnowledgePointID] = append(byPoint[observation.KnowledgePointID], observation)
+	}
+	var masteries []*models.StudentMastery
+	var cleared []int64
+	for _, id := range pointIDs {
+		items := byPoint[id]
+		if len(items) == 0 {
+			cleared = append(cleared, id)
+			continue
+		}
+		record := &models.StudentMastery{
+			StudentID:        attempt.StudentID,
+			KnowledgePointID: id,
+			Observations:     len(items),
+			LastObservedAt:   items[len(items)-1].ObservedAt,
+		}
+		sequence := make([]mastery.Observation, 0, len(items))
+		for _, item := range items {
+			if item.Correct {
+				record.Correct++
+			}
+			sequence = append(sequence, mastery.Observation{Correct: item.Correct, Guess: item.Guess})
+		}
+		record.Probability = mastery.DefaultParams.Estimate(sequence)
+		masteries = append(masteries, record)
+	}
+
+	return masteryRepo.SaveAttemptMastery(ctx, attempt, observations, masteries, cleared)
+}
@@ -0,0 +1,72 @@
+package jobs
+
+import (
+	"context"
+
+	"EduGo_servers/internal/models"
+	"EduGo_servers/internal/repository"
+)
+
+// updateAttemptMistakes 把作答中答错的题目记入学生的错题本，已在错题本中的题目答对时累计连续答对次数
+// 每道题只计入比错题本最近一次记录更晚的作答，因此同一次作答被重新处理时不会重复计入；
+// 教师重新评分把这次作答的错题改为正确时撤销这次答错，错题本中不再有答错记录的题目被删除
+func updateAttemptMistakes(ctx context.Context, mistakeRepo repository.MistakeRepository, paper *models.Assessment, attempt *models.AssessmentAttempt) error {
+	if paper == nil {
+		return nil
+	}
+	inPaper := make(map[int64]bool, len(paper.Questions))
+	for _, item := range paper.Questions {
+		inPaper[item.QuestionID] = true
+	}
+	var answers []models.AttemptAnswer
+	questionIDs := make([]int64, 0, len(attempt.Answers))
+	for _, answer := range attempt.Answers {
+		if inPaper[answer.QuestionID] && answer.Correct != nil {
+			answers = append(answers, answer)
+			questionIDs = append(questionIDs, answer.QuestionID)
+		}
+	}
+
+	existing, err := mistakeRepo.GetStudentEntries(ctx, attempt.StudentID, questionIDs)
+	if err != nil {
+		return err
+	}
+	entries := make(map[int64]*models.MistakeEntry, len(existing))
+	for _, entry := range existing {
+		entries[entry.QuestionID] = entry
+	}
+
+	answeredAt := attemptObservedAt(attempt)
+	var changed []*models.MistakeEntry
+	var removed []int64
+	for _, answer := range answers {
+		entry := entries[answer.QuestionID]
+		switch {
+		case !*answer.Correct && entry == nil:
+			entry = &models.MistakeEntry{StudentID: attempt.StudentID, QuestionID: answer.QuestionID}
+			entry.AssessmentID, entry.AttemptID = attempt.AssessmentID, attempt.ID
+			entry.RecordWrong(answer.Response, answeredAt)
+		case !*answer.Correct && answeredAt.After(entry.LastAnsweredAt):
+			entry.AssessmentID, entry.AttemptID = attempt.AssessmentID, attempt.ID
+			entry.RecordWrong(answer.Response, answeredAt)
+		case !*answer.Correct || entry == nil:
+			continue
+		case entry.AttemptID == attempt.ID && entry.LastWrongAt.Equal(answeredAt):
+			// 重新评分后这次作答不再是错题
+			entry.WrongCount--
+			if entry.WrongCount <= 0 {
+				removed = append(removed, entry.ID)
+				continue
+			}
+		case answeredAt.After(entry.LastAnsweredAt):
+			entry.RecordCorrect(answeredAt)
+		default:
+			continue
+		}
+		changed = append(changed, entry)
+	}
+	if len(changed) == 0 && len(removed) == 0 {
+		return nil
+	}
+	return mistakeRepo.SaveEntries(ctx, changed, removed)
+}
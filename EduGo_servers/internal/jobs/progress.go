@@ -0,0 +1,66 @@
+package jobs
+
+import (
+	"context"
+
+	"EduGo_servers/internal/models"
+	"EduGo_servers/internal/repository"
+)
+
+// updateAttemptProgress 把交卷的测评计入包含该测评的课时的学习进度
+// 学习记录取学生各次交卷作答中的最高得分率和用时之和，因此同一次作答被重新处理时结果不变
+func updateAttemptProgress(ctx context.Context, progressRepo repository.ProgressRepository, paper *models.Assessment, attempt *models.AssessmentAttempt) error {
+	if paper == nil {
+		return nil
+	}
+	lessons, err := progressRepo.GetAssessmentLessons(ctx, paper.ID)
+	if err != nil || len(lessons) == 0 {
+		return err
+	}
+	summary, err := progressRepo.GetAttemptSummary(ctx, paper.ID, attempt.StudentID)
+	if err != nil || summary.LastSubmitted == nil {
+		return err
+	}
+
+	lessonIDs := make([]int64, 0, len(lessons))
+	for _, lesson := range lessons {
+		lessonIDs = append(lessonIDs, lesson.ID)
+	}
+	existing, err := progressRepo.GetActivities(ctx, attempt.StudentID, lessonIDs)
+	if err != nil {
+		return err
+	}
+	activities := make(map[int64]*models.LessonActivity, len(existing))
+	for _, activity := range existing {
+		if activity.ItemType == models.LessonItemAssessment && activity.ItemID == paper.ID {
+			activities[activity.LessonID] = activity
+		}
+	}
+
+	changed := make([]*models.LessonActivity, 0, len(lessons))
+	for _, lesson := range lessons {
+		activity, ok := activities[lesson.ID]
+		if !ok {
+			activity = &models.LessonActivity{
+				StudentID: attempt.StudentID,
+				LessonID:  lesson.ID,
+				ItemType:  models.LessonItemAssessment,
+				ItemID:    paper.ID,
+			}
+		}
+		activity.TimeSpent = summary.TimeSpent
+		activity.Score = summary.BestScore
+		activity.Completed = true
+		activity.CompletedAt = summary.LastSubmitted
+		changed = append(changed, activity)
+	}
+	if err := progressRepo.SaveActivities(ctx, changed); err != nil {
+		return err
+	}
+	for _, lesson := range lessons {
+		if err := progressRepo.RefreshProgress(ctx, lesson, []int64{attempt.StudentID}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,128 @@
+package jobs
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+
+	"EduGo_servers/internal/models"
+	"EduGo_servers/internal/reportcard"
+	"EduGo_servers/internal/repository"
+	"EduGo_servers/internal/storage"
+)
+
+// reportCardClaimTimeout 批量生成任务的处理时限，超过时限仍在处理中的任务视为处理实例已崩溃，重新领取
+const reportCardClaimTimeout = 30 * time.Minute
+
+var reportCardNotify = make(chan struct{}, 1)
+
+// NotifyReportCardBatch 通知成绩单任务有新的批量生成请求，无需等待下一次轮询
+func NotifyReportCardBatch() {
+	select {
+	case reportCardNotify <- struct{}{}:
+	default:
+	}
+}
+
+// StartReportCardWorker 启动成绩单批量生成任务，生成的压缩包保存在文件存储中
+func StartReportCardWorker(db *gorm.DB) {
+	go func() {
+		ticker := time.NewTicker(time.Minute)
+		defer ticker.Stop()
+
+		for {
+			processReportCardBatches(db)
+
+			select {
+			case <-ticker.C:
+			case <-reportCardNotify:
+			}
+		}
+	}()
+}
+
+func processReportCardBatches(db *gorm.DB) {
+	ctx := context.Background()
+	reportCardRepo := repository.NewReportCardRepository(db)
+
+	staleBefore := time.Now().Add(-reportCardClaimTimeout)
+	batches, err := reportCardRepo.GetPendingBatches(ctx, staleBefore)
+	if err != nil {
+		log.Printf("获取待处理的成绩单任务失败: %v", err)
+		return
+	}
+
+	for _, batch := range batches {
+		claimedAt := time.Now()
+		claimed, err := reportCardRepo.ClaimBatch(ctx, batch.ID, claimedAt, staleBefore)
+		if err != nil {
+			log.Printf("领取成绩单任务失败: %v", err)
+			continue
+		}
+		if !claimed {
+			continue
+		}
+		batch.Status = models.ReportCardBatchProcessing
+		batch.ClaimedAt = &claimedAt
+
+		err = generateReportCardBatch(ctx, db, batch)
+		now := time.Now()
+		if err != nil {
+			log.Printf("批量生成成绩单失败 (id=%d): %v", batch.ID, err)
+			batch.Status = models.ReportCardBatchFailed
+			batch.Error = "生成成绩单失败"
+		} else {
+			batch.Status = models.ReportCardBatchCompleted
+			batch.CompletedAt = &now
+		}
+
+		if err := reportCardRepo.UpdateBatch(ctx, batch); err != nil {
+			log.Printf("更新成绩单任务状态失败: %v", err)
+		}
+	}
+}
+
+// generateReportCardBatch 为班级的每名学生生成成绩单，打包后保存到文件存储
+func generateReportCardBatch(ctx context.Context, db *gorm.DB, batch *models.ReportCardBatch) error {
+	options, err := reportcard.ResolveOptions(ctx, db, batch.TemplateID)
+	if err != nil {
+		return fmt.Errorf("resolve template: %w", err)
+	}
+	students, err := repository.NewClassRepository(db).GetClassStudents(ctx, batch.ClassID)
+	if err != nil {
+		return err
+	}
+	batch.Total = len(students)
+
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+	for _, student := range students {
+		data, err := reportcard.Build(ctx, db, student.ID, batch.TermID)
+		if err != nil {
+			return fmt.Errorf("build report card for student %d: %w", student.ID, err)
+		}
+		w, err := archive.Create(reportcard.FileName(data, batch.Format))
+		if err != nil {
+			return err
+		}
+		if err := reportcard.Render(w, batch.Format, options, data); err != nil {
+			return fmt.Errorf("render report card for student %d: %w", student.ID, err)
+		}
+		batch.Generated++
+	}
+	if err := archive.Close(); err != nil {
+		return err
+	}
+
+	key := fmt.Sprintf("report-cards/batch_%d.zip", batch.ID)
+	if _, err := storage.Store.Save(ctx, key, &buf); err != nil {
+		return err
+	}
+	batch.StorageKey = key
+	return nil
+}
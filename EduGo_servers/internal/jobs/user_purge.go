@@ -0,0 +1,55 @@
+package jobs
+
+import (
+	"context"
+	"log"
+	"os"
+	"time"
+
+	"gorm.io/gorm"
+
+	"EduGo_servers/internal/repository"
+	"EduGo_servers/internal/storage"
+)
+
+// StartUserPurgeJob 启动定时任务，彻底删除软删除时间超过保留期的用户
+func StartUserPurgeJob(db *gorm.DB, interval, retention time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			purgeDeletedUsers(db, retention)
+			<-ticker.C
+		}
+	}()
+}
+
+func purgeDeletedUsers(db *gorm.DB, retention time.Duration) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	userRepo := repository.NewUserRepository(db)
+	purged, err := userRepo.PurgeDeletedUsers(ctx, time.Now().Add(-retention))
+	if err != nil {
+		log.Printf("清理已删除用户失败: %v", err)
+		return
+	}
+	if purged.Users > 0 {
+		log.Printf("已彻底删除 %d 个超过保留期的用户", purged.Users)
+	}
+
+	// 数据库记录删除后再删除文件，删除失败只留下无引用的文件
+	if storage.Store != nil {
+		for _, key := range purged.FileKeys {
+			if err := storage.Store.Delete(ctx, key); err != nil {
+				log.Printf("删除提交文件 %s 失败: %v", key, err)
+			}
+		}
+	}
+	for _, path := range purged.ExportPaths {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			log.Printf("删除导出文件 %s 失败: %v", path, err)
+		}
+	}
+}
@@ -0,0 +1,177 @@
+package lti
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// JWK RSA公钥的JSON Web Key表示
+type JWK struct {
+	Kty string `json:"kty"`
+	Use string `json:"use,omitempty"`
+	Alg string `json:"alg,omitempty"`
+	Kid string `json:"kid"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+// JWKS 密钥集
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// PublicJWK 把RSA公钥转换为签名用的JWK
+func PublicJWK(kid string, key *rsa.PublicKey) JWK {
+	return JWK{
+		Kty: "RSA",
+		Use: "sig",
+		Alg: "RS256",
+		Kid: kid,
+		N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+		E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+	}
+}
+
+// RSAPublicKey 解析JWK中的RSA公钥
+func (k *JWK) RSAPublicKey() (*rsa.PublicKey, error) {
+	if k.Kty != "RSA" {
+		return nil, errors.New("不支持的密钥类型: " + k.Kty)
+	}
+	n, err := base64.RawURLEncoding.DecodeString(k.N)
+	if err != nil {
+		return nil, errors.New("无效的密钥模数")
+	}
+	e, err := base64.RawURLEncoding.DecodeString(k.E)
+	if err != nil || len(e) == 0 || len(e) > 4 {
+		return nil, errors.New("无效的密钥指数")
+	}
+	return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+}
+
+// GenerateKey 生成2048位的RSA签名密钥，返回密钥ID和PKCS#8格式的PEM
+func GenerateKey() (string, string, error) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return "", "", err
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return "", "", err
+	}
+	sum := sha256.Sum256(x509.MarshalPKCS1PublicKey(&key.PublicKey))
+	kid := hex.EncodeToString(sum[:16])
+	return kid, string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})), nil
+}
+
+// ParsePrivateKey 解析PKCS#8格式的PEM私钥
+func ParsePrivateKey(value string) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode([]byte(value))
+	if block == nil {
+		return nil, errors.New("无效的PEM私钥")
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("私钥不是RSA密钥")
+	}
+	return rsaKey, nil
+}
+
+const keySetTTL = 10 * time.Minute
+
+type cachedKeySet struct {
+	keys      map[string]*rsa.PublicKey
+	fetchedAt time.Time
+}
+
+var (
+	keySetMu    sync.Mutex
+	keySetCache = map[string]*cachedKeySet{}
+	httpClient  = &http.Client{Timeout: 10 * time.Second}
+)
+
+// FetchKey 从对方的密钥集地址获取签名公钥，密钥集缓存10分钟，找不到密钥ID时重新获取一次以支持密钥轮换
+func FetchKey(ctx context.Context, keySetURL, kid string) (*rsa.PublicKey, error) {
+	keySetMu.Lock()
+	cached := keySetCache[keySetURL]
+	keySetMu.Unlock()
+	if cached != nil && time.Since(cached.fetchedAt) < keySetTTL {
+		if key := cached.find(kid); key != nil {
+			return key, nil
+		}
+	}
+
+	keys, err := fetchKeySet(ctx, keySetURL)
+	if err != nil {
+		return nil, err
+	}
+	cached = &cachedKeySet{keys: keys, fetchedAt: time.Now()}
+	keySetMu.Lock()
+	keySetCache[keySetURL] = cached
+	keySetMu.Unlock()
+
+	if key := cached.find(kid); key != nil {
+		return key, nil
+	}
+	return nil, fmt.Errorf("密钥集中没有密钥: %s", kid)
+}
+
+// find 按密钥ID查找公钥，令牌没有kid且密钥集只有一个密钥时使用该密钥
+func (s *cachedKeySet) find(kid string) *rsa.PublicKey {
+	if kid == "" && len(s.keys) == 1 {
+		for _, key := range s.keys {
+			return key
+		}
+	}
+	return s.keys[kid]
+}
+
+func fetchKeySet(ctx context.Context, keySetURL string) (map[string]*rsa.PublicKey, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, keySetURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("获取密钥集失败: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("获取密钥集失败: HTTP %d", resp.StatusCode)
+	}
+
+	var set JWKS
+	if err := json.NewDecoder(http.MaxBytesReader(nil, resp.Body, 1<<20)).Decode(&set); err != nil {
+		return nil, fmt.Errorf("解析密钥集失败: %w", err)
+	}
+	keys := make(map[string]*rsa.PublicKey, len(set.Keys))
+	for i := range set.Keys {
+		jwk := &set.Keys[i]
+		if jwk.Kty != "RSA" || (jwk.Use != "" && jwk.Use != "sig") {
+			continue
+		}
+		key, err := jwk.RSAPublicKey()
+		if err != nil {
+			continue
+		}
+		keys[jwk.Kid] = key
+	}
+	return keys, nil
+}
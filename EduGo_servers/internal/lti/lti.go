@@ -0,0 +1,122 @@
+// Package lti 实现LTI 1.3（学习工具互操作）的协议部分：消息声明、签名密钥和密钥集、自动提交表单。
+// EduGo既作为平台在课程中嵌入外部工具，也作为工具被其他学习管理系统启动。
+package lti
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+)
+
+// Version LTI消息的版本
+const Version = "1.3.0"
+
+// 消息类型
+const (
+	MessageResourceLink        = "LtiResourceLinkRequest"
+	MessageDeepLinkingRequest  = "LtiDeepLinkingRequest"
+	MessageDeepLinkingResponse = "LtiDeepLinkingResponse"
+)
+
+// id_token中的LTI声明
+const (
+	ClaimMessageType         = "https://purl.imsglobal.org/spec/lti/claim/message_type"
+	ClaimVersion             = "https://purl.imsglobal.org/spec/lti/claim/version"
+	ClaimDeploymentID        = "https://purl.imsglobal.org/spec/lti/claim/deployment_id"
+	ClaimTargetLinkURI       = "https://purl.imsglobal.org/spec/lti/claim/target_link_uri"
+	ClaimResourceLink        = "https://purl.imsglobal.org/spec/lti/claim/resource_link"
+	ClaimRoles               = "https://purl.imsglobal.org/spec/lti/claim/roles"
+	ClaimContext             = "https://purl.imsglobal.org/spec/lti/claim/context"
+	ClaimCustom              = "https://purl.imsglobal.org/spec/lti/claim/custom"
+	ClaimLaunchPresentation  = "https://purl.imsglobal.org/spec/lti/claim/launch_presentation"
+	ClaimToolPlatform        = "https://purl.imsglobal.org/spec/lti/claim/tool_platform"
+	ClaimDeepLinkingSettings = "https://purl.imsglobal.org/spec/lti-dl/claim/deep_linking_settings"
+	ClaimContentItems        = "https://purl.imsglobal.org/spec/lti-dl/claim/content_items"
+	ClaimDeepLinkingData     = "https://purl.imsglobal.org/spec/lti-dl/claim/data"
+	ClaimAGSEndpoint         = "https://purl.imsglobal.org/spec/lti-ags/claim/endpoint"
+)
+
+// 角色
+const (
+	RoleInstructor    = "http://purl.imsglobal.org/vocab/lis/v2/membership#Instructor"
+	RoleLearner       = "http://purl.imsglobal.org/vocab/lis/v2/membership#Learner"
+	RoleAdministrator = "http://purl.imsglobal.org/vocab/lis/v2/institution/person#Administrator"
+	ContextCourse     = "http://purl.imsglobal.org/vocab/lis/v2/course#CourseOffering"
+)
+
+// 成绩服务 (AGS) 的权限范围
+const (
+	ScopeLineItemReadOnly = "https://purl.imsglobal.org/spec/lti-ags/scope/lineitem.readonly"
+	ScopeResultReadOnly   = "https://purl.imsglobal.org/spec/lti-ags/scope/result.readonly"
+	ScopeScore            = "https://purl.imsglobal.org/spec/lti-ags/scope/score"
+)
+
+// 成绩服务的媒体类型
+const (
+	MediaTypeLineItem          = "application/vnd.ims.lis.v2.lineitem+json"
+	MediaTypeLineItemContainer = "application/vnd.ims.lis.v2.lineitemcontainer+json"
+	MediaTypeResultContainer   = "application/vnd.ims.lis.v2.resultcontainer+json"
+)
+
+// DefaultIssuer 默认的平台地址，部署时应通过LTI_ISSUER设置为对外的服务地址
+const DefaultIssuer = "http://localhost:8080"
+
+var (
+	// Issuer 平台的标识，也是LTI接口地址的前缀
+	Issuer = DefaultIssuer
+	// FrontendURL 作为工具被启动后跳转的前端地址
+	FrontendURL = DefaultIssuer
+)
+
+// Init 设置平台地址和前端地址，为空时分别使用默认值和平台地址
+func Init(issuer, frontendURL string) error {
+	if issuer == "" {
+		issuer = DefaultIssuer
+	}
+	if !IsHTTPURL(issuer) {
+		return errors.New("LTI_ISSUER必须是http或https地址: " + issuer)
+	}
+	Issuer = strings.TrimRight(issuer, "/")
+
+	if frontendURL == "" {
+		frontendURL = Issuer
+	}
+	if !IsHTTPURL(frontendURL) {
+		return errors.New("LTI_FRONTEND_URL必须是http或https地址: " + frontendURL)
+	}
+	FrontendURL = strings.TrimRight(frontendURL, "/")
+	return nil
+}
+
+// URL LTI接口的完整地址
+func URL(path string) string {
+	return Issuer + "/api/v1/lti" + path
+}
+
+// IsHTTPURL 检查是否为http或https的绝对地址
+func IsHTTPURL(value string) bool {
+	u, err := url.Parse(value)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// WithQuery 在地址后追加查询参数
+func WithQuery(rawURL string, params url.Values) string {
+	separator := "?"
+	if strings.Contains(rawURL, "?") {
+		separator = "&"
+	}
+	return rawURL + separator + params.Encode()
+}
+
+// IsInstructor 启动角色中是否有教师或管理员角色
+func IsInstructor(roles []string) bool {
+	for _, role := range roles {
+		// 简写的角色名等同于membership下的角色
+		name := role[strings.LastIndexAny(role, "#/")+1:]
+		switch name {
+		case "Instructor", "Administrator", "Faculty", "ContentDeveloper", "TeachingAssistant":
+			return true
+		}
+	}
+	return false
+}
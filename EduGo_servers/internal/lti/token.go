@@ -0,0 +1,162 @@
+package lti
+
+import (
+	"context"
+	"crypto/rsa"
+	"errors"
+	"html/template"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// clockSkew 校验令牌时间时允许的时钟误差
+const clockSkew = time.Minute
+
+// Sign 用平台（工具）的私钥以RS256签名消息
+func Sign(claims jwt.MapClaims, kid string, key *rsa.PrivateKey) (string, error) {
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = kid
+	return token.SignedString(key)
+}
+
+// Verify 校验对方用密钥集中的密钥签名的消息：只接受RS256，检查签发方、受众和有效期
+func Verify(ctx context.Context, value, keySetURL, issuer, audience string) (jwt.MapClaims, error) {
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(value, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		return FetchKey(ctx, keySetURL, kid)
+	},
+		jwt.WithValidMethods([]string{jwt.SigningMethodRS256.Alg()}),
+		jwt.WithIssuer(issuer),
+		jwt.WithAudience(audience),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(clockSkew),
+	)
+	if err != nil {
+		return nil, err
+	}
+	// 有多个受众时授权方必须是自己
+	if aud, ok := claims["aud"].([]interface{}); ok && len(aud) > 1 {
+		if azp, _ := claims["azp"].(string); azp != audience {
+			return nil, errors.New("无效的授权方")
+		}
+	}
+	return claims, nil
+}
+
+// VerifyLocal 校验本系统签名的令牌，keys为密钥ID到公钥的映射
+func VerifyLocal(value string, keys map[string]*rsa.PublicKey, audience string) (jwt.MapClaims, error) {
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(value, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		key, ok := keys[kid]
+		if !ok {
+			return nil, errors.New("未知的密钥")
+		}
+		return key, nil
+	},
+		jwt.WithValidMethods([]string{jwt.SigningMethodRS256.Alg()}),
+		jwt.WithIssuer(Issuer),
+		jwt.WithAudience(audience),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// UnverifiedIssuer 读取令牌的签发方，用于在校验签名前确定对方的密钥集
+func UnverifiedIssuer(value string) (string, error) {
+	claims := jwt.MapClaims{}
+	if _, _, err := jwt.NewParser().ParseUnverified(value, claims); err != nil {
+		return "", err
+	}
+	return claims.GetIssuer()
+}
+
+// String 读取字符串声明
+func String(claims jwt.MapClaims, name string) string {
+	value, _ := claims[name].(string)
+	return value
+}
+
+// AcceptedUntil 已校验的令牌最晚被接受的时间，即过期时间加上允许的时钟误差
+func AcceptedUntil(claims jwt.MapClaims) (time.Time, error) {
+	exp, err := claims.GetExpirationTime()
+	if err != nil || exp == nil {
+		return time.Time{}, errors.New("缺少过期时间")
+	}
+	return exp.Add(clockSkew), nil
+}
+
+// Object 读取对象声明
+func Object(claims jwt.MapClaims, name string) map[string]interface{} {
+	value, _ := claims[name].(map[string]interface{})
+	return value
+}
+
+// Strings 读取字符串数组声明
+func Strings(claims jwt.MapClaims, name string) []string {
+	items, _ := claims[name].([]interface{})
+	values := make([]string, 0, len(items))
+	for _, item := range items {
+		if value, ok := item.(string); ok {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// CheckMessage 检查消息的版本和类型
+func CheckMessage(claims jwt.MapClaims, messageType string) error {
+	if String(claims, ClaimVersion) != Version {
+		return errors.New("不支持的LTI版本")
+	}
+	if String(claims, ClaimMessageType) != messageType {
+		return errors.New("不支持的消息类型: " + String(claims, ClaimMessageType))
+	}
+	return nil
+}
+
+var formTemplate = template.Must(template.New("form").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>{{.Title}}</title></head>
+<body onload="document.forms[0].submit()">
+<form method="post" action="{{.Action}}">
+{{range $name, $value := .Fields}}<input type="hidden" name="{{$name}}" value="{{$value}}">
+{{end}}<noscript><button type="submit">继续</button></noscript>
+</form>
+</body>
+</html>
+`))
+
+// AutoSubmitForm 生成自动以POST提交到对方地址的页面，用于在浏览器中传递签名的消息
+func AutoSubmitForm(action string, fields map[string]string) ([]byte, error) {
+	var buf strings.Builder
+	err := formTemplate.Execute(&buf, struct {
+		Title  string
+		Action string
+		Fields map[string]string
+	}{"LTI", action, fields})
+	return []byte(buf.String()), err
+}
+
+var messageTemplate = template.Must(template.New("message").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>EduGo</title></head>
+<body><p>{{.}}</p></body>
+</html>
+`))
+
+// MessagePage 生成显示一条提示的页面，用于浏览器直接访问的LTI接口
+func MessagePage(message string) []byte {
+	var buf strings.Builder
+	if err := messageTemplate.Execute(&buf, message); err != nil {
+		return []byte(message)
+	}
+	return []byte(buf.String())
+}
@@ -0,0 +1,100 @@
+// Package mastery 用贝叶斯知识追踪（BKT）估计学生对知识点的掌握度
+//
+// 每个知识点把学生看作处于"已掌握"或"未掌握"两种状态之一：已掌握时仍可能失误答错，
+// 未掌握时也可能猜对；每作答一道题后有一定概率从未掌握变为已掌握。
+// 依次根据每次作答的对错更新已掌握的概率，最后的概率即为掌握度。
+package mastery
+
+import (
+	"EduGo_servers/internal/models"
+)
+
+// Params BKT模型参数，所有知识点共用
+type Params struct {
+	Init  float64 // 作答前已掌握的概率P(L0)
+	Learn float64 // 每作答一道题后从未掌握变为已掌握的概率P(T)
+	Slip  float64 // 已掌握但答错的概率P(S)
+}
+
+// DefaultParams 默认的模型参数
+var DefaultParams = Params{Init: 0.3, Learn: 0.1, Slip: 0.1}
+
+// 掌握程度的划分
+const (
+	MasteredThreshold = 0.95 // 掌握概率不低于该值视为已掌握
+	WeakThreshold     = 0.4  // 掌握概率低于该值视为薄弱
+)
+
+// 猜对概率的范围，猜对概率过高时答对不能说明已掌握
+const (
+	minGuess = 0.01
+	maxGuess = 0.5
+)
+
+// Observation 一次作答的结果
+type Observation struct {
+	Correct bool
+	Guess   float64 // 未掌握时猜对的概率
+}
+
+// Update 根据一次作答的结果更新已掌握的概率
+func (p Params) Update(known float64, observation Observation) float64 {
+	guess := clamp(observation.Guess, minGuess, maxGuess)
+	var posterior float64
+	if observation.Correct {
+		posterior = known * (1 - p.Slip) / (known*(1-p.Slip) + (1-known)*guess)
+	} else {
+		posterior = known * p.Slip / (known*p.Slip + (1-known)*(1-guess))
+	}
+	return posterior + (1-posterior)*p.Learn
+}
+
+// Estimate 从作答前的概率开始，按时间顺序依次处理作答结果，返回已掌握的概率
+func (p Params) Estimate(observations []Observation) float64 {
+	known := p.Init
+	for _, observation := range observations {
+		known = p.Update(known, observation)
+	}
+	return known
+}
+
+// Guess 未掌握时猜对题目的概率：选择题按随机选择估计，判断题为一半，填空题和简答题很难猜对
+func Guess(question *models.Question) float64 {
+	switch question.Type {
+	case models.QuestionSingleChoice:
+		if len(question.Options) > 0 {
+			return clamp(1/float64(len(question.Options)), minGuess, maxGuess)
+		}
+	case models.QuestionMultipleChoice:
+		// 随机选择一个非空的选项组合
+		if n := len(question.Options); n > 0 && n < 30 {
+			return clamp(1/float64(int(1)<<n-1), minGuess, maxGuess)
+		}
+	case models.QuestionTrueFalse:
+		return maxGuess
+	}
+	return minGuess
+}
+
+// Level 掌握程度，observations为作答的题数
+func Level(probability float64, observations int) string {
+	switch {
+	case observations == 0:
+		return models.MasteryNotStarted
+	case probability >= MasteredThreshold:
+		return models.MasteryMastered
+	case probability < WeakThreshold:
+		return models.MasteryWeak
+	}
+	return models.MasteryDeveloping
+}
+
+func clamp(value, low, high float64) float64 {
+	if value < low {
+		return low
+	}
+	if value > high {
+		return high
+	}
+	return value
+}
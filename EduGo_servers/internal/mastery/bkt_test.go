@@ -0,0 +1,174 @@
+package mastery
+
+import (
+	"math"
+	"testing"
+
+	"EduGo_servers/internal/models"
+)
+
+const tolerance = 1e-9
+
+func TestUpdate(t *testing.T) {
+	tests := []struct {
+		name        string
+		params      Params
+		known       float64
+		observation Observation
+		want        float64
+	}{
+		{
+			"答对后提高",
+			DefaultParams, 0.3, Observation{Correct: true, Guess: 0.25},
+			0.27/0.445 + (1-0.27/0.445)*0.1,
+		},
+		{
+			"答错后降低",
+			DefaultParams, 0.3, Observation{Correct: false, Guess: 0.25},
+			0.03/0.555 + (1-0.03/0.555)*0.1,
+		},
+		{
+			"容易猜对的题答对提高得少",
+			DefaultParams, 0.3, Observation{Correct: true, Guess: 0.5},
+			0.27/0.62 + (1-0.27/0.62)*0.1,
+		},
+		{
+			"猜对概率高于上限按上限计算",
+			DefaultParams, 0.3, Observation{Correct: true, Guess: 0.9},
+			0.27/0.62 + (1-0.27/0.62)*0.1,
+		},
+		{
+			"猜对概率低于下限按下限计算",
+			DefaultParams, 0.3, Observation{Correct: true, Guess: 0},
+			0.27/0.277 + (1-0.27/0.277)*0.1,
+		},
+		{
+			"不会失误时答错说明未掌握，只剩学习的概率",
+			Params{Init: 0.3, Learn: 0.2, Slip: 0}, 0.8, Observation{Correct: false, Guess: 0.25},
+			0.2,
+		},
+		{
+			"已确定掌握时答错也保持掌握",
+			DefaultParams, 1, Observation{Correct: false, Guess: 0.25},
+			1,
+		},
+		{
+			"不学习时答对只按贝叶斯公式更新",
+			Params{Slip: 0.1}, 0.5, Observation{Correct: true, Guess: 0.2},
+			0.45 / 0.55,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.params.Update(tt.known, tt.observation)
+			if math.Abs(got-tt.want) > tolerance {
+				t.Errorf("Update(%v, %+v) = %v, want %v", tt.known, tt.observation, got, tt.want)
+			}
+			if got < 0 || got > 1 {
+				t.Errorf("Update() = %v, want within [0, 1]", got)
+			}
+		})
+	}
+}
+
+// repeat 连续count次相同结果的作答
+func repeat(correct bool, guess float64, count int) []Observation {
+	observations := make([]Observation, count)
+	for i := range observations {
+		observations[i] = Observation{Correct: correct, Guess: guess}
+	}
+	return observations
+}
+
+func TestEstimateConverges(t *testing.T) {
+	tests := []struct {
+		name         string
+		observations []Observation
+		increasing   bool
+		check        func(known float64) bool
+	}{
+		{"连续答对达到掌握", repeat(true, 0.25, 10), true, func(known float64) bool { return known >= MasteredThreshold }},
+		{"连续答对判断题也能达到掌握", repeat(true, 0.5, 20), true, func(known float64) bool { return known >= MasteredThreshold }},
+		{"连续答错停留在薄弱", repeat(false, 0.25, 30), false, func(known float64) bool { return known < WeakThreshold && known >= DefaultParams.Learn }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			known := DefaultParams.Init
+			for i, observation := range tt.observations {
+				next := DefaultParams.Update(known, observation)
+				if tt.increasing && next < known || !tt.increasing && next > known+tolerance {
+					t.Fatalf("step %d: %v -> %v, want monotonic", i, known, next)
+				}
+				known = next
+			}
+			if got := DefaultParams.Estimate(tt.observations); math.Abs(got-known) > tolerance {
+				t.Errorf("Estimate() = %v, want %v", got, known)
+			}
+			if !tt.check(known) {
+				t.Errorf("Estimate() = %v", known)
+			}
+		})
+	}
+
+	// 连续答错收敛到不动点，继续答错不再变化
+	a := DefaultParams.Estimate(repeat(false, 0.25, 100))
+	b := DefaultParams.Estimate(repeat(false, 0.25, 200))
+	if math.Abs(a-b) > 1e-6 {
+		t.Errorf("Estimate() after 100 and 200 wrong answers = %v, %v, want converged", a, b)
+	}
+}
+
+func TestEstimateWithoutObservations(t *testing.T) {
+	if got := DefaultParams.Estimate(nil); got != DefaultParams.Init {
+		t.Errorf("Estimate(nil) = %v, want %v", got, DefaultParams.Init)
+	}
+}
+
+// options n个选项
+func options(n int) []models.QuestionOption {
+	return make([]models.QuestionOption, n)
+}
+
+func TestGuess(t *testing.T) {
+	tests := []struct {
+		name     string
+		question *models.Question
+		want     float64
+	}{
+		{"四个选项的单选题", &models.Question{Type: models.QuestionSingleChoice, Options: options(4)}, 0.25},
+		{"只有一个选项的单选题不超过上限", &models.Question{Type: models.QuestionSingleChoice, Options: options(1)}, maxGuess},
+		{"没有选项的单选题", &models.Question{Type: models.QuestionSingleChoice}, minGuess},
+		{"三个选项的多选题", &models.Question{Type: models.QuestionMultipleChoice, Options: options(3)}, 1.0 / 7},
+		{"选项很多的多选题不低于下限", &models.Question{Type: models.QuestionMultipleChoice, Options: options(10)}, minGuess},
+		{"选项过多的多选题", &models.Question{Type: models.QuestionMultipleChoice, Options: options(40)}, minGuess},
+		{"判断题", &models.Question{Type: models.QuestionTrueFalse}, maxGuess},
+		{"填空题", &models.Question{Type: models.QuestionFillBlank}, minGuess},
+		{"简答题", &models.Question{Type: models.QuestionShortAnswer}, minGuess},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Guess(tt.question); math.Abs(got-tt.want) > tolerance {
+				t.Errorf("Guess() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLevel(t *testing.T) {
+	tests := []struct {
+		probability  float64
+		observations int
+		want         string
+	}{
+		{0.99, 0, models.MasteryNotStarted},
+		{MasteredThreshold, 3, models.MasteryMastered},
+		{0.9, 3, models.MasteryDeveloping},
+		{WeakThreshold, 3, models.MasteryDeveloping},
+		{0.2, 3, models.MasteryWeak},
+	}
+	for _, tt := range tests {
+		if got := Level(tt.probability, tt.observations); got != tt.want {
+			t.Errorf("Level(%v, %d) = %q, want %q", tt.probability, tt.observations, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package mastery
+
+import (
+	"EduGo_servers/internal/models"
+)
+
+// Summary 一个知识点连同全部下级知识点的掌握情况
+type Summary struct {
+	Probability  float64 // 有作答记录的知识点掌握概率的平均值
+	Assessed     int     // 有作答记录的知识点数
+	Observations int     // 作答的题数合计
+}
+
+// Level 汇总后的掌握程度
+func (s Summary) Level() string {
+	return Level(s.Probability, s.Observations)
+}
+
+// Rollup 汇总每个知识点连同其全部下级知识点的掌握情况
+// points为同一学科的全部知识点，masteries为学生的掌握度，按知识点ID索引
+func Rollup(points []*models.KnowledgePoint, masteries map[int64]*models.StudentMastery) map[int64]Summary {
+	children := Children(points)
+	type total struct {
+		sum          float64
+		assessed     int
+		observations int
+	}
+	totals := make(map[int64]total, len(points))
+	var visit func(id int64) total
+	visit = func(id int64) total {
+		if t, ok := totals[id]; ok {
+			return t
+		}
+		var t total
+		totals[id] = t // 防止数据异常时出现环
+		if mastery, ok := masteries[id]; ok && mastery.Observations > 0 {
+			t.sum += mastery.Probability
+			t.assessed++
+			t.observations += mastery.Observations
+		}
+		for _, child := range children[id] {
+			c := visit(child.ID)
+			t.sum += c.sum
+			t.assessed += c.assessed
+			t.observations += c.observations
+		}
+		totals[id] = t
+		return t
+	}
+
+	summaries := make(map[int64]Summary, len(points))
+	for _, point := range points {
+		t := visit(point.ID)
+		summary := Summary{Assessed: t.assessed, Observations: t.observations}
+		if t.assessed > 0 {
+			summary.Probability = t.sum / float64(t.assessed)
+		}
+		summaries[point.ID] = summary
+	}
+	return summaries
+}
+
+// Children 按上级知识点分组，顶层知识点的键为0，同级知识点保持points中的顺序
+func Children(points []*models.KnowledgePoint) map[int64][]*models.KnowledgePoint {
+	children := make(map[int64][]*models.KnowledgePoint)
+	for _, point := range points {
+		var parentID int64
+		if point.ParentID != nil {
+			parentID = *point.ParentID
+		}
+		children[parentID] = append(children[parentID], point)
+	}
+	return children
+}
+
+// Descendants 知识点连同其全部下级知识点的ID
+func Descendants(points []*models.KnowledgePoint, id int64) []int64 {
+	children := Children(points)
+	ids := []int64{id}
+	seen := map[int64]bool{id: true}
+	for i := 0; i < len(ids); i++ {
+		for _, child := range children[ids[i]] {
+			if !seen[child.ID] {
+				seen[child.ID] = true
+				ids = append(ids, child.ID)
+			}
+		}
+	}
+	return ids
+}
+
+// IsDescendant 检查candidate是否为知识点id自身或其下级知识点，移动知识点时不能以它们作为上级，否则会出现环
+func IsDescendant(points []*models.KnowledgePoint, id, candidate int64) bool {
+	for _, descendant := range Descendants(points, id) {
+		if descendant == candidate {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,143 @@
+package mastery
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	"EduGo_servers/internal/models"
+)
+
+// point 上级为parentID的知识点，parentID为0表示顶层
+func point(id, parentID int64) *models.KnowledgePoint {
+	p := &models.KnowledgePoint{ID: id}
+	if parentID != 0 {
+		p.ParentID = &parentID
+	}
+	return p
+}
+
+// 1
+// ├── 2
+// │   ├── 4
+// │   └── 5
+// │       └── 7
+// └── 3
+// 6
+func samplePoints() []*models.KnowledgePoint {
+	return []*models.KnowledgePoint{
+		point(1, 0), point(2, 1), point(3, 1), point(4, 2), point(5, 2), point(6, 0), point(7, 5),
+	}
+}
+
+// cyclicPoints 数据异常时出现的环：8 -> 9 -> 10 -> 8，以及以自身为上级的11
+func cyclicPoints() []*models.KnowledgePoint {
+	return []*models.KnowledgePoint{point(8, 10), point(9, 8), point(10, 9), point(11, 11)}
+}
+
+func TestChildren(t *testing.T) {
+	children := Children(samplePoints())
+	want := map[int64][]int64{0: {1, 6}, 1: {2, 3}, 2: {4, 5}, 5: {7}}
+	if len(children) != len(want) {
+		t.Fatalf("Children() has %d parents, want %d", len(children), len(want))
+	}
+	for parentID, ids := range want {
+		var got []int64
+		for _, child := range children[parentID] {
+			got = append(got, child.ID)
+		}
+		if !reflect.DeepEqual(got, ids) {
+			t.Errorf("children of %d = %v, want %v", parentID, got, ids)
+		}
+	}
+}
+
+func TestDescendants(t *testing.T) {
+	tests := []struct {
+		name   string
+		points []*models.KnowledgePoint
+		id     int64
+		want   []int64
+	}{
+		{"顶层知识点", samplePoints(), 1, []int64{1, 2, 3, 4, 5, 7}},
+		{"中间知识点", samplePoints(), 2, []int64{2, 4, 5, 7}},
+		{"没有下级", samplePoints(), 6, []int64{6}},
+		{"不存在的知识点", samplePoints(), 99, []int64{99}},
+		{"环中的知识点", cyclicPoints(), 8, []int64{8, 9, 10}},
+		{"以自身为上级", cyclicPoints(), 11, []int64{11}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Descendants(tt.points, tt.id); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Descendants(%d) = %v, want %v", tt.id, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsDescendant(t *testing.T) {
+	tests := []struct {
+		name      string
+		id        int64
+		candidate int64
+		want      bool
+	}{
+		{"以自身为上级", 2, 2, true},
+		{"移动到直接下级下", 2, 4, true},
+		{"移动到更深的下级下", 1, 7, true},
+		{"移动到同级知识点下", 2, 3, false},
+		{"移动到原上级下", 2, 1, false},
+		{"移动到下级的兄弟下", 4, 5, false},
+		{"移动到另一棵树下", 1, 6, false},
+	}
+	points := samplePoints()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsDescendant(points, tt.id, tt.candidate); got != tt.want {
+				t.Errorf("IsDescendant(%d, %d) = %v, want %v", tt.id, tt.candidate, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRollup(t *testing.T) {
+	masteries := map[int64]*models.StudentMastery{
+		2: {Probability: 0.5, Observations: 2},
+		4: {Probability: 0.9, Observations: 3},
+		7: {Probability: 0.4, Observations: 1},
+		3: {Probability: 0.8, Observations: 0}, // 没有作答记录的不计入
+	}
+	tests := []struct {
+		id   int64
+		want Summary
+	}{
+		{1, Summary{Probability: 0.6, Assessed: 3, Observations: 6}},
+		{2, Summary{Probability: 0.6, Assessed: 3, Observations: 6}},
+		{5, Summary{Probability: 0.4, Assessed: 1, Observations: 1}},
+		{3, Summary{}},
+		{6, Summary{}},
+	}
+	summaries := Rollup(samplePoints(), masteries)
+	for _, tt := range tests {
+		got := summaries[tt.id]
+		if got.Assessed != tt.want.Assessed || got.Observations != tt.want.Observations || math.Abs(got.Probability-tt.want.Probability) > tolerance {
+			t.Errorf("Rollup()[%d] = %+v, want %+v", tt.id, got, tt.want)
+		}
+	}
+}
+
+func TestRollupWithCycle(t *testing.T) {
+	masteries := map[int64]*models.StudentMastery{
+		8:  {Probability: 0.6, Observations: 1},
+		11: {Probability: 0.3, Observations: 2},
+	}
+	summaries := Rollup(cyclicPoints(), masteries)
+	for _, id := range []int64{8, 9, 10, 11} {
+		if got := summaries[id]; got.Assessed > 1 {
+			t.Errorf("Rollup()[%d] = %+v, want each point counted at most once", id, got)
+		}
+	}
+	if got := summaries[11]; got.Assessed != 1 || got.Observations != 2 {
+		t.Errorf("Rollup()[11] = %+v, want its own mastery only", got)
+	}
+}
@@ -1,7 +1,10 @@
 package middleware
 
 import (
+	"EduGo_servers/internal/database"
 	"EduGo_servers/internal/models"
+	"EduGo_servers/internal/repository"
+	"log"
 	"net/http"
 	"strings"
 	"time"
@@ -79,6 +82,18 @@ func JWTMiddleware() gin.HandlerFunc {
 			return
 		}
 
+		// 已删除、未激活或被封禁的用户，令牌在过期前也不能继续使用
+		user, err := repository.NewUserRepository(database.DB).GetUserByID(c.Request.Context(), c.GetInt64("userID"))
+		if err != nil {
+			log.Printf("获取用户信息失败: %v", err)
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+			return
+		}
+		if user == nil || user.Status != models.UserStatusActive {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "用户不存在或已停用"})
+			return
+		}
+
 		// 设置用户名（如果有）
 		// 根据token.Claims的实际类型进行不同的处理
 		switch claims := token.Claims.(type) {
@@ -0,0 +1,56 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"EduGo_servers/internal/xapi"
+)
+
+// XAPICredentialFunc 校验学习记录存储的基本认证凭据，凭据无效时返回的用户ID为0
+type XAPICredentialFunc func(c *gin.Context, key, secret string) (userID int64, role string, err error)
+
+// XAPIVersion 检查请求头中的xAPI版本，并在响应中返回存储使用的版本
+func XAPIVersion() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Header("X-Experience-API-Version", xapi.Version)
+		if !xapi.IsSupportedVersion(c.GetHeader("X-Experience-API-Version")) {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "缺少或不支持的X-Experience-API-Version请求头"})
+			return
+		}
+		c.Next()
+	}
+}
+
+// XAPIAuth 学习记录存储的认证：基本认证使用xAPI凭据，也可以使用登录得到的Bearer令牌
+func XAPIAuth(authenticate XAPICredentialFunc) gin.HandlerFunc {
+	jwtAuth := JWTMiddleware()
+	return func(c *gin.Context) {
+		if !strings.HasPrefix(c.GetHeader("Authorization"), "Basic ") {
+			jwtAuth(c)
+			return
+		}
+
+		key, secret, ok := c.Request.BasicAuth()
+		if !ok {
+			c.Header("WWW-Authenticate", `Basic realm="xAPI"`)
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "无效的凭据"})
+			return
+		}
+		userID, role, err := authenticate(c, key, secret)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "服务器内部错误"})
+			return
+		}
+		if userID == 0 {
+			c.Header("WWW-Authenticate", `Basic realm="xAPI"`)
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "无效的凭据"})
+			return
+		}
+		c.Set("userID", userID)
+		c.Set("role", role)
+		c.Next()
+	}
+}
@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+// AcademicTerm 学期
+// 同一时间只有一个当前学期；未指定当前学期时以日期所在的学期为准
+type AcademicTerm struct {
+	ID        int64     `gorm:"primaryKey"`
+	Name      string    `gorm:"size:50;not null;uniqueIndex"` // 例如 2025-2026学年第一学期
+	StartDate time.Time `gorm:"type:date;not null;index"`
+	EndDate   time.Time `gorm:"type:date;not null"`
+	IsCurrent bool      `gorm:"index"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// Contains 检查日期是否在学期内（包含起止日期）
+func (t *AcademicTerm) Contains(date time.Time) bool {
+	day := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, t.StartDate.Location())
+	return !day.Before(t.StartDate) && !day.After(t.EndDate)
+}
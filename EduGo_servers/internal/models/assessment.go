@@ -0,0 +1,246 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 题型
+const (
+	QuestionSingleChoice   = "single_choice"   // 单选题
+	QuestionMultipleChoice = "multiple_choice" // 多选题
+	QuestionTrueFalse      = "true_false"      // 判断题
+	QuestionFillBlank      = "fill_blank"      // 填空题
+	QuestionShortAnswer    = "short_answer"    // 简答题，需要教师评分
+)
+
+// 测评作答状态
+const (
+	AttemptInProgress = "in_progress" // 作答中
+	AttemptSubmitted  = "submitted"   // 已提交，有简答题等待教师评分
+	AttemptGraded     = "graded"      // 已完成评分
+)
+
+// 测评模式
+const (
+	AssessmentFixed    = "fixed"    // 固定试卷，所有学生作答相同的题目
+	AssessmentAdaptive = "adaptive" // 自适应测评，根据学生的能力估计逐题选题
+)
+
+// IsValidQuestionType 检查题型是否有效
+func IsValidQuestionType(questionType string) bool {
+	switch questionType {
+	case QuestionSingleChoice, QuestionMultipleChoice, QuestionTrueFalse, QuestionFillBlank, QuestionShortAnswer:
+		return true
+	}
+	return false
+}
+
+// Question 题库中的题目
+type Question struct {
+	ID          int64            `gorm:"primaryKey"`
+	Type        string           `gorm:"size:20;not null;index"`
+	Subject     string           `gorm:"size:50;index"`
+	Stem        string           `gorm:"type:text;not null"` // 题干
+	Explanation string           `gorm:"type:text"`          // 答案解析
+	Difficulty  int              `gorm:"not null;default:3"` // 难度，1到5
+	Options     []QuestionOption `gorm:"foreignKey:QuestionID"`
+	Answers     []QuestionAnswer `gorm:"foreignKey:QuestionID"`
+	Tags        []QuestionTag    `gorm:"foreignKey:QuestionID"`
+	Parameter   *ItemParameter   `gorm:"foreignKey:QuestionID"` // 项目反应理论参数，未标定时为空
+	CreatedBy   int64            `gorm:"index"`
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	DeletedAt   gorm.DeletedAt `gorm:"index"`
+}
+
+// IsObjective 是否为可以自动评分的客观题
+func (q *Question) IsObjective() bool {
+	return q.Type != QuestionShortAnswer
+}
+
+// BlankCount 填空题的空数
+func (q *Question) BlankCount() int {
+	count := 0
+	for _, answer := range q.Answers {
+		if answer.Blank+1 > count {
+			count = answer.Blank + 1
+		}
+	}
+	return count
+}
+
+// QuestionOption 选择题的选项
+type QuestionOption struct {
+	ID         int64  `gorm:"primaryKey"`
+	QuestionID int64  `gorm:"not null;index"`
+	Label      string `gorm:"size:10;not null"` // A、B、C...
+	Content    string `gorm:"type:text;not null"`
+	IsCorrect  bool   `gorm:"not null"`
+	Position   int    `gorm:"not null"`
+}
+
+// QuestionAnswer 非选择题的参考答案
+// 判断题为一条true或false；填空题每个空可以有多个可接受的答案，Blank为空的序号（从0开始）；简答题为参考答案
+type QuestionAnswer struct {
+	ID         int64  `gorm:"primaryKey"`
+	QuestionID int64  `gorm:"not null;index"`
+	Blank      int    `gorm:"not null"`
+	Content    string `gorm:"type:text;not null"`
+}
+
+// QuestionTag 题目关联的知识点
+type QuestionTag struct {
+	ID               int64  `gorm:"primaryKey"`
+	QuestionID       int64  `gorm:"not null;uniqueIndex:idx_question_tag"`
+	KnowledgePoint   string `gorm:"size:100;not null;uniqueIndex:idx_question_tag;index"`
+	KnowledgePointID *int64 `gorm:"index"` // 知识点树中对应的节点，自由填写且没有对应节点时为空
+}
+
+// ItemParameter 题目的项目反应理论（2PL）参数，由历史作答标定
+type ItemParameter struct {
+	ID             int64   `gorm:"primaryKey"`
+	QuestionID     int64   `gorm:"not null;uniqueIndex"`
+	Discrimination float64 `gorm:"not null"` // 区分度a
+	Difficulty     float64 `gorm:"not null"` // 难度b，与学生能力在同一标尺上
+	Responses      int     // 参与标定的作答数
+	CalibratedAt   time.Time
+}
+
+// Assessment 测评试卷，发布在某个开课中
+type Assessment struct {
+	ID               int64                `gorm:"primaryKey"`
+	OfferingID       int64                `gorm:"not null;index"`
+	Offering         *CourseOffering      `gorm:"foreignKey:OfferingID"`
+	Title            string               `gorm:"size:200;not null"`
+	Description      string               `gorm:"type:text"`
+	Published        bool                 `gorm:"index"`
+	TimeLimitMinutes int                  // 作答时长，0表示不限时
+	OpensAt          *time.Time           // 开放作答时间，为空表示发布后即可作答
+	ClosesAt         *time.Time           // 截止时间，为空表示不限
+	MaxAttempts      int                  `gorm:"not null"` // 最多作答次数，0表示不限
+	ShowAnswers      bool                 // 提交后是否向学生显示正确答案和解析
+	Mode             string               `gorm:"size:20;not null;default:fixed"`
+	MinItems         int                  // 自适应测评至少作答的题数
+	MaxItems         int                  // 自适应测评最多作答的题数，0表示题目用完为止
+	TargetSE         float64              // 自适应测评能力估计的标准误不大于该值时停止，0表示不按精度停止
+	Questions        []AssessmentQuestion `gorm:"foreignKey:AssessmentID"` // 自适应测评时作为选题的题库
+	CreatedBy        int64
+	CreatedAt        time.Time
+	UpdatedAt        time.Time
+	DeletedAt        gorm.DeletedAt `gorm:"index"`
+}
+
+// IsAdaptive 是否为自适应测评
+func (a *Assessment) IsAdaptive() bool {
+	return a.Mode == AssessmentAdaptive
+}
+
+// TotalPoints 试卷总分
+func (a *Assessment) TotalPoints() float64 {
+	total := 0.0
+	for _, question := range a.Questions {
+		total += question.Points
+	}
+	return total
+}
+
+// IsOpen 检查在now是否可以开始作答
+func (a *Assessment) IsOpen(now time.Time) bool {
+	if !a.Published {
+		return false
+	}
+	if a.OpensAt != nil && now.Before(*a.OpensAt) {
+		return false
+	}
+	return a.ClosesAt == nil || now.Before(*a.ClosesAt)
+}
+
+// AttemptDeadline 在startedAt开始作答时的交卷期限，取作答时长和截止时间中较早的一个，不限时返回nil
+func (a *Assessment) AttemptDeadline(startedAt time.Time) *time.Time {
+	var deadline *time.Time
+	if a.TimeLimitMinutes > 0 {
+		t := startedAt.Add(time.Duration(a.TimeLimitMinutes) * time.Minute)
+		deadline = &t
+	}
+	if a.ClosesAt != nil && (deadline == nil || a.ClosesAt.Before(*deadline)) {
+		t := *a.ClosesAt
+		deadline = &t
+	}
+	return deadline
+}
+
+// AssessmentQuestion 试卷中的题目及分值
+type AssessmentQuestion struct {
+	ID           int64     `gorm:"primaryKey"`
+	AssessmentID int64     `gorm:"not null;uniqueIndex:idx_assessment_question"`
+	QuestionID   int64     `gorm:"not null;uniqueIndex:idx_assessment_question;index"`
+	Question     *Question `gorm:"foreignKey:QuestionID"`
+	Position     int       `gorm:"not null"`
+	Points       float64   `gorm:"not null"`
+}
+
+// AssessmentAttempt 学生的一次测评作答
+type AssessmentAttempt struct {
+	ID           int64       `gorm:"primaryKey"`
+	AssessmentID int64       `gorm:"not null;uniqueIndex:idx_attempt_number;index"`
+	Assessment   *Assessment `gorm:"foreignKey:AssessmentID"`
+	StudentID    int64       `gorm:"not null;uniqueIndex:idx_attempt_number;index"`
+	Student      *User       `gorm:"foreignKey:StudentID"`
+	Number       int         `gorm:"not null;uniqueIndex:idx_attempt_number"` // 第几次作答
+	Status       string      `gorm:"size:20;not null;index"`
+	StartedAt    time.Time   `gorm:"not null"`
+	DeadlineAt   *time.Time  // 交卷期限，为空表示不限时
+	SubmittedAt  *time.Time
+	Score        float64
+	MaxScore     float64
+	Answers      []AttemptAnswer `gorm:"foreignKey:AttemptID"`
+
+	// 自适应测评
+	Ability           *float64 // 能力估计值
+	AbilitySE         *float64 // 能力估计的标准误
+	CurrentQuestionID *int64   // 当前待作答的题目
+
+	MasteryUpdatedAt *time.Time // 已按该时间的评分结果更新知识点掌握度
+	MasteryFailures  int        `gorm:"not null;default:0"` // 计入掌握度连续失败的次数，达到上限后不再自动重试
+	CreatedAt        time.Time
+	UpdatedAt        time.Time
+}
+
+// Expired 检查作答在now是否已超过交卷期限
+func (a *AssessmentAttempt) Expired(now time.Time) bool {
+	return a.DeadlineAt != nil && now.After(*a.DeadlineAt)
+}
+
+// AttemptAnswer 学生对一道题的作答
+type AttemptAnswer struct {
+	ID         int64    `gorm:"primaryKey"`
+	AttemptID  int64    `gorm:"not null;uniqueIndex:idx_attempt_answer"`
+	QuestionID int64    `gorm:"not null;uniqueIndex:idx_attempt_answer"`
+	Response   string   `gorm:"type:text"` // JSON编码的字符串数组，见Responses
+	Score      *float64 // 为空表示尚未评分
+	Correct    *bool
+	Feedback   string `gorm:"type:text"`
+	GradedBy   *int64 // 教师评分时记录评分人，自动评分为空
+	UpdatedAt  time.Time
+}
+
+// Responses 作答内容：选择题为选中的选项标签，判断题为true或false，填空题按空的顺序排列，简答题为一条文字
+func (a *AttemptAnswer) Responses() []string {
+	var responses []string
+	if a.Response != "" {
+		_ = json.Unmarshal([]byte(a.Response), &responses)
+	}
+	return responses
+}
+
+// SetResponses 设置作答内容
+func (a *AttemptAnswer) SetResponses(responses []string) {
+	if responses == nil {
+		responses = []string{}
+	}
+	data, _ := json.Marshal(responses)
+	a.Response = string(data)
+}
@@ -0,0 +1,117 @@
+package models
+
+import (
+	"math"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 迟交策略
+const (
+	LatePolicyReject  = "reject"  // 截止后不再接收
+	LatePolicyAccept  = "accept"  // 接收但标记为迟交
+	LatePolicyPenalty = "penalty" // 接收并按迟交天数扣分
+)
+
+// Assignment 作业，布置在某个开课中
+type Assignment struct {
+	ID          int64           `gorm:"primaryKey"`
+	OfferingID  int64           `gorm:"not null;index"`
+	Offering    *CourseOffering `gorm:"foreignKey:OfferingID"`
+	Title       string          `gorm:"size:200;not null"`
+	Description string          `gorm:"type:text"`
+	Published   bool            `gorm:"index"` // 发布后学生才能看到
+	DueAt       time.Time       `gorm:"not null;index"`
+	MaxScore    float64         `gorm:"not null;default:100"`
+
+	// 迟交设置
+	LatePolicy         string     `gorm:"size:20;not null;default:reject"`
+	LatePenaltyPercent float64    // 每迟交一天扣除满分的百分比，仅penalty策略使用
+	LateCutoffAt       *time.Time // 迟交截止时间，之后不再接收，为空表示不限
+
+	AllowResubmit   bool                   // 是否允许在截止前重新提交
+	Attachments     []AssignmentAttachment `gorm:"foreignKey:AssignmentID"`
+	KnowledgePoints []KnowledgePoint       `gorm:"many2many:assignment_knowledge_points;"` // 作业涉及的知识点
+	CreatedBy       int64
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+	DeletedAt       gorm.DeletedAt `gorm:"index"`
+}
+
+// IsValidLatePolicy 检查迟交策略是否有效
+func IsValidLatePolicy(policy string) bool {
+	switch policy {
+	case LatePolicyReject, LatePolicyAccept, LatePolicyPenalty:
+		return true
+	}
+	return false
+}
+
+// LateDays 在now提交时迟交的天数，不足一天按一天计算，未迟交返回0
+func (a *Assignment) LateDays(now time.Time) int {
+	if !now.After(a.DueAt) {
+		return 0
+	}
+	return int(math.Ceil(now.Sub(a.DueAt).Hours() / 24))
+}
+
+// AcceptsSubmission 检查在now是否还能提交
+func (a *Assignment) AcceptsSubmission(now time.Time) bool {
+	if !now.After(a.DueAt) {
+		return true
+	}
+	if a.LatePolicy == LatePolicyReject {
+		return false
+	}
+	return a.LateCutoffAt == nil || now.Before(*a.LateCutoffAt)
+}
+
+// LatePenalty 迟交lateDays天应扣除的百分比，最多100
+func (a *Assignment) LatePenalty(lateDays int) float64 {
+	if a.LatePolicy != LatePolicyPenalty || lateDays <= 0 {
+		return 0
+	}
+	return math.Min(100, a.LatePenaltyPercent*float64(lateDays))
+}
+
+// AssignmentAttachment 教师上传的作业附件
+type AssignmentAttachment struct {
+	ID           int64  `gorm:"primaryKey"`
+	AssignmentID int64  `gorm:"not null;index"`
+	FileName     string `gorm:"size:255;not null"`
+	StorageKey   string `gorm:"size:500;not null"`
+	ContentType  string `gorm:"size:100"`
+	Size         int64
+	UploadedBy   int64
+	CreatedAt    time.Time
+}
+
+// Submission 学生的作业提交，每次重新提交都会新增一个版本，旧版本保留为提交历史
+type Submission struct {
+	ID           int64       `gorm:"primaryKey"`
+	AssignmentID int64       `gorm:"not null;uniqueIndex:idx_submission_version;index:idx_submission_latest,priority:1"`
+	Assignment   *Assignment `gorm:"foreignKey:AssignmentID"`
+	StudentID    int64       `gorm:"not null;uniqueIndex:idx_submission_version;index"`
+	Student      *User       `gorm:"foreignKey:StudentID"`
+	Version      int         `gorm:"not null;uniqueIndex:idx_submission_version"`
+	IsLatest     bool        `gorm:"not null;index:idx_submission_latest,priority:2"`
+	Content      string      `gorm:"type:text"` // 文字作答
+	IsLate       bool        `gorm:"not null"`
+	LateDays     int
+	Files        []SubmissionFile `gorm:"foreignKey:SubmissionID"`
+	SubmittedAt  time.Time        `gorm:"not null"`
+	CreatedAt    time.Time
+}
+
+// SubmissionFile 作业提交中上传的文件
+type SubmissionFile struct {
+	ID           int64       `gorm:"primaryKey"`
+	SubmissionID int64       `gorm:"not null;index"`
+	Submission   *Submission `gorm:"foreignKey:SubmissionID"`
+	FileName     string      `gorm:"size:255;not null"`
+	StorageKey   string      `gorm:"size:500;not null"`
+	ContentType  string      `gorm:"size:100"`
+	Size         int64
+	CreatedAt    time.Time
+}
@@ -0,0 +1,35 @@
+package models
+
+import "time"
+
+// 考勤状态
+const (
+	AttendancePresent = "present"
+	AttendanceLate    = "late"
+	AttendanceAbsent  = "absent"  // 无故缺勤
+	AttendanceExcused = "excused" // 请假
+)
+
+// AttendanceRecord 学生在某天某节课的考勤记录
+type AttendanceRecord struct {
+	ID        int64             `gorm:"primaryKey"`
+	SessionID int64             `gorm:"not null;uniqueIndex:idx_attendance_session_date_student"`
+	Session   *ScheduledSession `gorm:"foreignKey:SessionID"`
+	Date      time.Time         `gorm:"type:date;not null;uniqueIndex:idx_attendance_session_date_student;index"`
+	StudentID int64             `gorm:"not null;uniqueIndex:idx_attendance_session_date_student;index"`
+	Student   *User             `gorm:"foreignKey:StudentID"`
+	Status    string            `gorm:"size:20;not null;index"`
+	Note      string            `gorm:"size:255"`
+	MarkedBy  int64
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// IsValidAttendanceStatus 检查考勤状态是否有效
+func IsValidAttendanceStatus(status string) bool {
+	switch status {
+	case AttendancePresent, AttendanceLate, AttendanceAbsent, AttendanceExcused:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,49 @@
+package models
+
+import "time"
+
+// Class 班级
+// 学生固定编入一个班级，班级有一名班主任，各学科由任课教师负责
+type Class struct {
+	ID              int64                 `gorm:"primaryKey"`
+	Name            string                `gorm:"size:50;not null;uniqueIndex:idx_class_grade_name"` // 例如 三班
+	GradeLevel      string                `gorm:"size:50;not null;uniqueIndex:idx_class_grade_name"` // 年级，例如 七年级
+	EnrollmentYear  int                   // 入学年份
+	HeadTeacherID   *int64                `gorm:"index"` // 班主任
+	HeadTeacher     *User                 `gorm:"foreignKey:HeadTeacherID"`
+	Students        []User                `gorm:"many2many:class_students;"`
+	SubjectTeachers []ClassSubjectTeacher `gorm:"foreignKey:ClassID"`
+	CreatedBy       int64
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+}
+
+// ClassStudent 班级学生名单（Class.Students的连接表），每名学生只能属于一个班级
+type ClassStudent struct {
+	ClassID int64 `gorm:"primaryKey"`
+	UserID  int64 `gorm:"primaryKey;uniqueIndex:idx_class_students_user"`
+}
+
+// ClassSubjectTeacher 班级的学科任课教师，每个学科一名
+type ClassSubjectTeacher struct {
+	ID        int64  `gorm:"primaryKey"`
+	ClassID   int64  `gorm:"not null;uniqueIndex:idx_class_subject"`
+	Subject   string `gorm:"size:50;not null;uniqueIndex:idx_class_subject"`
+	TeacherID int64  `gorm:"not null;index"`
+	Teacher   *User  `gorm:"foreignKey:TeacherID"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// IsTeacher 检查教师是否为该班级的班主任或任课教师
+func (c *Class) IsTeacher(teacherID int64) bool {
+	if c.HeadTeacherID != nil && *c.HeadTeacherID == teacherID {
+		return true
+	}
+	for _, assignment := range c.SubjectTeachers {
+		if assignment.TeacherID == teacherID {
+			return true
+		}
+	}
+	return false
+}
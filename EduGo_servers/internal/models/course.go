@@ -0,0 +1,96 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Course 课程
+// 教师-学生关系、班级加入码等通过CourseID引用课程
+type Course struct {
+	ID          int64  `gorm:"primaryKey"`
+	Code        string `gorm:"size:50;not null;uniqueIndex"` // 课程代码，例如 MATH-7A
+	Name        string `gorm:"size:100;not null;index"`
+	Subject     string `gorm:"size:50;index"` // 学科
+	GradeLevel  string `gorm:"size:50;index"` // 年级
+	Description string `gorm:"type:text"`
+	Teachers    []User `gorm:"many2many:course_teachers;"` // 负责该课程的教师
+	CreatedBy   int64
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	DeletedAt   gorm.DeletedAt `gorm:"index"`
+}
+
+// HasTeacher 检查教师是否负责该课程
+func (c *Course) HasTeacher(teacherID int64) bool {
+	for _, teacher := range c.Teachers {
+		if teacher.ID == teacherID {
+			return true
+		}
+	}
+	return false
+}
+
+// CourseOffering 开课，即课程在某个学期的开设
+// 学期结转时开课会被复制到下一学期
+type CourseOffering struct {
+	ID       int64         `gorm:"primaryKey"`
+	CourseID int64         `gorm:"not null;uniqueIndex:idx_offering_course_term"`
+	Course   *Course       `gorm:"foreignKey:CourseID"`
+	TermID   int64         `gorm:"not null;uniqueIndex:idx_offering_course_term;index"`
+	Term     *AcademicTerm `gorm:"foreignKey:TermID"`
+	Teachers []User        `gorm:"many2many:course_offering_teachers;"` // 本学期的授课教师
+
+	// 选课设置
+	Published       bool       `gorm:"index"` // 发布后学生才能选课
+	Capacity        int        // 0表示不限人数
+	EnrolledCount   int        `gorm:"not null;default:0"`
+	WaitlistEnabled bool       // 满员后是否允许排队候补
+	EnrollStartAt   *time.Time // 选课开始时间，为空表示不限
+	EnrollEndAt     *time.Time // 选课截止时间，为空表示不限
+	DropDeadline    *time.Time // 退课截止时间，为空表示不限
+
+	GradingScaleID *int64 // 总评使用的评分制，为空时使用百分制
+
+	CreatedBy int64
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// HasTeacher 检查教师是否为该开课的授课教师
+func (o *CourseOffering) HasTeacher(teacherID int64) bool {
+	for _, teacher := range o.Teachers {
+		if teacher.ID == teacherID {
+			return true
+		}
+	}
+	return false
+}
+
+// EnrollmentOpen 检查当前是否在选课时间内
+func (o *CourseOffering) EnrollmentOpen(now time.Time) bool {
+	if !o.Published {
+		return false
+	}
+	if o.EnrollStartAt != nil && now.Before(*o.EnrollStartAt) {
+		return false
+	}
+	return o.EnrollEndAt == nil || now.Before(*o.EnrollEndAt)
+}
+
+// CanDrop 检查当前是否在退课截止时间之前
+func (o *CourseOffering) CanDrop(now time.Time) bool {
+	return o.DropDeadline == nil || now.Before(*o.DropDeadline)
+}
+
+// AvailableSeats 剩余名额，不限人数时返回-1
+func (o *CourseOffering) AvailableSeats() int {
+	if o.Capacity == 0 {
+		return -1
+	}
+	if o.EnrolledCount >= o.Capacity {
+		return 0
+	}
+	return o.Capacity - o.EnrolledCount
+}
@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+// 选课状态
+const (
+	EnrollmentEnrolled   = "enrolled"
+	EnrollmentWaitlisted = "waitlisted"
+	EnrollmentDropped    = "dropped"
+)
+
+// Enrollment 学生的选课记录，每名学生在每个开课中只有一条记录
+type Enrollment struct {
+	ID           int64           `gorm:"primaryKey"`
+	OfferingID   int64           `gorm:"not null;uniqueIndex:idx_enrollment_offering_student;index:idx_enrollment_waitlist,priority:1"`
+	Offering     *CourseOffering `gorm:"foreignKey:OfferingID"`
+	StudentID    int64           `gorm:"not null;uniqueIndex:idx_enrollment_offering_student;index"`
+	Student      *User           `gorm:"foreignKey:StudentID"`
+	Status       string          `gorm:"size:20;not null;index:idx_enrollment_waitlist,priority:2"`
+	WaitlistedAt *time.Time      `gorm:"index:idx_enrollment_waitlist,priority:3"` // 用于候补排序
+	EnrolledAt   *time.Time
+	DroppedAt    *time.Time
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+}
@@ -0,0 +1,126 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// 评分制类型
+const (
+	GradingScalePercentage = "percentage" // 百分制，直接显示百分比
+	GradingScaleLetter     = "letter"     // 等级制，A-F
+	GradingScaleFiveLevel  = "five_level" // 五级制，优秀/良好/中等/及格/不及格
+)
+
+// GradingScale 评分制，把百分比成绩换算成等级
+type GradingScale struct {
+	ID        int64              `gorm:"primaryKey"`
+	Name      string             `gorm:"size:50;not null;uniqueIndex"`
+	Type      string             `gorm:"size:20;not null"`
+	BuiltIn   bool               // 系统内置的评分制不能删除
+	Bands     []GradingScaleBand `gorm:"foreignKey:ScaleID"`
+	CreatedBy int64
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// GradingScaleBand 评分制的一个等级，成绩不低于MinPercent时得到该等级
+type GradingScaleBand struct {
+	ID         int64   `gorm:"primaryKey"`
+	ScaleID    int64   `gorm:"not null;index"`
+	Label      string  `gorm:"size:20;not null"`
+	MinPercent float64 `gorm:"not null"`
+	GradePoint float64 // 绩点，可选
+}
+
+// IsValidGradingScaleType 检查评分制类型是否有效
+func IsValidGradingScaleType(scaleType string) bool {
+	switch scaleType {
+	case GradingScalePercentage, GradingScaleLetter, GradingScaleFiveLevel:
+		return true
+	}
+	return false
+}
+
+// Convert 把百分比成绩换算为等级和绩点，Bands需要按MinPercent从高到低排列
+func (s *GradingScale) Convert(percent float64) (string, float64) {
+	if s.Type == GradingScalePercentage || len(s.Bands) == 0 {
+		return fmt.Sprintf("%.1f%%", percent), 0
+	}
+	for _, band := range s.Bands {
+		if percent >= band.MinPercent {
+			return band.Label, band.GradePoint
+		}
+	}
+	last := s.Bands[len(s.Bands)-1]
+	return last.Label, last.GradePoint
+}
+
+// GradeCategory 成绩分类，例如作业、测验、期末考试，每个分类按权重计入总评
+type GradeCategory struct {
+	ID         int64   `gorm:"primaryKey"`
+	OfferingID int64   `gorm:"not null;uniqueIndex:idx_grade_category_offering_name"`
+	Name       string  `gorm:"size:50;not null;uniqueIndex:idx_grade_category_offering_name"`
+	Weight     float64 `gorm:"not null"`           // 权重百分比
+	DropLowest int     `gorm:"not null;default:0"` // 计算总评时去掉得分率最低的几个成绩项
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+}
+
+// GradeItem 成绩项，可以关联一个作业
+type GradeItem struct {
+	ID           int64          `gorm:"primaryKey"`
+	OfferingID   int64          `gorm:"not null;index"`
+	CategoryID   int64          `gorm:"not null;index"`
+	Category     *GradeCategory `gorm:"foreignKey:CategoryID"`
+	Title        string         `gorm:"size:200;not null"`
+	MaxScore     float64        `gorm:"not null"`
+	AssignmentID *int64         `gorm:"index"`
+	AssessmentID *int64         `gorm:"index"` // 关联测评时按学生得分率最高的已评分作答自动记分
+	CreatedBy    int64
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+}
+
+// GradeEntry 学生在某个成绩项上的得分
+type GradeEntry struct {
+	ID          int64    `gorm:"primaryKey"`
+	ItemID      int64    `gorm:"not null;uniqueIndex:idx_grade_entry_item_student"`
+	StudentID   int64    `gorm:"not null;uniqueIndex:idx_grade_entry_item_student;index"`
+	Score       *float64 // 为空表示未评分
+	LatePenalty float64  // 迟交扣除满分的百分比，来自关联作业的迟交策略
+	Excused     bool     // 免考，不计入总评
+	Comment     string   `gorm:"size:500"`
+	GradedBy    int64
+	ScoredAt    *time.Time // 外部工具回传成绩时给出的时间，用于丢弃乱序到达的旧成绩，教师评分时清空
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+// EffectiveScore 扣除迟交分后的得分，不低于0
+func (e *GradeEntry) EffectiveScore(maxScore float64) float64 {
+	if e.Score == nil {
+		return 0
+	}
+	score := *e.Score - maxScore*e.LatePenalty/100
+	if score < 0 {
+		return 0
+	}
+	return score
+}
+
+// TermGrade 学生在某个开课的学期总评，由教师计算后保存
+type TermGrade struct {
+	ID         int64           `gorm:"primaryKey"`
+	OfferingID int64           `gorm:"not null;uniqueIndex:idx_term_grade_offering_student"`
+	Offering   *CourseOffering `gorm:"foreignKey:OfferingID"`
+	StudentID  int64           `gorm:"not null;uniqueIndex:idx_term_grade_offering_student;index"`
+	Student    *User           `gorm:"foreignKey:StudentID"`
+	Percent    float64
+	Label      string `gorm:"size:20"`
+	GradePoint float64
+	ComputedBy int64
+	ComputedAt time.Time
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+}
@@ -0,0 +1,29 @@
+package models
+
+import "time"
+
+// Invitation 邀请
+// 邀请预先指定注册后的角色，并可选地在注册时建立一条用户关系，
+// 例如"加入李老师的数学课"或"作为学生X的母亲进行关联"
+type Invitation struct {
+	ID        int64  `gorm:"primaryKey"`
+	CreatedBy int64  `gorm:"not null;index"` // 邀请人ID
+	Role      string `gorm:"size:20;not null"` // 注册后的角色
+	Email     string `gorm:"size:191"`         // 限定注册邮箱，为空时不限制
+
+	// 注册时建立的关系，RelationType为空时不建立关系
+	// 被邀请人总是关系的接收者，RelatedUserID为关系的发起者（如教师、学生、管理员）
+	RelationType  string `gorm:"size:20"`
+	RelatedUserID int64
+	CourseID      int64  // 教师-学生关系：课程ID
+	CourseName    string `gorm:"size:100"` // 教师-学生关系：课程名称
+	TermID        int64  // 教师-学生关系：学期ID
+	Semester      string `gorm:"size:50"`  // 教师-学生关系：学期名称
+	Relationship  string `gorm:"size:50"`  // 学生-家长关系：father, mother, guardian等
+	Department    string `gorm:"size:100"` // 管理员-教师关系：部门
+	Position      string `gorm:"size:100"` // 管理员-教师关系：职位
+
+	UsageLimit
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
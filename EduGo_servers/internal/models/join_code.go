@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+// JoinCode 班级加入码
+// 教师在课堂上发放加入码，学生使用加入码即可与教师建立该课程和学期的教师-学生关系
+type JoinCode struct {
+	ID         int64  `gorm:"primaryKey"`
+	TeacherID  int64  `gorm:"not null;index"`
+	Code       string `gorm:"size:16;not null;uniqueIndex"`
+	CourseID   int64  // 课程ID
+	CourseName string `gorm:"size:100"` // 课程名称
+	TermID     int64  // 学期ID
+	Semester   string `gorm:"size:50"` // 学期名称
+
+	RequireApproval bool // 为true时学生加入后关系处于待审批状态，需要教师确认
+
+	UsageLimit
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// IsBound 加入码是否绑定了课程和学期，早期创建的加入码可能没有绑定
+func (j *JoinCode) IsBound() bool {
+	return j.CourseID != 0 && j.TermID != 0
+}
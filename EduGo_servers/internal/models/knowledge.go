@@ -0,0 +1,48 @@
+package models
+
+import "time"
+
+// 知识点掌握程度
+const (
+	MasteryNotStarted = "not_started" // 还没有作答过相关题目
+	MasteryWeak       = "weak"        // 薄弱
+	MasteryDeveloping = "developing"  // 正在掌握
+	MasteryMastered   = "mastered"    // 已掌握
+)
+
+// KnowledgePoint 学科知识点树中的一个节点
+type KnowledgePoint struct {
+	ID          int64  `gorm:"primaryKey"`
+	Subject     string `gorm:"size:50;not null;index"`
+	ParentID    *int64 `gorm:"index"` // 上级知识点，为空表示顶层知识点
+	Name        string `gorm:"size:100;not null"`
+	Description string `gorm:"type:text"`
+	Position    int    `gorm:"not null"` // 在同级知识点中的顺序
+	CreatedBy   int64
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+// KnowledgeObservation 学生在一次测评中对某个知识点的一道题的作答结果，是估计掌握度的依据
+type KnowledgeObservation struct {
+	ID               int64     `gorm:"primaryKey"`
+	StudentID        int64     `gorm:"not null;index:idx_observation_student"`
+	KnowledgePointID int64     `gorm:"not null;index:idx_observation_student;index"`
+	AttemptID        int64     `gorm:"not null;index"`
+	QuestionID       int64     `gorm:"not null"`
+	Correct          bool      `gorm:"not null"`
+	Guess            float64   `gorm:"not null"` // 没有掌握时猜对的概率，由题型决定
+	ObservedAt       time.Time `gorm:"not null"` // 交卷时间
+}
+
+// StudentMastery 学生对一个知识点的掌握度，由贝叶斯知识追踪根据全部作答结果估计
+type StudentMastery struct {
+	ID               int64     `gorm:"primaryKey"`
+	StudentID        int64     `gorm:"not null;uniqueIndex:idx_student_mastery"`
+	KnowledgePointID int64     `gorm:"not null;uniqueIndex:idx_student_mastery;index"`
+	Probability      float64   `gorm:"not null"` // 已掌握的概率
+	Observations     int       `gorm:"not null"` // 作答的题数
+	Correct          int       `gorm:"not null"` // 答对的题数
+	LastObservedAt   time.Time `gorm:"not null"`
+	UpdatedAt        time.Time
+}
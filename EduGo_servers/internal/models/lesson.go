@@ -0,0 +1,98 @@
+package models
+
+import "time"
+
+// 课时中学习内容的类型
+const (
+	LessonItemResource   = "resource"   // 资源库中的学习资源
+	LessonItemAssessment = "assessment" // 测评
+)
+
+// 学习进度状态
+const (
+	ProgressNotStarted = "not_started" // 还没有开始
+	ProgressInProgress = "in_progress" // 学习中
+	ProgressCompleted  = "completed"   // 已完成全部学习内容
+)
+
+// CourseUnit 课程的单元
+type CourseUnit struct {
+	ID          int64    `gorm:"primaryKey"`
+	CourseID    int64    `gorm:"not null;index"`
+	Title       string   `gorm:"size:200;not null"`
+	Description string   `gorm:"type:text"`
+	Position    int      `gorm:"not null"` // 在课程中的顺序
+	Lessons     []Lesson `gorm:"foreignKey:UnitID"`
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+// Lesson 单元中的课时，由学习资源和测评组成，全部完成后课时即完成
+type Lesson struct {
+	ID          int64        `gorm:"primaryKey"`
+	UnitID      int64        `gorm:"not null;index"`
+	CourseID    int64        `gorm:"not null;index"`
+	Title       string       `gorm:"size:200;not null"`
+	Description string       `gorm:"type:text"`
+	Position    int          `gorm:"not null"` // 在单元中的顺序
+	Resources   []Resource   `gorm:"many2many:lesson_resources;"`
+	Assessments []Assessment `gorm:"many2many:lesson_assessments;"` // 课程各开课中的测评
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+// ItemCount 课时中学习内容的数量
+func (l *Lesson) ItemCount() int {
+	return len(l.Resources) + len(l.Assessments)
+}
+
+// HasItem 检查课时是否包含该学习内容
+func (l *Lesson) HasItem(itemType string, itemID int64) bool {
+	switch itemType {
+	case LessonItemResource:
+		for _, resource := range l.Resources {
+			if resource.ID == itemID {
+				return true
+			}
+		}
+	case LessonItemAssessment:
+		for _, assessment := range l.Assessments {
+			if assessment.ID == itemID {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// LessonActivity 学生在课时中学习一项内容的记录：查看资源或完成测评
+type LessonActivity struct {
+	ID          int64    `gorm:"primaryKey"`
+	StudentID   int64    `gorm:"not null;uniqueIndex:idx_lesson_activity;index"`
+	LessonID    int64    `gorm:"not null;uniqueIndex:idx_lesson_activity;index"`
+	ItemType    string   `gorm:"size:20;not null;uniqueIndex:idx_lesson_activity"`
+	ItemID      int64    `gorm:"not null;uniqueIndex:idx_lesson_activity"`
+	TimeSpent   int      `gorm:"not null"` // 学习时长（秒），测评为各次作答的用时之和
+	Score       *float64 // 测评的最高得分率，0到100
+	Completed   bool     `gorm:"not null"`
+	CompletedAt *time.Time
+	CreatedAt   time.Time // 第一次学习的时间
+	UpdatedAt   time.Time
+}
+
+// LessonProgress 学生在课时中的学习进度，由学习记录汇总，没有学习记录时不保存
+type LessonProgress struct {
+	ID             int64    `gorm:"primaryKey"`
+	StudentID      int64    `gorm:"not null;uniqueIndex:idx_lesson_progress;index"`
+	LessonID       int64    `gorm:"not null;uniqueIndex:idx_lesson_progress;index"`
+	CourseID       int64    `gorm:"not null;index"`
+	Status         string   `gorm:"size:20;not null"`
+	CompletedItems int      `gorm:"not null"`
+	TotalItems     int      `gorm:"not null"`
+	TimeSpent      int      `gorm:"not null"` // 学习时长（秒）
+	Score          *float64 // 课时中测评得分率的平均值，没有完成测评时为空
+	StartedAt      *time.Time
+	CompletedAt    *time.Time
+	LastActivityAt *time.Time
+	UpdatedAt      time.Time
+}
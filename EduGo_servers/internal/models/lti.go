@@ -0,0 +1,113 @@
+package models
+
+import "time"
+
+// LTIKey 平台和工具共用的LTI签名密钥，最新的密钥用于签名，全部密钥通过密钥集公开
+type LTIKey struct {
+	ID         int64  `gorm:"primaryKey"`
+	KID        string `gorm:"size:64;not null;uniqueIndex"`
+	PrivateKey string `gorm:"type:text;not null" json:"-"` // PKCS#8格式的PEM
+	CreatedAt  time.Time
+}
+
+// LTITool 在课程中嵌入的外部工具，EduGo作为平台启动工具
+type LTITool struct {
+	ID            int64  `gorm:"primaryKey"`
+	Name          string `gorm:"size:100;not null"`
+	ClientID      string `gorm:"size:64;not null;uniqueIndex"` // 平台分配给工具的客户端ID
+	DeploymentID  string `gorm:"size:64;not null"`
+	LoginURL      string `gorm:"size:500;not null"` // 工具的OIDC登录发起地址
+	TargetLinkURI string `gorm:"size:500;not null"` // 默认的启动地址
+	RedirectURIs  string `gorm:"type:text"`         // 允许的id_token接收地址，每行一个
+	DeepLinkURL   string `gorm:"size:500"`          // 深度链接的启动地址，为空表示工具不支持深度链接
+	KeySetURL     string `gorm:"size:500;not null"` // 工具的密钥集地址，用于校验深度链接响应和成绩服务的客户端断言
+	CreatedBy     int64
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+}
+
+// LTILink 开课中的外部工具链接，关联成绩项时工具可以通过成绩服务回传成绩
+type LTILink struct {
+	ID             int64    `gorm:"primaryKey"`
+	ResourceLinkID string   `gorm:"size:36;not null;uniqueIndex"`
+	OfferingID     int64    `gorm:"not null;index"`
+	ToolID         int64    `gorm:"not null;index"`
+	Tool           *LTITool `gorm:"foreignKey:ToolID"`
+	Title          string   `gorm:"size:200;not null"`
+	URL            string   `gorm:"size:500"`  // 为空时使用工具的默认启动地址
+	Custom         string   `gorm:"type:text"` // 自定义参数的JSON对象
+	GradeItemID    *int64   `gorm:"index"`
+	CreatedBy      int64
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+}
+
+// LTILaunch 平台发起的一次启动，Token作为login_hint，工具回到授权地址时用它确定用户和消息
+// 深度链接的启动在工具返回内容前一直有效
+type LTILaunch struct {
+	ID           int64  `gorm:"primaryKey"`
+	Token        string `gorm:"size:64;not null;uniqueIndex"`
+	UserID       int64  `gorm:"not null;index"`
+	ToolID       int64  `gorm:"not null;index"`
+	OfferingID   int64  `gorm:"not null"`
+	LinkID       *int64 // 资源链接的启动
+	MessageType  string `gorm:"size:50;not null"`
+	CategoryID   *int64 // 深度链接返回的内容需要成绩时创建成绩项的分类
+	AuthorizedAt *time.Time
+	ExpiresAt    time.Time `gorm:"not null;index"`
+	CreatedAt    time.Time
+}
+
+// LTIAssertion 工具换取访问令牌时已使用的客户端断言，在断言过期前拒绝重复使用
+type LTIAssertion struct {
+	ID        int64     `gorm:"primaryKey"`
+	ToolID    int64     `gorm:"not null;uniqueIndex:idx_lti_assertion_jti"`
+	JTI       string    `gorm:"size:191;not null;uniqueIndex:idx_lti_assertion_jti"`
+	ExpiresAt time.Time `gorm:"not null;index"`
+}
+
+// LTIPlatform 启动EduGo的外部平台（学习管理系统），EduGo作为工具
+type LTIPlatform struct {
+	ID           int64  `gorm:"primaryKey"`
+	Name         string `gorm:"size:100;not null"`
+	Issuer       string `gorm:"size:191;not null;uniqueIndex:idx_lti_platform_client"`
+	ClientID     string `gorm:"size:191;not null;uniqueIndex:idx_lti_platform_client"` // 平台分配给EduGo的客户端ID
+	DeploymentID string `gorm:"size:191"`                                              // 为空时接受任意部署
+	AuthLoginURL string `gorm:"size:500;not null"`                                     // 平台的OIDC授权地址
+	KeySetURL    string `gorm:"size:500;not null"`
+	CreatedBy    int64
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+}
+
+// LTIState 作为工具发起OIDC登录时保存的state和nonce，启动时一次性使用
+type LTIState struct {
+	ID         int64     `gorm:"primaryKey"`
+	State      string    `gorm:"size:64;not null;uniqueIndex"`
+	Nonce      string    `gorm:"size:64;not null"`
+	PlatformID int64     `gorm:"not null"`
+	ExpiresAt  time.Time `gorm:"not null;index"`
+}
+
+// LTIUser 外部平台的用户对应的EduGo用户
+type LTIUser struct {
+	ID         int64        `gorm:"primaryKey"`
+	PlatformID int64        `gorm:"not null;uniqueIndex:idx_lti_user_subject"`
+	Platform   *LTIPlatform `gorm:"foreignKey:PlatformID"`
+	Subject    string       `gorm:"size:191;not null;uniqueIndex:idx_lti_user_subject"`
+	UserID     int64        `gorm:"not null;index"`
+	User       *User        `gorm:"foreignKey:UserID"`
+	CreatedAt  time.Time
+}
+
+// LTIContext 外部平台的课程（上下文）对应的EduGo课程，绑定开课后学生启动时自动选课
+type LTIContext struct {
+	ID         int64  `gorm:"primaryKey"`
+	PlatformID int64  `gorm:"not null;uniqueIndex:idx_lti_context"`
+	ContextID  string `gorm:"size:191;not null;uniqueIndex:idx_lti_context"`
+	Title      string `gorm:"size:200"`
+	CourseID   int64  `gorm:"not null;index"`
+	OfferingID *int64
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+}
@@ -0,0 +1,116 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// 错题状态
+const (
+	MistakeActive   = "active"   // 待复习
+	MistakeResolved = "resolved" // 连续答对后自动移出错题本
+)
+
+// 错题重练状态
+const (
+	MistakePracticeInProgress = "in_progress" // 练习中
+	MistakePracticeCompleted  = "completed"   // 全部作答完毕
+)
+
+// MistakeResolveStreak 连续答对该次数后错题自动移出错题本
+const MistakeResolveStreak = 3
+
+// MistakeEntry 学生错题本中的一道题，同一道题只记录一次，再次答错时累计次数
+type MistakeEntry struct {
+	ID             int64       `gorm:"primaryKey"`
+	StudentID      int64       `gorm:"not null;uniqueIndex:idx_mistake_entry;index:idx_mistake_status"`
+	QuestionID     int64       `gorm:"not null;uniqueIndex:idx_mistake_entry;index"`
+	Question       *Question   `gorm:"foreignKey:QuestionID"`
+	AssessmentID   int64       `gorm:"not null;index"` // 最近一次在测评中答错时的试卷，决定是否向学生显示答案
+	Assessment     *Assessment `gorm:"foreignKey:AssessmentID"`
+	AttemptID      int64       `gorm:"not null"`
+	Response       string      `gorm:"type:text"` // 最近一次的错误作答，格式同AttemptAnswer
+	WrongCount     int         `gorm:"not null"`
+	CorrectStreak  int         `gorm:"not null"` // 最近一次答错后连续答对的次数
+	Status         string      `gorm:"size:20;not null;index:idx_mistake_status"`
+	LastWrongAt    time.Time   `gorm:"not null"`
+	LastAnsweredAt time.Time   `gorm:"not null"` // 最近一次计入的作答时间，更早的作答不再计入
+	ResolvedAt     *time.Time
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+}
+
+// Responses 最近一次的错误作答内容
+func (e *MistakeEntry) Responses() []string {
+	var responses []string
+	if e.Response != "" {
+		_ = json.Unmarshal([]byte(e.Response), &responses)
+	}
+	return responses
+}
+
+// RecordWrong 记录一次答错，已移出错题本的题目重新放回
+func (e *MistakeEntry) RecordWrong(response string, answeredAt time.Time) {
+	e.Response = response
+	e.WrongCount++
+	e.CorrectStreak = 0
+	e.Status = MistakeActive
+	e.LastWrongAt = answeredAt
+	e.LastAnsweredAt = answeredAt
+	e.ResolvedAt = nil
+}
+
+// RecordCorrect 记录一次答对，连续答对MistakeResolveStreak次后移出错题本
+func (e *MistakeEntry) RecordCorrect(answeredAt time.Time) {
+	e.CorrectStreak++
+	e.LastAnsweredAt = answeredAt
+	if e.Status == MistakeActive && e.CorrectStreak >= MistakeResolveStreak {
+		e.Status = MistakeResolved
+		e.ResolvedAt = &answeredAt
+	}
+}
+
+// MistakePractice 学生从错题本中抽题进行的一次重练
+type MistakePractice struct {
+	ID            int64                 `gorm:"primaryKey"`
+	StudentID     int64                 `gorm:"not null;index"`
+	Status        string                `gorm:"size:20;not null"`
+	Subject       string                `gorm:"size:50"`
+	QuestionCount int                   `gorm:"not null"`
+	CorrectCount  int                   `gorm:"not null"`
+	Items         []MistakePracticeItem `gorm:"foreignKey:PracticeID"`
+	CompletedAt   *time.Time
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+}
+
+// MistakePracticeItem 重练中的一道题
+type MistakePracticeItem struct {
+	ID         int64     `gorm:"primaryKey"`
+	PracticeID int64     `gorm:"not null;uniqueIndex:idx_practice_item"`
+	EntryID    int64     `gorm:"not null;index"`
+	QuestionID int64     `gorm:"not null;uniqueIndex:idx_practice_item"`
+	Question   *Question `gorm:"foreignKey:QuestionID"`
+	Position   int       `gorm:"not null"`
+	Response   string    `gorm:"type:text"` // 格式同AttemptAnswer，未作答为空
+	Correct    *bool
+	AnsweredAt *time.Time
+}
+
+// Responses 作答内容
+func (i *MistakePracticeItem) Responses() []string {
+	var responses []string
+	if i.Response != "" {
+		_ = json.Unmarshal([]byte(i.Response), &responses)
+	}
+	return responses
+}
+
+// SetResponses 设置作答内容
+func (i *MistakePracticeItem) SetResponses(responses []string) {
+	if responses == nil {
+		responses = []string{}
+	}
+	data, _ := json.Marshal(responses)
+	i.Response = string(data)
+}
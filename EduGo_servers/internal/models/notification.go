@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+// 通知类型
+const (
+	NotificationAttendanceAbsence = "attendance_absence"
+	NotificationReviewAssigned    = "review_assigned"
+	NotificationRecommendation    = "recommendation"
+)
+
+// Notification 站内通知
+type Notification struct {
+	ID        int64      `gorm:"primaryKey"`
+	UserID    int64      `gorm:"not null;index:idx_notification_user_read,priority:1"`
+	Type      string     `gorm:"size:50;not null"`
+	Title     string     `gorm:"size:255;not null"`
+	Content   string     `gorm:"type:text"`
+	Link      string     `gorm:"size:255"` // 相关资源，例如 /attendance/me?student_id=1
+	ReadAt    *time.Time `gorm:"index:idx_notification_user_read,priority:2"`
+	CreatedAt time.Time
+}
@@ -0,0 +1,58 @@
+package models
+
+import "time"
+
+// 数据导出状态常量
+const (
+	DataExportPending    = "pending"    // 等待处理
+	DataExportProcessing = "processing" // 处理中
+	DataExportCompleted  = "completed"  // 已完成，可以下载
+	DataExportFailed     = "failed"     // 失败
+)
+
+// 数据删除申请状态常量
+const (
+	ErasurePending   = "pending"   // 等待管理员审核
+	ErasureRejected  = "rejected"  // 已拒绝
+	ErasureCompleted = "completed" // 已完成匿名化
+)
+
+// LoginRecord 登录记录
+type LoginRecord struct {
+	ID        int64  `gorm:"primaryKey"`
+	UserID    int64  `gorm:"not null;index"`
+	IP        string `gorm:"size:64"`
+	UserAgent string `gorm:"size:255"`
+	CreatedAt time.Time
+}
+
+// DataExport 个人数据导出任务
+// 由本人、关联的家长或管理员发起，后台任务将用户的全部数据打包为可下载的压缩包
+type DataExport struct {
+	ID          int64      `gorm:"primaryKey"`
+	UserID      int64      `gorm:"not null;index"` // 数据所属用户
+	RequestedBy int64      `gorm:"not null;index"` // 发起人
+	Status      string     `gorm:"size:20;not null;index"`
+	FilePath    string     `gorm:"size:255"`
+	Error       string     `gorm:"size:255"`
+	ClaimedAt   *time.Time // 开始处理的时间，处理超时的任务会被重新领取
+	CompletedAt *time.Time
+	ExpiresAt   *time.Time // 压缩包的下载截止时间，过期后文件被清理
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+// ErasureRequest 数据删除（被遗忘权）申请
+// 审核通过后用户记录被匿名化，统计类数据仍然保留
+type ErasureRequest struct {
+	ID          int64  `gorm:"primaryKey"`
+	UserID      int64  `gorm:"not null;index"` // 数据所属用户
+	RequestedBy int64  `gorm:"not null"`       // 发起人
+	Reason      string `gorm:"size:500"`
+	Status      string `gorm:"size:20;not null;index"`
+	ReviewedBy  int64
+	ReviewNote  string `gorm:"size:500"`
+	ReviewedAt  *time.Time
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
@@ -0,0 +1,56 @@
+package models
+
+import "time"
+
+// 学生对推荐项的反馈
+const (
+	FeedbackHelpful    = "helpful"     // 有用
+	FeedbackAccepted   = "accepted"    // 练习题已加入复习队列
+	FeedbackNotHelpful = "not_helpful" // 没有用
+	FeedbackDismissed  = "dismissed"   // 不再推荐
+)
+
+// IsValidFeedback 检查学生可以提交的反馈是否有效，accepted由加入复习队列时记录
+func IsValidFeedback(action string) bool {
+	switch action {
+	case FeedbackHelpful, FeedbackNotHelpful, FeedbackDismissed:
+		return true
+	}
+	return false
+}
+
+// IsPositiveFeedback 是否为正面反馈，正面反馈会让推荐项更多地推荐给情况相似的同学
+func IsPositiveFeedback(action string) bool {
+	return action == FeedbackHelpful || action == FeedbackAccepted
+}
+
+// 教师对推荐的调整
+const (
+	OverridePin   = "pin"   // 置顶推荐
+	OverrideBlock = "block" // 不再推荐
+)
+
+// RecommendationFeedback 学生对推荐项的反馈，给出反馈后该项不再推荐给本人
+type RecommendationFeedback struct {
+	ID        int64  `gorm:"primaryKey"`
+	StudentID int64  `gorm:"not null;uniqueIndex:idx_recommendation_feedback"`
+	ItemType  string `gorm:"size:20;not null;uniqueIndex:idx_recommendation_feedback;index:idx_feedback_item,priority:1"`
+	ItemID    int64  `gorm:"not null;uniqueIndex:idx_recommendation_feedback;index:idx_feedback_item,priority:2"`
+	Action    string `gorm:"size:20;not null"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// RecommendationOverride 教师对学生推荐结果的调整：置顶推荐或不再推荐某一项
+type RecommendationOverride struct {
+	ID        int64  `gorm:"primaryKey"`
+	StudentID int64  `gorm:"not null;uniqueIndex:idx_recommendation_override"`
+	ItemType  string `gorm:"size:20;not null;uniqueIndex:idx_recommendation_override;index:idx_override_item,priority:1"`
+	ItemID    int64  `gorm:"not null;uniqueIndex:idx_recommendation_override;index:idx_override_item,priority:2"`
+	Action    string `gorm:"size:20;not null"`
+	Note      string `gorm:"size:500"` // 推荐理由，向学生显示
+	CreatedBy int64  `gorm:"not null"`
+	Creator   *User  `gorm:"foreignKey:CreatedBy"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
@@ -0,0 +1,68 @@
+package models
+
+import "time"
+
+// 成绩单批量生成任务状态
+const (
+	ReportCardBatchPending    = "pending"
+	ReportCardBatchProcessing = "processing"
+	ReportCardBatchCompleted  = "completed"
+	ReportCardBatchFailed     = "failed"
+)
+
+// 成绩单格式
+const (
+	ReportCardFormatPDF  = "pdf"
+	ReportCardFormatHTML = "html"
+)
+
+// ReportCardTemplate 成绩单模板
+// PDF按固定版式输出模板中的标题、学校名称和各部分开关；HTML可以用自定义的html/template覆盖默认版式
+type ReportCardTemplate struct {
+	ID             int64  `gorm:"primaryKey"`
+	Name           string `gorm:"size:100;not null;uniqueIndex"`
+	Title          string `gorm:"size:100"`
+	SchoolName     string `gorm:"size:100"`
+	Footer         string `gorm:"size:255"` // 页脚，例如签章说明
+	ShowCategories bool   // 是否列出各成绩分类的得分率
+	ShowAttendance bool
+	ShowComments   bool
+	HTML           string `gorm:"type:mediumtext"` // 自定义HTML模板，为空时使用默认版式
+	IsDefault      bool   `gorm:"index"`
+	CreatedBy      int64
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+}
+
+// ReportCardComment 教师在成绩单上的评语
+// OfferingID为0表示班主任的总评语，否则为该课程任课教师的评语
+type ReportCardComment struct {
+	ID         int64  `gorm:"primaryKey"`
+	TermID     int64  `gorm:"not null;uniqueIndex:idx_report_comment_term_student_offering"`
+	StudentID  int64  `gorm:"not null;uniqueIndex:idx_report_comment_term_student_offering;index"`
+	OfferingID int64  `gorm:"not null;uniqueIndex:idx_report_comment_term_student_offering"`
+	AuthorID   int64  `gorm:"not null"`
+	Author     *User  `gorm:"foreignKey:AuthorID"`
+	Content    string `gorm:"type:text;not null"`
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+}
+
+// ReportCardBatch 为整个班级批量生成成绩单的后台任务，结果打包为压缩包保存在文件存储中
+type ReportCardBatch struct {
+	ID          int64  `gorm:"primaryKey"`
+	ClassID     int64  `gorm:"not null;index"`
+	TermID      int64  `gorm:"not null"`
+	TemplateID  *int64 // 为空时使用默认模板
+	Format      string `gorm:"size:10;not null"`
+	Status      string `gorm:"size:20;not null;index"`
+	Total       int
+	Generated   int
+	StorageKey  string     `gorm:"size:255"`
+	Error       string     `gorm:"size:255"`
+	RequestedBy int64      `gorm:"not null;index"`
+	ClaimedAt   *time.Time // 开始处理的时间，处理超时的任务会被重新领取
+	CompletedAt *time.Time
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
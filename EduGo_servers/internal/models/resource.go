@@ -0,0 +1,90 @@
+package models
+
+import "time"
+
+// 学习资源类型
+const (
+	ResourceVideo       = "video"       // 视频
+	ResourceDocument    = "document"    // 文档
+	ResourceLink        = "link"        // 外部链接
+	ResourceInteractive = "interactive" // 交互式课件
+)
+
+// 学习资源的可见范围
+const (
+	ResourcePrivate = "private" // 仅创建人
+	ResourceCourse  = "course"  // 关联课程的教师和学生
+	ResourceSchool  = "school"  // 全校登录用户
+	ResourcePublic  = "public"  // 不需要登录
+)
+
+// IsValidResourceType 检查资源类型是否有效
+func IsValidResourceType(resourceType string) bool {
+	switch resourceType {
+	case ResourceVideo, ResourceDocument, ResourceLink, ResourceInteractive:
+		return true
+	}
+	return false
+}
+
+// IsValidResourceVisibility 检查可见范围是否有效
+func IsValidResourceVisibility(visibility string) bool {
+	switch visibility {
+	case ResourcePrivate, ResourceCourse, ResourceSchool, ResourcePublic:
+		return true
+	}
+	return false
+}
+
+// Resource 资源库中的学习资源，可以是上传的文件或外部链接
+// 标题和描述建立了全文索引（ngram分词，支持中文）
+type Resource struct {
+	ID              int64  `gorm:"primaryKey"`
+	Type            string `gorm:"size:20;not null;index"`
+	Title           string `gorm:"size:200;not null;index:idx_resource_search,class:FULLTEXT,option:WITH PARSER ngram"`
+	Description     string `gorm:"type:text;index:idx_resource_search,class:FULLTEXT,option:WITH PARSER ngram"`
+	Subject         string `gorm:"size:50;index"`
+	GradeLevel      string `gorm:"size:50;index"` // 年级
+	URL             string `gorm:"size:500"`      // 外部地址，链接类资源必填
+	FileName        string `gorm:"size:255"`      // 上传的文件，没有文件时为空
+	StorageKey      string `gorm:"size:255"`
+	ContentType     string `gorm:"size:100"`
+	Size            int64
+	Visibility      string           `gorm:"size:20;not null;index"`
+	OwnerID         int64            `gorm:"not null;index"`
+	Owner           *User            `gorm:"foreignKey:OwnerID"`
+	Courses         []Course         `gorm:"many2many:course_resources;"`          // 出现在这些课程的资源列表中
+	KnowledgePoints []KnowledgePoint `gorm:"many2many:resource_knowledge_points;"` // 资源涉及的知识点
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+}
+
+// HasFile 是否已上传文件
+func (r *Resource) HasFile() bool {
+	return r.StorageKey != ""
+}
+
+// InCourse 检查资源是否关联到课程
+func (r *Resource) InCourse(courseID int64) bool {
+	for _, course := range r.Courses {
+		if course.ID == courseID {
+			return true
+		}
+	}
+	return false
+}
+
+// VisibleTo 检查用户能否查看资源，courseIDs为用户所在的课程
+func (r *Resource) VisibleTo(userID int64, courseIDs []int64) bool {
+	switch r.Visibility {
+	case ResourceSchool, ResourcePublic:
+		return true
+	case ResourceCourse:
+		for _, courseID := range courseIDs {
+			if r.InCourse(courseID) {
+				return true
+			}
+		}
+	}
+	return r.OwnerID == userID
+}
@@ -0,0 +1,64 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// 复习题目的来源
+const (
+	ReviewSourcePractice  = "practice"       // 错题重练的结果
+	ReviewSourceTeacher   = "teacher"        // 教师布置
+	ReviewSourceRecommend = "recommendation" // 学生从推荐中加入
+)
+
+// ReviewItem 学生间隔复习队列中的一道题，按SM-2算法安排下一次复习的日期
+type ReviewItem struct {
+	ID             int64     `gorm:"primaryKey"`
+	StudentID      int64     `gorm:"not null;uniqueIndex:idx_review_item;index:idx_review_due,priority:1"`
+	QuestionID     int64     `gorm:"not null;uniqueIndex:idx_review_item;index"`
+	Question       *Question `gorm:"foreignKey:QuestionID"`
+	Source         string    `gorm:"size:20;not null"`
+	AssignedBy     *int64    // 最近一次布置该题的教师
+	EaseFactor     float64   `gorm:"not null"` // 难易系数
+	IntervalDays   int       `gorm:"not null"` // 当前的复习间隔
+	Repetitions    int       `gorm:"not null"` // 连续记住的次数
+	Lapses         int       `gorm:"not null"` // 遗忘的次数
+	ReviewCount    int       `gorm:"not null"`
+	DueAt          time.Time `gorm:"not null;index:idx_review_due,priority:2"` // 应复习的日期
+	LastReviewedAt *time.Time
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+}
+
+// ReviewLog 一次复习的作答和评分
+type ReviewLog struct {
+	ID           int64     `gorm:"primaryKey"`
+	ItemID       int64     `gorm:"not null;index"`
+	StudentID    int64     `gorm:"not null;index"`
+	QuestionID   int64     `gorm:"not null"`
+	Response     string    `gorm:"type:text"` // 格式同AttemptAnswer
+	Correct      *bool     // 简答题由学生自评，为空
+	Quality      int       `gorm:"not null"` // 回忆质量，0到5
+	EaseFactor   float64   `gorm:"not null"` // 本次复习后的难易系数
+	IntervalDays int       `gorm:"not null"` // 本次复习后的复习间隔
+	ReviewedAt   time.Time `gorm:"not null"`
+}
+
+// Responses 作答内容
+func (l *ReviewLog) Responses() []string {
+	var responses []string
+	if l.Response != "" {
+		_ = json.Unmarshal([]byte(l.Response), &responses)
+	}
+	return responses
+}
+
+// SetResponses 设置作答内容
+func (l *ReviewLog) SetResponses(responses []string) {
+	if responses == nil {
+		responses = []string{}
+	}
+	data, _ := json.Marshal(responses)
+	l.Response = string(data)
+}
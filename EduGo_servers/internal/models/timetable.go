@@ -0,0 +1,53 @@
+package models
+
+import "time"
+
+// Period 课节，全校统一的作息时间
+type Period struct {
+	ID        int64  `gorm:"primaryKey"`
+	Number    int    `gorm:"not null;uniqueIndex"` // 第几节
+	Name      string `gorm:"size:50;not null"`     // 例如 第一节
+	StartTime string `gorm:"size:5;not null"`      // HH:MM
+	EndTime   string `gorm:"size:5;not null"`      // HH:MM
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// Room 教室
+type Room struct {
+	ID        int64  `gorm:"primaryKey"`
+	Name      string `gorm:"size:50;not null;uniqueIndex"`
+	Building  string `gorm:"size:50"`
+	Capacity  int
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// ScheduledSession 课表中的一节课，每周固定在某天的某个课节上课
+type ScheduledSession struct {
+	ID         int64           `gorm:"primaryKey"`
+	OfferingID int64           `gorm:"not null;index"`
+	Offering   *CourseOffering `gorm:"foreignKey:OfferingID"`
+	TermID     int64           `gorm:"not null;index:idx_session_slot,priority:1"` // 与开课的学期一致，便于按学期查询
+	Weekday    int             `gorm:"not null;index:idx_session_slot,priority:2"` // 1-7，周一到周日
+	PeriodID   int64           `gorm:"not null;index:idx_session_slot,priority:3"`
+	Period     *Period         `gorm:"foreignKey:PeriodID"`
+	TeacherID  int64           `gorm:"not null;index"`
+	Teacher    *User           `gorm:"foreignKey:TeacherID"`
+	ClassID    *int64          `gorm:"index"` // 上课的班级，选修课可以为空
+	Class      *Class          `gorm:"foreignKey:ClassID"`
+	RoomID     *int64          `gorm:"index"`
+	Room       *Room           `gorm:"foreignKey:RoomID"`
+	CreatedBy  int64
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+}
+
+// CalendarFeed 用户的课表订阅地址令牌，重新生成后旧地址失效
+type CalendarFeed struct {
+	ID        int64  `gorm:"primaryKey"`
+	UserID    int64  `gorm:"not null;uniqueIndex"`
+	Token     string `gorm:"size:64;not null;uniqueIndex"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
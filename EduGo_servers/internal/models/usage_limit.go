@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+// UsageLimit 有使用次数和有效期限制、可以撤销的凭证（邀请、班级加入码）的使用状态
+type UsageLimit struct {
+	MaxUses   int // 最大使用次数，0表示不限
+	UsedCount int
+	ExpiresAt time.Time `gorm:"index"`
+	RevokedAt *time.Time
+}
+
+// IsUsable 检查凭证在指定时间是否可用
+func (u *UsageLimit) IsUsable(now time.Time) bool {
+	if u.RevokedAt != nil || !now.Before(u.ExpiresAt) {
+		return false
+	}
+	return u.MaxUses == 0 || u.UsedCount < u.MaxUses
+}
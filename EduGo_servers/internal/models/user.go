@@ -4,6 +4,7 @@ import "time"
 
 import (
 	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
 )
 
 // 用户类型常量
@@ -15,18 +16,27 @@ const (
 	RoleParent     = "parent"      // 家长
 )
 
+// 用户状态常量
+const (
+	UserStatusActive   = "active"   // 正常
+	UserStatusInactive = "inactive" // 未激活
+	UserStatusBlocked  = "blocked"  // 已封禁
+	UserStatusErased   = "erased"   // 已按用户申请匿名化
+)
+
 type User struct {
 	ID        int64  `gorm:"primaryKey"`
 	Username  string `gorm:"unique;not null"`
 	Password  string `gorm:"not null"`
-	Email     string `gorm:"unique;not null"`
-	Role      string `gorm:"default:'student'"`
-	Status    string `gorm:"default:'active'"`
-	FirstName string `gorm:"size:50"`
-	LastName  string `gorm:"size:50"`
-	CreatedAt time.Time
+	Email     *string `gorm:"size:191;unique"` // 可为空，例如由管理员创建的低年级学生
+	Role      string `gorm:"size:20;default:'student';index:idx_users_role_status"`
+	Status    string `gorm:"size:20;default:'active';index:idx_users_role_status"`
+	FirstName string `gorm:"size:50;index:idx_users_first_last,priority:1;index:idx_users_last_first,priority:2"`
+	LastName  string `gorm:"size:50;index:idx_users_first_last,priority:2;index:idx_users_last_first,priority:1"`
+	CreatedAt time.Time `gorm:"index"`
 	UpdatedAt time.Time
 	LastLoginAt *time.Time
+	DeletedAt gorm.DeletedAt `gorm:"index"` // 软删除时间，超过保留期后由清理任务彻底删除
 }
 
 // HashPassword 加密密码
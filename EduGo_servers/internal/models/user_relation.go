@@ -1,6 +1,10 @@
 package models
 
-import "time"
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
 
 // 关系类型常量
 const (
@@ -9,15 +13,23 @@ const (
 	RelationStudentParent = "student_parent"   // 学生-家长关系
 )
 
+// 关系状态常量
+const (
+	RelationStatusActive   = "active"   // 有效
+	RelationStatusInactive = "inactive" // 无效
+	RelationStatusPending  = "pending"  // 待审批，例如学生通过加入码申请加入需要教师审批的课程
+)
+
 // UserRelation 用户关系模型
 type UserRelation struct {
 	ID           int64     `gorm:"primaryKey"`
 	UserID       int64     `gorm:"not null;index"` // 关系发起者ID
 	RelatedUserID int64    `gorm:"not null;index"` // 关系接收者ID
 	RelationType string    `gorm:"not null"`       // 关系类型
-	Status       string    `gorm:"default:'active'"` // 关系状态：active, inactive
+	Status       string    `gorm:"default:'active'"` // 关系状态：active, inactive, pending
 	CreatedAt    time.Time
 	UpdatedAt    time.Time
+	DeletedAt    gorm.DeletedAt `gorm:"index"` // 随用户软删除，恢复用户时一并恢复
 }
 
 // AdminTeacherRelation 管理员-教师关系
@@ -34,7 +46,8 @@ type TeacherStudentRelation struct {
 	UserRelation
 	CourseID   int64  `gorm:"index"` // 课程ID
 	CourseName string `gorm:"size:100"` // 课程名称
-	Semester   string `gorm:"size:50"`  // 学期
+	TermID     int64  `gorm:"index"` // 学期ID
+	Semester   string `gorm:"size:50"`  // 学期名称
 }
 
 // StudentParentRelation 学生-家长关系
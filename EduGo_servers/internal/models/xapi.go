@@ -0,0 +1,74 @@
+package models
+
+import "time"
+
+// xAPI语句涉及的对象类型
+const (
+	XAPIRefActivity = "activity" // 活动
+	XAPIRefAgent    = "agent"    // 代理人
+)
+
+// xAPI文档的类型
+const (
+	XAPIDocumentState           = "state"            // 代理人在活动中的状态
+	XAPIDocumentActivityProfile = "activity_profile" // 活动档案
+)
+
+// XAPICredential 学习记录存储的基本认证凭据，客户端以凭据对应的EduGo用户身份提交语句
+type XAPICredential struct {
+	ID         int64  `gorm:"primaryKey"`
+	UserID     int64  `gorm:"not null;index"`
+	User       *User  `gorm:"foreignKey:UserID"`
+	Name       string `gorm:"size:100;not null"`            // 用途，例如"VR客户端"
+	Key        string `gorm:"size:64;not null;uniqueIndex"` // 基本认证的用户名
+	SecretHash string `gorm:"size:64;not null" json:"-"`    // 密钥的SHA-256，密钥只在创建时返回一次
+	LastUsedAt *time.Time
+	CreatedAt  time.Time
+}
+
+// XAPIStatement 学习记录存储中的语句，Body为返回给客户端的语句JSON，其余字段用于查询和统计
+type XAPIStatement struct {
+	ID           int64  `gorm:"primaryKey"`
+	StatementID  string `gorm:"size:36;not null;uniqueIndex"`
+	ActorKey     string `gorm:"size:191;not null;index"`
+	UserID       *int64 `gorm:"index"` // 执行者对应的EduGo用户，不是本系统用户时为空
+	VerbID       string `gorm:"size:191;not null;index"`
+	ActivityID   string `gorm:"size:191;index"` // 对象为活动时的活动ID
+	Registration string `gorm:"size:36;index"`
+	VoidTarget   string `gorm:"size:36"`        // 作废语句作废的语句ID
+	Voided       bool   `gorm:"not null;index"` // 已被其他语句作废
+	Completion   *bool
+	Success      *bool
+	Scaled       *float64           // 得分率，-1到1
+	Duration     int                // 时长（秒）
+	AuthorityID  int64              `gorm:"not null;index"` // 提交语句的用户
+	Timestamp    time.Time          `gorm:"precision:3;not null"`
+	Stored       time.Time          `gorm:"precision:3;not null;index"`
+	Body         string             `gorm:"type:longtext;not null"`
+	Refs         []XAPIStatementRef `gorm:"foreignKey:StatementID"`
+}
+
+// XAPIStatementRef 语句涉及的活动和代理人，Direct表示语句的执行者或对象
+type XAPIStatementRef struct {
+	ID          int64  `gorm:"primaryKey"`
+	StatementID int64  `gorm:"not null;index"`
+	Kind        string `gorm:"size:20;not null;index:idx_xapi_statement_ref,priority:1"`
+	Value       string `gorm:"size:191;not null;index:idx_xapi_statement_ref,priority:2"`
+	Direct      bool   `gorm:"not null"`
+}
+
+// XAPIDocument 代理人在活动中的状态或活动档案
+type XAPIDocument struct {
+	ID           int64     `gorm:"primaryKey"`
+	Kind         string    `gorm:"size:20;not null"`
+	Key          string    `gorm:"size:64;not null;uniqueIndex"` // 类型、活动、代理人、注册和文档ID的摘要
+	ActivityID   string    `gorm:"size:191;not null;index"`
+	AgentKey     string    `gorm:"size:191;index"` // 状态所属的代理人
+	UserID       *int64    `gorm:"index"`          // 代理人对应的EduGo用户
+	Registration string    `gorm:"size:36"`
+	DocumentID   string    `gorm:"size:191;not null"` // stateId或profileId
+	ContentType  string    `gorm:"size:100;not null"`
+	Content      []byte    `gorm:"type:longblob"`
+	ETag         string    `gorm:"size:64;not null"`
+	UpdatedAt    time.Time `gorm:"precision:3"`
+}
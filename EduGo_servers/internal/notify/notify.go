@@ -0,0 +1,44 @@
+// Package notify 发送站内通知，并通过注册的渠道（邮件、短信等）推送给用户
+package notify
+
+import (
+	"context"
+	"log"
+
+	"gorm.io/gorm"
+
+	"EduGo_servers/internal/models"
+	"EduGo_servers/internal/repository"
+)
+
+// Channel 站外推送渠道，站内通知保存成功后调用
+type Channel interface {
+	Name() string
+	Deliver(ctx context.Context, notification *models.Notification) error
+}
+
+var channels []Channel
+
+// RegisterChannel 注册推送渠道，应在启动时调用
+func RegisterChannel(channel Channel) {
+	channels = append(channels, channel)
+}
+
+// Send 保存站内通知并推送到所有已注册的渠道，推送失败只记录日志
+func Send(ctx context.Context, db *gorm.DB, notifications ...*models.Notification) error {
+	if len(notifications) == 0 {
+		return nil
+	}
+	if err := repository.NewNotificationRepository(db).CreateNotifications(ctx, notifications); err != nil {
+		return err
+	}
+
+	for _, notification := range notifications {
+		for _, channel := range channels {
+			if err := channel.Deliver(ctx, notification); err != nil {
+				log.Printf("通过%s推送通知失败 (id=%d): %v", channel.Name(), notification.ID, err)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+// Package progress 汇总学生在课程课时中的学习进度
+//
+// 课时由学习资源和测评组成。学生查看资源、完成测评后记录一项学习记录，
+// 课时的进度由其中学习内容的学习记录汇总：全部学习内容完成后课时即完成。
+package progress
+
+import (
+	"math"
+	"time"
+
+	"EduGo_servers/internal/models"
+)
+
+// Summarize 根据学生在课时中的学习记录更新课时进度，不属于课时的学习记录不计入
+func Summarize(record *models.LessonProgress, lesson *models.Lesson, activities []*models.LessonActivity) {
+	record.LessonID = lesson.ID
+	record.CourseID = lesson.CourseID
+	record.TotalItems = lesson.ItemCount()
+	record.CompletedItems = 0
+	record.TimeSpent = 0
+	record.Score = nil
+	record.StartedAt = nil
+	record.CompletedAt = nil
+	record.LastActivityAt = nil
+
+	var scoreSum float64
+	scored := 0
+	for _, activity := range activities {
+		if !lesson.HasItem(activity.ItemType, activity.ItemID) {
+			continue
+		}
+		record.TimeSpent += activity.TimeSpent
+		if activity.Score != nil {
+			scoreSum += *activity.Score
+			scored++
+		}
+		if activity.Completed {
+			record.CompletedItems++
+			if activity.CompletedAt != nil {
+				record.CompletedAt = later(record.CompletedAt, *activity.CompletedAt)
+			}
+		}
+		if record.StartedAt == nil || activity.CreatedAt.Before(*record.StartedAt) {
+			started := activity.CreatedAt
+			record.StartedAt = &started
+		}
+		record.LastActivityAt = later(record.LastActivityAt, activity.UpdatedAt)
+	}
+	if scored > 0 {
+		score := math.Round(scoreSum/float64(scored)*100) / 100
+		record.Score = &score
+	}
+
+	switch {
+	case record.StartedAt == nil:
+		record.Status = models.ProgressNotStarted
+	case record.TotalItems > 0 && record.CompletedItems >= record.TotalItems:
+		record.Status = models.ProgressCompleted
+	default:
+		record.Status = models.ProgressInProgress
+		record.CompletedAt = nil
+	}
+}
+
+// Percent 完成百分比，total为0时返回0
+func Percent(completed, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return math.Round(float64(completed)*10000/float64(total)) / 100
+}
+
+func later(current *time.Time, t time.Time) *time.Time {
+	if current == nil || t.After(*current) {
+		return &t
+	}
+	return current
+}
@@ -0,0 +1,109 @@
+// Package questionio 题库的导入和导出：IMS QTI 2.x 题目包和电子表格模板（CSV、XLSX）
+//
+// 导入时只负责解析文件，得到的题目草稿由调用方按添加题目的规则校验后保存。
+package questionio
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+
+	"EduGo_servers/internal/models"
+)
+
+// MaxImportQuestions 每次最多导入的题目数
+const MaxImportQuestions = 1000
+
+// ErrTooManyQuestions 文件中的题目超过MaxImportQuestions
+var ErrTooManyQuestions = errors.New("questionio: too many questions")
+
+// Draft 从文件中解析出的一道题，字段与添加题目的输入相同
+type Draft struct {
+	Source string // 题目在文件中的位置，例如"第3行"或题目包中的文件名，用于校验报告
+	Error  string // 解析失败的原因，不为空时其余字段无效
+
+	Type            string
+	Subject         string
+	Stem            string
+	Explanation     string
+	Difficulty      *int
+	Options         []DraftOption
+	Answer          *bool      // 判断题
+	Blanks          [][]string // 填空题每个空可接受的答案
+	ReferenceAnswer string     // 简答题
+	KnowledgePoints []string
+}
+
+// DraftOption 选择题的选项
+type DraftOption struct {
+	Content   string
+	IsCorrect bool
+}
+
+// blankPattern 题干中表示填空位置的下划线
+var blankPattern = regexp.MustCompile(`_{3,}`)
+
+// blankMark 导入时在题干中标记填空的位置
+const blankMark = "____"
+
+// lomDifficulties LOM元数据中的难度，依次对应难度等级1到5
+var lomDifficulties = []string{"very easy", "easy", "medium", "difficult", "very difficult"}
+
+// typeNames 电子表格中可以使用的题型名称
+var typeNames = map[string]string{
+	"单选题": models.QuestionSingleChoice,
+	"单选":  models.QuestionSingleChoice,
+	"多选题": models.QuestionMultipleChoice,
+	"多选":  models.QuestionMultipleChoice,
+	"判断题": models.QuestionTrueFalse,
+	"判断":  models.QuestionTrueFalse,
+	"填空题": models.QuestionFillBlank,
+	"填空":  models.QuestionFillBlank,
+	"简答题": models.QuestionShortAnswer,
+	"简答":  models.QuestionShortAnswer,
+}
+
+// typeLabels 导出电子表格时使用的题型名称
+var typeLabels = map[string]string{
+	models.QuestionSingleChoice:   "单选题",
+	models.QuestionMultipleChoice: "多选题",
+	models.QuestionTrueFalse:      "判断题",
+	models.QuestionFillBlank:      "填空题",
+	models.QuestionShortAnswer:    "简答题",
+}
+
+// splitKnowledgePoints 拆分用逗号、分号或顿号分隔的知识点
+func splitKnowledgePoints(value string) []string {
+	fields := strings.FieldsFunc(value, func(r rune) bool {
+		switch r {
+		case ',', '，', ';', '；', '、':
+			return true
+		}
+		return false
+	})
+	points := make([]string, 0, len(fields))
+	for _, field := range fields {
+		if field = strings.TrimSpace(field); field != "" {
+			points = append(points, field)
+		}
+	}
+	return points
+}
+
+// knowledgePoints 题目的知识点名称
+func knowledgePoints(question *models.Question) []string {
+	points := make([]string, 0, len(question.Tags))
+	for _, tag := range question.Tags {
+		points = append(points, tag.KnowledgePoint)
+	}
+	return points
+}
+
+// blanks 填空题每个空可接受的答案
+func blanks(question *models.Question) [][]string {
+	result := make([][]string, question.BlankCount())
+	for _, answer := range question.Answers {
+		result[answer.Blank] = append(result[answer.Blank], answer.Content)
+	}
+	return result
+}
@@ -0,0 +1,734 @@
+package questionio
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+	"text/template"
+
+	"EduGo_servers/internal/models"
+)
+
+// maxQTIFileSize 题目包中单个XML文件解压后的最大大小
+const maxQTIFileSize = 2 << 20
+
+// ErrInvalidPackage 不是有效的QTI题目包
+var ErrInvalidPackage = errors.New("questionio: invalid QTI package")
+
+// qtiManifest 题目包的imsmanifest.xml，只读取题目资源和LOM元数据
+type qtiManifest struct {
+	Resources []struct {
+		Type     string `xml:"type,attr"`
+		Href     string `xml:"href,attr"`
+		Metadata qtiLOM `xml:"metadata>lom"`
+		Files    []struct {
+			Href string `xml:"href,attr"`
+		} `xml:"file"`
+	} `xml:"resources>resource"`
+}
+
+// qtiLOM 题目的LOM元数据：关键词作为知识点，学科分类作为学科，难度对应难度等级
+type qtiLOM struct {
+	Keywords   []qtiLangString `xml:"general>keyword"`
+	Difficulty qtiLangString   `xml:"educational>difficulty>value"`
+	Taxons     []qtiLangString `xml:"classification>taxonPath>taxon>entry"`
+}
+
+// qtiLangString LOM中的文字，兼容LOM（string）和IMS元数据1.2（langstring）两种写法
+type qtiLangString struct {
+	Text       string `xml:",chardata"`
+	String     string `xml:"string"`
+	LangString string `xml:"langstring"`
+}
+
+func (s qtiLangString) Value() string {
+	for _, value := range []string{s.String, s.LangString, s.Text} {
+		if value = strings.TrimSpace(value); value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// qtiItem QTI 2.x 的assessmentItem，题干和交互从itemBody中逐个解析
+type qtiItem struct {
+	XMLName      xml.Name
+	Title        string `xml:"title,attr"`
+	Declarations []struct {
+		Identifier  string   `xml:"identifier,attr"`
+		Cardinality string   `xml:"cardinality,attr"`
+		Correct     []string `xml:"correctResponse>value"`
+		Mapping     []struct {
+			Key   string  `xml:"mapKey,attr"`
+			Value float64 `xml:"mappedValue,attr"`
+		} `xml:"mapping>mapEntry"`
+	} `xml:"responseDeclaration"`
+	Body     qtiMarkup   `xml:"itemBody"`
+	Feedback []qtiMarkup `xml:"modalFeedback"`
+}
+
+type qtiMarkup struct {
+	Inner []byte `xml:",innerxml"`
+}
+
+// qtiInteraction itemBody中的一个作答区域
+type qtiInteraction struct {
+	Kind       string
+	Response   string
+	MaxChoices string
+	Choices    []qtiChoice
+}
+
+type qtiChoice struct {
+	Identifier string
+	Content    string
+}
+
+// ParseQTI 解析QTI 2.x 题目包（包含imsmanifest.xml的zip文件）或单个题目XML文件
+// 支持单选、多选、判断（两个选项的标识分别为true和false）、填空和简答题
+func ParseQTI(fileName string, data []byte) ([]Draft, error) {
+	switch strings.ToLower(path.Ext(fileName)) {
+	case ".xml":
+		draft := parseQTIItem(data)
+		draft.Source = fileName
+		return []Draft{draft}, nil
+	case ".zip":
+		return parseQTIPackage(data)
+	default:
+		return nil, ErrUnsupportedFormat
+	}
+}
+
+// parseQTIPackage 按imsmanifest.xml中的题目资源解析题目包，没有清单时解析包中全部题目XML文件
+func parseQTIPackage(data []byte) ([]Draft, error) {
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, ErrInvalidPackage
+	}
+	files := make(map[string]*zip.File, len(archive.File))
+	for _, file := range archive.File {
+		files[path.Clean(file.Name)] = file
+	}
+
+	var drafts []Draft
+	manifestFile, ok := files["imsmanifest.xml"]
+	if !ok {
+		names := make([]string, 0, len(files))
+		for name := range files {
+			if strings.EqualFold(path.Ext(name), ".xml") {
+				names = append(names, name)
+			}
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			content, err := readZipFile(files[name])
+			if err != nil {
+				return nil, ErrInvalidPackage
+			}
+			if !isQTIItem(content) {
+				continue
+			}
+			if len(drafts) == MaxImportQuestions {
+				return nil, ErrTooManyQuestions
+			}
+			draft := parseQTIItem(content)
+			draft.Source = name
+			drafts = append(drafts, draft)
+		}
+		return drafts, nil
+	}
+
+	var manifest qtiManifest
+	if err := decodeZipXML(manifestFile, &manifest); err != nil {
+		return nil, ErrInvalidPackage
+	}
+	for _, resource := range manifest.Resources {
+		if !strings.HasPrefix(resource.Type, "imsqti_item") {
+			continue
+		}
+		href := resource.Href
+		if href == "" && len(resource.Files) > 0 {
+			href = resource.Files[0].Href
+		}
+		if len(drafts) == MaxImportQuestions {
+			return nil, ErrTooManyQuestions
+		}
+
+		name := path.Clean(href)
+		file, ok := files[name]
+		if !ok {
+			drafts = append(drafts, Draft{Source: href, Error: "题目文件不存在"})
+			continue
+		}
+		content, err := readZipFile(file)
+		if err != nil {
+			return nil, ErrInvalidPackage
+		}
+		draft := parseQTIItem(content)
+		draft.Source = name
+		if draft.Error == "" {
+			applyLOM(&draft, resource.Metadata)
+		}
+		drafts = append(drafts, draft)
+	}
+	return drafts, nil
+}
+
+// applyLOM 把清单中的元数据写入题目
+func applyLOM(draft *Draft, lom qtiLOM) {
+	for _, keyword := range lom.Keywords {
+		if value := keyword.Value(); value != "" {
+			draft.KnowledgePoints = append(draft.KnowledgePoints, value)
+		}
+	}
+	if len(lom.Taxons) > 0 {
+		draft.Subject = lom.Taxons[0].Value()
+	}
+	value := strings.ToLower(lom.Difficulty.Value())
+	for i, name := range lomDifficulties {
+		if value == name {
+			difficulty := i + 1
+			draft.Difficulty = &difficulty
+		}
+	}
+}
+
+// isQTIItem 检查XML文件的根元素是否为assessmentItem
+func isQTIItem(data []byte) bool {
+	decoder := newQTIDecoder(data)
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return false
+		}
+		if start, ok := token.(xml.StartElement); ok {
+			return start.Name.Local == "assessmentItem"
+		}
+	}
+}
+
+// parseQTIItem 解析一道QTI题目
+func parseQTIItem(data []byte) Draft {
+	var item qtiItem
+	if err := newQTIDecoder(data).Decode(&item); err != nil {
+		return Draft{Error: "XML格式错误"}
+	}
+	if item.XMLName.Local != "assessmentItem" {
+		return Draft{Error: "不是QTI 2.x 题目（assessmentItem）"}
+	}
+
+	stem, interactions, problem := parseQTIBody(item.Body.Inner)
+	if problem != "" {
+		return Draft{Error: problem}
+	}
+	if stem == "" {
+		stem = strings.TrimSpace(item.Title)
+	}
+	draft := Draft{Stem: stem}
+	var explanations []string
+	for _, feedback := range item.Feedback {
+		if text, _, problem := parseQTIBody(feedback.Inner); problem == "" && text != "" {
+			explanations = append(explanations, text)
+		}
+	}
+	draft.Explanation = strings.Join(explanations, "\n")
+
+	correct := make(map[string][]string, len(item.Declarations))
+	cardinality := make(map[string]string, len(item.Declarations))
+	for _, declaration := range item.Declarations {
+		values := make([]string, 0, len(declaration.Correct)+len(declaration.Mapping))
+		for _, value := range declaration.Correct {
+			values = append(values, strings.TrimSpace(value))
+		}
+		for _, entry := range declaration.Mapping {
+			if entry.Value > 0 {
+				values = append(values, strings.TrimSpace(entry.Key))
+			}
+		}
+		correct[declaration.Identifier] = values
+		cardinality[declaration.Identifier] = declaration.Cardinality
+	}
+
+	kinds := map[string]int{}
+	for _, interaction := range interactions {
+		kinds[interaction.Kind]++
+	}
+	switch {
+	case len(interactions) == 0:
+		draft.Error = "题目中没有作答区域"
+	case len(interactions) == 1 && interactions[0].Kind == "choiceInteraction":
+		interaction := interactions[0]
+		answers := correct[interaction.Response]
+		if isTrueFalse(interaction.Choices) {
+			draft.Type = models.QuestionTrueFalse
+			if len(answers) == 1 {
+				value := strings.EqualFold(answers[0], "true")
+				draft.Answer = &value
+			}
+			break
+		}
+		draft.Type = models.QuestionSingleChoice
+		if cardinality[interaction.Response] == "multiple" || (interaction.MaxChoices != "" && interaction.MaxChoices != "1") {
+			draft.Type = models.QuestionMultipleChoice
+		}
+		isCorrect := make(map[string]bool, len(answers))
+		for _, answer := range answers {
+			isCorrect[answer] = true
+		}
+		for _, choice := range interaction.Choices {
+			draft.Options = append(draft.Options, DraftOption{Content: choice.Content, IsCorrect: isCorrect[choice.Identifier]})
+		}
+	case kinds["textEntryInteraction"] == len(interactions):
+		draft.Type = models.QuestionFillBlank
+		for _, interaction := range interactions {
+			draft.Blanks = append(draft.Blanks, uniqueStrings(correct[interaction.Response]))
+		}
+	case len(interactions) == 1 && interactions[0].Kind == "extendedTextInteraction":
+		draft.Type = models.QuestionShortAnswer
+		draft.ReferenceAnswer = strings.Join(correct[interactions[0].Response], "\n")
+	default:
+		draft.Error = "不支持的题目结构，每道题只能包含一个选择或简答作答区域，或者若干填空"
+	}
+	return draft
+}
+
+// qtiBlockElements 前后需要换行的元素
+var qtiBlockElements = map[string]bool{
+	"p": true, "div": true, "br": true, "li": true, "tr": true, "pre": true, "blockquote": true, "prompt": true,
+	"h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true,
+}
+
+// qtiIgnoredElements 内容不导入的元素
+var qtiIgnoredElements = map[string]bool{
+	"rubricBlock": true, "feedbackBlock": true, "feedbackInline": true, "templateBlock": true, "templateInline": true,
+	"printedVariable": true, "stylesheet": true,
+}
+
+// qtiMediaElements 无法转换为文字的多媒体元素
+var qtiMediaElements = map[string]bool{
+	"img": true, "object": true, "audio": true, "video": true, "math": true,
+}
+
+// parseQTIBody 从itemBody（或反馈）的XHTML中提取纯文本题干和作答区域，填空的位置用下划线标记
+// 无法导入时返回原因
+func parseQTIBody(data []byte) (string, []*qtiInteraction, string) {
+	decoder := newQTIDecoder(data)
+	var stem, choice strings.Builder
+	var interactions []*qtiInteraction
+	var current *qtiInteraction
+	inChoice := false
+	skip := 0
+
+	target := func() *strings.Builder {
+		if inChoice {
+			return &choice
+		}
+		return &stem
+	}
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", nil, "XML格式错误"
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			if skip > 0 {
+				skip++
+				continue
+			}
+			name := t.Name.Local
+			switch {
+			case name == "choiceInteraction" || name == "extendedTextInteraction":
+				if current != nil {
+					return "", nil, "作答区域不能嵌套"
+				}
+				current = &qtiInteraction{Kind: name, Response: attrValue(t, "responseIdentifier"), MaxChoices: attrValue(t, "maxChoices")}
+				interactions = append(interactions, current)
+				stem.WriteString("\n")
+			case name == "textEntryInteraction":
+				interactions = append(interactions, &qtiInteraction{Kind: name, Response: attrValue(t, "responseIdentifier")})
+				stem.WriteString(blankMark)
+				skip = 1
+			case name == "simpleChoice":
+				if current == nil || current.Kind != "choiceInteraction" {
+					return "", nil, "选项不在选择作答区域中"
+				}
+				current.Choices = append(current.Choices, qtiChoice{Identifier: attrValue(t, "identifier")})
+				inChoice = true
+				choice.Reset()
+			case strings.HasSuffix(name, "Interaction"):
+				return "", nil, fmt.Sprintf("不支持的作答类型%s", name)
+			case qtiMediaElements[name]:
+				return "", nil, "题目包含图片、公式等多媒体内容，暂不支持导入"
+			case qtiIgnoredElements[name]:
+				skip = 1
+			case qtiBlockElements[name]:
+				target().WriteString("\n")
+			}
+		case xml.EndElement:
+			if skip > 0 {
+				skip--
+				continue
+			}
+			switch name := t.Name.Local; {
+			case name == "choiceInteraction" || name == "extendedTextInteraction":
+				current = nil
+				stem.WriteString("\n")
+			case name == "simpleChoice":
+				current.Choices[len(current.Choices)-1].Content = plainText(choice.String())
+				inChoice = false
+			case qtiBlockElements[name]:
+				target().WriteString("\n")
+			}
+		case xml.CharData:
+			if skip == 0 {
+				target().Write(t)
+			}
+		}
+	}
+	return plainText(stem.String()), interactions, ""
+}
+
+// plainText 合并每行中的连续空白，去掉空行
+func plainText(text string) string {
+	var lines []string
+	for _, line := range strings.Split(text, "\n") {
+		if line = strings.Join(strings.Fields(line), " "); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// isTrueFalse 两个选项的标识分别为true和false时视为判断题
+func isTrueFalse(choices []qtiChoice) bool {
+	if len(choices) != 2 {
+		return false
+	}
+	first, second := strings.ToLower(choices[0].Identifier), strings.ToLower(choices[1].Identifier)
+	return (first == "true" && second == "false") || (first == "false" && second == "true")
+}
+
+func uniqueStrings(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	result := make([]string, 0, len(values))
+	for _, value := range values {
+		if value != "" && !seen[value] {
+			seen[value] = true
+			result = append(result, value)
+		}
+	}
+	return result
+}
+
+func attrValue(element xml.StartElement, name string) string {
+	for _, attr := range element.Attr {
+		if attr.Name.Local == name {
+			return attr.Value
+		}
+	}
+	return ""
+}
+
+// newQTIDecoder 创建XML解码器，题目中常见的HTML实体（例如&nbsp;）按HTML解析
+func newQTIDecoder(data []byte) *xml.Decoder {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	decoder.Entity = xml.HTMLEntity
+	return decoder
+}
+
+func readZipFile(file *zip.File) ([]byte, error) {
+	reader, err := file.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	data, err := io.ReadAll(io.LimitReader(reader, maxQTIFileSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxQTIFileSize {
+		return nil, ErrInvalidPackage
+	}
+	return data, nil
+}
+
+// qtiItemView 导出一道题时模板使用的数据
+type qtiItemView struct {
+	Identifier  string
+	Href        string
+	Title       string
+	Stem        string
+	Cardinality string
+	MaxChoices  int
+	Choices     []qtiChoice
+	Correct     []string
+	Blanks      [][]string
+	Parts       []qtiBodyPart // 填空题的题干，文字和填空交替
+	Reference   string
+	Explanation string
+
+	Subject         string
+	Difficulty      string
+	KnowledgePoints []string
+}
+
+// qtiBodyPart 填空题题干的一段，Blank不小于0时为第Blank个空
+type qtiBodyPart struct {
+	Text  string
+	Blank int
+}
+
+var qtiTemplates = template.Must(template.New("qti").Funcs(template.FuncMap{
+	"xml": func(value string) string {
+		var b strings.Builder
+		xml.EscapeText(&b, []byte(value))
+		return b.String()
+	},
+	"text": func(value string) string {
+		lines := strings.Split(value, "\n")
+		for i, line := range lines {
+			var b strings.Builder
+			xml.EscapeText(&b, []byte(line))
+			lines[i] = b.String()
+		}
+		return strings.Join(lines, "<br/>")
+	},
+	"blankID": func(index int) string { return fmt.Sprintf("RESPONSE_%d", index+1) },
+}).Parse(`
+{{- define "item" -}}
+<?xml version="1.0" encoding="UTF-8"?>
+<assessmentItem xmlns="http://www.imsglobal.org/xsd/imsqti_v2p1" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" xsi:schemaLocation="http://www.imsglobal.org/xsd/imsqti_v2p1 http://www.imsglobal.org/xsd/qti/qtiv2p1/imsqti_v2p1.xsd" identifier="{{.Identifier}}" title="{{xml .Title}}" adaptive="false" timeDependent="false">
+{{- if .Choices}}
+  <responseDeclaration identifier="RESPONSE" cardinality="{{.Cardinality}}" baseType="identifier">
+    <correctResponse>
+{{- range .Correct}}
+      <value>{{.}}</value>
+{{- end}}
+    </correctResponse>
+  </responseDeclaration>
+{{- else if .Blanks}}
+{{- range $i, $accepted := .Blanks}}
+  <responseDeclaration identifier="{{blankID $i}}" cardinality="single" baseType="string">
+    <correctResponse>
+      <value>{{xml (index $accepted 0)}}</value>
+    </correctResponse>
+    <mapping defaultValue="0">
+{{- range $accepted}}
+      <mapEntry mapKey="{{xml .}}" mappedValue="1" caseSensitive="false"/>
+{{- end}}
+    </mapping>
+  </responseDeclaration>
+{{- end}}
+{{- else}}
+  <responseDeclaration identifier="RESPONSE" cardinality="single" baseType="string">
+{{- if .Reference}}
+    <correctResponse>
+      <value>{{xml .Reference}}</value>
+    </correctResponse>
+{{- end}}
+  </responseDeclaration>
+{{- end}}
+  <outcomeDeclaration identifier="SCORE" cardinality="single" baseType="float"/>
+{{- if .Explanation}}
+  <outcomeDeclaration identifier="FEEDBACK" cardinality="single" baseType="identifier"/>
+{{- end}}
+  <itemBody>
+{{- if .Choices}}
+    <choiceInteraction responseIdentifier="RESPONSE" shuffle="false" maxChoices="{{.MaxChoices}}">
+      <prompt>{{text .Stem}}</prompt>
+{{- range .Choices}}
+      <simpleChoice identifier="{{.Identifier}}">{{text .Content}}</simpleChoice>
+{{- end}}
+    </choiceInteraction>
+{{- else if .Blanks}}
+    <p>{{range .Parts}}{{if ge .Blank 0}}<textEntryInteraction responseIdentifier="{{blankID .Blank}}"/>{{else}}{{text .Text}}{{end}}{{end}}</p>
+{{- else}}
+    <extendedTextInteraction responseIdentifier="RESPONSE">
+      <prompt>{{text .Stem}}</prompt>
+    </extendedTextInteraction>
+{{- end}}
+  </itemBody>
+{{- if .Choices}}
+  <responseProcessing template="http://www.imsglobal.org/question/qti_v2p1/rptemplates/match_correct"/>
+{{- else if .Blanks}}
+  <responseProcessing>
+    <setOutcomeValue identifier="SCORE">
+      <sum>
+{{- range $i, $accepted := .Blanks}}
+        <mapResponse identifier="{{blankID $i}}"/>
+{{- end}}
+      </sum>
+    </setOutcomeValue>
+  </responseProcessing>
+{{- end}}
+{{- if .Explanation}}
+  <modalFeedback outcomeIdentifier="FEEDBACK" identifier="EXPLANATION" showHide="hide">{{text .Explanation}}</modalFeedback>
+{{- end}}
+</assessmentItem>
+{{end}}
+
+{{- define "manifest" -}}
+<?xml version="1.0" encoding="UTF-8"?>
+<manifest xmlns="http://www.imsglobal.org/xsd/imscp_v1p1" xmlns:imsmd="http://ltsc.ieee.org/xsd/LOM" identifier="MANIFEST-EDUGO-QUESTIONS">
+  <metadata>
+    <schema>QTIv2.1 Package</schema>
+    <schemaversion>1.0.0</schemaversion>
+  </metadata>
+  <organizations/>
+  <resources>
+{{- range .}}
+    <resource identifier="{{.Identifier}}" type="imsqti_item_xmlv2p1" href="{{.Href}}">
+      <metadata>
+        <imsmd:lom>
+{{- if .KnowledgePoints}}
+          <imsmd:general>
+{{- range .KnowledgePoints}}
+            <imsmd:keyword>
+              <imsmd:string>{{xml .}}</imsmd:string>
+            </imsmd:keyword>
+{{- end}}
+          </imsmd:general>
+{{- end}}
+          <imsmd:educational>
+            <imsmd:difficulty>
+              <imsmd:source>LOMv1.0</imsmd:source>
+              <imsmd:value>{{.Difficulty}}</imsmd:value>
+            </imsmd:difficulty>
+          </imsmd:educational>
+{{- if .Subject}}
+          <imsmd:classification>
+            <imsmd:purpose>
+              <imsmd:source>LOMv1.0</imsmd:source>
+              <imsmd:value>discipline</imsmd:value>
+            </imsmd:purpose>
+            <imsmd:taxonPath>
+              <imsmd:taxon>
+                <imsmd:entry>
+                  <imsmd:string>{{xml .Subject}}</imsmd:string>
+                </imsmd:entry>
+              </imsmd:taxon>
+            </imsmd:taxonPath>
+          </imsmd:classification>
+{{- end}}
+        </imsmd:lom>
+      </metadata>
+      <file href="{{.Href}}"/>
+    </resource>
+{{- end}}
+  </resources>
+</manifest>
+{{end}}`))
+
+// ExportQTI 把题目导出为QTI 2.1 题目包（zip），学科、难度和知识点写入清单的LOM元数据
+func ExportQTI(w io.Writer, questions []*models.Question) error {
+	archive := zip.NewWriter(w)
+	views := make([]*qtiItemView, 0, len(questions))
+	for _, question := range questions {
+		view := newQTIItemView(question)
+		file, err := archive.Create(view.Href)
+		if err != nil {
+			return err
+		}
+		if err := qtiTemplates.ExecuteTemplate(file, "item", view); err != nil {
+			return err
+		}
+		views = append(views, view)
+	}
+
+	file, err := archive.Create("imsmanifest.xml")
+	if err != nil {
+		return err
+	}
+	if err := qtiTemplates.ExecuteTemplate(file, "manifest", views); err != nil {
+		return err
+	}
+	return archive.Close()
+}
+
+// newQTIItemView 构造导出一道题使用的数据
+func newQTIItemView(question *models.Question) *qtiItemView {
+	identifier := fmt.Sprintf("Q%d", question.ID)
+	view := &qtiItemView{
+		Identifier:      identifier,
+		Href:            "items/" + identifier + ".xml",
+		Title:           qtiTitle(question.Stem),
+		Stem:            question.Stem,
+		Explanation:     question.Explanation,
+		Subject:         question.Subject,
+		KnowledgePoints: knowledgePoints(question),
+	}
+	if question.Difficulty >= 1 && question.Difficulty <= len(lomDifficulties) {
+		view.Difficulty = lomDifficulties[question.Difficulty-1]
+	} else {
+		view.Difficulty = lomDifficulties[2]
+	}
+
+	switch question.Type {
+	case models.QuestionSingleChoice, models.QuestionMultipleChoice:
+		view.Cardinality, view.MaxChoices = "single", 1
+		if question.Type == models.QuestionMultipleChoice {
+			view.Cardinality, view.MaxChoices = "multiple", 0
+		}
+		for _, option := range question.Options {
+			view.Choices = append(view.Choices, qtiChoice{Identifier: option.Label, Content: option.Content})
+			if option.IsCorrect {
+				view.Correct = append(view.Correct, option.Label)
+			}
+		}
+	case models.QuestionTrueFalse:
+		view.Cardinality, view.MaxChoices = "single", 1
+		view.Choices = []qtiChoice{{Identifier: "true", Content: "正确"}, {Identifier: "false", Content: "错误"}}
+		view.Correct = []string{"false"}
+		if len(question.Answers) > 0 && question.Answers[0].Content == "true" {
+			view.Correct = []string{"true"}
+		}
+	case models.QuestionFillBlank:
+		for _, accepted := range blanks(question) {
+			view.Blanks = append(view.Blanks, uniqueStrings(accepted))
+		}
+		view.Parts = blankParts(question.Stem, len(view.Blanks))
+	case models.QuestionShortAnswer:
+		if len(question.Answers) > 0 {
+			view.Reference = question.Answers[0].Content
+		}
+	}
+	return view
+}
+
+// blankParts 把填空题题干中的下划线依次替换为填空，下划线不够时其余的空放在题干末尾
+func blankParts(stem string, count int) []qtiBodyPart {
+	var parts []qtiBodyPart
+	blank := 0
+	last := 0
+	for _, match := range blankPattern.FindAllStringIndex(stem, count) {
+		parts = append(parts, qtiBodyPart{Text: stem[last:match[0]], Blank: -1}, qtiBodyPart{Blank: blank})
+		blank++
+		last = match[1]
+	}
+	parts = append(parts, qtiBodyPart{Text: stem[last:], Blank: -1})
+	for ; blank < count; blank++ {
+		parts = append(parts, qtiBodyPart{Text: " ", Blank: -1}, qtiBodyPart{Blank: blank})
+	}
+	return parts
+}
+
+// qtiTitle 题目标题取题干第一行的前50个字
+func qtiTitle(stem string) string {
+	title := strings.TrimSpace(strings.SplitN(stem, "\n", 2)[0])
+	if runes := []rune(title); len(runes) > 50 {
+		title = string(runes[:50]) + "…"
+	}
+	return title
+}
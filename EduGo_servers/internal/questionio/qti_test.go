@@ -0,0 +1,449 @@
+package questionio
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"EduGo_servers/internal/models"
+)
+
+// zipFile 压缩包中的一个文件
+type zipFile struct {
+	Name    string
+	Content string
+}
+
+// zipArchive 按顺序把文件写入zip压缩包
+func zipArchive(t *testing.T, files ...zipFile) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+	for _, file := range files {
+		w, err := archive.Create(file.Name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(file.Content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := archive.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func difficulty(value int) *int { return &value }
+
+func answer(value bool) *bool { return &value }
+
+// checkDrafts 逐题比较解析结果
+func checkDrafts(t *testing.T, got, want []Draft) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("got %d drafts, want %d: %+v", len(got), len(want), got)
+	}
+	for i := range want {
+		if !reflect.DeepEqual(got[i], want[i]) {
+			t.Errorf("draft %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+// qtiItemXML 带命名空间的assessmentItem
+func qtiItemXML(declarations, body string) string {
+	return `<?xml version="1.0" encoding="UTF-8"?>
+<assessmentItem xmlns="http://www.imsglobal.org/xsd/imsqti_v2p1" identifier="ITEM" title="标题" adaptive="false" timeDependent="false">` +
+		declarations + `<itemBody>` + body + `</itemBody></assessmentItem>`
+}
+
+const (
+	singleChoiceItem = `<?xml version="1.0" encoding="UTF-8"?>
+<assessmentItem xmlns="http://www.imsglobal.org/xsd/imsqti_v2p1" identifier="ITEM" title="加法">
+  <responseDeclaration identifier="RESPONSE" cardinality="single" baseType="identifier">
+    <correctResponse><value>B</value></correctResponse>
+  </responseDeclaration>
+  <itemBody>
+    <choiceInteraction responseIdentifier="RESPONSE" shuffle="false" maxChoices="1">
+      <prompt>1 + 1 = ?</prompt>
+      <simpleChoice identifier="A">1</simpleChoice>
+      <simpleChoice identifier="B"> 2 </simpleChoice>
+      <simpleChoice identifier="C">3</simpleChoice>
+    </choiceInteraction>
+  </itemBody>
+  <modalFeedback outcomeIdentifier="FEEDBACK" identifier="EXPLANATION" showHide="hide">1加1等于2</modalFeedback>
+</assessmentItem>`
+
+	multipleChoiceItem = `<assessmentItem xmlns="http://www.imsglobal.org/xsd/imsqti_v2p1" identifier="ITEM">
+  <responseDeclaration identifier="RESPONSE" cardinality="multiple" baseType="identifier">
+    <correctResponse><value>A</value><value>C</value></correctResponse>
+  </responseDeclaration>
+  <itemBody>
+    <p>下列哪些是质数？</p>
+    <choiceInteraction responseIdentifier="RESPONSE" maxChoices="0">
+      <simpleChoice identifier="A">2</simpleChoice>
+      <simpleChoice identifier="B">4</simpleChoice>
+      <simpleChoice identifier="C">5</simpleChoice>
+    </choiceInteraction>
+  </itemBody>
+</assessmentItem>`
+
+	trueFalseItem = `<assessmentItem xmlns="http://www.imsglobal.org/xsd/imsqti_v2p1" identifier="ITEM">
+  <responseDeclaration identifier="RESPONSE" cardinality="single" baseType="identifier">
+    <correctResponse><value>false</value></correctResponse>
+  </responseDeclaration>
+  <itemBody>
+    <choiceInteraction responseIdentifier="RESPONSE" maxChoices="1">
+      <prompt>太阳从西边升起。</prompt>
+      <simpleChoice identifier="true">对</simpleChoice>
+      <simpleChoice identifier="false">错</simpleChoice>
+    </choiceInteraction>
+  </itemBody>
+</assessmentItem>`
+
+	fillBlankItem = `<assessmentItem xmlns="http://www.imsglobal.org/xsd/imsqti_v2p1" identifier="ITEM">
+  <responseDeclaration identifier="R1" cardinality="single" baseType="string">
+    <correctResponse><value>北京</value></correctResponse>
+    <mapping defaultValue="0">
+      <mapEntry mapKey="北京" mappedValue="1"/>
+      <mapEntry mapKey="北京市" mappedValue="1"/>
+      <mapEntry mapKey="南京" mappedValue="0"/>
+    </mapping>
+  </responseDeclaration>
+  <responseDeclaration identifier="R2" cardinality="single" baseType="string">
+    <correctResponse><value>上海</value></correctResponse>
+  </responseDeclaration>
+  <itemBody>
+    <p>中国的首都是<textEntryInteraction responseIdentifier="R1"/>，最大的城市是<textEntryInteraction responseIdentifier="R2"/>。</p>
+  </itemBody>
+</assessmentItem>`
+
+	shortAnswerItem = `<assessmentItem xmlns="http://www.imsglobal.org/xsd/imsqti_v2p1" identifier="ITEM">
+  <responseDeclaration identifier="RESPONSE" cardinality="single" baseType="string">
+    <correctResponse><value>物体保持静止或匀速直线运动。</value></correctResponse>
+  </responseDeclaration>
+  <itemBody>
+    <extendedTextInteraction responseIdentifier="RESPONSE">
+      <prompt>简述&nbsp;牛顿第一定律。<rubricBlock view="scorer"><p>评分标准</p></rubricBlock></prompt>
+    </extendedTextInteraction>
+  </itemBody>
+</assessmentItem>`
+)
+
+func TestParseQTIItem(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want Draft
+	}{
+		{
+			"单选题", singleChoiceItem,
+			Draft{Type: models.QuestionSingleChoice, Stem: "1 + 1 = ?", Explanation: "1加1等于2", Options: []DraftOption{
+				{Content: "1"}, {Content: "2", IsCorrect: true}, {Content: "3"},
+			}},
+		},
+		{
+			"多选题", multipleChoiceItem,
+			Draft{Type: models.QuestionMultipleChoice, Stem: "下列哪些是质数？", Options: []DraftOption{
+				{Content: "2", IsCorrect: true}, {Content: "4"}, {Content: "5", IsCorrect: true},
+			}},
+		},
+		{"判断题", trueFalseItem, Draft{Type: models.QuestionTrueFalse, Stem: "太阳从西边升起。", Answer: answer(false)}},
+		{
+			"填空题", fillBlankItem,
+			Draft{Type: models.QuestionFillBlank, Stem: "中国的首都是____，最大的城市是____。", Blanks: [][]string{{"北京", "北京市"}, {"上海"}}},
+		},
+		{"简答题", shortAnswerItem, Draft{Type: models.QuestionShortAnswer, Stem: "简述 牛顿第一定律。", ReferenceAnswer: "物体保持静止或匀速直线运动。"}},
+		{
+			"没有题干时使用标题",
+			qtiItemXML("", `<extendedTextInteraction responseIdentifier="RESPONSE"/>`),
+			Draft{Type: models.QuestionShortAnswer, Stem: "标题"},
+		},
+		{"XML格式错误", `<assessmentItem><itemBody>`, Draft{Error: "XML格式错误"}},
+		{"不是题目", `<manifest/>`, Draft{Error: "不是QTI 2.x 题目（assessmentItem）"}},
+		{"没有作答区域", qtiItemXML("", `<p>题干</p>`), Draft{Error: "题目中没有作答区域", Stem: "题干"}},
+		{
+			"不支持的作答类型",
+			qtiItemXML("", `<orderInteraction responseIdentifier="RESPONSE"/>`),
+			Draft{Error: "不支持的作答类型orderInteraction"},
+		},
+		{
+			"多媒体内容",
+			qtiItemXML("", `<p>看图<img src="a.png"/></p><extendedTextInteraction responseIdentifier="RESPONSE"/>`),
+			Draft{Error: "题目包含图片、公式等多媒体内容，暂不支持导入"},
+		},
+		{
+			"作答区域嵌套",
+			qtiItemXML("", `<choiceInteraction responseIdentifier="A"><choiceInteraction responseIdentifier="B"/></choiceInteraction>`),
+			Draft{Error: "作答区域不能嵌套"},
+		},
+		{
+			"选项不在选择作答区域中",
+			qtiItemXML("", `<simpleChoice identifier="A">1</simpleChoice>`),
+			Draft{Error: "选项不在选择作答区域中"},
+		},
+		{
+			"选择和简答混合",
+			qtiItemXML("", `<choiceInteraction responseIdentifier="A"><simpleChoice identifier="A">1</simpleChoice></choiceInteraction>`+
+				`<extendedTextInteraction responseIdentifier="B"/>`),
+			Draft{Error: "不支持的题目结构，每道题只能包含一个选择或简答作答区域，或者若干填空", Stem: "标题"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			drafts, err := ParseQTI("item.xml", []byte(tt.data))
+			if err != nil {
+				t.Fatalf("ParseQTI() error = %v", err)
+			}
+			tt.want.Source = "item.xml"
+			checkDrafts(t, drafts, []Draft{tt.want})
+		})
+	}
+}
+
+// manifestXML 题目包清单，每个资源为 href 和 LOM 元数据
+func manifestXML(resources ...string) string {
+	return `<?xml version="1.0" encoding="UTF-8"?>
+<manifest xmlns="http://www.imsglobal.org/xsd/imscp_v1p1" xmlns:imsmd="http://ltsc.ieee.org/xsd/LOM" identifier="M">
+  <resources>` + strings.Join(resources, "") + `</resources>
+</manifest>`
+}
+
+func TestParseQTIPackage(t *testing.T) {
+	tests := []struct {
+		name  string
+		files []zipFile
+		want  []Draft
+	}{
+		{
+			"按清单解析并读取元数据",
+			[]zipFile{
+				{"items/single.xml", singleChoiceItem},
+				{"items/blank.xml", fillBlankItem},
+				{"imsmanifest.xml", manifestXML(
+					`<resource identifier="R1" type="imsqti_item_xmlv2p1" href="items/blank.xml">
+					  <metadata><imsmd:lom>
+					    <imsmd:general><imsmd:keyword><imsmd:string>中国地理</imsmd:string></imsmd:keyword></imsmd:general>
+					    <imsmd:educational><imsmd:difficulty><imsmd:value>easy</imsmd:value></imsmd:difficulty></imsmd:educational>
+					    <imsmd:classification><imsmd:taxonPath><imsmd:taxon><imsmd:entry><imsmd:string>地理</imsmd:string></imsmd:entry></imsmd:taxon></imsmd:taxonPath></imsmd:classification>
+					  </imsmd:lom></metadata>
+					</resource>`,
+					`<resource identifier="R2" type="webcontent" href="items/single.xml"/>`,
+					`<resource identifier="R3" type="imsqti_item_xmlv2p0"><file href="./items/single.xml"/></resource>`,
+					`<resource identifier="R4" type="imsqti_item_xmlv2p1" href="items/missing.xml"/>`,
+				)},
+			},
+			[]Draft{
+				{
+					Source: "items/blank.xml", Type: models.QuestionFillBlank, Subject: "地理", Difficulty: difficulty(2),
+					Stem: "中国的首都是____，最大的城市是____。", Blanks: [][]string{{"北京", "北京市"}, {"上海"}}, KnowledgePoints: []string{"中国地理"},
+				},
+				{
+					Source: "items/single.xml", Type: models.QuestionSingleChoice, Stem: "1 + 1 = ?", Explanation: "1加1等于2",
+					Options: []DraftOption{{Content: "1"}, {Content: "2", IsCorrect: true}, {Content: "3"}},
+				},
+				{Source: "items/missing.xml", Error: "题目文件不存在"},
+			},
+		},
+		{
+			"IMS元数据1.2的langstring",
+			[]zipFile{
+				{"q.xml", trueFalseItem},
+				{"imsmanifest.xml", manifestXML(`<resource type="imsqti_item_xmlv2p1" href="q.xml"><metadata><lom>
+				  <general><keyword><langstring>天文</langstring></keyword></general>
+				  <educational><difficulty><value><langstring>Very Difficult</langstring></value></difficulty></educational>
+				</lom></metadata></resource>`)},
+			},
+			[]Draft{{Source: "q.xml", Type: models.QuestionTrueFalse, Stem: "太阳从西边升起。", Answer: answer(false),
+				Difficulty: difficulty(5), KnowledgePoints: []string{"天文"}}},
+		},
+		{
+			"没有清单时按文件名顺序解析全部题目",
+			[]zipFile{
+				{"b/short.xml", shortAnswerItem},
+				{"a/multiple.xml", multipleChoiceItem},
+				{"a/readme.XML", `<notes>不是题目</notes>`},
+				{"a/image.png", "PNG"},
+			},
+			[]Draft{
+				{Source: "a/multiple.xml", Type: models.QuestionMultipleChoice, Stem: "下列哪些是质数？",
+					Options: []DraftOption{{Content: "2", IsCorrect: true}, {Content: "4"}, {Content: "5", IsCorrect: true}}},
+				{Source: "b/short.xml", Type: models.QuestionShortAnswer, Stem: "简述 牛顿第一定律。", ReferenceAnswer: "物体保持静止或匀速直线运动。"},
+			},
+		},
+		{"空的题目包", []zipFile{{"imsmanifest.xml", manifestXML()}}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			drafts, err := ParseQTI("questions.ZIP", zipArchive(t, tt.files...))
+			if err != nil {
+				t.Fatalf("ParseQTI() error = %v", err)
+			}
+			checkDrafts(t, drafts, tt.want)
+		})
+	}
+}
+
+// manyItems count道相同题目的题目包，withManifest为false时不带清单
+func manyItems(t *testing.T, count int, withManifest bool) []byte {
+	t.Helper()
+	files := make([]zipFile, 0, count+1)
+	resources := make([]string, 0, count)
+	for i := 0; i < count; i++ {
+		name := fmt.Sprintf("items/q%04d.xml", i)
+		files = append(files, zipFile{name, trueFalseItem})
+		resources = append(resources, `<resource type="imsqti_item_xmlv2p1" href="`+name+`"/>`)
+	}
+	if withManifest {
+		files = append(files, zipFile{"imsmanifest.xml", manifestXML(resources...)})
+	}
+	return zipArchive(t, files...)
+}
+
+func TestParseQTIInvalidPackage(t *testing.T) {
+	// 解压后超过大小限制的文件，压缩后很小
+	oversized := "<assessmentItem>" + strings.Repeat(" ", maxQTIFileSize) + "</assessmentItem>"
+	tests := []struct {
+		name     string
+		fileName string
+		data     []byte
+		want     error
+	}{
+		{"不支持的扩展名", "questions.rar", []byte("data"), ErrUnsupportedFormat},
+		{"不是zip文件", "questions.zip", []byte("not a zip archive"), ErrInvalidPackage},
+		{"截断的zip文件", "questions.zip", zipArchive(t, zipFile{"q.xml", trueFalseItem})[:40], ErrInvalidPackage},
+		{"清单格式错误", "questions.zip", zipArchive(t, zipFile{"imsmanifest.xml", "<manifest><resources>"}), ErrInvalidPackage},
+		{
+			"清单中的题目文件过大", "questions.zip",
+			zipArchive(t, zipFile{"q.xml", oversized}, zipFile{"imsmanifest.xml", manifestXML(`<resource type="imsqti_item_xmlv2p1" href="q.xml"/>`)}),
+			ErrInvalidPackage,
+		},
+		{"没有清单时题目文件过大", "questions.zip", zipArchive(t, zipFile{"q.xml", oversized}), ErrInvalidPackage},
+		{"清单中的题目超过上限", "questions.zip", manyItems(t, MaxImportQuestions+1, true), ErrTooManyQuestions},
+		{"没有清单时题目超过上限", "questions.zip", manyItems(t, MaxImportQuestions+1, false), ErrTooManyQuestions},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			drafts, err := ParseQTI(tt.fileName, tt.data)
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("ParseQTI() error = %v, want %v", err, tt.want)
+			}
+			if drafts != nil {
+				t.Errorf("ParseQTI() = %d drafts, want none", len(drafts))
+			}
+		})
+	}
+}
+
+func TestParseQTIMaxQuestions(t *testing.T) {
+	for _, withManifest := range []bool{true, false} {
+		drafts, err := ParseQTI("questions.zip", manyItems(t, MaxImportQuestions, withManifest))
+		if err != nil {
+			t.Fatalf("ParseQTI() with manifest %v: error = %v", withManifest, err)
+		}
+		if len(drafts) != MaxImportQuestions {
+			t.Errorf("ParseQTI() with manifest %v: got %d drafts, want %d", withManifest, len(drafts), MaxImportQuestions)
+		}
+	}
+}
+
+// sampleQuestions 每种题型一道题，用于导出后重新导入
+func sampleQuestions() []*models.Question {
+	return []*models.Question{
+		{
+			ID: 1, Type: models.QuestionSingleChoice, Subject: "数学", Stem: "1 + 1 = ?\n请选择一项", Explanation: "1加1等于2", Difficulty: 1,
+			Options: []models.QuestionOption{
+				{Label: "A", Content: "1"}, {Label: "B", Content: "2", IsCorrect: true}, {Label: "C", Content: "a < b & c"},
+			},
+			Tags: []models.QuestionTag{{KnowledgePoint: "加法"}},
+		},
+		{
+			ID: 2, Type: models.QuestionMultipleChoice, Subject: "数学", Stem: "下列哪些是质数？", Difficulty: 2,
+			Options: []models.QuestionOption{
+				{Label: "A", Content: "2", IsCorrect: true}, {Label: "B", Content: "4"}, {Label: "C", Content: "5", IsCorrect: true},
+			},
+			Tags: []models.QuestionTag{{KnowledgePoint: "质数"}, {KnowledgePoint: "因数分解"}},
+		},
+		{
+			ID: 3, Type: models.QuestionTrueFalse, Subject: "语文", Stem: "《红楼梦》的作者是曹雪芹。", Difficulty: 2,
+			Answers: []models.QuestionAnswer{{Content: "true"}},
+		},
+		{
+			ID: 4, Type: models.QuestionFillBlank, Subject: "地理", Stem: "中国的首都是____，最大的城市是____。", Difficulty: 1,
+			Answers: []models.QuestionAnswer{{Blank: 0, Content: "北京"}, {Blank: 1, Content: "上海"}, {Blank: 1, Content: "上海市"}},
+		},
+		{
+			ID: 5, Type: models.QuestionShortAnswer, Subject: "物理", Stem: "简述牛顿第一定律。", Explanation: "惯性定律", Difficulty: 3,
+			Answers: []models.QuestionAnswer{{Content: "一切物体在没有受到外力作用时，总保持静止或匀速直线运动状态。"}},
+			Tags:    []models.QuestionTag{{KnowledgePoint: "力学"}},
+		},
+	}
+}
+
+// sampleDrafts sampleQuestions重新导入后应得到的题目，不含Source
+func sampleDrafts() []Draft {
+	return []Draft{
+		{
+			Type: models.QuestionSingleChoice, Subject: "数学", Stem: "1 + 1 = ?\n请选择一项", Explanation: "1加1等于2", Difficulty: difficulty(1),
+			Options:         []DraftOption{{Content: "1"}, {Content: "2", IsCorrect: true}, {Content: "a < b & c"}},
+			KnowledgePoints: []string{"加法"},
+		},
+		{
+			Type: models.QuestionMultipleChoice, Subject: "数学", Stem: "下列哪些是质数？", Difficulty: difficulty(2),
+			Options:         []DraftOption{{Content: "2", IsCorrect: true}, {Content: "4"}, {Content: "5", IsCorrect: true}},
+			KnowledgePoints: []string{"质数", "因数分解"},
+		},
+		{Type: models.QuestionTrueFalse, Subject: "语文", Stem: "《红楼梦》的作者是曹雪芹。", Difficulty: difficulty(2), Answer: answer(true)},
+		{
+			Type: models.QuestionFillBlank, Subject: "地理", Stem: "中国的首都是____，最大的城市是____。", Difficulty: difficulty(1),
+			Blanks: [][]string{{"北京"}, {"上海", "上海市"}},
+		},
+		{
+			Type: models.QuestionShortAnswer, Subject: "物理", Stem: "简述牛顿第一定律。", Explanation: "惯性定律", Difficulty: difficulty(3),
+			ReferenceAnswer: "一切物体在没有受到外力作用时，总保持静止或匀速直线运动状态。", KnowledgePoints: []string{"力学"},
+		},
+	}
+}
+
+func TestQTIRoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	if err := ExportQTI(&buf, sampleQuestions()); err != nil {
+		t.Fatalf("ExportQTI() error = %v", err)
+	}
+	drafts, err := ParseQTI("questions.zip", buf.Bytes())
+	if err != nil {
+		t.Fatalf("ParseQTI() error = %v", err)
+	}
+
+	want := sampleDrafts()
+	for i := range want {
+		want[i].Source = fmt.Sprintf("items/Q%d.xml", i+1)
+	}
+	checkDrafts(t, drafts, want)
+}
+
+func TestBlankParts(t *testing.T) {
+	tests := []struct {
+		name  string
+		stem  string
+		count int
+		want  []qtiBodyPart
+	}{
+		{"下划线与空数相同", "A___B______C", 2, []qtiBodyPart{{"A", -1}, {"", 0}, {"B", -1}, {"", 1}, {"C", -1}}},
+		{"下划线多于空数", "A___B___C", 1, []qtiBodyPart{{"A", -1}, {"", 0}, {"B___C", -1}}},
+		{"下划线不够时放在末尾", "A___B", 3, []qtiBodyPart{{"A", -1}, {"", 0}, {"B", -1}, {" ", -1}, {"", 1}, {" ", -1}, {"", 2}}},
+		{"两个下划线不是填空", "A__B", 1, []qtiBodyPart{{"A__B", -1}, {" ", -1}, {"", 0}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := blankParts(tt.stem, tt.count); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("blankParts(%q, %d) = %+v, want %+v", tt.stem, tt.count, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,358 @@
+package questionio
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding/simplifiedchinese"
+
+	"EduGo_servers/internal/models"
+)
+
+var (
+	// ErrUnsupportedFormat 不支持的文件格式
+	ErrUnsupportedFormat = errors.New("questionio: unsupported file format")
+	// ErrMissingColumns 电子表格的表头缺少题型或题干列
+	ErrMissingColumns = errors.New("questionio: missing required columns")
+)
+
+// 电子表格的列
+const (
+	columnType            = "type"
+	columnSubject         = "subject"
+	columnStem            = "stem"
+	columnAnswer          = "answer"
+	columnDifficulty      = "difficulty"
+	columnKnowledgePoints = "knowledge_points"
+	columnExplanation     = "explanation"
+)
+
+// columnNames 表头名称对应的列，中英文均可
+var columnNames = map[string]string{
+	"题型":               columnType,
+	"type":             columnType,
+	"学科":               columnSubject,
+	"subject":          columnSubject,
+	"题干":               columnStem,
+	"stem":             columnStem,
+	"答案":               columnAnswer,
+	"answer":           columnAnswer,
+	"难度":               columnDifficulty,
+	"difficulty":       columnDifficulty,
+	"知识点":              columnKnowledgePoints,
+	"knowledge_points": columnKnowledgePoints,
+	"解析":               columnExplanation,
+	"explanation":      columnExplanation,
+}
+
+// optionColumn 选项列的表头，例如"选项A"或"option_a"
+var optionColumn = regexp.MustCompile(`^(?:选项|option[ _]?)([a-z])$`)
+
+// 判断题答案的写法
+var (
+	trueValues  = []string{"正确", "对", "是", "√", "✓", "true", "t", "yes", "y", "1"}
+	falseValues = []string{"错误", "错", "否", "×", "✗", "false", "f", "no", "n", "0"}
+)
+
+// 填空题答案的分隔符：多个空之间用"|"，同一个空的多个可接受答案之间用";"
+const (
+	blankSeparator       = "|"
+	alternativeSeparator = ";"
+)
+
+// templateRows 导入模板中的示例题目
+var templateRows = [][]string{
+	{"单选题", "数学", "1 + 1 = ?", "1", "2", "3", "4", "B", "1", "加法", "1加1等于2"},
+	{"多选题", "数学", "下列哪些是质数？", "2", "4", "5", "9", "AC", "2", "质数，因数分解", ""},
+	{"判断题", "语文", "《红楼梦》的作者是曹雪芹。", "", "", "", "", "正确", "2", "古典文学", ""},
+	{"填空题", "地理", "中国的首都是____，最大的城市是____。", "", "", "", "", "北京|上海;上海市", "1", "中国地理", "同一个空有多个可接受的答案时用分号分隔"},
+	{"简答题", "物理", "简述牛顿第一定律。", "", "", "", "", "一切物体在没有受到外力作用时，总保持静止或匀速直线运动状态。", "3", "力学", ""},
+}
+
+// ParseSpreadsheet 解析按导入模板填写的CSV或XLSX文件，第一个非空行为表头，之后每行一道题
+// CSV文件可以是UTF-8或GBK编码；XLSX文件只读取第一个工作表
+func ParseSpreadsheet(fileName string, data []byte) ([]Draft, error) {
+	var rows [][]string
+	var err error
+	switch strings.ToLower(path.Ext(fileName)) {
+	case ".csv":
+		rows, err = readCSV(data)
+	case ".xlsx":
+		rows, err = readXLSX(data)
+	default:
+		return nil, ErrUnsupportedFormat
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	header := -1
+	for i, row := range rows {
+		if !isBlankRow(row) {
+			header = i
+			break
+		}
+	}
+	if header < 0 {
+		return nil, ErrMissingColumns
+	}
+	columns, options := parseHeader(rows[header])
+	if _, ok := columns[columnType]; !ok {
+		return nil, ErrMissingColumns
+	}
+	if _, ok := columns[columnStem]; !ok {
+		return nil, ErrMissingColumns
+	}
+
+	var drafts []Draft
+	for i := header + 1; i < len(rows); i++ {
+		if isBlankRow(rows[i]) {
+			continue
+		}
+		if len(drafts) == MaxImportQuestions {
+			return nil, ErrTooManyQuestions
+		}
+		draft := parseRow(rows[i], columns, options)
+		draft.Source = fmt.Sprintf("第%d行", i+1)
+		drafts = append(drafts, draft)
+	}
+	return drafts, nil
+}
+
+// readCSV 读取CSV文件，不是有效的UTF-8时按GB18030（兼容GBK）解码
+func readCSV(data []byte) ([][]string, error) {
+	data = bytes.TrimPrefix(data, []byte("\ufeff"))
+	if !utf8.Valid(data) {
+		decoded, err := simplifiedchinese.GB18030.NewDecoder().Bytes(data)
+		if err != nil {
+			return nil, err
+		}
+		data = decoded
+	}
+
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	return reader.ReadAll()
+}
+
+// parseHeader 解析表头，返回各列的位置和按字母排列的选项列位置
+func parseHeader(row []string) (map[string]int, []int) {
+	columns := map[string]int{}
+	letters := map[int]byte{}
+	var options []int
+	for i, name := range row {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if column, ok := columnNames[name]; ok {
+			if _, exists := columns[column]; !exists {
+				columns[column] = i
+			}
+			continue
+		}
+		if match := optionColumn.FindStringSubmatch(name); match != nil {
+			letters[i] = match[1][0]
+			options = append(options, i)
+		}
+	}
+	sort.SliceStable(options, func(a, b int) bool { return letters[options[a]] < letters[options[b]] })
+	return columns, options
+}
+
+// parseRow 解析一行题目
+func parseRow(row []string, columns map[string]int, options []int) Draft {
+	cell := func(column string) string {
+		if i, ok := columns[column]; ok && i < len(row) {
+			return strings.TrimSpace(row[i])
+		}
+		return ""
+	}
+
+	draft := Draft{
+		Subject:         cell(columnSubject),
+		Stem:            cell(columnStem),
+		Explanation:     cell(columnExplanation),
+		KnowledgePoints: splitKnowledgePoints(cell(columnKnowledgePoints)),
+	}
+	typeName := cell(columnType)
+	if typeName == "" {
+		draft.Error = "题型不能为空"
+		return draft
+	}
+	if questionType, ok := typeNames[typeName]; ok {
+		draft.Type = questionType
+	} else {
+		draft.Type = strings.ToLower(typeName)
+	}
+	if value := cell(columnDifficulty); value != "" {
+		difficulty, err := strconv.Atoi(value)
+		if err != nil {
+			draft.Error = "难度必须是1到5的整数"
+			return draft
+		}
+		draft.Difficulty = &difficulty
+	}
+
+	answer := cell(columnAnswer)
+	switch draft.Type {
+	case models.QuestionSingleChoice, models.QuestionMultipleChoice:
+		// 保留最后一个非空选项之前的空选项，由校验报告指出
+		last := -1
+		for i, column := range options {
+			if column < len(row) && strings.TrimSpace(row[column]) != "" {
+				last = i
+			}
+		}
+		for _, column := range options[:last+1] {
+			content := ""
+			if column < len(row) {
+				content = strings.TrimSpace(row[column])
+			}
+			draft.Options = append(draft.Options, DraftOption{Content: content})
+		}
+		if answer == "" {
+			draft.Error = "选择题需要填写答案"
+			return draft
+		}
+		for _, r := range strings.ToUpper(answer) {
+			switch {
+			case r >= 'A' && r <= 'Z':
+				index := int(r - 'A')
+				if index >= len(draft.Options) {
+					draft.Error = fmt.Sprintf("答案中的选项%c不存在", r)
+					return draft
+				}
+				draft.Options[index].IsCorrect = true
+			case strings.ContainsRune(" ,，、;；", r):
+			default:
+				draft.Error = "选择题的答案应为选项字母，例如A或ACD"
+				return draft
+			}
+		}
+	case models.QuestionTrueFalse:
+		value, ok := parseTrueFalse(answer)
+		if !ok {
+			draft.Error = "判断题的答案应为“正确”或“错误”"
+			return draft
+		}
+		draft.Answer = &value
+	case models.QuestionFillBlank:
+		if answer == "" {
+			break
+		}
+		for _, blank := range strings.Split(strings.ReplaceAll(answer, "｜", blankSeparator), blankSeparator) {
+			draft.Blanks = append(draft.Blanks, strings.Split(strings.ReplaceAll(blank, "；", alternativeSeparator), alternativeSeparator))
+		}
+	case models.QuestionShortAnswer:
+		draft.ReferenceAnswer = answer
+	}
+	return draft
+}
+
+func parseTrueFalse(value string) (bool, bool) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	for _, candidate := range trueValues {
+		if value == candidate {
+			return true, true
+		}
+	}
+	for _, candidate := range falseValues {
+		if value == candidate {
+			return false, true
+		}
+	}
+	return false, false
+}
+
+func isBlankRow(row []string) bool {
+	for _, value := range row {
+		if strings.TrimSpace(value) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// WriteTemplate 写出CSV格式的导入模板，包含每种题型的示例
+func WriteTemplate(w io.Writer) error {
+	return writeCSV(w, 4, templateRows)
+}
+
+// WriteSpreadsheet 按导入模板的格式把题目导出为CSV，导出的文件可以直接重新导入
+func WriteSpreadsheet(w io.Writer, questions []*models.Question) error {
+	optionCount := 4
+	for _, question := range questions {
+		optionCount = max(optionCount, len(question.Options))
+	}
+
+	rows := make([][]string, 0, len(questions))
+	for _, question := range questions {
+		row := []string{typeLabels[question.Type], question.Subject, question.Stem}
+		options := make([]string, optionCount)
+		var answer strings.Builder
+		for i, option := range question.Options {
+			options[i] = option.Content
+			if option.IsCorrect {
+				answer.WriteString(option.Label)
+			}
+		}
+		row = append(row, options...)
+
+		switch question.Type {
+		case models.QuestionTrueFalse:
+			if len(question.Answers) > 0 && question.Answers[0].Content == "true" {
+				answer.WriteString(trueValues[0])
+			} else {
+				answer.WriteString(falseValues[0])
+			}
+		case models.QuestionFillBlank:
+			values := make([]string, 0, question.BlankCount())
+			for _, accepted := range blanks(question) {
+				values = append(values, strings.Join(accepted, alternativeSeparator))
+			}
+			answer.WriteString(strings.Join(values, blankSeparator))
+		case models.QuestionShortAnswer:
+			if len(question.Answers) > 0 {
+				answer.WriteString(question.Answers[0].Content)
+			}
+		}
+
+		row = append(row,
+			answer.String(),
+			strconv.Itoa(question.Difficulty),
+			strings.Join(knowledgePoints(question), "，"),
+			question.Explanation,
+		)
+		rows = append(rows, row)
+	}
+	return writeCSV(w, optionCount, rows)
+}
+
+// writeCSV 写出带表头的CSV，开头写入BOM以便Excel识别UTF-8编码
+func writeCSV(w io.Writer, optionCount int, rows [][]string) error {
+	if _, err := io.WriteString(w, "\ufeff"); err != nil {
+		return err
+	}
+	header := []string{"题型", "学科", "题干"}
+	for i := 0; i < optionCount; i++ {
+		header = append(header, "选项"+string(rune('A'+i)))
+	}
+	header = append(header, "答案", "难度", "知识点", "解析")
+
+	writer := csv.NewWriter(w)
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+	if err := writer.WriteAll(rows); err != nil {
+		return err
+	}
+	return writer.Error()
+}
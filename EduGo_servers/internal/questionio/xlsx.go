@@ -0,0 +1,215 @@
+package questionio
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// XLSX文件的读取限制
+const (
+	maxXLSXPartSize = 50 << 20 // 单个部件解压后的最大大小
+	maxXLSXRows     = 10000    // 有内容的最大行号
+	maxXLSXColumns  = 100
+)
+
+// errInvalidXLSX XLSX文件结构不完整
+var errInvalidXLSX = errors.New("questionio: invalid xlsx file")
+
+type xlsxWorkbook struct {
+	Sheets []struct {
+		RelID string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+	} `xml:"sheets>sheet"`
+}
+
+type xlsxRelationships struct {
+	Relationships []struct {
+		ID     string `xml:"Id,attr"`
+		Target string `xml:"Target,attr"`
+	} `xml:"Relationship"`
+}
+
+// xlsxText 共享字符串或内联字符串，带格式的文字分成多段
+type xlsxText struct {
+	Text string `xml:"t"`
+	Runs []struct {
+		Text string `xml:"t"`
+	} `xml:"r"`
+}
+
+func (t xlsxText) String() string {
+	if len(t.Runs) == 0 {
+		return t.Text
+	}
+	var b strings.Builder
+	for _, run := range t.Runs {
+		b.WriteString(run.Text)
+	}
+	return b.String()
+}
+
+type xlsxSharedStrings struct {
+	Items []xlsxText `xml:"si"`
+}
+
+type xlsxWorksheet struct {
+	Rows []struct {
+		Number int `xml:"r,attr"`
+		Cells  []struct {
+			Ref    string   `xml:"r,attr"`
+			Type   string   `xml:"t,attr"`
+			Value  string   `xml:"v"`
+			Inline xlsxText `xml:"is"`
+		} `xml:"c"`
+	} `xml:"sheetData>row"`
+}
+
+// readXLSX 读取XLSX文件第一个工作表的单元格文字，返回的行号与表格中的行号一致（第一行下标为0）
+func readXLSX(data []byte) ([][]string, error) {
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, err
+	}
+	files := make(map[string]*zip.File, len(archive.File))
+	for _, file := range archive.File {
+		files[file.Name] = file
+	}
+
+	sheetPath, err := firstSheetPath(files)
+	if err != nil {
+		return nil, err
+	}
+
+	var shared xlsxSharedStrings
+	if file, ok := files["xl/sharedStrings.xml"]; ok {
+		if err := decodeZipXML(file, &shared); err != nil {
+			return nil, err
+		}
+	}
+
+	file, ok := files[sheetPath]
+	if !ok {
+		return nil, errInvalidXLSX
+	}
+	var sheet xlsxWorksheet
+	if err := decodeZipXML(file, &sheet); err != nil {
+		return nil, err
+	}
+
+	var rows [][]string
+	for _, row := range sheet.Rows {
+		var values []string
+		for j, cell := range row.Cells {
+			column := j
+			if cell.Ref != "" {
+				column = columnIndex(cell.Ref)
+			}
+			if column < 0 || column >= maxXLSXColumns {
+				return nil, errInvalidXLSX
+			}
+			var value string
+			switch cell.Type {
+			case "s":
+				index, err := strconv.Atoi(cell.Value)
+				if err != nil || index < 0 || index >= len(shared.Items) {
+					return nil, errInvalidXLSX
+				}
+				value = shared.Items[index].String()
+			case "inlineStr":
+				value = cell.Inline.String()
+			case "b":
+				value = map[string]string{"1": "TRUE", "0": "FALSE"}[cell.Value]
+			default:
+				value = cell.Value
+			}
+			for len(values) <= column {
+				values = append(values, "")
+			}
+			values[column] = value
+		}
+		// 只设置了格式的空行不计入
+		if isBlankRow(values) {
+			continue
+		}
+
+		number := row.Number
+		if number == 0 {
+			number = len(rows) + 1
+		}
+		if number <= len(rows) {
+			return nil, errInvalidXLSX
+		}
+		if number > maxXLSXRows {
+			return nil, ErrTooManyQuestions
+		}
+		for len(rows) < number-1 {
+			rows = append(rows, nil)
+		}
+		rows = append(rows, values)
+	}
+	return rows, nil
+}
+
+// firstSheetPath 从工作簿和关系文件中找到第一个工作表的路径
+func firstSheetPath(files map[string]*zip.File) (string, error) {
+	const fallback = "xl/worksheets/sheet1.xml"
+
+	workbookFile, ok := files["xl/workbook.xml"]
+	relsFile, hasRels := files["xl/_rels/workbook.xml.rels"]
+	if !ok || !hasRels {
+		return fallback, nil
+	}
+	var workbook xlsxWorkbook
+	if err := decodeZipXML(workbookFile, &workbook); err != nil {
+		return "", err
+	}
+	var rels xlsxRelationships
+	if err := decodeZipXML(relsFile, &rels); err != nil {
+		return "", err
+	}
+	if len(workbook.Sheets) == 0 {
+		return "", errInvalidXLSX
+	}
+	for _, rel := range rels.Relationships {
+		if rel.ID != workbook.Sheets[0].RelID {
+			continue
+		}
+		if strings.HasPrefix(rel.Target, "/") {
+			return strings.TrimPrefix(rel.Target, "/"), nil
+		}
+		return path.Join("xl", rel.Target), nil
+	}
+	return fallback, nil
+}
+
+// columnIndex 单元格引用（例如"C12"）的列号，A列为0
+func columnIndex(ref string) int {
+	index := 0
+	letters := 0
+	for _, r := range ref {
+		if r < 'A' || r > 'Z' {
+			break
+		}
+		index = index*26 + int(r-'A'+1)
+		letters++
+	}
+	if letters == 0 || letters > 3 {
+		return -1
+	}
+	return index - 1
+}
+
+// decodeZipXML 解码压缩包中的XML文件，限制解压后的大小
+func decodeZipXML(file *zip.File, v interface{}) error {
+	reader, err := file.Open()
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+	return xml.NewDecoder(io.LimitReader(reader, maxXLSXPartSize)).Decode(v)
+}
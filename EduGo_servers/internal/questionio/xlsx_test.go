@@ -0,0 +1,300 @@
+package questionio
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"EduGo_servers/internal/models"
+)
+
+const (
+	xlsxWorkbookXML = `<?xml version="1.0" encoding="UTF-8"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">
+  <sheets><sheet name="题目" sheetId="1" r:id="rId3"/><sheet name="说明" sheetId="2" r:id="rId1"/></sheets>
+</workbook>`
+
+	xlsxRelsXML = `<?xml version="1.0" encoding="UTF-8"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+  <Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>
+  <Relationship Id="rId3" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/questions.xml"/>
+</Relationships>`
+
+	xlsxSharedStringsXML = `<?xml version="1.0" encoding="UTF-8"?>
+<sst xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">
+  <si><t>题型</t></si>
+  <si><t>单选题</t></si>
+  <si><r><t>1 + </t></r><r><rPr><b/></rPr><t>1 = ?</t></r></si>
+</sst>`
+)
+
+// worksheetXML 由若干行组成的工作表
+func worksheetXML(rows ...string) string {
+	return `<?xml version="1.0" encoding="UTF-8"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>` +
+		strings.Join(rows, "") + `</sheetData></worksheet>`
+}
+
+// inlineRow 第number行，按列顺序写入内联字符串，空字符串跳过该列
+func inlineRow(number int, values ...string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, `<row r="%d">`, number)
+	for i, value := range values {
+		if value == "" {
+			continue
+		}
+		fmt.Fprintf(&b, `<c r="%s%d" t="inlineStr"><is><t>%s</t></is></c>`, columnName(i), number, value)
+	}
+	b.WriteString(`</row>`)
+	return b.String()
+}
+
+// columnName 列号对应的字母，A列为0
+func columnName(index int) string {
+	name := ""
+	for index++; index > 0; index = (index - 1) / 26 {
+		name = string(rune('A'+(index-1)%26)) + name
+	}
+	return name
+}
+
+// xlsxArchive 只包含工作表的XLSX文件，工作表位于默认路径
+func xlsxArchive(t *testing.T, rows ...string) []byte {
+	return zipArchive(t, zipFile{"xl/worksheets/sheet1.xml", worksheetXML(rows...)})
+}
+
+var xlsxHeader = []string{"题型", "学科", "题干", "选项A", "选项B", "选项C", "答案", "难度", "知识点", "解析"}
+
+func TestParseXLSX(t *testing.T) {
+	tests := []struct {
+		name  string
+		files []zipFile
+		want  []Draft
+	}{
+		{
+			"每种题型",
+			[]zipFile{{"xl/worksheets/sheet1.xml", worksheetXML(
+				inlineRow(1, xlsxHeader...),
+				inlineRow(2, "单选题", "数学", "1 + 1 = ?", "1", "2", "3", "B", "1", "加法", "1加1等于2"),
+				inlineRow(3, "多选题", "数学", "下列哪些是质数？", "2", "4", "5", "AC", "2", "质数，因数分解"),
+				inlineRow(4, "判断题", "语文", "《红楼梦》的作者是曹雪芹。", "", "", "", "正确"),
+				inlineRow(5, "填空题", "地理", "中国的首都是____，最大的城市是____。", "", "", "", "北京|上海;上海市"),
+				inlineRow(6, "简答题", "物理", "简述牛顿第一定律。", "", "", "", "保持静止或匀速直线运动", "3"),
+			)}},
+			[]Draft{
+				{
+					Source: "第2行", Type: models.QuestionSingleChoice, Subject: "数学", Stem: "1 + 1 = ?", Explanation: "1加1等于2", Difficulty: difficulty(1),
+					Options: []DraftOption{{Content: "1"}, {Content: "2", IsCorrect: true}, {Content: "3"}}, KnowledgePoints: []string{"加法"},
+				},
+				{
+					Source: "第3行", Type: models.QuestionMultipleChoice, Subject: "数学", Stem: "下列哪些是质数？", Difficulty: difficulty(2),
+					Options: []DraftOption{{Content: "2", IsCorrect: true}, {Content: "4"}, {Content: "5", IsCorrect: true}}, KnowledgePoints: []string{"质数", "因数分解"},
+				},
+				{Source: "第4行", Type: models.QuestionTrueFalse, Subject: "语文", Stem: "《红楼梦》的作者是曹雪芹。", Answer: answer(true), KnowledgePoints: []string{}},
+				{
+					Source: "第5行", Type: models.QuestionFillBlank, Subject: "地理", Stem: "中国的首都是____，最大的城市是____。",
+					Blanks: [][]string{{"北京"}, {"上海", "上海市"}}, KnowledgePoints: []string{},
+				},
+				{
+					Source: "第6行", Type: models.QuestionShortAnswer, Subject: "物理", Stem: "简述牛顿第一定律。", Difficulty: difficulty(3),
+					ReferenceAnswer: "保持静止或匀速直线运动", KnowledgePoints: []string{},
+				},
+			},
+		},
+		{
+			"按工作簿关系读取第一个工作表和共享字符串",
+			[]zipFile{
+				{"xl/workbook.xml", xlsxWorkbookXML},
+				{"xl/_rels/workbook.xml.rels", xlsxRelsXML},
+				{"xl/sharedStrings.xml", xlsxSharedStringsXML},
+				{"xl/worksheets/sheet1.xml", worksheetXML(inlineRow(1, "说明"))},
+				{"xl/worksheets/questions.xml", worksheetXML(
+					`<row r="1"><c r="A1" t="s"><v>0</v></c><c r="B1" t="inlineStr"><is><t>题干</t></is></c>`+
+						`<c r="C1" t="inlineStr"><is><t>答案</t></is></c></row>`,
+					`<row r="2"><c r="A2" t="s"><v>1</v></c><c r="B2" t="s"><v>2</v></c></row>`,
+					`<row r="3"><c r="A3" t="inlineStr"><is><t>判断题</t></is></c><c r="B3"><v>2</v></c><c r="C3" t="b"><v>0</v></c></row>`,
+				)},
+			},
+			[]Draft{
+				{Source: "第2行", Type: models.QuestionSingleChoice, Stem: "1 + 1 = ?", KnowledgePoints: []string{}, Error: "选择题需要填写答案"},
+				{Source: "第3行", Type: models.QuestionTrueFalse, Stem: "2", Answer: answer(false), KnowledgePoints: []string{}},
+			},
+		},
+		{
+			"跳过空行并保留表格中的行号",
+			[]zipFile{{"xl/worksheets/sheet1.xml", worksheetXML(
+				`<row r="2"><c r="A2" s="1"/></row>`,
+				inlineRow(3, "题型", "", "题干"),
+				`<row r="4"/>`,
+				inlineRow(7, "简答题", "", "什么是光合作用？"),
+				`<row><c t="inlineStr"><is><t>简答题</t></is></c><c/><c t="inlineStr"><is><t>没有行号</t></is></c></row>`,
+			)}},
+			[]Draft{
+				{Source: "第7行", Type: models.QuestionShortAnswer, Stem: "什么是光合作用？", KnowledgePoints: []string{}},
+				{Source: "第8行", Type: models.QuestionShortAnswer, Stem: "没有行号", KnowledgePoints: []string{}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			drafts, err := ParseSpreadsheet("questions.xlsx", zipArchive(t, tt.files...))
+			if err != nil {
+				t.Fatalf("ParseSpreadsheet() error = %v", err)
+			}
+			checkDrafts(t, drafts, tt.want)
+		})
+	}
+}
+
+func TestParseXLSXInvalid(t *testing.T) {
+	// 解压后超过大小限制的工作表，压缩后很小
+	oversized := worksheetXML(inlineRow(1, "题型", "题干") + strings.Repeat(" ", maxXLSXPartSize))
+	tests := []struct {
+		name  string
+		data  func(t *testing.T) []byte
+		check func(err error) bool
+	}{
+		{
+			"不是zip文件",
+			func(t *testing.T) []byte { return []byte("not a zip archive") },
+			func(err error) bool { return err != nil },
+		},
+		{
+			"没有工作表",
+			func(t *testing.T) []byte { return zipArchive(t, zipFile{"xl/styles.xml", "<styleSheet/>"}) },
+			func(err error) bool { return errors.Is(err, errInvalidXLSX) },
+		},
+		{
+			"工作簿中没有工作表",
+			func(t *testing.T) []byte {
+				return zipArchive(t,
+					zipFile{"xl/workbook.xml", "<workbook><sheets/></workbook>"},
+					zipFile{"xl/_rels/workbook.xml.rels", xlsxRelsXML},
+					zipFile{"xl/worksheets/sheet1.xml", worksheetXML(inlineRow(1, "题型", "题干"))},
+				)
+			},
+			func(err error) bool { return errors.Is(err, errInvalidXLSX) },
+		},
+		{
+			"工作表格式错误",
+			func(t *testing.T) []byte {
+				return zipArchive(t, zipFile{"xl/worksheets/sheet1.xml", "<worksheet><sheetData><row>"})
+			},
+			func(err error) bool { return err != nil },
+		},
+		{
+			"共享字符串下标越界",
+			func(t *testing.T) []byte {
+				return zipArchive(t,
+					zipFile{"xl/sharedStrings.xml", xlsxSharedStringsXML},
+					zipFile{"xl/worksheets/sheet1.xml", worksheetXML(`<row r="1"><c r="A1" t="s"><v>3</v></c></row>`)},
+				)
+			},
+			func(err error) bool { return errors.Is(err, errInvalidXLSX) },
+		},
+		{
+			"列数超过上限",
+			func(t *testing.T) []byte {
+				return xlsxArchive(t, `<row r="1"><c r="CW1" t="inlineStr"><is><t>题型</t></is></c></row>`)
+			},
+			func(err error) bool { return errors.Is(err, errInvalidXLSX) },
+		},
+		{
+			"无效的单元格引用",
+			func(t *testing.T) []byte {
+				return xlsxArchive(t, `<row r="1"><c r="1A" t="inlineStr"><is><t>题型</t></is></c></row>`)
+			},
+			func(err error) bool { return errors.Is(err, errInvalidXLSX) },
+		},
+		{
+			"行号不是递增的",
+			func(t *testing.T) []byte {
+				return xlsxArchive(t, inlineRow(3, "题型", "题干"), inlineRow(2, "简答题", "题干"))
+			},
+			func(err error) bool { return errors.Is(err, errInvalidXLSX) },
+		},
+		{
+			"行号超过上限",
+			func(t *testing.T) []byte {
+				return xlsxArchive(t, inlineRow(1, "题型", "题干"), inlineRow(maxXLSXRows+1, "简答题", "题干"))
+			},
+			func(err error) bool { return errors.Is(err, ErrTooManyQuestions) },
+		},
+		{
+			"工作表解压后过大",
+			func(t *testing.T) []byte { return zipArchive(t, zipFile{"xl/worksheets/sheet1.xml", oversized}) },
+			func(err error) bool { return err != nil },
+		},
+		{
+			"题目超过上限",
+			func(t *testing.T) []byte {
+				rows := []string{inlineRow(1, "题型", "题干")}
+				for i := 0; i <= MaxImportQuestions; i++ {
+					rows = append(rows, inlineRow(i+2, "简答题", fmt.Sprintf("第%d题", i+1)))
+				}
+				return xlsxArchive(t, rows...)
+			},
+			func(err error) bool { return errors.Is(err, ErrTooManyQuestions) },
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			drafts, err := ParseSpreadsheet("questions.xlsx", tt.data(t))
+			if !tt.check(err) {
+				t.Fatalf("ParseSpreadsheet() error = %v", err)
+			}
+			if drafts != nil {
+				t.Errorf("ParseSpreadsheet() = %d drafts, want none", len(drafts))
+			}
+		})
+	}
+}
+
+func TestParseXLSXMaxQuestions(t *testing.T) {
+	rows := []string{inlineRow(1, "题型", "题干")}
+	for i := 0; i < MaxImportQuestions; i++ {
+		rows = append(rows, inlineRow(i+2, "简答题", fmt.Sprintf("第%d题", i+1)))
+	}
+	drafts, err := ParseSpreadsheet("questions.xlsx", xlsxArchive(t, rows...))
+	if err != nil {
+		t.Fatalf("ParseSpreadsheet() error = %v", err)
+	}
+	if len(drafts) != MaxImportQuestions {
+		t.Errorf("got %d drafts, want %d", len(drafts), MaxImportQuestions)
+	}
+}
+
+func TestColumnIndex(t *testing.T) {
+	tests := []struct {
+		ref  string
+		want int
+	}{
+		{"A1", 0}, {"Z9", 25}, {"AA10", 26}, {"CV1", 99}, {"XFD1048576", 16383}, {"1A", -1}, {"ABCD1", -1},
+	}
+	for _, tt := range tests {
+		if got := columnIndex(tt.ref); got != tt.want {
+			t.Errorf("columnIndex(%q) = %d, want %d", tt.ref, got, tt.want)
+		}
+	}
+}
+
+func TestSpreadsheetRoundTrip(t *testing.T) {
+	var buf strings.Builder
+	if err := WriteSpreadsheet(&buf, sampleQuestions()); err != nil {
+		t.Fatalf("WriteSpreadsheet() error = %v", err)
+	}
+	drafts, err := ParseSpreadsheet("questions.csv", []byte(buf.String()))
+	if err != nil {
+		t.Fatalf("ParseSpreadsheet() error = %v", err)
+	}
+
+	want := sampleDrafts()
+	for i := range want {
+		want[i].Source = fmt.Sprintf("第%d行", i+2)
+		if want[i].KnowledgePoints == nil {
+			want[i].KnowledgePoints = []string{}
+		}
+	}
+	checkDrafts(t, drafts, want)
+}
@@ -0,0 +1,122 @@
+// Package recommend 根据学生的薄弱知识点、近期学习情况和相似同学的反馈，推荐学习资源和练习题
+//
+// 候选项由调用方按学生的知识点收集，排序策略给每个候选项打分并给出推荐理由。
+// 排序策略可以替换，默认按各项信号加权求和。
+package recommend
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// 推荐项的类型
+const (
+	ItemResource = "resource" // 资源库中的学习资源
+	ItemQuestion = "question" // 练习题
+)
+
+// 推荐理由
+const (
+	ReasonWeakPoint = "weak_point" // 知识点掌握度低
+	ReasonMistake   = "mistake"    // 知识点上有没有订正的错题
+	ReasonRecent    = "recent"     // 最近在学习的知识点
+	ReasonPeers     = "peers"      // 情况相似的同学认为有用
+	ReasonTeacher   = "teacher"    // 教师推荐
+)
+
+// IsValidItemType 检查推荐项的类型是否有效
+func IsValidItemType(itemType string) bool {
+	return itemType == ItemResource || itemType == ItemQuestion
+}
+
+// RecentWindow 最近学习的时间范围
+const RecentWindow = 14 * 24 * time.Hour
+
+// ItemKey 推荐项的类型和ID
+type ItemKey struct {
+	Type string
+	ID   int64
+}
+
+// Candidate 候选推荐项
+type Candidate struct {
+	ItemKey
+	KnowledgePointIDs []int64 // 候选项涉及的知识点
+}
+
+// Profile 学生的学习情况，排序策略根据它给候选项打分
+type Profile struct {
+	Mastery     map[int64]float64   // 有掌握度记录的知识点的掌握概率
+	Mistakes    map[int64]int       // 知识点上没有订正的错题数
+	Recent      map[int64]time.Time // 最近作答过的知识点及最后一次作答的时间
+	PeerHelpful map[ItemKey]int     // 情况相似的同学认为有用的人数
+	Now         time.Time
+}
+
+// Reason 推荐理由，由调用方根据知识点名称等生成说明
+type Reason struct {
+	Kind             string
+	KnowledgePointID int64   // weak_point、mistake、recent涉及的知识点
+	Value            float64 // weak_point为掌握概率，mistake为错题数，peers为人数
+}
+
+// Recommendation 打分后的推荐项
+type Recommendation struct {
+	Candidate
+	Score   float64
+	Reasons []Reason
+}
+
+// Strategy 排序策略
+type Strategy interface {
+	// Rank 给候选项打分，返回值得推荐的候选项，按分数从高到低排列
+	Rank(profile *Profile, candidates []Candidate) []Recommendation
+}
+
+// DefaultStrategy 默认的排序策略
+const DefaultStrategy = "weighted"
+
+var strategies = map[string]Strategy{
+	DefaultStrategy: DefaultWeights,
+}
+
+// Current 当前使用的排序策略，由InitStrategy设置
+var Current = DefaultStrategy
+
+// RegisterStrategy 注册排序策略，应在InitStrategy之前调用
+func RegisterStrategy(name string, strategy Strategy) {
+	strategies[name] = strategy
+}
+
+// GetStrategy 获取排序策略
+func GetStrategy(name string) (Strategy, bool) {
+	strategy, ok := strategies[name]
+	return strategy, ok
+}
+
+// InitStrategy 设置当前使用的排序策略，name为空时使用默认策略
+func InitStrategy(name string) error {
+	if name == "" {
+		name = DefaultStrategy
+	}
+	if _, ok := strategies[name]; !ok {
+		return fmt.Errorf("unknown recommendation strategy: %s", name)
+	}
+	Current = name
+	return nil
+}
+
+// Sort 按分数从高到低排列推荐项，分数相同时按类型和ID排列，保证结果稳定
+func Sort(recommendations []Recommendation) {
+	sort.SliceStable(recommendations, func(i, j int) bool {
+		a, b := recommendations[i], recommendations[j]
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		if a.Type != b.Type {
+			return a.Type < b.Type
+		}
+		return a.ID < b.ID
+	})
+}
@@ -0,0 +1,68 @@
+package recommend
+
+import "EduGo_servers/internal/mastery"
+
+// Weighted 加权排序策略：分别计算薄弱程度、错题、近期学习和同学反馈的得分，按权重求和
+type Weighted struct {
+	Weak    float64
+	Mistake float64
+	Recent  float64
+	Peer    float64
+}
+
+// DefaultWeights 默认的权重，薄弱知识点最重要
+var DefaultWeights = Weighted{Weak: 1, Mistake: 0.6, Recent: 0.3, Peer: 0.4}
+
+// maxMistakeSignal 错题数达到该值后得分不再增加
+const maxMistakeSignal = 3
+
+// Rank 给候选项打分，每项信号取候选项涉及的知识点中最强的一个
+func (w Weighted) Rank(profile *Profile, candidates []Candidate) []Recommendation {
+	var result []Recommendation
+	for _, candidate := range candidates {
+		recommendation := Recommendation{Candidate: candidate}
+
+		var weak, mistake, recent *Reason
+		var recentScore float64
+		for _, pointID := range candidate.KnowledgePointIDs {
+			probability, known := profile.Mastery[pointID]
+			if known && probability < mastery.WeakThreshold && (weak == nil || probability < weak.Value) {
+				weak = &Reason{Kind: ReasonWeakPoint, KnowledgePointID: pointID, Value: probability}
+			}
+			if count := profile.Mistakes[pointID]; count > 0 && (mistake == nil || float64(count) > mistake.Value) {
+				mistake = &Reason{Kind: ReasonMistake, KnowledgePointID: pointID, Value: float64(count)}
+			}
+			// 已掌握的知识点不再因为近期学习而推荐
+			if at, ok := profile.Recent[pointID]; ok && (!known || probability < mastery.MasteredThreshold) {
+				age := profile.Now.Sub(at)
+				if score := 1 - float64(age)/float64(RecentWindow); age >= 0 && score > recentScore {
+					recentScore = score
+					recent = &Reason{Kind: ReasonRecent, KnowledgePointID: pointID}
+				}
+			}
+		}
+
+		if weak != nil {
+			recommendation.Score += w.Weak * (1 - weak.Value)
+			recommendation.Reasons = append(recommendation.Reasons, *weak)
+		}
+		if mistake != nil {
+			recommendation.Score += w.Mistake * min(mistake.Value, maxMistakeSignal) / maxMistakeSignal
+			recommendation.Reasons = append(recommendation.Reasons, *mistake)
+		}
+		if recent != nil {
+			recommendation.Score += w.Recent * recentScore
+			recommendation.Reasons = append(recommendation.Reasons, *recent)
+		}
+		if peers := profile.PeerHelpful[candidate.ItemKey]; peers > 0 {
+			recommendation.Score += w.Peer * float64(peers) / float64(peers+2)
+			recommendation.Reasons = append(recommendation.Reasons, Reason{Kind: ReasonPeers, Value: float64(peers)})
+		}
+
+		if recommendation.Score > 0 {
+			result = append(result, recommendation)
+		}
+	}
+	Sort(result)
+	return result
+}
@@ -0,0 +1,327 @@
+// Package reportcard 汇总学生一个学期的成绩、考勤和教师评语，按模板生成HTML或PDF成绩单
+package reportcard
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"gorm.io/gorm"
+
+	"EduGo_servers/internal/grading"
+	"EduGo_servers/internal/models"
+	"EduGo_servers/internal/repository"
+	"EduGo_servers/internal/storage"
+)
+
+// ErrNotFound 学生、学期或模板不存在
+var ErrNotFound = errors.New("reportcard: not found")
+
+// Options 成绩单的版式设置，来自成绩单模板
+type Options struct {
+	Title          string
+	SchoolName     string
+	Footer         string
+	ShowCategories bool
+	ShowAttendance bool
+	ShowComments   bool
+	HTML           string // 自定义HTML模板，为空时使用默认版式
+}
+
+// DefaultOptions 没有配置模板时使用的默认版式
+func DefaultOptions() Options {
+	return Options{
+		Title:          "学生成绩报告单",
+		ShowCategories: true,
+		ShowAttendance: true,
+		ShowComments:   true,
+	}
+}
+
+// OptionsFromTemplate 根据成绩单模板生成版式设置
+func OptionsFromTemplate(template *models.ReportCardTemplate) Options {
+	options := Options{
+		Title:          template.Title,
+		SchoolName:     template.SchoolName,
+		Footer:         template.Footer,
+		ShowCategories: template.ShowCategories,
+		ShowAttendance: template.ShowAttendance,
+		ShowComments:   template.ShowComments,
+		HTML:           template.HTML,
+	}
+	if options.Title == "" {
+		options.Title = DefaultOptions().Title
+	}
+	return options
+}
+
+// ResolveOptions 获取模板对应的版式设置，templateID为空时使用默认模板，没有默认模板时使用内置版式
+// 指定的模板不存在时返回ErrNotFound
+func ResolveOptions(ctx context.Context, db *gorm.DB, templateID *int64) (Options, error) {
+	reportCardRepo := repository.NewReportCardRepository(db)
+	var template *models.ReportCardTemplate
+	var err error
+	if templateID != nil {
+		template, err = reportCardRepo.GetTemplateByID(ctx, *templateID)
+		if err == nil && template == nil {
+			err = ErrNotFound
+		}
+	} else {
+		template, err = reportCardRepo.GetDefaultTemplate(ctx)
+	}
+	if err != nil {
+		return Options{}, err
+	}
+	if template == nil {
+		return DefaultOptions(), nil
+	}
+	return OptionsFromTemplate(template), nil
+}
+
+// Render 按格式生成成绩单
+func Render(w io.Writer, format string, options Options, data *Data) error {
+	if format == models.ReportCardFormatHTML {
+		return RenderHTML(w, options, data)
+	}
+	return RenderPDF(w, options, data)
+}
+
+// FileName 成绩单的文件名
+func FileName(data *Data, format string) string {
+	return storage.SafeFileName(fmt.Sprintf("%s_%s_%s.%s", data.Term.Name, data.Student.Username, data.Student.Name, format))
+}
+
+// Student 成绩单上的学生信息
+type Student struct {
+	ID       int64
+	Name     string
+	Username string
+}
+
+// Term 成绩单对应的学期
+type Term struct {
+	ID        int64
+	Name      string
+	StartDate time.Time
+	EndDate   time.Time
+}
+
+// CourseResult 一门课程的成绩
+type CourseResult struct {
+	OfferingID    int64
+	CourseCode    string
+	CourseName    string
+	Teachers      string
+	Graded        bool
+	Final         bool // 是否为教师已计算保存的学期总评，否则为按当前得分计算的结果
+	Percent       float64
+	Label         string
+	GradePoint    float64
+	HasGradePoint bool // 评分制是否有绩点（百分制没有）
+	Categories    []grading.CategoryResult
+	Comment       string
+	CommentBy     string
+}
+
+// Attendance 学期考勤汇总
+type Attendance struct {
+	Total       int64
+	Present     int64
+	Late        int64
+	Absent      int64
+	Excused     int64
+	AbsenceRate float64 // (absent + excused) / total
+}
+
+// Data 一名学生一个学期的成绩单内容
+type Data struct {
+	Student        Student
+	ClassName      string
+	Term           Term
+	Courses        []CourseResult
+	GPA            float64
+	HasGPA         bool
+	Attendance     Attendance
+	GeneralComment string
+	CommentBy      string
+	GeneratedAt    time.Time
+}
+
+// displayName 用户的显示名称，未填写姓名时使用用户名
+func displayName(user *models.User) string {
+	if user == nil {
+		return ""
+	}
+	if name := user.LastName + user.FirstName; name != "" {
+		return name
+	}
+	return user.Username
+}
+
+// Build 汇总学生在学期中的成绩、考勤和评语
+// 已保存学期总评的课程使用保存的结果，其余课程按当前得分计算
+func Build(ctx context.Context, db *gorm.DB, studentID, termID int64) (*Data, error) {
+	student, err := repository.NewUserRepository(db).GetUserByID(ctx, studentID)
+	if err != nil {
+		return nil, err
+	}
+	term, err := repository.NewAcademicTermRepository(db).GetTermByID(ctx, termID)
+	if err != nil {
+		return nil, err
+	}
+	if student == nil || term == nil {
+		return nil, ErrNotFound
+	}
+
+	data := &Data{
+		Student:     Student{ID: student.ID, Name: displayName(student), Username: student.Username},
+		Term:        Term{ID: term.ID, Name: term.Name, StartDate: term.StartDate, EndDate: term.EndDate},
+		Courses:     []CourseResult{},
+		GeneratedAt: time.Now(),
+	}
+
+	classRepo := repository.NewClassRepository(db)
+	classes, err := classRepo.GetStudentClasses(ctx, []int64{studentID})
+	if err != nil {
+		return nil, err
+	}
+	if classID, ok := classes[studentID]; ok {
+		class, err := classRepo.GetClassByID(ctx, classID)
+		if err != nil {
+			return nil, err
+		}
+		if class != nil {
+			data.ClassName = class.GradeLevel + class.Name
+		}
+	}
+
+	comments, err := repository.NewReportCardRepository(db).GetComments(ctx, studentID, termID)
+	if err != nil {
+		return nil, err
+	}
+	commentsByOffering := make(map[int64]*models.ReportCardComment, len(comments))
+	for _, comment := range comments {
+		commentsByOffering[comment.OfferingID] = comment
+	}
+	if comment := commentsByOffering[0]; comment != nil {
+		data.GeneralComment = comment.Content
+		data.CommentBy = displayName(comment.Author)
+	}
+
+	if err := buildCourses(ctx, db, data, studentID, termID, commentsByOffering); err != nil {
+		return nil, err
+	}
+
+	counts, err := repository.NewAttendanceRepository(db).CountAttendance(ctx, &repository.AttendanceQuery{StudentID: studentID, TermID: termID})
+	if err != nil {
+		return nil, err
+	}
+	for _, count := range counts {
+		data.Attendance.Total += count.Count
+		switch count.Status {
+		case models.AttendancePresent:
+			data.Attendance.Present += count.Count
+		case models.AttendanceLate:
+			data.Attendance.Late += count.Count
+		case models.AttendanceAbsent:
+			data.Attendance.Absent += count.Count
+		case models.AttendanceExcused:
+			data.Attendance.Excused += count.Count
+		}
+	}
+	if data.Attendance.Total > 0 {
+		data.Attendance.AbsenceRate = float64(data.Attendance.Absent+data.Attendance.Excused) / float64(data.Attendance.Total)
+	}
+	return data, nil
+}
+
+func buildCourses(ctx context.Context, db *gorm.DB, data *Data, studentID, termID int64, comments map[int64]*models.ReportCardComment) error {
+	offerings, err := repository.NewCourseOfferingRepository(db).GetStudentOfferings(ctx, studentID, termID)
+	if err != nil {
+		return err
+	}
+
+	gradebookRepo := repository.NewGradebookRepository(db)
+	termGrades, err := gradebookRepo.GetStudentTermGrades(ctx, studentID, termID)
+	if err != nil {
+		return err
+	}
+	finalGrades := make(map[int64]*models.TermGrade, len(termGrades))
+	for _, grade := range termGrades {
+		finalGrades[grade.OfferingID] = grade
+	}
+
+	var totalPoints float64
+	var pointCourses int
+	for _, offering := range offerings {
+		result := CourseResult{OfferingID: offering.ID}
+		if offering.Course != nil {
+			result.CourseCode = offering.Course.Code
+			result.CourseName = offering.Course.Name
+		}
+		for i, teacher := range offering.Teachers {
+			if i > 0 {
+				result.Teachers += "、"
+			}
+			result.Teachers += displayName(&teacher)
+		}
+
+		scale := &models.GradingScale{Type: models.GradingScalePercentage}
+		if offering.GradingScaleID != nil {
+			configured, err := gradebookRepo.GetGradingScaleByID(ctx, *offering.GradingScaleID)
+			if err != nil {
+				return err
+			}
+			if configured != nil {
+				scale = configured
+			}
+		}
+		result.HasGradePoint = scale.Type != models.GradingScalePercentage && len(scale.Bands) > 0
+
+		categories, err := gradebookRepo.GetCategories(ctx, offering.ID)
+		if err != nil {
+			return err
+		}
+		items, err := gradebookRepo.GetItems(ctx, offering.ID)
+		if err != nil {
+			return err
+		}
+		entries, err := gradebookRepo.GetEntries(ctx, offering.ID, studentID)
+		if err != nil {
+			return err
+		}
+		computed := grading.Compute(studentID, categories, items, entries)
+		result.Categories = computed.Categories
+
+		if grade := finalGrades[offering.ID]; grade != nil {
+			result.Graded = true
+			result.Final = true
+			result.Percent = grade.Percent
+			result.Label = grade.Label
+			result.GradePoint = grade.GradePoint
+		} else if computed.Graded {
+			result.Graded = true
+			result.Percent = computed.Percent
+			result.Label, result.GradePoint = scale.Convert(computed.Percent)
+		}
+
+		if comment := comments[offering.ID]; comment != nil {
+			result.Comment = comment.Content
+			result.CommentBy = displayName(comment.Author)
+		}
+
+		if result.Graded && result.HasGradePoint {
+			totalPoints += result.GradePoint
+			pointCourses++
+		}
+		data.Courses = append(data.Courses, result)
+	}
+
+	if pointCourses > 0 {
+		data.HasGPA = true
+		data.GPA = totalPoints / float64(pointCourses)
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+package reportcard
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"time"
+)
+
+// view 模板中可以使用的数据：版式设置和成绩单内容的字段都可以直接访问，例如 {{.Title}}、{{.Student.Name}}
+type view struct {
+	Options
+	*Data
+}
+
+var templateFuncs = template.FuncMap{
+	"percent": func(value float64) string { return fmt.Sprintf("%.1f", value) },
+	"rate":    func(value float64) string { return fmt.Sprintf("%.1f%%", value*100) },
+	"date":    func(layout string, value time.Time) string { return value.Format(layout) },
+}
+
+const defaultHTML = `<!DOCTYPE html>
+<html lang="zh-CN">
+<head>
+<meta charset="utf-8">
+<title>{{.Title}} - {{.Student.Name}}</title>
+<style>
+body { font-family: "Songti SC", "SimSun", serif; margin: 40px; color: #222; }
+h1, h2 { text-align: center; margin: 4px 0; }
+.info { display: flex; justify-content: space-between; margin: 16px 0; }
+table { width: 100%; border-collapse: collapse; margin: 12px 0; }
+th, td { border: 1px solid #555; padding: 6px; text-align: center; }
+td.left { text-align: left; }
+.categories { font-size: 12px; color: #555; }
+.comment { margin: 8px 0; white-space: pre-wrap; }
+.footer { margin-top: 32px; font-size: 12px; text-align: right; }
+@media print { body { margin: 0; } }
+</style>
+</head>
+<body>
+{{if .SchoolName}}<h2>{{.SchoolName}}</h2>{{end}}
+<h1>{{.Title}}</h1>
+<div class="info">
+  <span>姓名：{{.Student.Name}}</span>
+  <span>学号：{{.Student.Username}}</span>
+  {{if .ClassName}}<span>班级：{{.ClassName}}</span>{{end}}
+  <span>学期：{{.Term.Name}}（{{date "2006-01-02" .Term.StartDate}} 至 {{date "2006-01-02" .Term.EndDate}}）</span>
+</div>
+<table>
+  <tr><th>课程</th><th>任课教师</th><th>总评</th><th>等级</th><th>绩点</th></tr>
+  {{range .Courses}}
+  <tr>
+    <td class="left">{{.CourseName}}
+      {{if and $.ShowCategories .Categories}}<div class="categories">{{range .Categories}}{{if .Graded}}{{.Name}} {{percent .Percent}}%（权重{{.Weight}}） {{end}}{{end}}</div>{{end}}
+    </td>
+    <td>{{.Teachers}}</td>
+    {{if .Graded}}
+    <td>{{percent .Percent}}{{if not .Final}}（暂定）{{end}}</td>
+    <td>{{.Label}}</td>
+    <td>{{if .HasGradePoint}}{{.GradePoint}}{{else}}-{{end}}</td>
+    {{else}}
+    <td>-</td><td>-</td><td>-</td>
+    {{end}}
+  </tr>
+  {{if and $.ShowComments .Comment}}<tr><td class="left" colspan="5">任课教师评语（{{.CommentBy}}）：{{.Comment}}</td></tr>{{end}}
+  {{else}}
+  <tr><td colspan="5">本学期没有课程</td></tr>
+  {{end}}
+</table>
+{{if .HasGPA}}<p>平均绩点：{{printf "%.2f" .GPA}}</p>{{end}}
+{{if .ShowAttendance}}
+<table>
+  <tr><th>应到</th><th>出勤</th><th>迟到</th><th>缺勤</th><th>请假</th><th>缺勤率</th></tr>
+  <tr><td>{{.Attendance.Total}}</td><td>{{.Attendance.Present}}</td><td>{{.Attendance.Late}}</td><td>{{.Attendance.Absent}}</td><td>{{.Attendance.Excused}}</td><td>{{rate .Attendance.AbsenceRate}}</td></tr>
+</table>
+{{end}}
+{{if and .ShowComments .GeneralComment}}<div class="comment"><strong>班主任评语（{{.CommentBy}}）：</strong>{{.GeneralComment}}</div>{{end}}
+<div class="footer">{{if .Footer}}{{.Footer}}<br>{{end}}生成时间：{{date "2006-01-02 15:04" .GeneratedAt}}</div>
+</body>
+</html>
+`
+
+// ParseHTMLTemplate 解析自定义HTML模板，并用示例数据检查模板中引用的字段是否存在
+func ParseHTMLTemplate(source string) (*template.Template, error) {
+	tmpl, err := template.New("report_card").Funcs(templateFuncs).Parse(source)
+	if err != nil {
+		return nil, err
+	}
+	sample := &Data{Courses: []CourseResult{{CourseName: "示例课程", Graded: true}}}
+	if err := tmpl.Execute(io.Discard, view{Options: DefaultOptions(), Data: sample}); err != nil {
+		return nil, err
+	}
+	return tmpl, nil
+}
+
+// RenderHTML 按版式设置生成HTML成绩单
+func RenderHTML(w io.Writer, options Options, data *Data) error {
+	source := options.HTML
+	if source == "" {
+		source = defaultHTML
+	}
+	tmpl, err := template.New("report_card").Funcs(templateFuncs).Parse(source)
+	if err != nil {
+		return err
+	}
+	return tmpl.Execute(w, view{Options: options, Data: data})
+}
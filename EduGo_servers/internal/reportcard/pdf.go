@@ -0,0 +1,264 @@
+package reportcard
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf16"
+)
+
+// 页面尺寸（A4，单位为点）和边距
+const (
+	pageWidth  = 595.0
+	pageHeight = 842.0
+	pageMargin = 50.0
+)
+
+// pdfDocument 生成只包含文字和线条的简单PDF
+// 使用阅读器内置的STSong-Light中文字体（Adobe-GB1），不需要嵌入字体文件
+type pdfDocument struct {
+	pages []*bytes.Buffer
+	page  *bytes.Buffer
+	y     float64 // 当前行的基线位置，从页面顶部向下排版
+}
+
+func newPDFDocument() *pdfDocument {
+	doc := &pdfDocument{}
+	doc.addPage()
+	return doc
+}
+
+func (d *pdfDocument) addPage() {
+	d.page = &bytes.Buffer{}
+	d.pages = append(d.pages, d.page)
+	d.y = pageHeight - pageMargin
+}
+
+// ensureSpace 当前页剩余空间不足height时换页
+func (d *pdfDocument) ensureSpace(height float64) {
+	if d.y-height < pageMargin {
+		d.addPage()
+	}
+}
+
+// textWidth 估算文字宽度：半角字符为字号的一半，其余为一个字号
+func textWidth(text string, size float64) float64 {
+	var width float64
+	for _, r := range text {
+		if r < 0x80 {
+			width += size / 2
+		} else {
+			width += size
+		}
+	}
+	return width
+}
+
+// wrapText 按宽度把文字拆成多行
+func wrapText(text string, size, maxWidth float64) []string {
+	var lines []string
+	for _, paragraph := range strings.Split(text, "\n") {
+		var line []rune
+		var width float64
+		for _, r := range paragraph {
+			w := textWidth(string(r), size)
+			if width+w > maxWidth && len(line) > 0 {
+				lines = append(lines, string(line))
+				line, width = nil, 0
+			}
+			line = append(line, r)
+			width += w
+		}
+		lines = append(lines, string(line))
+	}
+	return lines
+}
+
+// encodeText 把文字编码为UCS-2大端序的十六进制字符串，超出基本平面的字符替换为问号
+func encodeText(text string) string {
+	var buf strings.Builder
+	for _, r := range text {
+		if r > 0xFFFF || utf16.IsSurrogate(r) {
+			r = '?'
+		}
+		fmt.Fprintf(&buf, "%04X", r)
+	}
+	return buf.String()
+}
+
+// text 在当前行的x位置输出文字
+func (d *pdfDocument) text(x, size float64, text string) {
+	if text == "" {
+		return
+	}
+	fmt.Fprintf(d.page, "BT /F1 %.1f Tf %.1f %.1f Td <%s> Tj ET\n", size, x, d.y, encodeText(text))
+}
+
+// centered 输出居中的一行文字并换行
+func (d *pdfDocument) centered(size float64, text string) {
+	d.ensureSpace(size * 1.6)
+	d.text((pageWidth-textWidth(text, size))/2, size, text)
+	d.y -= size * 1.6
+}
+
+// paragraph 从x位置开始输出自动换行的文字
+func (d *pdfDocument) paragraph(x, size float64, text string) {
+	for _, line := range wrapText(text, size, pageWidth-pageMargin-x) {
+		d.ensureSpace(size * 1.5)
+		d.text(x, size, line)
+		d.y -= size * 1.5
+	}
+}
+
+// cells 输出一行表格的各列文字，columns为各列的起始x位置
+func (d *pdfDocument) cells(size float64, columns []float64, values ...string) {
+	d.ensureSpace(size * 1.8)
+	for i, value := range values {
+		d.text(columns[i], size, value)
+	}
+	d.y -= size * 1.5
+}
+
+// separator 在表格行下方画线并留出间距
+func (d *pdfDocument) separator() {
+	d.y += 4
+	d.rule()
+	d.y -= 12
+}
+
+// row 输出一行带下划线的表格
+func (d *pdfDocument) row(size float64, columns []float64, values ...string) {
+	d.cells(size, columns, values...)
+	d.separator()
+}
+
+// rule 在当前位置画一条横线
+func (d *pdfDocument) rule() {
+	fmt.Fprintf(d.page, "0.5 w %.1f %.1f m %.1f %.1f l S\n", pageMargin, d.y, pageWidth-pageMargin, d.y)
+}
+
+// WriteTo 输出完整的PDF文件
+func (d *pdfDocument) WriteTo(w io.Writer) (int64, error) {
+	var out bytes.Buffer
+	var offsets []int
+	object := func(body string) {
+		offsets = append(offsets, out.Len())
+		fmt.Fprintf(&out, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+
+	// 1: 目录 2: 页面树 3-5: 字体 之后每页两个对象（页面和内容）
+	const firstPage = 6
+	kids := make([]string, len(d.pages))
+	for i := range d.pages {
+		kids[i] = fmt.Sprintf("%d 0 R", firstPage+i*2)
+	}
+
+	out.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+	object("<< /Type /Catalog /Pages 2 0 R >>")
+	object(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(d.pages)))
+	object("<< /Type /Font /Subtype /Type0 /BaseFont /STSong-Light /Encoding /UniGB-UCS2-H /DescendantFonts [4 0 R] >>")
+	object("<< /Type /Font /Subtype /CIDFontType0 /BaseFont /STSong-Light " +
+		"/CIDSystemInfo << /Registry (Adobe) /Ordering (GB1) /Supplement 2 >> /FontDescriptor 5 0 R /DW 1000 /W [1 95 500] >>")
+	object("<< /Type /FontDescriptor /FontName /STSong-Light /Flags 6 /FontBBox [-25 -254 1000 880] " +
+		"/ItalicAngle 0 /Ascent 880 /Descent -120 /CapHeight 880 /StemV 93 >>")
+	for i, page := range d.pages {
+		object(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.0f %.0f] /Resources << /Font << /F1 3 0 R >> >> /Contents %d 0 R >>",
+			pageWidth, pageHeight, firstPage+i*2+1))
+		object(fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", page.Len(), page.String()))
+	}
+
+	xref := out.Len()
+	fmt.Fprintf(&out, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&out, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&out, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+	return out.WriteTo(w)
+}
+
+// RenderPDF 按版式设置生成PDF成绩单
+func RenderPDF(w io.Writer, options Options, data *Data) error {
+	doc := newPDFDocument()
+	if options.SchoolName != "" {
+		doc.centered(14, options.SchoolName)
+	}
+	doc.centered(18, options.Title)
+	doc.y -= 6
+
+	info := fmt.Sprintf("姓名：%s    学号：%s", data.Student.Name, data.Student.Username)
+	if data.ClassName != "" {
+		info += "    班级：" + data.ClassName
+	}
+	doc.paragraph(pageMargin, 11, info)
+	doc.paragraph(pageMargin, 11, fmt.Sprintf("学期：%s（%s 至 %s）",
+		data.Term.Name, data.Term.StartDate.Format("2006-01-02"), data.Term.EndDate.Format("2006-01-02")))
+	doc.y -= 6
+
+	columns := []float64{pageMargin, 230, 360, 430, 490}
+	doc.row(11, columns, "课程", "任课教师", "总评", "等级", "绩点")
+	if len(data.Courses) == 0 {
+		doc.row(10, columns, "本学期没有课程")
+	}
+	for _, course := range data.Courses {
+		score, label, point := "-", "-", "-"
+		if course.Graded {
+			score = fmt.Sprintf("%.1f", course.Percent)
+			if !course.Final {
+				score += "（暂定）"
+			}
+			label = course.Label
+			if course.HasGradePoint {
+				point = fmt.Sprintf("%.1f", course.GradePoint)
+			}
+		}
+
+		doc.cells(10, columns, course.CourseName, course.Teachers, score, label, point)
+		if options.ShowCategories {
+			var parts []string
+			for _, category := range course.Categories {
+				if category.Graded {
+					parts = append(parts, fmt.Sprintf("%s %.1f%%（权重%g）", category.Name, category.Percent, category.Weight))
+				}
+			}
+			if len(parts) > 0 {
+				doc.paragraph(pageMargin+12, 8, strings.Join(parts, "；"))
+			}
+		}
+		if options.ShowComments && course.Comment != "" {
+			doc.paragraph(pageMargin+12, 9, fmt.Sprintf("任课教师评语（%s）：%s", course.CommentBy, course.Comment))
+		}
+		doc.separator()
+	}
+
+	if data.HasGPA {
+		doc.y -= 4
+		doc.paragraph(pageMargin, 11, fmt.Sprintf("平均绩点：%.2f", data.GPA))
+	}
+
+	if options.ShowAttendance {
+		doc.y -= 8
+		doc.paragraph(pageMargin, 12, "考勤")
+		attendanceColumns := []float64{pageMargin, 130, 210, 290, 370, 450}
+		doc.row(10, attendanceColumns, "应到", "出勤", "迟到", "缺勤", "请假", "缺勤率")
+		a := data.Attendance
+		doc.row(10, attendanceColumns,
+			fmt.Sprint(a.Total), fmt.Sprint(a.Present), fmt.Sprint(a.Late), fmt.Sprint(a.Absent), fmt.Sprint(a.Excused),
+			fmt.Sprintf("%.1f%%", a.AbsenceRate*100))
+	}
+
+	if options.ShowComments && data.GeneralComment != "" {
+		doc.y -= 8
+		doc.paragraph(pageMargin, 12, fmt.Sprintf("班主任评语（%s）", data.CommentBy))
+		doc.paragraph(pageMargin, 10, data.GeneralComment)
+	}
+
+	doc.y -= 16
+	if options.Footer != "" {
+		doc.paragraph(pageMargin, 9, options.Footer)
+	}
+	doc.paragraph(pageMargin, 9, "生成时间："+data.GeneratedAt.Format("2006-01-02 15:04"))
+
+	_, err := doc.WriteTo(w)
+	return err
+}
@@ -0,0 +1,125 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"EduGo_servers/internal/models"
+	"gorm.io/gorm"
+)
+
+type AcademicTermRepository interface {
+	CreateTerm(ctx context.Context, term *models.AcademicTerm) error
+	GetTermByID(ctx context.Context, id int64) (*models.AcademicTerm, error)
+	GetTerms(ctx context.Context) ([]*models.AcademicTerm, error)
+	GetCurrentTerm(ctx context.Context, now time.Time) (*models.AcademicTerm, error)
+	UpdateTerm(ctx context.Context, term *models.AcademicTerm) error
+	SetCurrentTerm(ctx context.Context, id int64) error
+	DeleteTerm(ctx context.Context, id int64) error
+	TermNameExists(ctx context.Context, name string, excludeID int64) (bool, error)
+	TermOverlaps(ctx context.Context, start, end time.Time, excludeID int64) (bool, error)
+	IsTermInUse(ctx context.Context, id int64) (bool, error)
+}
+
+type academicTermRepository struct {
+	db *gorm.DB
+}
+
+func NewAcademicTermRepository(db *gorm.DB) AcademicTermRepository {
+	return &academicTermRepository{db: db}
+}
+
+func (r *academicTermRepository) CreateTerm(ctx context.Context, term *models.AcademicTerm) error {
+	return r.db.WithContext(ctx).Create(term).Error
+}
+
+func (r *academicTermRepository) GetTermByID(ctx context.Context, id int64) (*models.AcademicTerm, error) {
+	var term models.AcademicTerm
+	err := r.db.WithContext(ctx).First(&term, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return &term, err
+}
+
+// GetTerms 获取所有学期，按开始日期倒序
+func (r *academicTermRepository) GetTerms(ctx context.Context) ([]*models.AcademicTerm, error) {
+	var terms []*models.AcademicTerm
+	err := r.db.WithContext(ctx).Order("start_date DESC").Find(&terms).Error
+	return terms, err
+}
+
+// GetCurrentTerm 获取当前学期：优先返回标记为当前的学期，否则返回包含指定日期的学期
+func (r *academicTermRepository) GetCurrentTerm(ctx context.Context, now time.Time) (*models.AcademicTerm, error) {
+	var term models.AcademicTerm
+	err := r.db.WithContext(ctx).Where("is_current = ?", true).First(&term).Error
+	if err == nil {
+		return &term, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	today := now.Format("2006-01-02")
+	err = r.db.WithContext(ctx).
+		Where("start_date <= ? AND end_date >= ?", today, today).
+		Order("start_date DESC").
+		First(&term).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return &term, err
+}
+
+func (r *academicTermRepository) UpdateTerm(ctx context.Context, term *models.AcademicTerm) error {
+	return r.db.WithContext(ctx).Save(term).Error
+}
+
+// SetCurrentTerm 将指定学期设为当前学期，并取消其他学期的当前标记
+func (r *academicTermRepository) SetCurrentTerm(ctx context.Context, id int64) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&models.AcademicTerm{}).
+			Where("is_current = ? AND id <> ?", true, id).
+			Update("is_current", false).Error
+		if err != nil {
+			return err
+		}
+		return tx.Model(&models.AcademicTerm{}).Where("id = ?", id).Update("is_current", true).Error
+	})
+}
+
+func (r *academicTermRepository) DeleteTerm(ctx context.Context, id int64) error {
+	return r.db.WithContext(ctx).Delete(&models.AcademicTerm{}, id).Error
+}
+
+func (r *academicTermRepository) TermNameExists(ctx context.Context, name string, excludeID int64) (bool, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&models.AcademicTerm{}).
+		Where("name = ? AND id <> ?", name, excludeID).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// TermOverlaps 检查日期范围是否与其他学期重叠
+func (r *academicTermRepository) TermOverlaps(ctx context.Context, start, end time.Time, excludeID int64) (bool, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&models.AcademicTerm{}).
+		Where("id <> ? AND start_date <= ? AND end_date >= ?", excludeID, end.Format("2006-01-02"), start.Format("2006-01-02")).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// IsTermInUse 检查学期是否被开课或教师-学生关系引用
+func (r *academicTermRepository) IsTermInUse(ctx context.Context, id int64) (bool, error) {
+	for _, model := range []interface{}{&models.CourseOffering{}, &models.TeacherStudentRelation{}} {
+		var count int64
+		if err := r.db.WithContext(ctx).Model(model).Where("term_id = ?", id).Count(&count).Error; err != nil {
+			return false, err
+		}
+		if count > 0 {
+			return true, nil
+		}
+	}
+	return false, nil
+}
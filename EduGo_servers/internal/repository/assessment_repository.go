@@ -0,0 +1,318 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"EduGo_servers/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	// ErrAttemptLimitReached 学生的作答次数已达到试卷允许的上限
+	ErrAttemptLimitReached = errors.New("attempt limit reached")
+	// ErrAttemptNotInProgress 作答已经提交，不能再修改
+	ErrAttemptNotInProgress = errors.New("attempt is not in progress")
+)
+
+type AssessmentRepository interface {
+	CreateAssessment(ctx context.Context, assessment *models.Assessment) error
+	GetAssessmentByID(ctx context.Context, id int64) (*models.Assessment, error)
+	GetOfferingAssessments(ctx context.Context, offeringID int64, publishedOnly bool) ([]*models.Assessment, error)
+	UpdateAssessment(ctx context.Context, assessment *models.Assessment) error
+	ReplaceQuestions(ctx context.Context, assessmentID int64, questions []models.AssessmentQuestion) error
+	DeleteAssessment(ctx context.Context, id int64) error
+
+	CountAttempts(ctx context.Context, assessmentID int64) (int64, error)
+	CreateAttempt(ctx context.Context, attempt *models.AssessmentAttempt, maxAttempts int) error
+	GetAttemptByID(ctx context.Context, id int64) (*models.AssessmentAttempt, error)
+	GetInProgressAttempt(ctx context.Context, assessmentID, studentID int64) (*models.AssessmentAttempt, error)
+	GetAttempts(ctx context.Context, assessmentID int64) ([]*models.AssessmentAttempt, error)
+	GetStudentAttempts(ctx context.Context, assessmentID, studentID int64) ([]*models.AssessmentAttempt, error)
+	GetAllStudentAttempts(ctx context.Context, studentID int64) ([]*models.AssessmentAttempt, error)
+	SaveResponses(ctx context.Context, attemptID int64, answers []*models.AttemptAnswer) error
+	SubmitAttempt(ctx context.Context, attempt *models.AssessmentAttempt) error
+	SaveGrading(ctx context.Context, attempt *models.AssessmentAttempt, answer *models.AttemptAnswer) error
+	SaveAdaptiveAnswer(ctx context.Context, attempt *models.AssessmentAttempt, answer *models.AttemptAnswer) error
+	IsQuestionRevealed(ctx context.Context, studentID, questionID int64) (bool, error)
+}
+
+type assessmentRepository struct {
+	db *gorm.DB
+}
+
+func NewAssessmentRepository(db *gorm.DB) AssessmentRepository {
+	return &assessmentRepository{db: db}
+}
+
+// orderAnswers 按作答顺序加载作答记录
+func orderAnswers(db *gorm.DB) *gorm.DB {
+	return db.Order("id")
+}
+
+func (r *assessmentRepository) CreateAssessment(ctx context.Context, assessment *models.Assessment) error {
+	return r.db.WithContext(ctx).Omit("Offering", "Questions").Create(assessment).Error
+}
+
+// GetAssessmentByID 获取试卷及其题目，已从题库删除的题目仍然加载
+func (r *assessmentRepository) GetAssessmentByID(ctx context.Context, id int64) (*models.Assessment, error) {
+	var assessment models.Assessment
+	err := r.db.WithContext(ctx).
+		Preload("Offering").Preload("Offering.Course").Preload("Offering.Course.Teachers").Preload("Offering.Teachers").
+		Preload("Questions", func(db *gorm.DB) *gorm.DB { return db.Order("position") }).
+		Preload("Questions.Question", func(db *gorm.DB) *gorm.DB { return db.Unscoped() }).
+		Preload("Questions.Question.Options", func(db *gorm.DB) *gorm.DB { return db.Order("position") }).
+		Preload("Questions.Question.Answers", func(db *gorm.DB) *gorm.DB { return db.Order("blank, id") }).
+		Preload("Questions.Question.Tags").
+		Preload("Questions.Question.Parameter").
+		First(&assessment, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return &assessment, err
+}
+
+// GetOfferingAssessments 获取开课的试卷，只加载题目分值
+func (r *assessmentRepository) GetOfferingAssessments(ctx context.Context, offeringID int64, publishedOnly bool) ([]*models.Assessment, error) {
+	db := r.db.WithContext(ctx).Preload("Questions").Where("offering_id = ?", offeringID)
+	if publishedOnly {
+		db = db.Where("published = ?", true)
+	}
+
+	var assessments []*models.Assessment
+	err := db.Order("id").Find(&assessments).Error
+	return assessments, err
+}
+
+func (r *assessmentRepository) UpdateAssessment(ctx context.Context, assessment *models.Assessment) error {
+	return r.db.WithContext(ctx).Omit("Offering", "Questions").Save(assessment).Error
+}
+
+// ReplaceQuestions 替换试卷的题目
+func (r *assessmentRepository) ReplaceQuestions(ctx context.Context, assessmentID int64, questions []models.AssessmentQuestion) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("assessment_id = ?", assessmentID).Delete(&models.AssessmentQuestion{}).Error; err != nil {
+			return err
+		}
+		if len(questions) == 0 {
+			return nil
+		}
+		return tx.Omit("Question").Create(&questions).Error
+	})
+}
+
+// DeleteAssessment 软删除试卷，作答记录保留
+func (r *assessmentRepository) DeleteAssessment(ctx context.Context, id int64) error {
+	return r.db.WithContext(ctx).Delete(&models.Assessment{}, id).Error
+}
+
+func (r *assessmentRepository) CountAttempts(ctx context.Context, assessmentID int64) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&models.AssessmentAttempt{}).Where("assessment_id = ?", assessmentID).Count(&count).Error
+	return count, err
+}
+
+// CreateAttempt 开始一次作答，maxAttempts大于0时限制学生的作答次数
+func (r *assessmentRepository) CreateAttempt(ctx context.Context, attempt *models.AssessmentAttempt, maxAttempts int) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var last models.AssessmentAttempt
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("assessment_id = ? AND student_id = ?", attempt.AssessmentID, attempt.StudentID).
+			Order("number DESC").
+			First(&last).Error
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			attempt.Number = 1
+		case err != nil:
+			return err
+		default:
+			attempt.Number = last.Number + 1
+		}
+		if maxAttempts > 0 && attempt.Number > maxAttempts {
+			return ErrAttemptLimitReached
+		}
+
+		attempt.Status = models.AttemptInProgress
+		return tx.Omit("Assessment", "Student", "Answers").Create(attempt).Error
+	})
+}
+
+func (r *assessmentRepository) GetAttemptByID(ctx context.Context, id int64) (*models.AssessmentAttempt, error) {
+	var attempt models.AssessmentAttempt
+	err := r.db.WithContext(ctx).Preload("Student").Preload("Answers", orderAnswers).First(&attempt, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return &attempt, err
+}
+
+// GetInProgressAttempt 获取学生正在进行的作答
+func (r *assessmentRepository) GetInProgressAttempt(ctx context.Context, assessmentID, studentID int64) (*models.AssessmentAttempt, error) {
+	var attempt models.AssessmentAttempt
+	err := r.db.WithContext(ctx).Preload("Answers", orderAnswers).
+		Where("assessment_id = ? AND student_id = ? AND status = ?", assessmentID, studentID, models.AttemptInProgress).
+		First(&attempt).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return &attempt, err
+}
+
+// GetAttempts 获取试卷的全部作答，按学生和次数排列
+func (r *assessmentRepository) GetAttempts(ctx context.Context, assessmentID int64) ([]*models.AssessmentAttempt, error) {
+	var attempts []*models.AssessmentAttempt
+	err := r.db.WithContext(ctx).Preload("Student").Preload("Answers", orderAnswers).
+		Where("assessment_id = ?", assessmentID).
+		Order("student_id, number").
+		Find(&attempts).Error
+	return attempts, err
+}
+
+func (r *assessmentRepository) GetStudentAttempts(ctx context.Context, assessmentID, studentID int64) ([]*models.AssessmentAttempt, error) {
+	var attempts []*models.AssessmentAttempt
+	err := r.db.WithContext(ctx).Preload("Answers", orderAnswers).
+		Where("assessment_id = ? AND student_id = ?", assessmentID, studentID).
+		Order("number").
+		Find(&attempts).Error
+	return attempts, err
+}
+
+// GetAllStudentAttempts 获取学生的全部作答，包括已删除试卷的作答
+func (r *assessmentRepository) GetAllStudentAttempts(ctx context.Context, studentID int64) ([]*models.AssessmentAttempt, error) {
+	var attempts []*models.AssessmentAttempt
+	err := r.db.WithContext(ctx).
+		Preload("Assessment", func(db *gorm.DB) *gorm.DB { return db.Unscoped() }).
+		Preload("Answers", orderAnswers).
+		Where("student_id = ?", studentID).
+		Order("id").
+		Find(&attempts).Error
+	return attempts, err
+}
+
+// revealedQuestions 学生已经看到答案的题目ID：学生在自己的开课中提交过、已发布且公布答案的试卷上的题目，
+// 自适应测评只包括学生作答过的题目
+func revealedQuestions(db *gorm.DB, studentID int64) *gorm.DB {
+	enrolled := db.Model(&models.Enrollment{}).Select("offering_id").
+		Where("student_id = ? AND status = ?", studentID, models.EnrollmentEnrolled)
+	scheduled := db.Model(&models.ScheduledSession{}).Select("offering_id").
+		Where("class_id IN (?)", db.Table("class_students").Select("class_id").Where("user_id = ?", studentID))
+	submitted := db.Model(&models.AssessmentAttempt{}).Select("assessment_id").
+		Where("student_id = ? AND status <> ?", studentID, models.AttemptInProgress)
+	visible := db.Model(&models.Assessment{}).Select("id").
+		Where("id IN (?) AND published = ? AND show_answers = ?", submitted, true, true).
+		Where("(offering_id IN (?) OR offering_id IN (?))", enrolled, scheduled)
+
+	papers := db.Model(&models.AssessmentQuestion{}).Select("question_id").
+		Where("assessment_id IN (?)", visible.Session(&gorm.Session{}).Where("mode <> ?", models.AssessmentAdaptive))
+	answered := db.Model(&models.AttemptAnswer{}).Select("question_id").
+		Where("attempt_id IN (?)", db.Model(&models.AssessmentAttempt{}).Select("id").
+			Where("student_id = ? AND status <> ? AND assessment_id IN (?)", studentID, models.AttemptInProgress, visible))
+	return db.Raw("? UNION ?", papers, answered)
+}
+
+// IsQuestionRevealed 学生是否已经可以看到题目的答案，规则见revealedQuestions
+func (r *assessmentRepository) IsQuestionRevealed(ctx context.Context, studentID, questionID int64) (bool, error) {
+	db := r.db.WithContext(ctx)
+	var count int64
+	err := db.Model(&models.Question{}).Unscoped().
+		Where("id = ? AND id IN (?)", questionID, revealedQuestions(db, studentID)).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// SaveResponses 保存作答进度，只能在作答进行中保存
+func (r *assessmentRepository) SaveResponses(ctx context.Context, attemptID int64, answers []*models.AttemptAnswer) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var attempt models.AssessmentAttempt
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&attempt, attemptID).Error
+		if err != nil {
+			return err
+		}
+		if attempt.Status != models.AttemptInProgress {
+			return ErrAttemptNotInProgress
+		}
+		if len(answers) == 0 {
+			return nil
+		}
+		return tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "attempt_id"}, {Name: "question_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"response", "updated_at"}),
+		}).Create(&answers).Error
+	})
+}
+
+// SubmitAttempt 交卷，保存评分结果。作答已经提交时返回ErrAttemptNotInProgress
+func (r *assessmentRepository) SubmitAttempt(ctx context.Context, attempt *models.AssessmentAttempt) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.AssessmentAttempt{}).
+			Where("id = ? AND status = ?", attempt.ID, models.AttemptInProgress).
+			Updates(map[string]interface{}{
+				"status":              attempt.Status,
+				"submitted_at":        attempt.SubmittedAt,
+				"score":               attempt.Score,
+				"max_score":           attempt.MaxScore,
+				"ability":             attempt.Ability,
+				"ability_se":          attempt.AbilitySE,
+				"current_question_id": attempt.CurrentQuestionID,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrAttemptNotInProgress
+		}
+		if len(attempt.Answers) == 0 {
+			return nil
+		}
+		return tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "attempt_id"}, {Name: "question_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"response", "score", "correct", "updated_at"}),
+		}).Create(&attempt.Answers).Error
+	})
+}
+
+// SaveGrading 保存教师对一道题的评分，并更新作答的总分和状态
+func (r *assessmentRepository) SaveGrading(ctx context.Context, attempt *models.AssessmentAttempt, answer *models.AttemptAnswer) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "attempt_id"}, {Name: "question_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"score", "correct", "feedback", "graded_by", "updated_at"}),
+		}).Create(answer).Error
+		if err != nil {
+			return err
+		}
+		return tx.Model(&models.AssessmentAttempt{}).Where("id = ?", attempt.ID).
+			Updates(map[string]interface{}{
+				"status":     attempt.Status,
+				"score":      attempt.Score,
+				"ability":    attempt.Ability,
+				"ability_se": attempt.AbilitySE,
+			}).Error
+	})
+}
+
+// SaveAdaptiveAnswer 保存自适应测评当前题目的作答，并更新能力估计、下一题和作答状态
+// 当前题目已经作答过（例如重复提交）或作答已结束时返回ErrAttemptNotInProgress
+func (r *assessmentRepository) SaveAdaptiveAnswer(ctx context.Context, attempt *models.AssessmentAttempt, answer *models.AttemptAnswer) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.AssessmentAttempt{}).
+			Where("id = ? AND status = ? AND current_question_id = ?", attempt.ID, models.AttemptInProgress, answer.QuestionID).
+			Updates(map[string]interface{}{
+				"status":              attempt.Status,
+				"submitted_at":        attempt.SubmittedAt,
+				"score":               attempt.Score,
+				"max_score":           attempt.MaxScore,
+				"ability":             attempt.Ability,
+				"ability_se":          attempt.AbilitySE,
+				"current_question_id": attempt.CurrentQuestionID,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrAttemptNotInProgress
+		}
+		return tx.Create(answer).Error
+	})
+}
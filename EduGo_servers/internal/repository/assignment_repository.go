@@ -0,0 +1,174 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"EduGo_servers/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrResubmitNotAllowed 作业不允许重新提交而学生已经提交过
+var ErrResubmitNotAllowed = errors.New("resubmission is not allowed")
+
+type AssignmentRepository interface {
+	CreateAssignment(ctx context.Context, assignment *models.Assignment) error
+	GetAssignmentByID(ctx context.Context, id int64) (*models.Assignment, error)
+	GetOfferingAssignments(ctx context.Context, offeringID int64, publishedOnly bool) ([]*models.Assignment, error)
+	UpdateAssignment(ctx context.Context, assignment *models.Assignment) error
+	DeleteAssignment(ctx context.Context, id int64) error
+	SetKnowledgePoints(ctx context.Context, assignment *models.Assignment, points []models.KnowledgePoint) error
+
+	CreateAttachment(ctx context.Context, attachment *models.AssignmentAttachment) error
+	GetAttachmentByID(ctx context.Context, id int64) (*models.AssignmentAttachment, error)
+	DeleteAttachment(ctx context.Context, id int64) error
+
+	CreateSubmission(ctx context.Context, submission *models.Submission, allowResubmit bool) error
+	GetSubmissionHistory(ctx context.Context, assignmentID, studentID int64) ([]*models.Submission, error)
+	GetLatestSubmissions(ctx context.Context, assignmentID int64) ([]*models.Submission, error)
+	GetStudentSubmissions(ctx context.Context, studentID int64) ([]*models.Submission, error)
+	GetSubmissionFileByID(ctx context.Context, id int64) (*models.SubmissionFile, error)
+}
+
+type assignmentRepository struct {
+	db *gorm.DB
+}
+
+func NewAssignmentRepository(db *gorm.DB) AssignmentRepository {
+	return &assignmentRepository{db: db}
+}
+
+func (r *assignmentRepository) CreateAssignment(ctx context.Context, assignment *models.Assignment) error {
+	return r.db.WithContext(ctx).Omit("Offering", "Attachments", "KnowledgePoints").Create(assignment).Error
+}
+
+func (r *assignmentRepository) GetAssignmentByID(ctx context.Context, id int64) (*models.Assignment, error) {
+	var assignment models.Assignment
+	err := r.db.WithContext(ctx).
+		Preload("Offering").Preload("Offering.Course").Preload("Offering.Course.Teachers").Preload("Offering.Teachers").
+		Preload("Attachments", func(db *gorm.DB) *gorm.DB { return db.Order("id") }).
+		Preload("KnowledgePoints").
+		First(&assignment, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return &assignment, err
+}
+
+// GetOfferingAssignments 获取开课的作业，按截止时间排列
+func (r *assignmentRepository) GetOfferingAssignments(ctx context.Context, offeringID int64, publishedOnly bool) ([]*models.Assignment, error) {
+	db := r.db.WithContext(ctx).
+		Preload("Attachments", func(db *gorm.DB) *gorm.DB { return db.Order("id") }).
+		Preload("KnowledgePoints").
+		Where("offering_id = ?", offeringID)
+	if publishedOnly {
+		db = db.Where("published = ?", true)
+	}
+
+	var assignments []*models.Assignment
+	err := db.Order("due_at, id").Find(&assignments).Error
+	return assignments, err
+}
+
+func (r *assignmentRepository) UpdateAssignment(ctx context.Context, assignment *models.Assignment) error {
+	return r.db.WithContext(ctx).Omit("Offering", "Attachments", "KnowledgePoints").Save(assignment).Error
+}
+
+// SetKnowledgePoints 替换作业关联的知识点
+func (r *assignmentRepository) SetKnowledgePoints(ctx context.Context, assignment *models.Assignment, points []models.KnowledgePoint) error {
+	return r.db.WithContext(ctx).Model(assignment).Association("KnowledgePoints").Replace(points)
+}
+
+// DeleteAssignment 软删除作业，提交记录和文件保留
+func (r *assignmentRepository) DeleteAssignment(ctx context.Context, id int64) error {
+	return r.db.WithContext(ctx).Delete(&models.Assignment{}, id).Error
+}
+
+func (r *assignmentRepository) CreateAttachment(ctx context.Context, attachment *models.AssignmentAttachment) error {
+	return r.db.WithContext(ctx).Create(attachment).Error
+}
+
+func (r *assignmentRepository) GetAttachmentByID(ctx context.Context, id int64) (*models.AssignmentAttachment, error) {
+	var attachment models.AssignmentAttachment
+	err := r.db.WithContext(ctx).First(&attachment, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return &attachment, err
+}
+
+func (r *assignmentRepository) DeleteAttachment(ctx context.Context, id int64) error {
+	return r.db.WithContext(ctx).Delete(&models.AssignmentAttachment{}, id).Error
+}
+
+// CreateSubmission 保存一次提交，版本号在该学生已有提交的基础上递增，之前的版本不再是最新提交
+func (r *assignmentRepository) CreateSubmission(ctx context.Context, submission *models.Submission, allowResubmit bool) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var latest models.Submission
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("assignment_id = ? AND student_id = ?", submission.AssignmentID, submission.StudentID).
+			Order("version DESC").
+			First(&latest).Error
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			submission.Version = 1
+		case err != nil:
+			return err
+		case !allowResubmit:
+			return ErrResubmitNotAllowed
+		default:
+			submission.Version = latest.Version + 1
+			err := tx.Model(&models.Submission{}).
+				Where("assignment_id = ? AND student_id = ? AND is_latest = ?", submission.AssignmentID, submission.StudentID, true).
+				Update("is_latest", false).Error
+			if err != nil {
+				return err
+			}
+		}
+
+		submission.IsLatest = true
+		return tx.Omit("Assignment", "Student").Create(submission).Error
+	})
+}
+
+// GetSubmissionHistory 获取学生对某个作业的全部提交，最新的在前
+func (r *assignmentRepository) GetSubmissionHistory(ctx context.Context, assignmentID, studentID int64) ([]*models.Submission, error) {
+	var submissions []*models.Submission
+	err := r.db.WithContext(ctx).
+		Preload("Files").
+		Where("assignment_id = ? AND student_id = ?", assignmentID, studentID).
+		Order("version DESC").
+		Find(&submissions).Error
+	return submissions, err
+}
+
+// GetLatestSubmissions 获取每名学生对某个作业的最新提交
+func (r *assignmentRepository) GetLatestSubmissions(ctx context.Context, assignmentID int64) ([]*models.Submission, error) {
+	var submissions []*models.Submission
+	err := r.db.WithContext(ctx).
+		Preload("Files").Preload("Student").
+		Where("assignment_id = ? AND is_latest = ?", assignmentID, true).
+		Order("submitted_at").
+		Find(&submissions).Error
+	return submissions, err
+}
+
+func (r *assignmentRepository) GetStudentSubmissions(ctx context.Context, studentID int64) ([]*models.Submission, error) {
+	var submissions []*models.Submission
+	err := r.db.WithContext(ctx).
+		Preload("Files").
+		Where("student_id = ?", studentID).
+		Order("assignment_id, version").
+		Find(&submissions).Error
+	return submissions, err
+}
+
+func (r *assignmentRepository) GetSubmissionFileByID(ctx context.Context, id int64) (*models.SubmissionFile, error) {
+	var file models.SubmissionFile
+	err := r.db.WithContext(ctx).Preload("Submission").First(&file, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return &file, err
+}
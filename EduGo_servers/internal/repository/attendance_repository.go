@@ -0,0 +1,107 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"EduGo_servers/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// AttendanceQuery 考勤记录查询条件
+type AttendanceQuery struct {
+	StudentID  int64
+	TermID     int64
+	OfferingID int64
+	From       *time.Time
+	To         *time.Time
+}
+
+// AttendanceCount 按学生和状态统计的考勤数量
+type AttendanceCount struct {
+	StudentID int64
+	Status    string
+	Count     int64
+}
+
+type AttendanceRepository interface {
+	GetSessionAttendance(ctx context.Context, sessionID int64, date time.Time) ([]*models.AttendanceRecord, error)
+	SaveAttendance(ctx context.Context, records []*models.AttendanceRecord) error
+	GetAttendanceRecords(ctx context.Context, query *AttendanceQuery) ([]*models.AttendanceRecord, error)
+	CountAttendance(ctx context.Context, query *AttendanceQuery) ([]AttendanceCount, error)
+}
+
+type attendanceRepository struct {
+	db *gorm.DB
+}
+
+func NewAttendanceRepository(db *gorm.DB) AttendanceRepository {
+	return &attendanceRepository{db: db}
+}
+
+func (r *attendanceRepository) GetSessionAttendance(ctx context.Context, sessionID int64, date time.Time) ([]*models.AttendanceRecord, error) {
+	var records []*models.AttendanceRecord
+	err := r.db.WithContext(ctx).
+		Where("session_id = ? AND date = ?", sessionID, date.Format("2006-01-02")).
+		Find(&records).Error
+	return records, err
+}
+
+// SaveAttendance 批量保存考勤，同一节课同一天同一学生的记录会被覆盖
+func (r *attendanceRepository) SaveAttendance(ctx context.Context, records []*models.AttendanceRecord) error {
+	if len(records) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).
+		Omit("Session", "Student").
+		Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "session_id"}, {Name: "date"}, {Name: "student_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"status", "note", "marked_by", "updated_at"}),
+		}).
+		Create(records).Error
+}
+
+// attendanceScope 按查询条件过滤考勤记录，学期和开课条件通过排课关联
+func (r *attendanceRepository) attendanceScope(ctx context.Context, query *AttendanceQuery) *gorm.DB {
+	db := r.db.WithContext(ctx).Model(&models.AttendanceRecord{})
+	if query.StudentID > 0 {
+		db = db.Where("attendance_records.student_id = ?", query.StudentID)
+	}
+	if query.TermID > 0 || query.OfferingID > 0 {
+		db = db.Joins("JOIN scheduled_sessions ON scheduled_sessions.id = attendance_records.session_id")
+		if query.TermID > 0 {
+			db = db.Where("scheduled_sessions.term_id = ?", query.TermID)
+		}
+		if query.OfferingID > 0 {
+			db = db.Where("scheduled_sessions.offering_id = ?", query.OfferingID)
+		}
+	}
+	if query.From != nil {
+		db = db.Where("attendance_records.date >= ?", query.From.Format("2006-01-02"))
+	}
+	if query.To != nil {
+		db = db.Where("attendance_records.date <= ?", query.To.Format("2006-01-02"))
+	}
+	return db
+}
+
+// GetAttendanceRecords 获取考勤记录，按日期倒序
+func (r *attendanceRepository) GetAttendanceRecords(ctx context.Context, query *AttendanceQuery) ([]*models.AttendanceRecord, error) {
+	var records []*models.AttendanceRecord
+	err := r.attendanceScope(ctx, query).
+		Preload("Session").Preload("Session.Offering").Preload("Session.Offering.Course").Preload("Session.Period").
+		Order("attendance_records.date DESC, attendance_records.session_id").
+		Find(&records).Error
+	return records, err
+}
+
+// CountAttendance 按学生和状态统计考勤数量
+func (r *attendanceRepository) CountAttendance(ctx context.Context, query *AttendanceQuery) ([]AttendanceCount, error) {
+	var counts []AttendanceCount
+	err := r.attendanceScope(ctx, query).
+		Select("attendance_records.student_id, attendance_records.status, COUNT(*) AS count").
+		Group("attendance_records.student_id, attendance_records.status").
+		Scan(&counts).Error
+	return counts, err
+}
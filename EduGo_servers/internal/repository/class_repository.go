@@ -0,0 +1,243 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"EduGo_servers/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	// ErrClassInUse 班级课表已有考勤记录，不能删除
+	ErrClassInUse = errors.New("class is in use")
+	// ErrStudentInClass 学生已在班级中
+	ErrStudentInClass = errors.New("student already in a class")
+)
+
+// ClassListQuery 班级列表查询条件
+type ClassListQuery struct {
+	Page       int
+	PageSize   int
+	GradeLevel string
+	TeacherID  int64  // 只返回该教师担任班主任或任课教师的班级
+	Search     string // 按班级名称模糊搜索
+}
+
+type ClassRepository interface {
+	CreateClass(ctx context.Context, class *models.Class) error
+	GetClassByID(ctx context.Context, id int64) (*models.Class, error)
+	ClassNameExists(ctx context.Context, gradeLevel, name string, excludeID int64) (bool, error)
+	ListClasses(ctx context.Context, query *ClassListQuery) ([]*models.Class, int64, error)
+	UpdateClass(ctx context.Context, class *models.Class) error
+	DeleteClass(ctx context.Context, id int64) error
+	GetClassStudents(ctx context.Context, classID int64) ([]*models.User, error)
+	GetStudentClasses(ctx context.Context, studentIDs []int64) (map[int64]int64, error)
+	AddClassStudents(ctx context.Context, class *models.Class, students []models.User) error
+	RemoveClassStudent(ctx context.Context, classID, studentID int64) (bool, error)
+	SetSubjectTeacher(ctx context.Context, classID int64, subject string, teacherID int64) (*models.ClassSubjectTeacher, error)
+	RemoveSubjectTeacher(ctx context.Context, classID, assignmentID int64) (bool, error)
+}
+
+type classRepository struct {
+	db *gorm.DB
+}
+
+func NewClassRepository(db *gorm.DB) ClassRepository {
+	return &classRepository{db: db}
+}
+
+func (r *classRepository) CreateClass(ctx context.Context, class *models.Class) error {
+	return r.db.WithContext(ctx).Omit("Students").Create(class).Error
+}
+
+// GetClassByID 获取班级，包括班主任和任课教师，不包括学生名单
+func (r *classRepository) GetClassByID(ctx context.Context, id int64) (*models.Class, error) {
+	var class models.Class
+	err := r.db.WithContext(ctx).
+		Preload("HeadTeacher").
+		Preload("SubjectTeachers", func(db *gorm.DB) *gorm.DB { return db.Order("subject") }).
+		Preload("SubjectTeachers.Teacher").
+		First(&class, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return &class, err
+}
+
+func (r *classRepository) ClassNameExists(ctx context.Context, gradeLevel, name string, excludeID int64) (bool, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&models.Class{}).
+		Where("grade_level = ? AND name = ? AND id <> ?", gradeLevel, name, excludeID).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// ListClasses 分页获取班级列表，返回班级和总数
+func (r *classRepository) ListClasses(ctx context.Context, query *ClassListQuery) ([]*models.Class, int64, error) {
+	if query.Page < 1 {
+		query.Page = 1
+	}
+	if query.PageSize <= 0 {
+		query.PageSize = DefaultPageSize
+	}
+	if query.PageSize > MaxPageSize {
+		query.PageSize = MaxPageSize
+	}
+
+	db := r.db.WithContext(ctx).Model(&models.Class{})
+	if query.GradeLevel != "" {
+		db = db.Where("classes.grade_level = ?", query.GradeLevel)
+	}
+	if query.TeacherID > 0 {
+		db = db.Where("classes.head_teacher_id = ? OR classes.id IN (?)", query.TeacherID,
+			r.db.Model(&models.ClassSubjectTeacher{}).Select("class_id").Where("teacher_id = ?", query.TeacherID))
+	}
+	if search := strings.TrimSpace(query.Search); search != "" {
+		db = db.Where("LOWER(classes.name) LIKE ?", "%"+escapeLike(strings.ToLower(search))+"%")
+	}
+
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var classes []*models.Class
+	err := db.Preload("HeadTeacher").
+		Preload("SubjectTeachers", func(db *gorm.DB) *gorm.DB { return db.Order("subject") }).
+		Preload("SubjectTeachers.Teacher").
+		Order("classes.grade_level, classes.name").
+		Offset((query.Page - 1) * query.PageSize).
+		Limit(query.PageSize).
+		Find(&classes).Error
+	return classes, total, err
+}
+
+func (r *classRepository) UpdateClass(ctx context.Context, class *models.Class) error {
+	return r.db.WithContext(ctx).Omit("HeadTeacher", "Students", "SubjectTeachers").Save(class).Error
+}
+
+// DeleteClass 删除班级及其学生名单、任课教师安排和班级课表
+// 班级课表已有考勤记录时返回ErrClassInUse，考勤记录需要保留
+func (r *classRepository) DeleteClass(ctx context.Context, id int64) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// 锁定班级，避免检查之后又在班级课表上记录考勤
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&models.Class{}, id).Error; err != nil {
+			return err
+		}
+		var count int64
+		err := tx.Model(&models.AttendanceRecord{}).
+			Where("session_id IN (?)", tx.Model(&models.ScheduledSession{}).Select("id").Where("class_id = ?", id)).
+			Count(&count).Error
+		if err != nil {
+			return err
+		}
+		if count > 0 {
+			return ErrClassInUse
+		}
+
+		if err := tx.Exec("DELETE FROM class_students WHERE class_id = ?", id).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("class_id = ?", id).Delete(&models.ClassSubjectTeacher{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("class_id = ?", id).Delete(&models.ScheduledSession{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.Class{}, id).Error
+	})
+}
+
+// GetClassStudents 获取班级的学生名单，不包括已删除的学生
+func (r *classRepository) GetClassStudents(ctx context.Context, classID int64) ([]*models.User, error) {
+	var students []*models.User
+	err := r.db.WithContext(ctx).
+		Joins("JOIN class_students ON class_students.user_id = users.id").
+		Where("class_students.class_id = ?", classID).
+		Order("users.id").
+		Find(&students).Error
+	return students, err
+}
+
+// GetStudentClasses 获取学生当前所在的班级，返回学生ID到班级ID的映射
+func (r *classRepository) GetStudentClasses(ctx context.Context, studentIDs []int64) (map[int64]int64, error) {
+	var rows []struct {
+		ClassID int64
+		UserID  int64
+	}
+	err := r.db.WithContext(ctx).Table("class_students").
+		Select("class_id, user_id").
+		Where("user_id IN ?", studentIDs).
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	classes := make(map[int64]int64, len(rows))
+	for _, row := range rows {
+		classes[row.UserID] = row.ClassID
+	}
+	return classes, nil
+}
+
+// AddClassStudents 将学生编入班级，学生已在某个班级中时返回ErrStudentInClass，全部不编入
+func (r *classRepository) AddClassStudents(ctx context.Context, class *models.Class, students []models.User) error {
+	members := make([]models.ClassStudent, 0, len(students))
+	for _, student := range students {
+		members = append(members, models.ClassStudent{ClassID: class.ID, UserID: student.ID})
+	}
+	err := r.db.WithContext(ctx).Create(&members).Error
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return ErrStudentInClass
+	}
+	return err
+}
+
+func (r *classRepository) RemoveClassStudent(ctx context.Context, classID, studentID int64) (bool, error) {
+	result := r.db.WithContext(ctx).Exec("DELETE FROM class_students WHERE class_id = ? AND user_id = ?", classID, studentID)
+	return result.RowsAffected > 0, result.Error
+}
+
+// SetSubjectTeacher 设置班级某学科的任课教师，已有安排时替换教师
+func (r *classRepository) SetSubjectTeacher(ctx context.Context, classID int64, subject string, teacherID int64) (*models.ClassSubjectTeacher, error) {
+	var assignment models.ClassSubjectTeacher
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("class_id = ? AND subject = ?", classID, subject).First(&assignment).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			assignment = models.ClassSubjectTeacher{ClassID: classID, Subject: subject, TeacherID: teacherID}
+			return tx.Create(&assignment).Error
+		}
+		if err != nil {
+			return err
+		}
+		assignment.TeacherID = teacherID
+		return tx.Save(&assignment).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &assignment, nil
+}
+
+func (r *classRepository) RemoveSubjectTeacher(ctx context.Context, classID, assignmentID int64) (bool, error) {
+	result := r.db.WithContext(ctx).
+		Where("id = ? AND class_id = ?", assignmentID, classID).
+		Delete(&models.ClassSubjectTeacher{})
+	return result.RowsAffected > 0, result.Error
+}
+
+// removeClassMemberships 移除用户的班级成员身份、任课安排和班主任职务
+func removeClassMemberships(tx *gorm.DB, userIDs []int64) error {
+	if err := tx.Exec("DELETE FROM class_students WHERE user_id IN ?", userIDs).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("teacher_id IN ?", userIDs).Delete(&models.ClassSubjectTeacher{}).Error; err != nil {
+		return err
+	}
+	return tx.Model(&models.Class{}).
+		Where("head_teacher_id IN ?", userIDs).
+		Update("head_teacher_id", nil).Error
+}
@@ -0,0 +1,244 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"EduGo_servers/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrOfferingInUse 开课已有学生数据，不能取消
+var ErrOfferingInUse = errors.New("course offering is in use")
+
+type CourseOfferingRepository interface {
+	CreateOffering(ctx context.Context, offering *models.CourseOffering) error
+	GetOfferingByID(ctx context.Context, id int64) (*models.CourseOffering, error)
+	GetOffering(ctx context.Context, courseID, termID int64) (*models.CourseOffering, error)
+	GetOfferingsByCourse(ctx context.Context, courseID int64) ([]*models.CourseOffering, error)
+	GetOfferingsByTerm(ctx context.Context, termID int64, publishedOnly bool) ([]*models.CourseOffering, error)
+	UpdateOffering(ctx context.Context, offering *models.CourseOffering) error
+	DeleteOffering(ctx context.Context, id int64) error
+	CopyOfferings(ctx context.Context, fromTermID, toTermID, createdBy int64) (int, error)
+	GetOfferingStudentIDs(ctx context.Context, offeringID int64) ([]int64, error)
+	GetStudentOfferings(ctx context.Context, studentID, termID int64) ([]*models.CourseOffering, error)
+}
+
+type courseOfferingRepository struct {
+	db *gorm.DB
+}
+
+func NewCourseOfferingRepository(db *gorm.DB) CourseOfferingRepository {
+	return &courseOfferingRepository{db: db}
+}
+
+func (r *courseOfferingRepository) CreateOffering(ctx context.Context, offering *models.CourseOffering) error {
+	return r.db.WithContext(ctx).Create(offering).Error
+}
+
+func (r *courseOfferingRepository) GetOfferingByID(ctx context.Context, id int64) (*models.CourseOffering, error) {
+	var offering models.CourseOffering
+	err := r.db.WithContext(ctx).
+		Preload("Course").Preload("Term").Preload("Teachers").
+		First(&offering, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return &offering, err
+}
+
+// GetOffering 获取课程在指定学期的开课
+func (r *courseOfferingRepository) GetOffering(ctx context.Context, courseID, termID int64) (*models.CourseOffering, error) {
+	var offering models.CourseOffering
+	err := r.db.WithContext(ctx).
+		Preload("Course").Preload("Term").Preload("Teachers").
+		Where("course_id = ? AND term_id = ?", courseID, termID).
+		First(&offering).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return &offering, err
+}
+
+func (r *courseOfferingRepository) GetOfferingsByCourse(ctx context.Context, courseID int64) ([]*models.CourseOffering, error) {
+	var offerings []*models.CourseOffering
+	err := r.db.WithContext(ctx).
+		Preload("Course").Preload("Term").Preload("Teachers").
+		Where("course_id = ?", courseID).
+		Order("term_id DESC").
+		Find(&offerings).Error
+	return offerings, err
+}
+
+func (r *courseOfferingRepository) GetOfferingsByTerm(ctx context.Context, termID int64, publishedOnly bool) ([]*models.CourseOffering, error) {
+	db := r.db.WithContext(ctx).
+		Preload("Course").Preload("Term").Preload("Teachers").
+		Where("term_id = ?", termID)
+	if publishedOnly {
+		db = db.Where("published = ?", true)
+	}
+
+	var offerings []*models.CourseOffering
+	err := db.Order("course_id").Find(&offerings).Error
+	return offerings, err
+}
+
+// UpdateOffering 更新开课的选课设置，已选人数由选课操作原子维护，不在此更新
+func (r *courseOfferingRepository) UpdateOffering(ctx context.Context, offering *models.CourseOffering) error {
+	return r.db.WithContext(ctx).Omit("Course", "Term", "Teachers", "EnrolledCount").Save(offering).Error
+}
+
+// offeringInUse 检查开课是否已有学生数据：选课记录、排课班级的学生、作业提交、测评作答、考勤或成绩
+func offeringInUse(tx *gorm.DB, id int64) (bool, error) {
+	sessions := tx.Model(&models.ScheduledSession{}).Select("id").Where("offering_id = ?", id)
+	assignments := tx.Model(&models.Assignment{}).Unscoped().Select("id").Where("offering_id = ?", id)
+	assessments := tx.Model(&models.Assessment{}).Unscoped().Select("id").Where("offering_id = ?", id)
+	items := tx.Model(&models.GradeItem{}).Select("id").Where("offering_id = ?", id)
+	checks := []*gorm.DB{
+		tx.Model(&models.Enrollment{}).Where("offering_id = ?", id),
+		tx.Table("class_students").Where("class_id IN (?)",
+			tx.Model(&models.ScheduledSession{}).Select("class_id").Where("offering_id = ?", id)),
+		tx.Model(&models.Submission{}).Where("assignment_id IN (?)", assignments),
+		tx.Model(&models.AssessmentAttempt{}).Where("assessment_id IN (?)", assessments),
+		tx.Model(&models.AttendanceRecord{}).Where("session_id IN (?)", sessions),
+		tx.Model(&models.GradeEntry{}).Where("item_id IN (?)", items),
+		tx.Model(&models.TermGrade{}).Where("offering_id = ?", id),
+	}
+	for _, check := range checks {
+		var count int64
+		if err := check.Count(&count).Error; err != nil {
+			return false, err
+		}
+		if count > 0 {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// DeleteOffering 取消开课，已有学生数据（见offeringInUse）时返回ErrOfferingInUse
+func (r *courseOfferingRepository) DeleteOffering(ctx context.Context, id int64) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		offering := &models.CourseOffering{ID: id}
+		// 锁定开课，避免检查之后又有学生选课
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(offering).Error; err != nil {
+			return err
+		}
+		inUse, err := offeringInUse(tx, id)
+		if err != nil {
+			return err
+		}
+		if inUse {
+			return ErrOfferingInUse
+		}
+
+		if err := tx.Model(offering).Association("Teachers").Clear(); err != nil {
+			return err
+		}
+		for _, model := range []interface{}{&models.ScheduledSession{}, &models.Assignment{}, &models.Assessment{},
+			&models.GradeItem{}, &models.GradeCategory{}, &models.LTILink{}, &models.LTILaunch{}} {
+			if err := tx.Where("offering_id = ?", id).Delete(model).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Model(&models.LTIContext{}).Where("offering_id = ?", id).Update("offering_id", nil).Error; err != nil {
+			return err
+		}
+		return tx.Delete(offering).Error
+	})
+}
+
+// CopyOfferings 将一个学期的开课（包括授课教师和名额设置）复制到另一个学期，已存在的开课跳过，返回新建数量
+// 新开课为未发布状态，选课时间需要重新设置
+func (r *courseOfferingRepository) CopyOfferings(ctx context.Context, fromTermID, toTermID, createdBy int64) (int, error) {
+	created := 0
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var sources []*models.CourseOffering
+		if err := tx.Preload("Teachers").Where("term_id = ?", fromTermID).Find(&sources).Error; err != nil {
+			return err
+		}
+
+		for _, source := range sources {
+			var count int64
+			err := tx.Model(&models.CourseOffering{}).
+				Where("course_id = ? AND term_id = ?", source.CourseID, toTermID).
+				Count(&count).Error
+			if err != nil {
+				return err
+			}
+			if count > 0 {
+				continue
+			}
+
+			offering := &models.CourseOffering{
+				CourseID:  source.CourseID,
+				TermID:    toTermID,
+				Teachers:  source.Teachers,
+				CreatedBy: createdBy,
+
+				Capacity:        source.Capacity,
+				WaitlistEnabled: source.WaitlistEnabled,
+				GradingScaleID:  source.GradingScaleID,
+			}
+			if err := tx.Create(offering).Error; err != nil {
+				return err
+			}
+			created++
+		}
+		return nil
+	})
+	return created, err
+}
+
+// GetOfferingStudentIDs 获取开课的学生名单：已选上的学生和排课班级的学生
+func (r *courseOfferingRepository) GetOfferingStudentIDs(ctx context.Context, offeringID int64) ([]int64, error) {
+	var enrolled []int64
+	err := r.db.WithContext(ctx).Model(&models.Enrollment{}).
+		Where("offering_id = ? AND status = ?", offeringID, models.EnrollmentEnrolled).
+		Pluck("student_id", &enrolled).Error
+	if err != nil {
+		return nil, err
+	}
+
+	classIDs := r.db.Model(&models.ScheduledSession{}).
+		Select("class_id").
+		Where("offering_id = ? AND class_id IS NOT NULL", offeringID)
+	var classStudents []int64
+	err = r.db.WithContext(ctx).Table("class_students").
+		Where("class_id IN (?)", classIDs).
+		Distinct().
+		Pluck("user_id", &classStudents).Error
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[int64]bool, len(enrolled)+len(classStudents))
+	ids := make([]int64, 0, len(enrolled)+len(classStudents))
+	for _, id := range append(enrolled, classStudents...) {
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+// GetStudentOfferings 获取学生在某个学期的开课：已选上的开课和所在班级排了课的开课
+func (r *courseOfferingRepository) GetStudentOfferings(ctx context.Context, studentID, termID int64) ([]*models.CourseOffering, error) {
+	enrolled := r.db.Model(&models.Enrollment{}).
+		Select("offering_id").
+		Where("student_id = ? AND status = ?", studentID, models.EnrollmentEnrolled)
+	scheduled := r.db.Model(&models.ScheduledSession{}).
+		Select("offering_id").
+		Where("class_id IN (?)", r.db.Table("class_students").Select("class_id").Where("user_id = ?", studentID))
+
+	var offerings []*models.CourseOffering
+	err := r.db.WithContext(ctx).
+		Preload("Course").Preload("Term").Preload("Teachers").
+		Where("term_id = ?", termID).
+		Where("id IN (?) OR id IN (?)", enrolled, scheduled).
+		Order("course_id").
+		Find(&offerings).Error
+	return offerings, err
+}
@@ -0,0 +1,114 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"EduGo_servers/internal/models"
+	"gorm.io/gorm"
+)
+
+// CourseListQuery 课程列表查询条件
+type CourseListQuery struct {
+	Page       int
+	PageSize   int
+	Subject    string
+	GradeLevel string
+	TeacherID  int64  // 只返回该教师负责的课程
+	Search     string // 按课程代码、名称模糊搜索
+}
+
+type CourseRepository interface {
+	CreateCourse(ctx context.Context, course *models.Course) error
+	GetCourseByID(ctx context.Context, id int64) (*models.Course, error)
+	CourseCodeExists(ctx context.Context, code string) (bool, error)
+	ListCourses(ctx context.Context, query *CourseListQuery) ([]*models.Course, int64, error)
+	UpdateCourse(ctx context.Context, course *models.Course) error
+	ReplaceCourseTeachers(ctx context.Context, course *models.Course, teachers []models.User) error
+	DeleteCourse(ctx context.Context, id int64) error
+}
+
+type courseRepository struct {
+	db *gorm.DB
+}
+
+func NewCourseRepository(db *gorm.DB) CourseRepository {
+	return &courseRepository{db: db}
+}
+
+func (r *courseRepository) CreateCourse(ctx context.Context, course *models.Course) error {
+	return r.db.WithContext(ctx).Create(course).Error
+}
+
+func (r *courseRepository) GetCourseByID(ctx context.Context, id int64) (*models.Course, error) {
+	var course models.Course
+	err := r.db.WithContext(ctx).Preload("Teachers").First(&course, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return &course, err
+}
+
+// CourseCodeExists 检查课程代码是否已被占用（包括已删除的课程）
+func (r *courseRepository) CourseCodeExists(ctx context.Context, code string) (bool, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Unscoped().Model(&models.Course{}).Where("code = ?", code).Count(&count).Error
+	return count > 0, err
+}
+
+// ListCourses 分页获取课程列表，返回课程和总数
+func (r *courseRepository) ListCourses(ctx context.Context, query *CourseListQuery) ([]*models.Course, int64, error) {
+	if query.Page < 1 {
+		query.Page = 1
+	}
+	if query.PageSize <= 0 {
+		query.PageSize = DefaultPageSize
+	}
+	if query.PageSize > MaxPageSize {
+		query.PageSize = MaxPageSize
+	}
+
+	db := r.db.WithContext(ctx).Model(&models.Course{})
+	if query.Subject != "" {
+		db = db.Where("courses.subject = ?", query.Subject)
+	}
+	if query.GradeLevel != "" {
+		db = db.Where("courses.grade_level = ?", query.GradeLevel)
+	}
+	if query.TeacherID > 0 {
+		db = db.Where("courses.id IN (?)",
+			r.db.Table("course_teachers").Select("course_id").Where("user_id = ?", query.TeacherID))
+	}
+	// 与用户搜索一样只按前缀匹配以便走索引，大小写由数据库的排序规则忽略
+	if search := strings.TrimSpace(query.Search); search != "" {
+		prefix := escapeLike(search) + "%"
+		db = db.Where(r.db.Where("courses.code LIKE ?", prefix).Or("courses.name LIKE ?", prefix))
+	}
+
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var courses []*models.Course
+	err := db.Preload("Teachers").
+		Order("courses.code").
+		Offset((query.Page - 1) * query.PageSize).
+		Limit(query.PageSize).
+		Find(&courses).Error
+	return courses, total, err
+}
+
+func (r *courseRepository) UpdateCourse(ctx context.Context, course *models.Course) error {
+	return r.db.WithContext(ctx).Omit("Teachers").Save(course).Error
+}
+
+// ReplaceCourseTeachers 替换课程的负责教师
+func (r *courseRepository) ReplaceCourseTeachers(ctx context.Context, course *models.Course, teachers []models.User) error {
+	return r.db.WithContext(ctx).Model(course).Association("Teachers").Replace(teachers)
+}
+
+func (r *courseRepository) DeleteCourse(ctx context.Context, id int64) error {
+	return r.db.WithContext(ctx).Delete(&models.Course{}, id).Error
+}
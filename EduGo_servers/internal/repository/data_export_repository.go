@@ -0,0 +1,86 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"EduGo_servers/internal/models"
+	"gorm.io/gorm"
+)
+
+type DataExportRepository interface {
+	CreateDataExport(ctx context.Context, export *models.DataExport) error
+	GetDataExportByID(ctx context.Context, id int64) (*models.DataExport, error)
+	GetDataExportsByRequester(ctx context.Context, requesterID int64) ([]*models.DataExport, error)
+	GetPendingDataExports(ctx context.Context, staleBefore time.Time) ([]*models.DataExport, error)
+	ClaimDataExport(ctx context.Context, id int64, now, staleBefore time.Time) (bool, error)
+	UpdateDataExport(ctx context.Context, export *models.DataExport) error
+	GetExpiredDataExports(ctx context.Context, now time.Time) ([]*models.DataExport, error)
+}
+
+type dataExportRepository struct {
+	db *gorm.DB
+}
+
+func NewDataExportRepository(db *gorm.DB) DataExportRepository {
+	return &dataExportRepository{db: db}
+}
+
+func (r *dataExportRepository) CreateDataExport(ctx context.Context, export *models.DataExport) error {
+	return r.db.WithContext(ctx).Create(export).Error
+}
+
+func (r *dataExportRepository) GetDataExportByID(ctx context.Context, id int64) (*models.DataExport, error) {
+	var export models.DataExport
+	err := r.db.WithContext(ctx).First(&export, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return &export, err
+}
+
+// GetDataExportsByRequester 获取用户发起的导出任务，按创建时间倒序
+func (r *dataExportRepository) GetDataExportsByRequester(ctx context.Context, requesterID int64) ([]*models.DataExport, error) {
+	var exports []*models.DataExport
+	err := r.db.WithContext(ctx).
+		Where("requested_by = ?", requesterID).
+		Order("created_at DESC").
+		Find(&exports).Error
+	return exports, err
+}
+
+// claimableDataExports 可以领取的导出任务：等待中的任务，以及在staleBefore之前开始处理、处理实例可能已崩溃的任务
+func claimableDataExports(db *gorm.DB, staleBefore time.Time) *gorm.DB {
+	return db.Where("status = ? OR (status = ? AND (claimed_at IS NULL OR claimed_at < ?))",
+		models.DataExportPending, models.DataExportProcessing, staleBefore)
+}
+
+// GetPendingDataExports 获取可以领取的导出任务，按创建时间排序
+func (r *dataExportRepository) GetPendingDataExports(ctx context.Context, staleBefore time.Time) ([]*models.DataExport, error) {
+	var exports []*models.DataExport
+	err := claimableDataExports(r.db.WithContext(ctx), staleBefore).
+		Order("created_at").
+		Find(&exports).Error
+	return exports, err
+}
+
+// ClaimDataExport 将可以领取的导出任务标记为处理中并记录领取时间，返回是否成功领取，避免多个实例重复处理
+func (r *dataExportRepository) ClaimDataExport(ctx context.Context, id int64, now, staleBefore time.Time) (bool, error) {
+	result := claimableDataExports(r.db.WithContext(ctx).Model(&models.DataExport{}).Where("id = ?", id), staleBefore).
+		Updates(map[string]interface{}{"status": models.DataExportProcessing, "claimed_at": now})
+	return result.RowsAffected > 0, result.Error
+}
+
+func (r *dataExportRepository) UpdateDataExport(ctx context.Context, export *models.DataExport) error {
+	return r.db.WithContext(ctx).Save(export).Error
+}
+
+// GetExpiredDataExports 获取已过期但文件尚未清理的导出任务
+func (r *dataExportRepository) GetExpiredDataExports(ctx context.Context, now time.Time) ([]*models.DataExport, error) {
+	var exports []*models.DataExport
+	err := r.db.WithContext(ctx).
+		Where("expires_at IS NOT NULL AND expires_at < ? AND file_path <> ''", now).
+		Find(&exports).Error
+	return exports, err
+}
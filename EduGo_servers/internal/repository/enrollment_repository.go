@@ -0,0 +1,393 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"EduGo_servers/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	// ErrOfferingFull 开课已满员且不允许候补
+	ErrOfferingFull = errors.New("course offering is full")
+	// ErrAlreadyEnrolled 学生已选课或正在候补
+	ErrAlreadyEnrolled = errors.New("student already enrolled")
+	// ErrEnrollmentNotActive 选课记录已退课或状态已变化
+	ErrEnrollmentNotActive = errors.New("enrollment is not active")
+)
+
+type EnrollmentRepository interface {
+	GetEnrollmentByID(ctx context.Context, id int64) (*models.Enrollment, error)
+	GetStudentEnrollments(ctx context.Context, studentID int64) ([]*models.Enrollment, error)
+	GetOfferingEnrollments(ctx context.Context, offeringID int64, status string) ([]*models.Enrollment, error)
+	GetWaitlistPosition(ctx context.Context, enrollment *models.Enrollment) (int64, error)
+	Enroll(ctx context.Context, offering *models.CourseOffering, studentID int64, now time.Time) (*models.Enrollment, error)
+	Drop(ctx context.Context, offering *models.CourseOffering, enrollment *models.Enrollment, now time.Time) ([]*models.Enrollment, error)
+	PromoteWaitlist(ctx context.Context, offering *models.CourseOffering, now time.Time) ([]*models.Enrollment, error)
+}
+
+type enrollmentRepository struct {
+	db *gorm.DB
+}
+
+func NewEnrollmentRepository(db *gorm.DB) EnrollmentRepository {
+	return &enrollmentRepository{db: db}
+}
+
+func (r *enrollmentRepository) GetEnrollmentByID(ctx context.Context, id int64) (*models.Enrollment, error) {
+	var enrollment models.Enrollment
+	err := r.db.WithContext(ctx).
+		Preload("Offering").Preload("Offering.Course").Preload("Offering.Term").Preload("Offering.Teachers").
+		First(&enrollment, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return &enrollment, err
+}
+
+func (r *enrollmentRepository) GetStudentEnrollments(ctx context.Context, studentID int64) ([]*models.Enrollment, error) {
+	var enrollments []*models.Enrollment
+	err := r.db.WithContext(ctx).
+		Preload("Offering").Preload("Offering.Course").Preload("Offering.Term").
+		Where("student_id = ?", studentID).
+		Order("created_at DESC").
+		Find(&enrollments).Error
+	return enrollments, err
+}
+
+// GetOfferingEnrollments 获取开课的选课记录，候补学生按排队顺序排列
+func (r *enrollmentRepository) GetOfferingEnrollments(ctx context.Context, offeringID int64, status string) ([]*models.Enrollment, error) {
+	db := r.db.WithContext(ctx).Preload("Student").Where("offering_id = ?", offeringID)
+	if status != "" {
+		db = db.Where("status = ?", status)
+	}
+
+	var enrollments []*models.Enrollment
+	err := db.Order("status, waitlisted_at, id").Find(&enrollments).Error
+	return enrollments, err
+}
+
+// GetWaitlistPosition 获取候补学生的排队位置，从1开始
+func (r *enrollmentRepository) GetWaitlistPosition(ctx context.Context, enrollment *models.Enrollment) (int64, error) {
+	if enrollment.Status != models.EnrollmentWaitlisted || enrollment.WaitlistedAt == nil {
+		return 0, nil
+	}
+
+	var ahead int64
+	err := r.db.WithContext(ctx).Model(&models.Enrollment{}).
+		Where("offering_id = ? AND status = ?", enrollment.OfferingID, models.EnrollmentWaitlisted).
+		Where("waitlisted_at < ? OR (waitlisted_at = ? AND id < ?)", enrollment.WaitlistedAt, enrollment.WaitlistedAt, enrollment.ID).
+		Count(&ahead).Error
+	return ahead + 1, err
+}
+
+// claimSeat 原子地占用一个名额，满员时返回false
+func claimSeat(tx *gorm.DB, offeringID int64) (bool, error) {
+	result := tx.Model(&models.CourseOffering{}).
+		Where("id = ? AND (capacity = 0 OR enrolled_count < capacity)", offeringID).
+		Update("enrolled_count", gorm.Expr("enrolled_count + 1"))
+	return result.RowsAffected > 0, result.Error
+}
+
+// releaseSeat 释放一个名额
+func releaseSeat(tx *gorm.DB, offeringID int64) error {
+	return tx.Model(&models.CourseOffering{}).
+		Where("id = ? AND enrolled_count > 0", offeringID).
+		Update("enrolled_count", gorm.Expr("enrolled_count - 1")).Error
+}
+
+// syncEnrollmentRelations 同步选课学生与授课教师的教师-学生关系
+// 选课成功时建立或恢复关系，退课时将关系设为停用
+func syncEnrollmentRelations(tx *gorm.DB, offering *models.CourseOffering, studentID int64, status string) error {
+	for _, teacher := range offering.Teachers {
+		var relation models.TeacherStudentRelation
+		err := tx.Where("user_id = ? AND related_user_id = ?", teacher.ID, studentID).
+			Where("course_id = ? AND term_id = ?", offering.CourseID, offering.TermID).
+			First(&relation).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			if status != models.RelationStatusActive {
+				continue
+			}
+			relation = models.TeacherStudentRelation{
+				UserRelation: models.UserRelation{
+					UserID:        teacher.ID,
+					RelatedUserID: studentID,
+					RelationType:  models.RelationTeacherStudent,
+					Status:        status,
+				},
+				CourseID: offering.CourseID,
+				TermID:   offering.TermID,
+			}
+			if offering.Course != nil {
+				relation.CourseName = offering.Course.Name
+			}
+			if offering.Term != nil {
+				relation.Semester = offering.Term.Name
+			}
+			if err := tx.Create(&relation).Error; err != nil {
+				return err
+			}
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if relation.Status != status {
+			if err := tx.Model(&relation).Update("status", status).Error; err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Enroll 学生选课，有名额时直接选上，满员时进入候补（如果允许），否则返回ErrOfferingFull
+func (r *enrollmentRepository) Enroll(ctx context.Context, offering *models.CourseOffering, studentID int64, now time.Time) (*models.Enrollment, error) {
+	var enrollment models.Enrollment
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("offering_id = ? AND student_id = ?", offering.ID, studentID).
+			First(&enrollment).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		if err == nil && enrollment.Status != models.EnrollmentDropped {
+			return ErrAlreadyEnrolled
+		}
+
+		enrollment.OfferingID = offering.ID
+		enrollment.StudentID = studentID
+		enrollment.DroppedAt = nil
+
+		claimed, err := claimSeat(tx, offering.ID)
+		if err != nil {
+			return err
+		}
+		switch {
+		case claimed:
+			enrollment.Status = models.EnrollmentEnrolled
+			enrollment.EnrolledAt = &now
+			enrollment.WaitlistedAt = nil
+			if err := syncEnrollmentRelations(tx, offering, studentID, models.RelationStatusActive); err != nil {
+				return err
+			}
+		case offering.WaitlistEnabled:
+			enrollment.Status = models.EnrollmentWaitlisted
+			enrollment.WaitlistedAt = &now
+			enrollment.EnrolledAt = nil
+		default:
+			return ErrOfferingFull
+		}
+		return tx.Omit("Offering", "Student").Save(&enrollment).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &enrollment, nil
+}
+
+// Drop 退课或退出候补，退课释放的名额自动分配给候补学生，返回被递补的选课记录
+func (r *enrollmentRepository) Drop(ctx context.Context, offering *models.CourseOffering, enrollment *models.Enrollment, now time.Time) ([]*models.Enrollment, error) {
+	var promoted []*models.Enrollment
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		previous := enrollment.Status
+		result := tx.Model(&models.Enrollment{}).
+			Where("id = ? AND status = ?", enrollment.ID, previous).
+			Where("status <> ?", models.EnrollmentDropped).
+			Updates(map[string]interface{}{
+				"status":     models.EnrollmentDropped,
+				"dropped_at": now,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrEnrollmentNotActive
+		}
+		if previous != models.EnrollmentEnrolled {
+			return nil
+		}
+
+		if err := releaseSeat(tx, offering.ID); err != nil {
+			return err
+		}
+		if err := syncEnrollmentRelations(tx, offering, enrollment.StudentID, models.RelationStatusInactive); err != nil {
+			return err
+		}
+
+		var err error
+		promoted, err = promoteWaitlist(tx, offering, now)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	enrollment.Status = models.EnrollmentDropped
+	enrollment.DroppedAt = &now
+	return promoted, nil
+}
+
+// PromoteWaitlist 按排队顺序为候补学生分配空出的名额，例如扩容之后
+func (r *enrollmentRepository) PromoteWaitlist(ctx context.Context, offering *models.CourseOffering, now time.Time) ([]*models.Enrollment, error) {
+	var promoted []*models.Enrollment
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var err error
+		promoted, err = promoteWaitlist(tx, offering, now)
+		return err
+	})
+	return promoted, err
+}
+
+func promoteWaitlist(tx *gorm.DB, offering *models.CourseOffering, now time.Time) ([]*models.Enrollment, error) {
+	var promoted []*models.Enrollment
+	for {
+		var next models.Enrollment
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("offering_id = ? AND status = ?", offering.ID, models.EnrollmentWaitlisted).
+			Order("waitlisted_at, id").
+			First(&next).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return promoted, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		claimed, err := claimSeat(tx, offering.ID)
+		if err != nil {
+			return nil, err
+		}
+		if !claimed {
+			return promoted, nil
+		}
+
+		err = tx.Model(&next).Updates(map[string]interface{}{
+			"status":      models.EnrollmentEnrolled,
+			"enrolled_at": now,
+		}).Error
+		if err != nil {
+			return nil, err
+		}
+		if err := syncEnrollmentRelations(tx, offering, next.StudentID, models.RelationStatusActive); err != nil {
+			return nil, err
+		}
+		next.Status = models.EnrollmentEnrolled
+		next.EnrolledAt = &now
+		promoted = append(promoted, &next)
+	}
+}
+
+// dropStudentEnrollments 学生被删除或匿名化时退出所有选课和候补，释放的名额按顺序递补给候补学生
+func dropStudentEnrollments(tx *gorm.DB, studentIDs []int64, now time.Time) error {
+	var enrolled []int64
+	err := tx.Model(&models.Enrollment{}).
+		Where("student_id IN ? AND status = ?", studentIDs, models.EnrollmentEnrolled).
+		Pluck("offering_id", &enrolled).Error
+	if err != nil {
+		return err
+	}
+	err = tx.Model(&models.Enrollment{}).
+		Where("student_id IN ? AND status <> ?", studentIDs, models.EnrollmentDropped).
+		Updates(map[string]interface{}{
+			"status":     models.EnrollmentDropped,
+			"dropped_at": now,
+		}).Error
+	if err != nil {
+		return err
+	}
+
+	// 先将所有学生标记为退课，递补时才不会把名额分配给同批被删除的候补学生
+	for _, offeringID := range enrolled {
+		if err := releaseSeat(tx, offeringID); err != nil {
+			return err
+		}
+	}
+	promotedOfferings := make(map[int64]bool)
+	for _, offeringID := range enrolled {
+		if promotedOfferings[offeringID] {
+			continue
+		}
+		promotedOfferings[offeringID] = true
+
+		var offering models.CourseOffering
+		err := tx.Preload("Course").Preload("Term").Preload("Teachers").First(&offering, offeringID).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if _, err := promoteWaitlist(tx, &offering, now); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// restoreStudentEnrollments 恢复学生时恢复删除时随之退出的选课（退课时间与删除时间相同的记录）
+// 原来已选上的重新占用名额，名额已满时按开课设置进入候补队列末尾或保持退课；原来在候补中的按原来的候补时间回到队列
+func restoreStudentEnrollments(tx *gorm.DB, studentID int64, droppedAt, now time.Time) error {
+	var enrollments []*models.Enrollment
+	err := tx.Where("student_id = ? AND status = ? AND dropped_at = ?", studentID, models.EnrollmentDropped, droppedAt).
+		Order("id").
+		Find(&enrollments).Error
+	if err != nil {
+		return err
+	}
+
+	for _, enrollment := range enrollments {
+		var offering models.CourseOffering
+		err := tx.Preload("Course").Preload("Term").Preload("Teachers").First(&offering, enrollment.OfferingID).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		claimed := false
+		if enrollment.EnrolledAt != nil {
+			if claimed, err = claimSeat(tx, offering.ID); err != nil {
+				return err
+			}
+		}
+		switch {
+		case claimed:
+			enrollment.Status = models.EnrollmentEnrolled
+			enrollment.WaitlistedAt = nil
+		case offering.WaitlistEnabled:
+			if enrollment.EnrolledAt != nil {
+				enrollment.WaitlistedAt = &now
+				enrollment.EnrolledAt = nil
+			}
+			enrollment.Status = models.EnrollmentWaitlisted
+		default:
+			// 无法恢复的选课保持退课，恢复的关系随之停用
+			if err := syncEnrollmentRelations(tx, &offering, studentID, models.RelationStatusInactive); err != nil {
+				return err
+			}
+			continue
+		}
+		enrollment.DroppedAt = nil
+		if err := tx.Omit("Offering", "Student").Save(enrollment).Error; err != nil {
+			return err
+		}
+
+		if claimed {
+			err = syncEnrollmentRelations(tx, &offering, studentID, models.RelationStatusActive)
+		} else {
+			err = syncEnrollmentRelations(tx, &offering, studentID, models.RelationStatusInactive)
+			if err == nil {
+				// 候补学生之前可能有空出的名额
+				_, err = promoteWaitlist(tx, &offering, now)
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
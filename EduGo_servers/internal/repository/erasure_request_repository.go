@@ -0,0 +1,61 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"EduGo_servers/internal/models"
+	"gorm.io/gorm"
+)
+
+type ErasureRequestRepository interface {
+	CreateErasureRequest(ctx context.Context, request *models.ErasureRequest) error
+	GetErasureRequestByID(ctx context.Context, id int64) (*models.ErasureRequest, error)
+	GetErasureRequests(ctx context.Context, status string) ([]*models.ErasureRequest, error)
+	HasPendingErasureRequest(ctx context.Context, userID int64) (bool, error)
+	UpdateErasureRequest(ctx context.Context, request *models.ErasureRequest) error
+}
+
+type erasureRequestRepository struct {
+	db *gorm.DB
+}
+
+func NewErasureRequestRepository(db *gorm.DB) ErasureRequestRepository {
+	return &erasureRequestRepository{db: db}
+}
+
+func (r *erasureRequestRepository) CreateErasureRequest(ctx context.Context, request *models.ErasureRequest) error {
+	return r.db.WithContext(ctx).Create(request).Error
+}
+
+func (r *erasureRequestRepository) GetErasureRequestByID(ctx context.Context, id int64) (*models.ErasureRequest, error) {
+	var request models.ErasureRequest
+	err := r.db.WithContext(ctx).First(&request, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return &request, err
+}
+
+// GetErasureRequests 获取数据删除申请，status为空时返回全部
+func (r *erasureRequestRepository) GetErasureRequests(ctx context.Context, status string) ([]*models.ErasureRequest, error) {
+	var requests []*models.ErasureRequest
+	query := r.db.WithContext(ctx).Order("created_at")
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	err := query.Find(&requests).Error
+	return requests, err
+}
+
+func (r *erasureRequestRepository) HasPendingErasureRequest(ctx context.Context, userID int64) (bool, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&models.ErasureRequest{}).
+		Where("user_id = ? AND status = ?", userID, models.ErasurePending).
+		Count(&count).Error
+	return count > 0, err
+}
+
+func (r *erasureRequestRepository) UpdateErasureRequest(ctx context.Context, request *models.ErasureRequest) error {
+	return r.db.WithContext(ctx).Save(request).Error
+}
@@ -0,0 +1,264 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"EduGo_servers/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type GradebookRepository interface {
+	// 评分制
+	GetGradingScales(ctx context.Context) ([]*models.GradingScale, error)
+	GetGradingScaleByID(ctx context.Context, id int64) (*models.GradingScale, error)
+	GradingScaleNameExists(ctx context.Context, name string) (bool, error)
+	CreateGradingScale(ctx context.Context, scale *models.GradingScale) error
+	IsGradingScaleInUse(ctx context.Context, id int64) (bool, error)
+	DeleteGradingScale(ctx context.Context, id int64) error
+
+	// 成绩分类
+	CreateCategory(ctx context.Context, category *models.GradeCategory) error
+	GetCategoryByID(ctx context.Context, id int64) (*models.GradeCategory, error)
+	GetCategories(ctx context.Context, offeringID int64) ([]*models.GradeCategory, error)
+	CategoryNameExists(ctx context.Context, offeringID int64, name string, excludeID int64) (bool, error)
+	UpdateCategory(ctx context.Context, category *models.GradeCategory) error
+	CountCategoryItems(ctx context.Context, categoryID int64) (int64, error)
+	DeleteCategory(ctx context.Context, id int64) error
+
+	// 成绩项和得分
+	CreateItem(ctx context.Context, item *models.GradeItem) error
+	GetItemByID(ctx context.Context, id int64) (*models.GradeItem, error)
+	GetItems(ctx context.Context, offeringID int64) ([]*models.GradeItem, error)
+	GetItemByAssessment(ctx context.Context, assessmentID int64) (*models.GradeItem, error)
+	UpdateItem(ctx context.Context, item *models.GradeItem) error
+	DeleteItem(ctx context.Context, id int64) error
+	GetEntries(ctx context.Context, offeringID int64, studentID int64) ([]*models.GradeEntry, error)
+	GetStudentEntries(ctx context.Context, studentID int64) ([]*models.GradeEntry, error)
+	SaveEntries(ctx context.Context, entries []*models.GradeEntry) error
+
+	// 学期总评
+	SaveTermGrades(ctx context.Context, offeringID int64, grades []*models.TermGrade) error
+	GetOfferingTermGrades(ctx context.Context, offeringID int64) ([]*models.TermGrade, error)
+	GetStudentTermGrades(ctx context.Context, studentID, termID int64) ([]*models.TermGrade, error)
+}
+
+type gradebookRepository struct {
+	db *gorm.DB
+}
+
+func NewGradebookRepository(db *gorm.DB) GradebookRepository {
+	return &gradebookRepository{db: db}
+}
+
+// preloadBands 按最低分从高到低加载评分制的等级
+func preloadBands(db *gorm.DB) *gorm.DB {
+	return db.Preload("Bands", func(db *gorm.DB) *gorm.DB { return db.Order("min_percent DESC") })
+}
+
+func (r *gradebookRepository) GetGradingScales(ctx context.Context) ([]*models.GradingScale, error) {
+	var scales []*models.GradingScale
+	err := preloadBands(r.db.WithContext(ctx)).Order("id").Find(&scales).Error
+	return scales, err
+}
+
+func (r *gradebookRepository) GetGradingScaleByID(ctx context.Context, id int64) (*models.GradingScale, error) {
+	var scale models.GradingScale
+	err := preloadBands(r.db.WithContext(ctx)).First(&scale, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return &scale, err
+}
+
+func (r *gradebookRepository) GradingScaleNameExists(ctx context.Context, name string) (bool, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&models.GradingScale{}).Where("name = ?", name).Count(&count).Error
+	return count > 0, err
+}
+
+func (r *gradebookRepository) CreateGradingScale(ctx context.Context, scale *models.GradingScale) error {
+	return r.db.WithContext(ctx).Create(scale).Error
+}
+
+// IsGradingScaleInUse 检查是否有开课使用该评分制
+func (r *gradebookRepository) IsGradingScaleInUse(ctx context.Context, id int64) (bool, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&models.CourseOffering{}).Where("grading_scale_id = ?", id).Count(&count).Error
+	return count > 0, err
+}
+
+func (r *gradebookRepository) DeleteGradingScale(ctx context.Context, id int64) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("scale_id = ?", id).Delete(&models.GradingScaleBand{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.GradingScale{}, id).Error
+	})
+}
+
+func (r *gradebookRepository) CreateCategory(ctx context.Context, category *models.GradeCategory) error {
+	return r.db.WithContext(ctx).Create(category).Error
+}
+
+func (r *gradebookRepository) GetCategoryByID(ctx context.Context, id int64) (*models.GradeCategory, error) {
+	var category models.GradeCategory
+	err := r.db.WithContext(ctx).First(&category, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return &category, err
+}
+
+func (r *gradebookRepository) GetCategories(ctx context.Context, offeringID int64) ([]*models.GradeCategory, error) {
+	var categories []*models.GradeCategory
+	err := r.db.WithContext(ctx).Where("offering_id = ?", offeringID).Order("id").Find(&categories).Error
+	return categories, err
+}
+
+func (r *gradebookRepository) CategoryNameExists(ctx context.Context, offeringID int64, name string, excludeID int64) (bool, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&models.GradeCategory{}).
+		Where("offering_id = ? AND name = ? AND id <> ?", offeringID, name, excludeID).
+		Count(&count).Error
+	return count > 0, err
+}
+
+func (r *gradebookRepository) UpdateCategory(ctx context.Context, category *models.GradeCategory) error {
+	return r.db.WithContext(ctx).Save(category).Error
+}
+
+func (r *gradebookRepository) CountCategoryItems(ctx context.Context, categoryID int64) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&models.GradeItem{}).Where("category_id = ?", categoryID).Count(&count).Error
+	return count, err
+}
+
+func (r *gradebookRepository) DeleteCategory(ctx context.Context, id int64) error {
+	return r.db.WithContext(ctx).Delete(&models.GradeCategory{}, id).Error
+}
+
+func (r *gradebookRepository) CreateItem(ctx context.Context, item *models.GradeItem) error {
+	return r.db.WithContext(ctx).Omit("Category").Create(item).Error
+}
+
+func (r *gradebookRepository) GetItemByID(ctx context.Context, id int64) (*models.GradeItem, error) {
+	var item models.GradeItem
+	err := r.db.WithContext(ctx).Preload("Category").First(&item, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return &item, err
+}
+
+func (r *gradebookRepository) GetItems(ctx context.Context, offeringID int64) ([]*models.GradeItem, error) {
+	var items []*models.GradeItem
+	err := r.db.WithContext(ctx).Where("offering_id = ?", offeringID).Order("category_id, id").Find(&items).Error
+	return items, err
+}
+
+// GetItemByAssessment 获取关联测评的成绩项，没有时返回nil
+func (r *gradebookRepository) GetItemByAssessment(ctx context.Context, assessmentID int64) (*models.GradeItem, error) {
+	var item models.GradeItem
+	err := r.db.WithContext(ctx).Where("assessment_id = ?", assessmentID).First(&item).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return &item, err
+}
+
+func (r *gradebookRepository) UpdateItem(ctx context.Context, item *models.GradeItem) error {
+	return r.db.WithContext(ctx).Omit("Category").Save(item).Error
+}
+
+// DeleteItem 删除成绩项及其所有得分，关联该成绩项的外部工具链接不再回传成绩
+func (r *gradebookRepository) DeleteItem(ctx context.Context, id int64) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("item_id = ?", id).Delete(&models.GradeEntry{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.LTILink{}).Where("grade_item_id = ?", id).Update("grade_item_id", nil).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.GradeItem{}, id).Error
+	})
+}
+
+// GetEntries 获取开课所有成绩项的得分，studentID不为0时只获取该学生的得分
+func (r *gradebookRepository) GetEntries(ctx context.Context, offeringID int64, studentID int64) ([]*models.GradeEntry, error) {
+	db := r.db.WithContext(ctx).
+		Where("item_id IN (?)", r.db.Model(&models.GradeItem{}).Select("id").Where("offering_id = ?", offeringID))
+	if studentID != 0 {
+		db = db.Where("student_id = ?", studentID)
+	}
+
+	var entries []*models.GradeEntry
+	err := db.Order("item_id, student_id").Find(&entries).Error
+	return entries, err
+}
+
+func (r *gradebookRepository) GetStudentEntries(ctx context.Context, studentID int64) ([]*models.GradeEntry, error) {
+	var entries []*models.GradeEntry
+	err := r.db.WithContext(ctx).Where("student_id = ?", studentID).Order("item_id").Find(&entries).Error
+	return entries, err
+}
+
+// SaveEntries 批量保存得分，同一成绩项同一学生的得分会被覆盖
+func (r *gradebookRepository) SaveEntries(ctx context.Context, entries []*models.GradeEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).
+		Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "item_id"}, {Name: "student_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"score", "late_penalty", "excused", "comment", "graded_by", "scored_at", "updated_at"}),
+		}).
+		Create(&entries).Error
+}
+
+// SaveTermGrades 保存开课重新计算的学期总评，覆盖已有的总评，并删除这次没有生成总评的学生之前的总评
+func (r *gradebookRepository) SaveTermGrades(ctx context.Context, offeringID int64, grades []*models.TermGrade) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		stale := tx.Where("offering_id = ?", offeringID)
+		if len(grades) > 0 {
+			studentIDs := make([]int64, 0, len(grades))
+			for _, grade := range grades {
+				studentIDs = append(studentIDs, grade.StudentID)
+			}
+			stale = stale.Where("student_id NOT IN ?", studentIDs)
+		}
+		if err := stale.Delete(&models.TermGrade{}).Error; err != nil {
+			return err
+		}
+		if len(grades) == 0 {
+			return nil
+		}
+		return tx.Omit("Offering", "Student").
+			Clauses(clause.OnConflict{
+				Columns:   []clause.Column{{Name: "offering_id"}, {Name: "student_id"}},
+				DoUpdates: clause.AssignmentColumns([]string{"percent", "label", "grade_point", "computed_by", "computed_at", "updated_at"}),
+			}).
+			Create(&grades).Error
+	})
+}
+
+func (r *gradebookRepository) GetOfferingTermGrades(ctx context.Context, offeringID int64) ([]*models.TermGrade, error) {
+	var grades []*models.TermGrade
+	err := r.db.WithContext(ctx).Preload("Student").Where("offering_id = ?", offeringID).Order("student_id").Find(&grades).Error
+	return grades, err
+}
+
+// GetStudentTermGrades 获取学生的学期总评，termID为0时获取所有学期
+func (r *gradebookRepository) GetStudentTermGrades(ctx context.Context, studentID, termID int64) ([]*models.TermGrade, error) {
+	db := r.db.WithContext(ctx).
+		Preload("Offering").Preload("Offering.Course").Preload("Offering.Term").
+		Where("student_id = ?", studentID)
+	if termID != 0 {
+		db = db.Where("offering_id IN (?)", r.db.Model(&models.CourseOffering{}).Select("id").Where("term_id = ?", termID))
+	}
+
+	var grades []*models.TermGrade
+	err := db.Order("offering_id").Find(&grades).Error
+	return grades, err
+}
@@ -0,0 +1,64 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"EduGo_servers/internal/models"
+	"gorm.io/gorm"
+)
+
+// ErrInvitationUnavailable 邀请已过期、已撤销或已用完
+var ErrInvitationUnavailable = errors.New("invitation unavailable")
+
+type InvitationRepository interface {
+	CreateInvitation(ctx context.Context, invitation *models.Invitation) error
+	GetInvitationByID(ctx context.Context, id int64) (*models.Invitation, error)
+	GetInvitationsByCreator(ctx context.Context, creatorID int64) ([]*models.Invitation, error)
+	RevokeInvitation(ctx context.Context, id int64) error
+	ConsumeInvitation(ctx context.Context, id int64) error
+}
+
+type invitationRepository struct {
+	db *gorm.DB
+}
+
+func NewInvitationRepository(db *gorm.DB) InvitationRepository {
+	return &invitationRepository{db: db}
+}
+
+func (r *invitationRepository) CreateInvitation(ctx context.Context, invitation *models.Invitation) error {
+	return r.db.WithContext(ctx).Create(invitation).Error
+}
+
+func (r *invitationRepository) GetInvitationByID(ctx context.Context, id int64) (*models.Invitation, error) {
+	var invitation models.Invitation
+	err := r.db.WithContext(ctx).First(&invitation, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return &invitation, err
+}
+
+// GetInvitationsByCreator 获取用户创建的邀请，按创建时间倒序
+func (r *invitationRepository) GetInvitationsByCreator(ctx context.Context, creatorID int64) ([]*models.Invitation, error) {
+	var invitations []*models.Invitation
+	err := r.db.WithContext(ctx).
+		Where("created_by = ?", creatorID).
+		Order("created_at DESC").
+		Find(&invitations).Error
+	return invitations, err
+}
+
+// RevokeInvitation 撤销邀请
+func (r *invitationRepository) RevokeInvitation(ctx context.Context, id int64) error {
+	return r.db.WithContext(ctx).Model(&models.Invitation{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Update("revoked_at", time.Now()).Error
+}
+
+// ConsumeInvitation 使用一次邀请，不可用时返回ErrInvitationUnavailable
+func (r *invitationRepository) ConsumeInvitation(ctx context.Context, id int64) error {
+	return consumeUsage(ctx, r.db, &models.Invitation{}, id, ErrInvitationUnavailable)
+}
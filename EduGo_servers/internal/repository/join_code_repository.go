@@ -0,0 +1,77 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"EduGo_servers/internal/models"
+	"gorm.io/gorm"
+)
+
+// ErrJoinCodeUnavailable 加入码已过期、已撤销或已用完
+var ErrJoinCodeUnavailable = errors.New("join code unavailable")
+
+type JoinCodeRepository interface {
+	CreateJoinCode(ctx context.Context, joinCode *models.JoinCode) error
+	GetJoinCodeByID(ctx context.Context, id int64) (*models.JoinCode, error)
+	GetJoinCodeByCode(ctx context.Context, code string) (*models.JoinCode, error)
+	GetJoinCodesByTeacher(ctx context.Context, teacherID int64) ([]*models.JoinCode, error)
+	UpdateJoinCode(ctx context.Context, joinCode *models.JoinCode) error
+	CodeExists(ctx context.Context, code string) (bool, error)
+	ConsumeJoinCode(ctx context.Context, id int64) error
+}
+
+type joinCodeRepository struct {
+	db *gorm.DB
+}
+
+func NewJoinCodeRepository(db *gorm.DB) JoinCodeRepository {
+	return &joinCodeRepository{db: db}
+}
+
+func (r *joinCodeRepository) CreateJoinCode(ctx context.Context, joinCode *models.JoinCode) error {
+	return r.db.WithContext(ctx).Create(joinCode).Error
+}
+
+func (r *joinCodeRepository) GetJoinCodeByID(ctx context.Context, id int64) (*models.JoinCode, error) {
+	var joinCode models.JoinCode
+	err := r.db.WithContext(ctx).First(&joinCode, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return &joinCode, err
+}
+
+func (r *joinCodeRepository) GetJoinCodeByCode(ctx context.Context, code string) (*models.JoinCode, error) {
+	var joinCode models.JoinCode
+	err := r.db.WithContext(ctx).Where("code = ?", code).First(&joinCode).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return &joinCode, err
+}
+
+// GetJoinCodesByTeacher 获取教师的加入码，按创建时间倒序
+func (r *joinCodeRepository) GetJoinCodesByTeacher(ctx context.Context, teacherID int64) ([]*models.JoinCode, error) {
+	var joinCodes []*models.JoinCode
+	err := r.db.WithContext(ctx).
+		Where("teacher_id = ?", teacherID).
+		Order("created_at DESC").
+		Find(&joinCodes).Error
+	return joinCodes, err
+}
+
+func (r *joinCodeRepository) UpdateJoinCode(ctx context.Context, joinCode *models.JoinCode) error {
+	return r.db.WithContext(ctx).Save(joinCode).Error
+}
+
+func (r *joinCodeRepository) CodeExists(ctx context.Context, code string) (bool, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&models.JoinCode{}).Where("code = ?", code).Count(&count).Error
+	return count > 0, err
+}
+
+// ConsumeJoinCode 使用一次加入码，不可用时返回ErrJoinCodeUnavailable
+func (r *joinCodeRepository) ConsumeJoinCode(ctx context.Context, id int64) error {
+	return consumeUsage(ctx, r.db, &models.JoinCode{}, id, ErrJoinCodeUnavailable)
+}
@@ -0,0 +1,127 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"EduGo_servers/internal/models"
+	"gorm.io/gorm"
+)
+
+type KnowledgePointRepository interface {
+	CreateKnowledgePoint(ctx context.Context, point *models.KnowledgePoint) error
+	GetKnowledgePointByID(ctx context.Context, id int64) (*models.KnowledgePoint, error)
+	GetKnowledgePointsByIDs(ctx context.Context, ids []int64) ([]*models.KnowledgePoint, error)
+	GetSubjectKnowledgePoints(ctx context.Context, subject string) ([]*models.KnowledgePoint, error)
+	GetSubjects(ctx context.Context) ([]string, error)
+	NameExists(ctx context.Context, subject string, parentID *int64, name string, excludeID int64) (bool, error)
+	NextPosition(ctx context.Context, subject string, parentID *int64) (int, error)
+	HasChildren(ctx context.Context, id int64) (bool, error)
+	UpdateKnowledgePoint(ctx context.Context, point *models.KnowledgePoint) error
+	DeleteKnowledgePoint(ctx context.Context, id int64) error
+}
+
+type knowledgePointRepository struct {
+	db *gorm.DB
+}
+
+func NewKnowledgePointRepository(db *gorm.DB) KnowledgePointRepository {
+	return &knowledgePointRepository{db: db}
+}
+
+// siblings 筛选同一学科中同一上级知识点下的知识点
+func siblings(db *gorm.DB, subject string, parentID *int64) *gorm.DB {
+	db = db.Where("subject = ?", subject)
+	if parentID == nil {
+		return db.Where("parent_id IS NULL")
+	}
+	return db.Where("parent_id = ?", *parentID)
+}
+
+func (r *knowledgePointRepository) CreateKnowledgePoint(ctx context.Context, point *models.KnowledgePoint) error {
+	return r.db.WithContext(ctx).Create(point).Error
+}
+
+func (r *knowledgePointRepository) GetKnowledgePointByID(ctx context.Context, id int64) (*models.KnowledgePoint, error) {
+	var point models.KnowledgePoint
+	err := r.db.WithContext(ctx).First(&point, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return &point, err
+}
+
+// GetKnowledgePointsByIDs 批量获取知识点，不存在的知识点不返回
+func (r *knowledgePointRepository) GetKnowledgePointsByIDs(ctx context.Context, ids []int64) ([]*models.KnowledgePoint, error) {
+	var points []*models.KnowledgePoint
+	if len(ids) == 0 {
+		return points, nil
+	}
+	err := r.db.WithContext(ctx).Where("id IN ?", ids).Order("subject, position, id").Find(&points).Error
+	return points, err
+}
+
+// GetSubjectKnowledgePoints 获取学科的全部知识点，同级知识点按顺序排列
+func (r *knowledgePointRepository) GetSubjectKnowledgePoints(ctx context.Context, subject string) ([]*models.KnowledgePoint, error) {
+	var points []*models.KnowledgePoint
+	err := r.db.WithContext(ctx).Where("subject = ?", subject).Order("position, id").Find(&points).Error
+	return points, err
+}
+
+// GetSubjects 获取已建立知识点树的学科
+func (r *knowledgePointRepository) GetSubjects(ctx context.Context) ([]string, error) {
+	var subjects []string
+	err := r.db.WithContext(ctx).Model(&models.KnowledgePoint{}).Distinct().Order("subject").Pluck("subject", &subjects).Error
+	return subjects, err
+}
+
+// NameExists 检查同一上级知识点下是否已有同名的知识点
+func (r *knowledgePointRepository) NameExists(ctx context.Context, subject string, parentID *int64, name string, excludeID int64) (bool, error) {
+	var count int64
+	err := siblings(r.db.WithContext(ctx).Model(&models.KnowledgePoint{}), subject, parentID).
+		Where("name = ? AND id <> ?", name, excludeID).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// NextPosition 新知识点排在同级知识点的最后
+func (r *knowledgePointRepository) NextPosition(ctx context.Context, subject string, parentID *int64) (int, error) {
+	var position *int
+	err := siblings(r.db.WithContext(ctx).Model(&models.KnowledgePoint{}), subject, parentID).
+		Select("MAX(position)").
+		Scan(&position).Error
+	if err != nil || position == nil {
+		return 0, err
+	}
+	return *position + 1, nil
+}
+
+func (r *knowledgePointRepository) HasChildren(ctx context.Context, id int64) (bool, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&models.KnowledgePoint{}).Where("parent_id = ?", id).Count(&count).Error
+	return count > 0, err
+}
+
+func (r *knowledgePointRepository) UpdateKnowledgePoint(ctx context.Context, point *models.KnowledgePoint) error {
+	return r.db.WithContext(ctx).Save(point).Error
+}
+
+// DeleteKnowledgePoint 删除知识点，题目上的知识点名称保留但不再关联到知识点树，作业和资源的关联以及学生的掌握度一并删除
+func (r *knowledgePointRepository) DeleteKnowledgePoint(ctx context.Context, id int64) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.QuestionTag{}).Where("knowledge_point_id = ?", id).Update("knowledge_point_id", nil).Error; err != nil {
+			return err
+		}
+		for _, table := range []string{"assignment_knowledge_points", "resource_knowledge_points"} {
+			if err := tx.Exec("DELETE FROM "+table+" WHERE knowledge_point_id = ?", id).Error; err != nil {
+				return err
+			}
+		}
+		for _, model := range []interface{}{&models.KnowledgeObservation{}, &models.StudentMastery{}} {
+			if err := tx.Where("knowledge_point_id = ?", id).Delete(model).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Delete(&models.KnowledgePoint{}, id).Error
+	})
+}
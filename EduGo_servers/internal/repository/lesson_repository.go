@@ -0,0 +1,184 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"EduGo_servers/internal/models"
+	"gorm.io/gorm"
+)
+
+type LessonRepository interface {
+	CreateUnit(ctx context.Context, unit *models.CourseUnit) error
+	GetUnitByID(ctx context.Context, id int64) (*models.CourseUnit, error)
+	GetCourseUnits(ctx context.Context, courseID int64) ([]*models.CourseUnit, error)
+	NextUnitPosition(ctx context.Context, courseID int64) (int, error)
+	UpdateUnit(ctx context.Context, unit *models.CourseUnit) error
+	DeleteUnit(ctx context.Context, id int64) error
+
+	CreateLesson(ctx context.Context, lesson *models.Lesson) error
+	GetLessonByID(ctx context.Context, id int64) (*models.Lesson, error)
+	NextLessonPosition(ctx context.Context, unitID int64) (int, error)
+	UpdateLesson(ctx context.Context, lesson *models.Lesson) error
+	DeleteLesson(ctx context.Context, id int64) error
+
+	GetCourseAssessments(ctx context.Context, courseID int64, ids []int64) ([]*models.Assessment, error)
+}
+
+type lessonRepository struct {
+	db *gorm.DB
+}
+
+func NewLessonRepository(db *gorm.DB) LessonRepository {
+	return &lessonRepository{db: db}
+}
+
+// preloadLessonItems 预加载课时的学习资源和测评
+func preloadLessonItems(db *gorm.DB, prefix string) *gorm.DB {
+	return db.
+		Preload(prefix+"Resources", func(db *gorm.DB) *gorm.DB { return db.Order("resources.id") }).
+		Preload(prefix+"Assessments", func(db *gorm.DB) *gorm.DB { return db.Order("assessments.id") })
+}
+
+func (r *lessonRepository) CreateUnit(ctx context.Context, unit *models.CourseUnit) error {
+	return r.db.WithContext(ctx).Omit("Lessons").Create(unit).Error
+}
+
+func (r *lessonRepository) GetUnitByID(ctx context.Context, id int64) (*models.CourseUnit, error) {
+	var unit models.CourseUnit
+	err := r.db.WithContext(ctx).First(&unit, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return &unit, err
+}
+
+// GetCourseUnits 按顺序获取课程的单元及其课时和课时的学习内容
+func (r *lessonRepository) GetCourseUnits(ctx context.Context, courseID int64) ([]*models.CourseUnit, error) {
+	var units []*models.CourseUnit
+	db := r.db.WithContext(ctx).Preload("Lessons", func(db *gorm.DB) *gorm.DB { return db.Order("position, id") })
+	err := preloadLessonItems(db, "Lessons.").
+		Where("course_id = ?", courseID).
+		Order("position, id").
+		Find(&units).Error
+	return units, err
+}
+
+// NextUnitPosition 新单元排在课程的最后
+func (r *lessonRepository) NextUnitPosition(ctx context.Context, courseID int64) (int, error) {
+	var position *int
+	err := r.db.WithContext(ctx).Model(&models.CourseUnit{}).
+		Where("course_id = ?", courseID).
+		Select("MAX(position)").
+		Scan(&position).Error
+	if err != nil || position == nil {
+		return 0, err
+	}
+	return *position + 1, nil
+}
+
+func (r *lessonRepository) UpdateUnit(ctx context.Context, unit *models.CourseUnit) error {
+	return r.db.WithContext(ctx).Omit("Lessons").Save(unit).Error
+}
+
+// deleteLessons 删除课时及其学习内容、学习记录和进度
+func deleteLessons(tx *gorm.DB, lessonIDs []int64) error {
+	if len(lessonIDs) == 0 {
+		return nil
+	}
+	for _, table := range []string{"lesson_resources", "lesson_assessments"} {
+		if err := tx.Exec("DELETE FROM "+table+" WHERE lesson_id IN ?", lessonIDs).Error; err != nil {
+			return err
+		}
+	}
+	for _, model := range []interface{}{&models.LessonActivity{}, &models.LessonProgress{}} {
+		if err := tx.Where("lesson_id IN ?", lessonIDs).Delete(model).Error; err != nil {
+			return err
+		}
+	}
+	return tx.Where("id IN ?", lessonIDs).Delete(&models.Lesson{}).Error
+}
+
+// DeleteUnit 删除单元及其全部课时
+func (r *lessonRepository) DeleteUnit(ctx context.Context, id int64) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var lessonIDs []int64
+		if err := tx.Model(&models.Lesson{}).Where("unit_id = ?", id).Pluck("id", &lessonIDs).Error; err != nil {
+			return err
+		}
+		if err := deleteLessons(tx, lessonIDs); err != nil {
+			return err
+		}
+		return tx.Delete(&models.CourseUnit{}, id).Error
+	})
+}
+
+// saveLessonItems 替换课时的学习资源和测评
+func saveLessonItems(tx *gorm.DB, lesson *models.Lesson) error {
+	if err := tx.Model(lesson).Association("Resources").Replace(lesson.Resources); err != nil {
+		return err
+	}
+	return tx.Model(lesson).Association("Assessments").Replace(lesson.Assessments)
+}
+
+// CreateLesson 创建课时及其学习内容
+func (r *lessonRepository) CreateLesson(ctx context.Context, lesson *models.Lesson) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Resources", "Assessments").Create(lesson).Error; err != nil {
+			return err
+		}
+		return saveLessonItems(tx, lesson)
+	})
+}
+
+func (r *lessonRepository) GetLessonByID(ctx context.Context, id int64) (*models.Lesson, error) {
+	var lesson models.Lesson
+	err := preloadLessonItems(r.db.WithContext(ctx), "").First(&lesson, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return &lesson, err
+}
+
+// NextLessonPosition 新课时排在单元的最后
+func (r *lessonRepository) NextLessonPosition(ctx context.Context, unitID int64) (int, error) {
+	var position *int
+	err := r.db.WithContext(ctx).Model(&models.Lesson{}).
+		Where("unit_id = ?", unitID).
+		Select("MAX(position)").
+		Scan(&position).Error
+	if err != nil || position == nil {
+		return 0, err
+	}
+	return *position + 1, nil
+}
+
+// UpdateLesson 保存课时及其学习内容
+func (r *lessonRepository) UpdateLesson(ctx context.Context, lesson *models.Lesson) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Resources", "Assessments").Save(lesson).Error; err != nil {
+			return err
+		}
+		return saveLessonItems(tx, lesson)
+	})
+}
+
+// DeleteLesson 删除课时及其学习记录和进度
+func (r *lessonRepository) DeleteLesson(ctx context.Context, id int64) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return deleteLessons(tx, []int64{id})
+	})
+}
+
+// GetCourseAssessments 获取这些测评中属于课程各开课的测评
+func (r *lessonRepository) GetCourseAssessments(ctx context.Context, courseID int64, ids []int64) ([]*models.Assessment, error) {
+	var assessments []*models.Assessment
+	if len(ids) == 0 {
+		return assessments, nil
+	}
+	err := r.db.WithContext(ctx).
+		Joins("JOIN course_offerings ON course_offerings.id = assessments.offering_id").
+		Where("assessments.id IN ? AND course_offerings.course_id = ?", ids, courseID).
+		Find(&assessments).Error
+	return assessments, err
+}
@@ -0,0 +1,365 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"EduGo_servers/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type LTIRepository interface {
+	// 签名密钥
+	GetKeys(ctx context.Context) ([]*models.LTIKey, error)
+	CreateKey(ctx context.Context, key *models.LTIKey) error
+
+	// 外部工具和链接（EduGo作为平台）
+	CreateTool(ctx context.Context, tool *models.LTITool) error
+	GetToolByID(ctx context.Context, id int64) (*models.LTITool, error)
+	GetToolByClientID(ctx context.Context, clientID string) (*models.LTITool, error)
+	GetTools(ctx context.Context) ([]*models.LTITool, error)
+	UpdateTool(ctx context.Context, tool *models.LTITool) error
+	DeleteTool(ctx context.Context, id int64) error
+	CreateLinks(ctx context.Context, links []*models.LTILink) error
+	GetLinkByID(ctx context.Context, id int64) (*models.LTILink, error)
+	GetOfferingLinks(ctx context.Context, offeringID int64) ([]*models.LTILink, error)
+	GetToolOfferingLinks(ctx context.Context, toolID, offeringID int64) ([]*models.LTILink, error)
+	UpdateLink(ctx context.Context, link *models.LTILink) error
+	DeleteLink(ctx context.Context, id int64) error
+	CreateLaunch(ctx context.Context, launch *models.LTILaunch) error
+	GetLaunch(ctx context.Context, token string) (*models.LTILaunch, error)
+	AuthorizeLaunch(ctx context.Context, id int64, now time.Time) (bool, error)
+	DeleteLaunch(ctx context.Context, id int64) (bool, error)
+	UseAssertion(ctx context.Context, assertion *models.LTIAssertion) (bool, error)
+
+	// 外部平台（EduGo作为工具）
+	CreatePlatform(ctx context.Context, platform *models.LTIPlatform) error
+	GetPlatformByID(ctx context.Context, id int64) (*models.LTIPlatform, error)
+	GetPlatformsByIssuer(ctx context.Context, issuer string) ([]*models.LTIPlatform, error)
+	GetPlatforms(ctx context.Context) ([]*models.LTIPlatform, error)
+	PlatformExists(ctx context.Context, issuer, clientID string, excludeID int64) (bool, error)
+	UpdatePlatform(ctx context.Context, platform *models.LTIPlatform) error
+	DeletePlatform(ctx context.Context, id int64) error
+	CreateState(ctx context.Context, state *models.LTIState) error
+	TakeState(ctx context.Context, state string, now time.Time) (*models.LTIState, error)
+	GetLTIUser(ctx context.Context, platformID int64, subject string) (*models.LTIUser, error)
+	CreateLTIUser(ctx context.Context, user *models.User, ltiUser *models.LTIUser) error
+	GetUserLTIAccounts(ctx context.Context, userID int64) ([]*models.LTIUser, error)
+	GetContext(ctx context.Context, platformID int64, contextID string) (*models.LTIContext, error)
+	GetContextByID(ctx context.Context, id int64) (*models.LTIContext, error)
+	GetPlatformContexts(ctx context.Context, platformID int64) ([]*models.LTIContext, error)
+	CreateContext(ctx context.Context, course *models.Course, ltiContext *models.LTIContext) error
+	UpdateContext(ctx context.Context, ltiContext *models.LTIContext) error
+}
+
+type ltiRepository struct {
+	db *gorm.DB
+}
+
+func NewLTIRepository(db *gorm.DB) LTIRepository {
+	return &ltiRepository{db: db}
+}
+
+// GetKeys 获取全部签名密钥，最新的排在前面
+func (r *ltiRepository) GetKeys(ctx context.Context) ([]*models.LTIKey, error) {
+	var keys []*models.LTIKey
+	err := r.db.WithContext(ctx).Order("id DESC").Find(&keys).Error
+	return keys, err
+}
+
+func (r *ltiRepository) CreateKey(ctx context.Context, key *models.LTIKey) error {
+	return r.db.WithContext(ctx).Create(key).Error
+}
+
+func (r *ltiRepository) CreateTool(ctx context.Context, tool *models.LTITool) error {
+	return r.db.WithContext(ctx).Create(tool).Error
+}
+
+func (r *ltiRepository) GetToolByID(ctx context.Context, id int64) (*models.LTITool, error) {
+	var tool models.LTITool
+	err := r.db.WithContext(ctx).First(&tool, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return &tool, err
+}
+
+func (r *ltiRepository) GetToolByClientID(ctx context.Context, clientID string) (*models.LTITool, error) {
+	var tool models.LTITool
+	err := r.db.WithContext(ctx).Where("client_id = ?", clientID).First(&tool).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return &tool, err
+}
+
+func (r *ltiRepository) GetTools(ctx context.Context) ([]*models.LTITool, error) {
+	var tools []*models.LTITool
+	err := r.db.WithContext(ctx).Order("name, id").Find(&tools).Error
+	return tools, err
+}
+
+func (r *ltiRepository) UpdateTool(ctx context.Context, tool *models.LTITool) error {
+	return r.db.WithContext(ctx).Save(tool).Error
+}
+
+// DeleteTool 删除工具及其链接和未完成的启动，链接关联的成绩项保留
+func (r *ltiRepository) DeleteTool(ctx context.Context, id int64) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, model := range []interface{}{&models.LTILink{}, &models.LTILaunch{}, &models.LTIAssertion{}} {
+			if err := tx.Where("tool_id = ?", id).Delete(model).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Delete(&models.LTITool{}, id).Error
+	})
+}
+
+func (r *ltiRepository) CreateLinks(ctx context.Context, links []*models.LTILink) error {
+	if len(links) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).Omit("Tool").Create(&links).Error
+}
+
+func (r *ltiRepository) GetLinkByID(ctx context.Context, id int64) (*models.LTILink, error) {
+	var link models.LTILink
+	err := r.db.WithContext(ctx).Preload("Tool").First(&link, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return &link, err
+}
+
+func (r *ltiRepository) GetOfferingLinks(ctx context.Context, offeringID int64) ([]*models.LTILink, error) {
+	var links []*models.LTILink
+	err := r.db.WithContext(ctx).Preload("Tool").Where("offering_id = ?", offeringID).Order("id").Find(&links).Error
+	return links, err
+}
+
+// GetToolOfferingLinks 获取工具在开课中的链接，用于确定工具可以访问的成绩项
+func (r *ltiRepository) GetToolOfferingLinks(ctx context.Context, toolID, offeringID int64) ([]*models.LTILink, error) {
+	var links []*models.LTILink
+	err := r.db.WithContext(ctx).Where("tool_id = ? AND offering_id = ?", toolID, offeringID).Order("id").Find(&links).Error
+	return links, err
+}
+
+func (r *ltiRepository) UpdateLink(ctx context.Context, link *models.LTILink) error {
+	return r.db.WithContext(ctx).Omit("Tool").Save(link).Error
+}
+
+func (r *ltiRepository) DeleteLink(ctx context.Context, id int64) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("link_id = ?", id).Delete(&models.LTILaunch{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.LTILink{}, id).Error
+	})
+}
+
+// CreateLaunch 保存启动，同时清理已过期的启动
+func (r *ltiRepository) CreateLaunch(ctx context.Context, launch *models.LTILaunch) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("expires_at < ?", launch.CreatedAt).Delete(&models.LTILaunch{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(launch).Error
+	})
+}
+
+func (r *ltiRepository) GetLaunch(ctx context.Context, token string) (*models.LTILaunch, error) {
+	var launch models.LTILaunch
+	err := r.db.WithContext(ctx).Where("token = ?", token).First(&launch).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return &launch, err
+}
+
+// AuthorizeLaunch 标记启动已签发id_token，每次启动只能签发一次，已签发过时返回false
+func (r *ltiRepository) AuthorizeLaunch(ctx context.Context, id int64, now time.Time) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&models.LTILaunch{}).
+		Where("id = ? AND authorized_at IS NULL", id).
+		Update("authorized_at", now)
+	return result.RowsAffected > 0, result.Error
+}
+
+// DeleteLaunch 删除启动，已被删除时返回false，用于保证深度链接的响应只处理一次
+func (r *ltiRepository) DeleteLaunch(ctx context.Context, id int64) (bool, error) {
+	result := r.db.WithContext(ctx).Delete(&models.LTILaunch{}, id)
+	return result.RowsAffected > 0, result.Error
+}
+
+// UseAssertion 记录工具使用的客户端断言，同时清理已过期的记录，断言已被使用过时返回false
+func (r *ltiRepository) UseAssertion(ctx context.Context, assertion *models.LTIAssertion) (bool, error) {
+	var used bool
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("expires_at < ?", time.Now()).Delete(&models.LTIAssertion{}).Error; err != nil {
+			return err
+		}
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(assertion)
+		used = result.RowsAffected > 0
+		return result.Error
+	})
+	return used, err
+}
+
+func (r *ltiRepository) CreatePlatform(ctx context.Context, platform *models.LTIPlatform) error {
+	return r.db.WithContext(ctx).Create(platform).Error
+}
+
+func (r *ltiRepository) GetPlatformByID(ctx context.Context, id int64) (*models.LTIPlatform, error) {
+	var platform models.LTIPlatform
+	err := r.db.WithContext(ctx).First(&platform, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return &platform, err
+}
+
+func (r *ltiRepository) GetPlatformsByIssuer(ctx context.Context, issuer string) ([]*models.LTIPlatform, error) {
+	var platforms []*models.LTIPlatform
+	err := r.db.WithContext(ctx).Where("issuer = ?", issuer).Order("id").Find(&platforms).Error
+	return platforms, err
+}
+
+func (r *ltiRepository) GetPlatforms(ctx context.Context) ([]*models.LTIPlatform, error) {
+	var platforms []*models.LTIPlatform
+	err := r.db.WithContext(ctx).Order("name, id").Find(&platforms).Error
+	return platforms, err
+}
+
+// PlatformExists 检查同一平台和客户端ID是否已注册
+func (r *ltiRepository) PlatformExists(ctx context.Context, issuer, clientID string, excludeID int64) (bool, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&models.LTIPlatform{}).
+		Where("issuer = ? AND client_id = ? AND id <> ?", issuer, clientID, excludeID).
+		Count(&count).Error
+	return count > 0, err
+}
+
+func (r *ltiRepository) UpdatePlatform(ctx context.Context, platform *models.LTIPlatform) error {
+	return r.db.WithContext(ctx).Save(platform).Error
+}
+
+// DeletePlatform 删除平台及其用户和课程的对应关系，已创建的EduGo用户和课程保留
+func (r *ltiRepository) DeletePlatform(ctx context.Context, id int64) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, model := range []interface{}{&models.LTIUser{}, &models.LTIContext{}, &models.LTIState{}} {
+			if err := tx.Where("platform_id = ?", id).Delete(model).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Delete(&models.LTIPlatform{}, id).Error
+	})
+}
+
+// CreateState 保存登录的state，同时清理已过期的state
+func (r *ltiRepository) CreateState(ctx context.Context, state *models.LTIState) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("expires_at < ?", time.Now()).Delete(&models.LTIState{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(state).Error
+	})
+}
+
+// TakeState 取出并删除未过期的state，不存在、已使用或已过期时返回nil
+func (r *ltiRepository) TakeState(ctx context.Context, value string, now time.Time) (*models.LTIState, error) {
+	var state models.LTIState
+	err := r.db.WithContext(ctx).Where("state = ?", value).First(&state).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	result := r.db.WithContext(ctx).Delete(&models.LTIState{}, state.ID)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 || now.After(state.ExpiresAt) {
+		return nil, nil
+	}
+	return &state, nil
+}
+
+// GetLTIUser 获取外部用户对应的EduGo用户，用户已删除时User为空
+func (r *ltiRepository) GetLTIUser(ctx context.Context, platformID int64, subject string) (*models.LTIUser, error) {
+	var ltiUser models.LTIUser
+	err := r.db.WithContext(ctx).Preload("User").
+		Where("platform_id = ? AND subject = ?", platformID, subject).
+		First(&ltiUser).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return &ltiUser, err
+}
+
+// CreateLTIUser 在一个事务中创建EduGo用户和外部用户的对应关系
+func (r *ltiRepository) CreateLTIUser(ctx context.Context, user *models.User, ltiUser *models.LTIUser) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(user).Error; err != nil {
+			return err
+		}
+		ltiUser.UserID = user.ID
+		return tx.Omit("Platform", "User").Create(ltiUser).Error
+	})
+}
+
+func (r *ltiRepository) GetUserLTIAccounts(ctx context.Context, userID int64) ([]*models.LTIUser, error) {
+	var accounts []*models.LTIUser
+	err := r.db.WithContext(ctx).Preload("Platform").Where("user_id = ?", userID).Order("id").Find(&accounts).Error
+	return accounts, err
+}
+
+func (r *ltiRepository) GetContext(ctx context.Context, platformID int64, contextID string) (*models.LTIContext, error) {
+	var ltiContext models.LTIContext
+	err := r.db.WithContext(ctx).Where("platform_id = ? AND context_id = ?", platformID, contextID).First(&ltiContext).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return &ltiContext, err
+}
+
+func (r *ltiRepository) GetContextByID(ctx context.Context, id int64) (*models.LTIContext, error) {
+	var ltiContext models.LTIContext
+	err := r.db.WithContext(ctx).First(&ltiContext, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return &ltiContext, err
+}
+
+func (r *ltiRepository) GetPlatformContexts(ctx context.Context, platformID int64) ([]*models.LTIContext, error) {
+	var contexts []*models.LTIContext
+	err := r.db.WithContext(ctx).Where("platform_id = ?", platformID).Order("id").Find(&contexts).Error
+	return contexts, err
+}
+
+// CreateContext 在一个事务中创建EduGo课程和外部课程的对应关系
+func (r *ltiRepository) CreateContext(ctx context.Context, course *models.Course, ltiContext *models.LTIContext) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(course).Error; err != nil {
+			return err
+		}
+		ltiContext.CourseID = course.ID
+		return tx.Create(ltiContext).Error
+	})
+}
+
+func (r *ltiRepository) UpdateContext(ctx context.Context, ltiContext *models.LTIContext) error {
+	return r.db.WithContext(ctx).Save(ltiContext).Error
+}
+
+// deleteLTIData 删除用户在外部平台的身份对应关系和未完成的启动
+func deleteLTIData(tx *gorm.DB, userIDs []int64) error {
+	for _, model := range []interface{}{&models.LTIUser{}, &models.LTILaunch{}} {
+		if err := tx.Where("user_id IN ?", userIDs).Delete(model).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,150 @@
+package repository
+
+import (
+	"context"
+
+	"EduGo_servers/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type MasteryRepository interface {
+	GetPendingAttempts(ctx context.Context, afterID int64, maxFailures, limit int) ([]*models.AssessmentAttempt, error)
+	RecordAttemptFailure(ctx context.Context, attemptID int64) (int, error)
+	GetAttemptKnowledgePoints(ctx context.Context, attemptID int64) ([]int64, error)
+	GetObservations(ctx context.Context, studentID int64, knowledgePointIDs []int64, excludeAttemptID int64) ([]*models.KnowledgeObservation, error)
+	SaveAttemptMastery(ctx context.Context, attempt *models.AssessmentAttempt, observations []*models.KnowledgeObservation, masteries []*models.StudentMastery, cleared []int64) error
+	ResetAttempts(ctx context.Context) (int64, error)
+
+	GetStudentMasteries(ctx context.Context, studentIDs []int64, knowledgePointIDs []int64) ([]*models.StudentMastery, error)
+	GetStudentSubjects(ctx context.Context, studentID int64) ([]string, error)
+}
+
+type masteryRepository struct {
+	db *gorm.DB
+}
+
+func NewMasteryRepository(db *gorm.DB) MasteryRepository {
+	return &masteryRepository{db: db}
+}
+
+// GetPendingAttempts 按ID顺序获取afterID之后已交卷但评分结果还没有计入掌握度的作答，包括交卷后又被教师重新评分的作答
+// 连续失败达到maxFailures次的作答不再获取
+func (r *masteryRepository) GetPendingAttempts(ctx context.Context, afterID int64, maxFailures, limit int) ([]*models.AssessmentAttempt, error) {
+	var attempts []*models.AssessmentAttempt
+	err := r.db.WithContext(ctx).
+		Preload("Answers", orderAnswers).
+		Where("status <> ? AND (mastery_updated_at IS NULL OR mastery_updated_at < updated_at)", models.AttemptInProgress).
+		Where("id > ? AND mastery_failures < ?", afterID, maxFailures).
+		Order("id").
+		Limit(limit).
+		Find(&attempts).Error
+	return attempts, err
+}
+
+// RecordAttemptFailure 记录作答计入掌握度失败一次，返回连续失败的次数
+func (r *masteryRepository) RecordAttemptFailure(ctx context.Context, attemptID int64) (int, error) {
+	var failures int
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// 不修改updated_at，以便之后的评分变化仍能被发现
+		err := tx.Model(&models.AssessmentAttempt{}).Where("id = ?", attemptID).
+			UpdateColumn("mastery_failures", gorm.Expr("mastery_failures + 1")).Error
+		if err != nil {
+			return err
+		}
+		return tx.Model(&models.AssessmentAttempt{}).Where("id = ?", attemptID).
+			Pluck("mastery_failures", &failures).Error
+	})
+	return failures, err
+}
+
+// GetAttemptKnowledgePoints 获取作答上次计入掌握度时涉及的知识点
+func (r *masteryRepository) GetAttemptKnowledgePoints(ctx context.Context, attemptID int64) ([]int64, error) {
+	var ids []int64
+	err := r.db.WithContext(ctx).Model(&models.KnowledgeObservation{}).
+		Where("attempt_id = ?", attemptID).
+		Distinct().
+		Pluck("knowledge_point_id", &ids).Error
+	return ids, err
+}
+
+// GetObservations 按时间顺序获取学生在这些知识点上的作答结果，excludeAttemptID不为0时不包括该次作答
+func (r *masteryRepository) GetObservations(ctx context.Context, studentID int64, knowledgePointIDs []int64, excludeAttemptID int64) ([]*models.KnowledgeObservation, error) {
+	var observations []*models.KnowledgeObservation
+	if len(knowledgePointIDs) == 0 {
+		return observations, nil
+	}
+	err := r.db.WithContext(ctx).
+		Where("student_id = ? AND knowledge_point_id IN ? AND attempt_id <> ?", studentID, knowledgePointIDs, excludeAttemptID).
+		Order("observed_at, attempt_id, id").
+		Find(&observations).Error
+	return observations, err
+}
+
+// SaveAttemptMastery 替换作答计入的作答结果并保存重新估计的掌握度，cleared为不再有作答结果的知识点
+// 作答标记为已按当前的评分结果处理
+func (r *masteryRepository) SaveAttemptMastery(ctx context.Context, attempt *models.AssessmentAttempt, observations []*models.KnowledgeObservation, masteries []*models.StudentMastery, cleared []int64) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("attempt_id = ?", attempt.ID).Delete(&models.KnowledgeObservation{}).Error; err != nil {
+			return err
+		}
+		if len(observations) > 0 {
+			if err := tx.Create(&observations).Error; err != nil {
+				return err
+			}
+		}
+		if len(masteries) > 0 {
+			err := tx.Clauses(clause.OnConflict{
+				Columns:   []clause.Column{{Name: "student_id"}, {Name: "knowledge_point_id"}},
+				DoUpdates: clause.AssignmentColumns([]string{"probability", "observations", "correct", "last_observed_at", "updated_at"}),
+			}).Create(&masteries).Error
+			if err != nil {
+				return err
+			}
+		}
+		if len(cleared) > 0 {
+			err := tx.Where("student_id = ? AND knowledge_point_id IN ?", attempt.StudentID, cleared).
+				Delete(&models.StudentMastery{}).Error
+			if err != nil {
+				return err
+			}
+		}
+		// 不修改updated_at，以便之后的评分变化仍能被发现
+		return tx.Model(&models.AssessmentAttempt{}).Where("id = ?", attempt.ID).
+			UpdateColumns(map[string]interface{}{"mastery_updated_at": attempt.UpdatedAt, "mastery_failures": 0}).Error
+	})
+}
+
+// ResetAttempts 把全部已处理和多次处理失败的作答标记为待处理，以便按最新的题目知识点重新计算掌握度
+func (r *masteryRepository) ResetAttempts(ctx context.Context) (int64, error) {
+	result := r.db.WithContext(ctx).Model(&models.AssessmentAttempt{}).
+		Where("mastery_updated_at IS NOT NULL OR mastery_failures > 0").
+		UpdateColumns(map[string]interface{}{"mastery_updated_at": nil, "mastery_failures": 0})
+	return result.RowsAffected, result.Error
+}
+
+// GetStudentMasteries 获取学生的知识点掌握度，knowledgePointIDs为空时返回全部知识点
+func (r *masteryRepository) GetStudentMasteries(ctx context.Context, studentIDs []int64, knowledgePointIDs []int64) ([]*models.StudentMastery, error) {
+	var masteries []*models.StudentMastery
+	if len(studentIDs) == 0 {
+		return masteries, nil
+	}
+	db := r.db.WithContext(ctx).Where("student_id IN ?", studentIDs)
+	if len(knowledgePointIDs) > 0 {
+		db = db.Where("knowledge_point_id IN ?", knowledgePointIDs)
+	}
+	err := db.Order("student_id, knowledge_point_id").Find(&masteries).Error
+	return masteries, err
+}
+
+// GetStudentSubjects 获取学生有掌握度记录的学科
+func (r *masteryRepository) GetStudentSubjects(ctx context.Context, studentID int64) ([]string, error) {
+	var subjects []string
+	err := r.db.WithContext(ctx).Model(&models.StudentMastery{}).
+		Joins("JOIN knowledge_points ON knowledge_points.id = student_masteries.knowledge_point_id").
+		Where("student_masteries.student_id = ?", studentID).
+		Distinct().
+		Order("knowledge_points.subject").
+		Pluck("knowledge_points.subject", &subjects).Error
+	return subjects, err
+}
@@ -0,0 +1,210 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"EduGo_servers/internal/models"
+	"gorm.io/gorm"
+)
+
+// MistakeListQuery 错题本查询条件
+type MistakeListQuery struct {
+	Page              int
+	PageSize          int
+	StudentID         int64
+	Status            string
+	Subject           string
+	KnowledgePointIDs []int64 // 关联到其中任一知识点的题目
+}
+
+type MistakeRepository interface {
+	GetStudentEntries(ctx context.Context, studentID int64, questionIDs []int64) ([]*models.MistakeEntry, error)
+	SaveEntries(ctx context.Context, entries []*models.MistakeEntry, removed []int64) error
+	ListEntries(ctx context.Context, query *MistakeListQuery) ([]*models.MistakeEntry, int64, error)
+	GetEntryByID(ctx context.Context, id int64) (*models.MistakeEntry, error)
+	DeleteEntry(ctx context.Context, id int64) error
+	GetAllStudentEntries(ctx context.Context, studentID int64) ([]*models.MistakeEntry, error)
+
+	// 重练
+	GetPracticeEntries(ctx context.Context, studentID int64, subject string, knowledgePointIDs []int64, limit int) ([]*models.MistakeEntry, error)
+	CreatePractice(ctx context.Context, practice *models.MistakePractice) error
+	GetPracticeByID(ctx context.Context, id int64) (*models.MistakePractice, error)
+	SavePracticeAnswer(ctx context.Context, practice *models.MistakePractice, item *models.MistakePracticeItem, entry *models.MistakeEntry) error
+	GetStudentPractices(ctx context.Context, studentID int64) ([]*models.MistakePractice, error)
+}
+
+type mistakeRepository struct {
+	db *gorm.DB
+}
+
+func NewMistakeRepository(db *gorm.DB) MistakeRepository {
+	return &mistakeRepository{db: db}
+}
+
+// preloadArchivedQuestion 加载field指定的题目及其选项、答案和知识点，题目从题库删除后仍然加载
+func preloadArchivedQuestion(db *gorm.DB, field string) *gorm.DB {
+	return db.
+		Preload(field, func(db *gorm.DB) *gorm.DB { return db.Unscoped() }).
+		Preload(field+".Options", func(db *gorm.DB) *gorm.DB { return db.Order("position") }).
+		Preload(field+".Answers", func(db *gorm.DB) *gorm.DB { return db.Order("blank, id") }).
+		Preload(field+".Tags", func(db *gorm.DB) *gorm.DB { return db.Order("id") })
+}
+
+// filterEntries 按学科和知识点筛选错题
+func (r *mistakeRepository) filterEntries(ctx context.Context, db *gorm.DB, subject string, knowledgePointIDs []int64) *gorm.DB {
+	if subject != "" {
+		db = db.Where("mistake_entries.question_id IN (?)", r.db.WithContext(ctx).Unscoped().
+			Model(&models.Question{}).Select("id").Where("subject = ?", subject))
+	}
+	if len(knowledgePointIDs) > 0 {
+		db = db.Where("mistake_entries.question_id IN (?)", r.db.WithContext(ctx).
+			Model(&models.QuestionTag{}).Select("question_id").Where("knowledge_point_id IN ?", knowledgePointIDs))
+	}
+	return db
+}
+
+// GetStudentEntries 获取学生错题本中这些题目的记录，包括已移出的错题
+func (r *mistakeRepository) GetStudentEntries(ctx context.Context, studentID int64, questionIDs []int64) ([]*models.MistakeEntry, error) {
+	var entries []*models.MistakeEntry
+	if len(questionIDs) == 0 {
+		return entries, nil
+	}
+	err := r.db.WithContext(ctx).Where("student_id = ? AND question_id IN ?", studentID, questionIDs).Find(&entries).Error
+	return entries, err
+}
+
+// SaveEntries 在一个事务中保存错题记录，removed为撤销后不再是错题的记录
+func (r *mistakeRepository) SaveEntries(ctx context.Context, entries []*models.MistakeEntry, removed []int64) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, entry := range entries {
+			if err := tx.Omit("Question", "Assessment").Save(entry).Error; err != nil {
+				return err
+			}
+		}
+		if len(removed) > 0 {
+			return tx.Where("id IN ?", removed).Delete(&models.MistakeEntry{}).Error
+		}
+		return nil
+	})
+}
+
+// ListEntries 分页查询学生的错题，最近答错的排在前面
+func (r *mistakeRepository) ListEntries(ctx context.Context, query *MistakeListQuery) ([]*models.MistakeEntry, int64, error) {
+	if query.Page < 1 {
+		query.Page = 1
+	}
+	if query.PageSize <= 0 {
+		query.PageSize = DefaultPageSize
+	}
+	if query.PageSize > MaxPageSize {
+		query.PageSize = MaxPageSize
+	}
+
+	db := r.db.WithContext(ctx).Model(&models.MistakeEntry{}).Where("student_id = ?", query.StudentID)
+	if query.Status != "" {
+		db = db.Where("status = ?", query.Status)
+	}
+	db = r.filterEntries(ctx, db, query.Subject, query.KnowledgePointIDs)
+
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var entries []*models.MistakeEntry
+	err := preloadArchivedQuestion(db, "Question").
+		Preload("Assessment").
+		Order("last_wrong_at DESC, id DESC").
+		Offset((query.Page - 1) * query.PageSize).
+		Limit(query.PageSize).
+		Find(&entries).Error
+	return entries, total, err
+}
+
+func (r *mistakeRepository) GetEntryByID(ctx context.Context, id int64) (*models.MistakeEntry, error) {
+	var entry models.MistakeEntry
+	err := r.db.WithContext(ctx).First(&entry, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return &entry, err
+}
+
+func (r *mistakeRepository) DeleteEntry(ctx context.Context, id int64) error {
+	return r.db.WithContext(ctx).Delete(&models.MistakeEntry{}, id).Error
+}
+
+// GetAllStudentEntries 获取学生的全部错题，包括已移出的错题
+func (r *mistakeRepository) GetAllStudentEntries(ctx context.Context, studentID int64) ([]*models.MistakeEntry, error) {
+	var entries []*models.MistakeEntry
+	err := r.db.WithContext(ctx).Where("student_id = ?", studentID).Order("id").Find(&entries).Error
+	return entries, err
+}
+
+// GetPracticeEntries 获取可以重练的错题：未移出、题目仍在题库中且为客观题、来源试卷允许显示答案
+// 连续答对次数少的和较久没有作答的排在前面
+func (r *mistakeRepository) GetPracticeEntries(ctx context.Context, studentID int64, subject string, knowledgePointIDs []int64, limit int) ([]*models.MistakeEntry, error) {
+	db := r.db.WithContext(ctx).Model(&models.MistakeEntry{}).
+		Joins("JOIN questions ON questions.id = mistake_entries.question_id AND questions.deleted_at IS NULL").
+		Joins("JOIN assessments ON assessments.id = mistake_entries.assessment_id AND assessments.deleted_at IS NULL").
+		Where("mistake_entries.student_id = ? AND mistake_entries.status = ?", studentID, models.MistakeActive).
+		Where("questions.type <> ? AND assessments.show_answers = ?", models.QuestionShortAnswer, true)
+	db = r.filterEntries(ctx, db, subject, knowledgePointIDs)
+
+	var entries []*models.MistakeEntry
+	err := db.Select("mistake_entries.*").
+		Order("mistake_entries.correct_streak, mistake_entries.last_answered_at, mistake_entries.id").
+		Limit(limit).
+		Find(&entries).Error
+	return entries, err
+}
+
+// CreatePractice 创建重练及其题目
+func (r *mistakeRepository) CreatePractice(ctx context.Context, practice *models.MistakePractice) error {
+	return r.db.WithContext(ctx).Omit("Items.Question").Create(practice).Error
+}
+
+// GetPracticeByID 获取重练，题目按顺序排列
+func (r *mistakeRepository) GetPracticeByID(ctx context.Context, id int64) (*models.MistakePractice, error) {
+	var practice models.MistakePractice
+	db := r.db.WithContext(ctx).Preload("Items", func(db *gorm.DB) *gorm.DB { return db.Order("position") })
+	err := preloadArchivedQuestion(db, "Items.Question").
+		First(&practice, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return &practice, err
+}
+
+// SavePracticeAnswer 在一个事务中保存重练中一道题的作答、重练的进度和对应的错题，错题已删除时entry为nil
+func (r *mistakeRepository) SavePracticeAnswer(ctx context.Context, practice *models.MistakePractice, item *models.MistakePracticeItem, entry *models.MistakeEntry) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Question").Save(item).Error; err != nil {
+			return err
+		}
+		err := tx.Model(practice).Updates(map[string]interface{}{
+			"status":        practice.Status,
+			"correct_count": practice.CorrectCount,
+			"completed_at":  practice.CompletedAt,
+		}).Error
+		if err != nil {
+			return err
+		}
+		if entry == nil {
+			return nil
+		}
+		return tx.Omit("Question", "Assessment").Save(entry).Error
+	})
+}
+
+// GetStudentPractices 获取学生的全部错题重练及每道题的作答
+func (r *mistakeRepository) GetStudentPractices(ctx context.Context, studentID int64) ([]*models.MistakePractice, error) {
+	var practices []*models.MistakePractice
+	err := r.db.WithContext(ctx).
+		Preload("Items", func(db *gorm.DB) *gorm.DB { return db.Order("position") }).
+		Where("student_id = ?", studentID).
+		Order("id").
+		Find(&practices).Error
+	return practices, err
+}
@@ -0,0 +1,80 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"EduGo_servers/internal/models"
+	"gorm.io/gorm"
+)
+
+type NotificationRepository interface {
+	CreateNotifications(ctx context.Context, notifications []*models.Notification) error
+	GetNotifications(ctx context.Context, userID int64, unreadOnly bool, page, pageSize int) ([]*models.Notification, int64, error)
+	CountUnread(ctx context.Context, userID int64) (int64, error)
+	MarkRead(ctx context.Context, userID, id int64) (bool, error)
+	MarkAllRead(ctx context.Context, userID int64) (int64, error)
+}
+
+type notificationRepository struct {
+	db *gorm.DB
+}
+
+func NewNotificationRepository(db *gorm.DB) NotificationRepository {
+	return &notificationRepository{db: db}
+}
+
+func (r *notificationRepository) CreateNotifications(ctx context.Context, notifications []*models.Notification) error {
+	return r.db.WithContext(ctx).Create(notifications).Error
+}
+
+// GetNotifications 分页获取用户的通知，最新的在前
+func (r *notificationRepository) GetNotifications(ctx context.Context, userID int64, unreadOnly bool, page, pageSize int) ([]*models.Notification, int64, error) {
+	db := r.db.WithContext(ctx).Model(&models.Notification{}).Where("user_id = ?", userID)
+	if unreadOnly {
+		db = db.Where("read_at IS NULL")
+	}
+
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var notifications []*models.Notification
+	err := db.Order("id DESC").
+		Offset((page - 1) * pageSize).
+		Limit(pageSize).
+		Find(&notifications).Error
+	return notifications, total, err
+}
+
+func (r *notificationRepository) CountUnread(ctx context.Context, userID int64) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&models.Notification{}).
+		Where("user_id = ? AND read_at IS NULL", userID).
+		Count(&count).Error
+	return count, err
+}
+
+// MarkRead 将通知标记为已读，通知不存在或不属于该用户时返回false
+func (r *notificationRepository) MarkRead(ctx context.Context, userID, id int64) (bool, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&models.Notification{}).
+		Where("id = ? AND user_id = ?", id, userID).
+		Count(&count).Error
+	if err != nil || count == 0 {
+		return false, err
+	}
+
+	err = r.db.WithContext(ctx).Model(&models.Notification{}).
+		Where("id = ? AND read_at IS NULL", id).
+		Update("read_at", time.Now()).Error
+	return err == nil, err
+}
+
+func (r *notificationRepository) MarkAllRead(ctx context.Context, userID int64) (int64, error) {
+	result := r.db.WithContext(ctx).Model(&models.Notification{}).
+		Where("user_id = ? AND read_at IS NULL", userID).
+		Update("read_at", time.Now())
+	return result.RowsAffected, result.Error
+}
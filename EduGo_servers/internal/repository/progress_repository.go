@@ -0,0 +1,206 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"EduGo_servers/internal/models"
+	"EduGo_servers/internal/progress"
+	"gorm.io/gorm"
+)
+
+// AttemptSummary 学生在一份测评中已交卷作答的汇总
+type AttemptSummary struct {
+	BestScore     *float64 // 最高得分率，0到100，没有交卷的作答时为空
+	TimeSpent     int      // 各次作答的用时之和（秒）
+	LastSubmitted *time.Time
+}
+
+type ProgressRepository interface {
+	GetResourceLessons(ctx context.Context, resourceID int64, courseIDs []int64) ([]*models.Lesson, error)
+	GetAssessmentLessons(ctx context.Context, assessmentID int64) ([]*models.Lesson, error)
+	GetItemLessonIDs(ctx context.Context, itemType string, itemID int64) ([]int64, error)
+	GetAttemptSummary(ctx context.Context, assessmentID, studentID int64) (*AttemptSummary, error)
+
+	GetActivities(ctx context.Context, studentID int64, lessonIDs []int64) ([]*models.LessonActivity, error)
+	SaveActivities(ctx context.Context, activities []*models.LessonActivity) error
+	RefreshProgress(ctx context.Context, lesson *models.Lesson, studentIDs []int64) error
+	GetProgress(ctx context.Context, studentIDs []int64, lessonIDs []int64) ([]*models.LessonProgress, error)
+
+	GetAllStudentActivities(ctx context.Context, studentID int64) ([]*models.LessonActivity, error)
+	GetAllStudentProgress(ctx context.Context, studentID int64) ([]*models.LessonProgress, error)
+}
+
+type progressRepository struct {
+	db *gorm.DB
+}
+
+func NewProgressRepository(db *gorm.DB) ProgressRepository {
+	return &progressRepository{db: db}
+}
+
+// GetResourceLessons 获取这些课程中包含该资源的课时
+func (r *progressRepository) GetResourceLessons(ctx context.Context, resourceID int64, courseIDs []int64) ([]*models.Lesson, error) {
+	var lessons []*models.Lesson
+	if len(courseIDs) == 0 {
+		return lessons, nil
+	}
+	db := r.db.WithContext(ctx)
+	err := preloadLessonItems(db, "").
+		Where("course_id IN ? AND id IN (?)", courseIDs,
+			db.Table("lesson_resources").Select("lesson_id").Where("resource_id = ?", resourceID)).
+		Order("id").
+		Find(&lessons).Error
+	return lessons, err
+}
+
+// GetAssessmentLessons 获取包含该测评的课时
+func (r *progressRepository) GetAssessmentLessons(ctx context.Context, assessmentID int64) ([]*models.Lesson, error) {
+	var lessons []*models.Lesson
+	db := r.db.WithContext(ctx)
+	err := preloadLessonItems(db, "").
+		Where("id IN (?)", db.Table("lesson_assessments").Select("lesson_id").Where("assessment_id = ?", assessmentID)).
+		Order("id").
+		Find(&lessons).Error
+	return lessons, err
+}
+
+// GetItemLessonIDs 获取包含该学习内容的全部课时
+func (r *progressRepository) GetItemLessonIDs(ctx context.Context, itemType string, itemID int64) ([]int64, error) {
+	table, column := "lesson_resources", "resource_id"
+	if itemType == models.LessonItemAssessment {
+		table, column = "lesson_assessments", "assessment_id"
+	}
+	var lessonIDs []int64
+	err := r.db.WithContext(ctx).Table(table).Where(column+" = ?", itemID).Distinct().Pluck("lesson_id", &lessonIDs).Error
+	return lessonIDs, err
+}
+
+// GetAttemptSummary 汇总学生在一份测评中已交卷的作答：最高得分率和总用时
+func (r *progressRepository) GetAttemptSummary(ctx context.Context, assessmentID, studentID int64) (*AttemptSummary, error) {
+	var attempts []*models.AssessmentAttempt
+	err := r.db.WithContext(ctx).
+		Where("assessment_id = ? AND student_id = ? AND status <> ? AND submitted_at IS NOT NULL",
+			assessmentID, studentID, models.AttemptInProgress).
+		Find(&attempts).Error
+	if err != nil {
+		return nil, err
+	}
+
+	summary := &AttemptSummary{}
+	for _, attempt := range attempts {
+		if attempt.MaxScore > 0 {
+			score := attempt.Score / attempt.MaxScore * 100
+			if summary.BestScore == nil || score > *summary.BestScore {
+				summary.BestScore = &score
+			}
+		}
+		if seconds := int(attempt.SubmittedAt.Sub(attempt.StartedAt).Seconds()); seconds > 0 {
+			summary.TimeSpent += seconds
+		}
+		if summary.LastSubmitted == nil || attempt.SubmittedAt.After(*summary.LastSubmitted) {
+			summary.LastSubmitted = attempt.SubmittedAt
+		}
+	}
+	return summary, nil
+}
+
+func (r *progressRepository) GetActivities(ctx context.Context, studentID int64, lessonIDs []int64) ([]*models.LessonActivity, error) {
+	var activities []*models.LessonActivity
+	if len(lessonIDs) == 0 {
+		return activities, nil
+	}
+	err := r.db.WithContext(ctx).
+		Where("student_id = ? AND lesson_id IN ?", studentID, lessonIDs).
+		Order("id").
+		Find(&activities).Error
+	return activities, err
+}
+
+// SaveActivities 在一个事务中保存学习记录
+func (r *progressRepository) SaveActivities(ctx context.Context, activities []*models.LessonActivity) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, activity := range activities {
+			if err := tx.Save(activity).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// RefreshProgress 按学习记录重新汇总学生在课时中的进度，studentIDs为空时汇总有学习记录的全部学生
+// 课时的学习内容变化后需要重新汇总
+func (r *progressRepository) RefreshProgress(ctx context.Context, lesson *models.Lesson, studentIDs []int64) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		activities := tx.Where("lesson_id = ?", lesson.ID)
+		records := tx.Where("lesson_id = ?", lesson.ID)
+		if len(studentIDs) > 0 {
+			activities = activities.Where("student_id IN ?", studentIDs)
+			records = records.Where("student_id IN ?", studentIDs)
+		}
+
+		var lessonActivities []*models.LessonActivity
+		if err := activities.Order("id").Find(&lessonActivities).Error; err != nil {
+			return err
+		}
+		var existing []*models.LessonProgress
+		if err := records.Find(&existing).Error; err != nil {
+			return err
+		}
+
+		byStudent := map[int64][]*models.LessonActivity{}
+		for _, activity := range lessonActivities {
+			byStudent[activity.StudentID] = append(byStudent[activity.StudentID], activity)
+		}
+		saved := map[int64]*models.LessonProgress{}
+		for _, record := range existing {
+			saved[record.StudentID] = record
+		}
+
+		for studentID, studentActivities := range byStudent {
+			record, ok := saved[studentID]
+			if !ok {
+				record = &models.LessonProgress{StudentID: studentID}
+			}
+			progress.Summarize(record, lesson, studentActivities)
+			if err := tx.Save(record).Error; err != nil {
+				return err
+			}
+		}
+		// 没有学习记录的学生不保存进度
+		for studentID, record := range saved {
+			if _, ok := byStudent[studentID]; !ok {
+				if err := tx.Delete(record).Error; err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// GetProgress 获取学生在这些课时中的进度，没有学习记录的课时没有进度
+func (r *progressRepository) GetProgress(ctx context.Context, studentIDs []int64, lessonIDs []int64) ([]*models.LessonProgress, error) {
+	var records []*models.LessonProgress
+	if len(studentIDs) == 0 || len(lessonIDs) == 0 {
+		return records, nil
+	}
+	err := r.db.WithContext(ctx).
+		Where("student_id IN ? AND lesson_id IN ?", studentIDs, lessonIDs).
+		Order("student_id, lesson_id").
+		Find(&records).Error
+	return records, err
+}
+
+func (r *progressRepository) GetAllStudentActivities(ctx context.Context, studentID int64) ([]*models.LessonActivity, error) {
+	var activities []*models.LessonActivity
+	err := r.db.WithContext(ctx).Where("student_id = ?", studentID).Order("id").Find(&activities).Error
+	return activities, err
+}
+
+func (r *progressRepository) GetAllStudentProgress(ctx context.Context, studentID int64) ([]*models.LessonProgress, error) {
+	var records []*models.LessonProgress
+	err := r.db.WithContext(ctx).Where("student_id = ?", studentID).Order("lesson_id").Find(&records).Error
+	return records, err
+}
@@ -0,0 +1,253 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"EduGo_servers/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// QuestionListQuery 题库查询条件
+type QuestionListQuery struct {
+	Page           int
+	PageSize       int
+	Type           string
+	Subject        string
+	KnowledgePoint string
+	Difficulty     int
+	CreatedBy      int64
+	Search         string  // 按题干模糊搜索
+	IDs            []int64 // 只查询指定的题目
+
+	KnowledgePointIDs []int64 // 关联到其中任一知识点的题目
+}
+
+// ResponseRecord 一条已评分的客观题作答，用于标定题目参数
+type ResponseRecord struct {
+	AttemptID  int64
+	QuestionID int64
+	Correct    bool
+}
+
+type QuestionRepository interface {
+	CreateQuestion(ctx context.Context, question *models.Question) error
+	CreateQuestions(ctx context.Context, questions []*models.Question) error
+	GetQuestionByID(ctx context.Context, id int64) (*models.Question, error)
+	GetQuestionsByIDs(ctx context.Context, ids []int64) ([]*models.Question, error)
+	ListQuestions(ctx context.Context, query *QuestionListQuery) ([]*models.Question, int64, error)
+	FindQuestions(ctx context.Context, query *QuestionListQuery, limit int) ([]*models.Question, error)
+	UpdateQuestion(ctx context.Context, question *models.Question) error
+	DeleteQuestion(ctx context.Context, id int64) error
+	GetKnowledgePoints(ctx context.Context, subject string) ([]string, error)
+
+	// 项目反应理论参数
+	GetResponseRecords(ctx context.Context) ([]ResponseRecord, error)
+	SaveItemParameters(ctx context.Context, params []*models.ItemParameter) error
+}
+
+type questionRepository struct {
+	db *gorm.DB
+}
+
+func NewQuestionRepository(db *gorm.DB) QuestionRepository {
+	return &questionRepository{db: db}
+}
+
+// preloadQuestion 加载题目的选项、答案和知识点
+func preloadQuestion(db *gorm.DB) *gorm.DB {
+	return db.
+		Preload("Options", func(db *gorm.DB) *gorm.DB { return db.Order("position") }).
+		Preload("Answers", func(db *gorm.DB) *gorm.DB { return db.Order("blank, id") }).
+		Preload("Tags", func(db *gorm.DB) *gorm.DB { return db.Order("id") }).
+		Preload("Parameter")
+}
+
+// CreateQuestion 创建题目及其选项、答案和知识点
+func (r *questionRepository) CreateQuestion(ctx context.Context, question *models.Question) error {
+	return r.db.WithContext(ctx).Create(question).Error
+}
+
+// CreateQuestions 在一个事务中批量创建题目，任何一道题失败时全部不创建
+func (r *questionRepository) CreateQuestions(ctx context.Context, questions []*models.Question) error {
+	if len(questions) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return tx.Create(&questions).Error
+	})
+}
+
+func (r *questionRepository) GetQuestionByID(ctx context.Context, id int64) (*models.Question, error) {
+	var question models.Question
+	err := preloadQuestion(r.db.WithContext(ctx)).First(&question, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return &question, err
+}
+
+// GetQuestionsByIDs 批量获取题目，不存在的题目不返回
+func (r *questionRepository) GetQuestionsByIDs(ctx context.Context, ids []int64) ([]*models.Question, error) {
+	var questions []*models.Question
+	if len(ids) == 0 {
+		return questions, nil
+	}
+	err := preloadQuestion(r.db.WithContext(ctx)).Where("id IN ?", ids).Find(&questions).Error
+	return questions, err
+}
+
+func (r *questionRepository) ListQuestions(ctx context.Context, query *QuestionListQuery) ([]*models.Question, int64, error) {
+	if query.Page < 1 {
+		query.Page = 1
+	}
+	if query.PageSize <= 0 {
+		query.PageSize = DefaultPageSize
+	}
+	if query.PageSize > MaxPageSize {
+		query.PageSize = MaxPageSize
+	}
+
+	db := r.filterQuestions(r.db.WithContext(ctx).Model(&models.Question{}), query)
+
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var questions []*models.Question
+	err := preloadQuestion(db).
+		Order("questions.id DESC").
+		Offset((query.Page - 1) * query.PageSize).
+		Limit(query.PageSize).
+		Find(&questions).Error
+	return questions, total, err
+}
+
+// FindQuestions 按查询条件获取题目，不分页，最多返回limit道题
+func (r *questionRepository) FindQuestions(ctx context.Context, query *QuestionListQuery, limit int) ([]*models.Question, error) {
+	var questions []*models.Question
+	err := preloadQuestion(r.filterQuestions(r.db.WithContext(ctx).Model(&models.Question{}), query)).
+		Order("questions.id").
+		Limit(limit).
+		Find(&questions).Error
+	return questions, err
+}
+
+// filterQuestions 按查询条件筛选题目
+func (r *questionRepository) filterQuestions(db *gorm.DB, query *QuestionListQuery) *gorm.DB {
+	if len(query.IDs) > 0 {
+		db = db.Where("questions.id IN ?", query.IDs)
+	}
+	if query.Type != "" {
+		db = db.Where("questions.type = ?", query.Type)
+	}
+	if query.Subject != "" {
+		db = db.Where("questions.subject = ?", query.Subject)
+	}
+	if query.KnowledgePoint != "" {
+		db = db.Where("questions.id IN (?)",
+			r.db.Model(&models.QuestionTag{}).Select("question_id").Where("knowledge_point = ?", query.KnowledgePoint))
+	}
+	if len(query.KnowledgePointIDs) > 0 {
+		db = db.Where("questions.id IN (?)",
+			r.db.Model(&models.QuestionTag{}).Select("question_id").Where("knowledge_point_id IN ?", query.KnowledgePointIDs))
+	}
+	if query.Difficulty > 0 {
+		db = db.Where("questions.difficulty = ?", query.Difficulty)
+	}
+	if query.CreatedBy > 0 {
+		db = db.Where("questions.created_by = ?", query.CreatedBy)
+	}
+	if search := strings.TrimSpace(query.Search); search != "" {
+		db = db.Where("LOWER(questions.stem) LIKE ?", "%"+escapeLike(strings.ToLower(search))+"%")
+	}
+	return db
+}
+
+// UpdateQuestion 修改题目，选项、答案和知识点整体替换
+func (r *questionRepository) UpdateQuestion(ctx context.Context, question *models.Question) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Options", "Answers", "Tags").Save(question).Error; err != nil {
+			return err
+		}
+		for _, model := range []interface{}{&models.QuestionOption{}, &models.QuestionAnswer{}, &models.QuestionTag{}} {
+			if err := tx.Where("question_id = ?", question.ID).Delete(model).Error; err != nil {
+				return err
+			}
+		}
+
+		for i := range question.Options {
+			question.Options[i].ID = 0
+			question.Options[i].QuestionID = question.ID
+		}
+		for i := range question.Answers {
+			question.Answers[i].ID = 0
+			question.Answers[i].QuestionID = question.ID
+		}
+		for i := range question.Tags {
+			question.Tags[i].ID = 0
+			question.Tags[i].QuestionID = question.ID
+		}
+		if len(question.Options) > 0 {
+			if err := tx.Create(&question.Options).Error; err != nil {
+				return err
+			}
+		}
+		if len(question.Answers) > 0 {
+			if err := tx.Create(&question.Answers).Error; err != nil {
+				return err
+			}
+		}
+		if len(question.Tags) > 0 {
+			return tx.Create(&question.Tags).Error
+		}
+		return nil
+	})
+}
+
+// DeleteQuestion 软删除题目，已组卷的试卷和作答记录不受影响
+func (r *questionRepository) DeleteQuestion(ctx context.Context, id int64) error {
+	return r.db.WithContext(ctx).Delete(&models.Question{}, id).Error
+}
+
+// GetKnowledgePoints 获取题库中使用过的知识点，subject不为空时只返回该学科题目的知识点
+func (r *questionRepository) GetKnowledgePoints(ctx context.Context, subject string) ([]string, error) {
+	db := r.db.WithContext(ctx).Model(&models.QuestionTag{}).
+		Joins("JOIN questions ON questions.id = question_tags.question_id AND questions.deleted_at IS NULL")
+	if subject != "" {
+		db = db.Where("questions.subject = ?", subject)
+	}
+
+	var points []string
+	err := db.Distinct().Order("question_tags.knowledge_point").Pluck("question_tags.knowledge_point", &points).Error
+	return points, err
+}
+
+// GetResponseRecords 获取已交卷作答中全部已评分的客观题作答
+func (r *questionRepository) GetResponseRecords(ctx context.Context) ([]ResponseRecord, error) {
+	var records []ResponseRecord
+	err := r.db.WithContext(ctx).Model(&models.AttemptAnswer{}).
+		Select("attempt_answers.attempt_id, attempt_answers.question_id, attempt_answers.correct").
+		Joins("JOIN assessment_attempts ON assessment_attempts.id = attempt_answers.attempt_id").
+		Joins("JOIN questions ON questions.id = attempt_answers.question_id").
+		Where("assessment_attempts.status <> ? AND attempt_answers.correct IS NOT NULL AND questions.type <> ?",
+			models.AttemptInProgress, models.QuestionShortAnswer).
+		Scan(&records).Error
+	return records, err
+}
+
+// SaveItemParameters 保存题目参数，重新标定时覆盖已有的参数
+func (r *questionRepository) SaveItemParameters(ctx context.Context, params []*models.ItemParameter) error {
+	if len(params) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).
+		Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "question_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"discrimination", "difficulty", "responses", "calibrated_at"}),
+		}).
+		Create(&params).Error
+}
@@ -0,0 +1,230 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"EduGo_servers/internal/mastery"
+	"EduGo_servers/internal/models"
+	"EduGo_servers/internal/recommend"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// PracticeQuestionQuery 可以推荐给学生练习的题目的查询条件
+// 只包括出现在显示答案的测评中的题目，并排除已在学生错题本或复习队列中的题目
+type PracticeQuestionQuery struct {
+	StudentID         int64
+	KnowledgePointIDs []int64 // 涉及其中任一知识点的题目
+	IDs               []int64 // 只查询这些题目
+	Subject           string
+	Limit             int
+}
+
+type RecommendationRepository interface {
+	GetMistakePointCounts(ctx context.Context, studentID int64) (map[int64]int, error)
+	GetRecentPoints(ctx context.Context, studentID int64, since time.Time) (map[int64]time.Time, error)
+	GetSimilarStudents(ctx context.Context, studentID int64, weakPointIDs []int64, limit int) ([]int64, error)
+	GetHelpfulCounts(ctx context.Context, studentIDs []int64) (map[recommend.ItemKey]int, error)
+	GetPracticeQuestions(ctx context.Context, query *PracticeQuestionQuery) ([]*models.Question, error)
+
+	GetStudentFeedback(ctx context.Context, studentID int64) ([]*models.RecommendationFeedback, error)
+	SaveFeedback(ctx context.Context, feedback *models.RecommendationFeedback) error
+
+	GetOverrideByID(ctx context.Context, id int64) (*models.RecommendationOverride, error)
+	GetStudentOverrides(ctx context.Context, studentID int64) ([]*models.RecommendationOverride, error)
+	SaveOverrides(ctx context.Context, overrides []*models.RecommendationOverride) error
+	DeleteOverride(ctx context.Context, id int64) error
+}
+
+type recommendationRepository struct {
+	db *gorm.DB
+}
+
+func NewRecommendationRepository(db *gorm.DB) RecommendationRepository {
+	return &recommendationRepository{db: db}
+}
+
+// GetMistakePointCounts 统计学生每个知识点上没有订正的错题数
+func (r *recommendationRepository) GetMistakePointCounts(ctx context.Context, studentID int64) (map[int64]int, error) {
+	var rows []struct {
+		KnowledgePointID int64
+		Count            int
+	}
+	err := r.db.WithContext(ctx).Model(&models.MistakeEntry{}).
+		Select("question_tags.knowledge_point_id, COUNT(*) AS count").
+		Joins("JOIN question_tags ON question_tags.question_id = mistake_entries.question_id").
+		Where("mistake_entries.student_id = ? AND mistake_entries.status = ?", studentID, models.MistakeActive).
+		Where("question_tags.knowledge_point_id IS NOT NULL").
+		Group("question_tags.knowledge_point_id").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[int64]int, len(rows))
+	for _, row := range rows {
+		counts[row.KnowledgePointID] = row.Count
+	}
+	return counts, nil
+}
+
+// GetRecentPoints 获取学生在since之后作答过的知识点及最后一次作答的时间
+func (r *recommendationRepository) GetRecentPoints(ctx context.Context, studentID int64, since time.Time) (map[int64]time.Time, error) {
+	var rows []struct {
+		KnowledgePointID int64
+		LastObservedAt   time.Time
+	}
+	err := r.db.WithContext(ctx).Model(&models.KnowledgeObservation{}).
+		Select("knowledge_point_id, MAX(observed_at) AS last_observed_at").
+		Where("student_id = ? AND observed_at >= ?", studentID, since).
+		Group("knowledge_point_id").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	points := make(map[int64]time.Time, len(rows))
+	for _, row := range rows {
+		points[row.KnowledgePointID] = row.LastObservedAt
+	}
+	return points, nil
+}
+
+// GetSimilarStudents 获取情况相似的同学：在这些薄弱知识点中同样薄弱的知识点最多的学生，最多返回limit人
+func (r *recommendationRepository) GetSimilarStudents(ctx context.Context, studentID int64, weakPointIDs []int64, limit int) ([]int64, error) {
+	var ids []int64
+	if len(weakPointIDs) == 0 {
+		return ids, nil
+	}
+	err := r.db.WithContext(ctx).Model(&models.StudentMastery{}).
+		Select("student_id").
+		Where("knowledge_point_id IN ? AND probability < ? AND student_id <> ?", weakPointIDs, mastery.WeakThreshold, studentID).
+		Group("student_id").
+		Order("COUNT(*) DESC, student_id").
+		Limit(limit).
+		Pluck("student_id", &ids).Error
+	return ids, err
+}
+
+// GetHelpfulCounts 统计每个推荐项有多少名学生给出了正面反馈
+func (r *recommendationRepository) GetHelpfulCounts(ctx context.Context, studentIDs []int64) (map[recommend.ItemKey]int, error) {
+	counts := map[recommend.ItemKey]int{}
+	if len(studentIDs) == 0 {
+		return counts, nil
+	}
+
+	var rows []struct {
+		ItemType string
+		ItemID   int64
+		Count    int
+	}
+	err := r.db.WithContext(ctx).Model(&models.RecommendationFeedback{}).
+		Select("item_type, item_id, COUNT(*) AS count").
+		Where("student_id IN ? AND action IN ?", studentIDs, []string{models.FeedbackHelpful, models.FeedbackAccepted}).
+		Group("item_type, item_id").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		counts[recommend.ItemKey{Type: row.ItemType, ID: row.ItemID}] = row.Count
+	}
+	return counts, nil
+}
+
+// GetPracticeQuestions 获取可以推荐给学生练习的题目
+func (r *recommendationRepository) GetPracticeQuestions(ctx context.Context, query *PracticeQuestionQuery) ([]*models.Question, error) {
+	db := r.db.WithContext(ctx)
+	revealed := revealedQuestions(db, query.StudentID)
+	mistakes := db.Model(&models.MistakeEntry{}).Select("question_id").
+		Where("student_id = ? AND status = ?", query.StudentID, models.MistakeActive)
+	reviews := db.Model(&models.ReviewItem{}).Select("question_id").Where("student_id = ?", query.StudentID)
+
+	questions := db.Model(&models.Question{}).
+		Where("id IN (?) AND id NOT IN (?) AND id NOT IN (?)", revealed, mistakes, reviews)
+	if len(query.KnowledgePointIDs) > 0 {
+		questions = questions.Where("id IN (?)", db.Model(&models.QuestionTag{}).Select("question_id").
+			Where("knowledge_point_id IN ?", query.KnowledgePointIDs))
+	}
+	if len(query.IDs) > 0 {
+		questions = questions.Where("id IN ?", query.IDs)
+	}
+	if query.Subject != "" {
+		questions = questions.Where("subject = ?", query.Subject)
+	}
+
+	if query.Limit <= 0 {
+		query.Limit = MaxPageSize
+	}
+
+	var result []*models.Question
+	err := preloadQuestion(questions).
+		Order("id").
+		Limit(query.Limit).
+		Find(&result).Error
+	return result, err
+}
+
+func (r *recommendationRepository) GetStudentFeedback(ctx context.Context, studentID int64) ([]*models.RecommendationFeedback, error) {
+	var feedback []*models.RecommendationFeedback
+	err := r.db.WithContext(ctx).Where("student_id = ?", studentID).Order("id").Find(&feedback).Error
+	return feedback, err
+}
+
+// SaveFeedback 保存学生对推荐项的反馈，同一项只保留最近一次反馈
+func (r *recommendationRepository) SaveFeedback(ctx context.Context, feedback *models.RecommendationFeedback) error {
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "student_id"}, {Name: "item_type"}, {Name: "item_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"action", "updated_at"}),
+	}).Create(feedback).Error
+}
+
+func (r *recommendationRepository) GetOverrideByID(ctx context.Context, id int64) (*models.RecommendationOverride, error) {
+	var override models.RecommendationOverride
+	err := r.db.WithContext(ctx).Preload("Creator").First(&override, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return &override, err
+}
+
+// GetStudentOverrides 获取教师对学生推荐结果的调整，最近的排在前面
+func (r *recommendationRepository) GetStudentOverrides(ctx context.Context, studentID int64) ([]*models.RecommendationOverride, error) {
+	var overrides []*models.RecommendationOverride
+	err := r.db.WithContext(ctx).Preload("Creator").
+		Where("student_id = ?", studentID).
+		Order("updated_at DESC, id DESC").
+		Find(&overrides).Error
+	return overrides, err
+}
+
+// SaveOverrides 在一个事务中保存教师的调整，同一学生的同一项只保留最近一次调整
+func (r *recommendationRepository) SaveOverrides(ctx context.Context, overrides []*models.RecommendationOverride) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, override := range overrides {
+			err := tx.Omit("Creator").Clauses(clause.OnConflict{
+				Columns:   []clause.Column{{Name: "student_id"}, {Name: "item_type"}, {Name: "item_id"}},
+				DoUpdates: clause.AssignmentColumns([]string{"action", "note", "created_by", "updated_at"}),
+			}).Create(override).Error
+			if err != nil {
+				return err
+			}
+			// 更新已有的调整时重新读取ID
+			var saved models.RecommendationOverride
+			err = tx.Select("id", "created_at").
+				Where("student_id = ? AND item_type = ? AND item_id = ?", override.StudentID, override.ItemType, override.ItemID).
+				Take(&saved).Error
+			if err != nil {
+				return err
+			}
+			override.ID, override.CreatedAt = saved.ID, saved.CreatedAt
+		}
+		return nil
+	})
+}
+
+func (r *recommendationRepository) DeleteOverride(ctx context.Context, id int64) error {
+	return r.db.WithContext(ctx).Delete(&models.RecommendationOverride{}, id).Error
+}
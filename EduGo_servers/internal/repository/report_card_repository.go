@@ -0,0 +1,167 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"EduGo_servers/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type ReportCardRepository interface {
+	// 模板
+	CreateTemplate(ctx context.Context, template *models.ReportCardTemplate) error
+	GetTemplateByID(ctx context.Context, id int64) (*models.ReportCardTemplate, error)
+	GetDefaultTemplate(ctx context.Context) (*models.ReportCardTemplate, error)
+	GetTemplates(ctx context.Context) ([]*models.ReportCardTemplate, error)
+	TemplateNameExists(ctx context.Context, name string, excludeID int64) (bool, error)
+	UpdateTemplate(ctx context.Context, template *models.ReportCardTemplate) error
+	DeleteTemplate(ctx context.Context, id int64) error
+
+	// 评语
+	SaveComment(ctx context.Context, comment *models.ReportCardComment) error
+	GetComments(ctx context.Context, studentID, termID int64) ([]*models.ReportCardComment, error)
+
+	// 批量生成任务
+	CreateBatch(ctx context.Context, batch *models.ReportCardBatch) error
+	GetBatchByID(ctx context.Context, id int64) (*models.ReportCardBatch, error)
+	GetPendingBatches(ctx context.Context, staleBefore time.Time) ([]*models.ReportCardBatch, error)
+	ClaimBatch(ctx context.Context, id int64, now, staleBefore time.Time) (bool, error)
+	UpdateBatch(ctx context.Context, batch *models.ReportCardBatch) error
+}
+
+type reportCardRepository struct {
+	db *gorm.DB
+}
+
+func NewReportCardRepository(db *gorm.DB) ReportCardRepository {
+	return &reportCardRepository{db: db}
+}
+
+// CreateTemplate 创建模板，设为默认模板时取消其他模板的默认状态
+func (r *reportCardRepository) CreateTemplate(ctx context.Context, template *models.ReportCardTemplate) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := clearDefaultTemplate(tx, template); err != nil {
+			return err
+		}
+		return tx.Create(template).Error
+	})
+}
+
+func (r *reportCardRepository) GetTemplateByID(ctx context.Context, id int64) (*models.ReportCardTemplate, error) {
+	var template models.ReportCardTemplate
+	err := r.db.WithContext(ctx).First(&template, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return &template, err
+}
+
+// GetDefaultTemplate 获取默认模板，没有设置默认模板时返回nil
+func (r *reportCardRepository) GetDefaultTemplate(ctx context.Context) (*models.ReportCardTemplate, error) {
+	var template models.ReportCardTemplate
+	err := r.db.WithContext(ctx).Where("is_default = ?", true).First(&template).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return &template, err
+}
+
+func (r *reportCardRepository) GetTemplates(ctx context.Context) ([]*models.ReportCardTemplate, error) {
+	var templates []*models.ReportCardTemplate
+	err := r.db.WithContext(ctx).Order("id").Find(&templates).Error
+	return templates, err
+}
+
+func (r *reportCardRepository) TemplateNameExists(ctx context.Context, name string, excludeID int64) (bool, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&models.ReportCardTemplate{}).
+		Where("name = ? AND id <> ?", name, excludeID).
+		Count(&count).Error
+	return count > 0, err
+}
+
+func (r *reportCardRepository) UpdateTemplate(ctx context.Context, template *models.ReportCardTemplate) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := clearDefaultTemplate(tx, template); err != nil {
+			return err
+		}
+		return tx.Save(template).Error
+	})
+}
+
+// clearDefaultTemplate 模板设为默认时取消其他模板的默认状态
+func clearDefaultTemplate(tx *gorm.DB, template *models.ReportCardTemplate) error {
+	if !template.IsDefault {
+		return nil
+	}
+	return tx.Model(&models.ReportCardTemplate{}).
+		Where("is_default = ? AND id <> ?", true, template.ID).
+		Update("is_default", false).Error
+}
+
+func (r *reportCardRepository) DeleteTemplate(ctx context.Context, id int64) error {
+	return r.db.WithContext(ctx).Delete(&models.ReportCardTemplate{}, id).Error
+}
+
+// SaveComment 保存评语，同一学期同一学生同一课程的评语会被覆盖
+func (r *reportCardRepository) SaveComment(ctx context.Context, comment *models.ReportCardComment) error {
+	return r.db.WithContext(ctx).
+		Omit("Author").
+		Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "term_id"}, {Name: "student_id"}, {Name: "offering_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"author_id", "content", "updated_at"}),
+		}).
+		Create(comment).Error
+}
+
+func (r *reportCardRepository) GetComments(ctx context.Context, studentID, termID int64) ([]*models.ReportCardComment, error) {
+	var comments []*models.ReportCardComment
+	err := r.db.WithContext(ctx).
+		Preload("Author").
+		Where("student_id = ? AND term_id = ?", studentID, termID).
+		Order("offering_id").
+		Find(&comments).Error
+	return comments, err
+}
+
+func (r *reportCardRepository) CreateBatch(ctx context.Context, batch *models.ReportCardBatch) error {
+	return r.db.WithContext(ctx).Create(batch).Error
+}
+
+func (r *reportCardRepository) GetBatchByID(ctx context.Context, id int64) (*models.ReportCardBatch, error) {
+	var batch models.ReportCardBatch
+	err := r.db.WithContext(ctx).First(&batch, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return &batch, err
+}
+
+// claimableBatches 可以领取的批量生成任务：等待中的任务，以及在staleBefore之前开始处理、处理实例可能已崩溃的任务
+func claimableBatches(db *gorm.DB, staleBefore time.Time) *gorm.DB {
+	return db.Where("status = ? OR (status = ? AND (claimed_at IS NULL OR claimed_at < ?))",
+		models.ReportCardBatchPending, models.ReportCardBatchProcessing, staleBefore)
+}
+
+// GetPendingBatches 获取可以领取的批量生成任务，按创建时间排序
+func (r *reportCardRepository) GetPendingBatches(ctx context.Context, staleBefore time.Time) ([]*models.ReportCardBatch, error) {
+	var batches []*models.ReportCardBatch
+	err := claimableBatches(r.db.WithContext(ctx), staleBefore).
+		Order("created_at").
+		Find(&batches).Error
+	return batches, err
+}
+
+// ClaimBatch 将可以领取的任务标记为处理中并记录领取时间，返回是否成功领取，避免多个实例重复处理
+func (r *reportCardRepository) ClaimBatch(ctx context.Context, id int64, now, staleBefore time.Time) (bool, error) {
+	result := claimableBatches(r.db.WithContext(ctx).Model(&models.ReportCardBatch{}).Where("id = ?", id), staleBefore).
+		Updates(map[string]interface{}{"status": models.ReportCardBatchProcessing, "claimed_at": now})
+	return result.RowsAffected > 0, result.Error
+}
+
+func (r *reportCardRepository) UpdateBatch(ctx context.Context, batch *models.ReportCardBatch) error {
+	return r.db.WithContext(ctx).Save(batch).Error
+}
@@ -0,0 +1,227 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"EduGo_servers/internal/models"
+	"EduGo_servers/internal/recommend"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ResourceListQuery 资源库查询条件
+type ResourceListQuery struct {
+	Page              int
+	PageSize          int
+	Search            string // 全文搜索标题和描述，按相关度排序
+	Type              string
+	Subject           string
+	GradeLevel        string
+	Visibility        string
+	OwnerID           int64
+	CourseID          int64   // 只查询出现在该课程资源列表中的资源
+	KnowledgePointIDs []int64 // 关联到其中任一知识点的资源
+	IDs               []int64 // 只查询这些资源
+
+	// 可见范围：AllVisible为true时不限制；PublicOnly为true时只返回公开资源；
+	// 否则返回公开和全校可见的资源、ViewerID创建的资源以及关联到ViewerCourseIDs的课程可见资源
+	AllVisible      bool
+	PublicOnly      bool
+	ViewerID        int64
+	ViewerCourseIDs []int64
+}
+
+type ResourceRepository interface {
+	CreateResource(ctx context.Context, resource *models.Resource) error
+	GetResourceByID(ctx context.Context, id int64) (*models.Resource, error)
+	ListResources(ctx context.Context, query *ResourceListQuery) ([]*models.Resource, int64, error)
+	UpdateResource(ctx context.Context, resource *models.Resource) error
+	DeleteResource(ctx context.Context, id int64) error
+	GetUserCourseIDs(ctx context.Context, userID int64) ([]int64, error)
+	GetOwnedResources(ctx context.Context, ownerID int64) ([]*models.Resource, error)
+}
+
+type resourceRepository struct {
+	db *gorm.DB
+}
+
+func NewResourceRepository(db *gorm.DB) ResourceRepository {
+	return &resourceRepository{db: db}
+}
+
+// saveResourceAssociations 替换资源关联的课程和知识点
+func saveResourceAssociations(tx *gorm.DB, resource *models.Resource) error {
+	if err := tx.Model(resource).Association("Courses").Replace(resource.Courses); err != nil {
+		return err
+	}
+	return tx.Model(resource).Association("KnowledgePoints").Replace(resource.KnowledgePoints)
+}
+
+// CreateResource 创建资源及其关联的课程和知识点
+func (r *resourceRepository) CreateResource(ctx context.Context, resource *models.Resource) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Owner", "Courses", "KnowledgePoints").Create(resource).Error; err != nil {
+			return err
+		}
+		return saveResourceAssociations(tx, resource)
+	})
+}
+
+func (r *resourceRepository) GetResourceByID(ctx context.Context, id int64) (*models.Resource, error) {
+	var resource models.Resource
+	err := r.db.WithContext(ctx).
+		Preload("Owner").Preload("Courses").Preload("KnowledgePoints").
+		First(&resource, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return &resource, err
+}
+
+// ListResources 分页查询资源，有搜索词时按相关度排序，否则最新的排在前面
+func (r *resourceRepository) ListResources(ctx context.Context, query *ResourceListQuery) ([]*models.Resource, int64, error) {
+	if query.Page < 1 {
+		query.Page = 1
+	}
+	if query.PageSize <= 0 {
+		query.PageSize = DefaultPageSize
+	}
+	if query.PageSize > MaxPageSize {
+		query.PageSize = MaxPageSize
+	}
+
+	db := r.filterResources(r.db.WithContext(ctx).Model(&models.Resource{}), query)
+
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	if query.Search != "" {
+		db = db.Order(clause.Expr{SQL: "MATCH(resources.title, resources.description) AGAINST (?) DESC", Vars: []interface{}{query.Search}})
+	}
+	var resources []*models.Resource
+	err := db.
+		Preload("Owner").Preload("Courses").Preload("KnowledgePoints").
+		Order("resources.id DESC").
+		Offset((query.Page - 1) * query.PageSize).
+		Limit(query.PageSize).
+		Find(&resources).Error
+	return resources, total, err
+}
+
+// filterResources 按查询条件和可见范围筛选资源
+func (r *resourceRepository) filterResources(db *gorm.DB, query *ResourceListQuery) *gorm.DB {
+	switch {
+	case query.AllVisible:
+	case query.PublicOnly:
+		db = db.Where("resources.visibility = ?", models.ResourcePublic)
+	default:
+		visible := r.db.Where("resources.visibility IN ?", []string{models.ResourceSchool, models.ResourcePublic}).
+			Or("resources.owner_id = ?", query.ViewerID)
+		if len(query.ViewerCourseIDs) > 0 {
+			visible = visible.Or("resources.visibility = ? AND resources.id IN (?)", models.ResourceCourse,
+				r.db.Table("course_resources").Select("resource_id").Where("course_id IN ?", query.ViewerCourseIDs))
+		}
+		db = db.Where(visible)
+	}
+
+	if query.Search != "" {
+		db = db.Where("MATCH(resources.title, resources.description) AGAINST (?)", query.Search)
+	}
+	if query.Type != "" {
+		db = db.Where("resources.type = ?", query.Type)
+	}
+	if query.Subject != "" {
+		db = db.Where("resources.subject = ?", query.Subject)
+	}
+	if query.GradeLevel != "" {
+		db = db.Where("resources.grade_level = ?", query.GradeLevel)
+	}
+	if query.Visibility != "" {
+		db = db.Where("resources.visibility = ?", query.Visibility)
+	}
+	if query.OwnerID > 0 {
+		db = db.Where("resources.owner_id = ?", query.OwnerID)
+	}
+	if query.CourseID > 0 {
+		db = db.Where("resources.id IN (?)", r.db.Table("course_resources").Select("resource_id").Where("course_id = ?", query.CourseID))
+	}
+	if len(query.IDs) > 0 {
+		db = db.Where("resources.id IN ?", query.IDs)
+	}
+	if len(query.KnowledgePointIDs) > 0 {
+		db = db.Where("resources.id IN (?)", r.db.Table("resource_knowledge_points").
+			Select("resource_id").Where("knowledge_point_id IN ?", query.KnowledgePointIDs))
+	}
+	return db
+}
+
+// UpdateResource 保存资源及其关联的课程和知识点
+func (r *resourceRepository) UpdateResource(ctx context.Context, resource *models.Resource) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Owner", "Courses", "KnowledgePoints").Save(resource).Error; err != nil {
+			return err
+		}
+		return saveResourceAssociations(tx, resource)
+	})
+}
+
+// DeleteResource 删除资源及其关联和推荐记录，存储中的文件由调用方删除
+func (r *resourceRepository) DeleteResource(ctx context.Context, id int64) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, table := range []string{"course_resources", "resource_knowledge_points", "lesson_resources"} {
+			if err := tx.Exec("DELETE FROM "+table+" WHERE resource_id = ?", id).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Where("item_type = ? AND item_id = ?", models.LessonItemResource, id).Delete(&models.LessonActivity{}).Error; err != nil {
+			return err
+		}
+		for _, model := range []interface{}{&models.RecommendationFeedback{}, &models.RecommendationOverride{}} {
+			if err := tx.Where("item_type = ? AND item_id = ?", recommend.ItemResource, id).Delete(model).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Delete(&models.Resource{}, id).Error
+	})
+}
+
+// GetUserCourseIDs 获取用户所在的课程：负责或授课的课程，以及选上或所在班级排了课的课程
+func (r *resourceRepository) GetUserCourseIDs(ctx context.Context, userID int64) ([]int64, error) {
+	db := r.db.WithContext(ctx)
+	offeringCourses := func(offeringIDs *gorm.DB) *gorm.DB {
+		return db.Model(&models.CourseOffering{}).Where("id IN (?)", offeringIDs)
+	}
+	queries := []*gorm.DB{
+		db.Table("course_teachers").Where("user_id = ?", userID),
+		offeringCourses(db.Table("course_offering_teachers").Select("course_offering_id").Where("user_id = ?", userID)),
+		offeringCourses(db.Model(&models.Enrollment{}).Select("offering_id").
+			Where("student_id = ? AND status = ?", userID, models.EnrollmentEnrolled)),
+		offeringCourses(db.Model(&models.ScheduledSession{}).Select("offering_id").
+			Where("class_id IN (?)", db.Table("class_students").Select("class_id").Where("user_id = ?", userID))),
+	}
+
+	seen := map[int64]bool{}
+	ids := []int64{}
+	for _, query := range queries {
+		var courseIDs []int64
+		if err := query.Pluck("course_id", &courseIDs).Error; err != nil {
+			return nil, err
+		}
+		for _, id := range courseIDs {
+			if !seen[id] {
+				seen[id] = true
+				ids = append(ids, id)
+			}
+		}
+	}
+	return ids, nil
+}
+
+func (r *resourceRepository) GetOwnedResources(ctx context.Context, ownerID int64) ([]*models.Resource, error) {
+	var resources []*models.Resource
+	err := r.db.WithContext(ctx).Where("owner_id = ?", ownerID).Order("id").Find(&resources).Error
+	return resources, err
+}
@@ -0,0 +1,160 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"EduGo_servers/internal/models"
+	"gorm.io/gorm"
+)
+
+// ReviewListQuery 复习队列查询条件
+type ReviewListQuery struct {
+	Page      int
+	PageSize  int
+	StudentID int64
+}
+
+type ReviewRepository interface {
+	GetItemByID(ctx context.Context, id int64) (*models.ReviewItem, error)
+	GetItems(ctx context.Context, studentIDs []int64, questionIDs []int64) ([]*models.ReviewItem, error)
+	GetDueItems(ctx context.Context, studentID int64, before time.Time, limit int) ([]*models.ReviewItem, int64, error)
+	ListItems(ctx context.Context, query *ReviewListQuery) ([]*models.ReviewItem, int64, error)
+	CountDueItems(ctx context.Context, studentID int64, before time.Time) (int64, error)
+	SaveItems(ctx context.Context, items []*models.ReviewItem) error
+	SaveReview(ctx context.Context, item *models.ReviewItem, log *models.ReviewLog) error
+	GetItemLogs(ctx context.Context, itemID int64) ([]*models.ReviewLog, error)
+
+	GetAllStudentItems(ctx context.Context, studentID int64) ([]*models.ReviewItem, error)
+	GetStudentLogs(ctx context.Context, studentID int64) ([]*models.ReviewLog, error)
+}
+
+type reviewRepository struct {
+	db *gorm.DB
+}
+
+func NewReviewRepository(db *gorm.DB) ReviewRepository {
+	return &reviewRepository{db: db}
+}
+
+// GetItemByID 获取复习题目，题目从题库删除后仍然加载
+func (r *reviewRepository) GetItemByID(ctx context.Context, id int64) (*models.ReviewItem, error) {
+	var item models.ReviewItem
+	err := preloadArchivedQuestion(r.db.WithContext(ctx), "Question").First(&item, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return &item, err
+}
+
+// GetItems 获取这些学生复习队列中这些题目的记录，questionIDs为空时返回空
+func (r *reviewRepository) GetItems(ctx context.Context, studentIDs []int64, questionIDs []int64) ([]*models.ReviewItem, error) {
+	var items []*models.ReviewItem
+	if len(studentIDs) == 0 || len(questionIDs) == 0 {
+		return items, nil
+	}
+	err := r.db.WithContext(ctx).
+		Where("student_id IN ? AND question_id IN ?", studentIDs, questionIDs).
+		Find(&items).Error
+	return items, err
+}
+
+// dueItems 筛选学生在before之前应复习且题目仍在题库中的题目
+func (r *reviewRepository) dueItems(ctx context.Context, studentID int64, before time.Time) *gorm.DB {
+	return r.db.WithContext(ctx).Model(&models.ReviewItem{}).
+		Joins("JOIN questions ON questions.id = review_items.question_id AND questions.deleted_at IS NULL").
+		Where("review_items.student_id = ? AND review_items.due_at < ?", studentID, before)
+}
+
+// GetDueItems 获取学生应复习的题目，过期最久的排在前面，最多返回limit道题，同时返回应复习的总数
+func (r *reviewRepository) GetDueItems(ctx context.Context, studentID int64, before time.Time, limit int) ([]*models.ReviewItem, int64, error) {
+	total, err := r.CountDueItems(ctx, studentID, before)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var items []*models.ReviewItem
+	err = preloadArchivedQuestion(r.dueItems(ctx, studentID, before), "Question").
+		Select("review_items.*").
+		Order("review_items.due_at, review_items.id").
+		Limit(limit).
+		Find(&items).Error
+	return items, total, err
+}
+
+func (r *reviewRepository) CountDueItems(ctx context.Context, studentID int64, before time.Time) (int64, error) {
+	var count int64
+	err := r.dueItems(ctx, studentID, before).Count(&count).Error
+	return count, err
+}
+
+// ListItems 分页查询学生的复习队列，按应复习的日期排列
+func (r *reviewRepository) ListItems(ctx context.Context, query *ReviewListQuery) ([]*models.ReviewItem, int64, error) {
+	if query.Page < 1 {
+		query.Page = 1
+	}
+	if query.PageSize <= 0 {
+		query.PageSize = DefaultPageSize
+	}
+	if query.PageSize > MaxPageSize {
+		query.PageSize = MaxPageSize
+	}
+
+	db := r.db.WithContext(ctx).Model(&models.ReviewItem{}).Where("student_id = ?", query.StudentID)
+
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var items []*models.ReviewItem
+	err := preloadArchivedQuestion(db, "Question").
+		Order("due_at, id").
+		Offset((query.Page - 1) * query.PageSize).
+		Limit(query.PageSize).
+		Find(&items).Error
+	return items, total, err
+}
+
+// SaveItems 在一个事务中保存复习题目
+func (r *reviewRepository) SaveItems(ctx context.Context, items []*models.ReviewItem) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, item := range items {
+			if err := tx.Omit("Question").Save(item).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// SaveReview 在一个事务中保存复习后的进度和本次复习的记录
+func (r *reviewRepository) SaveReview(ctx context.Context, item *models.ReviewItem, log *models.ReviewLog) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Question").Save(item).Error; err != nil {
+			return err
+		}
+		log.ItemID = item.ID
+		return tx.Create(log).Error
+	})
+}
+
+// GetItemLogs 按时间顺序获取一道复习题目的复习记录
+func (r *reviewRepository) GetItemLogs(ctx context.Context, itemID int64) ([]*models.ReviewLog, error) {
+	var logs []*models.ReviewLog
+	err := r.db.WithContext(ctx).Where("item_id = ?", itemID).Order("reviewed_at, id").Find(&logs).Error
+	return logs, err
+}
+
+func (r *reviewRepository) GetAllStudentItems(ctx context.Context, studentID int64) ([]*models.ReviewItem, error) {
+	var items []*models.ReviewItem
+	err := r.db.WithContext(ctx).Where("student_id = ?", studentID).Order("id").Find(&items).Error
+	return items, err
+}
+
+func (r *reviewRepository) GetStudentLogs(ctx context.Context, studentID int64) ([]*models.ReviewLog, error) {
+	var logs []*models.ReviewLog
+	err := r.db.WithContext(ctx).Where("student_id = ?", studentID).Order("reviewed_at, id").Find(&logs).Error
+	return logs, err
+}
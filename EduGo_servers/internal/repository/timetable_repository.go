@@ -0,0 +1,330 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"EduGo_servers/internal/models"
+	"gorm.io/gorm"
+)
+
+// 课表冲突类型
+const (
+	ConflictTeacher = "teacher" // 教师同一时间有其他课
+	ConflictRoom    = "room"    // 教室同一时间被占用
+	ConflictClass   = "class"   // 班级同一时间有其他课
+	ConflictStudent = "student" // 部分学生同一时间有其他课
+)
+
+// ScheduleConflict 课表冲突
+type ScheduleConflict struct {
+	Type       string
+	SessionID  int64   // 与之冲突的课
+	StudentIDs []int64 // 学生冲突时，同时需要上两节课的学生
+}
+
+// SessionQuery 课表查询条件，多个条件同时生效
+type SessionQuery struct {
+	TermID     int64
+	ClassID    int64
+	TeacherID  int64
+	RoomID     int64
+	OfferingID int64
+	StudentID  int64 // 学生所在班级的课和已选上的课
+}
+
+type TimetableRepository interface {
+	CreatePeriod(ctx context.Context, period *models.Period) error
+	GetPeriodByID(ctx context.Context, id int64) (*models.Period, error)
+	GetPeriods(ctx context.Context) ([]*models.Period, error)
+	PeriodNumberExists(ctx context.Context, number int, excludeID int64) (bool, error)
+	UpdatePeriod(ctx context.Context, period *models.Period) error
+	DeletePeriod(ctx context.Context, id int64) error
+
+	CreateRoom(ctx context.Context, room *models.Room) error
+	GetRoomByID(ctx context.Context, id int64) (*models.Room, error)
+	GetRooms(ctx context.Context) ([]*models.Room, error)
+	RoomNameExists(ctx context.Context, name string, excludeID int64) (bool, error)
+	UpdateRoom(ctx context.Context, room *models.Room) error
+	DeleteRoom(ctx context.Context, id int64) error
+
+	CreateSession(ctx context.Context, session *models.ScheduledSession) error
+	GetSessionByID(ctx context.Context, id int64) (*models.ScheduledSession, error)
+	ListSessions(ctx context.Context, query *SessionQuery) ([]*models.ScheduledSession, error)
+	CountSessions(ctx context.Context, column string, id int64) (int64, error)
+	DeleteSession(ctx context.Context, id int64) error
+	FindConflicts(ctx context.Context, session *models.ScheduledSession) ([]ScheduleConflict, error)
+	GetSessionStudents(ctx context.Context, session *models.ScheduledSession) ([]*models.User, error)
+
+	GetCalendarFeed(ctx context.Context, userID int64) (*models.CalendarFeed, error)
+	GetCalendarFeedByToken(ctx context.Context, token string) (*models.CalendarFeed, error)
+	SaveCalendarFeed(ctx context.Context, feed *models.CalendarFeed) error
+}
+
+type timetableRepository struct {
+	db *gorm.DB
+}
+
+func NewTimetableRepository(db *gorm.DB) TimetableRepository {
+	return &timetableRepository{db: db}
+}
+
+func (r *timetableRepository) CreatePeriod(ctx context.Context, period *models.Period) error {
+	return r.db.WithContext(ctx).Create(period).Error
+}
+
+func (r *timetableRepository) GetPeriodByID(ctx context.Context, id int64) (*models.Period, error) {
+	var period models.Period
+	err := r.db.WithContext(ctx).First(&period, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return &period, err
+}
+
+func (r *timetableRepository) GetPeriods(ctx context.Context) ([]*models.Period, error) {
+	var periods []*models.Period
+	err := r.db.WithContext(ctx).Order("number").Find(&periods).Error
+	return periods, err
+}
+
+func (r *timetableRepository) PeriodNumberExists(ctx context.Context, number int, excludeID int64) (bool, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&models.Period{}).
+		Where("number = ? AND id <> ?", number, excludeID).
+		Count(&count).Error
+	return count > 0, err
+}
+
+func (r *timetableRepository) UpdatePeriod(ctx context.Context, period *models.Period) error {
+	return r.db.WithContext(ctx).Save(period).Error
+}
+
+func (r *timetableRepository) DeletePeriod(ctx context.Context, id int64) error {
+	return r.db.WithContext(ctx).Delete(&models.Period{}, id).Error
+}
+
+func (r *timetableRepository) CreateRoom(ctx context.Context, room *models.Room) error {
+	return r.db.WithContext(ctx).Create(room).Error
+}
+
+func (r *timetableRepository) GetRoomByID(ctx context.Context, id int64) (*models.Room, error) {
+	var room models.Room
+	err := r.db.WithContext(ctx).First(&room, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return &room, err
+}
+
+func (r *timetableRepository) GetRooms(ctx context.Context) ([]*models.Room, error) {
+	var rooms []*models.Room
+	err := r.db.WithContext(ctx).Order("building, name").Find(&rooms).Error
+	return rooms, err
+}
+
+func (r *timetableRepository) RoomNameExists(ctx context.Context, name string, excludeID int64) (bool, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&models.Room{}).
+		Where("name = ? AND id <> ?", name, excludeID).
+		Count(&count).Error
+	return count > 0, err
+}
+
+func (r *timetableRepository) UpdateRoom(ctx context.Context, room *models.Room) error {
+	return r.db.WithContext(ctx).Save(room).Error
+}
+
+func (r *timetableRepository) DeleteRoom(ctx context.Context, id int64) error {
+	return r.db.WithContext(ctx).Delete(&models.Room{}, id).Error
+}
+
+func (r *timetableRepository) CreateSession(ctx context.Context, session *models.ScheduledSession) error {
+	return r.db.WithContext(ctx).Omit("Offering", "Period", "Teacher", "Class", "Room").Create(session).Error
+}
+
+// preloadSessions 预加载课表展示需要的关联数据
+func preloadSessions(db *gorm.DB) *gorm.DB {
+	return db.Preload("Offering").Preload("Offering.Course").Preload("Offering.Term").
+		Preload("Period").Preload("Teacher").Preload("Class").Preload("Room")
+}
+
+func (r *timetableRepository) GetSessionByID(ctx context.Context, id int64) (*models.ScheduledSession, error) {
+	var session models.ScheduledSession
+	err := preloadSessions(r.db.WithContext(ctx)).First(&session, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return &session, err
+}
+
+// ListSessions 按条件获取课表，按星期和课节排序
+func (r *timetableRepository) ListSessions(ctx context.Context, query *SessionQuery) ([]*models.ScheduledSession, error) {
+	db := r.db.WithContext(ctx).Model(&models.ScheduledSession{})
+	if query.TermID > 0 {
+		db = db.Where("scheduled_sessions.term_id = ?", query.TermID)
+	}
+	if query.ClassID > 0 {
+		db = db.Where("scheduled_sessions.class_id = ?", query.ClassID)
+	}
+	if query.TeacherID > 0 {
+		db = db.Where("scheduled_sessions.teacher_id = ?", query.TeacherID)
+	}
+	if query.RoomID > 0 {
+		db = db.Where("scheduled_sessions.room_id = ?", query.RoomID)
+	}
+	if query.OfferingID > 0 {
+		db = db.Where("scheduled_sessions.offering_id = ?", query.OfferingID)
+	}
+	if query.StudentID > 0 {
+		db = db.Where("scheduled_sessions.class_id IN (?) OR scheduled_sessions.offering_id IN (?)",
+			r.db.Table("class_students").Select("class_id").Where("user_id = ?", query.StudentID),
+			r.db.Model(&models.Enrollment{}).Select("offering_id").
+				Where("student_id = ? AND status = ?", query.StudentID, models.EnrollmentEnrolled))
+	}
+
+	var sessions []*models.ScheduledSession
+	err := preloadSessions(db).
+		Joins("JOIN periods ON periods.id = scheduled_sessions.period_id").
+		Order("scheduled_sessions.weekday, periods.number, scheduled_sessions.id").
+		Find(&sessions).Error
+	return sessions, err
+}
+
+// CountSessions 统计引用了指定教室、课节或开课的课表数量，column为room_id、period_id或offering_id
+func (r *timetableRepository) CountSessions(ctx context.Context, column string, id int64) (int64, error) {
+	switch column {
+	case "room_id", "period_id", "offering_id":
+	default:
+		return 0, errors.New("invalid session column: " + column)
+	}
+
+	var count int64
+	err := r.db.WithContext(ctx).Model(&models.ScheduledSession{}).Where(column+" = ?", id).Count(&count).Error
+	return count, err
+}
+
+func (r *timetableRepository) DeleteSession(ctx context.Context, id int64) error {
+	return r.db.WithContext(ctx).Delete(&models.ScheduledSession{}, id).Error
+}
+
+// sessionStudents 获取需要上这节课的学生：班级学生和已选上该开课的学生
+func (r *timetableRepository) sessionStudents(ctx context.Context, session *models.ScheduledSession) (map[int64]bool, error) {
+	var ids []int64
+	if session.ClassID != nil {
+		err := r.db.WithContext(ctx).Table("class_students").
+			Where("class_id = ?", *session.ClassID).
+			Pluck("user_id", &ids).Error
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var enrolled []int64
+	err := r.db.WithContext(ctx).Model(&models.Enrollment{}).
+		Where("offering_id = ? AND status = ?", session.OfferingID, models.EnrollmentEnrolled).
+		Pluck("student_id", &enrolled).Error
+	if err != nil {
+		return nil, err
+	}
+
+	students := make(map[int64]bool, len(ids)+len(enrolled))
+	for _, id := range append(ids, enrolled...) {
+		students[id] = true
+	}
+	return students, nil
+}
+
+// GetSessionStudents 获取需要上这节课的学生名单，不包括已删除的学生
+func (r *timetableRepository) GetSessionStudents(ctx context.Context, session *models.ScheduledSession) ([]*models.User, error) {
+	ids, err := r.sessionStudents(ctx, session)
+	if err != nil || len(ids) == 0 {
+		return []*models.User{}, err
+	}
+
+	studentIDs := make([]int64, 0, len(ids))
+	for id := range ids {
+		studentIDs = append(studentIDs, id)
+	}
+
+	var students []*models.User
+	err = r.db.WithContext(ctx).Where("id IN ?", studentIDs).Order("id").Find(&students).Error
+	return students, err
+}
+
+// FindConflicts 检查同一学期同一时间的其他课，返回教师、教室、班级和学生冲突
+func (r *timetableRepository) FindConflicts(ctx context.Context, session *models.ScheduledSession) ([]ScheduleConflict, error) {
+	var others []*models.ScheduledSession
+	err := r.db.WithContext(ctx).
+		Where("term_id = ? AND weekday = ? AND period_id = ? AND id <> ?",
+			session.TermID, session.Weekday, session.PeriodID, session.ID).
+		Find(&others).Error
+	if err != nil || len(others) == 0 {
+		return nil, err
+	}
+
+	students, err := r.sessionStudents(ctx, session)
+	if err != nil {
+		return nil, err
+	}
+
+	conflicts := []ScheduleConflict{}
+	for _, other := range others {
+		if other.TeacherID == session.TeacherID {
+			conflicts = append(conflicts, ScheduleConflict{Type: ConflictTeacher, SessionID: other.ID})
+		}
+		if other.RoomID != nil && session.RoomID != nil && *other.RoomID == *session.RoomID {
+			conflicts = append(conflicts, ScheduleConflict{Type: ConflictRoom, SessionID: other.ID})
+		}
+		if other.ClassID != nil && session.ClassID != nil && *other.ClassID == *session.ClassID {
+			// 同一班级的冲突已包含学生冲突
+			conflicts = append(conflicts, ScheduleConflict{Type: ConflictClass, SessionID: other.ID})
+			continue
+		}
+		if other.OfferingID == session.OfferingID && other.ClassID == nil && session.ClassID == nil {
+			// 同一开课的学生必然重叠，教师或教室冲突之外不再重复报告
+			conflicts = append(conflicts, ScheduleConflict{Type: ConflictStudent, SessionID: other.ID})
+			continue
+		}
+		if len(students) == 0 {
+			continue
+		}
+
+		otherStudents, err := r.sessionStudents(ctx, other)
+		if err != nil {
+			return nil, err
+		}
+		overlap := []int64{}
+		for id := range otherStudents {
+			if students[id] {
+				overlap = append(overlap, id)
+			}
+		}
+		if len(overlap) > 0 {
+			conflicts = append(conflicts, ScheduleConflict{Type: ConflictStudent, SessionID: other.ID, StudentIDs: overlap})
+		}
+	}
+	return conflicts, nil
+}
+
+func (r *timetableRepository) GetCalendarFeed(ctx context.Context, userID int64) (*models.CalendarFeed, error) {
+	var feed models.CalendarFeed
+	err := r.db.WithContext(ctx).Where("user_id = ?", userID).First(&feed).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return &feed, err
+}
+
+func (r *timetableRepository) GetCalendarFeedByToken(ctx context.Context, token string) (*models.CalendarFeed, error) {
+	var feed models.CalendarFeed
+	err := r.db.WithContext(ctx).Where("token = ?", token).First(&feed).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return &feed, err
+}
+
+func (r *timetableRepository) SaveCalendarFeed(ctx context.Context, feed *models.CalendarFeed) error {
+	return r.db.WithContext(ctx).Save(feed).Error
+}
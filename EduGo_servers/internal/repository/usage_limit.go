@@ -0,0 +1,24 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// consumeUsage 使用一次有次数和期限限制的凭证（模型需要嵌入models.UsageLimit），
+// 使用条件判断和计数在同一条语句中完成以避免并发超用，条件与UsageLimit.IsUsable一致，不可用时返回unavailable
+func consumeUsage(ctx context.Context, db *gorm.DB, model interface{}, id int64, unavailable error) error {
+	result := db.WithContext(ctx).Model(model).
+		Where("id = ? AND revoked_at IS NULL AND expires_at > ?", id, time.Now()).
+		Where("max_uses = 0 OR used_count < max_uses").
+		Update("used_count", gorm.Expr("used_count + 1"))
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return unavailable
+	}
+	return nil
+}
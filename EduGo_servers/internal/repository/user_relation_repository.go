@@ -7,6 +7,32 @@ import (
 	"gorm.io/gorm"
 )
 
+// ErrInvalidRelationType 无效的关系类型
+var ErrInvalidRelationType = errors.New("invalid relation type")
+
+// ErrRelationExists 相同课程和学期的教师-学生关系已存在
+var ErrRelationExists = errors.New("relation already exists")
+
+// relationModel 返回关系类型对应的存储模型
+func relationModel(relationType string) (interface{}, bool) {
+	switch relationType {
+	case models.RelationAdminTeacher:
+		return &models.AdminTeacherRelation{}, true
+	case models.RelationTeacherStudent:
+		return &models.TeacherStudentRelation{}, true
+	case models.RelationStudentParent:
+		return &models.StudentParentRelation{}, true
+	}
+	return nil, false
+}
+
+// UserRelationSet 与某个用户相关的全部关系
+type UserRelationSet struct {
+	AdminTeacher   []*models.AdminTeacherRelation
+	TeacherStudent []*models.TeacherStudentRelation
+	StudentParent  []*models.StudentParentRelation
+}
+
 type UserRelationRepository interface {
 	CreateRelation(ctx context.Context, relation *models.UserRelation) error
 	GetRelationByID(ctx context.Context, id int64) (*models.UserRelation, error)
@@ -14,6 +40,8 @@ type UserRelationRepository interface {
 	GetRelationsByRelatedUserID(ctx context.Context, relatedUserID int64, relationType string) ([]*models.UserRelation, error)
 	UpdateRelation(ctx context.Context, relation *models.UserRelation) error
 	DeleteRelation(ctx context.Context, id int64) error
+	HasRelation(ctx context.Context, relationType string, userID, relatedUserID int64) (bool, error)
+	GetRelationsInvolvingUser(ctx context.Context, userID int64) (*UserRelationSet, error)
 	
 	// 特定关系类型的方法
 	CreateAdminTeacherRelation(ctx context.Context, relation *models.AdminTeacherRelation) error
@@ -24,6 +52,11 @@ type UserRelationRepository interface {
 	GetTeacherStudentRelations(ctx context.Context, teacherID int64) ([]*models.TeacherStudentRelation, error)
 	GetStudentParentRelations(ctx context.Context, studentID int64) ([]*models.StudentParentRelation, error)
 	
+	GetTeacherStudentRelationByID(ctx context.Context, id int64) (*models.TeacherStudentRelation, error)
+	GetTeacherStudentRelationsByStatus(ctx context.Context, teacherID int64, status string) ([]*models.TeacherStudentRelation, error)
+	UpdateTeacherStudentRelation(ctx context.Context, relation *models.TeacherStudentRelation) error
+	DeleteTeacherStudentRelation(ctx context.Context, id int64) error
+	
 	GetTeachersByAdminID(ctx context.Context, adminID int64) ([]*models.User, error)
 	GetStudentsByTeacherID(ctx context.Context, teacherID int64) ([]*models.User, error)
 	GetParentsByStudentID(ctx context.Context, studentID int64) ([]*models.User, error)
@@ -82,6 +115,34 @@ func (r *userRelationRepository) DeleteRelation(ctx context.Context, id int64) e
 	return r.db.WithContext(ctx).Delete(&models.UserRelation{}, id).Error
 }
 
+// HasRelation 检查两个用户之间是否存在有效的指定类型关系
+func (r *userRelationRepository) HasRelation(ctx context.Context, relationType string, userID, relatedUserID int64) (bool, error) {
+	model, ok := relationModel(relationType)
+	if !ok {
+		return false, ErrInvalidRelationType
+	}
+	var count int64
+	err := r.db.WithContext(ctx).Model(model).
+		Where("user_id = ? AND related_user_id = ? AND status = ?", userID, relatedUserID, models.RelationStatusActive).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// GetRelationsInvolvingUser 获取用户作为发起者或接收者的全部关系
+func (r *userRelationRepository) GetRelationsInvolvingUser(ctx context.Context, userID int64) (*UserRelationSet, error) {
+	set := &UserRelationSet{}
+	targets := []interface{}{&set.AdminTeacher, &set.TeacherStudent, &set.StudentParent}
+	for _, target := range targets {
+		err := r.db.WithContext(ctx).
+			Where("user_id = ? OR related_user_id = ?", userID, userID).
+			Find(target).Error
+		if err != nil {
+			return nil, err
+		}
+	}
+	return set, nil
+}
+
 // 特定关系类型的方法实现
 
 func (r *userRelationRepository) CreateAdminTeacherRelation(ctx context.Context, relation *models.AdminTeacherRelation) error {
@@ -89,9 +150,14 @@ func (r *userRelationRepository) CreateAdminTeacherRelation(ctx context.Context,
 	return r.db.WithContext(ctx).Create(relation).Error
 }
 
+// CreateTeacherStudentRelation 创建教师-学生关系，相同教师、学生、课程和学期的关系已存在时返回ErrRelationExists
 func (r *userRelationRepository) CreateTeacherStudentRelation(ctx context.Context, relation *models.TeacherStudentRelation) error {
 	relation.RelationType = models.RelationTeacherStudent
-	return r.db.WithContext(ctx).Create(relation).Error
+	err := r.db.WithContext(ctx).Create(relation).Error
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return ErrRelationExists
+	}
+	return err
 }
 
 func (r *userRelationRepository) CreateStudentParentRelation(ctx context.Context, relation *models.StudentParentRelation) error {
@@ -123,6 +189,35 @@ func (r *userRelationRepository) GetStudentParentRelations(ctx context.Context,
 	return relations, err
 }
 
+func (r *userRelationRepository) GetTeacherStudentRelationByID(ctx context.Context, id int64) (*models.TeacherStudentRelation, error) {
+	var relation models.TeacherStudentRelation
+	err := r.db.WithContext(ctx).First(&relation, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return &relation, err
+}
+
+// GetTeacherStudentRelationsByStatus 获取教师指定状态的教师-学生关系
+func (r *userRelationRepository) GetTeacherStudentRelationsByStatus(ctx context.Context, teacherID int64, status string) ([]*models.TeacherStudentRelation, error) {
+	var relations []*models.TeacherStudentRelation
+	err := r.db.WithContext(ctx).
+		Where("user_id = ? AND status = ?", teacherID, status).
+		Order("created_at").
+		Find(&relations).Error
+	return relations, err
+}
+
+func (r *userRelationRepository) UpdateTeacherStudentRelation(ctx context.Context, relation *models.TeacherStudentRelation) error {
+	return r.db.WithContext(ctx).Save(relation).Error
+}
+
+// DeleteTeacherStudentRelation 删除教师-学生关系
+// 软删除只用于随用户删除的关系，这里直接删除，学生之后可以重新加入同一课程
+func (r *userRelationRepository) DeleteTeacherStudentRelation(ctx context.Context, id int64) error {
+	return r.db.WithContext(ctx).Unscoped().Delete(&models.TeacherStudentRelation{}, id).Error
+}
+
 func (r *userRelationRepository) GetTeachersByAdminID(ctx context.Context, adminID int64) ([]*models.User, error) {
 	var users []*models.User
 	err := r.db.WithContext(ctx).
@@ -3,20 +3,143 @@ package repository
 import (
 	"context"
 	"errors"
+	"fmt"
+	"strings"
+	"time"
+
 	"EduGo_servers/internal/models"
 	"gorm.io/gorm"
 )
 
+// 分页默认值
+const (
+	DefaultPageSize = 20
+	MaxPageSize     = 100
+)
+
+// userSortColumns 允许排序的字段
+var userSortColumns = map[string]string{
+	"id":            "users.id",
+	"username":      "users.username",
+	"email":         "users.email",
+	"role":          "users.role",
+	"status":        "users.status",
+	"created_at":    "users.created_at",
+	"last_login_at": "users.last_login_at",
+}
+
+// UserListQuery 用户列表查询条件
+type UserListQuery struct {
+	Page     int   // 页码，从1开始
+	PageSize int   // 每页数量
+	Cursor   int64 // 游标（上一页最后一个用户ID），大于0时使用游标分页并按ID排序
+
+	Roles       []string   // 角色过滤，为空时不过滤
+	Status      string     // 状态过滤
+	CreatedFrom *time.Time // 创建时间起（含）
+	CreatedTo   *time.Time // 创建时间止（不含）
+	Search      string     // 按用户名、邮箱、姓名前缀搜索（不区分大小写）
+
+	SortBy    string // 排序字段，见userSortColumns
+	SortOrder string // asc 或 desc
+
+	// 与调用者的关系过滤
+	RelatedToUserID int64  // 调用者ID，大于0时启用
+	RelationType    string // 关系类型
+	RelationReverse bool   // false: 调用者为关系发起者；true: 调用者为关系接收者
+
+	Deleted bool // 为true时只返回已软删除的用户
+}
+
+// UserListResult 用户列表查询结果
+type UserListResult struct {
+	Users      []*models.User
+	Total      int64
+	NextCursor int64 // 下一页游标，0表示没有更多数据（仅游标分页时有效）
+}
+
+// PurgeResult 彻底删除用户的结果，数据库记录已删除的文件由调用方从存储中删除
+type PurgeResult struct {
+	Users       int64
+	FileKeys    []string // 作业提交文件在文件存储中的key
+	ExportPaths []string // 数据导出压缩包的路径
+}
+
+// Normalize 规范化分页和排序参数
+func (q *UserListQuery) Normalize() {
+	if q.Page < 1 {
+		q.Page = 1
+	}
+	if q.PageSize <= 0 {
+		q.PageSize = DefaultPageSize
+	}
+	if q.PageSize > MaxPageSize {
+		q.PageSize = MaxPageSize
+	}
+	if _, ok := userSortColumns[q.SortBy]; !ok {
+		q.SortBy = "id"
+	}
+	if q.SortOrder != "desc" {
+		q.SortOrder = "asc"
+	}
+	// 游标分页只支持按ID排序
+	if q.Cursor > 0 {
+		q.SortBy = "id"
+	}
+}
+
+// escapeLike 转义LIKE通配符
+func escapeLike(s string) string {
+	replacer := strings.NewReplacer("\\", "\\\\", "%", "\\%", "_", "\\_")
+	return replacer.Replace(s)
+}
+
+// maxNameSearchSplit 按姓名搜索时最多尝试的姓的长度
+const maxNameSearchSplit = 10
+
+// userSearchCondition 用户搜索条件：用户名、邮箱、名或姓按前缀匹配，全名按“姓+名”或“名 姓”匹配
+// 只使用前缀匹配以便走索引，大小写由数据库的排序规则忽略
+func userSearchCondition(db *gorm.DB, search string) *gorm.DB {
+	prefix := escapeLike(search) + "%"
+	cond := db.Where("users.username LIKE ?", prefix).
+		Or("users.email LIKE ?", prefix).
+		Or("users.first_name LIKE ?", prefix).
+		Or("users.last_name LIKE ?", prefix)
+
+	// “名 姓”或“姓 名”
+	if parts := strings.Fields(search); len(parts) == 2 {
+		cond = cond.Or("users.first_name = ? AND users.last_name LIKE ?", parts[0], escapeLike(parts[1])+"%").
+			Or("users.last_name = ? AND users.first_name LIKE ?", parts[0], escapeLike(parts[1])+"%")
+		return cond
+	}
+	// 中文姓名连写，依次尝试把前几个字作为姓
+	runes := []rune(search)
+	for i := 1; i < len(runes) && i <= maxNameSearchSplit; i++ {
+		cond = cond.Or("users.last_name = ? AND users.first_name LIKE ?", string(runes[:i]), escapeLike(string(runes[i:]))+"%")
+	}
+	return cond
+}
+
 type UserRepository interface {
 	CreateUser(ctx context.Context, user *models.User) error
 	GetUserByID(ctx context.Context, id int64) (*models.User, error)
 	GetUserByUsername(ctx context.Context, username string) (*models.User, error)
 	UpdateUser(ctx context.Context, user *models.User) error
 	DeleteUser(ctx context.Context, id int64) error
+	GetDeletedUserByID(ctx context.Context, id int64) (*models.User, error)
+	RestoreUser(ctx context.Context, id int64) error
+	PurgeDeletedUsers(ctx context.Context, deletedBefore time.Time) (*PurgeResult, error)
+	AnonymizeUser(ctx context.Context, id int64) error
 	UserExists(username string, email string) bool
 	IsFirstUser() bool
 	GetUsersByRole(ctx context.Context, role string) ([]*models.User, error)
+	GetUsersByIDs(ctx context.Context, ids []int64) ([]*models.User, error)
 	GetAllUsers(ctx context.Context) ([]*models.User, error)
+	ListUsers(ctx context.Context, query *UserListQuery) (*UserListResult, error)
+	
+	// 登录记录
+	CreateLoginRecord(ctx context.Context, record *models.LoginRecord) error
+	GetLoginRecords(ctx context.Context, userID int64) ([]*models.LoginRecord, error)
 	
 	// 用户关系相关
 	CreateUserRelation(ctx context.Context, relation *models.UserRelation) error
@@ -60,22 +183,285 @@ func (r *userRepository) UpdateUser(ctx context.Context, user *models.User) erro
 	return r.db.WithContext(ctx).Save(user).Error
 }
 
+// relationModels 所有关系类型的存储模型
+func relationModels() []interface{} {
+	return []interface{}{
+		&models.UserRelation{},
+		&models.AdminTeacherRelation{},
+		&models.TeacherStudentRelation{},
+		&models.StudentParentRelation{},
+	}
+}
+
+// DeleteUser 软删除用户，并以相同的删除时间软删除该用户的所有关系，同时退出所有选课以释放名额
 func (r *userRepository) DeleteUser(ctx context.Context, id int64) error {
-	return r.db.WithContext(ctx).Delete(&models.User{}, id).Error
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		result := tx.Model(&models.User{}).Where("id = ?", id).Update("deleted_at", now)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		for _, model := range relationModels() {
+			err := tx.Model(model).
+				Where("user_id = ? OR related_user_id = ?", id, id).
+				Update("deleted_at", now).Error
+			if err != nil {
+				return err
+			}
+		}
+		return dropStudentEnrollments(tx, []int64{id}, now)
+	})
+}
+
+// GetDeletedUserByID 获取已软删除的用户
+func (r *userRepository) GetDeletedUserByID(ctx context.Context, id int64) (*models.User, error) {
+	var user models.User
+	err := r.db.WithContext(ctx).Unscoped().
+		Where("id = ? AND deleted_at IS NOT NULL", id).
+		First(&user).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return &user, err
+}
+
+// RestoreUser 恢复已软删除的用户，以及随该用户一起删除且对方仍存在的关系和选课
+func (r *userRepository) RestoreUser(ctx context.Context, id int64) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var user models.User
+		err := tx.Unscoped().Where("id = ? AND deleted_at IS NOT NULL", id).First(&user).Error
+		if err != nil {
+			return err
+		}
+		deletedAt := user.DeletedAt.Time
+
+		if err := tx.Unscoped().Model(&user).Update("deleted_at", nil).Error; err != nil {
+			return err
+		}
+
+		activeUsers := tx.Model(&models.User{}).Select("id")
+		for _, model := range relationModels() {
+			err := tx.Unscoped().Model(model).
+				Where("deleted_at = ?", deletedAt).
+				Where("(user_id = ? AND related_user_id IN (?)) OR (related_user_id = ? AND user_id IN (?))",
+					id, activeUsers, id, activeUsers).
+				Update("deleted_at", nil).Error
+			if err != nil {
+				return err
+			}
+		}
+		return restoreStudentEnrollments(tx, id, deletedAt, time.Now())
+	})
+}
+
+// PurgeDeletedUsers 彻底删除在指定时间之前软删除的用户及其关系、学习数据、登录记录和数据导出，
+// 返回删除的用户数以及需要从存储中删除的提交文件和导出文件
+func (r *userRepository) PurgeDeletedUsers(ctx context.Context, deletedBefore time.Time) (*PurgeResult, error) {
+	purged := &PurgeResult{}
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		expired := tx.Unscoped().Model(&models.User{}).
+			Select("id").
+			Where("deleted_at IS NOT NULL AND deleted_at < ?", deletedBefore)
+
+		var ids []int64
+		if err := expired.Pluck("id", &ids).Error; err != nil {
+			return err
+		}
+		if len(ids) == 0 {
+			return nil
+		}
+
+		for _, model := range relationModels() {
+			err := tx.Unscoped().
+				Where("user_id IN ? OR related_user_id IN ?", ids, ids).
+				Delete(model).Error
+			if err != nil {
+				return err
+			}
+		}
+
+		if err := removeClassMemberships(tx, ids); err != nil {
+			return err
+		}
+		if err := dropStudentEnrollments(tx, ids, time.Now()); err != nil {
+			return err
+		}
+		if err := tx.Where("student_id IN ?", ids).Delete(&models.Enrollment{}).Error; err != nil {
+			return err
+		}
+		for _, model := range []interface{}{&models.AttendanceRecord{}, &models.GradeEntry{}, &models.TermGrade{}, &models.ReportCardComment{},
+			&models.KnowledgeObservation{}, &models.StudentMastery{}, &models.MistakeEntry{}, &models.ReviewItem{}, &models.ReviewLog{},
+			&models.RecommendationFeedback{}, &models.RecommendationOverride{}, &models.LessonActivity{}, &models.LessonProgress{}} {
+			if err := tx.Where("student_id IN ?", ids).Delete(model).Error; err != nil {
+				return err
+			}
+		}
+		practices := tx.Model(&models.MistakePractice{}).Select("id").Where("student_id IN ?", ids)
+		if err := tx.Where("practice_id IN (?)", practices).Delete(&models.MistakePracticeItem{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("student_id IN ?", ids).Delete(&models.MistakePractice{}).Error; err != nil {
+			return err
+		}
+
+		// 作业提交的文件由调用方在事务提交后从存储中删除
+		submissions := tx.Model(&models.Submission{}).Select("id").Where("student_id IN ?", ids)
+		if err := tx.Model(&models.SubmissionFile{}).Where("submission_id IN (?)", submissions).Pluck("storage_key", &purged.FileKeys).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("submission_id IN (?)", submissions).Delete(&models.SubmissionFile{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("student_id IN ?", ids).Delete(&models.Submission{}).Error; err != nil {
+			return err
+		}
+		attempts := tx.Model(&models.AssessmentAttempt{}).Select("id").Where("student_id IN ?", ids)
+		if err := tx.Where("attempt_id IN (?)", attempts).Delete(&models.AttemptAnswer{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("student_id IN ?", ids).Delete(&models.AssessmentAttempt{}).Error; err != nil {
+			return err
+		}
+		for _, model := range []interface{}{&models.Notification{}, &models.CalendarFeed{}} {
+			if err := tx.Where("user_id IN ?", ids).Delete(model).Error; err != nil {
+				return err
+			}
+		}
+		if err := deleteXAPIData(tx, ids); err != nil {
+			return err
+		}
+		if err := deleteLTIData(tx, ids); err != nil {
+			return err
+		}
+
+		// 登录记录、导出任务和删除申请同样包含个人信息，导出的压缩包由调用方在事务提交后删除
+		err := tx.Model(&models.DataExport{}).
+			Where("(user_id IN ? OR requested_by IN ?) AND file_path <> ''", ids, ids).
+			Pluck("file_path", &purged.ExportPaths).Error
+		if err != nil {
+			return err
+		}
+		if err := tx.Where("user_id IN ? OR requested_by IN ?", ids, ids).Delete(&models.DataExport{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id IN ? OR requested_by IN ?", ids, ids).Delete(&models.ErasureRequest{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id IN ?", ids).Delete(&models.LoginRecord{}).Error; err != nil {
+			return err
+		}
+
+		result := tx.Unscoped().Where("id IN ?", ids).Delete(&models.User{})
+		if result.Error != nil {
+			return result.Error
+		}
+		purged.Users = result.RowsAffected
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return purged, nil
 }
 
+// AnonymizeUser 匿名化用户：清除个人信息、删除关系、登录记录和学习记录存储中的数据，保留用户ID以免破坏统计类数据
+func (r *userRepository) AnonymizeUser(ctx context.Context, id int64) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Unscoped().Model(&models.User{}).Where("id = ?", id).Updates(map[string]interface{}{
+			"username":      fmt.Sprintf("erased_user_%d", id),
+			"email":         nil,
+			"password":      "",
+			"first_name":    "",
+			"last_name":     "",
+			"status":        models.UserStatusErased,
+			"last_login_at": nil,
+		})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+
+		for _, model := range relationModels() {
+			err := tx.Unscoped().
+				Where("user_id = ? OR related_user_id = ?", id, id).
+				Delete(model).Error
+			if err != nil {
+				return err
+			}
+		}
+
+		if err := removeClassMemberships(tx, []int64{id}); err != nil {
+			return err
+		}
+		if err := dropStudentEnrollments(tx, []int64{id}, time.Now()); err != nil {
+			return err
+		}
+
+		for _, model := range []interface{}{&models.LoginRecord{}, &models.Notification{}, &models.CalendarFeed{}} {
+			if err := tx.Where("user_id = ?", id).Delete(model).Error; err != nil {
+				return err
+			}
+		}
+		// 学习记录存储中的语句包含用户名等个人信息
+		if err := deleteXAPIData(tx, []int64{id}); err != nil {
+			return err
+		}
+		// 外部平台的身份对应关系可以关联到平台中的真实身份
+		if err := deleteLTIData(tx, []int64{id}); err != nil {
+			return err
+		}
+
+		// 已生成的导出文件立即过期，由清理任务删除
+		return tx.Model(&models.DataExport{}).
+			Where("user_id = ?", id).
+			Update("expires_at", time.Now()).Error
+	})
+}
+
+// CreateLoginRecord 记录一次登录，并更新用户的最后登录时间
+func (r *userRepository) CreateLoginRecord(ctx context.Context, record *models.LoginRecord) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(record).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.User{}).
+			Where("id = ?", record.UserID).
+			Update("last_login_at", record.CreatedAt).Error
+	})
+}
+
+// GetLoginRecords 获取用户的登录记录，按时间倒序
+func (r *userRepository) GetLoginRecords(ctx context.Context, userID int64) ([]*models.LoginRecord, error) {
+	var records []*models.LoginRecord
+	err := r.db.WithContext(ctx).
+		Where("user_id = ?", userID).
+		Order("created_at DESC").
+		Find(&records).Error
+	return records, err
+}
+
+// UserExists 检查用户名或邮箱是否已被占用（包括尚未彻底删除的用户）
 func (r *userRepository) UserExists(username string, email string) bool {
 	var count int64
-	r.db.Model(&models.User{}).
-		Where("username = ? OR email = ?", username, email).
-		Count(&count)
+	query := r.db.Unscoped().Model(&models.User{})
+	if email != "" {
+		query = query.Where("username = ? OR email = ?", username, email)
+	} else {
+		query = query.Where("username = ?", username)
+	}
+	query.Count(&count)
 	return count > 0
 }
 
 // IsFirstUser 检查是否是第一个用户
 func (r *userRepository) IsFirstUser() bool {
 	var count int64
-	r.db.Model(&models.User{}).Count(&count)
+	r.db.Unscoped().Model(&models.User{}).Count(&count)
 	return count == 0
 }
 
@@ -86,6 +472,16 @@ func (r *userRepository) GetUsersByRole(ctx context.Context, role string) ([]*mo
 	return users, err
 }
 
+// GetUsersByIDs 按ID批量获取用户，不包括已删除的用户
+func (r *userRepository) GetUsersByIDs(ctx context.Context, ids []int64) ([]*models.User, error) {
+	users := []*models.User{}
+	if len(ids) == 0 {
+		return users, nil
+	}
+	err := r.db.WithContext(ctx).Where("id IN ?", ids).Order("id").Find(&users).Error
+	return users, err
+}
+
 // GetAllUsers 获取所有用户
 func (r *userRepository) GetAllUsers(ctx context.Context) ([]*models.User, error) {
 	var users []*models.User
@@ -93,6 +489,75 @@ func (r *userRepository) GetAllUsers(ctx context.Context) ([]*models.User, error
 	return users, err
 }
 
+// ListUsers 分页获取用户列表，支持过滤、排序和搜索
+func (r *userRepository) ListUsers(ctx context.Context, query *UserListQuery) (*UserListResult, error) {
+	query.Normalize()
+
+	db := r.db.WithContext(ctx).Model(&models.User{})
+	if query.Deleted {
+		db = db.Unscoped().Where("users.deleted_at IS NOT NULL")
+	}
+	if len(query.Roles) > 0 {
+		db = db.Where("users.role IN ?", query.Roles)
+	}
+	if query.Status != "" {
+		db = db.Where("users.status = ?", query.Status)
+	}
+	if query.CreatedFrom != nil {
+		db = db.Where("users.created_at >= ?", *query.CreatedFrom)
+	}
+	if query.CreatedTo != nil {
+		db = db.Where("users.created_at < ?", *query.CreatedTo)
+	}
+	if search := strings.TrimSpace(query.Search); search != "" {
+		db = db.Where(userSearchCondition(r.db, search))
+	}
+	if query.RelatedToUserID > 0 {
+		model, ok := relationModel(query.RelationType)
+		if !ok {
+			return nil, ErrInvalidRelationType
+		}
+		selfColumn, otherColumn := "user_id", "related_user_id"
+		if query.RelationReverse {
+			selfColumn, otherColumn = otherColumn, selfColumn
+		}
+		subQuery := r.db.Model(model).
+			Select(otherColumn).
+			Where(selfColumn+" = ? AND status = ?", query.RelatedToUserID, models.RelationStatusActive)
+		db = db.Where("users.id IN (?)", subQuery)
+	}
+
+	result := &UserListResult{}
+	if err := db.Count(&result.Total).Error; err != nil {
+		return nil, err
+	}
+
+	if query.Cursor > 0 {
+		if query.SortOrder == "desc" {
+			db = db.Where("users.id < ?", query.Cursor)
+		} else {
+			db = db.Where("users.id > ?", query.Cursor)
+		}
+	} else {
+		db = db.Offset((query.Page - 1) * query.PageSize)
+	}
+
+	order := userSortColumns[query.SortBy] + " " + query.SortOrder
+	if query.SortBy != "id" {
+		// 保证排序稳定
+		order += ", users.id " + query.SortOrder
+	}
+
+	if err := db.Order(order).Limit(query.PageSize).Find(&result.Users).Error; err != nil {
+		return nil, err
+	}
+
+	if query.SortBy == "id" && len(result.Users) == query.PageSize {
+		result.NextCursor = result.Users[len(result.Users)-1].ID
+	}
+	return result, nil
+}
+
 // CreateUserRelation 创建用户关系
 func (r *userRepository) CreateUserRelation(ctx context.Context, relation *models.UserRelation) error {
 	return r.db.WithContext(ctx).Create(relation).Error
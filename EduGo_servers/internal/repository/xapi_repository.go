@@ -0,0 +1,284 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"EduGo_servers/internal/models"
+	"EduGo_servers/internal/xapi"
+	"gorm.io/gorm"
+)
+
+// XAPIStatementQuery 语句查询条件，Agent和Activity为标识键
+type XAPIStatementQuery struct {
+	Agent             string
+	VerbID            string
+	Activity          string
+	Registration      string
+	RelatedActivities bool // Activity也匹配情境活动和子语句中的活动
+	RelatedAgents     bool // Agent也匹配授权方、指导者、团队和小组成员
+	Since             *time.Time
+	Until             *time.Time
+	Ascending         bool
+	Limit             int
+	After             int64 // 翻页游标：上一页最后一条语句的主键
+
+	UserID    *int64 // 只返回执行者为该用户的语句
+	VisibleTo int64  // 大于0时只返回执行者为该用户或由该用户提交的语句
+}
+
+type XAPIRepository interface {
+	CreateCredential(ctx context.Context, credential *models.XAPICredential) error
+	GetCredentialByID(ctx context.Context, id int64) (*models.XAPICredential, error)
+	GetCredentialByKey(ctx context.Context, key string) (*models.XAPICredential, error)
+	GetUserCredentials(ctx context.Context, userID int64) ([]*models.XAPICredential, error)
+	TouchCredential(ctx context.Context, id int64, usedAt time.Time) error
+	DeleteCredential(ctx context.Context, id int64) error
+	GetAgentUser(ctx context.Context, username, email string) (*models.User, error)
+
+	GetStatement(ctx context.Context, statementID string) (*models.XAPIStatement, error)
+	GetStatementsByIDs(ctx context.Context, statementIDs []string) ([]*models.XAPIStatement, error)
+	SaveStatements(ctx context.Context, statements []*models.XAPIStatement) error
+	QueryStatements(ctx context.Context, query *XAPIStatementQuery) ([]*models.XAPIStatement, error)
+	GetUserStatements(ctx context.Context, userID int64, since *time.Time) ([]*models.XAPIStatement, error)
+
+	GetDocument(ctx context.Context, key string) (*models.XAPIDocument, error)
+	GetDocumentIDs(ctx context.Context, kind, activityID, agentKey string, registration *string, since *time.Time) ([]string, error)
+	SaveDocument(ctx context.Context, document *models.XAPIDocument) error
+	DeleteDocuments(ctx context.Context, kind, activityID, agentKey string, registration *string) error
+	DeleteDocument(ctx context.Context, id int64) error
+	GetUserDocuments(ctx context.Context, userID int64) ([]*models.XAPIDocument, error)
+}
+
+type xapiRepository struct {
+	db *gorm.DB
+}
+
+func NewXAPIRepository(db *gorm.DB) XAPIRepository {
+	return &xapiRepository{db: db}
+}
+
+func (r *xapiRepository) CreateCredential(ctx context.Context, credential *models.XAPICredential) error {
+	return r.db.WithContext(ctx).Omit("User").Create(credential).Error
+}
+
+func (r *xapiRepository) GetCredentialByID(ctx context.Context, id int64) (*models.XAPICredential, error) {
+	var credential models.XAPICredential
+	err := r.db.WithContext(ctx).First(&credential, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return &credential, err
+}
+
+// GetCredentialByKey 按基本认证的用户名获取凭据及其用户，用户已删除时凭据不可用
+func (r *xapiRepository) GetCredentialByKey(ctx context.Context, key string) (*models.XAPICredential, error) {
+	var credential models.XAPICredential
+	err := r.db.WithContext(ctx).Preload("User").Where("`key` = ?", key).First(&credential).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) || (err == nil && credential.User == nil) {
+		return nil, nil
+	}
+	return &credential, err
+}
+
+func (r *xapiRepository) GetUserCredentials(ctx context.Context, userID int64) ([]*models.XAPICredential, error) {
+	var credentials []*models.XAPICredential
+	err := r.db.WithContext(ctx).Where("user_id = ?", userID).Order("id DESC").Find(&credentials).Error
+	return credentials, err
+}
+
+func (r *xapiRepository) TouchCredential(ctx context.Context, id int64, usedAt time.Time) error {
+	return r.db.WithContext(ctx).Model(&models.XAPICredential{}).Where("id = ?", id).Update("last_used_at", usedAt).Error
+}
+
+func (r *xapiRepository) DeleteCredential(ctx context.Context, id int64) error {
+	return r.db.WithContext(ctx).Delete(&models.XAPICredential{}, id).Error
+}
+
+// GetAgentUser 按用户名或邮箱查找代理人对应的用户，优先使用用户名
+func (r *xapiRepository) GetAgentUser(ctx context.Context, username, email string) (*models.User, error) {
+	var user models.User
+	var err error
+	switch {
+	case username != "":
+		err = r.db.WithContext(ctx).Where("username = ?", username).First(&user).Error
+	case email != "":
+		err = r.db.WithContext(ctx).Where("email = ?", email).First(&user).Error
+	default:
+		return nil, nil
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return &user, err
+}
+
+func (r *xapiRepository) GetStatement(ctx context.Context, statementID string) (*models.XAPIStatement, error) {
+	var statement models.XAPIStatement
+	err := r.db.WithContext(ctx).Where("statement_id = ?", statementID).First(&statement).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return &statement, err
+}
+
+func (r *xapiRepository) GetStatementsByIDs(ctx context.Context, statementIDs []string) ([]*models.XAPIStatement, error) {
+	var statements []*models.XAPIStatement
+	if len(statementIDs) == 0 {
+		return statements, nil
+	}
+	err := r.db.WithContext(ctx).Where("statement_id IN ?", statementIDs).Find(&statements).Error
+	return statements, err
+}
+
+// SaveStatements 在一个事务中保存语句及其涉及的活动和代理人，作废语句把引用的语句标记为已作废
+// 作废语句不能被作废
+func (r *xapiRepository) SaveStatements(ctx context.Context, statements []*models.XAPIStatement) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, statement := range statements {
+			if err := tx.Create(statement).Error; err != nil {
+				return err
+			}
+			if statement.VoidTarget == "" {
+				continue
+			}
+			err := tx.Model(&models.XAPIStatement{}).
+				Where("statement_id = ? AND verb_id <> ?", statement.VoidTarget, xapi.VerbVoided).
+				Update("voided", true).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// QueryStatements 按条件查询未作废的语句，默认最新存储的排在前面，最多返回Limit+1条以便判断是否还有更多
+func (r *xapiRepository) QueryStatements(ctx context.Context, query *XAPIStatementQuery) ([]*models.XAPIStatement, error) {
+	db := r.db.WithContext(ctx)
+	refs := func(kind, value string, related bool) *gorm.DB {
+		sub := db.Model(&models.XAPIStatementRef{}).Select("statement_id").Where("kind = ? AND value = ?", kind, value)
+		if !related {
+			sub = sub.Where("direct = ?", true)
+		}
+		return sub
+	}
+
+	q := db.Model(&models.XAPIStatement{}).Where("voided = ?", false)
+	if query.Agent != "" {
+		q = q.Where("id IN (?)", refs(models.XAPIRefAgent, query.Agent, query.RelatedAgents))
+	}
+	if query.VerbID != "" {
+		q = q.Where("verb_id = ?", xapi.Key(query.VerbID))
+	}
+	if query.Activity != "" {
+		q = q.Where("id IN (?)", refs(models.XAPIRefActivity, query.Activity, query.RelatedActivities))
+	}
+	if query.Registration != "" {
+		q = q.Where("registration = ?", query.Registration)
+	}
+	if query.Since != nil {
+		q = q.Where("stored > ?", *query.Since)
+	}
+	if query.Until != nil {
+		q = q.Where("stored <= ?", *query.Until)
+	}
+	if query.UserID != nil {
+		q = q.Where("user_id = ?", *query.UserID)
+	}
+	if query.VisibleTo > 0 {
+		q = q.Where("user_id = ? OR authority_id = ?", query.VisibleTo, query.VisibleTo)
+	}
+
+	// 主键顺序即存储顺序
+	if query.Ascending {
+		if query.After > 0 {
+			q = q.Where("id > ?", query.After)
+		}
+		q = q.Order("id")
+	} else {
+		if query.After > 0 {
+			q = q.Where("id < ?", query.After)
+		}
+		q = q.Order("id DESC")
+	}
+
+	var statements []*models.XAPIStatement
+	err := q.Limit(query.Limit + 1).Find(&statements).Error
+	return statements, err
+}
+
+// GetUserStatements 按存储顺序获取执行者为该用户的未作废语句，供学习进度和数据导出使用
+func (r *xapiRepository) GetUserStatements(ctx context.Context, userID int64, since *time.Time) ([]*models.XAPIStatement, error) {
+	q := r.db.WithContext(ctx).Where("user_id = ? AND voided = ?", userID, false)
+	if since != nil {
+		q = q.Where("stored > ?", *since)
+	}
+	var statements []*models.XAPIStatement
+	err := q.Order("id").Find(&statements).Error
+	return statements, err
+}
+
+func (r *xapiRepository) GetDocument(ctx context.Context, key string) (*models.XAPIDocument, error) {
+	var document models.XAPIDocument
+	err := r.db.WithContext(ctx).Where("`key` = ?", key).First(&document).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return &document, err
+}
+
+// documentScope 活动（和代理人）下的文档，registration为空表示不限注册
+func (r *xapiRepository) documentScope(ctx context.Context, kind, activityID, agentKey string, registration *string) *gorm.DB {
+	q := r.db.WithContext(ctx).Model(&models.XAPIDocument{}).
+		Where("kind = ? AND activity_id = ? AND agent_key = ?", kind, activityID, agentKey)
+	if registration != nil {
+		q = q.Where("registration = ?", *registration)
+	}
+	return q
+}
+
+// GetDocumentIDs 获取活动（和代理人）下的文档ID，since不为空时只返回之后修改的文档
+func (r *xapiRepository) GetDocumentIDs(ctx context.Context, kind, activityID, agentKey string, registration *string, since *time.Time) ([]string, error) {
+	q := r.documentScope(ctx, kind, activityID, agentKey, registration)
+	if since != nil {
+		q = q.Where("updated_at > ?", *since)
+	}
+	var ids []string
+	err := q.Order("document_id").Distinct().Pluck("document_id", &ids).Error
+	return ids, err
+}
+
+func (r *xapiRepository) SaveDocument(ctx context.Context, document *models.XAPIDocument) error {
+	return r.db.WithContext(ctx).Save(document).Error
+}
+
+// DeleteDocuments 删除活动（和代理人）下的全部文档
+func (r *xapiRepository) DeleteDocuments(ctx context.Context, kind, activityID, agentKey string, registration *string) error {
+	return r.documentScope(ctx, kind, activityID, agentKey, registration).Delete(&models.XAPIDocument{}).Error
+}
+
+func (r *xapiRepository) DeleteDocument(ctx context.Context, id int64) error {
+	return r.db.WithContext(ctx).Delete(&models.XAPIDocument{}, id).Error
+}
+
+func (r *xapiRepository) GetUserDocuments(ctx context.Context, userID int64) ([]*models.XAPIDocument, error) {
+	var documents []*models.XAPIDocument
+	err := r.db.WithContext(ctx).Where("user_id = ?", userID).Order("id").Find(&documents).Error
+	return documents, err
+}
+
+// deleteXAPIData 删除用户的学习记录存储凭据、语句和状态
+func deleteXAPIData(tx *gorm.DB, userIDs []int64) error {
+	statements := tx.Model(&models.XAPIStatement{}).Select("id").Where("user_id IN ?", userIDs)
+	if err := tx.Where("statement_id IN (?)", statements).Delete(&models.XAPIStatementRef{}).Error; err != nil {
+		return err
+	}
+	for _, model := range []interface{}{&models.XAPIStatement{}, &models.XAPIDocument{}, &models.XAPICredential{}} {
+		if err := tx.Where("user_id IN ?", userIDs).Delete(model).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
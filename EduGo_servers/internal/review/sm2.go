@@ -0,0 +1,86 @@
+// Package review 用SM-2算法安排题目的间隔复习
+//
+// 每次复习按回忆质量（0到5）评分：达到3分视为记住，复习间隔依次为1天、6天，
+// 之后每次乘以难易系数；低于3分视为遗忘，从1天重新开始。难易系数随每次的评分调整，
+// 回忆越吃力系数越小、间隔增长越慢，最小为1.3。
+package review
+
+import (
+	"math"
+	"time"
+)
+
+// 回忆质量
+const (
+	MinQuality  = 0
+	MaxQuality  = 5
+	PassQuality = 3 // 不低于该分数视为记住
+)
+
+// 自动评分时默认的回忆质量
+const (
+	DefaultCorrectQuality   = 4
+	DefaultIncorrectQuality = 1
+)
+
+const (
+	InitialEaseFactor = 2.5
+	MinEaseFactor     = 1.3
+)
+
+// State 一道题的复习进度
+type State struct {
+	EaseFactor  float64 // 难易系数
+	Interval    int     // 当前的复习间隔（天）
+	Repetitions int     // 连续记住的次数
+}
+
+// Initial 还没有复习过的题目
+var Initial = State{EaseFactor: InitialEaseFactor}
+
+// Next 按本次复习的回忆质量计算下一次的复习进度
+func (s State) Next(quality int) State {
+	if s.EaseFactor == 0 {
+		s.EaseFactor = InitialEaseFactor
+	}
+	if quality < PassQuality {
+		s.Repetitions = 0
+		s.Interval = 1
+	} else {
+		switch s.Repetitions {
+		case 0:
+			s.Interval = 1
+		case 1:
+			s.Interval = 6
+		default:
+			s.Interval = int(math.Round(float64(s.Interval) * s.EaseFactor))
+		}
+		s.Repetitions++
+	}
+
+	q := float64(MaxQuality - quality)
+	s.EaseFactor += 0.1 - q*(0.08+q*0.02)
+	if s.EaseFactor < MinEaseFactor {
+		s.EaseFactor = MinEaseFactor
+	}
+	return s
+}
+
+// QualityFor 根据自动评分的对错给出默认的回忆质量
+func QualityFor(correct bool) int {
+	if correct {
+		return DefaultCorrectQuality
+	}
+	return DefaultIncorrectQuality
+}
+
+// StartOfDay t所在日期的零点（服务器时区）
+func StartOfDay(t time.Time) time.Time {
+	year, month, day := t.Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, t.Location())
+}
+
+// DueAt 在reviewedAt复习后下一次应复习的日期
+func DueAt(reviewedAt time.Time, interval int) time.Time {
+	return StartOfDay(reviewedAt).AddDate(0, 0, interval)
+}
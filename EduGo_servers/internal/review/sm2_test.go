@@ -0,0 +1,112 @@
+package review
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+const tolerance = 1e-9
+
+func TestNext(t *testing.T) {
+	tests := []struct {
+		name    string
+		state   State
+		quality int
+		want    State
+	}{
+		{"第一次记住", Initial, 4, State{EaseFactor: 2.5, Interval: 1, Repetitions: 1}},
+		{"第一次轻松记住", Initial, 5, State{EaseFactor: 2.6, Interval: 1, Repetitions: 1}},
+		{"零值按初始状态处理", State{}, 4, State{EaseFactor: 2.5, Interval: 1, Repetitions: 1}},
+		{"第二次记住间隔6天", State{EaseFactor: 2.5, Interval: 1, Repetitions: 1}, 4, State{EaseFactor: 2.5, Interval: 6, Repetitions: 2}},
+		{"之后按系数增长", State{EaseFactor: 2.5, Interval: 6, Repetitions: 2}, 4, State{EaseFactor: 2.5, Interval: 15, Repetitions: 3}},
+		{"按调整前的系数计算间隔", State{EaseFactor: 2.6, Interval: 6, Repetitions: 2}, 5, State{EaseFactor: 2.7, Interval: 16, Repetitions: 3}},
+		{"间隔四舍五入", State{EaseFactor: 2.5, Interval: 15, Repetitions: 3}, 3, State{EaseFactor: 2.36, Interval: 38, Repetitions: 4}},
+		{"低于3分重新开始", State{EaseFactor: 2.5, Interval: 15, Repetitions: 3}, 2, State{EaseFactor: 2.18, Interval: 1, Repetitions: 0}},
+		{"完全忘记", State{EaseFactor: 2.5, Interval: 38, Repetitions: 4}, 0, State{EaseFactor: 1.7, Interval: 1, Repetitions: 0}},
+		{"重新开始后第二次仍为6天", State{EaseFactor: 2.18, Interval: 1, Repetitions: 1}, 4, State{EaseFactor: 2.18, Interval: 6, Repetitions: 2}},
+		{"系数不低于1.3", State{EaseFactor: 1.4, Interval: 6, Repetitions: 2}, 3, State{EaseFactor: MinEaseFactor, Interval: 8, Repetitions: 3}},
+		{"遗忘时系数不低于1.3", State{EaseFactor: 1.5, Interval: 8, Repetitions: 3}, 0, State{EaseFactor: MinEaseFactor, Interval: 1, Repetitions: 0}},
+		{"系数为1.3时保持", State{EaseFactor: MinEaseFactor, Interval: 8, Repetitions: 3}, 1, State{EaseFactor: MinEaseFactor, Interval: 1, Repetitions: 0}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.state.Next(tt.quality)
+			if got.Interval != tt.want.Interval || got.Repetitions != tt.want.Repetitions || math.Abs(got.EaseFactor-tt.want.EaseFactor) > tolerance {
+				t.Errorf("%+v.Next(%d) = %+v, want %+v", tt.state, tt.quality, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNextIntervalSequence(t *testing.T) {
+	tests := []struct {
+		name    string
+		quality int
+		want    []int
+	}{
+		{"每次评4分", 4, []int{1, 6, 15, 38, 95}},
+		{"每次评5分", 5, []int{1, 6, 16, 45, 131}},
+		{"每次评3分", 3, []int{1, 6, 13, 27, 52}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			state := Initial
+			for i, want := range tt.want {
+				previous := state
+				state = state.Next(tt.quality)
+				if state.Interval != want {
+					t.Fatalf("review %d: Interval = %d, want %d", i+1, state.Interval, want)
+				}
+				// 第三次起间隔为上一次的间隔乘以上一次的难易系数
+				if i >= 2 && state.Interval != int(math.Round(float64(previous.Interval)*previous.EaseFactor)) {
+					t.Fatalf("review %d: Interval = %d, want %v × %v", i+1, state.Interval, previous.Interval, previous.EaseFactor)
+				}
+			}
+		})
+	}
+}
+
+func TestNextEaseFactorFloor(t *testing.T) {
+	for quality := MinQuality; quality <= MaxQuality; quality++ {
+		state := Initial
+		for i := 0; i < 20; i++ {
+			state = state.Next(quality)
+			if state.EaseFactor < MinEaseFactor {
+				t.Fatalf("quality %d, review %d: EaseFactor = %v, want at least %v", quality, i+1, state.EaseFactor, MinEaseFactor)
+			}
+		}
+		if quality < PassQuality && state.EaseFactor != MinEaseFactor {
+			t.Errorf("quality %d: EaseFactor = %v after repeated failures, want %v", quality, state.EaseFactor, MinEaseFactor)
+		}
+	}
+}
+
+func TestQualityFor(t *testing.T) {
+	if got := QualityFor(true); got < PassQuality {
+		t.Errorf("QualityFor(true) = %d, want at least %d", got, PassQuality)
+	}
+	if got := QualityFor(false); got >= PassQuality {
+		t.Errorf("QualityFor(false) = %d, want below %d", got, PassQuality)
+	}
+}
+
+func TestDueAt(t *testing.T) {
+	tests := []struct {
+		name       string
+		reviewedAt time.Time
+		interval   int
+		want       time.Time
+	}{
+		{"当天深夜复习", time.Date(2024, 3, 1, 23, 59, 0, 0, time.UTC), 1, time.Date(2024, 3, 2, 0, 0, 0, 0, time.UTC)},
+		{"当天凌晨复习", time.Date(2024, 3, 1, 0, 0, 1, 0, time.UTC), 6, time.Date(2024, 3, 7, 0, 0, 0, 0, time.UTC)},
+		{"跨月和闰日", time.Date(2024, 2, 20, 12, 0, 0, 0, time.UTC), 15, time.Date(2024, 3, 6, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DueAt(tt.reviewedAt, tt.interval); !got.Equal(tt.want) {
+				t.Errorf("DueAt(%v, %d) = %v, want %v", tt.reviewedAt, tt.interval, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,97 @@
+// Package storage 文件存储，作业附件和提交的文件通过它保存
+// 存储后端可以替换，默认使用本地磁盘
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"unicode/utf8"
+)
+
+// ErrNotFound 文件不存在
+var ErrNotFound = errors.New("storage: file not found")
+
+// ErrInvalidKey 文件键不合法
+var ErrInvalidKey = errors.New("storage: invalid key")
+
+// Storage 存储后端，文件通过以/分隔的键访问，例如 assignments/1/attachments/a.pdf
+type Storage interface {
+	Save(ctx context.Context, key string, r io.Reader) (int64, error)
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+}
+
+// Factory 根据位置参数创建存储后端，位置的含义由后端决定（本地磁盘为目录）
+type Factory func(location string) (Storage, error)
+
+var backends = map[string]Factory{
+	"local": func(location string) (Storage, error) { return NewLocalStorage(location) },
+}
+
+// Store 当前使用的存储后端，由InitStorage初始化
+var Store Storage
+
+// RegisterBackend 注册存储后端，例如对象存储，应在InitStorage之前调用
+func RegisterBackend(name string, factory Factory) {
+	backends[name] = factory
+}
+
+// InitStorage 初始化存储后端，backend为空时使用本地磁盘
+func InitStorage(backend, location string) error {
+	if backend == "" {
+		backend = "local"
+	}
+	factory, ok := backends[backend]
+	if !ok {
+		return fmt.Errorf("unknown storage backend: %s", backend)
+	}
+
+	store, err := factory(location)
+	if err != nil {
+		return fmt.Errorf("failed to init storage backend %s: %w", backend, err)
+	}
+	Store = store
+	return nil
+}
+
+// cleanKey 规范化文件键，拒绝绝对路径和跳出根目录的键
+func cleanKey(key string) (string, error) {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, "\\") {
+		return "", ErrInvalidKey
+	}
+	cleaned := path.Clean(key)
+	if cleaned == "." || cleaned == ".." || strings.HasPrefix(cleaned, "../") {
+		return "", ErrInvalidKey
+	}
+	return cleaned, nil
+}
+
+// maxFileNameLength 文件键中文件名的最大字节数
+const maxFileNameLength = 100
+
+// SafeFileName 去掉文件名中的路径和控制字符，用于生成文件键
+func SafeFileName(name string) string {
+	name = path.Base(strings.ReplaceAll(name, "\\", "/"))
+	name = strings.Map(func(r rune) rune {
+		if r < 0x20 || r == 0x7f || r == '/' {
+			return -1
+		}
+		return r
+	}, name)
+	if name == "" || name == "." || name == ".." {
+		return "file"
+	}
+	// 保留末尾的扩展名，从完整的UTF-8字符处截断
+	if len(name) > maxFileNameLength {
+		start := len(name) - maxFileNameLength
+		for start < len(name) && !utf8.RuneStart(name[start]) {
+			start++
+		}
+		name = name[start:]
+	}
+	return name
+}
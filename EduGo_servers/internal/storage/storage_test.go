@@ -0,0 +1,36 @@
+package storage
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestSafeFileName(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"普通文件名", "report.pdf", "report.pdf"},
+		{"去掉路径", "../../etc/passwd", "passwd"},
+		{"去掉Windows路径", `C:\Users\a\作业.docx`, "作业.docx"},
+		{"去掉控制字符", "a\x00b\nc.txt", "abc.txt"},
+		{"空文件名", "", "file"},
+		{"只有上级目录", "..", "file"},
+		{"过长时保留末尾", strings.Repeat("a", 120) + ".pdf", strings.Repeat("a", 96) + ".pdf"},
+		// 每个汉字占3个字节，从第100个字节处截断会切开一个汉字
+		{"过长时不切开多字节字符", strings.Repeat("作", 40) + ".pdf", strings.Repeat("作", 32) + ".pdf"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := SafeFileName(tt.in)
+			if got != tt.want {
+				t.Errorf("SafeFileName(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+			if !utf8.ValidString(got) || len(got) > maxFileNameLength {
+				t.Errorf("SafeFileName(%q) = %q, want valid UTF-8 of at most %d bytes", tt.in, got, maxFileNameLength)
+			}
+		})
+	}
+}
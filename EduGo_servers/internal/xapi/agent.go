@@ -0,0 +1,136 @@
+package xapi
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+)
+
+// Account 代理人在某个系统中的账号
+type Account struct {
+	HomePage string `json:"homePage"`
+	Name     string `json:"name"`
+}
+
+// Agent 代理人或小组
+type Agent struct {
+	ObjectType  string   `json:"objectType,omitempty"`
+	Name        string   `json:"name,omitempty"`
+	Mbox        string   `json:"mbox,omitempty"`
+	MboxSHA1Sum string   `json:"mbox_sha1sum,omitempty"`
+	OpenID      string   `json:"openid,omitempty"`
+	Account     *Account `json:"account,omitempty"`
+	Member      []Agent  `json:"member,omitempty"`
+}
+
+// AccountAgent 本系统用户对应的代理人
+func AccountAgent(username string) Agent {
+	return Agent{ObjectType: ObjectAgent, Account: &Account{HomePage: HomePage, Name: username}}
+}
+
+// ParseAgent 解析查询参数中的代理人JSON
+func ParseAgent(value string) (*Agent, error) {
+	var agent Agent
+	if err := json.Unmarshal([]byte(value), &agent); err != nil {
+		return nil, errors.New("无效的代理人")
+	}
+	if err := agent.Validate(); err != nil {
+		return nil, err
+	}
+	return &agent, nil
+}
+
+// IsGroup 是否为小组
+func (a *Agent) IsGroup() bool {
+	return a.ObjectType == ObjectGroup
+}
+
+// identifiers 代理人设置的反向函数标识 (IFI)
+func (a *Agent) identifiers() []string {
+	var ids []string
+	if a.Mbox != "" {
+		ids = append(ids, "mbox:"+strings.ToLower(a.Mbox))
+	}
+	if a.MboxSHA1Sum != "" {
+		ids = append(ids, "mbox_sha1sum:"+strings.ToLower(a.MboxSHA1Sum))
+	}
+	if a.OpenID != "" {
+		ids = append(ids, "openid:"+a.OpenID)
+	}
+	if a.Account != nil {
+		ids = append(ids, "account:"+a.Account.HomePage+"|"+a.Account.Name)
+	}
+	return ids
+}
+
+// IFI 代理人的唯一标识，匿名小组返回空字符串
+func (a *Agent) IFI() string {
+	if ids := a.identifiers(); len(ids) > 0 {
+		return ids[0]
+	}
+	return ""
+}
+
+// Validate 检查代理人：代理人和有标识的小组只能有一个标识，匿名小组需要成员
+func (a *Agent) Validate() error {
+	switch a.ObjectType {
+	case "", ObjectAgent, ObjectGroup:
+	default:
+		return errors.New("无效的代理人类型: " + a.ObjectType)
+	}
+	ids := a.identifiers()
+	switch {
+	case len(ids) > 1:
+		return errors.New("代理人只能有一个标识")
+	case len(ids) == 0 && !a.IsGroup():
+		return errors.New("代理人缺少标识")
+	case len(ids) == 0 && len(a.Member) == 0:
+		return errors.New("匿名小组需要成员")
+	}
+	if a.Mbox != "" && !strings.HasPrefix(a.Mbox, "mailto:") {
+		return errors.New("mbox必须以mailto:开头")
+	}
+	if a.Account != nil && (a.Account.Name == "" || !IsIRI(a.Account.HomePage)) {
+		return errors.New("无效的账号")
+	}
+	if !a.IsGroup() && len(a.Member) > 0 {
+		return errors.New("只有小组可以有成员")
+	}
+	for i := range a.Member {
+		member := &a.Member[i]
+		if member.IsGroup() {
+			return errors.New("小组成员不能是小组")
+		}
+		if err := member.Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Username 本系统账号的用户名，不是本系统账号时返回空字符串
+func (a *Agent) Username() string {
+	if a.Account != nil && strings.TrimRight(a.Account.HomePage, "/") == HomePage {
+		return a.Account.Name
+	}
+	return ""
+}
+
+// Email 代理人的邮箱，没有mbox时返回空字符串
+func (a *Agent) Email() string {
+	return strings.TrimPrefix(a.Mbox, "mailto:")
+}
+
+// Keys 代理人及小组成员的标识键
+func (a *Agent) Keys() []string {
+	var keys []string
+	if ifi := a.IFI(); ifi != "" {
+		keys = append(keys, Key(ifi))
+	}
+	for i := range a.Member {
+		if ifi := a.Member[i].IFI(); ifi != "" {
+			keys = append(keys, Key(ifi))
+		}
+	}
+	return keys
+}
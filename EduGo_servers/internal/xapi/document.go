@@ -0,0 +1,59 @@
+package xapi
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"mime"
+	"strings"
+)
+
+// ErrNotJSONObject POST合并文档时已有文档或新文档不是JSON对象
+var ErrNotJSONObject = errors.New("只能合并JSON对象")
+
+// ETag 文档内容的实体标签（带引号的SHA-1）
+func ETag(content []byte) string {
+	sum := sha1.Sum(content)
+	return `"` + hex.EncodeToString(sum[:]) + `"`
+}
+
+// IsJSON 检查Content-Type是否为application/json
+func IsJSON(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && mediaType == "application/json"
+}
+
+// Merge 把新文档的顶层属性合并到已有文档，两者都必须是JSON对象
+func Merge(existing, incoming []byte) ([]byte, error) {
+	var current, update map[string]json.RawMessage
+	if err := decodeObject(existing, &current); err != nil {
+		return nil, err
+	}
+	if err := decodeObject(incoming, &update); err != nil {
+		return nil, err
+	}
+	for key, value := range update {
+		current[key] = value
+	}
+	return json.Marshal(current)
+}
+
+func decodeObject(data []byte, value *map[string]json.RawMessage) error {
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 || data[0] != '{' {
+		return ErrNotJSONObject
+	}
+	if err := json.Unmarshal(data, value); err != nil {
+		return ErrNotJSONObject
+	}
+	return nil
+}
+
+// DocumentKey 文档的唯一键：类型、活动、代理人、注册和文档ID的摘要
+func DocumentKey(kind, activityID, agentKey, registration, documentID string) string {
+	sum := sha256.Sum256([]byte(strings.Join([]string{kind, activityID, agentKey, registration, documentID}, "\x00")))
+	return hex.EncodeToString(sum[:])
+}
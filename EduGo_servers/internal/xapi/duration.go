@@ -0,0 +1,33 @@
+package xapi
+
+import (
+	"errors"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+var durationPattern = regexp.MustCompile(`^P(?:(\d+(?:\.\d+)?)Y)?(?:(\d+(?:\.\d+)?)M)?(?:(\d+(?:\.\d+)?)W)?(?:(\d+(?:\.\d+)?)D)?(?:T(?:(\d+(?:\.\d+)?)H)?(?:(\d+(?:\.\d+)?)M)?(?:(\d+(?:\.\d+)?)S)?)?$`)
+
+// durationUnits 各时长单位的秒数，年按365天、月按30天计
+var durationUnits = []float64{365 * 86400, 30 * 86400, 7 * 86400, 86400, 3600, 60, 1}
+
+// ParseDuration 解析ISO 8601时长，例如PT1H30M、P1D、PT12.5S
+func ParseDuration(value string) (time.Duration, error) {
+	match := durationPattern.FindStringSubmatch(value)
+	if match == nil || value == "P" || value[len(value)-1] == 'T' {
+		return 0, errors.New("无效的时长: " + value)
+	}
+	var seconds float64
+	for i, unit := range durationUnits {
+		if match[i+1] == "" {
+			continue
+		}
+		n, err := strconv.ParseFloat(match[i+1], 64)
+		if err != nil {
+			return 0, errors.New("无效的时长: " + value)
+		}
+		seconds += n * unit
+	}
+	return time.Duration(seconds * float64(time.Second)), nil
+}
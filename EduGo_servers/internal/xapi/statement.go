@@ -0,0 +1,359 @@
+package xapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+)
+
+// Statement 校验和规范化后的语句，Body为保存和返回的语句JSON
+type Statement struct {
+	ID           string
+	Actor        Agent
+	VerbID       string
+	ObjectType   string
+	ObjectID     string // 活动ID、引用的语句ID或代理人标识
+	Registration string
+	Timestamp    time.Time
+	Stored       time.Time
+	Result       Result
+
+	DirectActivities  []string // 语句对象的活动键
+	RelatedActivities []string // 语句对象、情境活动和子语句涉及的全部活动键
+	DirectAgents      []string // 执行者和作为对象的代理人的键
+	RelatedAgents     []string // 语句涉及的全部代理人的键，包括授权方、指导者和团队
+
+	Body []byte
+}
+
+// Result 语句结果中进度和分析需要的部分
+type Result struct {
+	Completion *bool
+	Success    *bool
+	Scaled     *float64 // 得分率，-1到1
+	Duration   time.Duration
+}
+
+// IsVoiding 是否为作废其他语句的语句
+func (s *Statement) IsVoiding() bool {
+	return s.VerbID == VerbVoided
+}
+
+// ActorKey 执行者的标识键，匿名小组为空
+func (s *Statement) ActorKey() string {
+	if ifi := s.Actor.IFI(); ifi != "" {
+		return Key(ifi)
+	}
+	return ""
+}
+
+type verb struct {
+	ID      string            `json:"id"`
+	Display map[string]string `json:"display"`
+}
+
+type objectHeader struct {
+	ObjectType string `json:"objectType"`
+	ID         string `json:"id"`
+}
+
+type score struct {
+	Scaled *float64 `json:"scaled"`
+	Raw    *float64 `json:"raw"`
+	Min    *float64 `json:"min"`
+	Max    *float64 `json:"max"`
+}
+
+type result struct {
+	Score      *score `json:"score"`
+	Success    *bool  `json:"success"`
+	Completion *bool  `json:"completion"`
+	Duration   string `json:"duration"`
+}
+
+type statementContext struct {
+	Registration      string                     `json:"registration"`
+	Instructor        *Agent                     `json:"instructor"`
+	Team              *Agent                     `json:"team"`
+	ContextActivities map[string]json.RawMessage `json:"contextActivities"`
+	Statement         *objectHeader              `json:"statement"`
+}
+
+type statementBody struct {
+	ID        string            `json:"id"`
+	Actor     *Agent            `json:"actor"`
+	Verb      *verb             `json:"verb"`
+	Object    json.RawMessage   `json:"object"`
+	Result    *result           `json:"result"`
+	Context   *statementContext `json:"context"`
+	Timestamp string            `json:"timestamp"`
+	Stored    string            `json:"stored"`
+	Authority json.RawMessage   `json:"authority"`
+	Version   string            `json:"version"`
+}
+
+// Prepare 校验一条语句，设置ID、存储时间、授权方和版本，返回规范化的语句
+// id为PUT请求指定的语句ID，语句中的ID必须与之相同；都没有时生成新的ID，没有时间戳时使用存储时间
+func Prepare(data []byte, id string, authority Agent, stored time.Time) (*Statement, error) {
+	var body statementBody
+	if err := json.Unmarshal(data, &body); err != nil {
+		return nil, errors.New("无效的语句JSON")
+	}
+	if id != "" {
+		if body.ID != "" && body.ID != id {
+			return nil, errors.New("语句ID与statementId不一致")
+		}
+		body.ID = id
+	}
+	if body.Version != "" && !IsSupportedVersion(body.Version) {
+		return nil, errors.New("不支持的语句版本: " + body.Version)
+	}
+
+	statement := &Statement{Stored: stored.UTC().Truncate(time.Millisecond)}
+	if err := statement.parse(&body, false); err != nil {
+		return nil, err
+	}
+	if body.ID == "" {
+		generated, err := NewUUID()
+		if err != nil {
+			return nil, err
+		}
+		statement.ID = generated
+	}
+	statement.RelatedAgents = append(statement.RelatedAgents, authority.Keys()...)
+
+	// 保存时保留客户端提交的全部属性
+	var fields map[string]interface{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&fields); err != nil {
+		return nil, errors.New("无效的语句JSON")
+	}
+	fields["id"] = statement.ID
+	fields["stored"] = statement.Stored.Format(time.RFC3339Nano)
+	fields["authority"] = authority
+	if body.Version == "" {
+		fields["version"] = Version
+	}
+	if body.Timestamp == "" {
+		statement.Timestamp = statement.Stored
+		fields["timestamp"] = fields["stored"]
+	}
+	encoded, err := json.Marshal(fields)
+	if err != nil {
+		return nil, err
+	}
+	statement.Body = encoded
+	return statement, nil
+}
+
+// parse 校验语句或子语句的各部分，记录涉及的活动和代理人
+func (s *Statement) parse(body *statementBody, sub bool) error {
+	if body.ID != "" {
+		if sub || !IsUUID(body.ID) {
+			return errors.New("无效的语句ID")
+		}
+		s.ID = strings.ToLower(body.ID)
+	}
+	if sub && (body.Stored != "" || len(body.Authority) > 0 || body.Version != "") {
+		return errors.New("子语句不能包含stored、authority或version")
+	}
+
+	if body.Actor == nil {
+		return errors.New("语句缺少actor")
+	}
+	if err := body.Actor.Validate(); err != nil {
+		return err
+	}
+	if body.Verb == nil || !IsIRI(body.Verb.ID) {
+		return errors.New("语句缺少有效的verb.id")
+	}
+	if len(body.Object) == 0 {
+		return errors.New("语句缺少object")
+	}
+	if body.Timestamp != "" {
+		timestamp, err := time.Parse(time.RFC3339Nano, body.Timestamp)
+		if err != nil {
+			return errors.New("无效的timestamp")
+		}
+		if !sub {
+			s.Timestamp = timestamp.UTC()
+		}
+	}
+
+	actorKeys := body.Actor.Keys()
+	objectType, objectID, err := s.parseObject(body.Object, sub)
+	if err != nil {
+		return err
+	}
+	if body.Verb.ID == VerbVoided && objectType != ObjectStatementRef {
+		return errors.New("作废语句的对象必须是StatementRef")
+	}
+	if body.Result != nil {
+		if err := s.parseResult(body.Result, sub); err != nil {
+			return err
+		}
+	}
+	if body.Context != nil {
+		if err := s.parseContext(body.Context, sub); err != nil {
+			return err
+		}
+	}
+
+	if sub {
+		s.RelatedAgents = append(s.RelatedAgents, actorKeys...)
+		return nil
+	}
+	s.Actor = *body.Actor
+	s.VerbID = body.Verb.ID
+	s.ObjectType = objectType
+	s.ObjectID = objectID
+	s.DirectAgents = append(s.DirectAgents, actorKeys...)
+	s.RelatedAgents = append(s.RelatedAgents, actorKeys...)
+	return nil
+}
+
+// parseObject 校验语句对象，返回对象类型和ID
+func (s *Statement) parseObject(data json.RawMessage, sub bool) (string, string, error) {
+	var header objectHeader
+	if err := json.Unmarshal(data, &header); err != nil {
+		return "", "", errors.New("无效的object")
+	}
+	if header.ObjectType == "" {
+		header.ObjectType = ObjectActivity
+	}
+
+	switch header.ObjectType {
+	case ObjectActivity:
+		if !IsIRI(header.ID) {
+			return "", "", errors.New("活动缺少有效的id")
+		}
+		key := Key(header.ID)
+		if !sub {
+			s.DirectActivities = append(s.DirectActivities, key)
+		}
+		s.RelatedActivities = append(s.RelatedActivities, key)
+		return header.ObjectType, header.ID, nil
+
+	case ObjectAgent, ObjectGroup:
+		var agent Agent
+		if err := json.Unmarshal(data, &agent); err != nil {
+			return "", "", errors.New("无效的object")
+		}
+		if err := agent.Validate(); err != nil {
+			return "", "", err
+		}
+		keys := agent.Keys()
+		if !sub {
+			s.DirectAgents = append(s.DirectAgents, keys...)
+		}
+		s.RelatedAgents = append(s.RelatedAgents, keys...)
+		return header.ObjectType, agent.IFI(), nil
+
+	case ObjectStatementRef:
+		if !IsUUID(header.ID) {
+			return "", "", errors.New("StatementRef缺少有效的id")
+		}
+		return header.ObjectType, header.ID, nil
+
+	case ObjectSubStatement:
+		if sub {
+			return "", "", errors.New("子语句不能嵌套子语句")
+		}
+		var body statementBody
+		if err := json.Unmarshal(data, &body); err != nil {
+			return "", "", errors.New("无效的子语句")
+		}
+		if err := s.parse(&body, true); err != nil {
+			return "", "", err
+		}
+		return header.ObjectType, "", nil
+	}
+	return "", "", errors.New("无效的对象类型: " + header.ObjectType)
+}
+
+// parseResult 校验语句结果，子语句的结果不计入
+func (s *Statement) parseResult(r *result, sub bool) error {
+	var duration time.Duration
+	if r.Duration != "" {
+		var err error
+		if duration, err = ParseDuration(r.Duration); err != nil {
+			return err
+		}
+	}
+	if r.Score != nil {
+		if r.Score.Scaled != nil && (*r.Score.Scaled < -1 || *r.Score.Scaled > 1) {
+			return errors.New("score.scaled必须在-1到1之间")
+		}
+		if r.Score.Min != nil && r.Score.Max != nil && *r.Score.Min >= *r.Score.Max {
+			return errors.New("score.min必须小于score.max")
+		}
+		if r.Score.Raw != nil && ((r.Score.Min != nil && *r.Score.Raw < *r.Score.Min) ||
+			(r.Score.Max != nil && *r.Score.Raw > *r.Score.Max)) {
+			return errors.New("score.raw必须在min和max之间")
+		}
+	}
+	if sub {
+		return nil
+	}
+	s.Result = Result{Completion: r.Completion, Success: r.Success, Duration: duration}
+	if r.Score != nil {
+		s.Result.Scaled = r.Score.Scaled
+	}
+	return nil
+}
+
+// parseContext 校验语句情境，记录情境活动、指导者和团队，子语句的注册不计入
+func (s *Statement) parseContext(ctx *statementContext, sub bool) error {
+	if ctx.Registration != "" {
+		if !IsUUID(ctx.Registration) {
+			return errors.New("无效的registration")
+		}
+		if !sub {
+			s.Registration = ctx.Registration
+		}
+	}
+	for _, agent := range []*Agent{ctx.Instructor, ctx.Team} {
+		if agent == nil {
+			continue
+		}
+		if err := agent.Validate(); err != nil {
+			return err
+		}
+		s.RelatedAgents = append(s.RelatedAgents, agent.Keys()...)
+	}
+	if ctx.Team != nil && !ctx.Team.IsGroup() {
+		return errors.New("context.team必须是小组")
+	}
+	if ctx.Statement != nil && (ctx.Statement.ObjectType != ObjectStatementRef || !IsUUID(ctx.Statement.ID)) {
+		return errors.New("context.statement必须是StatementRef")
+	}
+
+	for kind, data := range ctx.ContextActivities {
+		switch kind {
+		case "parent", "grouping", "category", "other":
+		default:
+			return errors.New("无效的情境活动类型: " + kind)
+		}
+		// 单个活动或活动数组
+		var activities []objectHeader
+		if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '{' {
+			var activity objectHeader
+			if err := json.Unmarshal(trimmed, &activity); err != nil {
+				return errors.New("无效的情境活动")
+			}
+			activities = append(activities, activity)
+		} else if err := json.Unmarshal(data, &activities); err != nil {
+			return errors.New("无效的情境活动")
+		}
+		for _, activity := range activities {
+			if (activity.ObjectType != "" && activity.ObjectType != ObjectActivity) || !IsIRI(activity.ID) {
+				return errors.New("情境活动缺少有效的id")
+			}
+			s.RelatedActivities = append(s.RelatedActivities, Key(activity.ID))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,121 @@
+// Package xapi 实现学习记录存储 (LRS) 需要的 Experience API (xAPI) 1.0.3 规范：
+// 语句的校验和规范化、代理人的标识、ISO 8601 时长和状态、活动档案文档的合并。
+//
+// 账号主页为 HomePage 的代理人对应同名的 EduGo 用户，学习资源的活动ID为 HomePage/resources/{id}。
+package xapi
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Version 存储的语句和响应头使用的xAPI版本
+const Version = "1.0.3"
+
+// DefaultHomePage 未配置时本系统账号的主页
+const DefaultHomePage = "https://edugo.local"
+
+// HomePage 本系统账号的主页
+var HomePage = DefaultHomePage
+
+// 常用的ADL动词
+const (
+	VerbVoided      = "http://adlnet.gov/expapi/verbs/voided"
+	VerbExperienced = "http://adlnet.gov/expapi/verbs/experienced"
+	VerbAttempted   = "http://adlnet.gov/expapi/verbs/attempted"
+	VerbCompleted   = "http://adlnet.gov/expapi/verbs/completed"
+	VerbPassed      = "http://adlnet.gov/expapi/verbs/passed"
+	VerbFailed      = "http://adlnet.gov/expapi/verbs/failed"
+)
+
+// 语句对象的类型
+const (
+	ObjectActivity     = "Activity"
+	ObjectAgent        = "Agent"
+	ObjectGroup        = "Group"
+	ObjectStatementRef = "StatementRef"
+	ObjectSubStatement = "SubStatement"
+)
+
+// maxKeyLength 索引列保存的标识的最大长度，更长的标识保存摘要
+const maxKeyLength = 191
+
+// InitHomePage 设置本系统账号的主页，为空时使用默认主页
+func InitHomePage(homePage string) error {
+	if homePage == "" {
+		homePage = DefaultHomePage
+	}
+	if !IsIRI(homePage) {
+		return fmt.Errorf("无效的xAPI账号主页: %s", homePage)
+	}
+	HomePage = strings.TrimRight(homePage, "/")
+	return nil
+}
+
+// IsSupportedVersion 检查请求头中的xAPI版本，支持1.0.x
+func IsSupportedVersion(version string) bool {
+	if version == "1.0" {
+		return true
+	}
+	patch, ok := strings.CutPrefix(version, "1.0.")
+	if !ok {
+		return false
+	}
+	_, err := strconv.Atoi(patch)
+	return err == nil
+}
+
+// IsIRI 检查是否为带协议的绝对IRI
+func IsIRI(value string) bool {
+	u, err := url.Parse(value)
+	return err == nil && u.Scheme != "" && (u.Host != "" || u.Opaque != "")
+}
+
+var uuidPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// IsUUID 检查是否为UUID
+func IsUUID(value string) bool {
+	return uuidPattern.MatchString(value)
+}
+
+// NewUUID 生成随机的版本4 UUID
+func NewUUID() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	buf[6] = buf[6]&0x0f | 0x40
+	buf[8] = buf[8]&0x3f | 0x80
+	h := hex.EncodeToString(buf)
+	return h[0:8] + "-" + h[8:12] + "-" + h[12:16] + "-" + h[16:20] + "-" + h[20:], nil
+}
+
+// Key 把IRI或代理人标识转换为可以建索引的键，过长时使用摘要
+func Key(value string) string {
+	if len(value) <= maxKeyLength {
+		return value
+	}
+	sum := sha256.Sum256([]byte(value))
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// ResourceActivityID 学习资源的活动ID
+func ResourceActivityID(resourceID int64) string {
+	return HomePage + "/resources/" + strconv.FormatInt(resourceID, 10)
+}
+
+// ParseResourceActivityID 解析学习资源的活动ID，不是本系统的学习资源时返回false
+func ParseResourceActivityID(activityID string) (int64, bool) {
+	value, ok := strings.CutPrefix(activityID, HomePage+"/resources/")
+	if !ok {
+		return 0, false
+	}
+	id, err := strconv.ParseInt(value, 10, 64)
+	return id, err == nil && id > 0
+}
@@ -3,9 +3,16 @@ package main
 import (
 	"EduGo_servers/internal/controllers"
 	"EduGo_servers/internal/database"
+	"EduGo_servers/internal/jobs"
+	"EduGo_servers/internal/lti"
 	"EduGo_servers/internal/middleware"
+	"EduGo_servers/internal/recommend"
+	"EduGo_servers/internal/storage"
+	"EduGo_servers/internal/xapi"
 	"log"
 	"os"
+	"strconv"
+	"time"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
@@ -31,14 +38,61 @@ func main() {
 		log.Fatalf("Failed to connect to database: %v", dbErr)
 	}
 
+	// 作业附件和提交文件的存储，默认保存在本地磁盘
+	uploadDir := os.Getenv("STORAGE_DIR")
+	if uploadDir == "" {
+		uploadDir = "data/uploads"
+	}
+	if err := storage.InitStorage(os.Getenv("STORAGE_BACKEND"), uploadDir); err != nil {
+		log.Fatalf("Failed to init storage: %v", err)
+	}
+
+	// 学习推荐的排序策略，默认按各项信号加权
+	if err := recommend.InitStrategy(os.Getenv("RECOMMEND_STRATEGY")); err != nil {
+		log.Fatalf("Failed to init recommendation strategy: %v", err)
+	}
+
+	// 学习记录存储中本系统账号和活动的主页地址
+	if err := xapi.InitHomePage(os.Getenv("XAPI_HOMEPAGE")); err != nil {
+		log.Fatalf("Failed to init xAPI home page: %v", err)
+	}
+
+	// LTI 1.3的签发方（本服务的外部地址）和作为工具启动后跳转的前端地址
+	if err := lti.Init(os.Getenv("LTI_ISSUER"), os.Getenv("LTI_FRONTEND_URL")); err != nil {
+		log.Fatalf("Failed to init LTI: %v", err)
+	}
+
+	// 定时清理超过保留期的已删除用户，保留天数默认为30天
+	retentionDays := 30
+	if days, err := strconv.Atoi(os.Getenv("USER_PURGE_RETENTION_DAYS")); err == nil && days > 0 {
+		retentionDays = days
+	}
+	jobs.StartUserPurgeJob(database.DB, time.Hour, time.Duration(retentionDays)*24*time.Hour)
+
+	// 个人数据导出任务，导出文件保留7天
+	exportDir := os.Getenv("DATA_EXPORT_DIR")
+	if exportDir == "" {
+		exportDir = "data/exports"
+	}
+	jobs.StartDataExportWorker(database.DB, exportDir, 7*24*time.Hour)
+
+	// 成绩单批量生成任务
+	jobs.StartReportCardWorker(database.DB)
+
+	// 每天根据历史作答标定题目参数，每道题至少需要30次作答
+	jobs.StartItemCalibrationJob(database.DB, 24*time.Hour, 30)
+
+	// 根据测评结果更新学生的知识点掌握度
+	jobs.StartMasteryWorker(database.DB)
+
 	r := gin.Default()
 
 	// 配置CORS
 	r.Use(cors.New(cors.Config{
 		AllowOrigins:     []string{"*"},
 		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization"},
-		ExposeHeaders:    []string{"Content-Length"},
+		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization", "X-Experience-API-Version", "If-Match", "If-None-Match"},
+		ExposeHeaders:    []string{"Content-Length", "X-Experience-API-Version", "X-Experience-API-Consistent-Through", "ETag", "Last-Modified"},
 		AllowCredentials: true,
 	}))
 
@@ -48,6 +102,43 @@ func main() {
 		// 公开路由
 		v1.POST("/register", controllers.Register)
 		v1.POST("/login", controllers.Login)
+		v1.GET("/invitations/verify", controllers.VerifyInvitation)
+		v1.GET("/calendar/:token", controllers.ExportCalendarFeed)
+		v1.GET("/public/resources", controllers.GetPublicResources)
+		v1.GET("/public/resources/:id", controllers.GetPublicResource)
+		v1.GET("/public/resources/:id/file", controllers.DownloadPublicResourceFile)
+		v1.GET("/xapi/about", controllers.GetXAPIAbout)
+
+		// 学习记录存储 (xAPI)，支持xAPI凭据的基本认证和登录令牌
+		lrs := v1.Group("/xapi", middleware.XAPIVersion(), middleware.XAPIAuth(controllers.AuthenticateXAPICredential))
+		{
+			lrs.PUT("/statements", controllers.PutXAPIStatement)
+			lrs.POST("/statements", controllers.PostXAPIStatements)
+			lrs.GET("/statements", controllers.GetXAPIStatements)
+			lrs.PUT("/activities/state", controllers.PutXAPIState)
+			lrs.POST("/activities/state", controllers.PostXAPIState)
+			lrs.GET("/activities/state", controllers.GetXAPIState)
+			lrs.DELETE("/activities/state", controllers.DeleteXAPIState)
+			lrs.PUT("/activities/profile", controllers.PutXAPIActivityProfile)
+			lrs.POST("/activities/profile", controllers.PostXAPIActivityProfile)
+			lrs.GET("/activities/profile", controllers.GetXAPIActivityProfile)
+			lrs.DELETE("/activities/profile", controllers.DeleteXAPIActivityProfile)
+		}
+
+		// LTI 1.3，由外部工具、平台和浏览器表单直接访问
+		v1.GET("/lti/config", controllers.GetLTIConfig)
+		v1.GET("/lti/jwks", controllers.GetLTIKeySet)
+		v1.GET("/lti/authorize", controllers.AuthorizeLTILaunch)
+		v1.POST("/lti/authorize", controllers.AuthorizeLTILaunch)
+		v1.POST("/lti/deep-links/return", controllers.ReturnLTIDeepLinks)
+		v1.POST("/lti/token", controllers.IssueLTIAccessToken)
+		v1.GET("/lti/login", controllers.InitiateLTILogin)
+		v1.POST("/lti/login", controllers.InitiateLTILogin)
+		v1.POST("/lti/launch", controllers.HandleLTILaunch)
+		v1.GET("/lti/ags/offerings/:offering_id/lineitems", controllers.GetLTILineItems)
+		v1.GET("/lti/ags/offerings/:offering_id/lineitems/:item_id", controllers.GetLTILineItem)
+		v1.POST("/lti/ags/offerings/:offering_id/lineitems/:item_id/scores", controllers.PostLTIScore)
+		v1.GET("/lti/ags/offerings/:offering_id/lineitems/:item_id/results", controllers.GetLTIResults)
 
 		// 需要认证的路由
 		auth := v1.Group("/")
@@ -60,6 +151,243 @@ func main() {
 			auth.POST("/logout", controllers.Logout)
 			auth.POST("/refresh", controllers.RefreshToken)
 
+			// 邀请
+			auth.POST("/invitations", controllers.CreateInvitation)
+			auth.GET("/invitations", controllers.GetMyInvitations)
+			auth.DELETE("/invitations/:id", controllers.RevokeInvitation)
+
+			// 个人数据导出与删除
+			auth.POST("/data-exports", controllers.RequestDataExport)
+			auth.GET("/data-exports", controllers.GetMyDataExports)
+			auth.GET("/data-exports/:id/download", controllers.DownloadDataExport)
+			auth.POST("/erasure-requests", controllers.RequestErasure)
+
+			// 课程
+			courses := auth.Group("/courses")
+			{
+				courses.GET("", controllers.GetCourses)
+				courses.GET("/:id", controllers.GetCourse)
+				courses.POST("", middleware.TeacherOnly(), controllers.CreateCourse)
+				courses.PUT("/:id", middleware.TeacherOnly(), controllers.UpdateCourse)
+				courses.DELETE("/:id", middleware.AdminOnly(), controllers.DeleteCourse)
+				courses.GET("/:id/offerings", controllers.GetCourseOfferings)
+				courses.GET("/:id/resources", controllers.GetCourseResources)
+				courses.GET("/:id/units", controllers.GetCourseUnits)
+				courses.POST("/:id/units", middleware.TeacherOnly(), controllers.CreateCourseUnit)
+				courses.PUT("/:id/units/:unit_id", middleware.TeacherOnly(), controllers.UpdateCourseUnit)
+				courses.DELETE("/:id/units/:unit_id", middleware.TeacherOnly(), controllers.DeleteCourseUnit)
+				courses.POST("/:id/units/:unit_id/lessons", middleware.TeacherOnly(), controllers.CreateLesson)
+				courses.PUT("/:id/lessons/:lesson_id", middleware.TeacherOnly(), controllers.UpdateLesson)
+				courses.DELETE("/:id/lessons/:lesson_id", middleware.TeacherOnly(), controllers.DeleteLesson)
+				courses.POST("/:id/offerings", middleware.TeacherOnly(), controllers.CreateCourseOffering)
+				courses.DELETE("/:id/offerings/:offering_id", middleware.TeacherOnly(), controllers.DeleteCourseOffering)
+				courses.PUT("/:id/offerings/:offering_id/enrollment", middleware.TeacherOnly(), controllers.UpdateOfferingEnrollment)
+				courses.GET("/:id/offerings/:offering_id/enrollments", middleware.TeacherOnly(), controllers.GetOfferingEnrollments)
+				courses.DELETE("/:id/offerings/:offering_id/enrollments/:enrollment_id", middleware.TeacherOnly(), controllers.RemoveEnrollment)
+				courses.GET("/:id/offerings/:offering_id/attendance", middleware.TeacherOnly(), controllers.GetOfferingAttendanceStats)
+				courses.GET("/:id/offerings/:offering_id/assignments", controllers.GetOfferingAssignments)
+				courses.POST("/:id/offerings/:offering_id/assignments", middleware.TeacherOnly(), controllers.CreateAssignment)
+				courses.GET("/:id/offerings/:offering_id/assessments", controllers.GetOfferingAssessments)
+				courses.POST("/:id/offerings/:offering_id/assessments", middleware.TeacherOnly(), controllers.CreateAssessment)
+				courses.GET("/:id/offerings/:offering_id/mastery", middleware.TeacherOnly(), controllers.GetOfferingMastery)
+				courses.GET("/:id/offerings/:offering_id/gradebook", middleware.TeacherOnly(), controllers.GetGradebook)
+				courses.GET("/:id/offerings/:offering_id/gradebook/me", controllers.GetMyGradebook)
+				courses.PUT("/:id/offerings/:offering_id/gradebook/scale", middleware.TeacherOnly(), controllers.SetGradebookScale)
+				courses.POST("/:id/offerings/:offering_id/gradebook/categories", middleware.TeacherOnly(), controllers.CreateGradeCategory)
+				courses.PUT("/:id/offerings/:offering_id/gradebook/categories/:category_id", middleware.TeacherOnly(), controllers.UpdateGradeCategory)
+				courses.DELETE("/:id/offerings/:offering_id/gradebook/categories/:category_id", middleware.TeacherOnly(), controllers.DeleteGradeCategory)
+				courses.POST("/:id/offerings/:offering_id/gradebook/items", middleware.TeacherOnly(), controllers.CreateGradeItem)
+				courses.PUT("/:id/offerings/:offering_id/gradebook/items/:item_id", middleware.TeacherOnly(), controllers.UpdateGradeItem)
+				courses.DELETE("/:id/offerings/:offering_id/gradebook/items/:item_id", middleware.TeacherOnly(), controllers.DeleteGradeItem)
+				courses.PUT("/:id/offerings/:offering_id/gradebook/items/:item_id/scores", middleware.TeacherOnly(), controllers.SaveGradeScores)
+				courses.POST("/:id/offerings/:offering_id/gradebook/term-grades", middleware.TeacherOnly(), controllers.ComputeTermGrades)
+				courses.GET("/:id/offerings/:offering_id/gradebook/term-grades", middleware.TeacherOnly(), controllers.GetOfferingTermGrades)
+				courses.GET("/:id/offerings/:offering_id/lti-links", controllers.GetOfferingLTILinks)
+				courses.POST("/:id/offerings/:offering_id/lti-links", middleware.TeacherOnly(), controllers.CreateOfferingLTILink)
+				courses.POST("/:id/offerings/:offering_id/lti-links/deep-link", middleware.TeacherOnly(), controllers.StartLTIDeepLinking)
+				courses.PUT("/:id/offerings/:offering_id/lti-links/:link_id", middleware.TeacherOnly(), controllers.UpdateOfferingLTILink)
+				courses.DELETE("/:id/offerings/:offering_id/lti-links/:link_id", middleware.TeacherOnly(), controllers.DeleteOfferingLTILink)
+			}
+
+			// 学期
+			terms := auth.Group("/terms")
+			{
+				terms.GET("", controllers.GetTerms)
+				terms.GET("/current", controllers.GetCurrentTerm)
+				terms.GET("/:id", controllers.GetTerm)
+				terms.GET("/:id/offerings", controllers.GetTermOfferings)
+			}
+
+			// 课表
+			auth.GET("/periods", controllers.GetPeriods)
+			auth.GET("/rooms", controllers.GetRooms)
+			auth.GET("/timetable/me", controllers.GetMyTimetable)
+			auth.GET("/timetable", middleware.TeacherOnly(), controllers.GetTimetable)
+			auth.GET("/timetable/feed", controllers.GetCalendarFeed)
+			auth.POST("/timetable/feed/regenerate", controllers.RegenerateCalendarFeed)
+
+			// 考勤
+			auth.GET("/attendance/records", controllers.GetStudentAttendance)
+			auth.GET("/attendance/summary", controllers.GetAttendanceSummary)
+
+			// 作业
+			assignments := auth.Group("/assignments")
+			{
+				assignments.GET("/:id", controllers.GetAssignment)
+				assignments.PUT("/:id", middleware.TeacherOnly(), controllers.UpdateAssignment)
+				assignments.DELETE("/:id", middleware.TeacherOnly(), controllers.DeleteAssignment)
+				assignments.PUT("/:id/knowledge-points", middleware.TeacherOnly(), controllers.SetAssignmentKnowledgePoints)
+				assignments.POST("/:id/attachments", middleware.TeacherOnly(), controllers.UploadAssignmentAttachment)
+				assignments.DELETE("/:id/attachments/:attachment_id", middleware.TeacherOnly(), controllers.DeleteAssignmentAttachment)
+				assignments.GET("/:id/attachments/:attachment_id/download", controllers.DownloadAssignmentAttachment)
+				assignments.POST("/:id/submissions", controllers.SubmitAssignment)
+				assignments.GET("/:id/submissions", middleware.TeacherOnly(), controllers.GetAssignmentSubmissions)
+				assignments.GET("/:id/submissions/me", controllers.GetMySubmissions)
+				assignments.GET("/:id/submissions/students/:student_id", controllers.GetStudentSubmissions)
+			}
+			auth.GET("/submissions/files/:id/download", controllers.DownloadSubmissionFile)
+
+			// 题库
+			questions := auth.Group("/questions")
+			questions.Use(middleware.TeacherOnly())
+			{
+				questions.POST("", controllers.CreateQuestion)
+				questions.GET("", controllers.GetQuestions)
+				questions.GET("/knowledge-points", controllers.GetQuestionKnowledgePoints)
+				questions.POST("/import", controllers.ImportQuestions)
+				questions.GET("/import-template", controllers.GetQuestionImportTemplate)
+				questions.GET("/export", controllers.ExportQuestions)
+				questions.GET("/:id", controllers.GetQuestion)
+				questions.PUT("/:id", controllers.UpdateQuestion)
+				questions.DELETE("/:id", controllers.DeleteQuestion)
+			}
+
+			// 知识点
+			knowledgePoints := auth.Group("/knowledge-points")
+			{
+				knowledgePoints.GET("", controllers.GetKnowledgePoints)
+				knowledgePoints.GET("/subjects", controllers.GetKnowledgePointSubjects)
+				knowledgePoints.POST("", middleware.TeacherOnly(), controllers.CreateKnowledgePoint)
+				knowledgePoints.PUT("/:id", middleware.TeacherOnly(), controllers.UpdateKnowledgePoint)
+				knowledgePoints.DELETE("/:id", middleware.TeacherOnly(), controllers.DeleteKnowledgePoint)
+			}
+
+			// 知识点掌握度
+			auth.GET("/mastery/students/:id", controllers.GetStudentMastery)
+			auth.GET("/mastery/students/:id/knowledge-points/:knowledge_point_id", controllers.GetStudentKnowledgePointMastery)
+
+			// 错题本
+			mistakes := auth.Group("/mistakes")
+			{
+				mistakes.GET("", controllers.GetMistakes)
+				mistakes.DELETE("/:id", controllers.DeleteMistake)
+				mistakes.POST("/practices", controllers.CreateMistakePractice)
+				mistakes.GET("/practices/:id", controllers.GetMistakePractice)
+				mistakes.POST("/practices/:id/answers", controllers.AnswerMistakePractice)
+			}
+
+			// 间隔复习
+			reviews := auth.Group("/reviews")
+			{
+				reviews.GET("/today", controllers.GetTodayReviews)
+				reviews.GET("/students/:id", controllers.GetStudentReviews)
+				reviews.GET("/items/:id", controllers.GetReviewItem)
+				reviews.POST("/items/:id/answers", controllers.AnswerReview)
+				reviews.POST("/assignments", middleware.TeacherOnly(), controllers.AssignReviews)
+			}
+
+			// 学习推荐
+			recommendations := auth.Group("/recommendations")
+			{
+				recommendations.GET("/students/:id", controllers.GetStudentRecommendations)
+				recommendations.GET("/students/:id/overrides", controllers.GetStudentRecommendationOverrides)
+				recommendations.POST("/feedback", controllers.SubmitRecommendationFeedback)
+				recommendations.POST("/questions/:id/review", controllers.AddRecommendedQuestion)
+				recommendations.POST("/overrides", middleware.TeacherOnly(), controllers.CreateRecommendationOverrides)
+				recommendations.DELETE("/overrides/:id", middleware.TeacherOnly(), controllers.DeleteRecommendationOverride)
+			}
+
+			// 学习记录存储的凭据
+			xapiCredentials := auth.Group("/xapi/credentials")
+			{
+				xapiCredentials.GET("", controllers.GetXAPICredentials)
+				xapiCredentials.POST("", controllers.CreateXAPICredential)
+				xapiCredentials.DELETE("/:id", controllers.DeleteXAPICredential)
+			}
+
+			// 外部工具 (LTI)
+			ltiGroup := auth.Group("/lti")
+			{
+				ltiGroup.GET("/tools", middleware.TeacherOnly(), controllers.GetLTITools)
+				ltiGroup.POST("/links/:id/launch", controllers.LaunchLTILink)
+			}
+
+			// 学习进度
+			progress := auth.Group("/progress")
+			{
+				progress.GET("/students/:id", controllers.GetStudentProgress)
+			}
+
+			// 学习资源库
+			resources := auth.Group("/resources")
+			{
+				resources.GET("", controllers.GetResources)
+				resources.POST("", middleware.TeacherOnly(), controllers.CreateResource)
+				resources.GET("/:id", controllers.GetResource)
+				resources.PUT("/:id", controllers.UpdateResource)
+				resources.DELETE("/:id", controllers.DeleteResource)
+				resources.POST("/:id/file", controllers.UploadResourceFile)
+				resources.GET("/:id/file", controllers.DownloadResourceFile)
+				resources.POST("/:id/views", controllers.RecordResourceView)
+			}
+
+			// 测评
+			assessments := auth.Group("/assessments")
+			{
+				assessments.GET("/:id", controllers.GetAssessment)
+				assessments.PUT("/:id", middleware.TeacherOnly(), controllers.UpdateAssessment)
+				assessments.DELETE("/:id", middleware.TeacherOnly(), controllers.DeleteAssessment)
+				assessments.PUT("/:id/questions", middleware.TeacherOnly(), controllers.SetAssessmentQuestions)
+				assessments.POST("/:id/attempts", controllers.StartAssessmentAttempt)
+				assessments.GET("/:id/attempts", middleware.TeacherOnly(), controllers.GetAssessmentAttempts)
+				assessments.GET("/:id/attempts/me", controllers.GetMyAssessmentAttempts)
+			}
+			attempts := auth.Group("/assessment-attempts")
+			{
+				attempts.GET("/:id", controllers.GetAssessmentAttempt)
+				attempts.PUT("/:id/responses", controllers.SaveAttemptResponses)
+				attempts.POST("/:id/answer", controllers.AnswerAdaptiveQuestion)
+				attempts.POST("/:id/submit", controllers.SubmitAssessmentAttempt)
+				attempts.PUT("/:id/answers/:question_id", middleware.TeacherOnly(), controllers.GradeAttemptAnswer)
+			}
+
+			// 成绩
+			auth.GET("/grading-scales", controllers.GetGradingScales)
+			auth.GET("/grades", controllers.GetStudentTermGrades)
+
+			// 成绩单
+			auth.GET("/report-cards", controllers.GetReportCard)
+			auth.GET("/report-cards/comments", controllers.GetReportCardComments)
+			auth.PUT("/report-cards/comments", middleware.TeacherOnly(), controllers.SaveReportCardComment)
+			auth.GET("/report-cards/templates", middleware.TeacherOnly(), controllers.GetReportCardTemplates)
+			auth.POST("/report-cards/batches", middleware.TeacherOnly(), controllers.CreateReportCardBatch)
+			auth.GET("/report-cards/batches/:id", middleware.TeacherOnly(), controllers.GetReportCardBatch)
+			auth.GET("/report-cards/batches/:id/download", middleware.TeacherOnly(), controllers.DownloadReportCardBatch)
+
+			// 通知
+			auth.GET("/notifications", controllers.GetNotifications)
+			auth.POST("/notifications/:id/read", controllers.MarkNotificationRead)
+			auth.POST("/notifications/read-all", controllers.MarkAllNotificationsRead)
+
+			// 班级
+			classes := auth.Group("/classes")
+			classes.Use(middleware.TeacherOnly())
+			{
+				classes.GET("/:id", controllers.GetClass)
+				classes.GET("/:id/students", controllers.GetClassStudents)
+				classes.GET("/:id/progress", controllers.GetClassProgress)
+			}
+
 			// 超级管理员路由
 			superAdmin := auth.Group("/super-admin")
 			superAdmin.Use(middleware.SuperAdminOnly())
@@ -72,13 +400,75 @@ func main() {
 			admin := auth.Group("/admin")
 			admin.Use(middleware.AdminOnly())
 			{
+				admin.POST("/users", controllers.CreateUser)
+				admin.GET("/users/deleted", controllers.GetDeletedUsers)
 				admin.GET("/users/role/:role", controllers.GetUsersByRole)
 				admin.GET("/users/:id", controllers.GetUserByID)
 				admin.PUT("/users/:id/status", controllers.UpdateUserStatus)
+				admin.DELETE("/users/:id", controllers.DeleteUser)
+				admin.POST("/users/:id/restore", controllers.RestoreUser)
 				
 				// 管理员-教师关系
 				admin.POST("/relations/teacher", controllers.CreateAdminTeacherRelation)
 				admin.GET("/relations/teachers", controllers.GetTeachersByAdmin)
+
+				// 数据删除申请审核
+				admin.GET("/erasure-requests", controllers.GetErasureRequests)
+				admin.POST("/erasure-requests/:id/approve", controllers.ApproveErasureRequest)
+				admin.POST("/erasure-requests/:id/reject", controllers.RejectErasureRequest)
+
+				// 学期管理
+				admin.POST("/terms", controllers.CreateTerm)
+				admin.PUT("/terms/:id", controllers.UpdateTerm)
+				admin.DELETE("/terms/:id", controllers.DeleteTerm)
+				admin.POST("/terms/:id/current", controllers.SetCurrentTerm)
+				admin.POST("/terms/:id/rollover", controllers.RolloverTerm)
+
+				// 班级管理
+				admin.POST("/classes", controllers.CreateClass)
+				admin.GET("/classes", controllers.GetClasses)
+				admin.PUT("/classes/:id", controllers.UpdateClass)
+				admin.DELETE("/classes/:id", controllers.DeleteClass)
+				admin.POST("/classes/:id/students", controllers.AddClassStudents)
+				admin.DELETE("/classes/:id/students/:student_id", controllers.RemoveClassStudent)
+				admin.PUT("/classes/:id/subject-teachers", controllers.SetClassSubjectTeacher)
+				admin.DELETE("/classes/:id/subject-teachers/:assignment_id", controllers.RemoveClassSubjectTeacher)
+
+				// 课节、教室与排课
+				admin.POST("/periods", controllers.CreatePeriod)
+				admin.PUT("/periods/:id", controllers.UpdatePeriod)
+				admin.DELETE("/periods/:id", controllers.DeletePeriod)
+				admin.POST("/rooms", controllers.CreateRoom)
+				admin.PUT("/rooms/:id", controllers.UpdateRoom)
+				admin.DELETE("/rooms/:id", controllers.DeleteRoom)
+				admin.POST("/timetable/sessions", controllers.CreateSession)
+				admin.DELETE("/timetable/sessions/:id", controllers.DeleteSession)
+
+				// 评分制
+				admin.POST("/grading-scales", controllers.CreateGradingScale)
+				admin.DELETE("/grading-scales/:id", controllers.DeleteGradingScale)
+
+				// 成绩单模板
+				admin.POST("/report-card-templates", controllers.CreateReportCardTemplate)
+				admin.PUT("/report-card-templates/:id", controllers.UpdateReportCardTemplate)
+				admin.DELETE("/report-card-templates/:id", controllers.DeleteReportCardTemplate)
+
+				// 题目参数标定
+				admin.POST("/item-calibration", controllers.CalibrateItems)
+
+				// 知识点掌握度重新计算
+				admin.POST("/mastery/rebuild", controllers.RebuildMastery)
+
+				// LTI外部工具和外部平台
+				admin.POST("/lti/tools", controllers.CreateLTITool)
+				admin.PUT("/lti/tools/:id", controllers.UpdateLTITool)
+				admin.DELETE("/lti/tools/:id", controllers.DeleteLTITool)
+				admin.GET("/lti/platforms", controllers.GetLTIPlatforms)
+				admin.POST("/lti/platforms", controllers.CreateLTIPlatform)
+				admin.PUT("/lti/platforms/:id", controllers.UpdateLTIPlatform)
+				admin.DELETE("/lti/platforms/:id", controllers.DeleteLTIPlatform)
+				admin.GET("/lti/platforms/:id/contexts", controllers.GetLTIPlatformContexts)
+				admin.PUT("/lti/contexts/:id", controllers.UpdateLTIContext)
 			}
 			
 			// 教师路由
@@ -88,6 +478,22 @@ func main() {
 				// 教师-学生关系
 				teacher.POST("/relations/student", controllers.CreateTeacherStudentRelation)
 				teacher.GET("/relations/students", controllers.GetStudentsByTeacher)
+				teacher.GET("/relations/pending", controllers.GetPendingStudentRelations)
+				teacher.POST("/relations/:id/approve", controllers.ApproveStudentRelation)
+				teacher.POST("/relations/:id/reject", controllers.RejectStudentRelation)
+
+				// 班级加入码
+				teacher.POST("/join-codes", controllers.CreateJoinCode)
+				teacher.GET("/join-codes", controllers.GetJoinCodes)
+				teacher.POST("/join-codes/:id/regenerate", controllers.RegenerateJoinCode)
+				teacher.DELETE("/join-codes/:id", controllers.RevokeJoinCode)
+
+				// 我的班级
+				teacher.GET("/classes", controllers.GetMyClasses)
+
+				// 考勤
+				teacher.GET("/attendance/sessions/:id", controllers.GetSessionAttendance)
+				teacher.PUT("/attendance/sessions/:id", controllers.MarkSessionAttendance)
 			}
 			
 			// 学生路由
@@ -97,6 +503,14 @@ func main() {
 				// 学生-家长关系
 				student.POST("/relations/parent", controllers.CreateStudentParentRelation)
 				student.GET("/relations/parents", controllers.GetParentsByStudent)
+
+				// 使用班级加入码
+				student.POST("/join-codes/redeem", controllers.RedeemJoinCode)
+
+				// 选课
+				student.POST("/enrollments", controllers.EnrollInOffering)
+				student.GET("/enrollments", controllers.GetMyEnrollments)
+				student.POST("/enrollments/:id/drop", controllers.DropMyEnrollment)
 			}
 			
 			// 用户管理页面API